@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+func runCommand(args []string) int {
+	switch args[0] {
+	case "openapi":
+		gin.SetMode(gin.ReleaseMode)
+		_, apiSpec := buildRouter(&Config{}, nil, nil)
+		os.Stdout.Write(apiSpec.JSON())
+		return 0
+
+	case "openapi-diff":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, "usage: livecode-api openapi-diff <previous.json> <next.json>")
+			return 2
+		}
+		return diffSpecs(args[1], args[2])
+
+	case "vault-rewrap":
+		return rewrapVaultKeys()
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return 2
+	}
+}
+
+func diffSpecs(previousPath, nextPath string) int {
+	previous, err := openapi.LoadDocument(previousPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	next, err := openapi.LoadDocument(nextPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	changes := openapi.Diff(previous, next)
+	if len(changes) == 0 {
+		fmt.Println("no breaking changes")
+		return 0
+	}
+
+	fmt.Printf("%d breaking change(s):\n", len(changes))
+	for _, change := range changes {
+		fmt.Println("  " + change.String())
+	}
+	return 1
+}
+
+// rewrapVaultKeys moves every user's data key under VAULT_MASTER_KEY. Run it
+// after rotating the master key, with the old key still listed in
+// VAULT_PREVIOUS_MASTER_KEYS; once it succeeds the old key can be removed.
+func rewrapVaultKeys() int {
+	cfg := loadConfig()
+	if cfg.Vault == nil {
+		fmt.Fprintln(os.Stderr, "VAULT_MASTER_KEY is required")
+		return 2
+	}
+
+	if err := database.Connect(cfg.DatabaseURL); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+
+	if err := database.RunMigrations(cfg.DatabaseURL); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	rewrapped, err := handlers.RewrapVaultKeysInternal(cfg.Vault, database.DB)
+	fmt.Printf("rewrapped %d data key(s) under master key %s\n", rewrapped, cfg.Vault.CurrentID())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"livecode-api/internal/metrics"
 	"livecode-api/middleware"
 	"time"
 
@@ -28,6 +29,8 @@ func Connect(databaseURL string) error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	metrics.RegisterDBStats(DB)
+
 	middleware.Logger.Info("connected to PostgreSQL",
 		zap.String("host", "postgres"),
 		zap.Int("max_conns", 25),
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"livecode-api/internal/archive"
+	"livecode-api/internal/checksum"
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/throttle"
+	"livecode-api/internal/transfers"
+	"livecode-api/models"
+)
+
+type ArchivePolicy struct {
+	// MaxEntries bounds the files and directories of an archive, whether
+	// it is packed or extracted.
+	MaxEntries int
+	// MaxExtractedBytes bounds what the files of an extracted archive add
+	// up to.
+	MaxExtractedBytes int64
+}
+
+var archivePolicy = ArchivePolicy{
+	MaxEntries:        100000,
+	MaxExtractedBytes: 10 << 30,
+}
+
+func SetArchivePolicy(policy ArchivePolicy) {
+	archivePolicy = policy
+}
+
+// ListRemoteArchiveInternal lists the tree under dir for packing into an
+// archive, returning dir resolved to an absolute path.
+func ListRemoteArchiveInternal(ctx context.Context, session remotefs.FS, dir string) (string, []archive.Item, error) {
+	resolved, err := session.RealPath(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := session.Stat(resolved)
+	if err != nil {
+		return "", nil, err
+	}
+	if !info.IsDir() {
+		return "", nil, ErrRemoteNotDirectory
+	}
+
+	items, err := archive.List(ctx, session, resolved, archivePolicy.MaxEntries)
+	if err != nil {
+		return "", nil, err
+	}
+	return resolved, items, nil
+}
+
+// downloadArchive packs a remote directory into the staged file. The
+// archive is rebuilt from the start on every attempt; progress counts the
+// file data read from the server against the size of the files listed.
+func (s TransferStore) downloadArchive(ctx context.Context, session remotefs.FS, userID string, transfer *models.Transfer, buckets []*throttle.Bucket) error {
+	root, items, err := ListRemoteArchiveInternal(ctx, session, transfer.RemotePath)
+	if err != nil {
+		return err
+	}
+	info, err := session.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	for _, item := range items {
+		if item.Info.Mode().IsRegular() {
+			size += item.Info.Size()
+		}
+	}
+	_, err = s.DB.ExecContext(ctx, `
+		UPDATE transfers SET size = $2, modified_at = $3, staged_bytes = 0, transferred_bytes = 0, archive_entries = 0
+		WHERE id = $1 AND status = 'running'`,
+		transfer.ID, size, info.ModTime(),
+	)
+	if err != nil {
+		return errors.New("database error during transfer progress")
+	}
+	transfer.Size = &size
+
+	local, err := os.OpenFile(transferStagingPath(transfer.ID), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+	defer local.Close()
+
+	hash, err := checksum.New(transfer.ChecksumAlgorithm)
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+
+	var published time.Time
+	packer := archive.Packer{
+		FS:   session,
+		Root: root,
+		Source: func(r io.Reader) io.Reader {
+			return throttle.Reader(ctx, r, buckets...)
+		},
+		Progress: func(entries int, read int64) error {
+			transfer.ArchiveEntries = entries
+			return s.progress(ctx, userID, transfer, read, &published)
+		},
+	}
+	if err := packer.Pack(ctx, io.MultiWriter(local, hash), *transfer.Archive, items); err != nil {
+		return err
+	}
+	if err := local.Sync(); err != nil {
+		return err
+	}
+	if err := s.progress(ctx, userID, transfer, size, &published); err != nil {
+		return err
+	}
+
+	staged, err := local.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := s.DB.ExecContext(ctx, "UPDATE transfers SET staged_bytes = $2 WHERE id = $1", transfer.ID, staged); err != nil {
+		return errors.New("database error during transfer progress")
+	}
+
+	return s.verify(ctx, transfer, checksum.Hex(hash), func() (string, string, error) {
+		sum, err := checksum.Sum(transfer.ChecksumAlgorithm, io.NewSectionReader(local, 0, staged))
+		return sum, models.TransferVerifiedByReadback, err
+	})
+}
+
+// extract unpacks a staged archive into the remote directory. The whole
+// archive is checked against the policy before anything is written, and
+// entries are counted as they are extracted, so a later attempt skips the
+// ones that are done. Progress is measured in archive bytes.
+func (s TransferStore) extract(ctx context.Context, session remotefs.FS, userID string, transfer *models.Transfer, buckets []*throttle.Bucket) error {
+	file, err := os.Open(transferStagingPath(transfer.ID))
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+	defer file.Close()
+	size := *transfer.Size
+
+	source, err := checksum.Sum(transfer.ChecksumAlgorithm, io.NewSectionReader(file, 0, size))
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+	if _, err := s.DB.ExecContext(ctx, "UPDATE transfers SET source_checksum = $2 WHERE id = $1", transfer.ID, source); err != nil {
+		return errors.New("database error during transfer verification")
+	}
+	if transfer.ExpectedChecksum != nil && *transfer.ExpectedChecksum != source {
+		return transfers.Permanent(ErrTransferChecksumExpected)
+	}
+
+	stats, err := archive.Check(file, size, *transfer.Archive, archive.Limits{
+		MaxEntries: archivePolicy.MaxEntries,
+		MaxBytes:   archivePolicy.MaxExtractedBytes,
+		Symlinks:   transfer.Symlinks,
+	})
+	if err != nil {
+		return err
+	}
+	if stats.Links > 0 && transfer.Symlinks == archive.SymlinksCreate && !session.Capabilities().Symlinks {
+		return transfers.Permanent(remotefs.ErrUnsupported)
+	}
+
+	reader, err := archive.NewReader(file, size, *transfer.Archive)
+	if err != nil {
+		return err
+	}
+	if err := session.MkdirAll(transfer.RemotePath); err != nil {
+		return err
+	}
+
+	extractor := extractor{
+		session:   session,
+		root:      transfer.RemotePath,
+		overwrite: transfer.Overwrite,
+		symlinks:  transfer.Symlinks == archive.SymlinksCreate,
+		buckets:   buckets,
+		dirs:      map[string]bool{".": true},
+	}
+	done := transfer.ArchiveEntries
+	var published time.Time
+	for index := 0; ; index++ {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if index < done {
+			continue
+		}
+
+		if err := extractor.extract(ctx, entry, reader); err != nil {
+			return err
+		}
+		transfer.ArchiveEntries = index + 1
+		if err := s.progress(ctx, userID, transfer, reader.Offset(), &published); err != nil {
+			return err
+		}
+	}
+	return s.progress(ctx, userID, transfer, size, &published)
+}
+
+// extractor writes archive entries under root.
+type extractor struct {
+	session   remotefs.FS
+	root      string
+	overwrite bool
+	symlinks  bool
+	buckets   []*throttle.Bucket
+	// dirs holds the directories known to exist, relative to root.
+	dirs map[string]bool
+}
+
+// extract writes one entry. Files are written next to their target and
+// renamed into place, as uploads are. Symbolic links are only created if
+// the transfer asked for them; hard links and special files are left out.
+func (x *extractor) extract(ctx context.Context, entry *archive.Entry, data io.Reader) error {
+	target := path.Join(x.root, entry.Name)
+	switch {
+	case entry.Mode.IsDir():
+		if err := x.mkdir(entry.Name); err != nil {
+			return err
+		}
+	case entry.Mode.IsRegular():
+		if err := x.mkdir(path.Dir(entry.Name)); err != nil {
+			return err
+		}
+		if err := x.write(ctx, target, data); err != nil {
+			return err
+		}
+		capabilities := x.session.Capabilities()
+		if capabilities.Chmod {
+			if err := x.session.Chmod(target, entry.Mode.Perm()); err != nil {
+				return err
+			}
+		}
+		if capabilities.Chtimes && !entry.ModTime.IsZero() {
+			return x.session.Chtimes(target, entry.ModTime)
+		}
+	case entry.Mode&os.ModeSymlink != 0 && x.symlinks:
+		if err := x.mkdir(path.Dir(entry.Name)); err != nil {
+			return err
+		}
+		return x.symlink(entry.Link, target)
+	}
+	return nil
+}
+
+func (x *extractor) mkdir(dir string) error {
+	if x.dirs[dir] {
+		return nil
+	}
+	if err := x.session.MkdirAll(path.Join(x.root, dir)); err != nil {
+		return err
+	}
+	x.dirs[dir] = true
+	return nil
+}
+
+func (x *extractor) write(ctx context.Context, target string, data io.Reader) error {
+	part := target + transferPartSuffix
+	remote, err := x.session.Create(ctx, part, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(remote, throttle.Reader(ctx, data, x.buckets...)); err != nil {
+		remote.Close()
+		x.session.Remove(part)
+		return err
+	}
+	if err := remote.Close(); err != nil {
+		x.session.Remove(part)
+		return err
+	}
+
+	if _, err := RenameRemoteFileInternal(x.session, part, target, x.overwrite); err != nil {
+		x.session.Remove(part)
+		return err
+	}
+	return nil
+}
+
+func (x *extractor) symlink(link, target string) error {
+	if _, err := x.session.Lstat(target); err == nil {
+		if !x.overwrite {
+			return ErrRemoteExists
+		}
+		if err := DeleteRemoteFileInternal(x.session, target, false); err != nil {
+			return err
+		}
+	}
+	return x.session.Symlink(link, target)
+}
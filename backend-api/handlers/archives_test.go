@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"livecode-api/internal/archive"
+)
+
+func TestExtractor_WritesEntriesUnderRoot(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for _, h := range []tar.Header{
+		{Name: "site/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "site/index.html", Typeflag: tar.TypeReg, Mode: 0o600, Size: 5},
+		{Name: "site/css/app.css", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+		{Name: "site/home", Typeflag: tar.TypeSymlink, Linkname: "index.html"},
+	} {
+		w.WriteHeader(&h)
+		if h.Size > 0 {
+			w.Write([]byte("hello"))
+		}
+	}
+	w.Close()
+	gz.Close()
+
+	extract := func(overwrite, symlinks bool) error {
+		reader, err := archive.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), archive.TarGz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := extractor{session: session, root: "deploy", overwrite: overwrite, symlinks: symlinks, dirs: map[string]bool{".": true}}
+		session.MkdirAll("deploy")
+		for {
+			entry, err := reader.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := x.extract(context.Background(), entry, reader); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := extract(false, false); err != nil {
+		t.Fatalf("Expected the archive to be extracted, got: %v", err)
+	}
+	root := filepath.Join(server.Root, "deploy", "site")
+	if content, _ := os.ReadFile(filepath.Join(root, "css", "app.css")); string(content) != "hello" {
+		t.Errorf("Expected the nested file, got %q", content)
+	}
+	if info, err := os.Stat(filepath.Join(root, "index.html")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the file's permissions to be kept, got %v, %v", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "home")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the link to be skipped, got: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(root, "*"+transferPartSuffix)); len(matches) > 0 {
+		t.Errorf("Expected no temporary files, got %v", matches)
+	}
+
+	if err := extract(false, false); !errors.Is(err, ErrRemoteExists) {
+		t.Errorf("Expected ErrRemoteExists without overwrite, got: %v", err)
+	}
+	if err := extract(true, true); err != nil {
+		t.Fatalf("Expected the archive to be extracted again, got: %v", err)
+	}
+	// The test server makes link targets absolute, so only the link is checked.
+	if info, err := os.Lstat(filepath.Join(root, "home")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the link to be created, got %v, %v", info, err)
+	}
+}
+
+func TestListRemoteArchiveInternal(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+	os.MkdirAll(filepath.Join(server.Root, "logs", "2024"), 0o755)
+	os.WriteFile(filepath.Join(server.Root, "logs", "2024", "app.log"), []byte("x"), 0o644)
+
+	root, items, err := ListRemoteArchiveInternal(context.Background(), session, "logs")
+	if err != nil || filepath.Base(root) != "logs" || len(items) != 2 {
+		t.Errorf("Expected the directory and its file, got %s %v %v", root, items, err)
+	}
+	if _, _, err := ListRemoteArchiveInternal(context.Background(), session, "logs/2024/app.log"); !errors.Is(err, ErrRemoteNotDirectory) {
+		t.Errorf("Expected ErrRemoteNotDirectory, got: %v", err)
+	}
+
+	defer SetArchivePolicy(archivePolicy)
+	SetArchivePolicy(ArchivePolicy{MaxEntries: 1})
+	if _, _, err := ListRemoteArchiveInternal(context.Background(), session, "logs"); !errors.Is(err, archive.ErrTooManyEntries) {
+		t.Errorf("Expected the policy's entry limit to apply, got: %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"livecode-api/internal/throttle"
+	"livecode-api/models"
+
+	"github.com/google/uuid"
+)
+
+var bandwidthDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// transferBandwidth holds the token buckets of the transfers running on
+// this server: one for the whole server, one for each user with running
+// transfers and one for each transfer.
+type transferBandwidth struct {
+	mu           sync.Mutex
+	defaultLimit int64
+	global       *throttle.Bucket
+	users        map[string]*userBandwidth
+	jobs         map[string]*throttle.Bucket
+}
+
+type userBandwidth struct {
+	bucket *throttle.Bucket
+	jobs   int
+}
+
+var bandwidth = &transferBandwidth{
+	global: throttle.NewBucket(0),
+	users:  map[string]*userBandwidth{},
+	jobs:   map[string]*throttle.Bucket{},
+}
+
+// SetTransferBandwidth sets the server's limit in bytes per second, until
+// an admin sets another. 0 means no limit.
+func SetTransferBandwidth(limit int64) {
+	bandwidth.mu.Lock()
+	defer bandwidth.mu.Unlock()
+
+	bandwidth.defaultLimit = limit
+	bandwidth.global.SetRate(limit)
+}
+
+// acquire returns the buckets a transfer's data goes through, and a func
+// that releases them when the transfer stops running.
+func (b *transferBandwidth) acquire(userID, transferID string, limit *int64) ([]*throttle.Bucket, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	user, ok := b.users[userID]
+	if !ok {
+		user = &userBandwidth{bucket: throttle.NewBucket(0)}
+		b.users[userID] = user
+	}
+	user.jobs++
+
+	job := throttle.NewBucket(limitRate(limit))
+	b.jobs[transferID] = job
+
+	return []*throttle.Bucket{job, user.bucket, b.global}, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.jobs, transferID)
+		if user.jobs--; user.jobs == 0 {
+			delete(b.users, userID)
+		}
+	}
+}
+
+// AcquireStreamBandwidth returns the buckets of data streamed between the
+// client and a server outside a transfer, which shares the user's and the
+// server's limits with their transfers. Call release when the stream ends.
+func AcquireStreamBandwidth(ctx context.Context, userID string, db *sql.DB) ([]*throttle.Bucket, func(), error) {
+	buckets, release := bandwidth.acquire(userID, "stream:"+uuid.NewString(), nil)
+	if err := bandwidth.refresh(ctx, db); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return buckets, release, nil
+}
+
+type throttledFile struct {
+	io.Reader
+	io.Seeker
+}
+
+// ThrottleRemoteFile limits reads from file to the rate of the slowest of
+// buckets. Seeking is left to file.
+func ThrottleRemoteFile(ctx context.Context, file io.ReadSeeker, buckets []*throttle.Bucket) io.ReadSeeker {
+	return throttledFile{Reader: throttle.Reader(ctx, file, buckets...), Seeker: file}
+}
+
+// setTransfer changes the limit of a transfer running on this server.
+func (b *transferBandwidth) setTransfer(transferID string, limit *int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if job, ok := b.jobs[transferID]; ok {
+		job.SetRate(limitRate(limit))
+	}
+}
+
+// refresh reloads the server's limit and those of the users with running
+// transfers, and sets the buckets to the limits that apply now.
+func (b *transferBandwidth) refresh(ctx context.Context, db *sql.DB) error {
+	b.mu.Lock()
+	userIDs := make([]string, 0, len(b.users))
+	for id := range b.users {
+		userIDs = append(userIDs, id)
+	}
+	b.mu.Unlock()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT COALESCE(user_id::text, ''), bytes_per_second, timezone, windows
+		FROM bandwidth_limits
+		WHERE user_id IS NULL OR user_id::text = ANY($1)`,
+		userIDs,
+	)
+	if err != nil {
+		return errors.New("database error during bandwidth lookup")
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	limits := map[string]int64{}
+	for rows.Next() {
+		var userID string
+		var settings models.BandwidthSettings
+		if err := scanBandwidthSettings(rows, &settings, &userID); err != nil {
+			return errors.New("database error during bandwidth lookup")
+		}
+		limits[userID] = bandwidthSchedule(settings).Limit(now)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.New("database error during bandwidth lookup")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	global, ok := limits[""]
+	if !ok {
+		global = b.defaultLimit
+	}
+	b.global.SetRate(global)
+	for id, user := range b.users {
+		user.bucket.SetRate(limits[id])
+	}
+	return nil
+}
+
+func limitRate(limit *int64) int64 {
+	if limit == nil {
+		return 0
+	}
+	return *limit
+}
+
+func scanBandwidthSettings(row interface{ Scan(...any) error }, settings *models.BandwidthSettings, extra ...any) error {
+	var limit sql.NullInt64
+	var windows []byte
+
+	if err := row.Scan(append(extra, &limit, &settings.Timezone, &windows)...); err != nil {
+		return err
+	}
+	if limit.Valid {
+		settings.Limit = &limit.Int64
+	}
+	return json.Unmarshal(windows, &settings.Windows)
+}
+
+// bandwidthSchedule converts validated settings.
+func bandwidthSchedule(settings models.BandwidthSettings) throttle.Schedule {
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	schedule := throttle.Schedule{Location: location, Default: limitRate(settings.Limit)}
+	for _, w := range settings.Windows {
+		start, _ := time.Parse("15:04", w.Start)
+		end, _ := time.Parse("15:04", w.End)
+		window := throttle.Window{
+			Start: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+			End:   time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+			Limit: limitRate(w.Limit),
+		}
+		for _, day := range w.Days {
+			window.Days = append(window.Days, bandwidthDays[day])
+		}
+		schedule.Windows = append(schedule.Windows, window)
+	}
+	return schedule
+}
+
+// currentBandwidthLimit returns the limit settings give now, or nil.
+func currentBandwidthLimit(settings *models.BandwidthSettings) *int64 {
+	limit := bandwidthSchedule(*settings).Limit(time.Now())
+	if limit == 0 {
+		return nil
+	}
+	return &limit
+}
+
+// GetBandwidthSettingsInternal returns a user's limits, or the server's if
+// userID is empty. It also returns the limit that applies now.
+func GetBandwidthSettingsInternal(userID string, db *sql.DB) (*models.BandwidthSettings, *int64, error) {
+	settings := models.BandwidthSettings{Timezone: "UTC", Windows: []models.BandwidthWindow{}}
+
+	err := scanBandwidthSettings(db.QueryRow(
+		"SELECT bytes_per_second, timezone, windows FROM bandwidth_limits WHERE user_id IS NOT DISTINCT FROM NULLIF($1, '')::uuid",
+		userID,
+	), &settings)
+
+	if err == sql.ErrNoRows {
+		bandwidth.mu.Lock()
+		if userID == "" && bandwidth.defaultLimit > 0 {
+			limit := bandwidth.defaultLimit
+			settings.Limit = &limit
+		}
+		bandwidth.mu.Unlock()
+	} else if err != nil {
+		return nil, nil, errors.New("database error during bandwidth lookup")
+	}
+
+	return &settings, currentBandwidthLimit(&settings), nil
+}
+
+// UpdateBandwidthSettingsInternal replaces a user's limits, or the
+// server's if userID is empty. Transfers running on this server switch to
+// them at once, those on other servers at their next heartbeat.
+func UpdateBandwidthSettingsInternal(ctx context.Context, userID string, settings models.BandwidthSettings, db *sql.DB) (*models.BandwidthSettings, *int64, error) {
+	if settings.Timezone == "" {
+		settings.Timezone = "UTC"
+	}
+	if settings.Windows == nil {
+		settings.Windows = []models.BandwidthWindow{}
+	}
+	windows, err := json.Marshal(settings.Windows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conflict := "(user_id) WHERE user_id IS NOT NULL"
+	if userID == "" {
+		conflict = "((true)) WHERE user_id IS NULL"
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO bandwidth_limits (user_id, bytes_per_second, timezone, windows)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4)
+		ON CONFLICT `+conflict+` DO UPDATE
+		SET bytes_per_second = EXCLUDED.bytes_per_second, timezone = EXCLUDED.timezone,
+			windows = EXCLUDED.windows, updated_at = now()`,
+		userID, settings.Limit, settings.Timezone, windows,
+	)
+	if err != nil {
+		return nil, nil, errors.New("database error during bandwidth update")
+	}
+
+	if err := bandwidth.refresh(ctx, db); err != nil {
+		return nil, nil, err
+	}
+	return &settings, currentBandwidthLimit(&settings), nil
+}
+
+// SetTransferBandwidthInternal changes the limit of one of the user's
+// unfinished transfers. It returns nil if the transfer does not exist.
+func SetTransferBandwidthInternal(userID, transferID string, limit *int64, db *sql.DB) (*models.Transfer, error) {
+	var transfer models.Transfer
+	err := scanTransfer(db.QueryRow(`
+		UPDATE transfers SET bandwidth_limit = $3
+		WHERE id = $1 AND user_id = $2 AND status = ANY($4)
+		RETURNING `+transferColumns,
+		transferID, userID, limit, unfinishedTransferStatuses,
+	), &transfer)
+
+	if err == sql.ErrNoRows {
+		existing, err := getTransfer(userID, transferID, db)
+		if err != nil || existing == nil {
+			return nil, err
+		}
+		return nil, ErrTransferState
+	}
+	if err != nil {
+		return nil, errors.New("database error during transfer update")
+	}
+
+	bandwidth.setTransfer(transferID, limit)
+	return &transfer, nil
+}
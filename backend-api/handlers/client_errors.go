@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"livecode-api/internal/alerting"
+	"livecode-api/middleware"
+	"livecode-api/models"
+	"livecode-api/utils"
+
+	"go.uber.org/zap"
+)
+
+type ClientIssueAlertPolicy struct {
+	Threshold int
+	Window    time.Duration
+	Notifier  alerting.Notifier
+}
+
+var clientIssueAlertPolicy = ClientIssueAlertPolicy{
+	Threshold: 25,
+	Window:    15 * time.Minute,
+	Notifier:  alerting.LogNotifier{},
+}
+
+func SetClientIssueAlertPolicy(policy ClientIssueAlertPolicy) {
+	clientIssueAlertPolicy = policy
+}
+
+const clientIssueColumns = `id, fingerprint, error_type, title, status, event_count, first_seen_at, last_seen_at, last_alerted_at`
+
+func scanClientIssue(row interface{ Scan(...any) error }, issue *models.ClientIssue) error {
+	var lastAlertedAt sql.NullTime
+
+	err := row.Scan(
+		&issue.ID, &issue.Fingerprint, &issue.ErrorType, &issue.Title, &issue.Status,
+		&issue.EventCount, &issue.FirstSeenAt, &issue.LastSeenAt, &lastAlertedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if lastAlertedAt.Valid {
+		issue.LastAlertedAt = &lastAlertedAt.Time
+	}
+	return nil
+}
+
+func StoreClientErrorInternal(payload models.ClientErrorLog, userID string, db *sql.DB) (models.ClientErrorReceipt, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.ClientErrorReceipt{}, errors.New("database error during client error storage")
+	}
+	defer tx.Rollback()
+
+	receipt, err := storeClientError(tx, payload, userID)
+	if err != nil {
+		return models.ClientErrorReceipt{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.ClientErrorReceipt{}, errors.New("database error during client error storage")
+	}
+
+	go evaluateClientIssueAlert(receipt.Issue, db)
+
+	return receipt, nil
+}
+
+// storeClientError records a report in tx and counts it against its issue.
+func storeClientError(tx *sql.Tx, payload models.ClientErrorLog, userID string) (models.ClientErrorReceipt, error) {
+	fingerprint := utils.FingerprintClientError(payload.ErrorType, payload.ErrorMessage, payload.StackTrace)
+	title := utils.ClientIssueTitle(payload.ErrorType, payload.ErrorMessage)
+
+	breadcrumbs := payload.Breadcrumbs
+	if breadcrumbs == nil {
+		breadcrumbs = []models.ClientBreadcrumb{}
+	}
+	breadcrumbsJSON, err := json.Marshal(breadcrumbs)
+	if err != nil {
+		return models.ClientErrorReceipt{}, errors.New("failed to encode breadcrumbs")
+	}
+
+	var receipt models.ClientErrorReceipt
+	var inserted bool
+
+	row := tx.QueryRow(`
+		INSERT INTO client_issues (fingerprint, error_type, title, event_count)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (fingerprint) DO UPDATE SET
+			event_count = client_issues.event_count + 1,
+			last_seen_at = NOW(),
+			status = CASE WHEN client_issues.status = 'resolved' THEN 'regressed' ELSE client_issues.status END
+		RETURNING `+clientIssueColumns+`, (xmax = 0)`,
+		fingerprint, payload.ErrorType, title,
+	)
+
+	var lastAlertedAt sql.NullTime
+	err = row.Scan(
+		&receipt.Issue.ID, &receipt.Issue.Fingerprint, &receipt.Issue.ErrorType, &receipt.Issue.Title,
+		&receipt.Issue.Status, &receipt.Issue.EventCount, &receipt.Issue.FirstSeenAt, &receipt.Issue.LastSeenAt,
+		&lastAlertedAt, &inserted,
+	)
+	if err != nil {
+		return models.ClientErrorReceipt{}, errors.New("database error during issue upsert")
+	}
+	if lastAlertedAt.Valid {
+		receipt.Issue.LastAlertedAt = &lastAlertedAt.Time
+	}
+	receipt.NewIssue = inserted
+
+	_, err = tx.Exec(`
+		INSERT INTO client_issue_versions (issue_id, app_version, event_count)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (issue_id, app_version) DO UPDATE SET
+			event_count = client_issue_versions.event_count + 1,
+			last_seen_at = NOW()`,
+		receipt.Issue.ID, payload.AppVersion,
+	)
+	if err != nil {
+		return models.ClientErrorReceipt{}, errors.New("database error during version count update")
+	}
+
+	var reportUserID any
+	if userID != "" {
+		reportUserID = userID
+	}
+
+	var reportRequestID any
+	if payload.RequestID != "" {
+		reportRequestID = payload.RequestID
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO client_error_reports
+			(issue_id, user_id, request_id, error_type, error_message, stack_trace, breadcrumbs, app_version, os, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`,
+		receipt.Issue.ID, reportUserID, reportRequestID, payload.ErrorType, payload.ErrorMessage,
+		payload.StackTrace, string(breadcrumbsJSON), payload.AppVersion, payload.OS, payload.Timestamp,
+	).Scan(&receipt.ReportID)
+	if err != nil {
+		return models.ClientErrorReceipt{}, errors.New("database error during report insert")
+	}
+
+	return receipt, nil
+}
+
+func evaluateClientIssueAlert(issue models.ClientIssue, db *sql.DB) {
+	policy := clientIssueAlertPolicy
+	if policy.Threshold <= 0 || policy.Notifier == nil {
+		return
+	}
+
+	threshold := policy.Threshold
+	kind := "client_issue_threshold"
+	if issue.Status == models.ClientIssueStatusRegressed {
+		threshold = 1
+		kind = "client_issue_regressed"
+	}
+
+	var windowCount int64
+	err := db.QueryRow(`
+		WITH recent AS (
+			SELECT COUNT(*) AS total FROM client_error_reports
+			WHERE issue_id = $1 AND received_at > NOW() - make_interval(secs => $2)
+		)
+		UPDATE client_issues SET last_alerted_at = NOW()
+		FROM recent
+		WHERE client_issues.id = $1
+			AND recent.total >= $3
+			AND (client_issues.last_alerted_at IS NULL OR client_issues.last_alerted_at < NOW() - make_interval(secs => $2))
+		RETURNING recent.total`,
+		issue.ID, policy.Window.Seconds(), threshold,
+	).Scan(&windowCount)
+
+	if err == sql.ErrNoRows {
+		return
+	}
+
+	if err != nil {
+		middleware.Logger.Error("client_issue_alert_evaluation_failed",
+			zap.String("issue_id", issue.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alert := alerting.Alert{
+		Kind:     kind,
+		Severity: "warning",
+		Title:    issue.Title,
+		Message:  strconv.FormatInt(windowCount, 10) + " client error reports in the last " + policy.Window.String(),
+		Labels: map[string]string{
+			"issue_id":    issue.ID,
+			"fingerprint": issue.Fingerprint,
+			"status":      issue.Status,
+		},
+		FiredAt: time.Now().UTC(),
+	}
+
+	if err := policy.Notifier.Notify(ctx, alert); err != nil {
+		middleware.Logger.Error("client_issue_alert_delivery_failed",
+			zap.String("issue_id", issue.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+func ListClientIssuesInternal(filter models.ClientIssueFilter, db *sql.DB) ([]models.ClientIssue, int, error) {
+	query := `
+		SELECT ` + clientIssueColumns + `, COUNT(*) OVER()
+		FROM client_issues
+		WHERE ($1 = '' OR status = $1)
+			AND ($2 = '' OR EXISTS (
+				SELECT 1 FROM client_issue_versions v WHERE v.issue_id = client_issues.id AND v.app_version = $2
+			))
+		ORDER BY last_seen_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := db.Query(query, filter.Status, filter.AppVersion, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, errors.New("database error during issue listing")
+	}
+	defer rows.Close()
+
+	issues := []models.ClientIssue{}
+	total := 0
+
+	for rows.Next() {
+		var issue models.ClientIssue
+		var lastAlertedAt sql.NullTime
+
+		err := rows.Scan(
+			&issue.ID, &issue.Fingerprint, &issue.ErrorType, &issue.Title, &issue.Status,
+			&issue.EventCount, &issue.FirstSeenAt, &issue.LastSeenAt, &lastAlertedAt, &total,
+		)
+		if err != nil {
+			return nil, 0, errors.New("database error during issue listing")
+		}
+		if lastAlertedAt.Valid {
+			issue.LastAlertedAt = &lastAlertedAt.Time
+		}
+
+		issues = append(issues, issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.New("database error during issue listing")
+	}
+
+	return issues, total, nil
+}
+
+func GetClientIssueInternal(issueID string, reportLimit int, db *sql.DB) (*models.ClientIssue, error) {
+	var issue models.ClientIssue
+
+	err := scanClientIssue(db.QueryRow(
+		"SELECT "+clientIssueColumns+" FROM client_issues WHERE id = $1",
+		issueID,
+	), &issue)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.New("database error during issue lookup")
+	}
+
+	versionRows, err := db.Query(`
+		SELECT app_version, event_count, first_seen_at, last_seen_at
+		FROM client_issue_versions
+		WHERE issue_id = $1
+		ORDER BY last_seen_at DESC`,
+		issueID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during version lookup")
+	}
+	defer versionRows.Close()
+
+	issue.Versions = []models.ClientIssueVersion{}
+	for versionRows.Next() {
+		var version models.ClientIssueVersion
+		if err := versionRows.Scan(&version.AppVersion, &version.EventCount, &version.FirstSeenAt, &version.LastSeenAt); err != nil {
+			return nil, errors.New("database error during version lookup")
+		}
+		issue.Versions = append(issue.Versions, version)
+	}
+
+	reportRows, err := db.Query(`
+		SELECT id, issue_id, user_id, COALESCE(request_id, ''), error_type, error_message, COALESCE(stack_trace, ''),
+			breadcrumbs, app_version, os, occurred_at, received_at
+		FROM client_error_reports
+		WHERE issue_id = $1
+		ORDER BY received_at DESC
+		LIMIT $2`,
+		issueID, reportLimit,
+	)
+	if err != nil {
+		return nil, errors.New("database error during report lookup")
+	}
+	defer reportRows.Close()
+
+	issue.Reports = []models.ClientErrorReport{}
+	for reportRows.Next() {
+		var report models.ClientErrorReport
+		var userID sql.NullString
+		var breadcrumbs []byte
+
+		err := reportRows.Scan(
+			&report.ID, &report.IssueID, &userID, &report.RequestID, &report.ErrorType, &report.ErrorMessage,
+			&report.StackTrace, &breadcrumbs, &report.AppVersion, &report.OS, &report.OccurredAt, &report.ReceivedAt,
+		)
+		if err != nil {
+			return nil, errors.New("database error during report lookup")
+		}
+
+		if userID.Valid {
+			report.UserID = &userID.String
+		}
+
+		if err := json.Unmarshal(breadcrumbs, &report.Breadcrumbs); err != nil {
+			report.Breadcrumbs = []models.ClientBreadcrumb{}
+		}
+
+		issue.Reports = append(issue.Reports, report)
+	}
+
+	return &issue, nil
+}
+
+func UpdateClientIssueStatusInternal(issueID, status string, db *sql.DB) (*models.ClientIssue, error) {
+	var issue models.ClientIssue
+
+	err := scanClientIssue(db.QueryRow(`
+		UPDATE client_issues
+		SET status = $2,
+			last_alerted_at = CASE WHEN $2 = 'resolved' THEN NULL ELSE last_alerted_at END
+		WHERE id = $1
+		RETURNING `+clientIssueColumns,
+		issueID, status,
+	), &issue)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.New("database error during issue status update")
+	}
+
+	return &issue, nil
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"livecode-api/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrConnectionNameTaken       = errors.New("a connection with this name already exists in the folder")
+	ErrConnectionVersionConflict = errors.New("connection was changed by another client")
+)
+
+const connectionColumns = `id, name, protocol, host, port, username, auth_method, remote_directory, local_directory,
+	folder, tags, color, notes, options, version, created_at, updated_at`
+
+func scanConnection(row interface{ Scan(...any) error }, connection *models.Connection, extra ...any) error {
+	var tags, options []byte
+
+	dest := []any{
+		&connection.ID, &connection.Name, &connection.Protocol, &connection.Host, &connection.Port,
+		&connection.Username, &connection.AuthMethod, &connection.RemoteDirectory, &connection.LocalDirectory,
+		&connection.Folder, &tags, &connection.Color, &connection.Notes, &options, &connection.Version,
+		&connection.CreatedAt, &connection.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(tags, &connection.Tags); err != nil || connection.Tags == nil {
+		connection.Tags = []string{}
+	}
+	if err := json.Unmarshal(options, &connection.Options); err != nil {
+		connection.Options = models.ConnectionOptions{}
+	}
+	return nil
+}
+
+// lockConnections serialises writes to one user's connections for the rest
+// of tx, so change_seq values commit in order and a sync cursor never skips
+// a change that was still in flight.
+func lockConnections(tx *sql.Tx, userID string) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('connections'), hashtext($1))", userID)
+	return err
+}
+
+func isConnectionNameConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "connections_user_id_folder_name_key"
+}
+
+func ListConnectionsInternal(userID string, filter models.ConnectionFilter, db *sql.DB) ([]models.Connection, error) {
+	rows, err := db.Query(`
+		SELECT `+connectionColumns+`
+		FROM connections
+		WHERE user_id = $1 AND deleted_at IS NULL
+			AND ($2 = '' OR lower(folder) = lower($2) OR left(lower(folder), length($2) + 1) = lower($2) || '/')
+			AND ($3 = '' OR EXISTS (
+				SELECT 1 FROM jsonb_array_elements_text(tags) AS tag WHERE lower(tag) = lower($3)
+			))
+		ORDER BY lower(folder), lower(name)`,
+		userID, filter.Folder, filter.Tag,
+	)
+	if err != nil {
+		return nil, errors.New("database error during connection listing")
+	}
+	defer rows.Close()
+
+	connections := []models.Connection{}
+	for rows.Next() {
+		var connection models.Connection
+		if err := scanConnection(rows, &connection); err != nil {
+			return nil, errors.New("database error during connection listing")
+		}
+		connections = append(connections, connection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during connection listing")
+	}
+
+	return connections, nil
+}
+
+func GetConnectionInternal(userID, connectionID string, db *sql.DB) (*models.Connection, error) {
+	var connection models.Connection
+
+	err := scanConnection(db.QueryRow(
+		"SELECT "+connectionColumns+" FROM connections WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL",
+		connectionID, userID,
+	), &connection)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.New("database error during connection lookup")
+	}
+
+	return &connection, nil
+}
+
+func CreateConnectionInternal(userID string, input models.ConnectionInput, db *sql.DB) (*models.Connection, error) {
+	tags, options, err := encodeConnectionInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during connection creation")
+	}
+	defer tx.Rollback()
+
+	if err := lockConnections(tx, userID); err != nil {
+		return nil, errors.New("database error during connection creation")
+	}
+
+	var connection models.Connection
+	err = scanConnection(tx.QueryRow(`
+		INSERT INTO connections
+			(user_id, name, protocol, host, port, username, auth_method, remote_directory, local_directory,
+			folder, tags, color, notes, options)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING `+connectionColumns,
+		userID, input.Name, input.Protocol, input.Host, input.Port, input.Username, input.AuthMethod,
+		input.RemoteDirectory, input.LocalDirectory, input.Folder, tags, input.Color, input.Notes, options,
+	), &connection)
+
+	if isConnectionNameConflict(err) {
+		return nil, ErrConnectionNameTaken
+	}
+	if err != nil {
+		return nil, errors.New("database error during connection creation")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during connection creation")
+	}
+
+	return &connection, nil
+}
+
+// UpdateConnectionInternal replaces a connection if it is still at
+// input.Version. It returns nil when the connection does not exist and
+// ErrConnectionVersionConflict when another client changed it first.
+func UpdateConnectionInternal(userID, connectionID string, input models.ConnectionInput, db *sql.DB) (*models.Connection, error) {
+	tags, options, err := encodeConnectionInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during connection update")
+	}
+	defer tx.Rollback()
+
+	if err := lockConnections(tx, userID); err != nil {
+		return nil, errors.New("database error during connection update")
+	}
+
+	var connection models.Connection
+	err = scanConnection(tx.QueryRow(`
+		UPDATE connections
+		SET name = $4, protocol = $5, host = $6, port = $7, username = $8, auth_method = $9,
+			remote_directory = $10, local_directory = $11, folder = $12, tags = $13, color = $14,
+			notes = $15, options = $16,
+			version = version + 1,
+			change_seq = nextval('connections_change_seq')
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $3
+		RETURNING `+connectionColumns,
+		connectionID, userID, input.Version, input.Name, input.Protocol, input.Host, input.Port, input.Username,
+		input.AuthMethod, input.RemoteDirectory, input.LocalDirectory, input.Folder, tags, input.Color,
+		input.Notes, options,
+	), &connection)
+
+	if err == sql.ErrNoRows {
+		return nil, connectionMissOrConflict(tx, userID, connectionID)
+	}
+	if isConnectionNameConflict(err) {
+		return nil, ErrConnectionNameTaken
+	}
+	if err != nil {
+		return nil, errors.New("database error during connection update")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during connection update")
+	}
+
+	return &connection, nil
+}
+
+// DeleteConnectionInternal replaces a connection with a tombstone that sync
+// reports to other devices and drops its vault items. A non-zero version
+// must match the current one.
+func DeleteConnectionInternal(userID, connectionID string, version int, db *sql.DB) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, errors.New("database error during connection deletion")
+	}
+	defer tx.Rollback()
+
+	if err := lockConnections(tx, userID); err != nil {
+		return false, errors.New("database error during connection deletion")
+	}
+
+	var id string
+	err = tx.QueryRow(`
+		UPDATE connections
+		SET deleted_at = NOW(),
+			version = version + 1,
+			change_seq = nextval('connections_change_seq')
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+		RETURNING id`,
+		connectionID, userID, version,
+	).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		if err := connectionMissOrConflict(tx, userID, connectionID); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New("database error during connection deletion")
+	}
+
+	if _, err := tx.Exec("DELETE FROM vault_items WHERE connection_id = $1 AND user_id = $2", connectionID, userID); err != nil {
+		return false, errors.New("database error during connection secret deletion")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.New("database error during connection deletion")
+	}
+
+	return true, nil
+}
+
+// connectionMissOrConflict explains why a versioned write matched no rows:
+// nil if the connection does not exist, ErrConnectionVersionConflict if it
+// does at another version.
+func connectionMissOrConflict(tx *sql.Tx, userID, connectionID string) error {
+	var exists bool
+	err := tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM connections WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)",
+		connectionID, userID,
+	).Scan(&exists)
+	if err != nil {
+		return errors.New("database error during connection lookup")
+	}
+
+	if exists {
+		return ErrConnectionVersionConflict
+	}
+	return nil
+}
+
+// SyncConnectionsInternal returns the user's connections changed after the
+// since cursor, and the IDs of those deleted. A zero cursor is a full sync
+// and leaves tombstones out.
+func SyncConnectionsInternal(userID string, since int64, db *sql.DB) (models.ConnectionSyncResponse, error) {
+	rows, err := db.Query(`
+		SELECT `+connectionColumns+`, deleted_at IS NOT NULL, change_seq
+		FROM connections
+		WHERE user_id = $1 AND change_seq > $2
+		ORDER BY change_seq`,
+		userID, since,
+	)
+	if err != nil {
+		return models.ConnectionSyncResponse{}, errors.New("database error during connection sync")
+	}
+	defer rows.Close()
+
+	response := models.ConnectionSyncResponse{
+		Success:     true,
+		Connections: []models.Connection{},
+		Deleted:     []string{},
+	}
+	cursor := since
+
+	for rows.Next() {
+		var connection models.Connection
+		var deleted bool
+
+		if err := scanConnection(rows, &connection, &deleted, &cursor); err != nil {
+			return models.ConnectionSyncResponse{}, errors.New("database error during connection sync")
+		}
+
+		switch {
+		case !deleted:
+			response.Connections = append(response.Connections, connection)
+		case since > 0:
+			response.Deleted = append(response.Deleted, connection.ID)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.ConnectionSyncResponse{}, errors.New("database error during connection sync")
+	}
+
+	response.Cursor = strconv.FormatInt(cursor, 10)
+	return response, nil
+}
+
+func encodeConnectionInput(input models.ConnectionInput) (string, string, error) {
+	tags := input.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return "", "", errors.New("failed to encode connection tags")
+	}
+	optionsJSON, err := json.Marshal(input.Options)
+	if err != nil {
+		return "", "", errors.New("failed to encode connection options")
+	}
+
+	return string(tagsJSON), string(optionsJSON), nil
+}
@@ -0,0 +1,10 @@
+package handlers
+
+import "livecode-api/internal/events"
+
+var eventHub *events.Hub
+
+// SetEventHub sets the hub that handlers publish user events to.
+func SetEventHub(hub *events.Hub) {
+	eventHub = hub
+}
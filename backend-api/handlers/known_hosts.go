@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+
+	"livecode-api/internal/sshkeys"
+	"livecode-api/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrKnownHostChanged = errors.New("a different host key is already trusted")
+	ErrKnownHostPinned  = errors.New("a different host key is pinned for the user's team")
+)
+
+const knownHostColumns = `id, host, key_type, public_key, fingerprint_sha256, comment, team_id, first_seen_by, first_seen_at, updated_at`
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func scanKnownHost(row interface{ Scan(...any) error }, host *models.KnownHost) error {
+	var teamID, firstSeenBy sql.NullString
+
+	err := row.Scan(
+		&host.ID, &host.Host, &host.Type, &host.PublicKey, &host.FingerprintSHA256, &host.Comment,
+		&teamID, &firstSeenBy, &host.FirstSeenAt, &host.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	host.Hashed = sshkeys.KnownHost{Host: host.Host}.Hashed()
+	if teamID.Valid {
+		host.TeamID = &teamID.String
+	}
+	if firstSeenBy.Valid {
+		host.FirstSeenBy = &firstSeenBy.String
+	}
+	return nil
+}
+
+func queryKnownHosts(db *sql.DB, event, query string, args ...any) ([]models.KnownHost, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, errors.New("database error during " + event)
+	}
+	defer rows.Close()
+
+	hosts := []models.KnownHost{}
+	for rows.Next() {
+		var host models.KnownHost
+		if err := scanKnownHost(rows, &host); err != nil {
+			return nil, errors.New("database error during " + event)
+		}
+		hosts = append(hosts, host)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during " + event)
+	}
+
+	return hosts, nil
+}
+
+// ListKnownHostsInternal returns the user's own host keys followed by the
+// keys pinned for their teams.
+func ListKnownHostsInternal(userID string, db *sql.DB) ([]models.KnownHost, error) {
+	return queryKnownHosts(db, "known host listing", `
+		SELECT `+knownHostColumns+`
+		FROM known_hosts
+		WHERE user_id = $1
+			OR team_id IN (SELECT team_id FROM team_members WHERE user_id = $1)
+		ORDER BY team_id NULLS FIRST, host, key_type`,
+		userID,
+	)
+}
+
+// knownHostsFor returns the user's keys and team pins for address. Hashed
+// entries cannot be filtered in SQL, so every hashed entry is checked here.
+func knownHostsFor(userID, address string, db *sql.DB) ([]models.KnownHost, error) {
+	candidates, err := queryKnownHosts(db, "known host lookup", `
+		SELECT `+knownHostColumns+`
+		FROM known_hosts
+		WHERE (user_id = $1 OR team_id IN (SELECT team_id FROM team_members WHERE user_id = $1))
+			AND (host = $2 OR host LIKE '|%')
+		ORDER BY team_id NULLS FIRST, key_type`,
+		userID, address,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []models.KnownHost{}
+	for _, host := range candidates {
+		if sshkeys.MatchKnownHost(host.Host, address) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
+func recordKnownHostEvent(q execer, event, userID, teamID, host, keyType, expected, presented string, key models.KnownHostKey) error {
+	_, err := q.Exec(`
+		INSERT INTO known_host_events (id, event, user_id, team_id, host, key_type,
+			expected_fingerprint, presented_fingerprint, client_ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		uuid.New().String(), event, nullableString(userID), nullableString(teamID), host, keyType,
+		nullableString(expected), nullableString(presented), nullableString(key.ClientIP), nullableString(key.UserAgent),
+	)
+	if err != nil {
+		return errors.New("database error during known host event recording")
+	}
+	return nil
+}
+
+func describeHostKey(publicKey string) (sshkeys.Info, error) {
+	key, _, err := sshkeys.ParsePublicKey(publicKey)
+	if err != nil {
+		return sshkeys.Info{}, err
+	}
+	return sshkeys.Describe(key)
+}
+
+// decidingHostKey picks the key a presented key is checked against: a team
+// pin for the key type when there is one, the user's own key otherwise, and
+// the matching key among several.
+func decidingHostKey(hosts []models.KnownHost, info sshkeys.Info) *models.KnownHost {
+	var decisive *models.KnownHost
+	for i := range hosts {
+		host := &hosts[i]
+		if host.Type != info.Type {
+			continue
+		}
+		switch {
+		case decisive == nil,
+			host.TeamID != nil && decisive.TeamID == nil,
+			(host.TeamID == nil) == (decisive.TeamID == nil) && host.FingerprintSHA256 == info.FingerprintSHA256:
+			decisive = host
+		}
+	}
+	return decisive
+}
+
+// VerifyKnownHostInternal checks a presented host key against the user's
+// keys and their teams' pins. A team pin for the key type overrides the
+// user's own key. Mismatches are recorded as key_changed events.
+func VerifyKnownHostInternal(userID string, key models.KnownHostKey, db *sql.DB) (*models.KnownHostVerification, error) {
+	info, err := describeHostKey(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := knownHostsFor(userID, key.Address, db)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &models.KnownHostVerification{
+		Status:      models.KnownHostUnknown,
+		Host:        key.Address,
+		Fingerprint: info.FingerprintSHA256,
+		Expected:    expected,
+	}
+
+	decisive := decidingHostKey(expected, info)
+	if decisive == nil {
+		return verification, nil
+	}
+
+	verification.Pinned = decisive.TeamID != nil
+	if decisive.FingerprintSHA256 == info.FingerprintSHA256 {
+		verification.Status = models.KnownHostTrusted
+		return verification, nil
+	}
+
+	verification.Status = models.KnownHostChanged
+	teamID := ""
+	if decisive.TeamID != nil {
+		teamID = *decisive.TeamID
+	}
+	err = recordKnownHostEvent(db, models.KnownHostEventKeyChanged, userID, teamID, key.Address, info.Type,
+		decisive.FingerprintSHA256, info.FingerprintSHA256, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return verification, nil
+}
+
+// TrustKnownHostInternal records a host key for the user, the first time
+// they connect. A different key of the same type is only replaced when
+// key.Replace is set, and never when it contradicts a team pin.
+func TrustKnownHostInternal(userID string, key models.KnownHostKey, db *sql.DB) (*models.KnownHost, bool, error) {
+	info, err := describeHostKey(key.PublicKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := knownHostsFor(userID, key.Address, db)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var own *models.KnownHost
+	for i, host := range existing {
+		if host.Type != info.Type {
+			continue
+		}
+		if host.TeamID != nil && host.FingerprintSHA256 != info.FingerprintSHA256 {
+			return nil, false, ErrKnownHostPinned
+		}
+		if host.TeamID == nil && (own == nil || host.FingerprintSHA256 == info.FingerprintSHA256) {
+			own = &existing[i]
+		}
+	}
+
+	if own != nil && own.FingerprintSHA256 == info.FingerprintSHA256 {
+		return own, false, nil
+	}
+	if own != nil && !key.Replace {
+		return nil, false, ErrKnownHostChanged
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, errors.New("database error during known host update")
+	}
+	defer tx.Rollback()
+
+	var host models.KnownHost
+	if own == nil {
+		err = scanKnownHost(tx.QueryRow(`
+			INSERT INTO known_hosts (id, user_id, host, key_type, public_key, fingerprint_sha256, comment, first_seen_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $2)
+			ON CONFLICT (user_id, host, key_type) DO UPDATE SET updated_at = now()
+			RETURNING `+knownHostColumns,
+			uuid.New().String(), userID, key.Address, info.Type, info.AuthorizedKey, info.FingerprintSHA256, key.Comment,
+		), &host)
+	} else {
+		err = scanKnownHost(tx.QueryRow(`
+			UPDATE known_hosts
+			SET host = $3, public_key = $4, fingerprint_sha256 = $5, comment = $6, first_seen_by = $2, first_seen_at = now()
+			WHERE id = $1
+			RETURNING `+knownHostColumns,
+			own.ID, userID, key.Address, info.AuthorizedKey, info.FingerprintSHA256, key.Comment,
+		), &host)
+		if err == nil {
+			err = recordKnownHostEvent(tx, models.KnownHostEventKeyReplaced, userID, "", key.Address, info.Type,
+				own.FingerprintSHA256, info.FingerprintSHA256, key)
+		}
+	}
+	if err != nil {
+		return nil, false, errors.New("database error during known host update")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, errors.New("database error during known host update")
+	}
+
+	return &host, own != nil, nil
+}
+
+func DeleteKnownHostInternal(userID, hostID string, db *sql.DB) (bool, error) {
+	result, err := db.Exec("DELETE FROM known_hosts WHERE id = $1 AND user_id = $2", hostID, userID)
+	if err != nil {
+		return false, errors.New("database error during known host deletion")
+	}
+
+	count, _ := result.RowsAffected()
+	return count > 0, nil
+}
+
+// ImportKnownHostsInternal adds the hosts of a known_hosts file. Hosts
+// whose key differs from the one already trusted keep the trusted key and
+// are reported, with a key_changed event, instead.
+func ImportKnownHostsInternal(userID string, data string, key models.KnownHostKey, db *sql.DB) (*models.KnownHostsImportResult, error) {
+	hosts, skipped, err := sshkeys.ParseKnownHosts([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.KnownHostsImportResult{Skipped: skipped, Changed: []string{}}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during known host import")
+	}
+	defer tx.Rollback()
+
+	for _, host := range hosts {
+		info, err := sshkeys.Describe(host.Key)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		var trusted string
+		var inserted bool
+		err = tx.QueryRow(`
+			INSERT INTO known_hosts (id, user_id, host, key_type, public_key, fingerprint_sha256, comment, first_seen_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $2)
+			ON CONFLICT (user_id, host, key_type) DO UPDATE SET host = EXCLUDED.host
+			RETURNING fingerprint_sha256, xmax = 0`,
+			uuid.New().String(), userID, host.Host, info.Type, info.AuthorizedKey, info.FingerprintSHA256, truncateComment(host.Comment),
+		).Scan(&trusted, &inserted)
+		if err != nil {
+			return nil, errors.New("database error during known host import")
+		}
+
+		switch {
+		case inserted:
+			result.Imported++
+		case trusted == info.FingerprintSHA256:
+			result.Unchanged++
+		default:
+			result.Changed = append(result.Changed, host.Host)
+			err := recordKnownHostEvent(tx, models.KnownHostEventKeyChanged, userID, "", host.Host, info.Type,
+				trusted, info.FingerprintSHA256, key)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during known host import")
+	}
+
+	return result, nil
+}
+
+func truncateComment(comment string) string {
+	if runes := []rune(comment); len(runes) > 255 {
+		return string(runes[:255])
+	}
+	return comment
+}
+
+// ExportKnownHostsInternal returns the user's keys and team pins for a
+// known_hosts file.
+func ExportKnownHostsInternal(userID string, db *sql.DB) ([]sshkeys.KnownHost, error) {
+	hosts, err := ListKnownHostsInternal(userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]sshkeys.KnownHost, 0, len(hosts))
+	for _, host := range hosts {
+		key, _, err := sshkeys.ParsePublicKey(host.PublicKey)
+		if err != nil {
+			return nil, errors.New("stored key for known host " + host.ID + " is unreadable")
+		}
+		entries = append(entries, sshkeys.KnownHost{Host: host.Host, Key: key, Comment: host.Comment})
+	}
+	return entries, nil
+}
+
+// ListTeamKnownHostsInternal returns nil when the team does not exist.
+func ListTeamKnownHostsInternal(teamID string, db *sql.DB) ([]models.KnownHost, error) {
+	team, err := GetTeamInternal(teamID, db)
+	if err != nil || team == nil {
+		return nil, err
+	}
+
+	return queryKnownHosts(db, "known host listing",
+		"SELECT "+knownHostColumns+" FROM known_hosts WHERE team_id = $1 ORDER BY host, key_type",
+		teamID,
+	)
+}
+
+// PinKnownHostInternal pins a host key for every member of a team,
+// replacing a pinned key of the same type.
+func PinKnownHostInternal(teamID, adminID string, key models.KnownHostKey, db *sql.DB) (*models.KnownHost, error) {
+	info, err := describeHostKey(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during known host pinning")
+	}
+	defer tx.Rollback()
+
+	var previous sql.NullString
+	err = tx.QueryRow(
+		"SELECT fingerprint_sha256 FROM known_hosts WHERE team_id = $1 AND host = $2 AND key_type = $3 FOR UPDATE",
+		teamID, key.Address, info.Type,
+	).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.New("database error during known host lookup")
+	}
+
+	var host models.KnownHost
+	err = scanKnownHost(tx.QueryRow(`
+		INSERT INTO known_hosts (id, team_id, host, key_type, public_key, fingerprint_sha256, comment, first_seen_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (team_id, host, key_type) DO UPDATE
+		SET public_key = EXCLUDED.public_key, fingerprint_sha256 = EXCLUDED.fingerprint_sha256,
+			comment = EXCLUDED.comment, first_seen_by = EXCLUDED.first_seen_by, first_seen_at = now()
+		RETURNING `+knownHostColumns,
+		uuid.New().String(), teamID, key.Address, info.Type, info.AuthorizedKey, info.FingerprintSHA256, key.Comment, adminID,
+	), &host)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == "known_hosts_team_id_fkey" {
+		return nil, ErrTeamNotFound
+	}
+	if err != nil {
+		return nil, errors.New("database error during known host pinning")
+	}
+
+	event := models.KnownHostEventPinAdded
+	if previous.Valid {
+		event = models.KnownHostEventPinReplaced
+	}
+	if previous.String != info.FingerprintSHA256 {
+		err := recordKnownHostEvent(tx, event, adminID, teamID, key.Address, info.Type, previous.String, info.FingerprintSHA256, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during known host pinning")
+	}
+
+	return &host, nil
+}
+
+func UnpinKnownHostInternal(teamID, hostID, adminID string, key models.KnownHostKey, db *sql.DB) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, errors.New("database error during known host unpinning")
+	}
+	defer tx.Rollback()
+
+	var host, keyType, fingerprint string
+	err = tx.QueryRow(
+		"DELETE FROM known_hosts WHERE id = $1 AND team_id = $2 RETURNING host, key_type, fingerprint_sha256",
+		hostID, teamID,
+	).Scan(&host, &keyType, &fingerprint)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New("database error during known host unpinning")
+	}
+
+	if err := recordKnownHostEvent(tx, models.KnownHostEventPinRemoved, adminID, teamID, host, keyType, fingerprint, "", key); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.New("database error during known host unpinning")
+	}
+
+	return true, nil
+}
+
+func ListKnownHostEventsInternal(filter models.KnownHostEventFilter, db *sql.DB) ([]models.KnownHostEvent, int, error) {
+	rows, err := db.Query(`
+		SELECT id, event, user_id, team_id, host, key_type, expected_fingerprint, presented_fingerprint,
+			client_ip, user_agent, created_at, COUNT(*) OVER()
+		FROM known_host_events
+		WHERE ($1 = '' OR user_id::text = $1)
+			AND ($2 = '' OR team_id::text = $2)
+			AND ($3 = '' OR event = $3)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5`,
+		filter.UserID, filter.TeamID, filter.Event, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, 0, errors.New("database error during known host event listing")
+	}
+	defer rows.Close()
+
+	events := []models.KnownHostEvent{}
+	total := 0
+
+	for rows.Next() {
+		var event models.KnownHostEvent
+		var userID, teamID, expected, presented, clientIP, userAgent sql.NullString
+
+		err := rows.Scan(
+			&event.ID, &event.Event, &userID, &teamID, &event.Host, &event.Type, &expected, &presented,
+			&clientIP, &userAgent, &event.CreatedAt, &total,
+		)
+		if err != nil {
+			return nil, 0, errors.New("database error during known host event listing")
+		}
+
+		if userID.Valid {
+			event.UserID = &userID.String
+		}
+		if teamID.Valid {
+			event.TeamID = &teamID.String
+		}
+		if expected.Valid {
+			event.ExpectedFingerprint = &expected.String
+		}
+		if presented.Valid {
+			event.PresentedFingerprint = &presented.String
+		}
+		event.ClientIP = clientIP.String
+		event.UserAgent = userAgent.String
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.New("database error during known host event listing")
+	}
+
+	return events, total, nil
+}
@@ -4,41 +4,53 @@ import (
 	"database/sql"
 	"errors"
 
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/metrics"
 	"livecode-api/models"
 	"livecode-api/utils"
+
+	"github.com/google/uuid"
 )
 
 func LoginUserInternal(payload models.LoginRequest, db *sql.DB) (models.LoginResponse, error) {
-	query := `SELECT id, username, email, password_hash FROM users WHERE email = $1 OR username = $1 LIMIT 1`
+	query := `SELECT id, username, email, role, password_hash FROM users WHERE email = $1 OR username = $1 LIMIT 1`
 
 	var user models.UserData
 	var passwordHash string
 
-	err := db.QueryRow(query, payload.Identifier).Scan(&user.ID, &user.Username, &user.Email, &passwordHash)
+	err := db.QueryRow(query, payload.Identifier).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &passwordHash)
 
 	if err == sql.ErrNoRows {
+		metrics.LoginAttemptsTotal.WithLabelValues("unknown_identifier").Inc()
 		return models.LoginResponse{
 			Success: false,
 			Message: "Invalid credentials.",
+			Code:    string(apierror.CodeInvalidCredentials),
 		}, nil
 	}
 
 	if err != nil {
+		metrics.LoginAttemptsTotal.WithLabelValues("error").Inc()
 		return models.LoginResponse{}, errors.New("database error during login")
 	}
 
 	if !utils.CheckPasswordHash(payload.Password, passwordHash) {
+		metrics.LoginAttemptsTotal.WithLabelValues("wrong_password").Inc()
 		return models.LoginResponse{
 			Success: false,
 			Message: "Invalid credentials.",
+			Code:    string(apierror.CodeInvalidCredentials),
 		}, nil
 	}
 
-	tokens, err := utils.GenerateTokenPair(user.ID, user.Username, user.Email)
+	tokens, err := issueTokenPair(db, user, uuid.New().String())
 	if err != nil {
+		metrics.LoginAttemptsTotal.WithLabelValues("error").Inc()
 		return models.LoginResponse{}, errors.New("token generation failed")
 	}
 
+	metrics.LoginAttemptsTotal.WithLabelValues("success").Inc()
+
 	return models.LoginResponse{
 		Success:      true,
 		Message:      "Login successful.",
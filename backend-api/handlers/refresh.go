@@ -3,72 +3,157 @@ package handlers
 import (
 	"database/sql"
 	"errors"
-	"livecode-api/config"
-	"os"
-	"strconv"
-	"time"
 
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/events"
+	"livecode-api/internal/metrics"
 	"livecode-api/models"
 	"livecode-api/utils"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 func RefreshTokenInternal(refreshToken string, db *sql.DB) (models.RefreshTokenResponse, error) {
 	_, claims, err := utils.VerifyJWT(refreshToken)
 	if err != nil {
+		metrics.TokenRefreshesTotal.WithLabelValues("invalid").Inc()
 		return models.RefreshTokenResponse{
 			Success: false,
 			Message: "Invalid or expired refresh token.",
+			Code:    string(apierror.CodeRefreshTokenInvalid),
 		}, nil
 	}
 
 	userID, ok := claims["user_id"].(string)
 	if !ok {
+		metrics.TokenRefreshesTotal.WithLabelValues("invalid").Inc()
 		return models.RefreshTokenResponse{
 			Success: false,
 			Message: "Invalid user ID in token.",
+			Code:    string(apierror.CodeRefreshTokenInvalid),
+		}, nil
+	}
+
+	tokenID, ok := claims["jti"].(string)
+	legacy := !ok || tokenID == ""
+	expiresAt, _ := claims.GetExpirationTime()
+	if utils.TokenType(claims) != utils.TokenTypeRefresh || (legacy && expiresAt == nil) {
+		metrics.TokenRefreshesTotal.WithLabelValues("invalid").Inc()
+		return models.RefreshTokenResponse{
+			Success: false,
+			Message: "Invalid or expired refresh token.",
+			Code:    string(apierror.CodeRefreshTokenInvalid),
+		}, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+		return models.RefreshTokenResponse{}, errors.New("database error during token refresh")
+	}
+	defer tx.Rollback()
+
+	if legacy {
+		if tokenID, err = trackLegacyRefreshToken(tx, refreshToken, userID, expiresAt.Time); err != nil {
+			metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+			return models.RefreshTokenResponse{}, errors.New("database error during token refresh")
+		}
+	}
+
+	var tokenUserID, familyID string
+	var usedAt, revokedAt sql.NullTime
+
+	err = tx.QueryRow(
+		"SELECT user_id, family_id, used_at, revoked_at FROM refresh_tokens WHERE id = $1 FOR UPDATE",
+		tokenID,
+	).Scan(&tokenUserID, &familyID, &usedAt, &revokedAt)
+
+	if err == sql.ErrNoRows || (err == nil && tokenUserID != userID) {
+		metrics.TokenRefreshesTotal.WithLabelValues("invalid").Inc()
+		return models.RefreshTokenResponse{
+			Success: false,
+			Message: "Invalid or expired refresh token.",
+			Code:    string(apierror.CodeRefreshTokenInvalid),
+		}, nil
+	}
+
+	if err != nil {
+		metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+		return models.RefreshTokenResponse{}, errors.New("database error during token refresh")
+	}
+
+	if revokedAt.Valid {
+		metrics.TokenRefreshesTotal.WithLabelValues("revoked").Inc()
+		return models.RefreshTokenResponse{
+			Success: false,
+			Message: "Session has been revoked. Please sign in again.",
+			Code:    string(apierror.CodeSessionRevoked),
+		}, nil
+	}
+
+	if usedAt.Valid {
+		_, err = tx.Exec(
+			"UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL",
+			familyID,
+		)
+		if err != nil {
+			metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+			return models.RefreshTokenResponse{}, errors.New("database error during token family revocation")
+		}
+
+		if err = tx.Commit(); err != nil {
+			metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+			return models.RefreshTokenResponse{}, errors.New("database error during token family revocation")
+		}
+
+		eventHub.Publish(tokenUserID, events.TypeSessionRevoked, models.SessionRevokedEvent{Reason: "refresh_token_reused"})
+
+		metrics.RefreshTokenReuseDetectionsTotal.Inc()
+		metrics.TokenRefreshesTotal.WithLabelValues("reuse_detected").Inc()
+		return models.RefreshTokenResponse{
+			Success: false,
+			Message: "Refresh token reuse detected. Please sign in again.",
+			Code:    string(apierror.CodeRefreshTokenReused),
 		}, nil
 	}
 
 	var user models.UserData
-	query := `SELECT id, username, email FROM users WHERE id = $1 LIMIT 1`
-	err = db.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email)
+	query := `SELECT id, username, email, role FROM users WHERE id = $1 LIMIT 1`
+	err = tx.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.Role)
 
 	if err == sql.ErrNoRows {
+		metrics.TokenRefreshesTotal.WithLabelValues("user_not_found").Inc()
 		return models.RefreshTokenResponse{
 			Success: false,
 			Message: "User not found.",
+			Code:    string(apierror.CodeRefreshTokenInvalid),
 		}, nil
 	}
 
 	if err != nil {
+		metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
 		return models.RefreshTokenResponse{}, errors.New("database error during token refresh")
 	}
 
-	accessTokenExpiry, _ := strconv.Atoi(os.Getenv("ACCESS_TOKEN_EXPIRY_MINUTES"))
-	if accessTokenExpiry == 0 {
-		accessTokenExpiry = 15
+	if _, err = tx.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1", tokenID); err != nil {
+		metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+		return models.RefreshTokenResponse{}, errors.New("database error during token refresh")
 	}
 
-	accessTokenClaims := jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-		"exp":      time.Now().Add(time.Duration(accessTokenExpiry) * time.Minute).Unix(),
-		"iat":      time.Now().Unix(),
+	tokens, err := issueTokenPair(tx, user, familyID)
+	if err != nil {
+		metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+		return models.RefreshTokenResponse{}, errors.New("failed to generate token pair")
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(config.JWTSecret))
-
-	if err != nil {
-		return models.RefreshTokenResponse{}, errors.New("failed to generate access token")
+	if err = tx.Commit(); err != nil {
+		metrics.TokenRefreshesTotal.WithLabelValues("error").Inc()
+		return models.RefreshTokenResponse{}, errors.New("database error during token refresh")
 	}
 
+	metrics.TokenRefreshesTotal.WithLabelValues("rotated").Inc()
 	return models.RefreshTokenResponse{
-		Success:     true,
-		Message:     "Access token refreshed successfully.",
-		AccessToken: accessTokenString,
+		Success:      true,
+		Message:      "Access token refreshed successfully.",
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
@@ -4,6 +4,10 @@ import (
 	"database/sql"
 	"errors"
 
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/metrics"
+	"livecode-api/internal/usernames"
+	"livecode-api/middleware"
 	"livecode-api/models"
 	"livecode-api/utils"
 
@@ -14,15 +18,20 @@ func CheckFieldAvailableInternal(field, value string, db *sql.DB) (*bool, error)
 	var query string
 	switch field {
 	case "email":
-		query = "SELECT id FROM users WHERE email = $1 LIMIT 1"
+		query = "SELECT id FROM users WHERE email = $1 OR canonical_email = $2 LIMIT 1"
 	case "username":
-		query = "SELECT id FROM users WHERE username = $1 LIMIT 1"
+		conflict, err := usernameConflict(db, value, "")
+		if err != nil {
+			return nil, err
+		}
+		available := conflict == nil
+		return &available, nil
 	default:
 		return nil, nil
 	}
 
 	var id string
-	err := db.QueryRow(query, value).Scan(&id)
+	err := db.QueryRow(query, value, middleware.EmailPolicy().Canonical(value)).Scan(&id)
 
 	if err == sql.ErrNoRows {
 		available := true
@@ -40,78 +49,81 @@ func CheckFieldAvailableInternal(field, value string, db *sql.DB) (*bool, error)
 func RegisterUserInternal(payload models.RegisterRequest, db *sql.DB) (models.RegisterResponse, error) {
 	var fieldErrors []models.FieldError
 
+	canonicalEmail := middleware.EmailPolicy().Canonical(payload.Email)
+
 	var emailID string
 	emailErr := db.QueryRow(
-		"SELECT id FROM users WHERE email = $1 LIMIT 1",
-		payload.Email,
+		"SELECT id FROM users WHERE email = $1 OR canonical_email = $2 LIMIT 1",
+		payload.Email, canonicalEmail,
 	).Scan(&emailID)
 
 	if emailErr != nil && emailErr != sql.ErrNoRows {
+		metrics.RegistrationsTotal.WithLabelValues("error").Inc()
 		return models.RegisterResponse{}, errors.New("database error during email check")
 	}
 
 	if emailErr == nil {
-		fieldErrors = append(fieldErrors, models.FieldError{
-			Field:   "email",
-			Message: "This email is already taken.",
-		})
+		fieldErrors = append(fieldErrors, apierror.Field("email", apierror.FieldEmailTaken, nil))
 	}
 
-	var usernameID string
-	usernameErr := db.QueryRow(
-		"SELECT id FROM users WHERE username = $1 LIMIT 1",
-		payload.Username,
-	).Scan(&usernameID)
-
-	if usernameErr != nil && usernameErr != sql.ErrNoRows {
+	usernameErr, err := usernameConflict(db, payload.Username, "")
+	if err != nil {
+		metrics.RegistrationsTotal.WithLabelValues("error").Inc()
 		return models.RegisterResponse{}, errors.New("database error during username check")
 	}
 
-	if usernameErr == nil {
-		fieldErrors = append(fieldErrors, models.FieldError{
-			Field:   "username",
-			Message: "This username is already taken.",
-		})
+	if usernameErr != nil {
+		fieldErrors = append(fieldErrors, *usernameErr)
 	}
 
 	if len(fieldErrors) > 0 {
+		metrics.RegistrationsTotal.WithLabelValues("conflict").Inc()
 		return models.RegisterResponse{
 			Success:     false,
 			FieldErrors: fieldErrors,
 			Message:     "Account could not be created.",
+			Code:        string(apierror.CodeAccountConflict),
 		}, nil
 	}
 
 	passwordHash, err := utils.HashPassword(payload.Password)
 	if err != nil {
+		metrics.RegistrationsTotal.WithLabelValues("error").Inc()
 		return models.RegisterResponse{}, errors.New("password hashing failed")
 	}
 
 	userID := uuid.New().String()
 
 	_, err = db.Exec(
-		"INSERT INTO users (id, username, email, password_hash, is_oauth) VALUES ($1, $2, $3, $4, $5)",
-		userID, payload.Username, payload.Email, passwordHash, false,
+		"INSERT INTO users (id, username, username_skeleton, email, canonical_email, password_hash, is_oauth) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		userID, payload.Username, usernames.Skeleton(payload.Username), payload.Email, canonicalEmail, passwordHash, false,
 	)
 
 	if err != nil {
+		metrics.RegistrationsTotal.WithLabelValues("error").Inc()
 		return models.RegisterResponse{}, errors.New("database insert failed")
 	}
 
-	tokens, err := utils.GenerateTokenPair(userID, payload.Username, payload.Email)
+	user := models.UserData{
+		ID:       userID,
+		Username: payload.Username,
+		Email:    payload.Email,
+		Role:     models.RoleUser,
+	}
+
+	tokens, err := issueTokenPair(db, user, uuid.New().String())
 	if err != nil {
+		metrics.RegistrationsTotal.WithLabelValues("error").Inc()
 		return models.RegisterResponse{}, err
 	}
 
+	metrics.RegistrationsTotal.WithLabelValues("success").Inc()
+
 	return models.RegisterResponse{
 		Success:      true,
 		Message:      "Your account has been created successfully.",
 		AccessToken:  &tokens.AccessToken,
 		RefreshToken: &tokens.RefreshToken,
-		User: &models.UserData{
-			ID:       userID,
-			Username: payload.Username,
-			Email:    payload.Email,
-		},
+		User:         &user,
 	}, nil
 }
@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"livecode-api/internal/events"
+	"livecode-api/internal/scheduler"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"go.uber.org/zap"
+)
+
+var ErrScheduleLimit = errors.New("too many schedules")
+
+const (
+	maxSchedulesPerUser = 50
+	// maxScheduleRuns is how many runs are kept for each schedule.
+	maxScheduleRuns = 500
+	// scheduleRunTimeout bounds how long a run may take to plan a sync or
+	// queue a transfer.
+	scheduleRunTimeout = 10 * time.Minute
+)
+
+const scheduleColumns = `id, name, cron, timezone, missed_runs, enabled, kind, request, next_run_at, last_run_at,
+	created_at, updated_at`
+
+func scanSchedule(row interface{ Scan(...any) error }, schedule *models.Schedule, extra ...any) error {
+	var request []byte
+	var nextRunAt, lastRunAt sql.NullTime
+
+	dest := []any{
+		&schedule.ID, &schedule.Name, &schedule.Cron, &schedule.Timezone, &schedule.MissedRuns, &schedule.Enabled,
+		&schedule.Kind, &request, &nextRunAt, &lastRunAt, &schedule.CreatedAt, &schedule.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if nextRunAt.Valid {
+		schedule.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+	if schedule.Kind == models.ScheduleKindSync {
+		return json.Unmarshal(request, &schedule.Sync)
+	}
+	return json.Unmarshal(request, &schedule.Transfer)
+}
+
+const scheduleRunColumns = `id, schedule_id, scheduled_for, status, detail, transfer_id, sync_plan_id, created_at, finished_at`
+
+func scanScheduleRun(row interface{ Scan(...any) error }, run *models.ScheduleRun) error {
+	var detail, transferID, syncPlanID sql.NullString
+	var finishedAt sql.NullTime
+
+	err := row.Scan(&run.ID, &run.ScheduleID, &run.ScheduledFor, &run.Status, &detail, &transferID, &syncPlanID,
+		&run.CreatedAt, &finishedAt)
+	if err != nil {
+		return err
+	}
+
+	if detail.Valid {
+		run.Detail = &detail.String
+	}
+	if transferID.Valid {
+		run.TransferID = &transferID.String
+	}
+	if syncPlanID.Valid {
+		run.SyncPlanID = &syncPlanID.String
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return nil
+}
+
+// scheduleJob returns what a validated request runs, and when it is next
+// due, or nil if it is disabled.
+func scheduleJob(userID string, req models.ScheduleRequest, db *sql.DB) (string, []byte, *time.Time, error) {
+	kind, connectionID := models.ScheduleKindTransfer, ""
+	var job any = req.Transfer
+	if req.Sync != nil {
+		kind, connectionID, job = models.ScheduleKindSync, req.Sync.ConnectionID, req.Sync
+	} else {
+		connectionID = req.Transfer.ConnectionID
+	}
+
+	connection, err := GetConnectionInternal(userID, connectionID, db)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if connection == nil {
+		return "", nil, nil, ErrTransferConnectionNotFound
+	}
+	if err := checkRemoteSupported(connection); err != nil {
+		return "", nil, nil, err
+	}
+
+	request, err := json.Marshal(job)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if !*req.Enabled {
+		return kind, request, nil, nil
+	}
+	schedule, err := scheduler.Parse(req.Cron, req.Timezone)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	next := schedule.Next(time.Now())
+	if next.IsZero() {
+		return kind, request, nil, nil
+	}
+	return kind, request, &next, nil
+}
+
+func CreateScheduleInternal(userID string, req models.ScheduleRequest, db *sql.DB) (*models.Schedule, error) {
+	kind, request, next, err := scheduleJob(userID, req, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM transfer_schedules WHERE user_id = $1", userID).Scan(&count); err != nil {
+		return nil, errors.New("database error during schedule creation")
+	}
+	if count >= maxSchedulesPerUser {
+		return nil, ErrScheduleLimit
+	}
+
+	var schedule models.Schedule
+	err = scanSchedule(db.QueryRow(`
+		INSERT INTO transfer_schedules (user_id, name, cron, timezone, missed_runs, enabled, kind, request, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+scheduleColumns,
+		userID, req.Name, req.Cron, req.Timezone, req.MissedRuns, *req.Enabled, kind, request, next,
+	), &schedule)
+	if err != nil {
+		return nil, errors.New("database error during schedule creation")
+	}
+
+	return &schedule, nil
+}
+
+func ListSchedulesInternal(userID string, db *sql.DB) ([]models.Schedule, error) {
+	rows, err := db.Query(
+		"SELECT "+scheduleColumns+" FROM transfer_schedules WHERE user_id = $1 ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during schedule lookup")
+	}
+	defer rows.Close()
+
+	list := []models.Schedule{}
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := scanSchedule(rows, &schedule); err != nil {
+			return nil, errors.New("database error during schedule lookup")
+		}
+		list = append(list, schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during schedule lookup")
+	}
+
+	return list, nil
+}
+
+func GetScheduleInternal(userID, scheduleID string, db *sql.DB) (*models.Schedule, error) {
+	var schedule models.Schedule
+
+	err := scanSchedule(db.QueryRow(
+		"SELECT "+scheduleColumns+" FROM transfer_schedules WHERE id = $1 AND user_id = $2",
+		scheduleID, userID,
+	), &schedule)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error during schedule lookup")
+	}
+
+	return &schedule, nil
+}
+
+// UpdateScheduleInternal replaces a schedule. Its next run is worked out
+// again from now, so runs missed under the old settings are dropped.
+func UpdateScheduleInternal(userID, scheduleID string, req models.ScheduleRequest, db *sql.DB) (*models.Schedule, error) {
+	kind, request, next, err := scheduleJob(userID, req, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule models.Schedule
+	err = scanSchedule(db.QueryRow(`
+		UPDATE transfer_schedules
+		SET name = $3, cron = $4, timezone = $5, missed_runs = $6, enabled = $7, kind = $8, request = $9, next_run_at = $10
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+scheduleColumns,
+		scheduleID, userID, req.Name, req.Cron, req.Timezone, req.MissedRuns, *req.Enabled, kind, request, next,
+	), &schedule)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error during schedule update")
+	}
+
+	return &schedule, nil
+}
+
+// DeleteScheduleInternal removes a schedule and its runs. Transfers it
+// started carry on. It reports false if the schedule does not exist.
+func DeleteScheduleInternal(userID, scheduleID string, db *sql.DB) (bool, error) {
+	result, err := db.Exec("DELETE FROM transfer_schedules WHERE id = $1 AND user_id = $2", scheduleID, userID)
+	if err != nil {
+		return false, errors.New("database error during schedule deletion")
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ListScheduleRunsInternal returns a schedule's latest runs, or nil if the
+// schedule does not exist.
+func ListScheduleRunsInternal(userID, scheduleID string, limit int, db *sql.DB) ([]models.ScheduleRun, error) {
+	schedule, err := GetScheduleInternal(userID, scheduleID, db)
+	if err != nil || schedule == nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(
+		"SELECT "+scheduleRunColumns+" FROM transfer_schedule_runs WHERE schedule_id = $1 ORDER BY scheduled_for DESC, created_at DESC LIMIT $2",
+		scheduleID, limit,
+	)
+	if err != nil {
+		return nil, errors.New("database error during schedule run lookup")
+	}
+	defer rows.Close()
+
+	list := []models.ScheduleRun{}
+	for rows.Next() {
+		var run models.ScheduleRun
+		if err := scanScheduleRun(rows, &run); err != nil {
+			return nil, errors.New("database error during schedule run lookup")
+		}
+		list = append(list, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during schedule run lookup")
+	}
+
+	return list, nil
+}
+
+// ScheduleStore runs due schedules from Postgres. Grace is how late a run
+// may start before it counts as missed.
+type ScheduleStore struct {
+	DB    *sql.DB
+	Grace time.Duration
+}
+
+type pendingScheduleRun struct {
+	id       string
+	schedule models.Schedule
+	userID   string
+}
+
+// RunDue records the runs that are due and starts them. Several servers
+// can call it at once: each schedule is claimed by one of them.
+func (s ScheduleStore) RunDue(ctx context.Context, now time.Time) {
+	_, err := s.DB.ExecContext(ctx, `
+		UPDATE transfer_schedule_runs
+		SET status = 'failed', detail = 'server stopped before the run started', finished_at = now()
+		WHERE status = 'pending' AND created_at < $1`,
+		now.Add(-2*scheduleRunTimeout),
+	)
+	if err != nil {
+		middleware.Logger.Error("schedule_stale_runs_failed",
+			zap.Error(err),
+		)
+	}
+
+	pending, err := s.claim(ctx, now)
+	if err != nil {
+		middleware.Logger.Error("schedule_claim_failed",
+			zap.Error(err),
+		)
+	}
+
+	for _, run := range pending {
+		s.start(ctx, run)
+	}
+}
+
+// claim moves due schedules on to their next run, recording missed runs
+// and a pending run for each one to start.
+func (s ScheduleStore) claim(ctx context.Context, now time.Time) ([]pendingScheduleRun, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.New("database error during schedule claim")
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+scheduleColumns+`, user_id::text
+		FROM transfer_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT 20
+		FOR UPDATE SKIP LOCKED`,
+		now,
+	)
+	if err != nil {
+		return nil, errors.New("database error during schedule claim")
+	}
+
+	type due struct {
+		schedule models.Schedule
+		userID   string
+	}
+	var list []due
+	for rows.Next() {
+		var d due
+		if err := scanSchedule(rows, &d.schedule, &d.userID); err != nil {
+			rows.Close()
+			return nil, errors.New("database error during schedule claim")
+		}
+		list = append(list, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during schedule claim")
+	}
+
+	var pending []pendingScheduleRun
+	for _, d := range list {
+		var decision scheduler.Decision
+		if spec, err := scheduler.Parse(d.schedule.Cron, d.schedule.Timezone); err == nil {
+			decision = scheduler.Due(spec, *d.schedule.NextRunAt, now, s.Grace, d.schedule.MissedRuns)
+		}
+
+		if len(decision.Missed) > 0 {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO transfer_schedule_runs (schedule_id, scheduled_for, status, detail, finished_at)
+				SELECT $1, t, 'skipped', 'missed', now() FROM unnest($2::timestamptz[]) AS t`,
+				d.schedule.ID, decision.Missed,
+			)
+			if err != nil {
+				return nil, errors.New("database error during schedule claim")
+			}
+		}
+
+		var lastRunAt *time.Time
+		if !decision.Run.IsZero() {
+			run := pendingScheduleRun{schedule: d.schedule, userID: d.userID}
+			err := tx.QueryRowContext(ctx,
+				"INSERT INTO transfer_schedule_runs (schedule_id, scheduled_for, status) VALUES ($1, $2, 'pending') RETURNING id",
+				d.schedule.ID, decision.Run,
+			).Scan(&run.id)
+			if err != nil {
+				return nil, errors.New("database error during schedule claim")
+			}
+			pending = append(pending, run)
+			lastRunAt = &now
+		}
+
+		var next *time.Time
+		if !decision.Next.IsZero() {
+			next = &decision.Next
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE transfer_schedules SET next_run_at = $2, last_run_at = COALESCE($3, last_run_at) WHERE id = $1`,
+			d.schedule.ID, next, lastRunAt,
+		)
+		if err != nil {
+			return nil, errors.New("database error during schedule claim")
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM transfer_schedule_runs
+			WHERE schedule_id = $1 AND id NOT IN (
+				SELECT id FROM transfer_schedule_runs WHERE schedule_id = $1 ORDER BY scheduled_for DESC, created_at DESC LIMIT $2
+			)`,
+			d.schedule.ID, maxScheduleRuns,
+		)
+		if err != nil {
+			return nil, errors.New("database error during schedule claim")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during schedule claim")
+	}
+	return pending, nil
+}
+
+// start queues a run's download, or plans and executes its sync, and
+// records the outcome.
+func (s ScheduleStore) start(ctx context.Context, run pendingScheduleRun) {
+	ctx, cancel := context.WithTimeout(ctx, scheduleRunTimeout)
+	defer cancel()
+
+	audit := models.KnownHostKey{UserAgent: "livecode-scheduler"}
+	var transferID, syncPlanID *string
+	var err error
+
+	switch run.schedule.Kind {
+	case models.ScheduleKindSync:
+		var plan *models.SyncPlan
+		plan, err = PlanSyncInternal(ctx, run.userID, *run.schedule.Sync, audit, s.DB)
+		if err == nil {
+			syncPlanID = &plan.ID
+			_, _, err = ExecuteSyncPlanInternal(ctx, run.userID, plan.ID, audit, s.DB)
+		}
+	default:
+		var transfer *models.Transfer
+		transfer, err = CreateTransferInternal(run.userID, *run.schedule.Transfer, s.DB)
+		if err == nil {
+			transferID = &transfer.ID
+		}
+	}
+
+	status, detail := models.ScheduleRunStarted, ""
+	if err != nil {
+		status, detail = models.ScheduleRunFailed, err.Error()
+		middleware.Logger.Warn("schedule_run_failed",
+			zap.String("schedule_id", run.schedule.ID),
+			zap.Error(err),
+		)
+	}
+
+	var record models.ScheduleRun
+	err = scanScheduleRun(s.DB.QueryRow(`
+		UPDATE transfer_schedule_runs
+		SET status = $2, detail = NULLIF($3, ''), transfer_id = $4, sync_plan_id = $5, finished_at = now()
+		WHERE id = $1
+		RETURNING `+scheduleRunColumns,
+		run.id, status, detail, transferID, syncPlanID,
+	), &record)
+	if err != nil {
+		middleware.Logger.Error("schedule_run_record_failed",
+			zap.String("schedule_id", run.schedule.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	eventHub.Publish(run.userID, events.TypeScheduleRun, record)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/remotesearch"
+	"livecode-api/models"
+)
+
+type SearchPolicy struct {
+	// MaxResults, MaxDepth and Timeout are the defaults, and the most a
+	// request can ask for.
+	MaxResults int
+	MaxDepth   int
+	Timeout    time.Duration
+	// MaxBytes caps the file data a content search reads, and MaxFileSize
+	// the largest file it reads at all.
+	MaxBytes    int64
+	MaxFileSize int64
+}
+
+var searchPolicy = SearchPolicy{
+	MaxResults:  1000,
+	MaxDepth:    20,
+	Timeout:     2 * time.Minute,
+	MaxBytes:    1 << 30,
+	MaxFileSize: 64 << 20,
+}
+
+func SetSearchPolicy(policy SearchPolicy) {
+	searchPolicy = policy
+}
+
+// RemoteSearchRootInternal resolves the directory a search starts in, by
+// default the connection's remote directory.
+func RemoteSearchRootInternal(session remotefs.FS, connection *models.Connection, dir string) (string, error) {
+	if dir == "" {
+		dir = connection.RemoteDirectory
+	}
+	if dir == "" {
+		dir = "."
+	}
+
+	resolved, err := session.RealPath(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := session.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", ErrRemoteNotDirectory
+	}
+	return resolved, nil
+}
+
+// SearchRemoteFilesInternal searches the tree under root, passing each
+// match to emit. Hitting a limit ends the search early, which the summary
+// reports.
+func SearchRemoteFilesInternal(ctx context.Context, session remotefs.FS, root string, req models.RemoteSearchRequest, emit func(models.RemoteSearchMatch) error) (models.RemoteSearchSummary, error) {
+	timeout := searchPolicy.Timeout
+	if req.TimeoutSeconds > 0 {
+		timeout = min(timeout, time.Duration(req.TimeoutSeconds)*time.Second)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	summary, err := remotesearch.Search(ctx, session, remotesearch.Query{
+		Root:        root,
+		Name:        req.Name,
+		Content:     req.Content,
+		Regexp:      req.Regexp,
+		IgnoreCase:  req.IgnoreCase,
+		MaxDepth:    searchLimit(req.MaxDepth, searchPolicy.MaxDepth),
+		MaxResults:  searchLimit(req.MaxResults, searchPolicy.MaxResults),
+		MaxBytes:    searchPolicy.MaxBytes,
+		MaxFileSize: searchPolicy.MaxFileSize,
+	}, func(m remotesearch.Match) error {
+		return emit(models.RemoteSearchMatch{
+			File: RemoteFileFromInfo(m.Dir, m.Info),
+			Line: m.Line,
+			Text: m.Text,
+		})
+	})
+
+	return models.RemoteSearchSummary{
+		Path:         root,
+		Results:      summary.Results,
+		FilesScanned: summary.FilesScanned,
+		BytesScanned: summary.BytesScanned,
+		Method:       summary.Method,
+		Limit:        summary.Limit,
+	}, err
+}
+
+func searchLimit(requested, max int) int {
+	if requested <= 0 {
+		return max
+	}
+	return min(requested, max)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"livecode-api/internal/remotesearch"
+	"livecode-api/models"
+)
+
+func TestSearchRemoteFilesInternal_PolicyLimits(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+	for _, name := range []string{"a.conf", "b.conf", "c.conf", "sub/d.conf"} {
+		target := filepath.Join(server.Root, name)
+		os.MkdirAll(filepath.Dir(target), 0o755)
+		os.WriteFile(target, []byte("listen 80;\n"), 0o644)
+	}
+
+	defer SetSearchPolicy(searchPolicy)
+	policy := searchPolicy
+	policy.MaxResults = 2
+	SetSearchPolicy(policy)
+
+	root, err := RemoteSearchRootInternal(session, &models.Connection{}, "")
+	if err != nil {
+		t.Fatalf("Expected the login directory, got: %v", err)
+	}
+	if _, err := RemoteSearchRootInternal(session, &models.Connection{}, "a.conf"); !errors.Is(err, ErrRemoteNotDirectory) {
+		t.Errorf("Expected ErrRemoteNotDirectory, got: %v", err)
+	}
+
+	var matches []models.RemoteSearchMatch
+	summary, err := SearchRemoteFilesInternal(context.Background(), session, root, models.RemoteSearchRequest{
+		Content:    "listen",
+		MaxResults: 100,
+	}, func(m models.RemoteSearchMatch) error {
+		matches = append(matches, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the search to succeed, got: %v", err)
+	}
+	if len(matches) != 2 || summary.Limit != remotesearch.LimitResults || summary.Path != root {
+		t.Errorf("Expected the server's result limit to apply, got %d matches, %+v", len(matches), summary)
+	}
+	if m := matches[0]; m.File.Type != models.RemoteFileTypeFile || m.Line != 1 || m.Text != "listen 80;" {
+		t.Errorf("Expected a line match with its file, got %+v", m)
+	}
+}
@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/remotefs/ftpfs"
+	"livecode-api/internal/remotefs/s3fs"
+	"livecode-api/internal/remotefs/webdavfs"
+	"livecode-api/internal/sftpgw"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/internal/vault"
+	"livecode-api/models"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+var remotePool *remotefs.Pool
+
+// SetRemotePool sets the pool gateway sessions are taken from.
+func SetRemotePool(pool *remotefs.Pool) {
+	remotePool = pool
+}
+
+// remoteAddresses decides which servers the gateway may connect to. Until
+// it is set, every address is allowed.
+var remoteAddresses *remotefs.AddressPolicy
+
+// SetRemoteAddressPolicy sets the networks connections may reach.
+func SetRemoteAddressPolicy(policy *remotefs.AddressPolicy) {
+	remoteAddresses = policy
+}
+
+var (
+	ErrRemoteUnsupported     = errors.New("the gateway cannot open this connection")
+	ErrRemoteNoCredentials   = errors.New("no credentials for the connection in the vault")
+	ErrRemoteClientEncrypted = errors.New("the connection's credentials are encrypted on the client")
+	ErrRemoteExists          = errors.New("remote path already exists")
+	ErrRemoteNotEmpty        = errors.New("remote directory is not empty")
+	ErrRemoteIsDirectory     = errors.New("remote path is a directory")
+	ErrRemoteNotDirectory    = errors.New("remote path is not a directory")
+)
+
+// RemoteOffsetError rejects resuming an upload past the end of the file.
+type RemoteOffsetError struct {
+	Size int64
+}
+
+func (e *RemoteOffsetError) Error() string {
+	return "upload offset is beyond the end of the remote file (" + strconv.FormatInt(e.Size, 10) + " bytes)"
+}
+
+// HostKeyError rejects a server whose host key the user has not trusted,
+// or whose key differs from the trusted one.
+type HostKeyError struct {
+	Status      string
+	Address     string
+	Fingerprint string
+	PublicKey   string
+}
+
+func (e *HostKeyError) Error() string {
+	return "host key " + e.Fingerprint + " for " + e.Address + " is " + e.Status
+}
+
+// OpenRemoteSessionInternal returns a gateway session for one of the
+// user's connections, or nil if the connection does not exist. For SFTP the
+// server's host key must be trusted in the user's known hosts; audit
+// carries the request details recorded when it has changed.
+func OpenRemoteSessionInternal(ctx context.Context, userID, connectionID string, audit models.KnownHostKey, db *sql.DB) (*remotefs.Session, *models.Connection, error) {
+	connection, err := GetConnectionInternal(userID, connectionID, db)
+	if err != nil || connection == nil {
+		return nil, nil, err
+	}
+
+	dial, err := remoteDialer(userID, connection, audit, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Sessions are shared only while the connection and its credentials are
+	// unchanged; anything saved since is a new key.
+	var credentials string
+	err = db.QueryRow(`
+		SELECT COALESCE(string_agg(id::text || '.' || version, ',' ORDER BY id), '')
+		FROM vault_items WHERE user_id = $1 AND connection_id = $2`,
+		userID, connection.ID,
+	).Scan(&credentials)
+	if err != nil {
+		return nil, nil, errors.New("database error during vault item lookup")
+	}
+	key := connection.ID + "." + strconv.Itoa(connection.Version) + "|" + credentials
+
+	session, err := remotePool.Get(ctx, key, dial)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return session, connection, nil
+}
+
+// checkRemoteSupported returns ErrRemoteUnsupported if the gateway cannot
+// open the connection, such as one that logs in with an SSH agent only the
+// desktop app can reach.
+func checkRemoteSupported(connection *models.Connection) error {
+	switch connection.Protocol {
+	case models.ProtocolSFTP:
+		switch connection.AuthMethod {
+		case models.AuthMethodPassword, models.AuthMethodKeyboardInteractive, models.AuthMethodPublicKey:
+			return nil
+		}
+	case models.ProtocolFTP, models.ProtocolFTPS, models.ProtocolWebDAV, models.ProtocolS3:
+		return nil
+	}
+	return ErrRemoteUnsupported
+}
+
+// remoteDialer returns how to log in to the connection's server. Secrets
+// are only read from the vault when the pool has no idle session.
+func remoteDialer(userID string, connection *models.Connection, audit models.KnownHostKey, db *sql.DB) (remotefs.Dialer, error) {
+	if err := checkRemoteSupported(connection); err != nil {
+		return nil, err
+	}
+	address := net.JoinHostPort(connection.Host, strconv.Itoa(connection.Port))
+	options := connection.Options
+
+	switch connection.Protocol {
+	case models.ProtocolSFTP:
+		return func(ctx context.Context, timeout time.Duration) (remotefs.Conn, error) {
+			auth, err := connectionAuth(userID, connection, db)
+			if err != nil {
+				return nil, err
+			}
+			return sftpgw.Dial(ctx, sftpgw.Target{
+				Address:         address,
+				Username:        connection.Username,
+				Auth:            auth,
+				HostKeyCallback: knownHostCallback(userID, sshkeys.KnownHostAddress(connection.Host, connection.Port), audit, db),
+				Control:         remoteAddressControl(),
+			}, timeout)
+		}, nil
+
+	case models.ProtocolFTP, models.ProtocolFTPS:
+		return func(ctx context.Context, timeout time.Duration) (remotefs.Conn, error) {
+			password, err := connectionPassword(userID, connection, models.VaultItemPassword, db)
+			if err != nil {
+				return nil, err
+			}
+			config := ftpfs.Config{
+				Address:  address,
+				Username: connection.Username,
+				Password: password,
+				Active:   options.ActiveMode,
+				Timeout:  timeout,
+				Control:  remoteAddressControl(),
+			}
+			if connection.Protocol == models.ProtocolFTPS {
+				config.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+				config.Implicit = options.FTPSMode == "implicit"
+			}
+			return ftpfs.Dial(ctx, config)
+		}, nil
+
+	case models.ProtocolWebDAV:
+		return func(ctx context.Context, timeout time.Duration) (remotefs.Conn, error) {
+			password, err := connectionPassword(userID, connection, models.VaultItemPassword, db)
+			if err != nil {
+				return nil, err
+			}
+			scheme := options.WebDAVScheme
+			if scheme == "" {
+				scheme = "https"
+			}
+			return webdavfs.Dial(ctx, webdavfs.Config{
+				URL:      scheme + "://" + address + "/",
+				Username: connection.Username,
+				Password: password,
+				Timeout:  timeout,
+				Control:  remoteAddressControl(),
+			})
+		}, nil
+
+	case models.ProtocolS3:
+		return func(ctx context.Context, timeout time.Duration) (remotefs.Conn, error) {
+			secret, err := connectionPassword(userID, connection, models.VaultItemSecretAccessKey, db)
+			if err != nil {
+				return nil, err
+			}
+			return s3fs.Dial(ctx, s3fs.Config{
+				Endpoint:  address,
+				Secure:    options.S3Scheme != "http",
+				PathStyle: options.PathStyle,
+				Region:    options.Region,
+				AccessKey: connection.Username,
+				SecretKey: secret,
+				Bucket:    options.Bucket,
+				Timeout:   timeout,
+				Control:   remoteAddressControl(),
+			})
+		}, nil
+	}
+	return nil, ErrRemoteUnsupported
+}
+
+func remoteAddressControl() remotefs.DialControl {
+	if remoteAddresses == nil {
+		return nil
+	}
+	return remoteAddresses.Control
+}
+
+// connectionPassword returns the secret of kind stored for the connection,
+// or "" for anonymous connections.
+func connectionPassword(userID string, connection *models.Connection, kind string, db *sql.DB) (string, error) {
+	if connection.AuthMethod == models.AuthMethodAnonymous {
+		return "", nil
+	}
+	secret, err := connectionSecret(userID, connection.ID, kind, db)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", ErrRemoteNoCredentials
+	}
+	return secret, nil
+}
+
+func knownHostCallback(userID, address string, audit models.KnownHostKey, db *sql.DB) ssh.HostKeyCallback {
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		audit.Address = address
+		audit.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+
+		verification, err := VerifyKnownHostInternal(userID, audit, db)
+		if err != nil {
+			return err
+		}
+		if verification.Status == models.KnownHostTrusted {
+			return nil
+		}
+
+		return &HostKeyError{
+			Status:      verification.Status,
+			Address:     address,
+			Fingerprint: verification.Fingerprint,
+			PublicKey:   audit.PublicKey,
+		}
+	}
+}
+
+// connectionAuth builds the SSH auth methods from the secrets stored in the
+// vault for the connection.
+func connectionAuth(userID string, connection *models.Connection, db *sql.DB) ([]ssh.AuthMethod, error) {
+	if connection.AuthMethod != models.AuthMethodPublicKey {
+		password, err := connectionSecret(userID, connection.ID, models.VaultItemPassword, db)
+		if err != nil {
+			return nil, err
+		}
+		if password == "" {
+			return nil, ErrRemoteNoCredentials
+		}
+
+		answer := func(_, _ string, questions []string, _ []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range answers {
+				answers[i] = password
+			}
+			return answers, nil
+		}
+		return []ssh.AuthMethod{ssh.Password(password), ssh.KeyboardInteractive(answer)}, nil
+	}
+
+	privateKey, err := connectionSecret(userID, connection.ID, models.VaultItemPrivateKey, db)
+	if err != nil {
+		return nil, err
+	}
+	if privateKey == "" {
+		return nil, ErrRemoteNoCredentials
+	}
+	passphrase, err := connectionSecret(userID, connection.ID, models.VaultItemPassphrase, db)
+	if err != nil {
+		return nil, err
+	}
+
+	key, _, err := sshkeys.ParsePrivateKey([]byte(privateKey), []byte(passphrase))
+	if err != nil {
+		return nil, errors.New("stored private key for connection " + connection.ID + ": " + err.Error())
+	}
+	signer, err := ssh.NewSignerFromSigner(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+}
+
+// connectionSecret returns the decrypted secret of kind stored for the
+// connection, or "" if there is none.
+func connectionSecret(userID, connectionID, kind string, db *sql.DB) (string, error) {
+	var itemID string
+	err := db.QueryRow(
+		"SELECT id FROM vault_items WHERE user_id = $1 AND connection_id = $2 AND kind = $3",
+		userID, connectionID, kind,
+	).Scan(&itemID)
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.New("database error during vault item lookup")
+	}
+
+	item, err := GetVaultItemInternal(userID, itemID, db)
+	if err != nil || item == nil {
+		return "", err
+	}
+	if item.Encryption == vault.EncryptionClient {
+		return "", ErrRemoteClientEncrypted
+	}
+	return item.Secret, nil
+}
+
+// RemoteFileFromInfo describes info, an entry of dir.
+func RemoteFileFromInfo(dir string, info os.FileInfo) models.RemoteFile {
+	file := models.RemoteFile{
+		Name:        info.Name(),
+		Path:        path.Join(dir, info.Name()),
+		Type:        models.RemoteFileTypeOther,
+		Size:        info.Size(),
+		Mode:        "0" + strconv.FormatUint(uint64(info.Mode().Perm()), 8),
+		Permissions: info.Mode().String(),
+		ModifiedAt:  info.ModTime().UTC(),
+	}
+
+	switch {
+	case info.Mode().IsRegular():
+		file.Type = models.RemoteFileTypeFile
+	case info.IsDir():
+		file.Type = models.RemoteFileTypeDirectory
+	case info.Mode()&os.ModeSymlink != 0:
+		file.Type = models.RemoteFileTypeSymlink
+	}
+
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		file.UID, file.GID = &stat.UID, &stat.GID
+	}
+	return file
+}
+
+// RemoteCapabilitiesFrom describes what a session's protocol supports.
+func RemoteCapabilitiesFrom(fsys remotefs.FS) models.RemoteCapabilities {
+	capabilities := fsys.Capabilities()
+	return models.RemoteCapabilities{
+		Chmod:             capabilities.Chmod,
+		Chtimes:           capabilities.Chtimes,
+		Symlinks:          capabilities.Symlinks,
+		Ownership:         capabilities.Ownership,
+		ResumeUpload:      capabilities.ResumeUpload,
+		RenameDirectories: capabilities.RenameDirectories,
+		Exec:              capabilities.Exec,
+	}
+}
+
+// ListRemoteDirectoryInternal lists dir, resolved to an absolute path,
+// with directories first.
+func ListRemoteDirectoryInternal(fsys remotefs.FS, dir string) (string, []models.RemoteFile, error) {
+	resolved, err := fsys.RealPath(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	infos, err := fsys.ReadDir(resolved)
+	if err != nil {
+		if info, statErr := fsys.Stat(resolved); statErr == nil && !info.IsDir() {
+			return "", nil, ErrRemoteNotDirectory
+		}
+		return "", nil, err
+	}
+
+	files := make([]models.RemoteFile, 0, len(infos))
+	for _, info := range infos {
+		files = append(files, RemoteFileFromInfo(resolved, info))
+	}
+	sortRemoteFiles(files)
+
+	return resolved, files, nil
+}
+
+func sortRemoteFiles(files []models.RemoteFile) {
+	slices.SortFunc(files, func(a, b models.RemoteFile) int {
+		aDir, bDir := a.Type == models.RemoteFileTypeDirectory, b.Type == models.RemoteFileTypeDirectory
+		if aDir != bDir {
+			if aDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+func StatRemoteFileInternal(fsys remotefs.FS, name string) (*models.RemoteFile, error) {
+	info, err := fsys.Lstat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file := RemoteFileFromInfo(path.Dir(name), info)
+	return &file, nil
+}
+
+// OpenRemoteFileInternal opens a regular file for reading. The reader
+// seeks, so it can serve ranges.
+func OpenRemoteFileInternal(ctx context.Context, fsys remotefs.FS, name string) (*remotefs.Reader, os.FileInfo, error) {
+	info, err := fsys.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return nil, nil, ErrRemoteIsDirectory
+	}
+
+	return remotefs.NewReader(ctx, fsys, name, info.Size()), info, nil
+}
+
+// UploadRemoteFileInternal writes body to name. Without overwrite an
+// existing file is an error; with offset the upload resumes an existing
+// file from there, which the protocol must support.
+func UploadRemoteFileInternal(ctx context.Context, fsys remotefs.FS, name string, body io.Reader, offset int64, overwrite bool) (*models.RemoteFile, error) {
+	existing, err := fsys.Stat(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if existing != nil && existing.IsDir() {
+		return nil, ErrRemoteIsDirectory
+	}
+
+	switch {
+	case offset > 0:
+		if !fsys.Capabilities().ResumeUpload {
+			return nil, remotefs.ErrUnsupported
+		}
+		if existing == nil {
+			return nil, os.ErrNotExist
+		}
+		if existing.Size() < offset {
+			return nil, &RemoteOffsetError{Size: existing.Size()}
+		}
+	case existing != nil && !overwrite:
+		return nil, ErrRemoteExists
+	}
+
+	file, err := fsys.Create(ctx, name, offset)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	return StatRemoteFileInternal(fsys, name)
+}
+
+func MakeRemoteDirectoryInternal(fsys remotefs.FS, name string, parents bool) (*models.RemoteFile, error) {
+	if parents {
+		if err := fsys.MkdirAll(name); err != nil {
+			return nil, err
+		}
+		return StatRemoteFileInternal(fsys, name)
+	}
+
+	if _, err := fsys.Lstat(name); err == nil {
+		return nil, ErrRemoteExists
+	}
+	if err := fsys.Mkdir(name); err != nil {
+		return nil, err
+	}
+	return StatRemoteFileInternal(fsys, name)
+}
+
+// RenameRemoteFileInternal moves from to to, replacing a file at to if
+// overwrite is set.
+func RenameRemoteFileInternal(fsys remotefs.FS, from, to string, overwrite bool) (*models.RemoteFile, error) {
+	source, err := fsys.Lstat(from)
+	if err != nil {
+		return nil, err
+	}
+	if source.IsDir() && !fsys.Capabilities().RenameDirectories {
+		return nil, remotefs.ErrUnsupported
+	}
+
+	target, err := fsys.Lstat(to)
+	switch {
+	case err == nil && !overwrite:
+		return nil, ErrRemoteExists
+	case err == nil && target.IsDir():
+		return nil, ErrRemoteIsDirectory
+	case err == nil:
+		err = fsys.Rename(from, to, true)
+	case errors.Is(err, os.ErrNotExist):
+		err = fsys.Rename(from, to, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return StatRemoteFileInternal(fsys, to)
+}
+
+// DeleteRemoteFileInternal removes a file, link or empty directory, or a
+// whole tree when recursive is set.
+func DeleteRemoteFileInternal(fsys remotefs.FS, name string, recursive bool) error {
+	info, err := fsys.Lstat(name)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fsys.Remove(name)
+	}
+	if recursive {
+		return fsys.RemoveAll(name)
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return ErrRemoteNotEmpty
+	}
+	return fsys.RemoveDirectory(name)
+}
+
+func ChmodRemoteFileInternal(fsys remotefs.FS, name string, mode uint32) (*models.RemoteFile, error) {
+	if !fsys.Capabilities().Chmod {
+		return nil, remotefs.ErrUnsupported
+	}
+
+	fileMode := os.FileMode(mode & 0o777)
+	if mode&0o4000 != 0 {
+		fileMode |= os.ModeSetuid
+	}
+	if mode&0o2000 != 0 {
+		fileMode |= os.ModeSetgid
+	}
+	if mode&0o1000 != 0 {
+		fileMode |= os.ModeSticky
+	}
+
+	if err := fsys.Chmod(name, fileMode); err != nil {
+		return nil, err
+	}
+	return StatRemoteFileInternal(fsys, name)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/remotefs/s3fs"
+	"livecode-api/internal/remotefs/s3fs/s3test"
+	"livecode-api/internal/sftpgw"
+	"livecode-api/internal/sftpgw/sftptest"
+	"livecode-api/models"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestSFTPSession(t *testing.T) (*sftptest.Server, *sftpgw.Session) {
+	server := sftptest.NewServer(t)
+	session, err := sftpgw.Dial(context.Background(), sftpgw.Target{
+		Address:         server.Addr,
+		Username:        server.User,
+		Auth:            []ssh.AuthMethod{ssh.Password(server.Password)},
+		HostKeyCallback: ssh.FixedHostKey(server.HostKey),
+	}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to open SFTP session: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return server, session
+}
+
+func TestUploadRemoteFileInternal_CreateOverwriteAndResume(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+
+	file, err := UploadRemoteFileInternal(context.Background(), session, "notes.txt", strings.NewReader("hello world"), 0, false)
+	if err != nil {
+		t.Fatalf("Expected upload to succeed, got: %v", err)
+	}
+	if file.Size != 11 || file.Type != models.RemoteFileTypeFile {
+		t.Errorf("Expected an 11 byte file, got: %+v", file)
+	}
+
+	if _, err := UploadRemoteFileInternal(context.Background(), session, "notes.txt", strings.NewReader("x"), 0, false); !errors.Is(err, ErrRemoteExists) {
+		t.Errorf("Expected ErrRemoteExists, got: %v", err)
+	}
+
+	var offsetErr *RemoteOffsetError
+	if _, err := UploadRemoteFileInternal(context.Background(), session, "notes.txt", strings.NewReader("x"), 50, false); !errors.As(err, &offsetErr) || offsetErr.Size != 11 {
+		t.Errorf("Expected RemoteOffsetError with size 11, got: %v", err)
+	}
+
+	if _, err := UploadRemoteFileInternal(context.Background(), session, "notes.txt", strings.NewReader("there"), 6, false); err != nil {
+		t.Fatalf("Expected resume to succeed, got: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(server.Root, "notes.txt"))
+	if string(data) != "hello there" {
+		t.Errorf("Expected resumed content, got %q", data)
+	}
+
+	if _, err := UploadRemoteFileInternal(context.Background(), session, "notes.txt", strings.NewReader("new"), 0, true); err != nil {
+		t.Fatalf("Expected overwrite to succeed, got: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(server.Root, "notes.txt"))
+	if string(data) != "new" {
+		t.Errorf("Expected overwritten content, got %q", data)
+	}
+}
+
+func TestThrottleRemoteFile_RespectsUserBucket(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+	os.WriteFile(filepath.Join(server.Root, "large.bin"), bytes.Repeat([]byte("x"), 192<<10), 0o644)
+
+	buckets, release := bandwidth.acquire("throttled-user", "stream:test", nil)
+	defer release()
+	buckets[1].SetRate(256 << 10)
+
+	file, _, err := OpenRemoteFileInternal(context.Background(), session, "large.bin")
+	if err != nil {
+		t.Fatalf("Expected to open the file, got: %v", err)
+	}
+	defer file.Close()
+
+	start := time.Now()
+	data, err := io.ReadAll(ThrottleRemoteFile(context.Background(), file, buckets))
+	if err != nil || len(data) != 192<<10 {
+		t.Fatalf("Expected all data, got %d bytes: %v", len(data), err)
+	}
+	// The first 64 KiB come from the full bucket, the rest at 256 KiB/s.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the download to be throttled, took %v", elapsed)
+	}
+}
+
+func TestListRemoteDirectoryInternal_DirectoriesFirst(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+	os.WriteFile(filepath.Join(server.Root, "a.txt"), []byte("a"), 0o644)
+	os.Mkdir(filepath.Join(server.Root, "zeta"), 0o755)
+
+	resolved, files, err := ListRemoteDirectoryInternal(session, ".")
+	if err != nil {
+		t.Fatalf("Expected listing to succeed, got: %v", err)
+	}
+	if !strings.HasPrefix(resolved, "/") {
+		t.Errorf("Expected an absolute path, got %q", resolved)
+	}
+	if len(files) != 2 || files[0].Name != "zeta" || files[1].Name != "a.txt" {
+		t.Errorf("Expected zeta before a.txt, got: %+v", files)
+	}
+}
+
+func TestRemoteFileOperations(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+
+	if _, err := MakeRemoteDirectoryInternal(session, "site/assets", true); err != nil {
+		t.Fatalf("Expected mkdir -p to succeed, got: %v", err)
+	}
+	if _, err := MakeRemoteDirectoryInternal(session, "site", false); !errors.Is(err, ErrRemoteExists) {
+		t.Errorf("Expected ErrRemoteExists, got: %v", err)
+	}
+
+	UploadRemoteFileInternal(context.Background(), session, "site/index.html", strings.NewReader("<h1>"), 0, false)
+	UploadRemoteFileInternal(context.Background(), session, "site/old.html", strings.NewReader("old"), 0, false)
+
+	if _, err := RenameRemoteFileInternal(session, "site/old.html", "site/index.html", false); !errors.Is(err, ErrRemoteExists) {
+		t.Errorf("Expected ErrRemoteExists, got: %v", err)
+	}
+	if _, err := RenameRemoteFileInternal(session, "site/old.html", "site/index.html", true); err != nil {
+		t.Fatalf("Expected rename with overwrite to succeed, got: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(server.Root, "site", "index.html"))
+	if string(data) != "old" {
+		t.Errorf("Expected the renamed file to replace the target, got %q", data)
+	}
+
+	file, err := ChmodRemoteFileInternal(session, "site/index.html", 0o600)
+	if err != nil || file.Mode != "0600" {
+		t.Errorf("Expected mode 0600, got %+v, %v", file, err)
+	}
+
+	if err := DeleteRemoteFileInternal(session, "site", false); !errors.Is(err, ErrRemoteNotEmpty) {
+		t.Errorf("Expected ErrRemoteNotEmpty, got: %v", err)
+	}
+	if err := DeleteRemoteFileInternal(session, "site", true); err != nil {
+		t.Fatalf("Expected recursive delete to succeed, got: %v", err)
+	}
+	if _, err := StatRemoteFileInternal(session, "site"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the directory to be gone, got: %v", err)
+	}
+}
+
+func TestRemoteFileOperations_UnsupportedByProtocol(t *testing.T) {
+	server := s3test.NewServer(t)
+	server.CreateBucket("site")
+	endpoint, _ := url.Parse(server.URL)
+	conn, err := s3fs.Dial(context.Background(), s3fs.Config{
+		Endpoint:  endpoint.Host,
+		AccessKey: server.AccessKey,
+		SecretKey: server.SecretKey,
+		Bucket:    "site",
+		Timeout:   5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect to S3: %v", err)
+	}
+
+	if _, err := MakeRemoteDirectoryInternal(conn, "docs", false); err != nil {
+		t.Fatalf("Expected mkdir to succeed, got: %v", err)
+	}
+	if _, err := UploadRemoteFileInternal(context.Background(), conn, "docs/a.txt", strings.NewReader("hello"), 0, false); err != nil {
+		t.Fatalf("Expected upload to succeed, got: %v", err)
+	}
+
+	if _, err := UploadRemoteFileInternal(context.Background(), conn, "docs/a.txt", strings.NewReader("!"), 5, false); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected resuming to be unsupported, got: %v", err)
+	}
+	if _, err := ChmodRemoteFileInternal(conn, "docs/a.txt", 0o600); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected chmod to be unsupported, got: %v", err)
+	}
+	if _, err := RenameRemoteFileInternal(conn, "docs", "papers", false); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected renaming a directory to be unsupported, got: %v", err)
+	}
+
+	file, info, err := OpenRemoteFileInternal(context.Background(), conn, "docs/a.txt")
+	if err != nil {
+		t.Fatalf("Expected to open the file, got: %v", err)
+	}
+	defer file.Close()
+	file.Seek(1, io.SeekStart)
+	if data, _ := io.ReadAll(file); string(data) != "ello" || info.Size() != 5 {
+		t.Errorf("Expected to read from the seek offset, got %q", data)
+	}
+
+	if capabilities := RemoteCapabilitiesFrom(conn); capabilities.Chmod || capabilities.ResumeUpload {
+		t.Errorf("Expected no chmod or resume, got %+v", capabilities)
+	}
+}
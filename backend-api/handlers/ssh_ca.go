@@ -0,0 +1,724 @@
+package handlers
+
+import (
+	"crypto"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"livecode-api/internal/events"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/internal/vault"
+	"livecode-api/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrSSHCANotConfigured = errors.New("no active ssh certificate authority")
+	ErrSSHCANoPrincipals  = errors.New("user has no ssh principals")
+	ErrSSHCertKeyNotFound = errors.New("ssh key not found")
+	ErrTeamExists         = errors.New("team name already taken")
+	ErrTeamNotFound       = errors.New("team not found")
+	ErrTeamUserNotFound   = errors.New("user not found")
+)
+
+// sshCertificateClockSkew backdates certificates so servers with a slow
+// clock accept them straight away.
+const sshCertificateClockSkew = 5 * time.Minute
+
+const teamColumns = `t.id, t.name, t.principals, (SELECT COUNT(*) FROM team_members m WHERE m.team_id = t.id), t.created_at, t.updated_at`
+
+const sshCAColumns = `id, name, key_type, public_key, fingerprint_sha256, active, created_at, retired_at`
+
+const sshCertificateColumns = `serial, ca_id, user_id, ssh_key_id, key_fingerprint, key_id, principals,
+	valid_after, valid_before, issued_at, client_ip, user_agent, revoked_at, revoked_by, revocation_reason`
+
+// sshCAAssociatedData binds a CA's key material to its row, the way
+// vault.AssociatedData does for vault items.
+func sshCAAssociatedData(caID string) (keyOwner string, associatedData []byte) {
+	return "ssh-ca:" + caID, vault.AssociatedData("ssh-ca", caID)
+}
+
+func scanTeam(row interface{ Scan(...any) error }, team *models.Team) error {
+	var principals []byte
+
+	err := row.Scan(&team.ID, &team.Name, &principals, &team.Members, &team.CreatedAt, &team.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(principals, &team.Principals); err != nil || team.Principals == nil {
+		team.Principals = []string{}
+	}
+	return nil
+}
+
+func scanSSHCertificateAuthority(row interface{ Scan(...any) error }, ca *models.SSHCertificateAuthority) error {
+	var retiredAt sql.NullTime
+
+	err := row.Scan(&ca.ID, &ca.Name, &ca.Type, &ca.PublicKey, &ca.FingerprintSHA256, &ca.Active, &ca.CreatedAt, &retiredAt)
+	if err != nil {
+		return err
+	}
+
+	if retiredAt.Valid {
+		ca.RetiredAt = &retiredAt.Time
+	}
+	return nil
+}
+
+func scanSSHCertificate(row interface{ Scan(...any) error }, cert *models.SSHCertificate) error {
+	var userID, sshKeyID, clientIP, userAgent, revokedBy, reason sql.NullString
+	var revokedAt sql.NullTime
+	var principals []byte
+
+	err := row.Scan(
+		&cert.Serial, &cert.CAID, &userID, &sshKeyID, &cert.KeyFingerprint, &cert.KeyID, &principals,
+		&cert.ValidAfter, &cert.ValidBefore, &cert.IssuedAt, &clientIP, &userAgent, &revokedAt, &revokedBy, &reason,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(principals, &cert.Principals); err != nil || cert.Principals == nil {
+		cert.Principals = []string{}
+	}
+	if userID.Valid {
+		cert.UserID = &userID.String
+	}
+	if sshKeyID.Valid {
+		cert.SSHKeyID = &sshKeyID.String
+	}
+	cert.ClientIP = clientIP.String
+	cert.UserAgent = userAgent.String
+	if revokedAt.Valid {
+		cert.RevokedAt = &revokedAt.Time
+	}
+	if revokedBy.Valid {
+		cert.RevokedBy = &revokedBy.String
+	}
+	if reason.Valid {
+		cert.RevocationReason = &reason.String
+	}
+	return nil
+}
+
+// ListTeamsInternal returns every team, or only userID's teams when it is
+// set.
+func ListTeamsInternal(userID string, db *sql.DB) ([]models.Team, error) {
+	rows, err := db.Query(`
+		SELECT `+teamColumns+`
+		FROM teams t
+		WHERE $1 = '' OR EXISTS (SELECT 1 FROM team_members m WHERE m.team_id = t.id AND m.user_id::text = $1)
+		ORDER BY t.name`,
+		userID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during team listing")
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		var team models.Team
+		if err := scanTeam(rows, &team); err != nil {
+			return nil, errors.New("database error during team listing")
+		}
+		teams = append(teams, team)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during team listing")
+	}
+
+	return teams, nil
+}
+
+func GetTeamInternal(teamID string, db *sql.DB) (*models.Team, error) {
+	var team models.Team
+
+	err := scanTeam(db.QueryRow("SELECT "+teamColumns+" FROM teams t WHERE t.id = $1", teamID), &team)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error during team lookup")
+	}
+
+	return &team, nil
+}
+
+func CreateTeamInternal(input models.TeamInput, db *sql.DB) (*models.Team, error) {
+	principals, err := json.Marshal(input.Principals)
+	if err != nil {
+		return nil, errors.New("failed to encode team principals")
+	}
+
+	teamID := uuid.New().String()
+	_, err = db.Exec(
+		"INSERT INTO teams (id, name, principals) VALUES ($1, $2, $3)",
+		teamID, input.Name, string(principals),
+	)
+	if isUniqueViolation(err) {
+		return nil, ErrTeamExists
+	}
+	if err != nil {
+		return nil, errors.New("database error during team creation")
+	}
+
+	return GetTeamInternal(teamID, db)
+}
+
+// UpdateTeamInternal renames a team and replaces its principals. Existing
+// certificates keep the principals they were issued with.
+func UpdateTeamInternal(teamID string, input models.TeamInput, db *sql.DB) (*models.Team, error) {
+	principals, err := json.Marshal(input.Principals)
+	if err != nil {
+		return nil, errors.New("failed to encode team principals")
+	}
+
+	result, err := db.Exec(
+		"UPDATE teams SET name = $2, principals = $3 WHERE id = $1",
+		teamID, input.Name, string(principals),
+	)
+	if isUniqueViolation(err) {
+		return nil, ErrTeamExists
+	}
+	if err != nil {
+		return nil, errors.New("database error during team update")
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		return nil, nil
+	}
+
+	return GetTeamInternal(teamID, db)
+}
+
+func DeleteTeamInternal(teamID string, db *sql.DB) (bool, error) {
+	result, err := db.Exec("DELETE FROM teams WHERE id = $1", teamID)
+	if err != nil {
+		return false, errors.New("database error during team deletion")
+	}
+
+	count, _ := result.RowsAffected()
+	return count > 0, nil
+}
+
+// ListTeamMembersInternal returns nil when the team does not exist.
+func ListTeamMembersInternal(teamID string, db *sql.DB) ([]models.TeamMember, error) {
+	team, err := GetTeamInternal(teamID, db)
+	if err != nil || team == nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT u.id, u.username, u.email, m.created_at
+		FROM team_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.team_id = $1
+		ORDER BY u.username`,
+		teamID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during team member listing")
+	}
+	defer rows.Close()
+
+	members := []models.TeamMember{}
+	for rows.Next() {
+		var member models.TeamMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Email, &member.AddedAt); err != nil {
+			return nil, errors.New("database error during team member listing")
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during team member listing")
+	}
+
+	return members, nil
+}
+
+// AddTeamMemberInternal adds userID to a team; adding an existing member is
+// not an error.
+func AddTeamMemberInternal(teamID, userID string, db *sql.DB) error {
+	_, err := db.Exec(
+		"INSERT INTO team_members (team_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		teamID, userID,
+	)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "team_members_user_id_fkey" {
+			return ErrTeamUserNotFound
+		}
+		return ErrTeamNotFound
+	}
+	if err != nil {
+		return errors.New("database error during team member creation")
+	}
+	return nil
+}
+
+func RemoveTeamMemberInternal(teamID, userID string, db *sql.DB) (bool, error) {
+	result, err := db.Exec("DELETE FROM team_members WHERE team_id = $1 AND user_id = $2", teamID, userID)
+	if err != nil {
+		return false, errors.New("database error during team member deletion")
+	}
+
+	count, _ := result.RowsAffected()
+	return count > 0, nil
+}
+
+// ListSSHCertificateAuthoritiesInternal returns the CAs servers should
+// trust: the active one, and retired ones with certificates that have not
+// expired yet.
+func ListSSHCertificateAuthoritiesInternal(db *sql.DB) ([]models.SSHCertificateAuthority, error) {
+	rows, err := db.Query(`
+		SELECT ` + sshCAColumns + `
+		FROM ssh_certificate_authorities ca
+		WHERE active OR EXISTS (
+			SELECT 1 FROM ssh_certificates c WHERE c.ca_id = ca.id AND c.valid_before > now()
+		)
+		ORDER BY active DESC, created_at DESC`,
+	)
+	if err != nil {
+		return nil, errors.New("database error during certificate authority listing")
+	}
+	defer rows.Close()
+
+	authorities := []models.SSHCertificateAuthority{}
+	for rows.Next() {
+		var ca models.SSHCertificateAuthority
+		if err := scanSSHCertificateAuthority(rows, &ca); err != nil {
+			return nil, errors.New("database error during certificate authority listing")
+		}
+		authorities = append(authorities, ca)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during certificate authority listing")
+	}
+
+	return authorities, nil
+}
+
+// CreateSSHCertificateAuthorityInternal generates or imports a CA key and
+// makes it the active CA. The previous CA is retired but stays trusted
+// until its certificates expire.
+func CreateSSHCertificateAuthorityInternal(req models.SSHCertificateAuthorityRequest, db *sql.DB) (*models.SSHCertificateAuthority, error) {
+	if vaultKeyring == nil {
+		return nil, ErrVaultUnavailable
+	}
+
+	var privateKey crypto.Signer
+	var err error
+	if req.PrivateKey != "" {
+		privateKey, _, err = sshkeys.ParsePrivateKey([]byte(req.PrivateKey), []byte(req.Passphrase))
+	} else {
+		privateKey, err = sshkeys.Generate(req.Algorithm, req.Bits)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := sshkeys.PublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := sshkeys.Describe(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := sshkeys.MarshalOpenSSH(privateKey, req.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(encoded)
+
+	caID := uuid.New().String()
+	keyOwner, associatedData := sshCAAssociatedData(caID)
+
+	dataKey, wrapped, err := vaultKeyring.NewDataKey(keyOwner)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+
+	nonce, ciphertext, err := vault.Seal(dataKey, encoded, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during certificate authority creation")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE ssh_certificate_authorities SET active = false, retired_at = now() WHERE active")
+	if err != nil {
+		return nil, errors.New("database error during certificate authority rotation")
+	}
+
+	var ca models.SSHCertificateAuthority
+	err = scanSSHCertificateAuthority(tx.QueryRow(`
+		INSERT INTO ssh_certificate_authorities (id, name, key_type, public_key, fingerprint_sha256,
+			master_key_id, data_key_nonce, data_key, nonce, ciphertext)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+sshCAColumns,
+		caID, req.Name, info.Type, info.AuthorizedKey, info.FingerprintSHA256,
+		wrapped.MasterKeyID, wrapped.Nonce, wrapped.Ciphertext, nonce, ciphertext,
+	), &ca)
+	if err != nil {
+		return nil, errors.New("database error during certificate authority creation")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during certificate authority creation")
+	}
+
+	return &ca, nil
+}
+
+// activeSSHCA decrypts the active CA's private key.
+func activeSSHCA(db *sql.DB) (string, crypto.Signer, error) {
+	if vaultKeyring == nil {
+		return "", nil, ErrVaultUnavailable
+	}
+
+	var caID string
+	var wrapped vault.WrappedKey
+	var nonce, ciphertext []byte
+	err := db.QueryRow(
+		"SELECT id, master_key_id, data_key_nonce, data_key, nonce, ciphertext FROM ssh_certificate_authorities WHERE active",
+	).Scan(&caID, &wrapped.MasterKeyID, &wrapped.Nonce, &wrapped.Ciphertext, &nonce, &ciphertext)
+	if err == sql.ErrNoRows {
+		return "", nil, ErrSSHCANotConfigured
+	}
+	if err != nil {
+		return "", nil, errors.New("database error during certificate authority lookup")
+	}
+
+	keyOwner, associatedData := sshCAAssociatedData(caID)
+	dataKey, err := vaultKeyring.Unwrap(keyOwner, wrapped)
+	if err != nil {
+		return "", nil, err
+	}
+	defer clear(dataKey)
+
+	plaintext, err := vault.Open(dataKey, nonce, ciphertext, associatedData)
+	if err != nil {
+		return "", nil, err
+	}
+	defer clear(plaintext)
+
+	signer, _, err := sshkeys.ParsePrivateKey(plaintext, nil)
+	if err != nil {
+		return "", nil, errors.New("stored certificate authority key is unreadable")
+	}
+	return caID, signer, nil
+}
+
+// userPrincipals returns the union of the principals of userID's teams.
+func userPrincipals(userID string, db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT p
+		FROM team_members m
+		JOIN teams t ON t.id = m.team_id
+		CROSS JOIN LATERAL jsonb_array_elements_text(t.principals) AS p
+		WHERE m.user_id = $1
+		ORDER BY p`,
+		userID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during principal lookup")
+	}
+	defer rows.Close()
+
+	var principals []string
+	for rows.Next() {
+		var principal string
+		if err := rows.Scan(&principal); err != nil {
+			return nil, errors.New("database error during principal lookup")
+		}
+		principals = append(principals, principal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during principal lookup")
+	}
+
+	return principals, nil
+}
+
+// IssueSSHCertificateInternal signs a certificate for one of the user's
+// registered keys, or for issue.PublicKey, valid for every principal of the
+// user's teams. Every certificate is recorded before it is returned.
+func IssueSSHCertificateInternal(userID, username string, issue models.SSHCertificateIssue, db *sql.DB) (*models.SSHCertificate, error) {
+	publicKeyText := issue.PublicKey
+	if issue.SSHKeyID != "" {
+		err := db.QueryRow(
+			"SELECT public_key FROM ssh_keys WHERE id = $1 AND user_id = $2",
+			issue.SSHKeyID, userID,
+		).Scan(&publicKeyText)
+		if err == sql.ErrNoRows {
+			return nil, ErrSSHCertKeyNotFound
+		}
+		if err != nil {
+			return nil, errors.New("database error during ssh key lookup")
+		}
+	}
+
+	publicKey, _, err := sshkeys.ParsePublicKey(publicKeyText)
+	if err != nil {
+		return nil, err
+	}
+	info, err := sshkeys.Describe(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	principals, err := userPrincipals(userID, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(principals) == 0 {
+		return nil, ErrSSHCANoPrincipals
+	}
+
+	caID, signer, err := activeSSHCA(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var serial int64
+	if err := db.QueryRow("SELECT nextval('ssh_certificates_serial_seq')").Scan(&serial); err != nil {
+		return nil, errors.New("database error during certificate serial allocation")
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	template := sshkeys.UserCertificate{
+		Serial:      uint64(serial),
+		KeyID:       "livecode:" + username + ":" + strconv.FormatInt(serial, 10),
+		Principals:  principals,
+		ValidAfter:  now.Add(-sshCertificateClockSkew),
+		ValidBefore: now.Add(issue.ValidFor),
+	}
+
+	cert, err := sshkeys.SignUserCertificate(signer, publicKey, template)
+	if err != nil {
+		return nil, err
+	}
+
+	principalsJSON, err := json.Marshal(principals)
+	if err != nil {
+		return nil, errors.New("failed to encode certificate principals")
+	}
+
+	var record models.SSHCertificate
+	err = scanSSHCertificate(db.QueryRow(`
+		INSERT INTO ssh_certificates (serial, ca_id, user_id, ssh_key_id, key_fingerprint, key_id, principals,
+			valid_after, valid_before, client_ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+sshCertificateColumns,
+		serial, caID, userID, nullableString(issue.SSHKeyID), info.FingerprintSHA256, template.KeyID,
+		string(principalsJSON), template.ValidAfter, template.ValidBefore,
+		nullableString(issue.ClientIP), nullableString(issue.UserAgent),
+	), &record)
+	if err != nil {
+		return nil, errors.New("database error during certificate recording")
+	}
+
+	record.Certificate = string(ssh.MarshalAuthorizedKey(cert))
+	return &record, nil
+}
+
+func ListSSHCertificatesInternal(filter models.SSHCertificateFilter, db *sql.DB) ([]models.SSHCertificate, int, error) {
+	rows, err := db.Query(`
+		SELECT `+sshCertificateColumns+`, COUNT(*) OVER()
+		FROM ssh_certificates
+		WHERE $1 = '' OR user_id::text = $1
+		ORDER BY serial DESC
+		LIMIT $2 OFFSET $3`,
+		filter.UserID, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, 0, errors.New("database error during certificate listing")
+	}
+	defer rows.Close()
+
+	certificates := []models.SSHCertificate{}
+	total := 0
+
+	for rows.Next() {
+		var cert models.SSHCertificate
+		if err := scanSSHCertificate(scanWithTotal{rows, &total}, &cert); err != nil {
+			return nil, 0, errors.New("database error during certificate listing")
+		}
+		certificates = append(certificates, cert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.New("database error during certificate listing")
+	}
+
+	return certificates, total, nil
+}
+
+// scanWithTotal scans a row whose last column is a COUNT(*) OVER() total.
+type scanWithTotal struct {
+	rows  *sql.Rows
+	total *int
+}
+
+func (s scanWithTotal) Scan(dest ...any) error {
+	return s.rows.Scan(append(dest, s.total)...)
+}
+
+// RevokeSSHCertificateInternal revokes a certificate, limited to ownerID's
+// certificates when it is set. Revoking twice keeps the first revocation.
+// It returns nil when the certificate does not exist.
+func RevokeSSHCertificateInternal(serial int64, ownerID, revokedBy, reason string, db *sql.DB) (*models.SSHCertificate, error) {
+	result, err := db.Exec(`
+		UPDATE ssh_certificates
+		SET revoked_at = now(), revoked_by = $3, revocation_reason = $4
+		WHERE serial = $1 AND ($2 = '' OR user_id::text = $2) AND revoked_at IS NULL`,
+		serial, ownerID, revokedBy, nullableString(reason),
+	)
+	if err != nil {
+		return nil, errors.New("database error during certificate revocation")
+	}
+
+	var cert models.SSHCertificate
+	err = scanSSHCertificate(db.QueryRow(
+		"SELECT "+sshCertificateColumns+" FROM ssh_certificates WHERE serial = $1 AND ($2 = '' OR user_id::text = $2)",
+		serial, ownerID,
+	), &cert)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error during certificate lookup")
+	}
+
+	if revoked, _ := result.RowsAffected(); revoked > 0 && cert.UserID != nil && *cert.UserID != revokedBy {
+		eventHub.Publish(*cert.UserID, events.TypeNotification, models.NotificationEvent{
+			Kind:   "ssh_certificate_revoked",
+			Params: map[string]any{"serial": cert.Serial, "key_id": cert.KeyID},
+		})
+	}
+
+	return &cert, nil
+}
+
+// SSHKeyRevocationListInternal builds an OpenSSH KRL of the revoked
+// certificates that have not expired yet.
+func SSHKeyRevocationListInternal(db *sql.DB) ([]byte, error) {
+	rows, err := db.Query(`
+		SELECT ca.public_key, c.serial, EXTRACT(EPOCH FROM max(c.revoked_at) OVER ())::bigint
+		FROM ssh_certificates c
+		JOIN ssh_certificate_authorities ca ON ca.id = c.ca_id
+		WHERE c.revoked_at IS NOT NULL AND c.valid_before > now()
+		ORDER BY ca.public_key, c.serial`,
+	)
+	if err != nil {
+		return nil, errors.New("database error during revocation listing")
+	}
+	defer rows.Close()
+
+	krl := sshkeys.KRL{GeneratedAt: time.Now(), Comment: "LiveCode"}
+	var lastCA string
+
+	for rows.Next() {
+		var caKey string
+		var serial, version int64
+		if err := rows.Scan(&caKey, &serial, &version); err != nil {
+			return nil, errors.New("database error during revocation listing")
+		}
+		krl.Version = uint64(version)
+
+		if caKey != lastCA {
+			publicKey, _, err := sshkeys.ParsePublicKey(caKey)
+			if err != nil {
+				return nil, errors.New("stored certificate authority key is unreadable")
+			}
+			krl.Certificates = append(krl.Certificates, sshkeys.RevokedCertificates{CA: publicKey})
+			lastCA = caKey
+		}
+
+		revoked := &krl.Certificates[len(krl.Certificates)-1]
+		revoked.Serials = append(revoked.Serials, uint64(serial))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during revocation listing")
+	}
+
+	return krl.Marshal(), nil
+}
+
+// rewrapSSHCAKeys moves the CA data keys under the keyring's current master
+// key, like RewrapVaultKeysInternal does for users' data keys.
+func rewrapSSHCAKeys(keyring *vault.Keyring, db *sql.DB) (int, error) {
+	rows, err := db.Query(
+		"SELECT id, master_key_id, data_key_nonce, data_key FROM ssh_certificate_authorities WHERE master_key_id <> $1",
+		keyring.CurrentID(),
+	)
+	if err != nil {
+		return 0, errors.New("database error during certificate authority listing")
+	}
+
+	type wrappedCAKey struct {
+		caID    string
+		wrapped vault.WrappedKey
+	}
+	var pending []wrappedCAKey
+
+	for rows.Next() {
+		var key wrappedCAKey
+		if err := rows.Scan(&key.caID, &key.wrapped.MasterKeyID, &key.wrapped.Nonce, &key.wrapped.Ciphertext); err != nil {
+			rows.Close()
+			return 0, errors.New("database error during certificate authority listing")
+		}
+		pending = append(pending, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, errors.New("database error during certificate authority listing")
+	}
+
+	rewrapped := 0
+	for _, key := range pending {
+		keyOwner, _ := sshCAAssociatedData(key.caID)
+		next, changed, err := keyring.Rewrap(keyOwner, key.wrapped)
+		if err != nil {
+			return rewrapped, errors.New("data key for certificate authority " + key.caID + ": " + err.Error())
+		}
+		if !changed {
+			continue
+		}
+
+		result, err := db.Exec(
+			`UPDATE ssh_certificate_authorities SET master_key_id = $3, data_key_nonce = $4, data_key = $5
+			 WHERE id = $1 AND master_key_id = $2`,
+			key.caID, key.wrapped.MasterKeyID, next.MasterKeyID, next.Nonce, next.Ciphertext,
+		)
+		if err != nil {
+			return rewrapped, errors.New("database error during data key rewrap")
+		}
+		if count, _ := result.RowsAffected(); count == 1 {
+			rewrapped++
+		}
+	}
+
+	return rewrapped, nil
+}
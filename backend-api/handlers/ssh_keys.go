@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"crypto"
+	"database/sql"
+	"errors"
+
+	"livecode-api/internal/sshkeys"
+	"livecode-api/internal/vault"
+	"livecode-api/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrSSHKeyExists            = errors.New("ssh key already registered")
+	ErrSSHKeyNoPrivateKey      = errors.New("no private key stored for ssh key")
+	ErrSSHKeyClientEncrypted   = errors.New("private key is client-encrypted")
+	ErrSSHKeyVaultItemNotFound = errors.New("vault item not found")
+)
+
+const sshKeyColumns = `id, name, key_type, bits, public_key, comment, fingerprint_sha256, fingerprint_md5, source, vault_item_id, created_at, updated_at`
+
+func scanSSHKey(row interface{ Scan(...any) error }, key *models.SSHKey) error {
+	var vaultItemID sql.NullString
+
+	err := row.Scan(
+		&key.ID, &key.Name, &key.Type, &key.Bits, &key.PublicKey, &key.Comment,
+		&key.FingerprintSHA256, &key.FingerprintMD5, &key.Source, &vaultItemID,
+		&key.CreatedAt, &key.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if key.Comment != "" {
+		key.PublicKey += " " + key.Comment
+	}
+	if vaultItemID.Valid {
+		key.VaultItemID = &vaultItemID.String
+	}
+	return nil
+}
+
+func isSSHKeyDuplicate(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "ssh_keys_user_id_fingerprint_key"
+}
+
+func insertSSHKey(q queryRower, userID, name, comment, source string, info sshkeys.Info, vaultItemID string) (*models.SSHKey, error) {
+	var key models.SSHKey
+	err := scanSSHKey(q.QueryRow(`
+		INSERT INTO ssh_keys (id, user_id, name, key_type, bits, public_key, comment,
+			fingerprint_sha256, fingerprint_md5, source, vault_item_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+sshKeyColumns,
+		uuid.New().String(), userID, name, info.Type, info.Bits, info.AuthorizedKey, comment,
+		info.FingerprintSHA256, info.FingerprintMD5, source, nullableString(vaultItemID),
+	), &key)
+
+	if isSSHKeyDuplicate(err) {
+		return nil, ErrSSHKeyExists
+	}
+	if err != nil {
+		return nil, errors.New("database error during ssh key creation")
+	}
+	return &key, nil
+}
+
+func ListSSHKeysInternal(userID string, db *sql.DB) ([]models.SSHKey, error) {
+	rows, err := db.Query(
+		"SELECT "+sshKeyColumns+" FROM ssh_keys WHERE user_id = $1 ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during ssh key listing")
+	}
+	defer rows.Close()
+
+	keys := []models.SSHKey{}
+	for rows.Next() {
+		var key models.SSHKey
+		if err := scanSSHKey(rows, &key); err != nil {
+			return nil, errors.New("database error during ssh key listing")
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during ssh key listing")
+	}
+
+	return keys, nil
+}
+
+func GetSSHKeyInternal(userID, keyID string, db *sql.DB) (*models.SSHKey, error) {
+	var key models.SSHKey
+
+	err := scanSSHKey(db.QueryRow(
+		"SELECT "+sshKeyColumns+" FROM ssh_keys WHERE id = $1 AND user_id = $2",
+		keyID, userID,
+	), &key)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.New("database error during ssh key lookup")
+	}
+
+	return &key, nil
+}
+
+// CreateSSHKeyInternal registers a public key. input.PublicKey has been
+// validated already. A linked vault item must be a client-encrypted private
+// key, since the server cannot check that it matches.
+func CreateSSHKeyInternal(userID string, input models.SSHKeyInput, db *sql.DB) (*models.SSHKey, error) {
+	publicKey, _, err := sshkeys.ParsePublicKey(input.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := sshkeys.Describe(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.VaultItemID != "" {
+		var linkable bool
+		err := db.QueryRow(
+			`SELECT EXISTS (SELECT 1 FROM vault_items
+			 WHERE id = $1 AND user_id = $2 AND kind = $3 AND encryption = $4)`,
+			input.VaultItemID, userID, models.VaultItemPrivateKey, vault.EncryptionClient,
+		).Scan(&linkable)
+		if err != nil {
+			return nil, errors.New("database error during vault item lookup")
+		}
+		if !linkable {
+			return nil, ErrSSHKeyVaultItemNotFound
+		}
+	}
+
+	return insertSSHKey(db, userID, input.Name, input.Comment, models.SSHKeySourceRegistered, info, input.VaultItemID)
+}
+
+// GenerateSSHKeyInternal creates a key pair and keeps the private key in the
+// user's vault, encrypted by the server.
+func GenerateSSHKeyInternal(userID string, req models.SSHKeyGenerateRequest, db *sql.DB) (*models.SSHKey, error) {
+	if vaultKeyring == nil {
+		return nil, ErrVaultUnavailable
+	}
+
+	privateKey, err := sshkeys.Generate(req.Algorithm, req.Bits)
+	if err != nil {
+		return nil, err
+	}
+
+	return storeSSHKeyPair(userID, req.Name, req.Comment, models.SSHKeySourceGenerated, privateKey, db)
+}
+
+// ImportSSHKeyInternal stores an existing private key in the vault. The key
+// is decrypted with passphrase first and stored without it.
+func ImportSSHKeyInternal(userID string, req models.SSHKeyImportRequest, db *sql.DB) (*models.SSHKey, error) {
+	if vaultKeyring == nil {
+		return nil, ErrVaultUnavailable
+	}
+
+	privateKey, fileComment, err := sshkeys.ParsePrivateKey([]byte(req.PrivateKey), []byte(req.Passphrase))
+	if err != nil {
+		return nil, err
+	}
+	comment := req.Comment
+	if comment == "" {
+		comment = fileComment
+	}
+	if comment == "" {
+		comment = req.Name
+	}
+
+	return storeSSHKeyPair(userID, req.Name, comment, models.SSHKeySourceImported, privateKey, db)
+}
+
+func storeSSHKeyPair(userID, name, comment, source string, privateKey crypto.Signer, db *sql.DB) (*models.SSHKey, error) {
+	publicKey, err := sshkeys.PublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := sshkeys.Describe(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := sshkeys.MarshalOpenSSH(privateKey, comment, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(encoded)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during ssh key creation")
+	}
+	defer tx.Rollback()
+
+	input := models.VaultItemInput{
+		Kind:       models.VaultItemPrivateKey,
+		Label:      name,
+		Encryption: vault.EncryptionServer,
+		Secret:     string(encoded),
+	}
+	if err := prepareVaultWrite(tx, userID, input); err != nil {
+		return nil, err
+	}
+
+	itemID := uuid.New().String()
+	nonce, ciphertext, err := sealVaultItem(tx, userID, itemID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO vault_items (id, user_id, kind, label, encryption, nonce, ciphertext)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		itemID, userID, input.Kind, input.Label, input.Encryption, nonce, ciphertext,
+	)
+	if err != nil {
+		return nil, errors.New("database error during vault item creation")
+	}
+
+	key, err := insertSSHKey(tx, userID, name, comment, source, info, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during ssh key creation")
+	}
+
+	return key, nil
+}
+
+// ExportSSHKeyInternal returns the stored private key in format, encrypted
+// with passphrase unless it is empty.
+func ExportSSHKeyInternal(userID, keyID string, req models.SSHKeyExportRequest, db *sql.DB) (*models.SSHKeyExport, error) {
+	key, err := GetSSHKeyInternal(userID, keyID, db)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	if key.VaultItemID == nil {
+		return nil, ErrSSHKeyNoPrivateKey
+	}
+
+	item, err := GetVaultItemInternal(userID, *key.VaultItemID, db)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrSSHKeyNoPrivateKey
+	}
+	if item.Encryption == vault.EncryptionClient {
+		return nil, ErrSSHKeyClientEncrypted
+	}
+
+	privateKey, _, err := sshkeys.ParsePrivateKey([]byte(item.Secret), nil)
+	if err != nil {
+		return nil, errors.New("stored private key for ssh key " + keyID + ": " + err.Error())
+	}
+
+	var encoded []byte
+	switch req.Format {
+	case sshkeys.FormatPPK:
+		encoded, err = sshkeys.MarshalPPK(privateKey, key.Comment, []byte(req.Passphrase))
+	default:
+		encoded, err = sshkeys.MarshalOpenSSH(privateKey, key.Comment, []byte(req.Passphrase))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SSHKeyExport{
+		Format:     req.Format,
+		PrivateKey: string(encoded),
+		PublicKey:  key.PublicKey,
+	}, nil
+}
+
+// DeleteSSHKeyInternal removes a key. The private key of a generated or
+// imported key goes with it; a linked client-encrypted item is kept.
+func DeleteSSHKeyInternal(userID, keyID string, db *sql.DB) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, errors.New("database error during ssh key deletion")
+	}
+	defer tx.Rollback()
+
+	var source string
+	var vaultItemID sql.NullString
+	err = tx.QueryRow(
+		"DELETE FROM ssh_keys WHERE id = $1 AND user_id = $2 RETURNING source, vault_item_id",
+		keyID, userID,
+	).Scan(&source, &vaultItemID)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New("database error during ssh key deletion")
+	}
+
+	if vaultItemID.Valid && source != models.SSHKeySourceRegistered {
+		_, err := tx.Exec("DELETE FROM vault_items WHERE id = $1 AND user_id = $2", vaultItemID.String, userID)
+		if err != nil {
+			return false, errors.New("database error during vault item deletion")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.New("database error during ssh key deletion")
+	}
+
+	return true, nil
+}
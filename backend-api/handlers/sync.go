@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"livecode-api/internal/checksum"
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/syncplan"
+	"livecode-api/models"
+)
+
+var (
+	ErrSyncPlanExecuted   = errors.New("sync plan was already executed")
+	ErrSyncPlanExpired    = errors.New("sync plan has expired")
+	ErrSyncTooManyEntries = errors.New("remote directory has too many entries")
+	ErrSyncChecksumLimit  = errors.New("too much data to checksum")
+)
+
+const maxSyncIgnoreFileSize = 64 << 10
+
+type SyncPolicy struct {
+	// MaxEntries caps the remote files and directories a plan walks.
+	MaxEntries int
+	// MaxActions caps the actions in a plan, and the unfinished transfers
+	// started by sync plans.
+	MaxActions int
+	// MaxChecksumBytes caps the remote data read to compare checksums.
+	MaxChecksumBytes int64
+	PlanTTL          time.Duration
+}
+
+var syncPolicy = SyncPolicy{
+	MaxEntries:       100000,
+	MaxActions:       5000,
+	MaxChecksumBytes: 1 << 30,
+	PlanTTL:          time.Hour,
+}
+
+func SetSyncPolicy(policy SyncPolicy) {
+	syncPolicy = policy
+}
+
+const syncPlanColumns = `id, connection_id, remote_path, direction, criteria, mirror, delete_extraneous, actions,
+	created_at, expires_at, executed_at`
+
+func scanSyncPlan(row interface{ Scan(...any) error }, plan *models.SyncPlan) error {
+	var actions []byte
+	var executedAt sql.NullTime
+
+	err := row.Scan(
+		&plan.ID, &plan.ConnectionID, &plan.RemotePath, &plan.Direction, &plan.Criteria, &plan.Mirror,
+		&plan.Delete, &actions, &plan.CreatedAt, &plan.ExpiresAt, &executedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(actions, &plan.Actions); err != nil {
+		return err
+	}
+	plan.Summary = summarizeSync(plan.Actions)
+	if executedAt.Valid {
+		plan.ExecutedAt = &executedAt.Time
+	}
+	return nil
+}
+
+func summarizeSync(actions []models.SyncAction) models.SyncSummary {
+	var summary models.SyncSummary
+	for _, action := range actions {
+		switch action.Action {
+		case syncplan.ActionUpload:
+			summary.Uploads++
+			summary.UploadBytes += action.Local.Size
+		case syncplan.ActionDownload:
+			summary.Downloads++
+			summary.DownloadBytes += action.Remote.Size
+		case syncplan.ActionDeleteRemote:
+			summary.RemoteDeletes++
+		case syncplan.ActionDeleteLocal:
+			summary.LocalDeletes++
+		case syncplan.ActionMkdirRemote:
+			summary.RemoteDirectories++
+		case syncplan.ActionMkdirLocal:
+			summary.LocalDirectories++
+		case syncplan.ActionConflict:
+			summary.Conflicts++
+		}
+	}
+	return summary
+}
+
+// PlanSyncInternal compares the client's files with the remote directory
+// and stores the resulting plan. A remote directory that does not exist yet
+// is treated as empty, unless the sync only downloads.
+func PlanSyncInternal(ctx context.Context, userID string, req models.SyncRequest, audit models.KnownHostKey, db *sql.DB) (*models.SyncPlan, error) {
+	session, connection, err := OpenRemoteSessionInternal(ctx, userID, req.ConnectionID, audit, db)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrTransferConnectionNotFound
+	}
+	defer session.Release()
+
+	root := req.RemotePath
+	if !path.IsAbs(root) {
+		root = path.Join(connection.RemoteDirectory, root)
+	}
+	if resolved, err := session.RealPath(root); err == nil {
+		root = resolved
+	}
+
+	filter := &syncplan.Filter{Rules: syncplan.NewRules()}
+	for _, glob := range req.Include {
+		if p, ok := syncplan.ParsePattern(glob); ok {
+			filter.Include = append(filter.Include, p)
+		}
+	}
+	for _, glob := range req.Exclude {
+		if p, ok := syncplan.ParsePattern(glob); ok {
+			filter.Exclude = append(filter.Exclude, p)
+		}
+	}
+	for name, content := range req.LocalIgnoreFiles {
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		filter.Rules.Add(dir, content)
+	}
+
+	remote, err := walkSyncRemote(ctx, session, root, filter, req.IgnoreFiles)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && req.Direction != syncplan.DirectionDownload:
+		remote = nil
+	case err != nil:
+		return nil, err
+	}
+
+	local := make([]syncplan.Entry, 0, len(req.Local))
+	for _, entry := range req.Local {
+		dir := entry.Type == models.RemoteFileTypeDirectory
+		if filter.Skip(entry.Path, dir) {
+			continue
+		}
+		local = append(local, syncplan.Entry{
+			Path: entry.Path, Dir: dir, Size: entry.Size, ModTime: entry.ModifiedAt, SHA256: entry.SHA256,
+		})
+	}
+
+	criteria := req.Criteria
+	if criteria == "" {
+		criteria = syncplan.CriteriaModTime
+	}
+
+	var hashed int64
+	hash := func(name string) (string, error) {
+		file, info, err := OpenRemoteFileInternal(ctx, session, path.Join(root, name))
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		hashed += info.Size()
+		if hashed > syncPolicy.MaxChecksumBytes {
+			return "", ErrSyncChecksumLimit
+		}
+		return checksum.Sum(checksum.SHA256, contextReader{ctx: ctx, r: file})
+	}
+
+	planned, err := syncplan.Plan(local, remote, syncplan.Options{
+		Direction:  req.Direction,
+		Criteria:   criteria,
+		Mirror:     req.Mirror,
+		Delete:     req.Delete,
+		MaxActions: syncPolicy.MaxActions,
+	}, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]models.SyncAction, 0, len(planned))
+	for _, action := range planned {
+		actions = append(actions, models.SyncAction{
+			Path:      action.Path,
+			Action:    action.Type,
+			Reason:    action.Reason,
+			Directory: action.Dir,
+			Local:     syncSide(action.Local),
+			Remote:    syncSide(action.Remote),
+		})
+	}
+	encoded, err := json.Marshal(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plans that were never executed are of no use once expired.
+	if _, err := db.Exec(
+		"DELETE FROM sync_plans WHERE user_id = $1 AND executed_at IS NULL AND expires_at < now()",
+		userID,
+	); err != nil {
+		return nil, errors.New("database error during sync planning")
+	}
+
+	var plan models.SyncPlan
+	err = scanSyncPlan(db.QueryRow(`
+		INSERT INTO sync_plans (user_id, connection_id, remote_path, direction, criteria, mirror, delete_extraneous, actions, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+syncPlanColumns,
+		userID, req.ConnectionID, root, req.Direction, criteria, req.Mirror, req.Delete, encoded,
+		time.Now().Add(syncPolicy.PlanTTL),
+	), &plan)
+	if err != nil {
+		return nil, errors.New("database error during sync planning")
+	}
+
+	return &plan, nil
+}
+
+func syncSide(entry *syncplan.Entry) *models.SyncSide {
+	if entry == nil {
+		return nil
+	}
+	side := &models.SyncSide{ModifiedAt: entry.ModTime.UTC(), SHA256: strings.ToLower(entry.SHA256)}
+	if !entry.Dir {
+		side.Size = entry.Size
+	}
+	return side
+}
+
+// walkSyncRemote lists the files and directories under root that filter
+// keeps. Ignore files named in ignoreFiles add their patterns to the
+// filter's rules for the directory they are in. Symbolic links and special
+// files are left out.
+func walkSyncRemote(ctx context.Context, session remotefs.FS, root string, filter *syncplan.Filter, ignoreFiles []string) ([]syncplan.Entry, error) {
+	info, err := session.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, ErrRemoteNotDirectory
+	}
+
+	entries := []syncplan.Entry{}
+	pending := []string{""}
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		dir := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		infos, err := session.ReadDir(path.Join(root, dir))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, info := range infos {
+			if info.Mode().IsRegular() && info.Size() <= maxSyncIgnoreFileSize && isSyncIgnoreFile(info.Name(), ignoreFiles) {
+				content, err := readSyncIgnoreFile(ctx, session, path.Join(root, dir, info.Name()))
+				if err != nil {
+					return nil, err
+				}
+				filter.Rules.Add(dir, content)
+			}
+		}
+
+		for _, info := range infos {
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				continue
+			}
+			name := path.Join(dir, info.Name())
+			if filter.Skip(name, info.IsDir()) {
+				continue
+			}
+
+			entry := syncplan.Entry{Path: name, Dir: info.IsDir(), ModTime: info.ModTime()}
+			if info.IsDir() {
+				pending = append(pending, name)
+			} else {
+				entry.Size = info.Size()
+			}
+			entries = append(entries, entry)
+			if len(entries) > syncPolicy.MaxEntries {
+				return nil, ErrSyncTooManyEntries
+			}
+		}
+	}
+	return entries, nil
+}
+
+func isSyncIgnoreFile(name string, ignoreFiles []string) bool {
+	for _, ignoreFile := range ignoreFiles {
+		if name == ignoreFile {
+			return true
+		}
+	}
+	return false
+}
+
+func readSyncIgnoreFile(ctx context.Context, session remotefs.FS, name string) (string, error) {
+	file, err := session.Open(ctx, name, 0)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxSyncIgnoreFileSize))
+	return string(content), err
+}
+
+// GetSyncPlanInternal returns one of the user's sync plans, or nil if it
+// does not exist.
+func GetSyncPlanInternal(userID, planID string, db *sql.DB) (*models.SyncPlan, error) {
+	var plan models.SyncPlan
+
+	err := scanSyncPlan(db.QueryRow(
+		"SELECT "+syncPlanColumns+" FROM sync_plans WHERE id = $1 AND user_id = $2",
+		planID, userID,
+	), &plan)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error during sync plan lookup")
+	}
+
+	return &plan, nil
+}
+
+// ExecuteSyncPlanInternal starts a plan's remote side: missing remote
+// directories are created straight away, and uploads, downloads and remote
+// deletes become transfers. Uploads wait in staging for the client's data.
+// Conflicts and the local side are left to the client.
+func ExecuteSyncPlanInternal(ctx context.Context, userID, planID string, audit models.KnownHostKey, db *sql.DB) (*models.SyncPlan, []models.Transfer, error) {
+	plan, err := GetSyncPlanInternal(userID, planID, db)
+	if err != nil || plan == nil {
+		return nil, nil, err
+	}
+	if plan.ExecutedAt != nil {
+		return nil, nil, ErrSyncPlanExecuted
+	}
+	if time.Now().After(plan.ExpiresAt) {
+		return nil, nil, ErrSyncPlanExpired
+	}
+
+	jobs := plan.Summary.Uploads + plan.Summary.Downloads + plan.Summary.RemoteDeletes
+	var unfinished int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM transfers WHERE user_id = $1 AND sync_plan_id IS NOT NULL AND status = ANY($2)",
+		userID, unfinishedTransferStatuses,
+	).Scan(&unfinished)
+	if err != nil {
+		return nil, nil, errors.New("database error during sync execution")
+	}
+	if unfinished+jobs > syncPolicy.MaxActions {
+		return nil, nil, ErrTransferLimit
+	}
+
+	if plan.Summary.RemoteDirectories > 0 {
+		if err := makeSyncDirectories(ctx, userID, plan, audit, db); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, errors.New("database error during sync execution")
+	}
+	defer tx.Rollback()
+
+	if err := reserveTransferStaging(tx, userID, plan.Summary.UploadBytes); err != nil {
+		return nil, nil, err
+	}
+
+	var executedAt time.Time
+	err = tx.QueryRow(
+		"UPDATE sync_plans SET executed_at = now() WHERE id = $1 AND executed_at IS NULL AND expires_at > now() RETURNING executed_at",
+		planID,
+	).Scan(&executedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrSyncPlanExecuted
+	}
+	if err != nil {
+		return nil, nil, errors.New("database error during sync execution")
+	}
+	plan.ExecutedAt = &executedAt
+
+	list := []models.Transfer{}
+	staged := []string{}
+	defer func() {
+		for _, id := range staged {
+			os.Remove(transferStagingPath(id))
+		}
+	}()
+
+	for _, action := range plan.Actions {
+		var direction, status, expected string
+		var size *int64
+		var modifiedAt *time.Time
+
+		switch action.Action {
+		case syncplan.ActionUpload:
+			direction, status = models.TransferUpload, models.TransferQueued
+			size, modifiedAt = &action.Local.Size, &action.Local.ModifiedAt
+			expected = action.Local.SHA256
+			if action.Local.Size > 0 {
+				status = models.TransferStaging
+			}
+		case syncplan.ActionDownload:
+			direction, status = models.TransferDownload, models.TransferQueued
+			size, modifiedAt = &action.Remote.Size, &action.Remote.ModifiedAt
+		case syncplan.ActionDeleteRemote:
+			direction, status = models.TransferDelete, models.TransferQueued
+		default:
+			continue
+		}
+
+		var transfer models.Transfer
+		err := scanTransfer(tx.QueryRow(`
+			WITH created AS (
+				INSERT INTO transfers (user_id, connection_id, direction, remote_path, overwrite, modified_at, sync_plan_id, status, size,
+					expected_checksum)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''))
+				RETURNING *
+			), logged AS (
+				INSERT INTO transfer_events (transfer_id, status) SELECT id, status FROM created
+			)
+			SELECT `+transferColumns+` FROM created`,
+			userID, plan.ConnectionID, direction, path.Join(plan.RemotePath, action.Path), action.Remote != nil,
+			modifiedAt, planID, status, size, expected,
+		), &transfer)
+		if err != nil {
+			return nil, nil, errors.New("database error during sync execution")
+		}
+
+		if direction == models.TransferUpload {
+			if err := os.WriteFile(transferStagingPath(transfer.ID), nil, 0o600); err != nil {
+				return nil, nil, err
+			}
+			staged = append(staged, transfer.ID)
+		}
+		list = append(list, transfer)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, errors.New("database error during sync execution")
+	}
+	staged = nil
+
+	for _, transfer := range list {
+		publishTransferStatus(userID, transfer.ID, transfer.Status, "")
+	}
+	transferEngine.Notify()
+	return plan, list, nil
+}
+
+func makeSyncDirectories(ctx context.Context, userID string, plan *models.SyncPlan, audit models.KnownHostKey, db *sql.DB) error {
+	session, _, err := OpenRemoteSessionInternal(ctx, userID, plan.ConnectionID, audit, db)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return ErrTransferConnectionNotFound
+	}
+	defer session.Release()
+
+	for _, action := range plan.Actions {
+		if action.Action != syncplan.ActionMkdirRemote {
+			continue
+		}
+		if _, err := MakeRemoteDirectoryInternal(session, path.Join(plan.RemotePath, action.Path), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"livecode-api/internal/syncplan"
+)
+
+func TestWalkSyncRemote_FiltersAndIgnoreFiles(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+
+	for name, content := range map[string]string{
+		".gitignore":            "*.log\nbuild/\n",
+		"main.go":               "package main",
+		"debug.log":             "x",
+		"build/out.bin":         "x",
+		"src/.gitignore":        "!keep.log\n",
+		"src/keep.log":          "x",
+		"src/lib.go":            "package src",
+		"node_modules/a/b.js":   "x",
+		"docs/guide/intro.md":   "# Intro",
+		"docs/guide/.gitignore": "*.md\n",
+	} {
+		target := filepath.Join(server.Root, name)
+		os.MkdirAll(filepath.Dir(target), 0o755)
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Symlink("main.go", filepath.Join(server.Root, "link.go"))
+
+	exclude, _ := syncplan.ParsePattern("node_modules/")
+	filter := &syncplan.Filter{Exclude: []syncplan.Pattern{exclude}, Rules: syncplan.NewRules()}
+
+	entries, err := walkSyncRemote(context.Background(), session, ".", filter, []string{".gitignore"})
+	if err != nil {
+		t.Fatalf("Expected the walk to succeed, got: %v", err)
+	}
+
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Path)
+	}
+	slices.Sort(got)
+	want := []string{
+		".gitignore", "docs", "docs/guide", "docs/guide/.gitignore", "main.go",
+		"src", "src/.gitignore", "src/keep.log", "src/lib.go",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWalkSyncRemote_Limits(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+	for _, name := range []string{"a", "b", "c"} {
+		os.WriteFile(filepath.Join(server.Root, name), nil, 0o644)
+	}
+
+	defer SetSyncPolicy(syncPolicy)
+	SetSyncPolicy(SyncPolicy{MaxEntries: 2})
+
+	filter := &syncplan.Filter{Rules: syncplan.NewRules()}
+	if _, err := walkSyncRemote(context.Background(), session, ".", filter, nil); !errors.Is(err, ErrSyncTooManyEntries) {
+		t.Errorf("Expected ErrSyncTooManyEntries, got: %v", err)
+	}
+	if _, err := walkSyncRemote(context.Background(), session, "a", filter, nil); !errors.Is(err, ErrRemoteNotDirectory) {
+		t.Errorf("Expected ErrRemoteNotDirectory, got: %v", err)
+	}
+	if _, err := walkSyncRemote(context.Background(), session, "missing", filter, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing root to be reported, got: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"livecode-api/internal/telemetry"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"go.uber.org/zap"
+)
+
+const telemetryEventColumns = 11
+
+type TelemetryStore struct {
+	DB *sql.DB
+}
+
+func (s TelemetryStore) LoadConsent(ctx context.Context, installID string) (telemetry.Consent, bool, error) {
+	consent := telemetry.Consent{InstallID: installID}
+	err := s.DB.QueryRowContext(ctx,
+		"SELECT usage_opt_in, errors_opt_in, updated_at, secret_hash FROM telemetry_installs WHERE install_id = $1",
+		installID,
+	).Scan(&consent.Usage, &consent.Errors, &consent.UpdatedAt, &consent.SecretHash)
+
+	if err == sql.ErrNoRows {
+		return consent, false, nil
+	}
+	if err != nil {
+		return consent, false, errors.New("database error during consent lookup")
+	}
+
+	return consent, true, nil
+}
+
+func (s TelemetryStore) LoadRecentConsents(ctx context.Context, limit int) ([]telemetry.Consent, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT install_id, usage_opt_in, errors_opt_in, updated_at, secret_hash
+		FROM telemetry_installs ORDER BY updated_at DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, errors.New("database error during consent loading")
+	}
+	defer rows.Close()
+
+	var consents []telemetry.Consent
+	for rows.Next() {
+		var consent telemetry.Consent
+		if err := rows.Scan(&consent.InstallID, &consent.Usage, &consent.Errors, &consent.UpdatedAt, &consent.SecretHash); err != nil {
+			return nil, errors.New("database error during consent loading")
+		}
+		consents = append(consents, consent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during consent loading")
+	}
+
+	return consents, nil
+}
+
+func (s TelemetryStore) SaveConsent(ctx context.Context, consent telemetry.Consent, userID string, previousHash []byte) (bool, error) {
+	var consentUserID any
+	if userID != "" {
+		consentUserID = userID
+	}
+
+	result, err := s.DB.ExecContext(ctx, `
+		INSERT INTO telemetry_installs (install_id, user_id, usage_opt_in, errors_opt_in, secret_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (install_id) DO UPDATE SET
+			user_id = COALESCE(EXCLUDED.user_id, telemetry_installs.user_id),
+			usage_opt_in = EXCLUDED.usage_opt_in,
+			errors_opt_in = EXCLUDED.errors_opt_in,
+			secret_hash = EXCLUDED.secret_hash
+		WHERE telemetry_installs.secret_hash IS NOT DISTINCT FROM $6`,
+		consent.InstallID, consentUserID, consent.Usage, consent.Errors, consent.SecretHash, previousHash,
+	)
+	if err != nil {
+		return false, errors.New("database error during consent update")
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// WriteEvents stores a batch in one transaction, so a batch that is sent
+// again after a failure is not stored twice.
+func (s TelemetryStore) WriteEvents(ctx context.Context, events []telemetry.Event) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.New("database error during telemetry insert")
+	}
+	defer tx.Rollback()
+
+	usageEvents := make([]telemetry.Event, 0, len(events))
+	issues := map[string]models.ClientIssue{}
+
+	for _, event := range events {
+		if event.Type != telemetry.EventTypeError {
+			usageEvents = append(usageEvents, event)
+			continue
+		}
+
+		payload, err := clientErrorFromEvent(event)
+		if err != nil {
+			middleware.Logger.Warn("telemetry_error_event_skipped",
+				zap.String("install_id", event.InstallID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		receipt, err := storeClientError(tx, payload, event.UserID)
+		if err != nil {
+			return err
+		}
+		issues[receipt.Issue.ID] = receipt.Issue
+	}
+
+	if len(usageEvents) > 0 {
+		var query strings.Builder
+		query.WriteString(`INSERT INTO telemetry_events
+			(install_id, user_id, event_type, name, schema_version, sample_rate, app_version, os, session_id, occurred_at, payload)
+			VALUES `)
+
+		args := make([]any, 0, len(usageEvents)*telemetryEventColumns)
+		for i, event := range usageEvents {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+
+			query.WriteString("(")
+			for column := 0; column < telemetryEventColumns; column++ {
+				if column > 0 {
+					query.WriteString(", ")
+				}
+				query.WriteString("$" + strconv.Itoa(i*telemetryEventColumns+column+1))
+			}
+			query.WriteString(")")
+
+			args = append(args,
+				event.InstallID, nullableString(event.UserID), event.Type, event.Name, event.SchemaVersion,
+				event.SampleRate, event.AppVersion, event.OS, nullableString(event.SessionID), event.OccurredAt,
+				string(event.Payload),
+			)
+		}
+
+		if _, err := tx.ExecContext(ctx, query.String(), args...); err != nil {
+			return errors.New("database error during telemetry insert")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("database error during telemetry insert")
+	}
+
+	for _, issue := range issues {
+		go evaluateClientIssueAlert(issue, s.DB)
+	}
+	return nil
+}
+
+func clientErrorFromEvent(event telemetry.Event) (models.ClientErrorLog, error) {
+	var payload telemetry.ErrorPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return models.ClientErrorLog{}, errors.New("malformed error payload")
+	}
+
+	breadcrumbs := []models.ClientBreadcrumb{}
+	if len(payload.Breadcrumbs) > 0 {
+		if err := json.Unmarshal(payload.Breadcrumbs, &breadcrumbs); err != nil {
+			breadcrumbs = []models.ClientBreadcrumb{}
+		}
+	}
+	if len(breadcrumbs) > 100 {
+		breadcrumbs = breadcrumbs[len(breadcrumbs)-100:]
+	}
+
+	requestID := payload.RequestID
+	if !middleware.IsValidRequestID(requestID) {
+		requestID = ""
+	}
+
+	return models.ClientErrorLog{
+		Timestamp:    event.OccurredAt,
+		ErrorType:    payload.ErrorType,
+		ErrorMessage: payload.ErrorMessage,
+		StackTrace:   payload.StackTrace,
+		Breadcrumbs:  breadcrumbs,
+		AppVersion:   event.AppVersion,
+		OS:           event.OS,
+		RequestID:    requestID,
+	}, nil
+}
+
+func nullableString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"livecode-api/models"
+	"livecode-api/utils"
+
+	"github.com/google/uuid"
+)
+
+type dbExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func issueTokenPair(db dbExecutor, user models.UserData, familyID string) (*utils.TokenPair, error) {
+	tokens, err := utils.GenerateTokenPair(user.ID, user.Username, user.Email, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		"INSERT INTO refresh_tokens (id, user_id, family_id, expires_at) VALUES ($1, $2, $3, $4)",
+		tokens.RefreshTokenID, user.ID, familyID, tokens.RefreshTokenExpiresAt,
+	)
+	if err != nil {
+		return nil, errors.New("failed to store refresh token")
+	}
+
+	return tokens, nil
+}
+
+// legacyRefreshTokenNamespace derives IDs for refresh tokens issued before
+// they carried one.
+var legacyRefreshTokenNamespace = uuid.MustParse("3f0c6a52-8d1e-4b7a-9c45-2e6f1d8a7b90")
+
+// trackLegacyRefreshToken records a refresh token without an ID as the
+// first of a new family, under an ID derived from the token, and returns
+// that ID. It is accepted once like any other; using it again is reuse.
+// Nothing is recorded if the user no longer exists.
+func trackLegacyRefreshToken(db dbExecutor, token, userID string, expiresAt time.Time) (string, error) {
+	tokenID := uuid.NewSHA1(legacyRefreshTokenNamespace, []byte(token)).String()
+	_, err := db.Exec(`
+		INSERT INTO refresh_tokens (id, user_id, family_id, expires_at)
+		SELECT $1, id, $3, $4 FROM users WHERE id::text = $2
+		ON CONFLICT (id) DO NOTHING`,
+		tokenID, userID, uuid.NewString(), expiresAt,
+	)
+	if err != nil {
+		return "", err
+	}
+	return tokenID, nil
+}
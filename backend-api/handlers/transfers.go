@@ -0,0 +1,1156 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"livecode-api/internal/archive"
+	"livecode-api/internal/checksum"
+	"livecode-api/internal/events"
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/throttle"
+	"livecode-api/internal/transfers"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTransferConnectionNotFound = errors.New("connection not found")
+	ErrTransferState              = errors.New("transfer is not in a state that allows this")
+	ErrTransferLimit              = errors.New("too many unfinished transfers")
+	ErrTransferQuota              = errors.New("staged transfer data would exceed the user's quota")
+	ErrTransferBusy               = errors.New("another upload to this transfer is in progress")
+	ErrTransferIncomplete         = errors.New("upload body ended early")
+	ErrTransferChecksumMismatch   = errors.New("checksum of the copy does not match the source")
+	ErrTransferChecksumExpected   = errors.New("source data does not match the expected checksum")
+)
+
+// TransferOffsetError rejects data that does not continue where the staged
+// data ends.
+type TransferOffsetError struct {
+	Expected int64
+}
+
+func (e *TransferOffsetError) Error() string {
+	return "transfer data must start at offset " + strconv.FormatInt(e.Expected, 10)
+}
+
+// TransferSizeError rejects an upload larger than the policy allows.
+type TransferSizeError struct {
+	Max int64
+}
+
+func (e *TransferSizeError) Error() string {
+	return "uploads may not be larger than " + strconv.FormatInt(e.Max, 10) + " bytes"
+}
+
+const (
+	maxUnfinishedTransfersPerUser = 100
+	transferChunkSize             = 4 << 20
+	transferPartSuffix            = ".livecode-part"
+	transferProgressInterval      = 500 * time.Millisecond
+)
+
+var (
+	transferEngine     *transfers.Engine
+	transferStagingDir string
+)
+
+type TransferPolicy struct {
+	// MaxSize caps the size of an upload.
+	MaxSize int64
+	// MaxStagingBytes caps the data a user can have staged on the server:
+	// the size of their unfinished uploads and the downloads not yet
+	// deleted.
+	MaxStagingBytes int64
+	// StagingTTL is how long an upload may wait for more data before it
+	// is removed.
+	StagingTTL time.Duration
+}
+
+var transferPolicy = TransferPolicy{
+	MaxSize:         10 << 30,
+	MaxStagingBytes: 20 << 30,
+	StagingTTL:      24 * time.Hour,
+}
+
+func SetTransferPolicy(policy TransferPolicy) {
+	transferPolicy = policy
+}
+
+// SetTransferEngine sets the engine that runs transfer jobs and the
+// directory their data is staged in.
+func SetTransferEngine(engine *transfers.Engine, stagingDir string) {
+	transferEngine = engine
+	transferStagingDir = stagingDir
+}
+
+func transferStagingPath(id string) string {
+	return filepath.Join(transferStagingDir, id+".part")
+}
+
+var unfinishedTransferStatuses = []string{
+	models.TransferStaging, models.TransferQueued, models.TransferRunning, models.TransferPaused,
+}
+
+const transferColumns = `id, connection_id, direction, remote_path, overwrite, modified_at, sync_plan_id, status, size,
+	staged_bytes, transferred_bytes, bandwidth_limit, archive, symlinks, archive_entries, checksum_algorithm, expected_checksum, source_checksum, destination_checksum,
+	verified_by, verified_at, attempts, next_attempt_at, last_error, created_at, updated_at, started_at, finished_at`
+
+func scanTransfer(row interface{ Scan(...any) error }, transfer *models.Transfer, extra ...any) error {
+	var size, bandwidthLimit sql.NullInt64
+	var lastError, syncPlanID, archive, expected, source, destination, verifiedBy sql.NullString
+	var nextAttemptAt time.Time
+	var modifiedAt, verifiedAt, startedAt, finishedAt sql.NullTime
+
+	dest := []any{
+		&transfer.ID, &transfer.ConnectionID, &transfer.Direction, &transfer.RemotePath, &transfer.Overwrite,
+		&modifiedAt, &syncPlanID, &transfer.Status, &size, &transfer.StagedBytes, &transfer.TransferredBytes,
+		&bandwidthLimit, &archive, &transfer.Symlinks, &transfer.ArchiveEntries, &transfer.ChecksumAlgorithm, &expected, &source, &destination, &verifiedBy, &verifiedAt,
+		&transfer.Attempts, &nextAttemptAt, &lastError, &transfer.CreatedAt, &transfer.UpdatedAt, &startedAt, &finishedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if size.Valid {
+		transfer.Size = &size.Int64
+		if size.Int64 > 0 {
+			transfer.Progress = float64(transfer.TransferredBytes) / float64(size.Int64)
+		}
+	}
+	if transfer.Status == models.TransferCompleted {
+		transfer.Progress = 1
+	}
+	if bandwidthLimit.Valid {
+		transfer.BandwidthLimit = &bandwidthLimit.Int64
+	}
+	if transfer.Status == models.TransferQueued && nextAttemptAt.After(time.Now()) {
+		transfer.NextAttemptAt = &nextAttemptAt
+	}
+	if lastError.Valid {
+		transfer.LastError = &lastError.String
+	}
+	if archive.Valid {
+		transfer.Archive = &archive.String
+	}
+	if modifiedAt.Valid {
+		transfer.ModifiedAt = &modifiedAt.Time
+	}
+	if syncPlanID.Valid {
+		transfer.SyncPlanID = &syncPlanID.String
+	}
+	if expected.Valid {
+		transfer.ExpectedChecksum = &expected.String
+	}
+	if source.Valid {
+		transfer.SourceChecksum = &source.String
+	}
+	if destination.Valid {
+		transfer.DestinationChecksum = &destination.String
+	}
+	if verifiedBy.Valid {
+		transfer.VerifiedBy = &verifiedBy.String
+	}
+	if verifiedAt.Valid {
+		transfer.VerifiedAt = &verifiedAt.Time
+	}
+	if startedAt.Valid {
+		transfer.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		transfer.FinishedAt = &finishedAt.Time
+	}
+	return nil
+}
+
+// moveTransfer changes a transfer's status if it is currently one of from,
+// applies set as further assignments and records the change in its
+// history. set may use args from $5. It returns the transfer's direction,
+// or "" if the transfer was not in one of from.
+func moveTransfer(ctx context.Context, db *sql.DB, id string, from []string, to, detail, set string, args ...any) (string, error) {
+	var direction, userID string
+	err := db.QueryRowContext(ctx, `
+		WITH moved AS (
+			UPDATE transfers SET status = $3`+set+`
+			WHERE id = $1 AND status = ANY($2)
+			RETURNING id, user_id, status, direction
+		), logged AS (
+			INSERT INTO transfer_events (transfer_id, status, detail)
+			SELECT id, status, NULLIF($4, '') FROM moved
+		)
+		SELECT direction, user_id FROM moved`,
+		append([]any{id, from, to, detail}, args...)...,
+	).Scan(&direction, &userID)
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.New("database error during transfer update")
+	}
+
+	publishTransferStatus(userID, id, to, detail)
+	return direction, nil
+}
+
+func publishTransferStatus(userID, id, status, detail string) {
+	event := models.TransferStatusEvent{TransferID: id, Status: status}
+	if detail != "" {
+		event.Detail = &detail
+	}
+	eventHub.Publish(userID, events.TypeTransferStatus, event)
+}
+
+func CreateTransferInternal(userID string, req models.TransferRequest, db *sql.DB) (*models.Transfer, error) {
+	connection, err := GetConnectionInternal(userID, req.ConnectionID, db)
+	if err != nil {
+		return nil, err
+	}
+	if connection == nil {
+		return nil, ErrTransferConnectionNotFound
+	}
+	if err := checkRemoteSupported(connection); err != nil {
+		return nil, err
+	}
+
+	var staging int64
+	if req.Direction == models.TransferUpload {
+		if *req.Size > transferPolicy.MaxSize {
+			return nil, &TransferSizeError{Max: transferPolicy.MaxSize}
+		}
+		staging = *req.Size
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during transfer creation")
+	}
+	defer tx.Rollback()
+
+	if err := reserveTransferStaging(tx, userID, staging); err != nil {
+		return nil, err
+	}
+
+	var unfinished int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM transfers WHERE user_id = $1 AND sync_plan_id IS NULL AND status = ANY($2)",
+		userID, unfinishedTransferStatuses,
+	).Scan(&unfinished)
+	if err != nil {
+		return nil, errors.New("database error during transfer creation")
+	}
+	if unfinished >= maxUnfinishedTransfersPerUser {
+		return nil, ErrTransferLimit
+	}
+
+	status := models.TransferQueued
+	if req.Direction == models.TransferUpload && *req.Size > 0 {
+		status = models.TransferStaging
+	}
+
+	var transfer models.Transfer
+	err = scanTransfer(tx.QueryRow(`
+		INSERT INTO transfers (user_id, connection_id, direction, remote_path, overwrite, modified_at, status, size,
+			checksum_algorithm, expected_checksum, bandwidth_limit, archive, symlinks)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''), $13)
+		RETURNING `+transferColumns,
+		userID, req.ConnectionID, req.Direction, req.RemotePath, req.Overwrite, req.ModifiedAt, status, req.Size,
+		req.ChecksumAlgorithm, req.ExpectedChecksum, req.BandwidthLimit, req.Archive, req.Symlinks,
+	), &transfer)
+	if err != nil {
+		return nil, errors.New("database error during transfer creation")
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO transfer_events (transfer_id, status) VALUES ($1, $2)",
+		transfer.ID, status,
+	); err != nil {
+		return nil, errors.New("database error during transfer creation")
+	}
+
+	if req.Direction == models.TransferUpload {
+		if err := os.WriteFile(transferStagingPath(transfer.ID), nil, 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		os.Remove(transferStagingPath(transfer.ID))
+		return nil, errors.New("database error during transfer creation")
+	}
+
+	publishTransferStatus(userID, transfer.ID, status, "")
+	if status == models.TransferQueued {
+		transferEngine.Notify()
+	}
+	return &transfer, nil
+}
+
+// reserveTransferStaging checks that adding bytes of uploads keeps the
+// user within their staging quota. It holds a lock on the user's
+// transfers until tx ends, so transfers created at the same time cannot
+// both fit in the last of the quota.
+func reserveTransferStaging(tx *sql.Tx, userID string, bytes int64) error {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('transfers:' || $1))", userID); err != nil {
+		return errors.New("database error during transfer creation")
+	}
+
+	var staged int64
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN direction = 'upload' THEN size ELSE staged_bytes END), 0)
+		FROM transfers
+		WHERE user_id = $1 AND (
+			(direction = 'upload' AND status = ANY($2)) OR
+			(direction = 'download' AND status <> 'canceled')
+		)`,
+		userID, unfinishedTransferStatuses,
+	).Scan(&staged)
+	if err != nil {
+		return errors.New("database error during transfer creation")
+	}
+	if staged+bytes > transferPolicy.MaxStagingBytes {
+		return ErrTransferQuota
+	}
+	return nil
+}
+
+// ExpireStaging removes uploads that have been waiting for data for longer
+// than the policy's StagingTTL, with the data staged for them.
+func (s TransferStore) ExpireStaging(ctx context.Context, now time.Time) {
+	rows, err := s.DB.QueryContext(ctx,
+		"DELETE FROM transfers WHERE status = 'staging' AND updated_at < $1 RETURNING id",
+		now.Add(-transferPolicy.StagingTTL),
+	)
+	if err != nil {
+		middleware.Logger.Error("transfer_staging_expiry_failed",
+			zap.Error(err),
+		)
+		return
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			break
+		}
+		os.Remove(transferStagingPath(id))
+		count++
+	}
+	if count > 0 {
+		middleware.Logger.Info("transfer_staging_expired",
+			zap.Int("count", count),
+		)
+	}
+}
+
+func ListTransfersInternal(userID string, filter models.TransferFilter, db *sql.DB) ([]models.Transfer, int, error) {
+	rows, err := db.Query(`
+		SELECT `+transferColumns+`, COUNT(*) OVER()
+		FROM transfers
+		WHERE user_id = $1 AND ($2 = '' OR status = $2) AND ($5 = '' OR sync_plan_id::text = $5)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4`,
+		userID, filter.Status, filter.Limit, filter.Offset, filter.SyncPlanID,
+	)
+	if err != nil {
+		return nil, 0, errors.New("database error during transfer listing")
+	}
+	defer rows.Close()
+
+	list := []models.Transfer{}
+	total := 0
+
+	for rows.Next() {
+		var transfer models.Transfer
+		if err := scanTransfer(rows, &transfer, &total); err != nil {
+			return nil, 0, errors.New("database error during transfer listing")
+		}
+		list = append(list, transfer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.New("database error during transfer listing")
+	}
+
+	return list, total, nil
+}
+
+// GetTransferInternal returns one of the user's transfers with its status
+// history, or nil if it does not exist.
+func GetTransferInternal(userID, transferID string, db *sql.DB) (*models.Transfer, error) {
+	transfer, err := getTransfer(userID, transferID, db)
+	if err != nil || transfer == nil {
+		return transfer, err
+	}
+
+	rows, err := db.Query(
+		"SELECT status, detail, created_at FROM transfer_events WHERE transfer_id = $1 ORDER BY id",
+		transferID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during transfer lookup")
+	}
+	defer rows.Close()
+
+	transfer.History = []models.TransferEvent{}
+	for rows.Next() {
+		var event models.TransferEvent
+		var detail sql.NullString
+		if err := rows.Scan(&event.Status, &detail, &event.CreatedAt); err != nil {
+			return nil, errors.New("database error during transfer lookup")
+		}
+		if detail.Valid {
+			event.Detail = &detail.String
+		}
+		transfer.History = append(transfer.History, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during transfer lookup")
+	}
+
+	return transfer, nil
+}
+
+func getTransfer(userID, transferID string, db *sql.DB) (*models.Transfer, error) {
+	var transfer models.Transfer
+
+	err := scanTransfer(db.QueryRow(
+		"SELECT "+transferColumns+" FROM transfers WHERE id = $1 AND user_id = $2",
+		transferID, userID,
+	), &transfer)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error during transfer lookup")
+	}
+
+	return &transfer, nil
+}
+
+// lockTransferWriter takes a lock on the transfer that is held for as long
+// as the returned connection is, so only one request on any server writes
+// its data at a time. It returns nil if another request holds it.
+func lockTransferWriter(ctx context.Context, id string, db *sql.DB) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.New("database error during transfer lock")
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext('transfer_writer:' || $1))", id).Scan(&locked)
+	if err != nil || !locked {
+		conn.Close()
+		if err != nil {
+			return nil, errors.New("database error during transfer lock")
+		}
+		return nil, nil
+	}
+	return conn, nil
+}
+
+func unlockTransferWriter(conn *sql.Conn, id string) {
+	// Closing a connection the unlock failed on would hand it back to the
+	// pool still holding the lock.
+	if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext('transfer_writer:' || $1))", id); err != nil {
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	conn.Close()
+}
+
+// WriteTransferDataInternal stages upload data from offset, which must be
+// where the staged data ends. Whatever arrives is kept even if body fails
+// part way, so the client can continue from the new end. Once all the data
+// is staged the transfer is queued.
+func WriteTransferDataInternal(userID, transferID string, offset int64, body io.Reader, db *sql.DB) (*models.Transfer, error) {
+	lock, err := lockTransferWriter(context.Background(), transferID, db)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return nil, ErrTransferBusy
+	}
+	defer unlockTransferWriter(lock, transferID)
+
+	transfer, err := getTransfer(userID, transferID, db)
+	if err != nil || transfer == nil {
+		return nil, err
+	}
+	if transfer.Direction != models.TransferUpload || transfer.Status != models.TransferStaging {
+		return nil, ErrTransferState
+	}
+	if offset != transfer.StagedBytes {
+		return nil, &TransferOffsetError{Expected: transfer.StagedBytes}
+	}
+
+	file, err := os.OpenFile(transferStagingPath(transferID), os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Drop anything written past the recorded end before a crash.
+	if err := file.Truncate(offset); err != nil {
+		return nil, err
+	}
+	written, copyErr := io.Copy(io.NewOffsetWriter(file, offset), io.LimitReader(body, *transfer.Size-offset))
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+
+	staged := offset + written
+	result, err := db.Exec(
+		"UPDATE transfers SET staged_bytes = $3 WHERE id = $1 AND status = 'staging' AND staged_bytes = $2",
+		transferID, offset, staged,
+	)
+	if err != nil {
+		return nil, errors.New("database error during transfer upload")
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrTransferState
+	}
+
+	if staged == *transfer.Size {
+		if _, err := moveTransfer(context.Background(), db, transferID, []string{models.TransferStaging}, models.TransferQueued, "", ""); err != nil {
+			return nil, err
+		}
+		transferEngine.Notify()
+	}
+	if copyErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTransferIncomplete, copyErr)
+	}
+
+	return getTransfer(userID, transferID, db)
+}
+
+// OpenTransferDataInternal opens the data of a completed download.
+func OpenTransferDataInternal(userID, transferID string, db *sql.DB) (*models.Transfer, *os.File, error) {
+	transfer, err := getTransfer(userID, transferID, db)
+	if err != nil || transfer == nil {
+		return nil, nil, err
+	}
+	if transfer.Direction != models.TransferDownload || transfer.Status != models.TransferCompleted {
+		return nil, nil, ErrTransferState
+	}
+
+	file, err := os.Open(transferStagingPath(transferID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return transfer, file, nil
+}
+
+// changeTransferStatus moves one of the user's transfers from one of from
+// to to, or returns nil if it does not exist.
+func changeTransferStatus(userID, transferID string, from []string, to, set string, db *sql.DB) (*models.Transfer, error) {
+	transfer, err := getTransfer(userID, transferID, db)
+	if err != nil || transfer == nil {
+		return nil, err
+	}
+
+	direction, err := moveTransfer(context.Background(), db, transferID, from, to, "", set)
+	if err != nil {
+		return nil, err
+	}
+	if direction == "" {
+		return nil, ErrTransferState
+	}
+
+	return getTransfer(userID, transferID, db)
+}
+
+func PauseTransferInternal(userID, transferID string, db *sql.DB) (*models.Transfer, error) {
+	transfer, err := changeTransferStatus(userID, transferID,
+		[]string{models.TransferQueued, models.TransferRunning}, models.TransferPaused, ", heartbeat_at = NULL", db)
+	if transfer != nil {
+		transferEngine.Interrupt(transferID)
+	}
+	return transfer, err
+}
+
+// ResumeTransferInternal queues a paused transfer, or a failed one with a
+// fresh set of attempts.
+func ResumeTransferInternal(userID, transferID string, db *sql.DB) (*models.Transfer, error) {
+	transfer, err := changeTransferStatus(userID, transferID,
+		[]string{models.TransferPaused, models.TransferFailed}, models.TransferQueued,
+		", attempts = CASE WHEN status = 'failed' THEN 0 ELSE attempts END, next_attempt_at = now(), finished_at = NULL", db)
+	if transfer != nil {
+		transferEngine.Notify()
+	}
+	return transfer, err
+}
+
+func CancelTransferInternal(userID, transferID string, db *sql.DB) (*models.Transfer, error) {
+	transfer, err := changeTransferStatus(userID, transferID,
+		unfinishedTransferStatuses, models.TransferCanceled, ", heartbeat_at = NULL, finished_at = now()", db)
+	if transfer != nil {
+		transferEngine.Interrupt(transferID)
+		os.Remove(transferStagingPath(transferID))
+	}
+	return transfer, err
+}
+
+// DeleteTransferInternal removes a finished transfer and its staged data.
+// It reports false if the transfer does not exist.
+func DeleteTransferInternal(userID, transferID string, db *sql.DB) (bool, error) {
+	result, err := db.Exec(
+		"DELETE FROM transfers WHERE id = $1 AND user_id = $2 AND NOT status = ANY($3)",
+		transferID, userID, unfinishedTransferStatuses,
+	)
+	if err != nil {
+		return false, errors.New("database error during transfer deletion")
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		transfer, err := getTransfer(userID, transferID, db)
+		if err != nil || transfer == nil {
+			return false, err
+		}
+		return false, ErrTransferState
+	}
+
+	os.Remove(transferStagingPath(transferID))
+	return true, nil
+}
+
+// TransferStore keeps transfer jobs in Postgres and runs them over the
+// SFTP gateway.
+type TransferStore struct {
+	DB *sql.DB
+}
+
+// Claim takes due jobs in round-robin order across users, so one user's
+// queue cannot hold up everyone else's.
+func (s TransferStore) Claim(ctx context.Context, limit, perUser int) ([]transfers.Job, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.New("database error during transfer claim")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('transfers'))"); err != nil {
+		return nil, errors.New("database error during transfer claim")
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH running AS (
+			SELECT user_id, COUNT(*) AS jobs FROM transfers WHERE status = 'running' GROUP BY user_id
+		), ranked AS (
+			SELECT t.id, t.created_at,
+				ROW_NUMBER() OVER (PARTITION BY t.user_id ORDER BY t.created_at) + COALESCE(r.jobs, 0) AS slot
+			FROM transfers t
+			LEFT JOIN running r ON r.user_id = t.user_id
+			WHERE t.status = 'queued' AND t.next_attempt_at <= now()
+		), claimed AS (
+			UPDATE transfers
+			SET status = 'running', attempts = attempts + 1, heartbeat_at = now(), started_at = COALESCE(started_at, now())
+			WHERE id IN (SELECT id FROM ranked WHERE slot <= $2 ORDER BY slot, created_at LIMIT $1)
+			RETURNING id, user_id, attempts
+		), logged AS (
+			INSERT INTO transfer_events (transfer_id, status, detail)
+			SELECT id, 'running', 'attempt ' || attempts FROM claimed
+		)
+		SELECT id, user_id, attempts FROM claimed`,
+		limit, perUser,
+	)
+	if err != nil {
+		return nil, errors.New("database error during transfer claim")
+	}
+	defer rows.Close()
+
+	jobs := []transfers.Job{}
+	for rows.Next() {
+		var job transfers.Job
+		if err := rows.Scan(&job.ID, &job.UserID, &job.Attempts); err != nil {
+			return nil, errors.New("database error during transfer claim")
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during transfer claim")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during transfer claim")
+	}
+
+	for _, job := range jobs {
+		publishTransferStatus(job.UserID, job.ID, models.TransferRunning, "attempt "+strconv.Itoa(job.Attempts))
+	}
+	return jobs, nil
+}
+
+func (s TransferStore) Heartbeat(ctx context.Context, ids []string) error {
+	_, err := s.DB.ExecContext(ctx,
+		"UPDATE transfers SET heartbeat_at = now() WHERE status = 'running' AND id::text = ANY($1)",
+		ids,
+	)
+	if err != nil {
+		return errors.New("database error during transfer heartbeat")
+	}
+	// Limits may have been changed on another server, or a window may
+	// have started or ended.
+	return bandwidth.refresh(ctx, s.DB)
+}
+
+// RequeueStale keeps the attempt counted, so a job that brings the server
+// down is not retried forever: once it has had maxAttempts it fails.
+func (s TransferStore) RequeueStale(ctx context.Context, cutoff time.Time, maxAttempts int) (int, int, error) {
+	const detail = "server stopped while the transfer was running"
+
+	rows, err := s.DB.QueryContext(ctx, `
+		WITH moved AS (
+			UPDATE transfers SET
+				status = CASE WHEN attempts >= $3 THEN 'failed' ELSE 'queued' END,
+				heartbeat_at = NULL, next_attempt_at = now(),
+				finished_at = CASE WHEN attempts >= $3 THEN now() ELSE finished_at END,
+				last_error = CASE WHEN attempts >= $3 THEN $2 ELSE last_error END
+			WHERE status = 'running' AND heartbeat_at < $1
+			RETURNING id, user_id, status
+		), logged AS (
+			INSERT INTO transfer_events (transfer_id, status, detail)
+			SELECT id, status, $2 FROM moved
+		)
+		SELECT id, user_id, status FROM moved`,
+		cutoff, detail, maxAttempts,
+	)
+	if err != nil {
+		return 0, 0, errors.New("database error during transfer requeue")
+	}
+	defer rows.Close()
+
+	requeued, failed := 0, 0
+	for rows.Next() {
+		var id, userID, status string
+		if err := rows.Scan(&id, &userID, &status); err != nil {
+			return requeued, failed, errors.New("database error during transfer requeue")
+		}
+		publishTransferStatus(userID, id, status, detail)
+		if status == models.TransferFailed {
+			failed++
+		} else {
+			requeued++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return requeued, failed, errors.New("database error during transfer requeue")
+	}
+	return requeued, failed, nil
+}
+
+func (s TransferStore) Requeue(ctx context.Context, job transfers.Job, reason string) error {
+	_, err := moveTransfer(ctx, s.DB, job.ID, []string{models.TransferRunning}, models.TransferQueued, reason,
+		", attempts = attempts - 1, heartbeat_at = NULL, next_attempt_at = now()")
+	return err
+}
+
+func (s TransferStore) Retry(ctx context.Context, job transfers.Job, cause error, at time.Time) error {
+	_, err := moveTransfer(ctx, s.DB, job.ID, []string{models.TransferRunning}, models.TransferQueued, cause.Error(),
+		", heartbeat_at = NULL, next_attempt_at = $5, last_error = $4", at)
+	return err
+}
+
+func (s TransferStore) Fail(ctx context.Context, job transfers.Job, cause error) error {
+	_, err := moveTransfer(ctx, s.DB, job.ID, []string{models.TransferRunning}, models.TransferFailed, cause.Error(),
+		", heartbeat_at = NULL, finished_at = now(), last_error = $4")
+	return err
+}
+
+// Complete finishes a job. Staged upload data is no longer needed; staged
+// downloads stay until the transfer is deleted.
+func (s TransferStore) Complete(ctx context.Context, job transfers.Job) error {
+	direction, err := moveTransfer(ctx, s.DB, job.ID, []string{models.TransferRunning}, models.TransferCompleted, "",
+		", heartbeat_at = NULL, finished_at = now(), last_error = NULL")
+	if direction == models.TransferUpload {
+		os.Remove(transferStagingPath(job.ID))
+	}
+	return err
+}
+
+// Run copies a transfer's data between the staging directory and the
+// remote host in chunks, recording progress after each so a later attempt
+// resumes where this one stopped. Uploads go to a temporary file next to
+// the target, which is renamed into place at the end, as does each file
+// extracted from an uploaded archive.
+func (s TransferStore) Run(ctx context.Context, job transfers.Job) error {
+	transfer, err := getTransfer(job.UserID, job.ID, s.DB)
+	if err != nil {
+		return err
+	}
+	if transfer == nil {
+		return transfers.ErrInterrupted
+	}
+
+	audit := models.KnownHostKey{UserAgent: "livecode-transfers"}
+	session, _, err := OpenRemoteSessionInternal(ctx, job.UserID, transfer.ConnectionID, audit, s.DB)
+	if err != nil {
+		return permanentTransferError(err)
+	}
+	if session == nil {
+		return transfers.Permanent(ErrTransferConnectionNotFound)
+	}
+	defer session.Release()
+
+	buckets, release := bandwidth.acquire(job.UserID, job.ID, transfer.BandwidthLimit)
+	defer release()
+	if err := bandwidth.refresh(ctx, s.DB); err != nil {
+		return err
+	}
+
+	switch {
+	case transfer.Direction == models.TransferUpload && transfer.Archive != nil:
+		err = s.extract(ctx, session, job.UserID, transfer, buckets)
+	case transfer.Direction == models.TransferUpload:
+		err = s.upload(ctx, session, job.UserID, transfer, buckets)
+	case transfer.Direction == models.TransferDownload && transfer.Archive != nil:
+		err = s.downloadArchive(ctx, session, job.UserID, transfer, buckets)
+	case transfer.Direction == models.TransferDownload:
+		err = s.download(ctx, session, job.UserID, transfer, buckets)
+	default:
+		err = DeleteRemoteFileInternal(session, transfer.RemotePath, true)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+
+	if errors.Is(context.Cause(ctx), transfers.ErrInterrupted) && transfer.Direction == models.TransferUpload && transfer.Archive == nil {
+		if current, _ := getTransfer(job.UserID, job.ID, s.DB); current == nil || current.Status == models.TransferCanceled {
+			session.Remove(transfer.RemotePath + transferPartSuffix)
+		}
+	}
+	return permanentTransferError(err)
+}
+
+func (s TransferStore) upload(ctx context.Context, session remotefs.FS, userID string, transfer *models.Transfer, buckets []*throttle.Bucket) error {
+	part := transfer.RemotePath + transferPartSuffix
+	size := *transfer.Size
+
+	// Resume from what reached the server, if the protocol can.
+	offset := transfer.TransferredBytes
+	if offset > 0 && session.Capabilities().ResumeUpload {
+		info, err := session.Stat(part)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if info == nil {
+			offset = 0
+		} else {
+			offset = min(offset, info.Size())
+		}
+	} else {
+		offset = 0
+	}
+
+	file, err := os.Open(transferStagingPath(transfer.ID))
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+	defer file.Close()
+
+	hash, err := checksum.New(transfer.ChecksumAlgorithm)
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+	// A resumed upload hashes what earlier attempts sent from the staged copy.
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, offset)); err != nil {
+		return err
+	}
+
+	// Sync plans upload into directories that may not exist yet.
+	if transfer.SyncPlanID != nil {
+		if _, err := MakeRemoteDirectoryInternal(session, path.Dir(transfer.RemotePath), true); err != nil {
+			return err
+		}
+	}
+
+	remote, err := session.Create(ctx, part, offset)
+	if err != nil {
+		return err
+	}
+	var published time.Time
+	for {
+		n := min(transferChunkSize, size-offset)
+		chunk := throttle.Reader(ctx, io.TeeReader(io.NewSectionReader(file, offset, n), hash), buckets...)
+		if _, err := io.Copy(remote, chunk); err != nil {
+			remote.Close()
+			return err
+		}
+
+		offset += n
+		if err := s.progress(ctx, userID, transfer, offset, &published); err != nil {
+			remote.Close()
+			return err
+		}
+		if offset >= size {
+			break
+		}
+	}
+	if err := remote.Close(); err != nil {
+		return err
+	}
+
+	err = s.verify(ctx, transfer, checksum.Hex(hash), func() (string, string, error) {
+		return remoteChecksum(ctx, session, part, transfer.ChecksumAlgorithm)
+	})
+	if err != nil {
+		session.Remove(part)
+		return err
+	}
+
+	if _, err := RenameRemoteFileInternal(session, part, transfer.RemotePath, transfer.Overwrite); err != nil {
+		if errors.Is(err, ErrRemoteExists) || errors.Is(err, ErrRemoteIsDirectory) {
+			session.Remove(part)
+		}
+		return err
+	}
+	if transfer.ModifiedAt != nil && session.Capabilities().Chtimes {
+		return session.Chtimes(transfer.RemotePath, *transfer.ModifiedAt)
+	}
+	return nil
+}
+
+func (s TransferStore) download(ctx context.Context, session remotefs.FS, userID string, transfer *models.Transfer, buckets []*throttle.Bucket) error {
+	remote, info, err := OpenRemoteFileInternal(ctx, session, transfer.RemotePath)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+
+	size := info.Size()
+	offset := transfer.TransferredBytes
+	modified := info.ModTime()
+	if transfer.Size == nil || *transfer.Size != size || transfer.ModifiedAt == nil || !transfer.ModifiedAt.Equal(modified) {
+		// First attempt, or the file changed since the last one.
+		offset = 0
+		_, err := s.DB.ExecContext(ctx,
+			"UPDATE transfers SET size = $2, modified_at = $3, staged_bytes = 0, transferred_bytes = 0 WHERE id = $1 AND status = 'running'",
+			transfer.ID, size, modified,
+		)
+		if err != nil {
+			return errors.New("database error during transfer progress")
+		}
+	}
+	transfer.Size = &size
+
+	local, err := os.OpenFile(transferStagingPath(transfer.ID), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+	defer local.Close()
+
+	if err := local.Truncate(offset); err != nil {
+		return err
+	}
+
+	hash, err := checksum.New(transfer.ChecksumAlgorithm)
+	if err != nil {
+		return transfers.Permanent(err)
+	}
+	if _, err := io.Copy(hash, io.NewSectionReader(local, 0, offset)); err != nil {
+		return err
+	}
+
+	if _, err := remote.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	var published time.Time
+	for offset < size {
+		n := min(transferChunkSize, size-offset)
+		chunk := throttle.Reader(ctx, io.TeeReader(io.LimitReader(remote, n), hash), buckets...)
+		if _, err := io.Copy(io.NewOffsetWriter(local, offset), chunk); err != nil {
+			return err
+		}
+		if err := local.Sync(); err != nil {
+			return err
+		}
+
+		offset += n
+		if err := s.progress(ctx, userID, transfer, offset, &published); err != nil {
+			return err
+		}
+	}
+
+	return s.verify(ctx, transfer, checksum.Hex(hash), func() (string, string, error) {
+		sum, err := checksum.Sum(transfer.ChecksumAlgorithm, io.NewSectionReader(local, 0, size))
+		return sum, models.TransferVerifiedByReadback, err
+	})
+}
+
+// verify records the digests of a finished copy. A copy that does not
+// match its source is retried once from the start before the transfer
+// fails; a source that does not match the expected digest is not retried.
+func (s TransferStore) verify(ctx context.Context, transfer *models.Transfer, source string, destination func() (string, string, error)) error {
+	if transfer.ExpectedChecksum != nil && *transfer.ExpectedChecksum != source {
+		_, err := s.DB.ExecContext(ctx, "UPDATE transfers SET source_checksum = $2 WHERE id = $1", transfer.ID, source)
+		if err != nil {
+			return errors.New("database error during transfer verification")
+		}
+		return transfers.Permanent(ErrTransferChecksumExpected)
+	}
+
+	sum, verifiedBy, err := destination()
+	if err != nil {
+		return err
+	}
+
+	match := sum == source
+	var mismatches int
+	err = s.DB.QueryRowContext(ctx, `
+		UPDATE transfers
+		SET source_checksum = $2, destination_checksum = $3, verified_by = $4, verified_at = now(),
+			checksum_mismatches = checksum_mismatches + CASE WHEN $5 THEN 0 ELSE 1 END,
+			transferred_bytes = CASE WHEN $5 THEN transferred_bytes ELSE 0 END,
+			staged_bytes = CASE WHEN NOT $5 AND direction = 'download' THEN 0 ELSE staged_bytes END
+		WHERE id = $1 AND status = 'running'
+		RETURNING checksum_mismatches`,
+		transfer.ID, source, sum, verifiedBy, match,
+	).Scan(&mismatches)
+	if err == sql.ErrNoRows {
+		return transfers.ErrInterrupted
+	}
+	if err != nil {
+		return errors.New("database error during transfer verification")
+	}
+
+	switch {
+	case match:
+		return nil
+	case mismatches > 1:
+		return transfers.Permanent(ErrTransferChecksumMismatch)
+	}
+	return ErrTransferChecksumMismatch
+}
+
+// remoteChecksum has the remote host compute the digest of name, and reads
+// the file back when it cannot.
+func remoteChecksum(ctx context.Context, session remotefs.FS, name, algorithm string) (string, string, error) {
+	if sum, ok := execChecksum(ctx, session, name, algorithm); ok {
+		return sum, models.TransferVerifiedByExec, nil
+	}
+
+	file, _, err := OpenRemoteFileInternal(ctx, session, name)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	sum, err := checksum.Sum(algorithm, contextReader{ctx: ctx, r: file})
+	return sum, models.TransferVerifiedByReadback, err
+}
+
+// execChecksum runs the digest tool for algorithm on the remote host,
+// reporting false if the host has no shell or no such tool.
+func execChecksum(ctx context.Context, session remotefs.FS, name, algorithm string) (string, bool) {
+	command, ok := checksum.Command(algorithm, name)
+	if !ok {
+		return "", false
+	}
+	execer, ok := remotefs.AsExecer(session)
+	if !ok {
+		return "", false
+	}
+
+	output, err := execer.Exec(ctx, command)
+	if err != nil {
+		return "", false
+	}
+	return checksum.ParseOutput(algorithm, output)
+}
+
+// progress records how far a running job has got and tells the user, at
+// most every transferProgressInterval. It also picks up a bandwidth limit
+// changed on another server. It returns transfers.ErrInterrupted if the
+// job was paused or canceled meanwhile.
+func (s TransferStore) progress(ctx context.Context, userID string, transfer *models.Transfer, offset int64, published *time.Time) error {
+	var limit sql.NullInt64
+	err := s.DB.QueryRowContext(ctx, `
+		UPDATE transfers
+		SET transferred_bytes = $2, archive_entries = $3, heartbeat_at = now(),
+			staged_bytes = CASE WHEN direction = 'download' AND archive IS NULL THEN $2 ELSE staged_bytes END
+		WHERE id = $1 AND status = 'running'
+		RETURNING bandwidth_limit`,
+		transfer.ID, offset, transfer.ArchiveEntries,
+	).Scan(&limit)
+	if err == sql.ErrNoRows {
+		return transfers.ErrInterrupted
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		return errors.New("database error during transfer progress")
+	}
+	if limit.Valid {
+		bandwidth.setTransfer(transfer.ID, &limit.Int64)
+	} else {
+		bandwidth.setTransfer(transfer.ID, nil)
+	}
+
+	if offset == *transfer.Size || time.Since(*published) >= transferProgressInterval {
+		*published = time.Now()
+		event := models.TransferProgressEvent{TransferID: transfer.ID, TransferredBytes: offset, Size: transfer.Size}
+		if *transfer.Size > 0 {
+			event.Progress = float64(offset) / float64(*transfer.Size)
+		}
+		eventHub.Publish(userID, events.TypeTransferProgress, event)
+	}
+	return nil
+}
+
+// permanentTransferError marks errors that another attempt would hit again.
+func permanentTransferError(err error) error {
+	var hostKeyErr *HostKeyError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &hostKeyErr),
+		errors.Is(err, remotefs.ErrAuthFailed),
+		errors.Is(err, remotefs.ErrAddressBlocked),
+		errors.Is(err, remotefs.ErrUnsupported),
+		errors.Is(err, ErrRemoteUnsupported),
+		errors.Is(err, ErrRemoteNoCredentials),
+		errors.Is(err, ErrRemoteClientEncrypted),
+		errors.Is(err, ErrRemoteExists),
+		errors.Is(err, ErrRemoteIsDirectory),
+		errors.Is(err, ErrRemoteNotDirectory),
+		errors.Is(err, os.ErrNotExist),
+		errors.Is(err, os.ErrPermission),
+		errors.Is(err, archive.ErrInvalid),
+		errors.Is(err, archive.ErrUnsafePath),
+		errors.Is(err, archive.ErrTooManyEntries),
+		errors.Is(err, archive.ErrTooLarge),
+		errors.Is(err, archive.ErrLinks):
+		return transfers.Permanent(err)
+	}
+	return err
+}
+
+// contextReader stops a copy once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, context.Cause(r.ctx)
+	}
+	return r.r.Read(p)
+}
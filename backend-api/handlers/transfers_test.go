@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"livecode-api/internal/checksum"
+	"livecode-api/models"
+)
+
+func TestRemoteChecksum_ExecAndReadback(t *testing.T) {
+	server, session := newTestSFTPSession(t)
+	os.WriteFile(filepath.Join(server.Root, "app.tar"), []byte("abc"), 0o644)
+	want, _ := checksum.Sum(checksum.SHA256, strings.NewReader("abc"))
+
+	sum, verifiedBy, err := remoteChecksum(context.Background(), session, "app.tar", checksum.SHA256)
+	if err != nil || sum != want || verifiedBy != models.TransferVerifiedByReadback {
+		t.Errorf("Expected a readback digest without exec, got %s %s %v", sum, verifiedBy, err)
+	}
+
+	var commands []string
+	server.Exec = func(command string) (string, uint32) {
+		commands = append(commands, command)
+		return strings.ToUpper(want) + "  app.tar\n", 0
+	}
+	sum, verifiedBy, err = remoteChecksum(context.Background(), session, "app.tar", checksum.SHA256)
+	if err != nil || sum != want || verifiedBy != models.TransferVerifiedByExec {
+		t.Errorf("Expected the remote digest, got %s %s %v", sum, verifiedBy, err)
+	}
+	if len(commands) != 1 || commands[0] != "sha256sum -- 'app.tar'" {
+		t.Errorf("Unexpected commands: %v", commands)
+	}
+
+	server.Exec = func(string) (string, uint32) { return "sh: b3sum: not found\n", 127 }
+	want, _ = checksum.Sum(checksum.BLAKE3, strings.NewReader("abc"))
+	sum, verifiedBy, err = remoteChecksum(context.Background(), session, "app.tar", checksum.BLAKE3)
+	if err != nil || sum != want || verifiedBy != models.TransferVerifiedByReadback {
+		t.Errorf("Expected a readback when the tool is missing, got %s %s %v", sum, verifiedBy, err)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/usernames"
+	"livecode-api/models"
+
+	"github.com/google/uuid"
+)
+
+// usernameConflict reports whether username is taken, or reads the same as a
+// taken one, by an account other than exceptUserID.
+func usernameConflict(db *sql.DB, username, exceptUserID string) (*models.FieldError, error) {
+	var existing string
+	err := db.QueryRow(
+		`SELECT username FROM users
+		 WHERE (username = $1 OR username_skeleton = $2) AND id::text <> $3
+		 ORDER BY username = $1 DESC
+		 LIMIT 1`,
+		username, usernames.Skeleton(username), exceptUserID,
+	).Scan(&existing)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == username {
+		fieldErr := apierror.Field("username", apierror.FieldUsernameTaken, nil)
+		return &fieldErr, nil
+	}
+	fieldErr := apierror.Field("username", apierror.FieldUsernameConfusable, nil)
+	return &fieldErr, nil
+}
+
+func ChangeUsernameInternal(userID, username string, db *sql.DB) (models.ChangeUsernameResponse, error) {
+	usernameErr, err := usernameConflict(db, username, userID)
+	if err != nil {
+		return models.ChangeUsernameResponse{}, errors.New("database error during username check")
+	}
+
+	if usernameErr != nil {
+		return models.ChangeUsernameResponse{
+			Success:     false,
+			FieldErrors: []models.FieldError{*usernameErr},
+			Message:     "Username could not be changed.",
+			Code:        string(apierror.CodeAccountConflict),
+		}, nil
+	}
+
+	user := models.UserData{ID: userID, Username: username}
+	err = db.QueryRow(
+		"UPDATE users SET username = $2, username_skeleton = $3 WHERE id = $1 RETURNING email, role",
+		userID, username, usernames.Skeleton(username),
+	).Scan(&user.Email, &user.Role)
+
+	if err == sql.ErrNoRows {
+		return models.ChangeUsernameResponse{
+			Success: false,
+			Message: "User not found.",
+			Code:    string(apierror.CodeNotFound),
+		}, nil
+	}
+	if err != nil {
+		return models.ChangeUsernameResponse{}, errors.New("database update failed")
+	}
+
+	tokens, err := issueTokenPair(db, user, uuid.New().String())
+	if err != nil {
+		return models.ChangeUsernameResponse{}, err
+	}
+
+	return models.ChangeUsernameResponse{
+		Success:      true,
+		Message:      "Your username has been changed.",
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		User:         &user,
+	}, nil
+}
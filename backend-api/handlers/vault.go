@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"livecode-api/internal/vault"
+	"livecode-api/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var vaultKeyring *vault.Keyring
+
+// SetVaultKeyring enables server-side encryption of vault items. Without a
+// keyring only client-encrypted items can be stored.
+func SetVaultKeyring(keyring *vault.Keyring) {
+	vaultKeyring = keyring
+}
+
+var (
+	ErrVaultUnavailable        = errors.New("server-side vault encryption is not configured")
+	ErrVaultZeroKnowledge      = errors.New("vault only accepts client-encrypted items")
+	ErrVaultKDFRequired        = errors.New("zero-knowledge mode needs key derivation parameters")
+	ErrVaultMigrationRequired  = errors.New("server-encrypted items must be re-encrypted by the client first")
+	ErrVaultItemExists         = errors.New("vault item already exists")
+	ErrVaultVersionConflict    = errors.New("vault item was changed by another client")
+	ErrVaultConnectionNotFound = errors.New("connection not found")
+)
+
+const vaultItemColumns = `id, connection_id, kind, label, encryption, nonce, ciphertext, version, created_at, updated_at`
+
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type vaultState struct {
+	zeroKnowledge bool
+	hasKDF        bool
+}
+
+func scanVaultItem(row interface{ Scan(...any) error }, item *models.VaultItem) error {
+	var connectionID sql.NullString
+
+	err := row.Scan(
+		&item.ID, &connectionID, &item.Kind, &item.Label, &item.Encryption,
+		&item.Nonce, &item.Ciphertext, &item.Version, &item.CreatedAt, &item.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if connectionID.Valid {
+		item.ConnectionID = &connectionID.String
+	}
+	return nil
+}
+
+// lockVault creates the user's vault row if needed and locks it for the
+// rest of tx, serialising data key creation and mode changes.
+func lockVault(tx *sql.Tx, userID string) (vaultState, error) {
+	if _, err := tx.Exec("INSERT INTO vaults (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING", userID); err != nil {
+		return vaultState{}, err
+	}
+
+	var state vaultState
+	err := tx.QueryRow(
+		"SELECT zero_knowledge, kdf IS NOT NULL FROM vaults WHERE user_id = $1 FOR UPDATE",
+		userID,
+	).Scan(&state.zeroKnowledge, &state.hasKDF)
+	return state, err
+}
+
+// userDataKey unwraps the user's data key, generating and storing one first
+// when create is set. Callers clear the returned key when done.
+func userDataKey(q queryRower, userID string, create bool) ([]byte, error) {
+	if vaultKeyring == nil {
+		return nil, ErrVaultUnavailable
+	}
+
+	var masterKeyID sql.NullString
+	var wrapped vault.WrappedKey
+	err := q.QueryRow(
+		"SELECT master_key_id, data_key_nonce, data_key FROM vaults WHERE user_id = $1",
+		userID,
+	).Scan(&masterKeyID, &wrapped.Nonce, &wrapped.Ciphertext)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.New("database error during data key lookup")
+	}
+
+	if masterKeyID.Valid {
+		wrapped.MasterKeyID = masterKeyID.String
+		return vaultKeyring.Unwrap(userID, wrapped)
+	}
+
+	if !create {
+		return nil, errors.New("vault has no data key")
+	}
+
+	dataKey, wrapped, err := vaultKeyring.NewDataKey(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored string
+	err = q.QueryRow(
+		"UPDATE vaults SET master_key_id = $2, data_key_nonce = $3, data_key = $4 WHERE user_id = $1 RETURNING user_id",
+		userID, wrapped.MasterKeyID, wrapped.Nonce, wrapped.Ciphertext,
+	).Scan(&stored)
+	if err != nil {
+		clear(dataKey)
+		return nil, errors.New("database error during data key creation")
+	}
+
+	return dataKey, nil
+}
+
+// sealVaultItem returns the nonce and ciphertext to store for input. Client
+// encrypted input is stored as sent.
+func sealVaultItem(tx *sql.Tx, userID, itemID string, input models.VaultItemInput) ([]byte, []byte, error) {
+	if input.Encryption == vault.EncryptionClient {
+		return input.Nonce, input.Ciphertext, nil
+	}
+
+	dataKey, err := userDataKey(tx, userID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(dataKey)
+
+	return vault.Seal(dataKey, []byte(input.Secret), vault.AssociatedData(userID, itemID))
+}
+
+// prepareVaultWrite locks the vault and checks input against its mode and
+// the user's connections.
+func prepareVaultWrite(tx *sql.Tx, userID string, input models.VaultItemInput) error {
+	state, err := lockVault(tx, userID)
+	if err != nil {
+		return errors.New("database error during vault lookup")
+	}
+
+	if input.Encryption == vault.EncryptionServer {
+		if state.zeroKnowledge {
+			return ErrVaultZeroKnowledge
+		}
+		if vaultKeyring == nil {
+			return ErrVaultUnavailable
+		}
+	}
+
+	if input.ConnectionID == "" {
+		return nil
+	}
+
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM connections WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)",
+		input.ConnectionID, userID,
+	).Scan(&exists)
+	if err != nil {
+		return errors.New("database error during connection lookup")
+	}
+	if !exists {
+		return ErrVaultConnectionNotFound
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func GetVaultSettingsInternal(userID string, db *sql.DB) (models.VaultSettings, error) {
+	settings := models.VaultSettings{ServerEncryption: vaultKeyring != nil}
+
+	var kdf []byte
+	err := db.QueryRow(
+		"SELECT zero_knowledge, kdf FROM vaults WHERE user_id = $1",
+		userID,
+	).Scan(&settings.ZeroKnowledge, &kdf)
+	if err != nil && err != sql.ErrNoRows {
+		return models.VaultSettings{}, errors.New("database error during vault lookup")
+	}
+
+	if kdf != nil {
+		settings.KDF = &models.VaultKDF{}
+		if err := json.Unmarshal(kdf, settings.KDF); err != nil {
+			return models.VaultSettings{}, errors.New("failed to decode vault key derivation parameters")
+		}
+	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM vault_items WHERE user_id = $1", userID).Scan(&settings.Items)
+	if err != nil {
+		return models.VaultSettings{}, errors.New("database error during vault item count")
+	}
+
+	return settings, nil
+}
+
+// UpdateVaultSettingsInternal switches zero-knowledge mode and stores the
+// key derivation parameters. Enabling the mode requires every existing item
+// to be client-encrypted already, and discards the server-held data key.
+func UpdateVaultSettingsInternal(userID string, req models.VaultSettingsRequest, db *sql.DB) (models.VaultSettings, error) {
+	var kdf any
+	if req.KDF != nil {
+		encoded, err := json.Marshal(req.KDF)
+		if err != nil {
+			return models.VaultSettings{}, errors.New("failed to encode vault key derivation parameters")
+		}
+		kdf = string(encoded)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.VaultSettings{}, errors.New("database error during vault settings update")
+	}
+	defer tx.Rollback()
+
+	state, err := lockVault(tx, userID)
+	if err != nil {
+		return models.VaultSettings{}, errors.New("database error during vault lookup")
+	}
+
+	if req.ZeroKnowledge && req.KDF == nil && !state.hasKDF {
+		return models.VaultSettings{}, ErrVaultKDFRequired
+	}
+
+	if req.ZeroKnowledge && !state.zeroKnowledge {
+		var serverItems int
+		err := tx.QueryRow(
+			"SELECT COUNT(*) FROM vault_items WHERE user_id = $1 AND encryption = 'server'",
+			userID,
+		).Scan(&serverItems)
+		if err != nil {
+			return models.VaultSettings{}, errors.New("database error during vault item count")
+		}
+		if serverItems > 0 {
+			return models.VaultSettings{}, ErrVaultMigrationRequired
+		}
+	}
+
+	_, err = tx.Exec(
+		`UPDATE vaults
+		 SET zero_knowledge = $2, kdf = COALESCE($3::jsonb, kdf),
+			master_key_id = CASE WHEN $2 THEN NULL ELSE master_key_id END,
+			data_key_nonce = CASE WHEN $2 THEN NULL ELSE data_key_nonce END,
+			data_key = CASE WHEN $2 THEN NULL ELSE data_key END
+		 WHERE user_id = $1`,
+		userID, req.ZeroKnowledge, kdf,
+	)
+	if err != nil {
+		return models.VaultSettings{}, errors.New("database error during vault settings update")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.VaultSettings{}, errors.New("database error during vault settings update")
+	}
+
+	return GetVaultSettingsInternal(userID, db)
+}
+
+// ListVaultItemsInternal returns item metadata only; secrets are fetched one
+// item at a time.
+func ListVaultItemsInternal(userID, connectionID string, db *sql.DB) ([]models.VaultItem, error) {
+	rows, err := db.Query(`
+		SELECT `+vaultItemColumns+`
+		FROM vault_items
+		WHERE user_id = $1 AND ($2 = '' OR connection_id::text = $2)
+		ORDER BY created_at`,
+		userID, connectionID,
+	)
+	if err != nil {
+		return nil, errors.New("database error during vault item listing")
+	}
+	defer rows.Close()
+
+	items := []models.VaultItem{}
+	for rows.Next() {
+		var item models.VaultItem
+		if err := scanVaultItem(rows, &item); err != nil {
+			return nil, errors.New("database error during vault item listing")
+		}
+		item.Nonce, item.Ciphertext = nil, nil
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("database error during vault item listing")
+	}
+
+	return items, nil
+}
+
+// GetVaultItemInternal returns an item with its secret: decrypted for
+// server-encrypted items, as stored for client-encrypted ones.
+func GetVaultItemInternal(userID, itemID string, db *sql.DB) (*models.VaultItem, error) {
+	var item models.VaultItem
+
+	err := scanVaultItem(db.QueryRow(
+		"SELECT "+vaultItemColumns+" FROM vault_items WHERE id = $1 AND user_id = $2",
+		itemID, userID,
+	), &item)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.New("database error during vault item lookup")
+	}
+
+	if item.Encryption == vault.EncryptionClient {
+		return &item, nil
+	}
+
+	dataKey, err := userDataKey(db, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+
+	plaintext, err := vault.Open(dataKey, item.Nonce, item.Ciphertext, vault.AssociatedData(userID, item.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	item.Secret = string(plaintext)
+	item.Nonce, item.Ciphertext = nil, nil
+	clear(plaintext)
+
+	return &item, nil
+}
+
+// CreateVaultItemInternal stores a new item under input.ID, or a new ID if
+// it is empty. IDs only have to be unique among the user's items, so a
+// conflict never reveals another user's.
+func CreateVaultItemInternal(userID string, input models.VaultItemInput, db *sql.DB) (*models.VaultItem, error) {
+	itemID := input.ID
+	if itemID == "" {
+		itemID = uuid.New().String()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during vault item creation")
+	}
+	defer tx.Rollback()
+
+	if err := prepareVaultWrite(tx, userID, input); err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := sealVaultItem(tx, userID, itemID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var item models.VaultItem
+	err = scanVaultItem(tx.QueryRow(`
+		INSERT INTO vault_items (id, user_id, connection_id, kind, label, encryption, nonce, ciphertext)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+vaultItemColumns,
+		itemID, userID, nullableString(input.ConnectionID), input.Kind, input.Label, input.Encryption, nonce, ciphertext,
+	), &item)
+
+	if isUniqueViolation(err) {
+		return nil, ErrVaultItemExists
+	}
+	if err != nil {
+		return nil, errors.New("database error during vault item creation")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during vault item creation")
+	}
+
+	item.Nonce, item.Ciphertext = nil, nil
+	return &item, nil
+}
+
+// UpdateVaultItemInternal replaces an item if it is still at input.Version.
+// Changing Encryption moves an item between server and client encryption.
+func UpdateVaultItemInternal(userID, itemID string, input models.VaultItemInput, db *sql.DB) (*models.VaultItem, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("database error during vault item update")
+	}
+	defer tx.Rollback()
+
+	if err := prepareVaultWrite(tx, userID, input); err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := sealVaultItem(tx, userID, itemID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var item models.VaultItem
+	err = scanVaultItem(tx.QueryRow(`
+		UPDATE vault_items
+		SET connection_id = $4, kind = $5, label = $6, encryption = $7, nonce = $8, ciphertext = $9,
+			version = version + 1
+		WHERE id = $1 AND user_id = $2 AND version = $3
+		RETURNING `+vaultItemColumns,
+		itemID, userID, input.Version, nullableString(input.ConnectionID), input.Kind, input.Label,
+		input.Encryption, nonce, ciphertext,
+	), &item)
+
+	if err == sql.ErrNoRows {
+		return nil, vaultItemMissOrConflict(tx, userID, itemID)
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrVaultItemExists
+	}
+	if err != nil {
+		return nil, errors.New("database error during vault item update")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("database error during vault item update")
+	}
+
+	item.Nonce, item.Ciphertext = nil, nil
+	return &item, nil
+}
+
+func DeleteVaultItemInternal(userID, itemID string, version int, db *sql.DB) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, errors.New("database error during vault item deletion")
+	}
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRow(
+		"DELETE FROM vault_items WHERE id = $1 AND user_id = $2 AND ($3 = 0 OR version = $3) RETURNING id",
+		itemID, userID, version,
+	).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		if err := vaultItemMissOrConflict(tx, userID, itemID); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New("database error during vault item deletion")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.New("database error during vault item deletion")
+	}
+
+	return true, nil
+}
+
+func vaultItemMissOrConflict(tx *sql.Tx, userID, itemID string) error {
+	var exists bool
+	err := tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM vault_items WHERE id = $1 AND user_id = $2)",
+		itemID, userID,
+	).Scan(&exists)
+	if err != nil {
+		return errors.New("database error during vault item lookup")
+	}
+
+	if exists {
+		return ErrVaultVersionConflict
+	}
+	return nil
+}
+
+// RewrapVaultKeysInternal rewraps every data key, including those of SSH
+// certificate authorities, that is not yet under the keyring's current
+// master key and returns how many it changed. Items are not re-encrypted;
+// only the wrapped data keys change.
+func RewrapVaultKeysInternal(keyring *vault.Keyring, db *sql.DB) (int, error) {
+	rows, err := db.Query(
+		"SELECT user_id, master_key_id, data_key_nonce, data_key FROM vaults WHERE master_key_id <> $1",
+		keyring.CurrentID(),
+	)
+	if err != nil {
+		return 0, errors.New("database error during data key listing")
+	}
+
+	type wrappedUserKey struct {
+		userID  string
+		wrapped vault.WrappedKey
+	}
+	var pending []wrappedUserKey
+
+	for rows.Next() {
+		var key wrappedUserKey
+		if err := rows.Scan(&key.userID, &key.wrapped.MasterKeyID, &key.wrapped.Nonce, &key.wrapped.Ciphertext); err != nil {
+			rows.Close()
+			return 0, errors.New("database error during data key listing")
+		}
+		pending = append(pending, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, errors.New("database error during data key listing")
+	}
+
+	rewrapped := 0
+	for _, key := range pending {
+		next, changed, err := keyring.Rewrap(key.userID, key.wrapped)
+		if err != nil {
+			return rewrapped, errors.New("data key for user " + key.userID + ": " + err.Error())
+		}
+		if !changed {
+			continue
+		}
+
+		result, err := db.Exec(
+			`UPDATE vaults SET master_key_id = $3, data_key_nonce = $4, data_key = $5
+			 WHERE user_id = $1 AND master_key_id = $2`,
+			key.userID, key.wrapped.MasterKeyID, next.MasterKeyID, next.Nonce, next.Ciphertext,
+		)
+		if err != nil {
+			return rewrapped, errors.New("database error during data key rewrap")
+		}
+		if count, _ := result.RowsAffected(); count == 1 {
+			rewrapped++
+		}
+	}
+
+	caKeys, err := rewrapSSHCAKeys(keyring, db)
+	return rewrapped + caKeys, err
+}
@@ -0,0 +1,89 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"livecode-api/middleware"
+
+	"go.uber.org/zap"
+)
+
+type Alert struct {
+	Kind     string            `json:"kind"`
+	Severity string            `json:"severity"`
+	Title    string            `json:"title"`
+	Message  string            `json:"message"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	FiredAt  time.Time         `json:"fired_at"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, alert Alert) error {
+	middleware.Logger.Error("alert_fired",
+		zap.String("kind", alert.Kind),
+		zap.String("severity", alert.Severity),
+		zap.String("title", alert.Title),
+		zap.String("message", alert.Message),
+		zap.Any("labels", alert.Labels),
+	)
+	return nil
+}
+
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(ctx context.Context, alert Alert) error {
+	var firstErr error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, alert); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,196 @@
+package apierror
+
+import (
+	"net/http"
+	"strings"
+
+	"livecode-api/internal/i18n"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MediaTypeV2      = "application/vnd.livecode.v2+json"
+	ProblemMediaType = "application/problem+json"
+
+	problemTypePrefix = "urn:livecode:error:"
+)
+
+// Error describes a failed request once, for both response formats: clients
+// that accept MediaTypeV2 get an RFC 9457 problem document with Status, older
+// clients keep receiving the legacy body and status they were built against.
+type Error struct {
+	Status int
+	Code   Code
+	Detail string
+	Fields []models.FieldError
+
+	legacyStatus int
+	legacyBody   any
+}
+
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      Code                `json:"code"`
+	Errors    []models.FieldError `json:"errors,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+func Internal() *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "An unexpected error occurred. Please try again.")
+}
+
+func Validation(fields ...models.FieldError) *Error {
+	return New(http.StatusBadRequest, CodeValidationFailed, "Validation failed").WithFields(fields...)
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Detail
+}
+
+func (e *Error) WithFields(fields ...models.FieldError) *Error {
+	clone := *e
+	clone.Fields = append(append([]models.FieldError{}, e.Fields...), fields...)
+	return &clone
+}
+
+// WithLegacy overrides the status and body sent to clients that have not
+// opted into MediaTypeV2.
+func (e *Error) WithLegacy(status int, body any) *Error {
+	clone := *e
+	clone.legacyStatus = status
+	clone.legacyBody = body
+	return &clone
+}
+
+// Field builds a field error whose message is rendered from the default
+// locale's catalogue entry for code. Write re-renders it for other locales.
+func Field(field string, code Code, params map[string]any) models.FieldError {
+	message, _ := i18n.Default().FieldMessage(i18n.DefaultLocale, field, string(code), params)
+	return models.FieldError{
+		Field:   field,
+		Code:    string(code),
+		Message: message,
+		Params:  params,
+	}
+}
+
+func locale(c *gin.Context) string {
+	if value := c.GetString(i18n.ContextKey); value != "" {
+		return value
+	}
+	return i18n.DefaultLocale
+}
+
+// LocalizedMessage returns the catalogue message for code in the request's
+// locale. Requests in the default locale keep fallback, which is usually more
+// specific than the catalogue entry.
+func LocalizedMessage(c *gin.Context, code Code, fallback string) string {
+	locale := locale(c)
+	if locale == i18n.DefaultLocale {
+		return fallback
+	}
+
+	if message, ok := i18n.Default().Translate(locale, string(code), nil); ok {
+		return message
+	}
+	return fallback
+}
+
+func LocalizedFields(c *gin.Context, fields []models.FieldError) []models.FieldError {
+	locale := locale(c)
+	if locale == i18n.DefaultLocale || len(fields) == 0 {
+		return fields
+	}
+
+	localized := make([]models.FieldError, len(fields))
+	for i, field := range fields {
+		if message, ok := i18n.Default().FieldMessage(locale, field.Field, field.Code, field.Params); ok {
+			field.Message = message
+		}
+		localized[i] = field
+	}
+	return localized
+}
+
+func (e *Error) localized(c *gin.Context) *Error {
+	clone := *e
+	clone.Detail = LocalizedMessage(c, e.Code, e.Detail)
+	clone.Fields = LocalizedFields(c, e.Fields)
+	return &clone
+}
+
+func (e *Error) Problem(c *gin.Context) Problem {
+	return Problem{
+		Type:      problemTypePrefix + string(e.Code),
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Instance:  c.Request.URL.Path,
+		Code:      e.Code,
+		Errors:    e.Fields,
+		RequestID: c.GetString("correlation_id"),
+	}
+}
+
+func WantsProblem(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	return strings.Contains(accept, MediaTypeV2) || strings.Contains(accept, ProblemMediaType)
+}
+
+func Write(c *gin.Context, err *Error) {
+	c.Writer.Header().Add("Vary", "Accept")
+	err = err.localized(c)
+
+	if WantsProblem(c) {
+		c.Header("Content-Type", ProblemMediaType)
+		c.JSON(err.Status, err.Problem(c))
+		return
+	}
+
+	status := err.Status
+	if err.legacyStatus != 0 {
+		status = err.legacyStatus
+	}
+
+	c.JSON(status, err.legacy(c))
+}
+
+func Abort(c *gin.Context, err *Error) {
+	Write(c, err)
+	c.Abort()
+}
+
+func (e *Error) legacy(c *gin.Context) any {
+	requestID := c.GetString("correlation_id")
+
+	body, ok := e.legacyBody.(gin.H)
+	if e.legacyBody != nil && !ok {
+		return e.legacyBody
+	}
+
+	if body == nil {
+		body = gin.H{
+			"success": false,
+			"message": e.Detail,
+			"code":    e.Code,
+		}
+		if len(e.Fields) > 0 {
+			body["errors"] = e.Fields
+		}
+	}
+
+	if requestID != "" {
+		body["request_id"] = requestID
+	}
+	return body
+}
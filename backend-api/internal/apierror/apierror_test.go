@@ -0,0 +1,144 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"livecode-api/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter(err *Error) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", func(c *gin.Context) {
+		c.Set("correlation_id", "req-12345678")
+		Write(c, err)
+	})
+	return router
+}
+
+func TestWrite_ProblemForV2Clients(t *testing.T) {
+	err := New(http.StatusConflict, CodeAccountConflict, "Account could not be created.").
+		WithFields(Field("email", FieldEmailTaken, nil)).
+		WithLegacy(http.StatusOK, gin.H{"success": false})
+
+	req := httptest.NewRequest(http.MethodPost, "/register", nil)
+	req.Header.Set("Accept", MediaTypeV2)
+	rec := httptest.NewRecorder()
+	newTestRouter(err).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got: %d", rec.Code)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != ProblemMediaType {
+		t.Errorf("Expected content type %s, got: %s", ProblemMediaType, got)
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+
+	if problem.Type != "urn:livecode:error:account.conflict" || problem.Status != http.StatusConflict ||
+		problem.Instance != "/register" || problem.RequestID != "req-12345678" {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+
+	if len(problem.Errors) != 1 || problem.Errors[0].Code != string(FieldEmailTaken) {
+		t.Errorf("Expected email_taken field error, got: %+v", problem.Errors)
+	}
+}
+
+func TestWrite_LegacyForOldClients(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    *Error
+		status int
+		body   string
+	}{
+		{
+			name:   "default body",
+			err:    New(http.StatusTooManyRequests, CodeRateLimited, "Slow down."),
+			status: http.StatusTooManyRequests,
+			body:   `{"code":"rate_limit.exceeded","message":"Slow down.","request_id":"req-12345678","success":false}`,
+		},
+		{
+			name:   "legacy override",
+			err:    Validation().WithLegacy(http.StatusBadRequest, gin.H{"available": nil}),
+			status: http.StatusBadRequest,
+			body:   `{"available":null,"request_id":"req-12345678"}`,
+		},
+		{
+			name:   "legacy status only",
+			err:    New(http.StatusConflict, CodeAccountConflict, "Taken.").WithLegacy(http.StatusOK, nil),
+			status: http.StatusOK,
+			body:   `{"code":"account.conflict","message":"Taken.","request_id":"req-12345678","success":false}`,
+		},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/register", nil)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		newTestRouter(tc.err).ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got: %d", tc.name, tc.status, rec.Code)
+		}
+
+		if rec.Body.String() != tc.body {
+			t.Errorf("%s: expected body %s, got: %s", tc.name, tc.body, rec.Body.String())
+		}
+	}
+}
+
+func TestField_RendersDefaultLocaleMessage(t *testing.T) {
+	field := Field("password", FieldTooShort, map[string]any{"min": 8})
+	if field.Message != "Password must be at least 8 characters" {
+		t.Errorf("Expected English catalogue message, got: %q", field.Message)
+	}
+
+	field = Field("email", FieldEmailTaken, nil)
+	if field.Message != "This email is already taken." {
+		t.Errorf("Expected email taken message unchanged, got: %q", field.Message)
+	}
+}
+
+func TestWrite_LocalizesForNegotiatedLocale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/register", func(c *gin.Context) {
+		c.Set(i18n.ContextKey, "ro")
+		Write(c, Validation(Field("password", FieldTooShort, map[string]any{"min": 8})))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/register", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var body struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+
+	if body.Message != "Validarea a eșuat" {
+		t.Errorf("Expected Romanian detail, got: %q", body.Message)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Code != string(FieldTooShort) ||
+		body.Errors[0].Message != "Câmpul „Parolă” trebuie să aibă cel puțin 8 caractere" {
+		t.Errorf("Expected Romanian field error with stable code, got: %+v", body.Errors)
+	}
+}
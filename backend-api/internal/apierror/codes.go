@@ -0,0 +1,122 @@
+package apierror
+
+type Code string
+
+const (
+	CodeInternal           Code = "internal.unexpected"
+	CodeServiceUnavailable Code = "internal.service_unavailable"
+	CodeNotFound           Code = "resource.not_found"
+	CodeRateLimited        Code = "rate_limit.exceeded"
+
+	CodeInvalidJSON         Code = "request.invalid_json"
+	CodeBodyTooLarge        Code = "request.body_too_large"
+	CodeUnsupportedEncoding Code = "request.unsupported_encoding"
+	CodeValidationFailed    Code = "validation.failed"
+
+	CodeAuthRequired         Code = "auth.required"
+	CodeAuthMalformedHeader  Code = "auth.malformed_authorization_header"
+	CodeAuthInvalidToken     Code = "auth.invalid_token"
+	CodeAuthForbidden        Code = "auth.forbidden"
+	CodeInvalidCredentials   Code = "auth.invalid_credentials"
+	CodeRefreshTokenInvalid  Code = "auth.refresh_token_invalid"
+	CodeRefreshTokenReused   Code = "auth.refresh_token_reused"
+	CodeSessionRevoked       Code = "auth.session_revoked"
+	CodeAccountConflict      Code = "account.conflict"
+	CodeInvalidInstallID     Code = "telemetry.invalid_install_id"
+	CodeInvalidInstallSecret Code = "telemetry.invalid_install_secret"
+	CodeChallengeRequired    Code = "challenge.required"
+	CodeChallengeInvalid     Code = "challenge.invalid"
+	CodeChallengeExpired     Code = "challenge.expired"
+	CodeVersionConflict      Code = "resource.version_conflict"
+	CodeConnectionConflict   Code = "connection.conflict"
+
+	CodeVaultUnavailable       Code = "vault.unavailable"
+	CodeVaultZeroKnowledge     Code = "vault.zero_knowledge"
+	CodeVaultMigrationRequired Code = "vault.migration_required"
+	CodeVaultItemExists        Code = "vault.item_exists"
+
+	CodeSSHKeyConflict        Code = "ssh_key.conflict"
+	CodeSSHKeyNoPrivateKey    Code = "ssh_key.no_private_key"
+	CodeSSHKeyClientEncrypted Code = "ssh_key.client_encrypted"
+
+	CodeSSHCAUnavailable  Code = "ssh_ca.unavailable"
+	CodeSSHCANoPrincipals Code = "ssh_ca.no_principals"
+	CodeTeamConflict      Code = "team.conflict"
+
+	CodeKnownHostChanged Code = "known_host.changed"
+	CodeKnownHostPinned  Code = "known_host.pinned"
+	CodeKnownHostUnknown Code = "known_host.unknown"
+
+	CodeRemoteUnsupported      Code = "remote.unsupported"
+	CodeRemoteNoCredentials    Code = "remote.no_credentials"
+	CodeRemoteClientEncrypted  Code = "remote.client_encrypted"
+	CodeRemoteConnectFailed    Code = "remote.connect_failed"
+	CodeRemoteAddressBlocked   Code = "remote.address_blocked"
+	CodeRemoteAuthFailed       Code = "remote.auth_failed"
+	CodeRemotePermissionDenied Code = "remote.permission_denied"
+	CodeRemoteExists           Code = "remote.exists"
+	CodeRemoteNotEmpty         Code = "remote.not_empty"
+	CodeRemoteIsDirectory      Code = "remote.is_directory"
+	CodeRemoteNotDirectory     Code = "remote.not_directory"
+	CodeRemoteArchiveTooLarge  Code = "remote.archive_too_large"
+	CodeRemoteFailed           Code = "remote.failed"
+
+	CodeRemoteOperationUnsupported Code = "remote.operation_unsupported"
+
+	CodeTransferInvalidState   Code = "transfer.invalid_state"
+	CodeTransferLimitReached   Code = "transfer.limit_reached"
+	CodeTransferQuotaExceeded  Code = "transfer.quota_exceeded"
+	CodeTransferBusy           Code = "transfer.busy"
+	CodeTransferOffsetMismatch Code = "transfer.offset_mismatch"
+	CodeTransferIncomplete     Code = "transfer.incomplete"
+
+	CodeEventsTooManyStreams Code = "events.too_many_streams"
+
+	CodeSyncPlanExecuted  Code = "sync.plan_executed"
+	CodeSyncPlanExpired   Code = "sync.plan_expired"
+	CodeSyncTooLarge      Code = "sync.too_large"
+	CodeSyncChecksumLimit Code = "sync.checksum_limit"
+
+	CodeScheduleLimitReached Code = "schedule.limit_reached"
+)
+
+const (
+	FieldRequired                 Code = "validation.required"
+	FieldInvalidType              Code = "validation.invalid_type"
+	FieldInvalidFormat            Code = "validation.invalid_format"
+	FieldInvalidEmail             Code = "validation.invalid_email"
+	FieldEmailDisposable          Code = "validation.email_disposable"
+	FieldEmailUndeliverable       Code = "validation.email_undeliverable"
+	FieldInvalidUsername          Code = "validation.invalid_username"
+	FieldInvalidUsernameSeparated Code = "validation.invalid_username_separated"
+	FieldUsernameMixedScripts     Code = "validation.username_mixed_scripts"
+	FieldUsernameReserved         Code = "validation.username_reserved"
+	FieldUsernameNotAllowed       Code = "validation.username_not_allowed"
+	FieldInvalidCharacters        Code = "validation.invalid_characters"
+	FieldNotAllowed               Code = "validation.not_allowed"
+	FieldTooShort                 Code = "validation.too_short"
+	FieldTooLong                  Code = "validation.too_long"
+	FieldTooSmall                 Code = "validation.too_small"
+	FieldTooLarge                 Code = "validation.too_large"
+	FieldTooFewItems              Code = "validation.too_few_items"
+	FieldTooManyItems             Code = "validation.too_many_items"
+	FieldPasswordTooWeak          Code = "validation.password_too_weak"
+	FieldPasswordGuessable        Code = "validation.password_too_guessable"
+	FieldPasswordPersonal         Code = "validation.password_contains_user_info"
+	FieldPasswordBreached         Code = "validation.password_breached"
+	FieldInFuture                 Code = "validation.in_future"
+	FieldNotFound                 Code = "validation.not_found"
+	FieldIncorrect                Code = "validation.incorrect"
+	FieldExclusive                Code = "validation.exclusive"
+	FieldEmailTaken               Code = "account.email_taken"
+	FieldUsernameTaken            Code = "account.username_taken"
+	FieldUsernameConfusable       Code = "account.username_confusable"
+	FieldConnectionNameTaken      Code = "connection.name_taken"
+	FieldSSHKeyDuplicate          Code = "ssh_key.duplicate"
+	FieldSSHKeyTooWeak            Code = "ssh_key.too_weak"
+	FieldTeamNameTaken            Code = "team.name_taken"
+	FieldHostKeyUntrusted         Code = "known_host.untrusted"
+	FieldRemotePathProtected      Code = "remote.path_protected"
+	FieldTransferOffset           Code = "transfer.offset_expected"
+	FieldSyncDuplicatePath        Code = "sync.duplicate_path"
+)
@@ -0,0 +1,180 @@
+// Package archive packs remote directory trees into zip and tar.gz
+// archives, and reads uploaded archives for extraction. Entries are only
+// ever named relative to the directory they are extracted into: names
+// that would land outside it are refused, as are links that point out of
+// it or that other entries would be written through.
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	Zip   = "zip"
+	TarGz = "tar.gz"
+
+	// SymlinksSkip leaves an archive's links out, SymlinksReject refuses
+	// archives that have any and SymlinksCreate creates symbolic links
+	// whose targets stay inside the extracted tree.
+	SymlinksSkip   = "skip"
+	SymlinksReject = "reject"
+	SymlinksCreate = "create"
+)
+
+var Formats = []string{Zip, TarGz}
+
+var (
+	ErrFormat         = errors.New("unknown archive format")
+	ErrInvalid        = errors.New("archive is damaged or not in the expected format")
+	ErrUnsafePath     = errors.New("archive entry would be extracted outside the target directory")
+	ErrTooManyEntries = errors.New("too many entries for an archive")
+	ErrTooLarge       = errors.New("archive expands to more than the size limit")
+	ErrLinks          = errors.New("archive contains links")
+	ErrChanged        = errors.New("remote file changed while it was archived")
+)
+
+// Limits bound what Check accepts.
+type Limits struct {
+	MaxEntries int
+	// MaxBytes is the most the archive's files may add up to once
+	// extracted.
+	MaxBytes int64
+	Symlinks string
+}
+
+// Stats describes an archive that passed Check.
+type Stats struct {
+	Entries int
+	Bytes   int64
+	Links   int
+}
+
+// ContentType is the media type of an archive in format.
+func ContentType(format string) string {
+	if format == Zip {
+		return "application/zip"
+	}
+	return "application/gzip"
+}
+
+// Check reads through an archive before anything is extracted from it, so
+// an archive that breaks the limits is refused as a whole. Sizes are
+// checked as the entries declare them; Reader holds files to those sizes.
+// Hard links count as links, but are never created.
+func Check(r io.ReaderAt, size int64, format string, limits Limits) (Stats, error) {
+	reader, err := NewReader(r, size, format)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	links := map[string]string{}
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Entries++
+		if stats.Entries > limits.MaxEntries {
+			return stats, ErrTooManyEntries
+		}
+		for dir := path.Dir(entry.Name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := links[dir]; ok {
+				return stats, unsafePath(entry.Name)
+			}
+		}
+
+		if entry.IsLink() {
+			stats.Links++
+			switch {
+			case limits.Symlinks == SymlinksReject:
+				return stats, ErrLinks
+			case limits.Symlinks == SymlinksCreate && entry.Mode&fs.ModeSymlink != 0:
+				links[entry.Name] = entry.Link
+			}
+		}
+
+		stats.Bytes += entry.Size
+		if stats.Bytes > limits.MaxBytes {
+			return stats, ErrTooLarge
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(links)) {
+		if !linkInside(name, links[name], links) {
+			return stats, unsafePath(name)
+		}
+	}
+	return stats, nil
+}
+
+// cleanName turns an entry's name into a clean relative path, reporting
+// false if it is absolute or climbs out of the directory. Backslashes are
+// taken as separators, as some Windows tools write them.
+func cleanName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if name == "" || strings.ContainsRune(name, 0) || path.IsAbs(name) || hasVolume(name) {
+		return "", false
+	}
+
+	name = path.Clean(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+func hasVolume(name string) bool {
+	if len(name) < 2 || name[1] != ':' || (len(name) > 2 && name[2] != '/') {
+		return false
+	}
+	c := name[0] | 0x20
+	return c >= 'a' && c <= 'z'
+}
+
+// linkInside reports whether the target of the link name stays inside the
+// tree, without going through links to get there: "a/.." is not the root
+// when a is itself a link.
+func linkInside(name, target string, links map[string]string) bool {
+	target = strings.ReplaceAll(target, `\`, "/")
+	if target == "" || path.IsAbs(target) || hasVolume(target) {
+		return false
+	}
+
+	var dir []string
+	if parent := path.Dir(name); parent != "." {
+		dir = strings.Split(parent, "/")
+	}
+	parts := strings.Split(target, "/")
+	for i, part := range parts {
+		switch part {
+		case "", ".":
+		case "..":
+			if len(dir) == 0 {
+				return false
+			}
+			dir = dir[:len(dir)-1]
+		default:
+			dir = append(dir, part)
+			if _, ok := links[strings.Join(dir, "/")]; ok && i < len(parts)-1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func unsafePath(name string) error {
+	return fmt.Errorf("%w: %q", ErrUnsafePath, name)
+}
@@ -0,0 +1,275 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"livecode-api/internal/sftpgw"
+	"livecode-api/internal/sftpgw/sftptest"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestSession(t *testing.T) (*sftptest.Server, *sftpgw.Session) {
+	t.Helper()
+
+	server := sftptest.NewServer(t)
+	session, err := sftpgw.Dial(context.Background(), sftpgw.Target{
+		Address:         server.Addr,
+		Username:        server.User,
+		Auth:            []ssh.AuthMethod{ssh.Password(server.Password)},
+		HostKeyCallback: ssh.FixedHostKey(server.HostKey),
+	}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to open SFTP session: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return server, session
+}
+
+// testEntry is an entry for buildArchive; a link is written as one.
+type testEntry struct {
+	name, content, link string
+	dir, hardLink       bool
+}
+
+func buildArchive(t *testing.T, format string, entries ...testEntry) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	switch format {
+	case Zip:
+		w := zip.NewWriter(&buf)
+		for _, e := range entries {
+			header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+			content := e.content
+			switch {
+			case e.dir:
+				header.SetMode(fs.ModeDir | 0o755)
+			case e.link != "":
+				header.SetMode(fs.ModeSymlink | 0o777)
+				content = e.link
+			default:
+				header.SetMode(0o644)
+			}
+			f, err := w.CreateHeader(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte(content))
+		}
+		w.Close()
+	case TarGz:
+		gz := gzip.NewWriter(&buf)
+		w := tar.NewWriter(gz)
+		for _, e := range entries {
+			header := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.content))}
+			switch {
+			case e.dir:
+				header.Typeflag, header.Size = tar.TypeDir, 0
+			case e.hardLink:
+				header.Typeflag, header.Linkname, header.Size = tar.TypeLink, e.link, 0
+			case e.link != "":
+				header.Typeflag, header.Linkname, header.Size = tar.TypeSymlink, e.link, 0
+			}
+			if err := w.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(e.content))
+		}
+		w.Close()
+		gz.Close()
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+var testLimits = Limits{MaxEntries: 100, MaxBytes: 1 << 20, Symlinks: SymlinksSkip}
+
+func TestPackAndRead(t *testing.T) {
+	server, session := newTestSession(t)
+	files := map[string]string{
+		"site/index.html":       "<h1>Hello</h1>",
+		"site/assets/app.js":    "console.log(1)",
+		"site/assets/empty.txt": "",
+	}
+	for name, content := range files {
+		full := filepath.Join(server.Root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(full), 0o755)
+		os.WriteFile(full, []byte(content), 0o640)
+	}
+	os.Mkdir(filepath.Join(server.Root, "site", "logs"), 0o755)
+	os.Symlink("/etc/passwd", filepath.Join(server.Root, "site", "passwd"))
+
+	items, err := List(context.Background(), session, "site", 100)
+	if err != nil {
+		t.Fatalf("Expected the tree to be listed, got: %v", err)
+	}
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	want := []string{"assets", "assets/app.js", "assets/empty.txt", "index.html", "logs"}
+	if len(names) != len(want) || names[0] != want[0] || names[2] != want[2] || names[4] != want[4] {
+		t.Fatalf("Expected %v without the link, got %v", want, names)
+	}
+	if _, err := List(context.Background(), session, "site", 4); !errors.Is(err, ErrTooManyEntries) {
+		t.Errorf("Expected ErrTooManyEntries, got: %v", err)
+	}
+
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		var entries int
+		var read int64
+		packer := Packer{FS: session, Root: "site", Progress: func(n int, r int64) error {
+			entries, read = n, r
+			return nil
+		}}
+		if err := packer.Pack(context.Background(), &buf, format, items); err != nil {
+			t.Fatalf("%s: expected the archive to be written, got: %v", format, err)
+		}
+		if entries != len(items) || read != int64(len("<h1>Hello</h1>")+len("console.log(1)")) {
+			t.Errorf("%s: expected progress for every item, got %d entries, %d bytes", format, entries, read)
+		}
+
+		stats, err := Check(bytes.NewReader(buf.Bytes()), int64(buf.Len()), format, testLimits)
+		if err != nil || stats.Entries != len(items) || stats.Links != 0 {
+			t.Errorf("%s: expected the archive to pass, got %+v, %v", format, stats, err)
+		}
+
+		reader, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]string{}
+		for {
+			entry, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: unexpected error reading: %v", format, err)
+			}
+			if entry.Mode.IsDir() {
+				got[entry.Name] = "dir"
+				continue
+			}
+			if entry.Mode.Perm() != 0o640 {
+				t.Errorf("%s: expected %s to keep its permissions, got %v", format, entry.Name, entry.Mode)
+			}
+			content, _ := io.ReadAll(reader)
+			got[entry.Name] = string(content)
+		}
+		if got["logs"] != "dir" || got["assets/app.js"] != "console.log(1)" || got["index.html"] != "<h1>Hello</h1>" {
+			t.Errorf("%s: unexpected contents: %v", format, got)
+		}
+	}
+}
+
+func TestPack_FileShrank(t *testing.T) {
+	server, session := newTestSession(t)
+	os.WriteFile(filepath.Join(server.Root, "log.txt"), []byte("0123456789"), 0o644)
+
+	items, err := List(context.Background(), session, ".", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(server.Root, "log.txt"), []byte("0123"), 0o644)
+
+	err = Packer{FS: session, Root: "."}.Pack(context.Background(), io.Discard, TarGz, items)
+	if !errors.Is(err, ErrChanged) {
+		t.Errorf("Expected ErrChanged, got: %v", err)
+	}
+}
+
+func TestCheck_RefusesUnsafeArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		entries []testEntry
+		want    error
+	}{
+		{"parent", testLimits, []testEntry{{name: "../evil.sh", content: "x"}}, ErrUnsafePath},
+		{"nested parent", testLimits, []testEntry{{name: "a/../../evil.sh", content: "x"}}, ErrUnsafePath},
+		{"absolute", testLimits, []testEntry{{name: "/etc/cron.d/evil", content: "x"}}, ErrUnsafePath},
+		{"backslashes", testLimits, []testEntry{{name: `..\evil.sh`, content: "x"}}, ErrUnsafePath},
+		{"volume", testLimits, []testEntry{{name: "C:/evil.sh", content: "x"}}, ErrUnsafePath},
+		{"too many", Limits{MaxEntries: 2, MaxBytes: 100}, []testEntry{
+			{name: "a", content: "x"}, {name: "b", content: "x"}, {name: "c", content: "x"},
+		}, ErrTooManyEntries},
+		{"too large", Limits{MaxEntries: 10, MaxBytes: 5}, []testEntry{
+			{name: "a", content: "abc"}, {name: "b", content: "abc"},
+		}, ErrTooLarge},
+		{"links rejected", Limits{MaxEntries: 10, MaxBytes: 100, Symlinks: SymlinksReject}, []testEntry{
+			{name: "current", link: "releases/1"},
+		}, ErrLinks},
+		{"link out", Limits{MaxEntries: 10, MaxBytes: 100, Symlinks: SymlinksCreate}, []testEntry{
+			{name: "a/up", link: "../../etc"},
+		}, ErrUnsafePath},
+		{"absolute link", Limits{MaxEntries: 10, MaxBytes: 100, Symlinks: SymlinksCreate}, []testEntry{
+			{name: "passwd", link: "/etc/passwd"},
+		}, ErrUnsafePath},
+		{"write through link", Limits{MaxEntries: 10, MaxBytes: 100, Symlinks: SymlinksCreate}, []testEntry{
+			{name: "dir", link: "."}, {name: "dir/file", content: "x"},
+		}, ErrUnsafePath},
+		{"link through link", Limits{MaxEntries: 10, MaxBytes: 100, Symlinks: SymlinksCreate}, []testEntry{
+			{name: "up", link: "here/.."}, {name: "here", link: "."},
+		}, ErrUnsafePath},
+	}
+
+	for _, tt := range tests {
+		for _, format := range Formats {
+			r := buildArchive(t, format, tt.entries...)
+			if _, err := Check(r, r.Size(), format, tt.limits); !errors.Is(err, tt.want) {
+				t.Errorf("%s (%s): expected %v, got: %v", tt.name, format, tt.want, err)
+			}
+		}
+	}
+}
+
+func TestCheck_Links(t *testing.T) {
+	create := Limits{MaxEntries: 10, MaxBytes: 100, Symlinks: SymlinksCreate}
+	for _, format := range Formats {
+		r := buildArchive(t, format,
+			testEntry{name: "releases/1/app", content: "v1"},
+			testEntry{name: "current", link: "releases/1"},
+			testEntry{name: "releases/1/up", link: "../.."},
+		)
+		stats, err := Check(r, r.Size(), format, create)
+		if err != nil || stats.Links != 2 || stats.Bytes != 2 {
+			t.Errorf("%s: expected links inside the tree to pass, got %+v, %v", format, stats, err)
+		}
+
+		r = buildArchive(t, format, testEntry{name: "passwd", link: "/etc/passwd"}, testEntry{name: "a", content: "x"})
+		stats, err = Check(r, r.Size(), format, testLimits)
+		if err != nil || stats.Links != 1 {
+			t.Errorf("%s: expected skipped links not to matter, got %+v, %v", format, stats, err)
+		}
+	}
+
+	r := buildArchive(t, TarGz, testEntry{name: "a", content: "x"}, testEntry{name: "b", link: "a", hardLink: true})
+	if _, err := Check(r, r.Size(), TarGz, Limits{MaxEntries: 10, MaxBytes: 100, Symlinks: SymlinksReject}); !errors.Is(err, ErrLinks) {
+		t.Errorf("Expected hard links to count as links, got: %v", err)
+	}
+}
+
+func TestNewReader_Invalid(t *testing.T) {
+	data := bytes.NewReader([]byte("not an archive"))
+	for _, format := range Formats {
+		if _, err := Check(data, data.Size(), format, testLimits); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got: %v", format, err)
+		}
+	}
+	if _, err := NewReader(data, data.Size(), "rar"); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat, got: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"livecode-api/internal/remotefs"
+)
+
+// chunkSize is how much of a file Pack reads between progress reports.
+const chunkSize = 4 << 20
+
+// Item is a file or directory of a remote tree.
+type Item struct {
+	// Name is the path relative to the tree's root.
+	Name string
+	Info fs.FileInfo
+}
+
+// List walks the tree under root depth first and in name order, leaving
+// out links and special files. More than maxEntries items is an
+// ErrTooManyEntries, which also ends walks that links lead in circles.
+func List(ctx context.Context, fsys remotefs.FS, root string, maxEntries int) ([]Item, error) {
+	var items []Item
+	var walk func(dir, prefix string) error
+	walk = func(dir, prefix string) error {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		infos, err := fsys.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(infos, func(a, b fs.FileInfo) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+
+		for _, info := range infos {
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				continue
+			}
+			if len(items) == maxEntries {
+				return ErrTooManyEntries
+			}
+			name := path.Join(prefix, info.Name())
+			items = append(items, Item{Name: name, Info: info})
+			if info.IsDir() {
+				if err := walk(path.Join(dir, info.Name()), name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(root, ""); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Packer writes listed items of a remote tree as an archive.
+type Packer struct {
+	FS   remotefs.FS
+	Root string
+	// Source, if set, wraps each file as it is read, such as to throttle
+	// it.
+	Source func(io.Reader) io.Reader
+	// Progress, if set, is called with the number of items written and the
+	// file bytes read so far, after each item and each chunk of a larger
+	// file. An error from it stops the archive.
+	Progress func(entries int, read int64) error
+}
+
+// Pack writes items to w as an archive in format. Files are archived with
+// the size they were listed with; one that has shrunk since is an
+// ErrChanged.
+func (p Packer) Pack(ctx context.Context, w io.Writer, format string, items []Item) error {
+	archive, err := newWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	var read int64
+	for i, item := range items {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		content, err := archive.create(item)
+		if err != nil {
+			return err
+		}
+		if item.Info.Mode().IsRegular() {
+			if err := p.copy(ctx, content, item, i, &read); err != nil {
+				return err
+			}
+		}
+		if p.Progress != nil {
+			if err := p.Progress(i+1, read); err != nil {
+				return err
+			}
+		}
+	}
+	return archive.close()
+}
+
+func (p Packer) copy(ctx context.Context, w io.Writer, item Item, done int, read *int64) error {
+	file, err := p.FS.Open(ctx, path.Join(p.Root, item.Name), 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	size := item.Info.Size()
+	var r io.Reader = file
+	if p.Source != nil {
+		r = p.Source(r)
+	}
+	for copied := int64(0); copied < size; {
+		n, err := io.CopyN(w, r, min(chunkSize, size-copied))
+		copied += n
+		*read += n
+		if err == io.EOF {
+			return ErrChanged
+		}
+		if err != nil {
+			return err
+		}
+		if p.Progress != nil && copied < size {
+			if err := p.Progress(done, *read); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type writer struct {
+	zip  *zip.Writer
+	tar  *tar.Writer
+	gzip *gzip.Writer
+}
+
+func newWriter(w io.Writer, format string) (*writer, error) {
+	switch format {
+	case Zip:
+		return &writer{zip: zip.NewWriter(w)}, nil
+	case TarGz:
+		compressed := gzip.NewWriter(w)
+		return &writer{tar: tar.NewWriter(compressed), gzip: compressed}, nil
+	}
+	return nil, ErrFormat
+}
+
+// create starts an entry for item, returning where its content goes.
+func (w *writer) create(item Item) (io.Writer, error) {
+	info := item.Info
+	if w.zip != nil {
+		header := &zip.FileHeader{Name: item.Name, Method: zip.Deflate, Modified: info.ModTime()}
+		header.SetMode(info.Mode())
+		if info.IsDir() {
+			header.Name += "/"
+			header.Method = zip.Store
+		}
+		return w.zip.CreateHeader(header)
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     item.Name,
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+	}
+	if info.IsDir() {
+		header.Typeflag = tar.TypeDir
+		header.Name += "/"
+		header.Size = 0
+	}
+	if err := w.tar.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	return w.tar, nil
+}
+
+func (w *writer) close() error {
+	if w.zip != nil {
+		return w.zip.Close()
+	}
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gzip.Close()
+}
@@ -0,0 +1,213 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// maxLinkTarget bounds how much of a zip entry is read as a link target.
+const maxLinkTarget = 4096
+
+// Entry is a member of an archive.
+type Entry struct {
+	// Name is a clean, relative, slash-separated path.
+	Name string
+	// Mode has ModeDir or ModeSymlink set for directories and symbolic
+	// links, no type bits for files and ModeIrregular for anything else,
+	// hard links included.
+	Mode    fs.FileMode
+	Size    int64
+	ModTime time.Time
+	// Link is the target of a symbolic or hard link.
+	Link string
+}
+
+func (e *Entry) IsLink() bool {
+	return e.Mode&fs.ModeSymlink != 0 || e.Link != ""
+}
+
+// Reader reads an archive's entries in order. Reading it returns the
+// content of the current file.
+type Reader struct {
+	zip    []*zip.File
+	next   int
+	tar    *tar.Reader
+	count  *countingReader
+	data   io.ReadCloser
+	offset int64
+}
+
+// NewReader reads an archive of size bytes in format from r.
+func NewReader(r io.ReaderAt, size int64, format string) (*Reader, error) {
+	switch format {
+	case Zip:
+		// Names are checked by Next, whatever zipinsecurepath says.
+		archive, err := zip.NewReader(r, size)
+		if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+			return nil, invalid(err)
+		}
+		return &Reader{zip: archive.File}, nil
+	case TarGz:
+		count := &countingReader{r: io.NewSectionReader(r, 0, size)}
+		decompressed, err := gzip.NewReader(count)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return &Reader{tar: tar.NewReader(decompressed), count: count}, nil
+	}
+	return nil, ErrFormat
+}
+
+// Next moves to the next entry, returning io.EOF after the last. An entry
+// whose name leaves the archive's directory is an ErrUnsafePath.
+func (r *Reader) Next() (*Entry, error) {
+	if r.data != nil {
+		r.data.Close()
+		r.data = nil
+	}
+	for {
+		var entry *Entry
+		var err error
+		if r.tar != nil {
+			entry, err = r.nextTar()
+		} else {
+			entry, err = r.nextZip()
+		}
+		if entry != nil || err != nil {
+			return entry, err
+		}
+	}
+}
+
+// Offset reports how far into the archive reading has got, for progress.
+// It is the same for the same entry every time the archive is read.
+func (r *Reader) Offset() int64 {
+	if r.count != nil {
+		return r.count.n
+	}
+	return r.offset
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.data == nil {
+		return 0, io.EOF
+	}
+	n, err := r.data.Read(p)
+	if err != nil && err != io.EOF {
+		err = invalid(err)
+	}
+	return n, err
+}
+
+func (r *Reader) nextZip() (*Entry, error) {
+	if r.next == len(r.zip) {
+		return nil, io.EOF
+	}
+	file := r.zip[r.next]
+	r.next++
+	if offset, err := file.DataOffset(); err == nil {
+		r.offset = offset + int64(file.CompressedSize64)
+	}
+
+	name, ok := cleanName(file.Name)
+	if !ok {
+		return nil, unsafePath(file.Name)
+	}
+	if name == "." {
+		return nil, nil
+	}
+
+	mode := file.Mode()
+	entry := &Entry{Name: name, Mode: mode, ModTime: file.Modified}
+	switch {
+	case mode.IsDir():
+		return entry, nil
+	case mode.IsRegular(), mode&fs.ModeSymlink != 0:
+	default:
+		entry.Mode = fs.ModeIrregular | mode.Perm()
+		return entry, nil
+	}
+
+	data, err := file.Open()
+	if err != nil {
+		return nil, invalid(err)
+	}
+	if mode.IsRegular() {
+		entry.Size = int64(file.UncompressedSize64)
+		r.data = data
+		return entry, nil
+	}
+
+	defer data.Close()
+	target, err := io.ReadAll(io.LimitReader(data, maxLinkTarget+1))
+	if err != nil {
+		return nil, invalid(err)
+	}
+	if len(target) > maxLinkTarget {
+		return nil, invalid(errors.New("link target of " + name + " is too long"))
+	}
+	entry.Link = string(target)
+	return entry, nil
+}
+
+func (r *Reader) nextTar() (*Entry, error) {
+	header, err := r.tar.Next()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil && !(header != nil && errors.Is(err, tar.ErrInsecurePath)) {
+		return nil, invalid(err)
+	}
+	if header.Typeflag == tar.TypeXGlobalHeader {
+		return nil, nil
+	}
+
+	name, ok := cleanName(header.Name)
+	if !ok {
+		return nil, unsafePath(header.Name)
+	}
+	if name == "." {
+		return nil, nil
+	}
+
+	perm := fs.FileMode(header.Mode).Perm()
+	entry := &Entry{Name: name, ModTime: header.ModTime}
+	switch header.Typeflag {
+	case tar.TypeReg:
+		entry.Mode = perm
+		entry.Size = header.Size
+		r.data = io.NopCloser(r.tar)
+	case tar.TypeDir:
+		entry.Mode = fs.ModeDir | perm
+	case tar.TypeSymlink:
+		entry.Mode = fs.ModeSymlink | perm
+		entry.Link = header.Linkname
+	case tar.TypeLink:
+		entry.Mode = fs.ModeIrregular | perm
+		entry.Link = header.Linkname
+	default:
+		entry.Mode = fs.ModeIrregular | perm
+	}
+	return entry, nil
+}
+
+func invalid(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalid, err)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
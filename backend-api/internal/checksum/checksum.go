@@ -0,0 +1,105 @@
+// Package checksum computes the digests transfers are verified with, and
+// the remote commands that compute the same digests on an SSH server.
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/blake3"
+)
+
+const (
+	SHA256 = "sha256"
+	BLAKE3 = "blake3"
+	XXH64  = "xxh64"
+)
+
+var Algorithms = []string{SHA256, BLAKE3, XXH64}
+
+var ErrUnknownAlgorithm = errors.New("unknown checksum algorithm")
+
+// New returns a hash for algorithm.
+func New(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case SHA256:
+		return sha256.New(), nil
+	case BLAKE3:
+		return blake3.New(), nil
+	case XXH64:
+		return xxhash.New(), nil
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// Size is the length of algorithm's hex digest, or 0 if it is unknown.
+func Size(algorithm string) int {
+	h, err := New(algorithm)
+	if err != nil {
+		return 0
+	}
+	return h.Size() * 2
+}
+
+// Sum returns the hex digest of r.
+func Sum(algorithm string, r io.Reader) (string, error) {
+	h, err := New(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return Hex(h), nil
+}
+
+func Hex(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Command is the shell command that prints algorithm's digest of name, in
+// the "digest  name" format of coreutils.
+func Command(algorithm, name string) (string, bool) {
+	var tool string
+	switch algorithm {
+	case SHA256:
+		tool = "sha256sum"
+	case BLAKE3:
+		tool = "b3sum"
+	case XXH64:
+		tool = "xxhsum -H1"
+	default:
+		return "", false
+	}
+	return tool + " -- " + quote(name), true
+}
+
+// ParseOutput reads the digest from the output of Command, reporting false
+// if it does not look like one.
+func ParseOutput(algorithm string, output []byte) (string, bool) {
+	line, _, _ := strings.Cut(string(output), "\n")
+	// coreutils escapes names with a leading backslash.
+	fields := strings.Fields(strings.TrimPrefix(line, `\`))
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	digest := strings.ToLower(fields[0])
+	if len(digest) != Size(algorithm) {
+		return "", false
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", false
+	}
+	return digest, true
+}
+
+// quote makes s a single POSIX shell word.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,64 @@
+package checksum
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSum_KnownDigests(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{SHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{BLAKE3, "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+		{XXH64, "44bc2cf5ad770999"},
+	}
+
+	for _, tt := range tests {
+		got, err := Sum(tt.algorithm, strings.NewReader("abc"))
+		if err != nil || got != tt.want {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.algorithm, tt.want, got, err)
+		}
+		if Size(tt.algorithm) != len(tt.want) {
+			t.Errorf("%s: expected size %d, got %d", tt.algorithm, len(tt.want), Size(tt.algorithm))
+		}
+	}
+
+	if _, err := Sum("md5", strings.NewReader("abc")); err != ErrUnknownAlgorithm {
+		t.Errorf("Expected ErrUnknownAlgorithm, got: %v", err)
+	}
+}
+
+func TestCommand_QuotesName(t *testing.T) {
+	got, ok := Command(SHA256, "/srv/it's here.txt")
+	if !ok || got != `sha256sum -- '/srv/it'\''s here.txt'` {
+		t.Errorf("Unexpected command: %q", got)
+	}
+	if _, ok := Command("md5", "x"); ok {
+		t.Error("Expected no command for an unknown algorithm")
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		output string
+		want   string
+		ok     bool
+	}{
+		{digest + "  /srv/app.tar\n", digest, true},
+		{`\` + strings.ToUpper(digest) + "  /srv/a\\nb\n", digest, true},
+		{"sha256sum: /srv/app.tar: No such file or directory\n", "", false},
+		{"abcd  /srv/app.tar\n", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseOutput(SHA256, []byte(tt.output))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseOutput(%q): expected %q %v, got %q %v", tt.output, tt.want, tt.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package emails
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed disposable.txt
+var disposableDomains string
+
+// DomainList is a set of domains that also covers their subdomains.
+type DomainList struct {
+	domains map[string]bool
+}
+
+func NewDomainList(domains ...string) *DomainList {
+	list := &DomainList{domains: map[string]bool{}}
+	list.Add(domains...)
+	return list
+}
+
+// DefaultDisposable returns the built-in list of disposable mail providers.
+func DefaultDisposable() *DomainList {
+	list := NewDomainList()
+	_ = list.read(strings.NewReader(disposableDomains))
+	return list
+}
+
+func (l *DomainList) Add(domains ...string) {
+	for _, domain := range domains {
+		domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+		if ascii, err := Normalize("x@" + domain); err == nil {
+			l.domains[ascii[2:]] = true
+		}
+	}
+}
+
+// LoadFile adds one domain per line from path. Blank lines and lines
+// starting with # are skipped.
+func (l *DomainList) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return l.read(file)
+}
+
+func (l *DomainList) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l.Add(line)
+	}
+	return scanner.Err()
+}
+
+// Contains reports whether domain, an IDNA ASCII domain, or any domain it
+// is a subdomain of is listed.
+func (l *DomainList) Contains(domain string) bool {
+	if l == nil {
+		return false
+	}
+	for {
+		if l.domains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+		domain = parent
+	}
+}
+
+func (l *DomainList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.domains)
+}
@@ -0,0 +1,127 @@
+package emails
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/models"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	MaxLength      = 255
+	maxLocalLength = 64
+)
+
+var ErrInvalid = errors.New("invalid email address")
+
+// Policy is the per-deployment email policy.
+type Policy struct {
+	// ProviderRules enables Canonical's provider-specific rules. Without
+	// them the canonical address is the normalised one.
+	ProviderRules bool
+	Disposable    *DomainList
+	// Resolver, when set, rejects domains that cannot receive mail.
+	Resolver      Resolver
+	LookupTimeout time.Duration
+}
+
+func DefaultPolicy() *Policy {
+	return &Policy{
+		ProviderRules: true,
+		Disposable:    DefaultDisposable(),
+		LookupTimeout: 3 * time.Second,
+	}
+}
+
+// Normalize trims address, lower-cases it and converts the domain to its
+// IDNA ASCII form, so "Ada@Bücher.example" is stored as
+// "ada@xn--bcher-kva.example".
+func Normalize(address string) (string, error) {
+	address = strings.TrimSpace(address)
+
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return "", ErrInvalid
+	}
+	local, domain := address[:at], strings.TrimSuffix(address[at+1:], ".")
+
+	if !validLocal(local) || !strings.Contains(domain, ".") {
+		return "", ErrInvalid
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	return strings.ToLower(local) + "@" + ascii, nil
+}
+
+// validLocal accepts the dot-atom local parts people actually use, including
+// non-ASCII ones, and rejects quoting, whitespace and control characters.
+func validLocal(local string) bool {
+	if local == "" || len(local) > maxLocalLength {
+		return false
+	}
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return false
+	}
+	for _, r := range local {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`"(),:;<>@[\]`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonical maps a normalised address to the mailbox it delivers to, so
+// "john.smith+spam@googlemail.com" and "johnsmith@gmail.com" are one
+// account.
+func (p *Policy) Canonical(address string) string {
+	if !p.ProviderRules {
+		return address
+	}
+	return canonical(address)
+}
+
+// Check validates a raw address and returns it normalised. A failing MX
+// lookup is returned as err alongside the other results, so callers can
+// decide whether to fail open.
+func (p *Policy) Check(ctx context.Context, address string) (string, []models.FieldError, error) {
+	if len(strings.TrimSpace(address)) > MaxLength {
+		return address, []models.FieldError{apierror.Field("email", apierror.FieldTooLong, map[string]any{"max": MaxLength})}, nil
+	}
+
+	normalized, err := Normalize(address)
+	if err != nil || len(normalized) > MaxLength {
+		return strings.TrimSpace(strings.ToLower(address)), []models.FieldError{apierror.Field("email", apierror.FieldInvalidEmail, nil)}, nil
+	}
+
+	domain := normalized[strings.LastIndex(normalized, "@")+1:]
+	if p.Disposable.Contains(domain) {
+		return normalized, []models.FieldError{apierror.Field("email", apierror.FieldEmailDisposable, nil)}, nil
+	}
+
+	if p.Resolver == nil {
+		return normalized, nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.LookupTimeout)
+	defer cancel()
+
+	deliverable, err := acceptsMail(ctx, p.Resolver, domain)
+	if err != nil {
+		return normalized, nil, err
+	}
+	if !deliverable {
+		return normalized, []models.FieldError{apierror.Field("email", apierror.FieldEmailUndeliverable, nil)}, nil
+	}
+
+	return normalized, nil, nil
+}
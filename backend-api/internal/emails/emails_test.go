@@ -0,0 +1,92 @@
+package emails
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"livecode-api/internal/apierror"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		" John+Spam@Gmail.com ": "john+spam@gmail.com",
+		"ada@Bücher.example":    "ada@xn--bcher-kva.example",
+		"ada@example.com.":      "ada@example.com",
+	}
+	for input, expected := range cases {
+		got, err := Normalize(input)
+		if err != nil || got != expected {
+			t.Errorf("Normalize(%q): expected %q, got: %q, %v", input, expected, got, err)
+		}
+	}
+
+	for _, invalid := range []string{"ada", "ada@localhost", "@example.com", ".ada@example.com", "a..da@example.com", "a da@example.com", "ada@exa mple.com", "ada@-example.com"} {
+		if _, err := Normalize(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	policy := DefaultPolicy()
+	cases := map[string]string{
+		"john.smith+spam@gmail.com": "johnsmith@gmail.com",
+		"johnsmith@googlemail.com":  "johnsmith@gmail.com",
+		"ada+livecode@outlook.com":  "ada@outlook.com",
+		"ada.lovelace@outlook.com":  "ada.lovelace@outlook.com",
+		"ada.l+tag@example.com":     "ada.l+tag@example.com",
+		"+only-a-tag@gmail.com":     "+only-a-tag@gmail.com",
+	}
+	for input, expected := range cases {
+		if got := policy.Canonical(input); got != expected {
+			t.Errorf("Canonical(%q): expected %q, got: %q", input, expected, got)
+		}
+	}
+
+	policy.ProviderRules = false
+	if got := policy.Canonical("john.smith+spam@gmail.com"); got != "john.smith+spam@gmail.com" {
+		t.Errorf("Expected provider rules to be off, got: %q", got)
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Disposable.Add("throwaway.example")
+	policy.Resolver = StaticResolver{
+		MX: map[string][]*net.MX{
+			"example.com":    {{Host: "mx.example.com.", Pref: 10}},
+			"nomail.example": {{Host: ".", Pref: 0}},
+		},
+		Hosts: map[string][]string{
+			"implicit.example": {"192.0.2.1"},
+		},
+	}
+
+	cases := []struct {
+		address string
+		code    apierror.Code
+	}{
+		{"Ada@Example.com", ""},
+		{"ada@implicit.example", ""},
+		{"ada", apierror.FieldInvalidEmail},
+		{"ada@mailinator.com", apierror.FieldEmailDisposable},
+		{"ada@inbox.mailinator.com", apierror.FieldEmailDisposable},
+		{"ada@throwaway.example", apierror.FieldEmailDisposable},
+		{"ada@nomail.example", apierror.FieldEmailUndeliverable},
+		{"ada@unknown.example", apierror.FieldEmailUndeliverable},
+	}
+
+	for _, tc := range cases {
+		_, errors, err := policy.Check(context.Background(), tc.address)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.address, err)
+		}
+		switch {
+		case tc.code == "" && len(errors) > 0:
+			t.Errorf("%s: expected to pass, got: %v", tc.address, errors)
+		case tc.code != "" && (len(errors) != 1 || errors[0].Code != string(tc.code)):
+			t.Errorf("%s: expected %s, got: %v", tc.address, tc.code, errors)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package emails
+
+import "strings"
+
+type providerRule struct {
+	// domain replaces aliases of the provider's main domain.
+	domain    string
+	stripTag  bool
+	stripDots bool
+}
+
+// providers lists mailbox providers whose addressing rules are documented:
+// Gmail ignores dots and everything after +, the others only support +
+// subaddressing. migrations/000008 applies the same rules in SQL to
+// addresses registered before it.
+var providers = map[string]providerRule{
+	"gmail.com":      {domain: "gmail.com", stripTag: true, stripDots: true},
+	"googlemail.com": {domain: "gmail.com", stripTag: true, stripDots: true},
+
+	"outlook.com":    {stripTag: true},
+	"hotmail.com":    {stripTag: true},
+	"live.com":       {stripTag: true},
+	"icloud.com":     {stripTag: true},
+	"me.com":         {stripTag: true},
+	"fastmail.com":   {stripTag: true},
+	"proton.me":      {stripTag: true},
+	"protonmail.com": {stripTag: true},
+}
+
+func canonical(address string) string {
+	at := strings.LastIndex(address, "@")
+	local, domain := address[:at], address[at+1:]
+
+	rule, ok := providers[domain]
+	if !ok {
+		return address
+	}
+
+	if rule.stripTag {
+		local, _, _ = strings.Cut(local, "+")
+	}
+	if rule.stripDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if local == "" {
+		return address
+	}
+	if rule.domain != "" {
+		domain = rule.domain
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,64 @@
+package emails
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// Resolver is the subset of *net.Resolver the MX check needs.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// acceptsMail reports whether domain publishes a mail exchanger, or failing
+// that an address record as the implicit MX of RFC 5321. A null MX (RFC 7505)
+// means the domain accepts no mail.
+func acceptsMail(ctx context.Context, resolver Resolver, domain string) (bool, error) {
+	records, err := resolver.LookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+			return false, nil
+		}
+		return true, nil
+	}
+	if err != nil && !notFound(err) {
+		return false, err
+	}
+
+	hosts, err := resolver.LookupHost(ctx, domain)
+	if err != nil {
+		if notFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(hosts) > 0, nil
+}
+
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// StaticResolver answers lookups from fixed records, for tests and offline
+// deployments. Unlisted domains are not found.
+type StaticResolver struct {
+	MX    map[string][]*net.MX
+	Hosts map[string][]string
+}
+
+func (r StaticResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if records, ok := r.MX[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r StaticResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if hosts, ok := r.Hosts[host]; ok {
+		return hosts, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
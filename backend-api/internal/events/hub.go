@@ -0,0 +1,312 @@
+// Package events fans per-user events out to live streams. Recent events
+// are kept in a bounded buffer per user, so a client that reconnects can
+// pick up from the last event it saw. With a PostgresRelay, events reach
+// the streams on every server, not only the one that published them.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"livecode-api/middleware"
+
+	"go.uber.org/zap"
+)
+
+const (
+	TypeTransferProgress = "transfer.progress"
+	TypeTransferStatus   = "transfer.status"
+	TypeScheduleRun      = "schedule.run"
+	TypeSessionRevoked   = "session.revoked"
+	TypeNotification     = "notification"
+	// TypeReset tells a client that events it asked to replay are gone, so
+	// it should reload its state.
+	TypeReset = "stream.reset"
+)
+
+var (
+	ErrTooManySubscribers = errors.New("too many event streams for this user")
+	ErrClosed             = errors.New("event hub closed")
+)
+
+type Event struct {
+	ID   uint64
+	Type string
+	Data json.RawMessage
+}
+
+type Config struct {
+	// BufferSize is how many recent events are kept per user for replay.
+	BufferSize int
+	// SubscriberBuffer is how many events may wait for a slow stream before
+	// it is dropped. The client then reconnects and replays.
+	SubscriberBuffer int
+	MaxSubscribers   int
+	// Retention is how long events are kept for users without a stream.
+	Retention time.Duration
+}
+
+type userEvents struct {
+	buffer []Event
+	// dropped is the newest event no longer in buffer.
+	dropped uint64
+	subs    map[*Subscription]struct{}
+	touched time.Time
+}
+
+type Hub struct {
+	cfg Config
+
+	mu     sync.Mutex
+	start  uint64
+	seq    uint64
+	users  map[string]*userEvents
+	swept  uint64
+	closed bool
+	// relay, once set, carries published events to every hub, and numbers
+	// them instead of seq.
+	relay relay
+
+	stop chan struct{}
+}
+
+func NewHub(cfg Config) *Hub {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 256
+	}
+	if cfg.SubscriberBuffer <= 0 {
+		cfg.SubscriberBuffer = 64
+	}
+	if cfg.MaxSubscribers <= 0 {
+		cfg.MaxSubscribers = 8
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 10 * time.Minute
+	}
+
+	// IDs continue from the clock, so IDs handed out before a restart are
+	// recognised as older than anything this hub has.
+	start := uint64(time.Now().UnixMicro())
+	h := &Hub{
+		cfg:   cfg,
+		start: start,
+		seq:   start,
+		users: map[string]*userEvents{},
+		stop:  make(chan struct{}),
+	}
+	go h.sweep()
+	return h
+}
+
+// Publish sends an event to the user's streams and keeps it for replay.
+// It does nothing on a nil Hub, so subsystems work without one.
+func (h *Hub) Publish(userID, eventType string, data any) {
+	if h == nil || userID == "" {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		middleware.Logger.Error("event_encode_failed",
+			zap.String("type", eventType),
+			zap.Error(err),
+		)
+		return
+	}
+
+	h.mu.Lock()
+	relay := h.relay
+	if relay == nil && !h.closed {
+		h.seq++
+		h.deliver(userID, Event{ID: h.seq, Type: eventType, Data: payload})
+	}
+	h.mu.Unlock()
+
+	if relay == nil {
+		return
+	}
+	if err := relay.send(userID, eventType, payload); err != nil {
+		middleware.Logger.Error("event_relay_failed",
+			zap.String("type", eventType),
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+	}
+}
+
+// receive delivers an event numbered by the relay.
+func (h *Hub) receive(userID string, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.seq = max(h.seq, event.ID)
+	h.deliver(userID, event)
+}
+
+// resume starts relayed numbering after last, the newest event this hub
+// may have missed. Open streams are ended so their clients replay, and
+// get a reset for the events that did not reach this hub.
+func (h *Hub) resume(r relay, last uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.relay = r
+	h.start = last
+	h.seq = last
+	for _, user := range h.users {
+		for sub := range user.subs {
+			h.remove(sub)
+		}
+	}
+}
+
+func (h *Hub) deliver(userID string, event Event) {
+	user := h.user(userID)
+	user.touched = time.Now()
+	if len(user.buffer) == h.cfg.BufferSize {
+		user.dropped = user.buffer[0].ID
+		user.buffer = append(user.buffer[:0], user.buffer[1:]...)
+	}
+	user.buffer = append(user.buffer, event)
+
+	for sub := range user.subs {
+		select {
+		case sub.events <- event:
+		default:
+			middleware.Logger.Warn("event_stream_dropped",
+				zap.String("user_id", userID),
+			)
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe opens a stream of the user's events. With a lastEventID it
+// also returns the buffered events after it, or a TypeReset event if some
+// of them are no longer available.
+func (h *Hub) Subscribe(userID, lastEventID string) (*Subscription, []Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, ErrClosed
+	}
+
+	user := h.user(userID)
+	if len(user.subs) >= h.cfg.MaxSubscribers {
+		return nil, nil, ErrTooManySubscribers
+	}
+
+	var replay []Event
+	if lastEventID != "" {
+		last, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil || last < h.start || last < user.dropped || last > h.seq {
+			replay = []Event{{ID: h.seq, Type: TypeReset, Data: json.RawMessage("{}")}}
+		} else {
+			for _, event := range user.buffer {
+				if event.ID > last {
+					replay = append(replay, event)
+				}
+			}
+		}
+	}
+
+	sub := &Subscription{
+		hub:    h,
+		userID: userID,
+		events: make(chan Event, h.cfg.SubscriberBuffer),
+	}
+	user.subs[sub] = struct{}{}
+	user.touched = time.Now()
+	return sub, replay, nil
+}
+
+// Close ends every stream and stops accepting new ones.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	close(h.stop)
+
+	for _, user := range h.users {
+		for sub := range user.subs {
+			h.remove(sub)
+		}
+	}
+}
+
+func (h *Hub) user(userID string) *userEvents {
+	user, ok := h.users[userID]
+	if !ok {
+		// Events of an earlier entry for this user may have been swept.
+		user = &userEvents{dropped: h.swept, subs: map[*Subscription]struct{}{}}
+		h.users[userID] = user
+	}
+	return user
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	user := h.users[sub.userID]
+	if _, ok := user.subs[sub]; !ok {
+		return
+	}
+	delete(user.subs, sub)
+	user.touched = time.Now()
+	close(sub.events)
+}
+
+// sweep forgets the events of users who have had no stream for longer
+// than the retention period.
+func (h *Hub) sweep() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
+		cutoff := time.Now().Add(-h.cfg.Retention)
+		h.mu.Lock()
+		for userID, user := range h.users {
+			if len(user.subs) > 0 || user.touched.After(cutoff) {
+				continue
+			}
+			if n := len(user.buffer); n > 0 {
+				h.swept = max(h.swept, user.buffer[n-1].ID)
+			}
+			delete(h.users, userID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+type Subscription struct {
+	hub    *Hub
+	userID string
+	events chan Event
+}
+
+// Events delivers the stream's events. It is closed when the stream falls
+// too far behind or the hub closes.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"livecode-api/middleware"
+
+	"go.uber.org/zap"
+)
+
+func newTestHub(t *testing.T, cfg Config) *Hub {
+	middleware.Logger = zap.NewNop()
+	hub := NewHub(cfg)
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+func id(event Event) string {
+	return strconv.FormatUint(event.ID, 10)
+}
+
+func TestHub_FansOutPerUser(t *testing.T) {
+	hub := newTestHub(t, Config{})
+
+	first, _, _ := hub.Subscribe("u1", "")
+	second, _, _ := hub.Subscribe("u1", "")
+	other, _, _ := hub.Subscribe("u2", "")
+
+	hub.Publish("u1", TypeNotification, map[string]string{"kind": "test"})
+
+	for _, sub := range []*Subscription{first, second} {
+		event := <-sub.Events()
+		if event.Type != TypeNotification || string(event.Data) != `{"kind":"test"}` {
+			t.Errorf("Unexpected event: %s %s", event.Type, event.Data)
+		}
+	}
+	select {
+	case event := <-other.Events():
+		t.Errorf("Expected no event for another user, got %s", event.Type)
+	default:
+	}
+}
+
+func TestHub_ReplaysAfterLastEventID(t *testing.T) {
+	hub := newTestHub(t, Config{BufferSize: 3})
+
+	sub, _, _ := hub.Subscribe("u1", "")
+	for i := range 5 {
+		hub.Publish("u1", TypeTransferProgress, i)
+	}
+	var seen []Event
+	for range 5 {
+		seen = append(seen, <-sub.Events())
+	}
+	sub.Close()
+
+	_, replay, _ := hub.Subscribe("u1", id(seen[2]))
+	if len(replay) != 2 || replay[0].ID != seen[3].ID || replay[1].ID != seen[4].ID {
+		t.Errorf("Expected the two events after the third, got %v", replay)
+	}
+
+	_, replay, _ = hub.Subscribe("u1", id(seen[4]))
+	if len(replay) != 0 {
+		t.Errorf("Expected nothing to replay when up to date, got %v", replay)
+	}
+
+	for _, lastID := range []string{id(seen[0]), "1", "not-a-number", "99999999999999999"} {
+		_, replay, _ := hub.Subscribe("u1", lastID)
+		if len(replay) != 1 || replay[0].Type != TypeReset {
+			t.Errorf("Expected a reset for Last-Event-ID %q, got %v", lastID, replay)
+		}
+	}
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	hub := newTestHub(t, Config{SubscriberBuffer: 2})
+
+	sub, _, _ := hub.Subscribe("u1", "")
+	for i := range 3 {
+		hub.Publish("u1", TypeTransferProgress, i)
+	}
+
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("Expected the buffered events before the stream closed, got %d", received)
+	}
+}
+
+func TestHub_LimitsSubscribersAndCloses(t *testing.T) {
+	hub := newTestHub(t, Config{MaxSubscribers: 2})
+
+	first, _, _ := hub.Subscribe("u1", "")
+	hub.Subscribe("u1", "")
+	if _, _, err := hub.Subscribe("u1", ""); err != ErrTooManySubscribers {
+		t.Errorf("Expected ErrTooManySubscribers, got %v", err)
+	}
+
+	first.Close()
+	first.Close()
+	if _, _, err := hub.Subscribe("u1", ""); err != nil {
+		t.Errorf("Expected a free slot after closing a stream, got %v", err)
+	}
+
+	hub.Close()
+	if _, ok := <-first.Events(); ok {
+		t.Error("Expected streams to be closed with the hub")
+	}
+	if _, _, err := hub.Subscribe("u1", ""); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	hub.Publish("u1", TypeNotification, nil)
+}
+
+func TestHub_NilPublishIsNoop(t *testing.T) {
+	var hub *Hub
+	hub.Publish("u1", TypeNotification, nil)
+}
+
+// fanout stands in for Postgres: it numbers events and hands them to
+// every hub.
+type fanout struct {
+	seq  uint64
+	hubs []*Hub
+}
+
+func (f *fanout) send(userID, eventType string, data json.RawMessage) error {
+	f.seq++
+	for _, hub := range f.hubs {
+		hub.receive(userID, Event{ID: f.seq, Type: eventType, Data: data})
+	}
+	return nil
+}
+
+func TestHub_RelaysBetweenServers(t *testing.T) {
+	relay := &fanout{seq: 41}
+	first, second := newTestHub(t, Config{}), newTestHub(t, Config{})
+	relay.hubs = []*Hub{first, second}
+	first.resume(relay, relay.seq)
+	second.resume(relay, relay.seq)
+
+	sub, _, _ := second.Subscribe("u1", "")
+	first.Publish("u1", TypeTransferStatus, "done")
+	first.Publish("u1", TypeTransferStatus, "queued")
+
+	event := <-sub.Events()
+	if event.ID != 42 || string(event.Data) != `"done"` {
+		t.Errorf("Expected the event published on the other server, got %d %s", event.ID, event.Data)
+	}
+
+	_, replay, _ := first.Subscribe("u1", "42")
+	if len(replay) != 1 || replay[0].ID != 43 {
+		t.Errorf("Expected replay on either server, got %v", replay)
+	}
+
+	// A reconnected listener may have missed events, so open streams end
+	// and older IDs get a reset.
+	relay.seq = 50
+	second.resume(relay, relay.seq)
+	for range sub.Events() {
+	}
+	_, replay, _ = second.Subscribe("u1", "43")
+	if len(replay) != 1 || replay[0].Type != TypeReset {
+		t.Errorf("Expected a reset for events from before the resume, got %v", replay)
+	}
+}
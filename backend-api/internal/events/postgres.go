@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"livecode-api/middleware"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const relayChannel = "livecode_events"
+
+// maxRelayedData keeps a notification under Postgres' 8000 byte limit.
+const maxRelayedData = 7000
+
+var ErrEventTooLarge = errors.New("event too large to relay")
+
+type relay interface {
+	send(userID, eventType string, data json.RawMessage) error
+}
+
+type relayedEvent struct {
+	ID     uint64          `json:"id"`
+	UserID string          `json:"user_id"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// PostgresRelay carries events between servers with LISTEN/NOTIFY. Event
+// IDs come from a shared sequence, so a client can replay from any server.
+type PostgresRelay struct {
+	db          *sql.DB
+	databaseURL string
+	hub         *Hub
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartPostgresRelay listens for events on a connection of its own and
+// hands publishing on hub over to the relay. It reconnects in the
+// background if the connection is lost.
+func StartPostgresRelay(ctx context.Context, db *sql.DB, databaseURL string, hub *Hub) (*PostgresRelay, error) {
+	r := &PostgresRelay{db: db, databaseURL: databaseURL, hub: hub, done: make(chan struct{})}
+
+	conn, err := r.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go r.run(runCtx, conn)
+	return r, nil
+}
+
+func (r *PostgresRelay) send(userID, eventType string, data json.RawMessage) error {
+	if len(data) > maxRelayedData {
+		return ErrEventTooLarge
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `
+		SELECT pg_notify($1, json_build_object(
+			'id', nextval('event_ids'), 'user_id', $2::text, 'type', $3::text, 'data', $4::json
+		)::text)`,
+		relayChannel, userID, eventType, string(data),
+	)
+	return err
+}
+
+// listen connects and resumes the hub after the last event it may have
+// missed.
+func (r *PostgresRelay) listen(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, r.databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect event listener: %w", err)
+	}
+
+	var last uint64
+	if _, err = conn.Exec(ctx, "LISTEN "+relayChannel); err == nil {
+		err = conn.QueryRow(ctx,
+			"SELECT CASE WHEN is_called THEN last_value ELSE last_value - 1 END FROM event_ids",
+		).Scan(&last)
+	}
+	if err != nil {
+		conn.Close(context.Background())
+		return nil, fmt.Errorf("listen for events: %w", err)
+	}
+
+	r.hub.resume(r, last)
+	return conn, nil
+}
+
+func (r *PostgresRelay) run(ctx context.Context, conn *pgx.Conn) {
+	defer close(r.done)
+
+	backoff := time.Second
+	for {
+		if conn != nil {
+			err := r.receive(ctx, conn)
+			conn.Close(context.Background())
+			if ctx.Err() != nil {
+				return
+			}
+			middleware.Logger.Warn("event_listener_disconnected",
+				zap.Error(err),
+			)
+			backoff = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		var err error
+		if conn, err = r.listen(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			middleware.Logger.Warn("event_listener_reconnect_failed",
+				zap.Duration("retry_in", backoff),
+				zap.Error(err),
+			)
+			backoff = min(2*backoff, 30*time.Second)
+		}
+	}
+}
+
+func (r *PostgresRelay) receive(ctx context.Context, conn *pgx.Conn) error {
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event relayedEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			middleware.Logger.Error("event_relay_decode_failed",
+				zap.Error(err),
+			)
+			continue
+		}
+		r.hub.receive(event.UserID, Event{ID: event.ID, Type: event.Type, Data: event.Data})
+	}
+}
+
+// Close stops listening. Events published afterwards are still sent to
+// the other servers.
+func (r *PostgresRelay) Close(ctx context.Context) error {
+	r.cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
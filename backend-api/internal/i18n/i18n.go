@@ -0,0 +1,264 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	DefaultLocale = "en"
+
+	// ContextKey is the gin context key holding the negotiated locale.
+	ContextKey = "locale"
+)
+
+//go:embed locales/*.json
+var embedded embed.FS
+
+var (
+	placeholderRegex = regexp.MustCompile(`\{([a-z_]+)\}`)
+	defaultCatalog   = mustLoad(embedded)
+)
+
+// Message is a catalogue entry. Entries without Plural have a single form in
+// Other; plural entries pick a CLDR category from the numeric parameter named
+// by Plural, so clients can select the same form with Intl.PluralRules.
+type Message struct {
+	Plural string `json:"plural,omitempty"`
+	One    string `json:"one,omitempty"`
+	Few    string `json:"few,omitempty"`
+	Other  string `json:"other"`
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*m = Message{Other: text}
+		return nil
+	}
+
+	type plain Message
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+func (m Message) form(category string) string {
+	switch {
+	case category == "one" && m.One != "":
+		return m.One
+	case category == "few" && m.Few != "":
+		return m.Few
+	default:
+		return m.Other
+	}
+}
+
+type CatalogResponse struct {
+	Success  bool               `json:"success"`
+	Locale   string             `json:"locale"`
+	Locales  []string           `json:"locales"`
+	Messages map[string]Message `json:"messages"`
+}
+
+type CatalogQuery struct {
+	Locale string `form:"locale" binding:"omitempty,max=35" description:"BCP 47 language tag; defaults to the Accept-Language header"`
+}
+
+type Catalog struct {
+	locales  []string
+	messages map[string]map[string]Message
+	matcher  language.Matcher
+}
+
+func Default() *Catalog {
+	return defaultCatalog
+}
+
+// Load reads one <locale>.json file per locale from the locales directory of
+// fsys. The default locale must be present; it is the fallback for every key.
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{messages: map[string]map[string]Message{}}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		var messages map[string]Message
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+
+		locale := strings.TrimSuffix(path.Base(file), ".json")
+		if _, err := language.Parse(locale); err != nil {
+			return nil, fmt.Errorf("invalid locale %s: %w", locale, err)
+		}
+		catalog.messages[locale] = messages
+	}
+
+	if _, ok := catalog.messages[DefaultLocale]; !ok {
+		return nil, errors.New("default locale catalogue is missing")
+	}
+
+	catalog.locales = []string{DefaultLocale}
+	for locale := range catalog.messages {
+		if locale != DefaultLocale {
+			catalog.locales = append(catalog.locales, locale)
+		}
+	}
+	sort.Strings(catalog.locales[1:])
+
+	tags := make([]language.Tag, 0, len(catalog.locales))
+	for _, locale := range catalog.locales {
+		tags = append(tags, language.MustParse(locale))
+	}
+	catalog.matcher = language.NewMatcher(tags)
+
+	return catalog, nil
+}
+
+func mustLoad(fsys fs.FS) *Catalog {
+	catalog, err := Load(fsys)
+	if err != nil {
+		panic("i18n: " + err.Error())
+	}
+	return catalog
+}
+
+func (c *Catalog) Locales() []string {
+	return append([]string{}, c.locales...)
+}
+
+// Negotiate picks the best supported locale for an Accept-Language header
+// value or a single language tag, falling back to the default locale.
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return DefaultLocale
+	}
+
+	_, index := language.MatchStrings(c.matcher, acceptLanguage)
+	return c.locales[index]
+}
+
+func (c *Catalog) Messages(locale string) map[string]Message {
+	messages := maps.Clone(c.messages[DefaultLocale])
+	maps.Copy(messages, c.messages[locale])
+	return messages
+}
+
+func (c *Catalog) lookup(locale, key string) (Message, bool) {
+	if message, ok := c.messages[locale][key]; ok {
+		return message, true
+	}
+	message, ok := c.messages[DefaultLocale][key]
+	return message, ok
+}
+
+// Translate renders key in locale, substituting {name} placeholders from
+// params. The boolean is false when no locale has the key.
+func (c *Catalog) Translate(locale, key string, params map[string]any) (string, bool) {
+	message, ok := c.lookup(locale, key)
+	if !ok {
+		return "", false
+	}
+
+	category := "other"
+	if message.Plural != "" {
+		if count, ok := number(params[message.Plural]); ok {
+			category = pluralCategory(locale, count)
+		}
+	}
+
+	return interpolate(message.form(category), params), true
+}
+
+// Label returns the display name of a field path such as "items[0].name",
+// using the catalogue's field.<name> entry for its last segment.
+func (c *Catalog) Label(locale, field string) string {
+	for strings.HasSuffix(field, "]") {
+		field = field[:strings.LastIndex(field, "[")]
+	}
+	field = field[strings.LastIndex(field, ".")+1:]
+	if field == "" {
+		field = "general"
+	}
+
+	if label, ok := c.Translate(locale, "field."+field, nil); ok {
+		return label
+	}
+
+	field = strings.ReplaceAll(field, "_", " ")
+	return strings.ToUpper(field[:1]) + field[1:]
+}
+
+// FieldMessage renders a field error code, exposing the field's label to the
+// message as {field}.
+func (c *Catalog) FieldMessage(locale, field, code string, params map[string]any) (string, bool) {
+	values := maps.Clone(params)
+	if values == nil {
+		values = map[string]any{}
+	}
+	values["field"] = c.Label(locale, field)
+
+	return c.Translate(locale, code, values)
+}
+
+func interpolate(text string, params map[string]any) string {
+	return placeholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		value, ok := params[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		return format(value)
+	})
+}
+
+func format(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case []string:
+		return strings.Join(typed, ", ")
+	case []any:
+		items := make([]string, 0, len(typed))
+		for _, item := range typed {
+			items = append(items, format(item))
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+func number(value any) (float64, bool) {
+	switch typed := value.(type) {
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case float64:
+		return typed, true
+	case json.Number:
+		parsed, err := typed.Float64()
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"testing"
+)
+
+func TestCatalog_LocalesCoverDefaultKeys(t *testing.T) {
+	catalog := Default()
+
+	for _, locale := range catalog.Locales() {
+		for key, message := range catalog.messages[DefaultLocale] {
+			translated, ok := catalog.messages[locale][key]
+			if !ok {
+				t.Errorf("%s: missing key %s", locale, key)
+				continue
+			}
+			if translated.Plural != message.Plural {
+				t.Errorf("%s: key %s pluralises on %q, expected %q", locale, key, translated.Plural, message.Plural)
+			}
+		}
+	}
+}
+
+func TestCatalog_Negotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          "en",
+		"ro-RO,ro;q=0.9,en;q=0.8":   "ro",
+		"de-AT":                     "de",
+		"fr-FR,de;q=0.7,en;q=0.5":   "de",
+		"fr-FR":                     "en",
+		"not a language tag at all": "en",
+	}
+
+	for header, expected := range cases {
+		if got := Default().Negotiate(header); got != expected {
+			t.Errorf("Negotiate(%q): expected %s, got: %s", header, expected, got)
+		}
+	}
+}
+
+func TestCatalog_Translate(t *testing.T) {
+	cases := []struct {
+		locale   string
+		key      string
+		params   map[string]any
+		expected string
+	}{
+		{"en", "validation.too_long", map[string]any{"field": "Email", "max": 255}, "Email must not exceed 255 characters"},
+		{"en", "validation.too_short", map[string]any{"field": "Code", "min": 1}, "Code must be at least 1 character"},
+		{"ro", "validation.too_short", map[string]any{"field": "Parolă", "min": 1}, "Câmpul „Parolă” trebuie să aibă cel puțin 1 caracter"},
+		{"ro", "validation.too_short", map[string]any{"field": "Parolă", "min": 8}, "Câmpul „Parolă” trebuie să aibă cel puțin 8 caractere"},
+		{"ro", "validation.too_long", map[string]any{"field": "Email", "max": 255.0}, "Câmpul „Email” nu poate depăși 255 de caractere"},
+		{"ro", "validation.too_long", map[string]any{"field": "Email", "max": 101}, "Câmpul „Email” nu poate depăși 101 caractere"},
+		{"de", "validation.not_allowed", map[string]any{"field": "Status", "allowed": []any{"open", "resolved"}}, "Status muss einer der folgenden Werte sein: open, resolved"},
+		{"de", "validation.required", nil, "{field} ist erforderlich"},
+	}
+
+	for _, tc := range cases {
+		got, ok := Default().Translate(tc.locale, tc.key, tc.params)
+		if !ok || got != tc.expected {
+			t.Errorf("Translate(%s, %s): expected %q, got: %q", tc.locale, tc.key, tc.expected, got)
+		}
+	}
+
+	if _, ok := Default().Translate("de", "does.not_exist", nil); ok {
+		t.Error("Expected unknown key to be reported")
+	}
+}
+
+func TestCatalog_FieldMessage(t *testing.T) {
+	got, _ := Default().FieldMessage("de", "breadcrumbs[3].message", "validation.required", nil)
+	if got != "Message ist erforderlich" {
+		t.Errorf("Expected humanised label for unknown field, got: %q", got)
+	}
+
+	got, _ = Default().FieldMessage("de", "username", "validation.required", nil)
+	if got != "Benutzername ist erforderlich" {
+		t.Errorf("Expected catalogue label, got: %q", got)
+	}
+}
@@ -0,0 +1,28 @@
+package i18n
+
+import "math"
+
+// pluralCategory implements the CLDR cardinal rules for the catalogue's
+// locales. English and German only distinguish one from other.
+func pluralCategory(locale string, n float64) string {
+	integer := n == math.Trunc(n)
+
+	switch locale {
+	case "ro":
+		switch {
+		case n == 1:
+			return "one"
+		case !integer || n == 0:
+			return "few"
+		case int64(n)%100 >= 1 && int64(n)%100 <= 19:
+			return "few"
+		default:
+			return "other"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
@@ -1,11 +1,26 @@
 package metrics
 
 import (
+	"database/sql"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var HTTPRequestsTotal = promauto.NewCounterVec(
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var HTTPRequestsTotal = factory.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "http_requests_total",
 		Help: "Total number of HTTP requests processed",
@@ -13,7 +28,7 @@ var HTTPRequestsTotal = promauto.NewCounterVec(
 	[]string{"method", "path", "status"},
 )
 
-var HTTPRequestDuration = promauto.NewHistogramVec(
+var HTTPRequestDuration = factory.NewHistogramVec(
 	prometheus.HistogramOpts{
 		Name:    "http_request_duration_seconds",
 		Help:    "Histogram of HTTP request durations in seconds",
@@ -22,9 +37,120 @@ var HTTPRequestDuration = promauto.NewHistogramVec(
 	[]string{"method", "path"},
 )
 
-var HTTPRequestsInFlight = promauto.NewGauge(
+var HTTPRequestsInFlight = factory.NewGauge(
 	prometheus.GaugeOpts{
 		Name: "http_requests_in_flight",
 		Help: "Current number of HTTP requests being processed",
 	},
 )
+
+var LoginAttemptsTotal = factory.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "livecode_login_attempts_total",
+		Help: "Total number of login attempts by outcome",
+	},
+	[]string{"outcome"},
+)
+
+var RegistrationsTotal = factory.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "livecode_registrations_total",
+		Help: "Total number of registration attempts by outcome",
+	},
+	[]string{"outcome"},
+)
+
+var TokenRefreshesTotal = factory.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "livecode_token_refreshes_total",
+		Help: "Total number of refresh token exchanges by outcome",
+	},
+	[]string{"outcome"},
+)
+
+var RefreshTokenReuseDetectionsTotal = factory.NewCounter(
+	prometheus.CounterOpts{
+		Name: "livecode_refresh_token_reuse_detections_total",
+		Help: "Total number of already rotated refresh tokens presented again",
+	},
+)
+
+var RateLimitRejectionsTotal = factory.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "livecode_rate_limit_rejections_total",
+		Help: "Total number of requests rejected by a rate limiter",
+	},
+	[]string{"limiter"},
+)
+
+var ProofOfWorkVerificationsTotal = factory.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "livecode_pow_verifications_total",
+		Help: "Total number of proof-of-work checks by route scope and outcome",
+	},
+	[]string{"scope", "outcome"},
+)
+
+var PasswordHashDuration = factory.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "livecode_password_hash_duration_seconds",
+		Help:    "Histogram of Argon2id hash and verify durations in seconds",
+		Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2},
+	},
+	[]string{"operation"},
+)
+
+var JWTVerificationFailuresTotal = factory.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "livecode_jwt_verification_failures_total",
+		Help: "Total number of JWT verification failures by reason",
+	},
+	[]string{"reason"},
+)
+
+var TelemetryEventsTotal = factory.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "livecode_telemetry_events_total",
+		Help: "Total number of client telemetry events received by type and outcome",
+	},
+	[]string{"type", "outcome"},
+)
+
+var TelemetrySinkQueueDepth = factory.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "livecode_telemetry_sink_queue_depth",
+		Help: "Number of telemetry events waiting to be written",
+	},
+)
+
+var TelemetrySinkFlushDuration = factory.NewHistogram(
+	prometheus.HistogramOpts{
+		Name:    "livecode_telemetry_sink_flush_duration_seconds",
+		Help:    "Histogram of telemetry batch write durations in seconds",
+		Buckets: prometheus.DefBuckets,
+	},
+)
+
+var TelemetrySinkWriteFailuresTotal = factory.NewCounter(
+	prometheus.CounterOpts{
+		Name: "livecode_telemetry_sink_write_failures_total",
+		Help: "Total number of telemetry events lost because a batch write failed",
+	},
+)
+
+var (
+	dbStatsMu        sync.Mutex
+	dbStatsCollector prometheus.Collector
+)
+
+func RegisterDBStats(db *sql.DB) {
+	dbStatsMu.Lock()
+	defer dbStatsMu.Unlock()
+
+	if dbStatsCollector != nil {
+		Registry.Unregister(dbStatsCollector)
+	}
+
+	dbStatsCollector = collectors.NewDBStatsCollector(db, "livecode")
+	Registry.MustRegister(dbStatsCollector)
+}
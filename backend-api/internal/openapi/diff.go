@@ -0,0 +1,294 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type BreakingChange struct {
+	Location string
+	Message  string
+}
+
+func (c BreakingChange) String() string {
+	return c.Location + ": " + c.Message
+}
+
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &document, nil
+}
+
+// Diff reports changes in next that would break a client written against
+// previous: removed operations or responses, stricter requests and responses
+// that drop or retype fields the client may read.
+func Diff(previous, next *Document) []BreakingChange {
+	d := &differ{previous: previous, next: next, visited: map[string]bool{}}
+
+	for _, path := range sortedKeys(previous.Paths) {
+		for _, method := range sortedKeys(previous.Paths[path]) {
+			location := strings.ToUpper(method) + " " + path
+			before := previous.Paths[path][method]
+
+			after, ok := next.Paths[path][method]
+			if !ok {
+				d.report(location, "operation was removed")
+				continue
+			}
+
+			d.compareOperation(location, before, after)
+		}
+	}
+
+	return d.changes
+}
+
+type differ struct {
+	previous *Document
+	next     *Document
+	visited  map[string]bool
+	changes  []BreakingChange
+}
+
+func (d *differ) report(location, format string, args ...any) {
+	d.changes = append(d.changes, BreakingChange{Location: location, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) compareOperation(location string, before, after *OperationObject) {
+	if !requiresAuth(before.Security) && requiresAuth(after.Security) {
+		d.report(location, "operation now requires authentication")
+	}
+
+	beforeParameters := map[string]*ParameterObject{}
+	for _, parameter := range before.Parameters {
+		beforeParameters[parameter.In+":"+parameter.Name] = parameter
+	}
+
+	for _, parameter := range after.Parameters {
+		parameterLocation := fmt.Sprintf("%s %s parameter %q", location, parameter.In, parameter.Name)
+		previous, ok := beforeParameters[parameter.In+":"+parameter.Name]
+
+		if !ok {
+			if parameter.Required {
+				d.report(parameterLocation, "new required parameter")
+			}
+			continue
+		}
+
+		if parameter.Required && !previous.Required {
+			d.report(parameterLocation, "parameter became required")
+		}
+		d.compareRequestSchema(parameterLocation, previous.Schema, parameter.Schema)
+	}
+
+	switch {
+	case before.RequestBody == nil && after.RequestBody != nil && after.RequestBody.Required:
+		d.report(location+" request body", "request body became required")
+	case before.RequestBody != nil && after.RequestBody != nil:
+		for contentType, media := range before.RequestBody.Content {
+			nextMedia, ok := after.RequestBody.Content[contentType]
+			if !ok {
+				d.report(location+" request body", "content type %s is no longer accepted", contentType)
+				continue
+			}
+			d.compareRequestSchema(location+" request body", media.Schema, nextMedia.Schema)
+		}
+	}
+
+	for _, status := range sortedKeys(before.Responses) {
+		responseLocation := location + " response " + status
+
+		nextResponse, ok := after.Responses[status]
+		if !ok {
+			if strings.HasPrefix(status, "2") {
+				d.report(responseLocation, "response was removed")
+			}
+			continue
+		}
+
+		for contentType, media := range before.Responses[status].Content {
+			nextMedia, ok := nextResponse.Content[contentType]
+			if !ok {
+				d.report(responseLocation, "content type %s is no longer returned", contentType)
+				continue
+			}
+			d.compareResponseSchema(responseLocation, media.Schema, nextMedia.Schema)
+		}
+	}
+}
+
+func (d *differ) compareRequestSchema(location string, before, after *Schema) {
+	if before.refName() != "" && after.refName() != "" {
+		key := "request|" + before.refName() + "|" + after.refName()
+		if d.visited[key] {
+			return
+		}
+		d.visited[key] = true
+	}
+
+	before, after = d.previous.resolve(before), d.next.resolve(after)
+	if before == nil || after == nil {
+		return
+	}
+
+	if len(before.Type) > 0 && len(after.Type) > 0 {
+		for _, name := range before.Type {
+			if !after.Type.Has(name) && !(name == "integer" && after.Type.Has("number")) {
+				d.report(location, "no longer accepts type %s", name)
+			}
+		}
+	} else if len(before.Type) == 0 && len(after.Type) > 0 {
+		d.report(location, "type was restricted to %s", strings.Join(after.Type, ", "))
+	}
+
+	if len(after.Enum) > 0 {
+		if len(before.Enum) == 0 {
+			d.report(location, "values were restricted to an enum")
+		}
+		for _, value := range before.Enum {
+			if !inEnum(after.Enum, value) {
+				d.report(location, "value %v is no longer accepted", value)
+			}
+		}
+	}
+
+	if tightenedMax(before.MaxLength, after.MaxLength) || tightenedMin(before.MinLength, after.MinLength) {
+		d.report(location, "length limits were tightened")
+	}
+	if tightenedMax(before.MaxItems, after.MaxItems) || tightenedMin(before.MinItems, after.MinItems) {
+		d.report(location, "item limits were tightened")
+	}
+	if tightenedMaxFloat(before.Maximum, after.Maximum) || tightenedMinFloat(before.Minimum, after.Minimum) {
+		d.report(location, "numeric limits were tightened")
+	}
+	if after.Pattern != "" && after.Pattern != before.Pattern {
+		d.report(location, "pattern changed to %s", after.Pattern)
+	}
+	if after.Format != "" && after.Format != before.Format {
+		d.report(location, "format changed to %s", after.Format)
+	}
+
+	for _, name := range after.Required {
+		if !contains(before.Required, name) {
+			d.report(joinPath(location, name), "property became required")
+		}
+	}
+
+	for _, name := range sortedKeys(after.Properties) {
+		if previous, ok := before.Properties[name]; ok {
+			d.compareRequestSchema(joinPath(location, name), previous, after.Properties[name])
+		}
+	}
+
+	if before.Items != nil && after.Items != nil {
+		d.compareRequestSchema(location+"[]", before.Items, after.Items)
+	}
+}
+
+func (d *differ) compareResponseSchema(location string, before, after *Schema) {
+	if before.refName() != "" && after.refName() != "" {
+		key := "response|" + before.refName() + "|" + after.refName()
+		if d.visited[key] {
+			return
+		}
+		d.visited[key] = true
+	}
+
+	before, after = d.previous.resolve(before), d.next.resolve(after)
+	if before == nil || after == nil {
+		return
+	}
+
+	if len(before.Type) > 0 {
+		for _, name := range after.Type {
+			if !before.Type.Has(name) && !(name == "integer" && before.Type.Has("number")) {
+				d.report(location, "may now return type %s", name)
+			}
+		}
+	}
+
+	if len(before.Enum) > 0 {
+		for _, value := range after.Enum {
+			if !inEnum(before.Enum, value) {
+				d.report(location, "may now return value %v", value)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(before.Properties) {
+		nextProperty, ok := after.Properties[name]
+		if !ok {
+			d.report(joinPath(location, name), "property was removed")
+			continue
+		}
+		d.compareResponseSchema(joinPath(location, name), before.Properties[name], nextProperty)
+	}
+
+	if before.Items != nil && after.Items != nil {
+		d.compareResponseSchema(location+"[]", before.Items, after.Items)
+	}
+}
+
+func (s *Schema) refName() string {
+	if s == nil {
+		return ""
+	}
+	return s.Ref
+}
+
+func requiresAuth(security []map[string][]string) bool {
+	if len(security) == 0 {
+		return false
+	}
+	for _, requirement := range security {
+		if len(requirement) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func tightenedMax(before, after *int) bool {
+	return after != nil && (before == nil || *after < *before)
+}
+
+func tightenedMin(before, after *int) bool {
+	return after != nil && (before == nil || *after > *before)
+}
+
+func tightenedMaxFloat(before, after *float64) bool {
+	return after != nil && (before == nil || *after < *before)
+}
+
+func tightenedMinFloat(before, after *float64) bool {
+	return after != nil && (before == nil || *after > *before)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
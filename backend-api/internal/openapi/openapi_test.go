@@ -0,0 +1,279 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type testItem struct {
+	Name string `json:"name" binding:"required,max=5"`
+}
+
+type testRequest struct {
+	Email string     `json:"email" binding:"required,email"`
+	Mode  string     `json:"mode,omitempty" binding:"omitempty,oneof=fast slow"`
+	Items []testItem `json:"items" binding:"max=2,dive"`
+}
+
+type testQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=10"`
+}
+
+type testResponse struct {
+	Success bool    `json:"success"`
+	Note    *string `json:"note"`
+}
+
+func newTestSpec(t *testing.T, validateResponses bool, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	middleware.Logger = zap.NewNop()
+
+	spec := NewSpec(Info{Title: "Test", Version: "1"})
+	router := gin.New()
+	if validateResponses {
+		router.Use(spec.ValidateResponses())
+	}
+	router.Use(spec.ValidateRequests())
+	router.POST("/items/:id", handler)
+	router.GET("/undocumented", handler)
+
+	undocumented, err := spec.Build(router.Routes(), []Operation{{
+		Method:    http.MethodPost,
+		Path:      "/items/:id",
+		Query:     testQuery{},
+		Request:   testRequest{},
+		Responses: map[int]any{http.StatusOK: testResponse{}},
+	}})
+	if err != nil {
+		t.Fatalf("Expected spec to build, got: %v", err)
+	}
+	if len(undocumented) != 1 || undocumented[0] != "GET /undocumented" {
+		t.Errorf("Expected GET /undocumented to be reported, got: %v", undocumented)
+	}
+
+	return router
+}
+
+func TestSpec_Build(t *testing.T) {
+	spec := NewSpec(Info{Title: "Test", Version: "1"})
+	router := gin.New()
+	router.POST("/items/:id", func(c *gin.Context) {})
+
+	if _, err := spec.Build(router.Routes(), []Operation{{Method: http.MethodGet, Path: "/missing"}}); err == nil {
+		t.Error("Expected an error for an operation without a route")
+	}
+
+	if _, err := spec.Build(router.Routes(), []Operation{{Method: http.MethodPost, Path: "/items/:id", Request: testRequest{}}}); err != nil {
+		t.Fatalf("Expected spec to build, got: %v", err)
+	}
+
+	document := spec.Document()
+	operation := document.Paths["/items/{id}"]["post"]
+	if operation == nil || operation.OperationID != "postItemsId" {
+		t.Fatalf("Expected operation postItemsId at /items/{id}, got: %+v", document.Paths)
+	}
+
+	if operation.Parameters[0].In != "path" || operation.Parameters[0].Name != "id" {
+		t.Errorf("Expected path parameter id, got: %+v", operation.Parameters[0])
+	}
+
+	schema := document.Components.Schemas["testRequest"]
+	if schema == nil {
+		t.Fatal("Expected testRequest component")
+	}
+	if schema.Properties["email"].Format != "email" || len(schema.Required) != 1 {
+		t.Errorf("Expected required email property, got: %+v", schema)
+	}
+	if len(schema.Properties["mode"].Enum) != 2 {
+		t.Errorf("Expected mode enum, got: %+v", schema.Properties["mode"])
+	}
+	if *schema.Properties["items"].MaxItems != 2 || schema.Properties["items"].Items.Ref != "#/components/schemas/testItem" {
+		t.Errorf("Expected items limited to 2 testItem refs, got: %+v", schema.Properties["items"])
+	}
+}
+
+func TestSpec_BuildRawResponse(t *testing.T) {
+	spec := NewSpec(Info{Title: "Test", Version: "1"})
+	router := gin.New()
+	router.GET("/export", func(c *gin.Context) {})
+
+	_, err := spec.Build(router.Routes(), []Operation{{
+		Method:    http.MethodGet,
+		Path:      "/export",
+		Responses: map[int]any{http.StatusOK: Raw{ContentType: "application/octet-stream"}},
+	}})
+	if err != nil {
+		t.Fatalf("Expected spec to build, got: %v", err)
+	}
+
+	content := spec.Document().Paths["/export"]["get"].Responses["200"].Content
+	media, ok := content["application/octet-stream"]
+	if !ok || len(content) != 1 || media.Schema.Format != "binary" {
+		t.Errorf("Expected a single binary media type, got: %+v", content)
+	}
+}
+
+func TestSpec_BuildRawRequest(t *testing.T) {
+	spec := NewSpec(Info{Title: "Test", Version: "1"})
+	router := gin.New()
+	router.PUT("/upload", func(c *gin.Context) {})
+
+	_, err := spec.Build(router.Routes(), []Operation{{
+		Method:    http.MethodPut,
+		Path:      "/upload",
+		Request:   Raw{ContentType: "application/octet-stream"},
+		Responses: map[int]any{http.StatusNoContent: nil},
+	}})
+	if err != nil {
+		t.Fatalf("Expected spec to build, got: %v", err)
+	}
+
+	content := spec.Document().Paths["/upload"]["put"].RequestBody.Content
+	media, ok := content["application/octet-stream"]
+	if !ok || len(content) != 1 || media.Schema.Format != "binary" {
+		t.Errorf("Expected a single binary request body, got: %+v", content)
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	router := newTestSpec(t, false, func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "application/json", body)
+	})
+
+	cases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		field  string
+	}{
+		{"valid", "/items/1", `{"email":"a@b.co","items":[{"name":"x"}]}`, http.StatusOK, ""},
+		{"missing email", "/items/1", `{"items":[]}`, http.StatusBadRequest, "email"},
+		{"bad enum", "/items/1", `{"email":"a@b.co","mode":"warp"}`, http.StatusBadRequest, "mode"},
+		{"nested too long", "/items/1", `{"email":"a@b.co","items":[{"name":"toolong"}]}`, http.StatusBadRequest, "items[0].name"},
+		{"query out of range", "/items/1?limit=50", `{"email":"a@b.co"}`, http.StatusBadRequest, "limit"},
+		{"undocumented", "/undocumented", ``, http.StatusOK, ""},
+	}
+
+	for _, tc := range cases {
+		method := http.MethodPost
+		if tc.path == "/undocumented" {
+			method = http.MethodGet
+		}
+
+		req := httptest.NewRequest(method, tc.path, strings.NewReader(tc.body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got: %d (%s)", tc.name, tc.status, rec.Code, rec.Body.String())
+			continue
+		}
+
+		if tc.status == http.StatusOK && method == http.MethodPost && rec.Body.String() != tc.body {
+			t.Errorf("%s: expected body to reach the handler unchanged, got: %s", tc.name, rec.Body.String())
+		}
+
+		if tc.field != "" {
+			var response struct {
+				Errors []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &response)
+
+			if len(response.Errors) != 1 || response.Errors[0].Field != tc.field {
+				t.Errorf("%s: expected one error on %s, got: %s", tc.name, tc.field, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestValidateResponses_PassesResponseThrough(t *testing.T) {
+	router := newTestSpec(t, true, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": "yes"})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/items/1", strings.NewReader(`{"email":"a@b.co"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"success":"yes"}` {
+		t.Errorf("Expected mismatching response to be delivered unchanged, got: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDiff(t *testing.T) {
+	build := func(operations []Operation, routes ...string) *Document {
+		router := gin.New()
+		for _, route := range routes {
+			method, path, _ := strings.Cut(route, " ")
+			router.Handle(method, path, func(c *gin.Context) {})
+		}
+
+		spec := NewSpec(Info{Title: "Test", Version: "1"})
+		if _, err := spec.Build(router.Routes(), operations); err != nil {
+			t.Fatalf("Expected spec to build, got: %v", err)
+		}
+
+		var document Document
+		if err := json.Unmarshal(spec.JSON(), &document); err != nil {
+			t.Fatalf("Expected spec to round-trip, got: %v", err)
+		}
+		return &document
+	}
+
+	type before struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	type after struct {
+		Name  string `json:"name" binding:"required"`
+		Count string `json:"count"`
+	}
+
+	previous := build([]Operation{
+		{Method: http.MethodPost, Path: "/things", Request: before{}, Responses: map[int]any{http.StatusOK: before{}}},
+		{Method: http.MethodGet, Path: "/old", Responses: map[int]any{http.StatusOK: nil}},
+	}, "POST /things", "GET /old")
+
+	if changes := Diff(previous, previous); len(changes) != 0 {
+		t.Errorf("Expected no changes against itself, got: %v", changes)
+	}
+
+	next := build([]Operation{
+		{Method: http.MethodPost, Path: "/things", Request: after{}, Responses: map[int]any{http.StatusOK: struct {
+			Name string `json:"name"`
+		}{}}},
+	}, "POST /things")
+
+	changes := Diff(previous, next)
+	expected := []string{
+		"POST /things request body.name: property became required",
+		"POST /things request body.count: no longer accepts type integer",
+		"POST /things response 200.count: property was removed",
+		"GET /old: operation was removed",
+	}
+
+	for _, want := range expected {
+		found := false
+		for _, change := range changes {
+			if strings.Contains(change.String(), want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected change %q, got: %v", want, changes)
+		}
+	}
+}
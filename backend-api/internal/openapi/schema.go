@@ -0,0 +1,332 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SchemaType []string
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+func (t SchemaType) Has(name string) bool {
+	for _, value := range t {
+		if value == name {
+			return true
+		}
+	}
+	return false
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Example              any                `json:"example,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+type schemaGenerator struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}}
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: SchemaType{"string"}, Format: "byte"}
+		}
+		return &Schema{Type: SchemaType{"array"}, Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: SchemaType{"object"}, AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.componentName(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := g.components[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	g.names[t] = name
+	g.components[name] = &Schema{}
+	*g.components[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := g.structSchema(indirect(field.Type))
+			for key, value := range embedded.Properties {
+				schema.Properties[key] = value
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property, required := g.fieldSchema(field)
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+func (g *schemaGenerator) fieldSchema(field reflect.StructField) (*Schema, bool) {
+	property := g.schemaFor(field.Type)
+	required := applyBindingRules(property, field.Tag.Get("binding"))
+
+	if _, omitEmpty, _ := jsonFieldName(field); !omitEmpty && !required && len(property.Type) > 0 {
+		switch field.Type.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			property.Type = append(property.Type, "null")
+		}
+	}
+
+	if property.Ref == "" {
+		if format := field.Tag.Get("format"); format != "" {
+			property.Format = format
+		}
+		if pattern := field.Tag.Get("pattern"); pattern != "" {
+			property.Pattern = pattern
+		}
+		if example := field.Tag.Get("example"); example != "" {
+			property.Example = parseExample(example, property.Type)
+		}
+	}
+
+	if description := field.Tag.Get("description"); description != "" {
+		property.Description = description
+	}
+
+	return property, required
+}
+
+func applyBindingRules(schema *Schema, binding string) bool {
+	if binding == "" {
+		return false
+	}
+
+	required := false
+	target := schema
+
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch key {
+		case "required":
+			if target == schema {
+				required = true
+			}
+		case "dive":
+			if target.Items != nil {
+				target = target.Items
+			} else if target.AdditionalProperties != nil {
+				target = target.AdditionalProperties
+			}
+		case "min", "max", "len":
+			applyLengthRule(target, key, value)
+		case "email":
+			target.Format = "email"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		case "url":
+			target.Format = "uri"
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				target.Enum = append(target.Enum, parseExample(option, target.Type))
+			}
+		case "gte", "gt":
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				target.Minimum = &number
+			}
+		case "lte", "lt":
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				target.Maximum = &number
+			}
+		}
+	}
+
+	if required && schema.Type.Has("string") && schema.Format != "date-time" && schema.MinLength == nil {
+		minLength := 1
+		schema.MinLength = &minLength
+	}
+
+	return required
+}
+
+func applyLengthRule(schema *Schema, key, value string) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case schema.Type.Has("string"):
+		if key == "min" || key == "len" {
+			schema.MinLength = &number
+		}
+		if key == "max" || key == "len" {
+			schema.MaxLength = &number
+		}
+	case schema.Type.Has("array"):
+		if key == "min" || key == "len" {
+			schema.MinItems = &number
+		}
+		if key == "max" || key == "len" {
+			schema.MaxItems = &number
+		}
+	case schema.Type.Has("object"):
+		if key == "min" || key == "len" {
+			schema.MinProperties = &number
+		}
+		if key == "max" || key == "len" {
+			schema.MaxProperties = &number
+		}
+	case schema.Type.Has("integer"), schema.Type.Has("number"):
+		bound := float64(number)
+		if key == "min" || key == "len" {
+			schema.Minimum = &bound
+		}
+		if key == "max" || key == "len" {
+			schema.Maximum = &bound
+		}
+	}
+}
+
+func parseExample(value string, schemaType SchemaType) any {
+	switch {
+	case schemaType.Has("integer"):
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	case schemaType.Has("number"):
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case schemaType.Has("boolean"):
+		if flag, err := strconv.ParseBool(value); err == nil {
+			return flag
+		}
+	}
+	return value
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, options, _ := strings.Cut(tag, ",")
+	return name, strings.Contains(options, "omitempty"), false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,371 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"livecode-api/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Version = "3.1.0"
+
+type AuthMode int
+
+const (
+	AuthNone AuthMode = iota
+	AuthOptional
+	AuthRequired
+)
+
+type Operation struct {
+	Method             string
+	Path               string
+	OperationID        string
+	Summary            string
+	Description        string
+	Tags               []string
+	Auth               AuthMode
+	Headers            []Parameter
+	Query              any
+	Request            any
+	RequestContentType string
+	Responses          map[int]any
+}
+
+// Raw documents a request or response body that is not JSON, such as a text
+// or binary file. Request and response validation skip it.
+type Raw struct {
+	ContentType string
+}
+
+type Parameter struct {
+	Name        string
+	Description string
+	Required    bool
+	Format      string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Document struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       Info                                   `json:"info"`
+	Servers    []Server                               `json:"servers,omitempty"`
+	Paths      map[string]map[string]*OperationObject `json:"paths"`
+	Components Components                             `json:"components"`
+}
+
+type OperationObject struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []*ParameterObject         `json:"parameters,omitempty"`
+	RequestBody *RequestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]*ResponseObject `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type ParameterObject struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type ResponseObject struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Spec holds the generated document together with an index of its operations
+// keyed by gin route, which the validation middleware looks up per request.
+type Spec struct {
+	mu         sync.RWMutex
+	info       Info
+	document   *Document
+	raw        []byte
+	operations map[string]*OperationObject
+}
+
+func NewSpec(info Info) *Spec {
+	return &Spec{info: info}
+}
+
+// Build generates the document from the routes registered on the engine.
+// Documented operations without a matching route are an error; registered
+// routes without an operation are returned so the caller can report them.
+func (s *Spec) Build(routes gin.RoutesInfo, operations []Operation) ([]string, error) {
+	generator := newSchemaGenerator()
+
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		registered[routeKey(route.Method, route.Path)] = true
+	}
+
+	document := &Document{
+		OpenAPI: Version,
+		Info:    s.info,
+		Paths:   map[string]map[string]*OperationObject{},
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	index := map[string]*OperationObject{}
+
+	for _, operation := range operations {
+		key := routeKey(operation.Method, operation.Path)
+		if !registered[key] {
+			return nil, fmt.Errorf("operation %s has no registered route", key)
+		}
+		if _, exists := index[key]; exists {
+			return nil, fmt.Errorf("operation %s is documented twice", key)
+		}
+
+		object := generator.operationObject(operation)
+		index[key] = object
+
+		path := toOpenAPIPath(operation.Path)
+		if document.Paths[path] == nil {
+			document.Paths[path] = map[string]*OperationObject{}
+		}
+		document.Paths[path][strings.ToLower(operation.Method)] = object
+	}
+
+	undocumented := []string{}
+	for _, route := range routes {
+		if _, ok := index[routeKey(route.Method, route.Path)]; !ok {
+			undocumented = append(undocumented, routeKey(route.Method, route.Path))
+		}
+	}
+	sort.Strings(undocumented)
+
+	document.Components.Schemas = generator.components
+
+	raw, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.document = document
+	s.raw = append(raw, '\n')
+	s.operations = index
+	s.mu.Unlock()
+
+	return undocumented, nil
+}
+
+func (s *Spec) JSON() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.raw
+}
+
+func (s *Spec) Document() *Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.document
+}
+
+func (s *Spec) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := s.JSON()
+		if raw == nil {
+			apierror.Write(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeServiceUnavailable, "API specification is not available."))
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json", raw)
+	}
+}
+
+func (s *Spec) operation(method, ginPath string) (*OperationObject, *Document) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.operations[routeKey(method, ginPath)], s.document
+}
+
+func (g *schemaGenerator) operationObject(operation Operation) *OperationObject {
+	object := &OperationObject{
+		OperationID: operation.OperationID,
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		Tags:        operation.Tags,
+		Responses:   map[string]*ResponseObject{},
+	}
+
+	if object.OperationID == "" {
+		object.OperationID = defaultOperationID(operation.Method, operation.Path)
+	}
+
+	switch operation.Auth {
+	case AuthRequired:
+		object.Security = []map[string][]string{{"bearerAuth": {}}}
+	case AuthOptional:
+		object.Security = []map[string][]string{{}, {"bearerAuth": {}}}
+	}
+
+	for _, segment := range strings.Split(operation.Path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			object.Parameters = append(object.Parameters, &ParameterObject{
+				Name:     segment[1:],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: SchemaType{"string"}},
+			})
+		}
+	}
+
+	for _, header := range operation.Headers {
+		object.Parameters = append(object.Parameters, &ParameterObject{
+			Name:        header.Name,
+			In:          "header",
+			Description: header.Description,
+			Required:    header.Required,
+			Schema:      &Schema{Type: SchemaType{"string"}, Format: header.Format},
+		})
+	}
+
+	if operation.Query != nil {
+		object.Parameters = append(object.Parameters, g.queryParameters(reflect.TypeOf(operation.Query))...)
+	}
+
+	if operation.Request != nil {
+		contentType := operation.RequestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		var schema *Schema
+		if raw, ok := operation.Request.(Raw); ok {
+			contentType, schema = raw.ContentType, rawSchema(raw.ContentType)
+		} else {
+			schema = g.schemaFor(reflect.TypeOf(operation.Request))
+		}
+		object.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: schema}},
+		}
+	}
+
+	for status, body := range operation.Responses {
+		response := &ResponseObject{Description: http.StatusText(status)}
+		if raw, ok := body.(Raw); ok {
+			response.Content = map[string]MediaType{raw.ContentType: {Schema: rawSchema(raw.ContentType)}}
+		} else if body != nil {
+			response.Content = map[string]MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(body))}}
+		}
+		if status >= http.StatusBadRequest {
+			if response.Content == nil {
+				response.Content = map[string]MediaType{}
+			}
+			response.Content[apierror.ProblemMediaType] = MediaType{Schema: g.schemaFor(reflect.TypeOf(apierror.Problem{}))}
+		}
+		object.Responses[strconv.Itoa(status)] = response
+	}
+
+	return object
+}
+
+func rawSchema(contentType string) *Schema {
+	schema := &Schema{Type: SchemaType{"string"}}
+	if !strings.HasPrefix(contentType, "text/") {
+		schema.Format = "binary"
+	}
+	return schema
+}
+
+func (g *schemaGenerator) queryParameters(t reflect.Type) []*ParameterObject {
+	t = indirect(t)
+	parameters := []*ParameterObject{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schema, required := g.fieldSchema(field)
+		description := schema.Description
+		schema.Description = ""
+
+		parameters = append(parameters, &ParameterObject{
+			Name:        name,
+			In:          "query",
+			Description: description,
+			Required:    required,
+			Schema:      schema,
+		})
+	}
+
+	return parameters
+}
+
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+func toOpenAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func defaultOperationID(method, path string) string {
+	var builder strings.Builder
+	builder.WriteString(strings.ToLower(method))
+
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' || r == '.' }) {
+		segment = strings.TrimLeft(segment, ":*")
+		if segment == "" {
+			continue
+		}
+		builder.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,419 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxValidatedBodyBytes     = 1 << 20
+	maxValidatedResponseBytes = 1 << 20
+)
+
+var (
+	emailFormatRegex = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	patternCache     sync.Map
+)
+
+// ValidateRequests rejects requests whose parameters or JSON body do not match
+// the documented operation. Routes missing from the spec pass through untouched.
+func (s *Spec) ValidateRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation, document := s.operation(c.Request.Method, c.FullPath())
+		if operation == nil {
+			c.Next()
+			return
+		}
+
+		errors := []models.FieldError{}
+
+		for _, parameter := range operation.Parameters {
+			var value string
+			var present bool
+
+			switch parameter.In {
+			case "query":
+				value, present = c.GetQuery(parameter.Name)
+			case "header":
+				value = c.GetHeader(parameter.Name)
+				present = value != ""
+			default:
+				continue
+			}
+
+			if !present {
+				if parameter.Required {
+					errors = append(errors, apierror.Field(parameter.Name, apierror.FieldRequired, nil))
+				}
+				continue
+			}
+
+			errors = append(errors, document.validate(parameter.Schema, coerceParameter(parameter.Schema, value), parameter.Name)...)
+		}
+
+		if operation.RequestBody != nil {
+			if media, ok := operation.RequestBody.Content["application/json"]; ok {
+				body, complete, err := readBody(c.Request)
+				if err != nil {
+					apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid request body"))
+					return
+				}
+
+				if complete {
+					var value any
+					decoder := json.NewDecoder(bytes.NewReader(body))
+					decoder.UseNumber()
+
+					if err := decoder.Decode(&value); err != nil {
+						apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+						return
+					}
+
+					errors = append(errors, document.validate(media.Schema, value, "")...)
+				}
+			}
+		}
+
+		if len(errors) > 0 {
+			middleware.GetLogger(c).Warn("openapi_request_invalid",
+				zap.String("operation", operation.OperationID),
+				zap.Int("errors_count", len(errors)),
+			)
+			apierror.Abort(c, apierror.Validation(errors...))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ValidateResponses checks JSON responses against the documented status codes
+// and schemas. Mismatches are logged, never returned to the client.
+func (s *Spec) ValidateResponses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation, document := s.operation(c.Request.Method, c.FullPath())
+		if operation == nil {
+			c.Next()
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := c.Writer.Status()
+		response, ok := operation.Responses[strconv.Itoa(status)]
+		if !ok {
+			middleware.GetLogger(c).Warn("openapi_response_undocumented_status",
+				zap.String("operation", operation.OperationID),
+				zap.Int("status", status),
+			)
+			return
+		}
+
+		contentType, _, _ := strings.Cut(c.Writer.Header().Get("Content-Type"), ";")
+		contentType = strings.TrimSpace(contentType)
+		media, ok := response.Content[contentType]
+		if !ok || writer.truncated || !strings.HasSuffix(contentType, "json") {
+			return
+		}
+
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(writer.body.Bytes()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			middleware.GetLogger(c).Warn("openapi_response_invalid_json",
+				zap.String("operation", operation.OperationID),
+				zap.Int("status", status),
+				zap.Error(err),
+			)
+			return
+		}
+
+		if errors := document.validate(media.Schema, value, ""); len(errors) > 0 {
+			fields := make([]string, 0, len(errors))
+			for _, fieldError := range errors {
+				fields = append(fields, fieldError.Field+": "+fieldError.Message)
+			}
+			middleware.GetLogger(c).Warn("openapi_response_mismatch",
+				zap.String("operation", operation.OperationID),
+				zap.Int("status", status),
+				zap.Strings("errors", fields),
+			)
+		}
+	}
+}
+
+type capturingWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(data string) (int, error) {
+	w.capture([]byte(data))
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Unwrap lets http.ResponseController reach the connection, so streaming
+// handlers can extend their write deadline.
+func (w *capturingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *capturingWriter) capture(data []byte) {
+	if w.body.Len()+len(data) > maxValidatedResponseBytes {
+		w.truncated = true
+		return
+	}
+	w.body.Write(data)
+}
+
+func readBody(r *http.Request) ([]byte, bool, error) {
+	if r.Body == nil {
+		return nil, true, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBodyBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(body) > maxValidatedBodyBytes {
+		r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+		return nil, false, nil
+	}
+
+	r.Body = readCloser{Reader: bytes.NewReader(body), Closer: r.Body}
+	return body, true, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func coerceParameter(schema *Schema, value string) any {
+	switch {
+	case schema.Type.Has("integer"), schema.Type.Has("number"):
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case schema.Type.Has("boolean"):
+		if flag, err := strconv.ParseBool(value); err == nil {
+			return flag
+		}
+	}
+	return value
+}
+
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		schema = d.Components.Schemas[name]
+	}
+	return schema
+}
+
+func (d *Document) validate(schema *Schema, value any, path string) []models.FieldError {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	fail := func(code apierror.Code, params map[string]any) []models.FieldError {
+		field := path
+		if field == "" {
+			field = "general"
+		}
+		return []models.FieldError{apierror.Field(field, code, params)}
+	}
+
+	if len(schema.Type) > 0 && !matchesType(schema.Type, value) {
+		return fail(apierror.FieldInvalidType, map[string]any{"expected": []string(schema.Type)})
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fail(apierror.FieldNotAllowed, map[string]any{"allowed": schema.Enum})
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return d.validateString(schema, typed, fail)
+	case json.Number:
+		number, _ := typed.Float64()
+		if schema.Minimum != nil && number < *schema.Minimum {
+			return fail(apierror.FieldTooSmall, map[string]any{"min": *schema.Minimum})
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			return fail(apierror.FieldTooLarge, map[string]any{"max": *schema.Maximum})
+		}
+	case []any:
+		if schema.MinItems != nil && len(typed) < *schema.MinItems {
+			return fail(apierror.FieldTooFewItems, map[string]any{"min": *schema.MinItems})
+		}
+		if schema.MaxItems != nil && len(typed) > *schema.MaxItems {
+			return fail(apierror.FieldTooManyItems, map[string]any{"max": *schema.MaxItems})
+		}
+
+		errors := []models.FieldError{}
+		for i, item := range typed {
+			errors = append(errors, d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errors
+	case map[string]any:
+		if schema.MinProperties != nil && len(typed) < *schema.MinProperties {
+			return fail(apierror.FieldTooFewItems, map[string]any{"min": *schema.MinProperties})
+		}
+		if schema.MaxProperties != nil && len(typed) > *schema.MaxProperties {
+			return fail(apierror.FieldTooManyItems, map[string]any{"max": *schema.MaxProperties})
+		}
+
+		errors := []models.FieldError{}
+		for _, required := range schema.Required {
+			if _, ok := typed[required]; !ok {
+				errors = append(errors, apierror.Field(joinPath(path, required), apierror.FieldRequired, nil))
+			}
+		}
+
+		for _, key := range sortedKeys(typed) {
+			item := typed[key]
+			if property, ok := schema.Properties[key]; ok {
+				errors = append(errors, d.validate(property, item, joinPath(path, key))...)
+			} else if schema.AdditionalProperties != nil {
+				errors = append(errors, d.validate(schema.AdditionalProperties, item, joinPath(path, key))...)
+			}
+		}
+		return errors
+	}
+
+	return nil
+}
+
+func (d *Document) validateString(schema *Schema, value string, fail func(apierror.Code, map[string]any) []models.FieldError) []models.FieldError {
+	length := utf8.RuneCountInString(value)
+
+	if schema.MinLength != nil && length < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			return fail(apierror.FieldRequired, nil)
+		}
+		return fail(apierror.FieldTooShort, map[string]any{"min": *schema.MinLength})
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return fail(apierror.FieldTooLong, map[string]any{"max": *schema.MaxLength})
+	}
+
+	switch schema.Format {
+	case "email":
+		if !emailFormatRegex.MatchString(value) {
+			return fail(apierror.FieldInvalidEmail, nil)
+		}
+	case "uuid":
+		if _, err := uuid.Parse(value); err != nil {
+			return fail(apierror.FieldInvalidFormat, map[string]any{"format": "uuid"})
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fail(apierror.FieldInvalidFormat, map[string]any{"format": "date-time"})
+		}
+	}
+
+	if schema.Pattern != "" {
+		pattern, err := compilePattern(schema.Pattern)
+		if err == nil && !pattern.MatchString(value) {
+			return fail(apierror.FieldInvalidFormat, map[string]any{"pattern": schema.Pattern})
+		}
+	}
+
+	return nil
+}
+
+func compilePattern(expression string) (*regexp.Regexp, error) {
+	if cached, ok := patternCache.Load(expression); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	pattern, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	patternCache.Store(expression, pattern)
+	return pattern, nil
+}
+
+func matchesType(types SchemaType, value any) bool {
+	for _, name := range types {
+		switch name {
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "integer":
+			if number, ok := value.(json.Number); ok {
+				if _, err := number.Int64(); err == nil {
+					return true
+				}
+			}
+		case "number":
+			if _, ok := value.(json.Number); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]any); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]any); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(options []any, value any) bool {
+	for _, option := range options {
+		if fmt.Sprint(option) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
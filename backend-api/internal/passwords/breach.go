@@ -0,0 +1,78 @@
+package passwords
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type BreachStore interface {
+	// Count returns how often password appears in the breach corpus.
+	Count(ctx context.Context, password string) (int, error)
+}
+
+// RangeDirectory is an offline copy of the Pwned Passwords range dataset:
+// one <PREFIX>.txt file per five-character SHA-1 prefix, each holding
+// "SUFFIX:COUNT" lines. A lookup only ever reads the file for its prefix,
+// the same k-anonymity split the online range API uses.
+type RangeDirectory struct {
+	root string
+}
+
+func OpenRangeDirectory(root string) (*RangeDirectory, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	return &RangeDirectory{root: root}, nil
+}
+
+func (d *RangeDirectory) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return d.countSuffix(ctx, hash[:5], hash[5:])
+}
+
+func (d *RangeDirectory) countSuffix(ctx context.Context, prefix, suffix string) (int, error) {
+	file, err := os.Open(filepath.Join(d.root, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lines := 0; scanner.Scan(); lines++ {
+		if lines%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("%s.txt: invalid count for %s", prefix, candidate)
+		}
+		return parsed, nil
+	}
+
+	return 0, scanner.Err()
+}
@@ -0,0 +1,234 @@
+package passwords
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+	"unicode"
+)
+
+const (
+	DictionaryPasswords  = "passwords"
+	DictionaryWords      = "words"
+	DictionaryUserInputs = "user_inputs"
+)
+
+//go:embed dictionaries/*.txt
+var embeddedDictionaries embed.FS
+
+var builtinDictionaries = map[string]map[string]int{
+	DictionaryPasswords: mustLoadDictionary("dictionaries/passwords.txt"),
+	DictionaryWords:     mustLoadDictionary("dictionaries/words.txt"),
+}
+
+// l33tTables lists the substitutions tried when matching dictionary words.
+// Characters that stand in for more than one letter get a table each.
+var l33tTables = []map[rune]rune{
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '9': 'g', '1': 'i', '!': 'i', '|': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '9': 'g', '1': 'l', '!': 'l', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+}
+
+func mustLoadDictionary(name string) map[string]int {
+	file, err := embeddedDictionaries.Open(name)
+	if err != nil {
+		panic("passwords: " + err.Error())
+	}
+	defer file.Close()
+
+	ranked := map[string]int{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if _, exists := ranked[word]; word != "" && !exists {
+			ranked[word] = len(ranked) + 1
+		}
+	}
+	return ranked
+}
+
+func rankedInputs(inputs []string) map[string]int {
+	ranked := map[string]int{}
+	for _, input := range inputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if _, exists := ranked[input]; input != "" && !exists {
+			ranked[input] = len(ranked) + 1
+		}
+	}
+	return ranked
+}
+
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func dictionaryMatches(runes []rune, dictionaries map[string]map[string]int) []Match {
+	lower := lowerRunes(runes)
+	matches := []Match{}
+
+	for i := range lower {
+		for j := i; j < len(lower); j++ {
+			word := string(lower[i : j+1])
+			for name, ranked := range dictionaries {
+				rank, ok := ranked[word]
+				if !ok {
+					continue
+				}
+
+				token := string(runes[i : j+1])
+				matches = append(matches, Match{
+					Pattern:    PatternDictionary,
+					I:          i,
+					J:          j,
+					Token:      token,
+					Dictionary: name,
+					Rank:       rank,
+					Guesses:    float64(rank) * uppercaseVariations(token),
+				})
+			}
+		}
+	}
+
+	return matches
+}
+
+func reverseDictionaryMatches(runes []rune, dictionaries map[string]map[string]int) []Match {
+	n := len(runes)
+	reversed := make([]rune, n)
+	for i, r := range runes {
+		reversed[n-1-i] = r
+	}
+
+	matches := dictionaryMatches(reversed, dictionaries)
+	for k := range matches {
+		i, j := n-1-matches[k].J, n-1-matches[k].I
+		matches[k].I, matches[k].J = i, j
+		matches[k].Token = string(runes[i : j+1])
+		matches[k].Reversed = true
+		matches[k].Guesses *= 2
+	}
+	return matches
+}
+
+func l33tMatches(runes []rune, dictionaries map[string]map[string]int) []Match {
+	matches := []Match{}
+	seen := map[[2]int]bool{}
+
+	for _, table := range l33tTables {
+		translated := make([]rune, len(runes))
+		substituted := false
+		for i, r := range runes {
+			if letter, ok := table[r]; ok {
+				translated[i] = letter
+				substituted = true
+			} else {
+				translated[i] = r
+			}
+		}
+		if !substituted {
+			continue
+		}
+
+		for _, match := range dictionaryMatches(translated, dictionaries) {
+			token := runes[match.I : match.J+1]
+			if match.I == match.J || string(lowerRunes(token)) == string(lowerRunes(translated[match.I:match.J+1])) {
+				continue
+			}
+
+			key := [2]int{match.I, match.J}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			match.Token = string(token)
+			match.L33t = true
+			match.Guesses = float64(match.Rank) * uppercaseVariations(match.Token) * l33tVariations(token, table)
+			matches = append(matches, match)
+		}
+	}
+
+	return matches
+}
+
+// uppercaseVariations estimates how many capitalisations of a word an
+// attacker tries before this one: common shapes cost a factor of two, others
+// the number of ways to place that many capitals.
+func uppercaseVariations(token string) float64 {
+	upper, lower := 0, 0
+	runes := []rune(token)
+	for _, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	if upper == 0 {
+		return 1
+	}
+
+	first := unicode.IsUpper(runes[0])
+	last := unicode.IsUpper(runes[len(runes)-1])
+	if lower == 0 || (upper == 1 && (first || last)) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+func l33tVariations(token []rune, table map[rune]rune) float64 {
+	variations := 1.0
+	counted := map[rune]bool{}
+
+	for _, r := range token {
+		letter, ok := table[r]
+		if !ok || counted[r] {
+			continue
+		}
+		counted[r] = true
+
+		substituted, unsubstituted := 0, 0
+		for _, other := range token {
+			switch {
+			case other == r:
+				substituted++
+			case unicode.ToLower(other) == letter:
+				unsubstituted++
+			}
+		}
+
+		if unsubstituted == 0 {
+			variations *= 2
+			continue
+		}
+
+		possibilities := 0.0
+		for i := 1; i <= min(substituted, unsubstituted); i++ {
+			possibilities += binomial(substituted+unsubstituted, i)
+		}
+		variations *= possibilities
+	}
+
+	return variations
+}
+
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
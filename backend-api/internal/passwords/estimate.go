@@ -0,0 +1,239 @@
+package passwords
+
+import (
+	"maps"
+	"math"
+	"sort"
+	"time"
+)
+
+type Pattern string
+
+const (
+	PatternDictionary Pattern = "dictionary"
+	PatternSpatial    Pattern = "spatial"
+	PatternRepeat     Pattern = "repeat"
+	PatternSequence   Pattern = "sequence"
+	PatternDate       Pattern = "date"
+	PatternBruteforce Pattern = "bruteforce"
+)
+
+const (
+	// maxEstimatedLength bounds the quadratic matching work; characters past
+	// it only add bruteforce guesses.
+	maxEstimatedLength = 100
+
+	bruteforceCardinality         = 10
+	minSubmatchGuessesSingleChar  = 10
+	minSubmatchGuessesMultiChar   = 50
+	minGuessesBeforeGrowingLength = 10000
+)
+
+// Match is one pattern found in the password, spanning runes I through J.
+type Match struct {
+	Pattern Pattern
+	I, J    int
+	Token   string
+	Guesses float64
+
+	Dictionary string
+	Rank       int
+	Reversed   bool
+	L33t       bool
+
+	Keyboard string
+	Turns    int
+
+	Base    string
+	Repeats int
+
+	Year int
+}
+
+type Result struct {
+	Guesses      float64
+	GuessesLog10 float64
+	Score        int
+	Sequence     []Match
+	Feedback     Feedback
+}
+
+// Estimate approximates how many guesses an attacker who knows common
+// passwords, words, keyboard walks, sequences and dates needs for password,
+// in the manner of zxcvbn. userInputs are treated as the most likely words.
+func Estimate(password string, userInputs ...string) Result {
+	dictionaries := maps.Clone(builtinDictionaries)
+	if inputs := rankedInputs(userInputs); len(inputs) > 0 {
+		dictionaries[DictionaryUserInputs] = inputs
+	}
+
+	runes := []rune(password)
+	overflow := 0
+	if len(runes) > maxEstimatedLength {
+		overflow = len(runes) - maxEstimatedLength
+		runes = runes[:maxEstimatedLength]
+	}
+
+	result := estimate(runes, dictionaries)
+	if overflow > 0 {
+		result.Guesses *= math.Pow(bruteforceCardinality, float64(overflow))
+	}
+
+	result.GuessesLog10 = math.Log10(result.Guesses)
+	result.Score = score(result.Guesses)
+	result.Feedback = feedback(result.Score, result.Sequence)
+	return result
+}
+
+func estimate(runes []rune, dictionaries map[string]map[string]int) Result {
+	matches := []Match{}
+	matches = append(matches, dictionaryMatches(runes, dictionaries)...)
+	matches = append(matches, reverseDictionaryMatches(runes, dictionaries)...)
+	matches = append(matches, l33tMatches(runes, dictionaries)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, dictionaries)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes, time.Now().Year())...)
+
+	sequence, guesses := mostGuessableSequence(runes, matches)
+	return Result{Guesses: guesses, Sequence: sequence}
+}
+
+type step struct {
+	match   Match
+	product float64
+	total   float64
+}
+
+// mostGuessableSequence finds the non-overlapping cover of the password by
+// matches and bruteforce runs that needs the fewest guesses. Longer
+// sequences are penalised because an attacker must also guess their order.
+func mostGuessableSequence(runes []rune, matches []Match) ([]Match, float64) {
+	n := len(runes)
+	if n == 0 {
+		return nil, 1
+	}
+
+	byEnd := make([][]Match, n)
+	for _, match := range matches {
+		byEnd[match.J] = append(byEnd[match.J], match)
+	}
+	for k := range byEnd {
+		sort.SliceStable(byEnd[k], func(a, b int) bool { return byEnd[k][a].I < byEnd[k][b].I })
+	}
+
+	// best[k][l] is the cheapest sequence of l matches covering runes 0..k.
+	best := make([][]*step, n)
+	for k := range best {
+		best[k] = make([]*step, n+2)
+	}
+
+	update := func(match Match, length int) {
+		product := matchGuesses(match, n)
+		if length > 1 {
+			product *= best[match.I-1][length-1].product
+		}
+		total := factorial(length)*product + math.Pow(minGuessesBeforeGrowingLength, float64(length-1))
+
+		for shorter := 1; shorter <= length; shorter++ {
+			if candidate := best[match.J][shorter]; candidate != nil && candidate.total <= total {
+				return
+			}
+		}
+		best[match.J][length] = &step{match: match, product: product, total: total}
+	}
+
+	bruteforce := func(i, j int) Match {
+		return Match{Pattern: PatternBruteforce, I: i, J: j, Token: string(runes[i : j+1])}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, match := range byEnd[k] {
+			if match.I == 0 {
+				update(match, 1)
+				continue
+			}
+			for length, previous := range best[match.I-1] {
+				if previous != nil {
+					update(match, length+1)
+				}
+			}
+		}
+
+		update(bruteforce(0, k), 1)
+		for i := 1; i <= k; i++ {
+			for length, previous := range best[i-1] {
+				if previous != nil && previous.match.Pattern != PatternBruteforce {
+					update(bruteforce(i, k), length+1)
+				}
+			}
+		}
+	}
+
+	length := 0
+	for l, candidate := range best[n-1] {
+		if candidate != nil && (length == 0 || candidate.total < best[n-1][length].total) {
+			length = l
+		}
+	}
+
+	guesses := best[n-1][length].total
+	sequence := make([]Match, length)
+	for k := n - 1; k >= 0; length-- {
+		current := best[k][length]
+		current.match.Guesses = matchGuesses(current.match, n)
+		sequence[length-1] = current.match
+		k = current.match.I - 1
+	}
+
+	return sequence, guesses
+}
+
+func matchGuesses(match Match, passwordLength int) float64 {
+	guesses := match.Guesses
+	length := match.J - match.I + 1
+
+	if match.Pattern == PatternBruteforce {
+		guesses = math.Min(math.Pow(bruteforceCardinality, float64(length)), math.MaxFloat64)
+		if length == 1 {
+			guesses = math.Max(guesses, minSubmatchGuessesSingleChar+1)
+		} else {
+			guesses = math.Max(guesses, minSubmatchGuessesMultiChar+1)
+		}
+	}
+
+	if length < passwordLength {
+		if length == 1 {
+			return math.Max(guesses, minSubmatchGuessesSingleChar)
+		}
+		return math.Max(guesses, minSubmatchGuessesMultiChar)
+	}
+	return math.Max(guesses, 1)
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+	return result
+}
+
+// score maps guesses onto 0-4 using the thresholds zxcvbn uses, from "too
+// guessable" to "very unguessable" against an offline attack.
+func score(guesses float64) int {
+	const delta = 5
+
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
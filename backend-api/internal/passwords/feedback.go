@@ -0,0 +1,134 @@
+package passwords
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Feedback holds message catalogue keys rather than text so callers can
+// render them in the request's locale.
+type Feedback struct {
+	Warning     string
+	Suggestions []string
+}
+
+const (
+	WarningTop10           = "password.warning.top_10"
+	WarningTop100          = "password.warning.top_100"
+	WarningCommon          = "password.warning.common"
+	WarningSimilarToCommon = "password.warning.similar_to_common"
+	WarningWordByItself    = "password.warning.word_by_itself"
+	WarningUserInputs      = "password.warning.user_inputs"
+	WarningStraightRow     = "password.warning.straight_row"
+	WarningKeyboardPattern = "password.warning.keyboard_pattern"
+	WarningRepeatedChar    = "password.warning.repeated_char"
+	WarningRepeatedPattern = "password.warning.repeated_pattern"
+	WarningSequence        = "password.warning.sequence"
+	WarningYears           = "password.warning.years"
+	WarningDates           = "password.warning.dates"
+
+	SuggestionAddWord           = "password.suggestion.add_word"
+	SuggestionNoNeedForSymbols  = "password.suggestion.no_need_for_symbols"
+	SuggestionLongerKeyboard    = "password.suggestion.longer_keyboard_pattern"
+	SuggestionAvoidRepeats      = "password.suggestion.avoid_repeats"
+	SuggestionAvoidSequences    = "password.suggestion.avoid_sequences"
+	SuggestionAvoidDates        = "password.suggestion.avoid_dates"
+	SuggestionCapitalization    = "password.suggestion.capitalization"
+	SuggestionAllUppercase      = "password.suggestion.all_uppercase"
+	SuggestionReversedWords     = "password.suggestion.reversed_words"
+	SuggestionPredictableL33t   = "password.suggestion.predictable_substitutions"
+	SuggestionAvoidPersonalInfo = "password.suggestion.avoid_personal_info"
+)
+
+func feedback(score int, sequence []Match) Feedback {
+	if len(sequence) == 0 {
+		return Feedback{Suggestions: []string{SuggestionAddWord, SuggestionNoNeedForSymbols}}
+	}
+	if score > 2 {
+		return Feedback{}
+	}
+
+	longest := sequence[0]
+	for _, match := range sequence[1:] {
+		if utf8.RuneCountInString(match.Token) > utf8.RuneCountInString(longest.Token) {
+			longest = match
+		}
+	}
+
+	result := matchFeedback(longest, len(sequence) == 1)
+	result.Suggestions = append([]string{SuggestionAddWord}, result.Suggestions...)
+	return result
+}
+
+func matchFeedback(match Match, whole bool) Feedback {
+	switch match.Pattern {
+	case PatternDictionary:
+		return dictionaryFeedback(match, whole)
+	case PatternSpatial:
+		if match.Turns == 1 {
+			return Feedback{Warning: WarningStraightRow, Suggestions: []string{SuggestionLongerKeyboard}}
+		}
+		return Feedback{Warning: WarningKeyboardPattern, Suggestions: []string{SuggestionLongerKeyboard}}
+	case PatternRepeat:
+		if utf8.RuneCountInString(match.Base) == 1 {
+			return Feedback{Warning: WarningRepeatedChar, Suggestions: []string{SuggestionAvoidRepeats}}
+		}
+		return Feedback{Warning: WarningRepeatedPattern, Suggestions: []string{SuggestionAvoidRepeats}}
+	case PatternSequence:
+		return Feedback{Warning: WarningSequence, Suggestions: []string{SuggestionAvoidSequences}}
+	case PatternDate:
+		if utf8.RuneCountInString(match.Token) == 4 {
+			return Feedback{Warning: WarningYears, Suggestions: []string{SuggestionAvoidDates}}
+		}
+		return Feedback{Warning: WarningDates, Suggestions: []string{SuggestionAvoidDates}}
+	}
+	return Feedback{}
+}
+
+func dictionaryFeedback(match Match, whole bool) Feedback {
+	result := Feedback{}
+
+	switch match.Dictionary {
+	case DictionaryPasswords:
+		switch {
+		case whole && !match.L33t && !match.Reversed && match.Rank <= 10:
+			result.Warning = WarningTop10
+		case whole && !match.L33t && !match.Reversed && match.Rank <= 100:
+			result.Warning = WarningTop100
+		case whole && !match.L33t && !match.Reversed:
+			result.Warning = WarningCommon
+		default:
+			result.Warning = WarningSimilarToCommon
+		}
+	case DictionaryWords:
+		if whole {
+			result.Warning = WarningWordByItself
+		}
+	case DictionaryUserInputs:
+		result.Warning = WarningUserInputs
+		result.Suggestions = append(result.Suggestions, SuggestionAvoidPersonalInfo)
+	}
+
+	runes := []rune(match.Token)
+	upper := 0
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == len(runes):
+		result.Suggestions = append(result.Suggestions, SuggestionAllUppercase)
+	case upper == 1 && unicode.IsUpper(runes[0]):
+		result.Suggestions = append(result.Suggestions, SuggestionCapitalization)
+	}
+
+	if match.Reversed {
+		result.Suggestions = append(result.Suggestions, SuggestionReversedWords)
+	}
+	if match.L33t {
+		result.Suggestions = append(result.Suggestions, SuggestionPredictableL33t)
+	}
+
+	return result
+}
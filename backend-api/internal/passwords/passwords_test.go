@@ -0,0 +1,144 @@
+package passwords
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"livecode-api/internal/apierror"
+)
+
+func TestEstimate_Scores(t *testing.T) {
+	cases := []struct {
+		password string
+		maxScore int
+		minScore int
+		warning  string
+	}{
+		{"password", 0, 0, WarningTop10},
+		{"P@ssw0rd", 1, 0, WarningSimilarToCommon},
+		{"qwertyuiop", 1, 0, ""},
+		{"aaaaaaaaaaaa", 1, 0, WarningRepeatedChar},
+		{"abcdefgh", 1, 0, WarningSequence},
+		{"zxcvbnm,./", 1, 0, WarningStraightRow},
+		{"01/02/1990", 2, 0, WarningDates},
+		{"correct horse battery staple", 4, 4, ""},
+		{"Tr0ub4dor&3x!kQ", 4, 3, ""},
+	}
+
+	for _, tc := range cases {
+		result := Estimate(tc.password)
+		if result.Score > tc.maxScore || result.Score < tc.minScore {
+			t.Errorf("%q: expected score in [%d, %d], got: %d (%v)", tc.password, tc.minScore, tc.maxScore, result.Score, result.Sequence)
+		}
+		if tc.warning != "" && result.Feedback.Warning != tc.warning {
+			t.Errorf("%q: expected warning %s, got: %s", tc.password, tc.warning, result.Feedback.Warning)
+		}
+	}
+}
+
+func TestEstimate_UserInputs(t *testing.T) {
+	without := Estimate("adalovelace77")
+	with := Estimate("adalovelace77", "adalovelace")
+
+	if with.Guesses >= without.Guesses {
+		t.Errorf("Expected user inputs to lower the estimate, got: %g >= %g", with.Guesses, without.Guesses)
+	}
+	if with.Feedback.Warning != WarningUserInputs {
+		t.Errorf("Expected user inputs warning, got: %s", with.Feedback.Warning)
+	}
+}
+
+func TestEstimate_SequenceCoversPassword(t *testing.T) {
+	password := "xX9!qwerty2019abcabc"
+	result := Estimate(password)
+
+	next := 0
+	for _, match := range result.Sequence {
+		if match.I != next {
+			t.Fatalf("Expected match to start at %d, got: %+v", next, match)
+		}
+		next = match.J + 1
+	}
+	if next != len([]rune(password)) {
+		t.Errorf("Expected sequence to cover all %d runes, covered %d", len([]rune(password)), next)
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.RequiredClasses = 3
+
+	cases := []struct {
+		name     string
+		password string
+		user     UserContext
+		codes    []apierror.Code
+	}{
+		{"strong", "violet-Harbour-71-lantern", UserContext{Username: "@ada", Email: "ada@example.com"}, nil},
+		{"short", "aB3$", UserContext{}, []apierror.Code{apierror.FieldTooShort, apierror.FieldPasswordGuessable}},
+		{"counts characters, not bytes", "ÄÖÜäöü12", UserContext{}, []apierror.Code{apierror.FieldPasswordGuessable}},
+		{"too long", string(make([]rune, 129)), UserContext{}, []apierror.Code{apierror.FieldTooLong}},
+		{"classes", "violetharbourlantern", UserContext{}, []apierror.Code{apierror.FieldPasswordTooWeak}},
+		{"username", "Xq7!adalovelace-Harbour", UserContext{Username: "@adalovelace"}, []apierror.Code{apierror.FieldPasswordPersonal}},
+		{"email", "Xq7!Harbour-lovelace", UserContext{Email: "lovelace@example.com"}, []apierror.Code{apierror.FieldPasswordPersonal}},
+	}
+
+	for _, tc := range cases {
+		evaluation, err := policy.Evaluate(context.Background(), tc.password, tc.user)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+
+		codes := []apierror.Code{}
+		for _, field := range evaluation.Errors {
+			codes = append(codes, apierror.Code(field.Code))
+		}
+
+		if len(codes) != len(tc.codes) {
+			t.Errorf("%s: expected %v, got: %v", tc.name, tc.codes, codes)
+			continue
+		}
+		for i := range codes {
+			if codes[i] != tc.codes[i] {
+				t.Errorf("%s: expected %v, got: %v", tc.name, tc.codes, codes)
+			}
+		}
+	}
+}
+
+func TestRangeDirectory(t *testing.T) {
+	root := t.TempDir()
+
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	if err := os.WriteFile(filepath.Join(root, "5BAA6.txt"),
+		[]byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenRangeDirectory(root)
+	if err != nil {
+		t.Fatalf("Expected directory to open, got: %v", err)
+	}
+
+	if count, err := store.Count(context.Background(), "password"); err != nil || count != 9545824 {
+		t.Errorf("Expected breach count 9545824, got: %d, %v", count, err)
+	}
+	if count, err := store.Count(context.Background(), "violet-Harbour-71-lantern"); err != nil || count != 0 {
+		t.Errorf("Expected unknown password to have count 0, got: %d, %v", count, err)
+	}
+
+	policy := DefaultPolicy()
+	policy.MinScore = 0
+	policy.Breaches = store
+
+	evaluation, err := policy.Evaluate(context.Background(), "password", UserContext{})
+	if err != nil || evaluation.BreachCount != 9545824 || evaluation.Acceptable() {
+		t.Errorf("Expected breached password to be rejected, got: %+v, %v", evaluation, err)
+	}
+
+	if _, err := OpenRangeDirectory(filepath.Join(root, "5BAA6.txt")); err == nil {
+		t.Error("Expected a file path to be rejected")
+	}
+}
@@ -0,0 +1,356 @@
+package passwords
+
+import (
+	"math"
+	"unicode"
+)
+
+// keyboard is an adjacency graph built from staggered key rows, where each
+// row sits half a key to the right of the one above it.
+type keyboard struct {
+	name      string
+	neighbors map[rune]map[rune]int
+	keys      float64
+	degree    float64
+}
+
+var keyboards = []*keyboard{
+	newKeyboard("qwerty", []string{"1234567890-=", "qwertyuiop[]", "asdfghjkl;'", "zxcvbnm,./"}),
+	newKeyboard("qwertz", []string{"1234567890ß", "qwertzuiopü", "asdfghjklöä", "yxcvbnm,.-"}),
+}
+
+var shiftedDigits = map[rune]rune{'!': '1', '@': '2', '#': '3', '$': '4', '%': '5', '^': '6', '&': '7', '*': '8', '(': '9', ')': '0'}
+
+func newKeyboard(name string, rows []string) *keyboard {
+	grid := make([][]rune, len(rows))
+	for r, row := range rows {
+		grid[r] = []rune(row)
+	}
+
+	at := func(r, c int) (rune, bool) {
+		if r < 0 || r >= len(grid) || c < 0 || c >= len(grid[r]) {
+			return 0, false
+		}
+		return grid[r][c], true
+	}
+
+	offsets := [][2]int{{0, -1}, {0, 1}, {-1, 0}, {-1, 1}, {1, -1}, {1, 0}}
+	k := &keyboard{name: name, neighbors: map[rune]map[rune]int{}}
+	edges := 0
+
+	for r, row := range grid {
+		for c, key := range row {
+			k.neighbors[key] = map[rune]int{}
+			for direction, offset := range offsets {
+				if neighbor, ok := at(r+offset[0], c+offset[1]); ok {
+					k.neighbors[key][neighbor] = direction
+					edges++
+				}
+			}
+		}
+	}
+
+	k.keys = float64(len(k.neighbors))
+	k.degree = float64(edges) / k.keys
+	return k
+}
+
+func (k *keyboard) key(r rune) rune {
+	r = unicode.ToLower(r)
+	if digit, ok := shiftedDigits[r]; ok && k.name == "qwerty" {
+		return digit
+	}
+	return r
+}
+
+func spatialMatches(runes []rune) []Match {
+	matches := []Match{}
+
+	for _, board := range keyboards {
+		for i := 0; i < len(runes)-2; {
+			j, turns, last := i, 0, -1
+			for j+1 < len(runes) {
+				direction, ok := board.neighbors[board.key(runes[j])][board.key(runes[j+1])]
+				if !ok {
+					break
+				}
+				if direction != last {
+					turns++
+					last = direction
+				}
+				j++
+			}
+
+			if j-i+1 >= 3 {
+				matches = append(matches, Match{
+					Pattern:  PatternSpatial,
+					I:        i,
+					J:        j,
+					Token:    string(runes[i : j+1]),
+					Keyboard: board.name,
+					Turns:    turns,
+					Guesses:  board.guesses(j-i+1, turns),
+				})
+				i = j
+				continue
+			}
+			i++
+		}
+	}
+
+	return matches
+}
+
+// guesses counts the walks of the given length and number of turns an
+// attacker enumerates from every starting key.
+func (k *keyboard) guesses(length, turns int) float64 {
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * k.keys * math.Pow(k.degree, float64(j))
+		}
+	}
+	return guesses
+}
+
+func repeatMatches(runes []rune, dictionaries map[string]map[string]int) []Match {
+	matches := []Match{}
+
+	for i := 0; i < len(runes)-1; {
+		bestBase, bestCount := 0, 0
+		for base := 1; base <= (len(runes)-i)/2; base++ {
+			count := 1
+			for i+(count+1)*base <= len(runes) && equalRunes(runes[i:i+base], runes[i+count*base:i+(count+1)*base]) {
+				count++
+			}
+			if count >= 2 && count*base > bestCount*bestBase {
+				bestBase, bestCount = base, count
+			}
+		}
+
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		base := runes[i : i+bestBase]
+		j := i + bestBase*bestCount - 1
+		matches = append(matches, Match{
+			Pattern: PatternRepeat,
+			I:       i,
+			J:       j,
+			Token:   string(runes[i : j+1]),
+			Base:    string(base),
+			Repeats: bestCount,
+			Guesses: estimate(base, dictionaries).Guesses * float64(bestCount),
+		})
+		i = j + 1
+	}
+
+	return matches
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sequenceMatches(runes []rune) []Match {
+	matches := []Match{}
+
+	for i := 0; i < len(runes)-2; {
+		delta := int(runes[i+1]) - int(runes[i])
+		if delta == 0 || delta < -5 || delta > 5 || !sameClass(runes[i], runes[i+1]) {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j+1 < len(runes) && int(runes[j+1])-int(runes[j]) == delta && sameClass(runes[j], runes[j+1]) {
+			j++
+		}
+
+		if j-i+1 >= 3 {
+			base := 26.0
+			switch {
+			case runes[i] == 'a' || runes[i] == 'A' || runes[i] == 'z' || runes[i] == 'Z' || runes[i] == '0' || runes[i] == '1' || runes[i] == '9':
+				base = 4
+			case unicode.IsDigit(runes[i]):
+				base = 10
+			}
+			if delta < 0 {
+				base *= 2
+			}
+
+			matches = append(matches, Match{
+				Pattern: PatternSequence,
+				I:       i,
+				J:       j,
+				Token:   string(runes[i : j+1]),
+				Guesses: base * float64(j-i+1),
+			})
+		}
+		i = j
+	}
+
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	switch {
+	case unicode.IsLower(a):
+		return unicode.IsLower(b)
+	case unicode.IsUpper(a):
+		return unicode.IsUpper(b)
+	case unicode.IsDigit(a):
+		return unicode.IsDigit(b)
+	}
+	return false
+}
+
+const minYearSpace = 20
+
+func dateMatches(runes []rune, referenceYear int) []Match {
+	matches := []Match{}
+
+	yearSpace := func(year int) float64 {
+		return math.Max(math.Abs(float64(year-referenceYear)), minYearSpace)
+	}
+
+	for i := range runes {
+		if year, ok := digitsAt(runes, i, 4); ok && year >= 1900 && year <= 2049 {
+			matches = append(matches, Match{
+				Pattern: PatternDate,
+				I:       i,
+				J:       i + 3,
+				Token:   string(runes[i : i+4]),
+				Year:    year,
+				Guesses: yearSpace(year),
+			})
+		}
+
+		for _, length := range []int{6, 8} {
+			if year, ok := compactDate(runes, i, length); ok {
+				matches = append(matches, Match{
+					Pattern: PatternDate,
+					I:       i,
+					J:       i + length - 1,
+					Token:   string(runes[i : i+length]),
+					Year:    year,
+					Guesses: 365 * yearSpace(year),
+				})
+			}
+		}
+
+		if j, year, ok := separatedDate(runes, i); ok {
+			matches = append(matches, Match{
+				Pattern: PatternDate,
+				I:       i,
+				J:       j,
+				Token:   string(runes[i : j+1]),
+				Year:    year,
+				Guesses: 365 * yearSpace(year) * 4,
+			})
+		}
+	}
+
+	return matches
+}
+
+func digitsAt(runes []rune, i, length int) (int, bool) {
+	if i+length > len(runes) {
+		return 0, false
+	}
+	value := 0
+	for _, r := range runes[i : i+length] {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+		value = value*10 + int(r-'0')
+	}
+	return value, true
+}
+
+// compactDate recognises day-month-year, month-day-year and year-month-day
+// runs of digits without separators.
+func compactDate(runes []rune, i, length int) (int, bool) {
+	if _, ok := digitsAt(runes, i, length); !ok {
+		return 0, false
+	}
+
+	yearLength := length - 4
+	a, _ := digitsAt(runes, i, 2)
+	b, _ := digitsAt(runes, i+2, 2)
+	if year, _ := digitsAt(runes, i+4, yearLength); validYear(year, yearLength) && (validDayMonth(a, b) || validDayMonth(b, a)) {
+		return expandYear(year, yearLength), true
+	}
+
+	year, _ := digitsAt(runes, i, yearLength)
+	month, _ := digitsAt(runes, i+yearLength, 2)
+	day, _ := digitsAt(runes, i+yearLength+2, 2)
+	if validYear(year, yearLength) && validDayMonth(day, month) {
+		return expandYear(year, yearLength), true
+	}
+
+	return 0, false
+}
+
+func separatedDate(runes []rune, i int) (int, int, bool) {
+	readNumber := func(at, maxLength int) (int, int) {
+		for length := maxLength; length >= 1; length-- {
+			if value, ok := digitsAt(runes, at, length); ok {
+				return value, length
+			}
+		}
+		return 0, 0
+	}
+
+	first, firstLength := readNumber(i, 2)
+	if firstLength == 0 || i+firstLength >= len(runes) {
+		return 0, 0, false
+	}
+
+	separator := runes[i+firstLength]
+	switch separator {
+	case '-', '/', '.', '_', ' ':
+	default:
+		return 0, 0, false
+	}
+
+	secondStart := i + firstLength + 1
+	second, secondLength := readNumber(secondStart, 2)
+	if secondLength == 0 || secondStart+secondLength >= len(runes) || runes[secondStart+secondLength] != separator {
+		return 0, 0, false
+	}
+
+	yearStart := secondStart + secondLength + 1
+	for _, yearLength := range []int{4, 2} {
+		if year, ok := digitsAt(runes, yearStart, yearLength); ok && validYear(year, yearLength) && (validDayMonth(first, second) || validDayMonth(second, first)) {
+			return yearStart + yearLength - 1, expandYear(year, yearLength), true
+		}
+	}
+
+	return 0, 0, false
+}
+
+func validDayMonth(day, month int) bool {
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+func validYear(year, length int) bool {
+	return length == 2 || (year >= 1900 && year <= 2049)
+}
+
+func expandYear(year, length int) int {
+	if length == 4 {
+		return year
+	}
+	if year > 50 {
+		return 1900 + year
+	}
+	return 2000 + year
+}
@@ -0,0 +1,195 @@
+package passwords
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/models"
+)
+
+// MaxInputLength caps any password the API accepts, whatever the policy, so
+// hashing and estimation stay cheap.
+const MaxInputLength = 1024
+
+const minUserInputLength = 3
+
+// Policy is the per-deployment password policy. Lengths are in characters.
+type Policy struct {
+	MinLength         int
+	MaxLength         int
+	RequiredClasses   int
+	MinScore          int
+	RejectUserContext bool
+	Breaches          BreachStore
+	BreachThreshold   int
+}
+
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MinLength:         8,
+		MaxLength:         128,
+		MinScore:          3,
+		RejectUserContext: true,
+		BreachThreshold:   1,
+	}
+}
+
+func (p *Policy) Validate() error {
+	switch {
+	case p.MinLength < 1:
+		return errors.New("minimum password length must be at least 1")
+	case p.MaxLength < p.MinLength:
+		return errors.New("maximum password length must not be below the minimum")
+	case p.MaxLength > MaxInputLength:
+		return errors.New("maximum password length must not exceed 1024")
+	case p.RequiredClasses < 0 || p.RequiredClasses > 4:
+		return errors.New("required character classes must be between 0 and 4")
+	case p.MinScore < 0 || p.MinScore > 4:
+		return errors.New("minimum password score must be between 0 and 4")
+	case p.Breaches != nil && p.BreachThreshold < 1:
+		return errors.New("breach threshold must be at least 1")
+	}
+	return nil
+}
+
+type UserContext struct {
+	Username string
+	Email    string
+}
+
+// inputs lists the personal strings a password must not contain: the
+// username without its @ and the email with its local part and domain name.
+func (u UserContext) inputs() map[string]string {
+	inputs := map[string]string{}
+	add := func(kind, value string) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if utf8.RuneCountInString(value) >= minUserInputLength {
+			inputs[value] = kind
+		}
+	}
+
+	add("username", strings.TrimPrefix(u.Username, "@"))
+
+	add("email", u.Email)
+	if local, domain, ok := strings.Cut(u.Email, "@"); ok {
+		add("email", local)
+		add("email", strings.Split(domain, ".")[0])
+	}
+
+	return inputs
+}
+
+type Evaluation struct {
+	Strength    Result
+	BreachCount int
+	Errors      []models.FieldError
+}
+
+func (e Evaluation) Acceptable() bool {
+	return len(e.Errors) == 0
+}
+
+// Evaluate checks password against every rule of the policy. A failing breach
+// lookup is returned as err alongside the other results, so callers can
+// decide whether to fail open.
+func (p *Policy) Evaluate(ctx context.Context, password string, user UserContext) (Evaluation, error) {
+	evaluation := Evaluation{Errors: []models.FieldError{}}
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		evaluation.Errors = append(evaluation.Errors, apierror.Field("password", apierror.FieldTooShort, map[string]any{"min": p.MinLength}))
+	}
+	if length > p.MaxLength {
+		evaluation.Errors = append(evaluation.Errors, apierror.Field("password", apierror.FieldTooLong, map[string]any{"max": p.MaxLength}))
+		return evaluation, nil
+	}
+
+	if missing := missingClasses(password); 4-len(missing) < p.RequiredClasses {
+		evaluation.Errors = append(evaluation.Errors, apierror.Field("password", apierror.FieldPasswordTooWeak,
+			map[string]any{"required": p.RequiredClasses, "missing": missing}))
+	}
+
+	inputs := user.inputs()
+	if p.RejectUserContext {
+		lower := strings.ToLower(password)
+		for _, kind := range []string{"username", "email"} {
+			if containsInput(lower, inputs, kind) {
+				evaluation.Errors = append(evaluation.Errors, apierror.Field("password", apierror.FieldPasswordPersonal,
+					map[string]any{"matches": kind}))
+				break
+			}
+		}
+	}
+
+	userInputs := make([]string, 0, len(inputs))
+	for input := range inputs {
+		userInputs = append(userInputs, input)
+	}
+
+	evaluation.Strength = Estimate(password, userInputs...)
+	if evaluation.Strength.Score < p.MinScore {
+		evaluation.Errors = append(evaluation.Errors, apierror.Field("password", apierror.FieldPasswordGuessable,
+			map[string]any{"score": evaluation.Strength.Score, "min_score": p.MinScore}))
+	}
+
+	if p.Breaches == nil || password == "" {
+		return evaluation, nil
+	}
+
+	count, err := p.Breaches.Count(ctx, password)
+	if err != nil {
+		return evaluation, err
+	}
+
+	evaluation.BreachCount = count
+	if count >= p.BreachThreshold {
+		evaluation.Errors = append(evaluation.Errors, apierror.Field("password", apierror.FieldPasswordBreached,
+			map[string]any{"count": count}))
+	}
+
+	return evaluation, nil
+}
+
+func containsInput(password string, inputs map[string]string, kind string) bool {
+	for input, inputKind := range inputs {
+		if inputKind == kind && strings.Contains(password, input) {
+			return true
+		}
+	}
+	return false
+}
+
+func missingClasses(password string) []string {
+	var lower, upper, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			special = true
+		}
+	}
+
+	missing := []string{}
+	if !lower {
+		missing = append(missing, "lowercase")
+	}
+	if !upper {
+		missing = append(missing, "uppercase")
+	}
+	if !digit {
+		missing = append(missing, "digit")
+	}
+	if !special {
+		missing = append(missing, "special")
+	}
+	return missing
+}
@@ -0,0 +1,86 @@
+package pow
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// globalWeight dampens abuse seen across all clients relative to abuse from
+// the requesting IP, so one noisy client barely slows down everyone else.
+const globalWeight = 10
+
+type score struct {
+	value float64
+	at    time.Time
+}
+
+// decayed returns the score at now, halving every halfLife.
+func (s score) decayed(now time.Time, halfLife time.Duration) float64 {
+	if s.at.IsZero() {
+		return 0
+	}
+	return s.value * math.Exp2(-now.Sub(s.at).Seconds()/halfLife.Seconds())
+}
+
+// Adaptive raises the difficulty for an IP by one bit every time its recent
+// rate-limit rejections double, plus a smaller rise for rejections across
+// all clients. Scores decay with HalfLife, so difficulty falls back to Base
+// once the abuse stops.
+type Adaptive struct {
+	Base     int
+	Max      int
+	HalfLife time.Duration
+
+	mu        sync.Mutex
+	ips       map[string]score
+	global    score
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewAdaptive(base, max int, halfLife time.Duration) *Adaptive {
+	return &Adaptive{
+		Base:     base,
+		Max:      max,
+		HalfLife: halfLife,
+		ips:      map[string]score{},
+		now:      time.Now,
+	}
+}
+
+// ObserveAbuse implements middleware.AbuseObserver.
+func (a *Adaptive) ObserveAbuse(limiter, ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	a.ips[ip] = score{value: a.ips[ip].decayed(now, a.HalfLife) + 1, at: now}
+	a.global = score{value: a.global.decayed(now, a.HalfLife) + 1, at: now}
+	a.sweep(now)
+}
+
+func (a *Adaptive) Difficulty(ip string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	extra := math.Log2(1+a.ips[ip].decayed(now, a.HalfLife)) +
+		math.Log2(1+a.global.decayed(now, a.HalfLife)/globalWeight)
+
+	return min(a.Base+int(extra), a.Max)
+}
+
+// sweep forgets IPs whose score has decayed to nothing. Callers hold mu.
+func (a *Adaptive) sweep(now time.Time) {
+	if now.Sub(a.lastSweep) < time.Minute {
+		return
+	}
+	a.lastSweep = now
+
+	for ip, s := range a.ips {
+		if s.decayed(now, a.HalfLife) < 0.5 {
+			delete(a.ips, ip)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Algorithm = "sha256"
+
+	ScopeRegister   = "register"
+	ScopeCheckField = "check_field"
+
+	maxNonceLength = 64
+)
+
+var Scopes = []string{ScopeRegister, ScopeCheckField}
+
+var (
+	ErrMalformed          = errors.New("malformed challenge")
+	ErrInvalidSignature   = errors.New("challenge signature does not match")
+	ErrWrongScope         = errors.New("challenge was issued for another route")
+	ErrExpired            = errors.New("challenge has expired")
+	ErrInsufficientWork   = errors.New("nonce does not meet the challenge difficulty")
+	ErrAlreadyUsed        = errors.New("challenge has already been used")
+	errUnsupportedVersion = errors.New("unsupported challenge version")
+)
+
+// Challenge is what a client must solve: find a nonce such that
+// SHA-256(token + ":" + nonce) starts with Difficulty zero bits.
+type Challenge struct {
+	Token      string
+	Scope      string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+// Issuer signs challenges and verifies solutions. Challenges are stateless
+// until solved; solved ones are remembered until they expire so each is
+// accepted once. They are bound to the scope and client IP they were issued
+// for.
+type Issuer struct {
+	key        []byte
+	ttl        time.Duration
+	difficulty *Adaptive
+	enforced   map[string]bool
+
+	mu        sync.Mutex
+	spent     map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewIssuer(key []byte, ttl time.Duration, difficulty *Adaptive, enforced []string) *Issuer {
+	issuer := &Issuer{
+		key:        key,
+		ttl:        ttl,
+		difficulty: difficulty,
+		enforced:   map[string]bool{},
+		spent:      map[string]time.Time{},
+		now:        time.Now,
+	}
+	for _, scope := range enforced {
+		issuer.enforced[scope] = true
+	}
+	return issuer
+}
+
+func (i *Issuer) Enforced(scope string) bool {
+	return i.enforced[scope]
+}
+
+func (i *Issuer) Issue(scope, ip string) (Challenge, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Challenge{}, err
+	}
+
+	challenge := Challenge{
+		Scope:      scope,
+		Difficulty: i.difficulty.Difficulty(ip),
+		ExpiresAt:  i.now().Add(i.ttl).Truncate(time.Second),
+	}
+
+	payload := strings.Join([]string{
+		"v1",
+		scope,
+		strconv.Itoa(challenge.Difficulty),
+		strconv.FormatInt(challenge.ExpiresAt.Unix(), 10),
+		hex.EncodeToString(id),
+	}, ".")
+	challenge.Token = payload + "." + i.sign(payload, ip)
+
+	return challenge, nil
+}
+
+// Verify checks that nonce solves token for scope and ip, and marks the
+// challenge as used.
+func (i *Issuer) Verify(token, nonce, scope, ip string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 6 || nonce == "" || len(nonce) > maxNonceLength {
+		return ErrMalformed
+	}
+	if parts[0] != "v1" {
+		return errUnsupportedVersion
+	}
+
+	payload := strings.Join(parts[:5], ".")
+	if !hmac.Equal([]byte(parts[5]), []byte(i.sign(payload, ip))) {
+		return ErrInvalidSignature
+	}
+
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return ErrMalformed
+	}
+	expiresUnix, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if parts[1] != scope {
+		return ErrWrongScope
+	}
+
+	now := i.now()
+	expiresAt := time.Unix(expiresUnix, 0)
+	if now.After(expiresAt) {
+		return ErrExpired
+	}
+
+	if LeadingZeroBits(Solve(token, nonce)) < difficulty {
+		return ErrInsufficientWork
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, used := i.spent[parts[4]]; used {
+		return ErrAlreadyUsed
+	}
+	i.spent[parts[4]] = expiresAt
+	i.sweep(now)
+
+	return nil
+}
+
+func (i *Issuer) sign(payload, ip string) string {
+	mac := hmac.New(sha256.New, i.key)
+	fmt.Fprintf(mac, "%s\n%s", payload, ip)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sweep forgets spent challenges that would be rejected as expired anyway.
+// Callers hold mu.
+func (i *Issuer) sweep(now time.Time) {
+	if now.Sub(i.lastSweep) < time.Minute {
+		return
+	}
+	i.lastSweep = now
+
+	for id, expiresAt := range i.spent {
+		if now.After(expiresAt) {
+			delete(i.spent, id)
+		}
+	}
+}
+
+// Solve returns the hash a nonce is judged by.
+func Solve(token, nonce string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(token + ":" + nonce))
+}
+
+func LeadingZeroBits(hash [sha256.Size]byte) int {
+	count := 0
+	for _, b := range hash {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
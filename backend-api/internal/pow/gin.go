@@ -0,0 +1,74 @@
+package pow
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/metrics"
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderChallenge = "X-PoW-Challenge"
+	HeaderNonce     = "X-PoW-Nonce"
+)
+
+type ChallengeQuery struct {
+	Scope string `form:"scope" binding:"required,oneof=register check_field"`
+}
+
+type ChallengeResponse struct {
+	Success    bool      `json:"success"`
+	Challenge  string    `json:"challenge"`
+	Algorithm  string    `json:"algorithm" description:"Find a nonce so that SHA-256(challenge + \":\" + nonce) starts with difficulty zero bits"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Enforced   bool      `json:"enforced" description:"Whether the route currently rejects requests without a solution"`
+}
+
+// Require rejects requests to an enforced scope that do not carry a solved
+// challenge in the X-PoW-Challenge and X-PoW-Nonce headers. For scopes that
+// are not enforced it does nothing.
+func (i *Issuer) Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !i.Enforced(scope) {
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader(HeaderChallenge)
+		nonce := c.GetHeader(HeaderNonce)
+		if token == "" || nonce == "" {
+			metrics.ProofOfWorkVerificationsTotal.WithLabelValues(scope, "missing").Inc()
+			apierror.Abort(c, apierror.New(http.StatusPreconditionRequired, apierror.CodeChallengeRequired,
+				"Solve a proof-of-work challenge from GET /api/v1/auth/challenge and send it in the X-PoW-Challenge and X-PoW-Nonce headers."))
+			return
+		}
+
+		err := i.Verify(token, nonce, scope, c.ClientIP())
+		if err == nil {
+			metrics.ProofOfWorkVerificationsTotal.WithLabelValues(scope, "solved").Inc()
+			c.Next()
+			return
+		}
+
+		middleware.GetLogger(c).Warn("pow_verification_failed",
+			zap.String("scope", scope),
+			zap.Error(err),
+		)
+
+		if errors.Is(err, ErrExpired) {
+			metrics.ProofOfWorkVerificationsTotal.WithLabelValues(scope, "expired").Inc()
+			apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeChallengeExpired, "The proof-of-work challenge has expired. Request a new one."))
+			return
+		}
+
+		metrics.ProofOfWorkVerificationsTotal.WithLabelValues(scope, "invalid").Inc()
+		apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeChallengeInvalid, "The proof-of-work solution is not valid. Request a new challenge."))
+	}
+}
@@ -0,0 +1,152 @@
+package pow
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func solve(t *testing.T, challenge Challenge) string {
+	t.Helper()
+	for i := 0; i < 1<<24; i++ {
+		nonce := strconv.Itoa(i)
+		if LeadingZeroBits(Solve(challenge.Token, nonce)) >= challenge.Difficulty {
+			return nonce
+		}
+	}
+	t.Fatal("no nonce found")
+	return ""
+}
+
+func TestIssuer_Verify(t *testing.T) {
+	issuer := NewIssuer([]byte("secret"), time.Minute, NewAdaptive(8, 12, time.Minute), nil)
+
+	challenge, err := issuer.Issue(ScopeRegister, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := solve(t, challenge)
+
+	cases := []struct {
+		name  string
+		token string
+		nonce string
+		scope string
+		ip    string
+		err   error
+	}{
+		{"wrong scope", challenge.Token, nonce, ScopeCheckField, "192.0.2.1", ErrWrongScope},
+		{"other ip", challenge.Token, nonce, ScopeRegister, "192.0.2.2", ErrInvalidSignature},
+		{"tampered difficulty", replaceField(challenge.Token, 2, "0"), nonce, ScopeRegister, "192.0.2.1", ErrInvalidSignature},
+		{"garbage", "v1.register", nonce, ScopeRegister, "192.0.2.1", ErrMalformed},
+		{"valid", challenge.Token, nonce, ScopeRegister, "192.0.2.1", nil},
+		{"replayed", challenge.Token, nonce, ScopeRegister, "192.0.2.1", ErrAlreadyUsed},
+	}
+
+	for _, tc := range cases {
+		if err := issuer.Verify(tc.token, tc.nonce, tc.scope, tc.ip); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got: %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestIssuer_VerifyRejectsExpiredAndUnsolved(t *testing.T) {
+	issuer := NewIssuer([]byte("secret"), time.Minute, NewAdaptive(16, 16, time.Minute), nil)
+
+	challenge, _ := issuer.Issue(ScopeRegister, "192.0.2.1")
+	unsolved := ""
+	for i := 0; unsolved == ""; i++ {
+		if LeadingZeroBits(Solve(challenge.Token, strconv.Itoa(i))) < challenge.Difficulty {
+			unsolved = strconv.Itoa(i)
+		}
+	}
+	if err := issuer.Verify(challenge.Token, unsolved, ScopeRegister, "192.0.2.1"); !errors.Is(err, ErrInsufficientWork) {
+		t.Errorf("Expected insufficient work, got: %v", err)
+	}
+
+	issuer.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if err := issuer.Verify(challenge.Token, unsolved, ScopeRegister, "192.0.2.1"); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected expired, got: %v", err)
+	}
+}
+
+func TestAdaptive_RisesWithAbuseAndDecays(t *testing.T) {
+	now := time.Now()
+	adaptive := NewAdaptive(10, 20, time.Minute)
+	adaptive.now = func() time.Time { return now }
+
+	for i := 0; i < 15; i++ {
+		adaptive.ObserveAbuse("auth", "192.0.2.1")
+	}
+
+	if got := adaptive.Difficulty("192.0.2.1"); got != 15 {
+		t.Errorf("Expected abusive IP to get difficulty 15, got: %d", got)
+	}
+	if got := adaptive.Difficulty("192.0.2.2"); got != 11 {
+		t.Errorf("Expected other IPs to get difficulty 11, got: %d", got)
+	}
+
+	for i := 0; i < 10000; i++ {
+		adaptive.ObserveAbuse("auth", "192.0.2.1")
+	}
+	if got := adaptive.Difficulty("192.0.2.1"); got != 20 {
+		t.Errorf("Expected difficulty to be capped at 20, got: %d", got)
+	}
+
+	now = now.Add(time.Hour)
+	if got := adaptive.Difficulty("192.0.2.1"); got != 10 {
+		t.Errorf("Expected difficulty to decay back to 10, got: %d", got)
+	}
+}
+
+func TestIssuer_Require(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	middleware.Logger = zap.NewNop()
+
+	issuer := NewIssuer([]byte("secret"), time.Minute, NewAdaptive(8, 8, time.Minute), []string{ScopeRegister})
+	router := gin.New()
+	router.POST("/register", issuer.Require(ScopeRegister), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/check", issuer.Require(ScopeCheckField), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path string, headers map[string]string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send(http.MethodGet, "/check", nil); code != http.StatusOK {
+		t.Errorf("Expected unenforced scope to pass, got: %d", code)
+	}
+	if code := send(http.MethodPost, "/register", nil); code != http.StatusPreconditionRequired {
+		t.Errorf("Expected missing solution to be rejected with 428, got: %d", code)
+	}
+
+	challenge, _ := issuer.Issue(ScopeRegister, "192.0.2.1")
+	headers := map[string]string{HeaderChallenge: challenge.Token, HeaderNonce: solve(t, challenge)}
+	if code := send(http.MethodPost, "/register", headers); code != http.StatusOK {
+		t.Errorf("Expected solved challenge to pass, got: %d", code)
+	}
+	if code := send(http.MethodPost, "/register", headers); code != http.StatusForbidden {
+		t.Errorf("Expected reused challenge to be rejected with 403, got: %d", code)
+	}
+}
+
+func replaceField(token string, index int, value string) string {
+	fields := strings.Split(token, ".")
+	fields[index] = value
+	return strings.Join(fields, ".")
+}
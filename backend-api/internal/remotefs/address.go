@@ -0,0 +1,96 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrAddressBlocked is returned for a server on a network the gateway must
+// not reach on a user's behalf, such as its own host or a private network.
+var ErrAddressBlocked = errors.New("the server's address is not allowed")
+
+// DialControl vets each connection a backend opens, as
+// net.Dialer.ControlContext does. It sees the resolved IP, so a host name
+// cannot pass the check and then resolve somewhere else.
+type DialControl func(ctx context.Context, network, address string, c syscall.RawConn) error
+
+// reserved are networks outside the standard library's classifications
+// that are not reachable on the internet either.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// AddressPolicy refuses connections to loopback, private, link-local,
+// unspecified, multicast and reserved addresses, unless they are in one of
+// the Allowed networks.
+type AddressPolicy struct {
+	Allowed []netip.Prefix
+}
+
+// ParseAllowedNetworks reads a comma-separated list of CIDR prefixes or
+// single IPs.
+func ParseAllowedNetworks(list string) ([]netip.Prefix, error) {
+	var allowed []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, err
+			}
+			allowed = append(allowed, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		allowed = append(allowed, prefix.Masked())
+	}
+	return allowed, nil
+}
+
+// Allows reports whether a connection to addr may be opened.
+func (p *AddressPolicy) Allows(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p.Allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Control is a DialControl that enforces the policy.
+func (p *AddressPolicy) Control(_ context.Context, _, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrAddressBlocked, address)
+	}
+	if !p.Allows(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrAddressBlocked, addrPort.Addr())
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestAddressPolicy_BlocksInternalNetworks(t *testing.T) {
+	policy := &AddressPolicy{}
+
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"fe80::1", "fc00::1", "0.0.0.0", "::", "224.0.0.1", "ff02::1", "100.64.0.1", "::ffff:127.0.0.1",
+	} {
+		if policy.Allows(netip.MustParseAddr(addr)) {
+			t.Errorf("Expected %s to be blocked", addr)
+		}
+	}
+
+	for _, addr := range []string{"1.1.1.1", "2606:4700::1111"} {
+		if !policy.Allows(netip.MustParseAddr(addr)) {
+			t.Errorf("Expected %s to be allowed", addr)
+		}
+	}
+}
+
+func TestAddressPolicy_AllowedNetworks(t *testing.T) {
+	allowed, err := ParseAllowedNetworks("10.0.0.0/8, 192.168.1.5")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	policy := &AddressPolicy{Allowed: allowed}
+
+	if !policy.Allows(netip.MustParseAddr("10.9.8.7")) || !policy.Allows(netip.MustParseAddr("192.168.1.5")) {
+		t.Error("Expected allowed networks to be reachable")
+	}
+	if policy.Allows(netip.MustParseAddr("192.168.1.6")) {
+		t.Error("Expected other private addresses to stay blocked")
+	}
+
+	if _, err := ParseAllowedNetworks("10.0.0.0/33"); err == nil {
+		t.Error("Expected an invalid prefix to be rejected")
+	}
+}
+
+func TestAddressPolicy_ControlChecksDialedAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	policy := &AddressPolicy{}
+	dialer := net.Dialer{ControlContext: policy.Control}
+	if _, err := dialer.DialContext(context.Background(), "tcp", listener.Addr().String()); !errors.Is(err, ErrAddressBlocked) {
+		t.Errorf("Expected ErrAddressBlocked, got: %v", err)
+	}
+
+	policy.Allowed = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	conn, err := dialer.DialContext(context.Background(), "tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected an allowed address to connect, got: %v", err)
+	}
+	conn.Close()
+}
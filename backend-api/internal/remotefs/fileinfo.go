@@ -0,0 +1,22 @@
+package remotefs
+
+import (
+	"io/fs"
+	"time"
+)
+
+// FileInfo describes a file, for backends whose protocol has nothing that
+// implements fs.FileInfo.
+type FileInfo struct {
+	FileName string
+	FileSize int64
+	FileMode fs.FileMode
+	Modified time.Time
+}
+
+func (i *FileInfo) Name() string       { return i.FileName }
+func (i *FileInfo) Size() int64        { return i.FileSize }
+func (i *FileInfo) Mode() fs.FileMode  { return i.FileMode }
+func (i *FileInfo) ModTime() time.Time { return i.Modified }
+func (i *FileInfo) IsDir() bool        { return i.FileMode.IsDir() }
+func (i *FileInfo) Sys() any           { return nil }
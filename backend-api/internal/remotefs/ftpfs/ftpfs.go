@@ -0,0 +1,784 @@
+// Package ftpfs is the FTP and FTPS backend of remotefs. It speaks the parts
+// of RFC 959 and its extensions a file manager needs: EPSV and EPRT, MLSD
+// and MLST, REST STREAM, MFMT and TLS as in RFC 4217, in passive or active
+// mode.
+package ftpfs
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"livecode-api/internal/remotefs"
+)
+
+var errBusy = errors.New("ftp: a transfer is already in progress on this connection")
+
+// Config describes how to reach and log in to a server.
+type Config struct {
+	Address string
+	// Username and Password log in; an empty Username logs in anonymously.
+	Username string
+	Password string
+	// TLS enables FTPS. The connection is upgraded with AUTH TLS unless
+	// Implicit is set, in which case it starts with a TLS handshake.
+	TLS      *tls.Config
+	Implicit bool
+	// Active has the server connect to the client for data rather than the
+	// other way round.
+	Active bool
+	// Timeout bounds connecting, each command, and each read or write of a
+	// data connection.
+	Timeout time.Duration
+	// Control, if set, vets each connection before it is opened.
+	Control remotefs.DialControl
+}
+
+// Conn is a logged-in control connection. It runs one command at a time,
+// and a file opened with Open or Create must be closed before the next.
+type Conn struct {
+	config   Config
+	conn     net.Conn
+	text     *textproto.Conn
+	features map[string]string
+	home     string
+	noEPSV   bool
+	busy     bool
+	dead     bool
+}
+
+// Dial connects and logs in. Errors wrap remotefs.ErrAuthFailed or
+// remotefs.ErrConnectFailed.
+func Dial(ctx context.Context, config Config) (*Conn, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.TLS != nil {
+		config.TLS = config.TLS.Clone()
+		if config.TLS.ServerName == "" {
+			config.TLS.ServerName, _, _ = net.SplitHostPort(config.Address)
+		}
+		// Servers commonly require data connections to resume the
+		// control connection's TLS session.
+		if config.TLS.ClientSessionCache == nil {
+			config.TLS.ClientSessionCache = tls.NewLRUClientSessionCache(4)
+		}
+	}
+
+	dialer := net.Dialer{Timeout: config.Timeout, ControlContext: config.Control}
+	conn, err := dialer.DialContext(ctx, "tcp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+
+	c := &Conn{config: config, features: map[string]string{}}
+	if err := c.login(conn); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Conn) setConn(conn net.Conn) {
+	c.conn = conn
+	c.text = textproto.NewConn(conn)
+}
+
+func (c *Conn) login(conn net.Conn) error {
+	if c.config.TLS != nil && c.config.Implicit {
+		conn = tls.Client(conn, c.config.TLS)
+	}
+	c.setConn(conn)
+
+	connectErr := func(err error) error {
+		return fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+
+	c.conn.SetDeadline(time.Now().Add(c.config.Timeout))
+	if _, err := c.response(2); err != nil {
+		return connectErr(err)
+	}
+	if c.config.TLS != nil && !c.config.Implicit {
+		if _, err := c.cmd(2, "AUTH TLS"); err != nil {
+			return connectErr(err)
+		}
+		c.setConn(tls.Client(c.conn, c.config.TLS))
+	}
+
+	user, password := c.config.Username, c.config.Password
+	if user == "" {
+		user, password = "anonymous", "anonymous@"
+	}
+	code, message, err := c.exchange("USER %s", user)
+	if err == nil && code == 331 {
+		code, message, err = c.exchange("PASS %s", password)
+	}
+	switch {
+	case err != nil:
+		return connectErr(err)
+	case code/100 != 2:
+		return fmt.Errorf("%w: %d %s", remotefs.ErrAuthFailed, code, message)
+	}
+
+	if c.config.TLS != nil {
+		if _, err := c.cmd(2, "PBSZ 0"); err != nil {
+			return connectErr(err)
+		}
+		if _, err := c.cmd(2, "PROT P"); err != nil {
+			return connectErr(err)
+		}
+	}
+
+	if code, message, err := c.exchange("FEAT"); err != nil {
+		return connectErr(err)
+	} else if code == 211 {
+		for _, line := range strings.Split(message, "\n")[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+			c.features[strings.ToUpper(name)] = value
+		}
+	}
+	if _, ok := c.features["UTF8"]; ok {
+		c.exchange("OPTS UTF8 ON")
+	}
+	if _, err := c.cmd(2, "TYPE I"); err != nil {
+		return connectErr(err)
+	}
+
+	message, err = c.cmd(257, "PWD")
+	if err != nil {
+		return connectErr(err)
+	}
+	c.home = quotedPath(message)
+	if c.home == "" {
+		c.home = "/"
+	}
+	return nil
+}
+
+// quotedPath returns the path in a 257 reply, in which quotes are doubled.
+func quotedPath(message string) string {
+	start := strings.IndexByte(message, '"')
+	if start < 0 {
+		return ""
+	}
+	var b strings.Builder
+	for i := start + 1; i < len(message); i++ {
+		if message[i] == '"' {
+			if i+1 < len(message) && message[i+1] == '"' {
+				b.WriteByte('"')
+				i++
+				continue
+			}
+			break
+		}
+		b.WriteByte(message[i])
+	}
+	return b.String()
+}
+
+// exchange sends a command and returns the server's reply, whatever its
+// code. Errors are those of the connection.
+func (c *Conn) exchange(format string, args ...any) (int, string, error) {
+	if c.busy {
+		return 0, "", errBusy
+	}
+	c.conn.SetDeadline(time.Now().Add(c.config.Timeout))
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		c.dead = true
+		return 0, "", err
+	}
+	code, message, err := c.text.ReadResponse(0)
+	if err != nil {
+		c.dead = true
+		return 0, "", err
+	}
+	if code == 421 {
+		c.dead = true
+	}
+	return code, message, nil
+}
+
+// cmd sends a command whose reply must start with expect, in the sense of
+// textproto.Reader.ReadResponse.
+func (c *Conn) cmd(expect int, format string, args ...any) (string, error) {
+	code, message, err := c.exchange(format, args...)
+	if err != nil {
+		return "", err
+	}
+	if !codeMatches(code, expect) {
+		return message, replyError(code, message)
+	}
+	return message, nil
+}
+
+func (c *Conn) response(expect int) (string, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.config.Timeout))
+	code, message, err := c.text.ReadResponse(0)
+	if err != nil {
+		c.dead = true
+		return "", err
+	}
+	if !codeMatches(code, expect) {
+		return message, replyError(code, message)
+	}
+	return message, nil
+}
+
+func codeMatches(code, expect int) bool {
+	switch {
+	case expect < 10:
+		return code/100 == expect
+	case expect < 100:
+		return code/10 == expect
+	}
+	return code == expect
+}
+
+func replyError(code int, message string) error {
+	err := &remotefs.ServerError{Code: code, Message: message}
+	switch code {
+	case 450, 550:
+		err.Err = fs.ErrNotExist
+	case 532, 553:
+		err.Err = fs.ErrPermission
+	case 500, 502, 504:
+		err.Err = remotefs.ErrUnsupported
+	}
+	return err
+}
+
+func (c *Conn) feature(name string) bool {
+	_, ok := c.features[name]
+	return ok
+}
+
+func (c *Conn) Capabilities() remotefs.Capabilities {
+	return remotefs.Capabilities{
+		Chmod:             true,
+		Chtimes:           c.feature("MFMT"),
+		ResumeUpload:      strings.EqualFold(c.features["REST"], "STREAM"),
+		RenameDirectories: true,
+	}
+}
+
+// Alive reports false once the connection has failed or the server has
+// closed it, which idle FTP connections are after a few minutes.
+func (c *Conn) Alive() bool {
+	if c.dead {
+		return false
+	}
+	if c.busy {
+		return true
+	}
+
+	// Anything the server sends unasked, such as a 421, means it is
+	// hanging up.
+	c.conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	_, err := c.text.R.Peek(1)
+	c.conn.SetReadDeadline(time.Time{})
+
+	var netErr net.Error
+	if err == nil || !errors.As(err, &netErr) || !netErr.Timeout() {
+		c.dead = true
+	}
+	return !c.dead
+}
+
+func (c *Conn) Close() error {
+	if !c.dead && !c.busy {
+		c.conn.SetDeadline(time.Now().Add(time.Second))
+		c.text.PrintfLine("QUIT")
+	}
+	c.dead = true
+	return c.conn.Close()
+}
+
+func (c *Conn) RealPath(name string) (string, error) {
+	if path.IsAbs(name) {
+		return path.Clean(name), nil
+	}
+	return path.Join(c.home, name), nil
+}
+
+func (c *Conn) Stat(name string) (fs.FileInfo, error) {
+	resolved, err := c.RealPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if resolved == "/" {
+		return &remotefs.FileInfo{FileName: "/", FileMode: fs.ModeDir | 0o755}, nil
+	}
+
+	if c.feature("MLST") {
+		message, err := c.cmd(250, "MLST %s", resolved)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(message, "\n")[1:] {
+			if info, ok := parseFacts(strings.TrimPrefix(line, " ")); ok {
+				info.FileName = path.Base(resolved)
+				return info, nil
+			}
+		}
+		return nil, &remotefs.ServerError{Code: 250, Message: "unexpected MLST reply"}
+	}
+
+	infos, err := c.ReadDir(path.Dir(resolved))
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.Name() == path.Base(resolved) {
+			return info, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Lstat is Stat; FTP servers describe the targets of links.
+func (c *Conn) Lstat(name string) (fs.FileInfo, error) {
+	return c.Stat(name)
+}
+
+func (c *Conn) ReadDir(name string) ([]fs.FileInfo, error) {
+	command, parse := "MLSD %s", parseFacts
+	if !c.feature("MLST") {
+		command, parse = "LIST -a %s", parseList
+	}
+
+	data, err := c.transfer(context.Background(), 0, command, name)
+	if err != nil {
+		return nil, err
+	}
+	listing, err := io.ReadAll(data)
+	if closeErr := data.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []fs.FileInfo
+	for _, line := range strings.Split(string(listing), "\n") {
+		info, ok := parse(strings.TrimRight(line, "\r"))
+		if !ok || info.FileName == "." || info.FileName == ".." {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// transfer sends command over a new data connection, which the caller
+// reads or writes and then closes. A REST for offset must come right
+// before the command, after the data connection is set up.
+func (c *Conn) transfer(ctx context.Context, offset int64, format string, args ...any) (*dataConn, error) {
+	if c.busy {
+		return nil, errBusy
+	}
+
+	var conn net.Conn
+	var err error
+	if c.config.Active {
+		conn, err = c.activeTransfer(offset, format, args...)
+	} else {
+		conn, err = c.passiveTransfer(ctx, offset, format, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.config.TLS != nil {
+		tlsConn := tls.Client(conn, c.config.TLS)
+		tlsConn.SetDeadline(time.Now().Add(c.config.Timeout))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			c.dead = true
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	c.busy = true
+	data := &dataConn{c: c, conn: conn}
+	data.stop = context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	data.ctx = ctx
+	return data, nil
+}
+
+func (c *Conn) restart(offset int64, format string, args ...any) error {
+	if offset > 0 {
+		if _, err := c.cmd(350, "REST %d", offset); err != nil {
+			return err
+		}
+	}
+	_, err := c.cmd(1, format, args...)
+	return err
+}
+
+func (c *Conn) passiveTransfer(ctx context.Context, offset int64, format string, args ...any) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+
+	var port int
+	if !c.noEPSV {
+		code, message, err := c.exchange("EPSV")
+		if err != nil {
+			return nil, err
+		}
+		if code == 229 {
+			port = epsvPort(message)
+		} else {
+			c.noEPSV = true
+		}
+	}
+	if port == 0 {
+		message, err := c.cmd(227, "PASV")
+		if err != nil {
+			return nil, err
+		}
+		// The address in the reply is ignored: behind NAT it is often
+		// private, and trusting it would let a server aim us elsewhere.
+		if port = pasvPort(message); port == 0 {
+			return nil, &remotefs.ServerError{Code: 227, Message: message}
+		}
+	}
+
+	dialer := net.Dialer{Timeout: c.config.Timeout, ControlContext: c.config.Control}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.restart(offset, format, args...); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *Conn) activeTransfer(offset int64, format string, args ...any) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(c.conn.LocalAddr().String())
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	ip := net.ParseIP(host)
+	port := listener.Addr().(*net.TCPAddr).Port
+	family := 1
+	if ip.To4() == nil {
+		family = 2
+	}
+	code, _, err := c.exchange("EPRT |%d|%s|%d|", family, host, port)
+	if err != nil {
+		return nil, err
+	}
+	if code/100 != 2 {
+		if ip.To4() == nil {
+			return nil, remotefs.ErrUnsupported
+		}
+		hostPart := strings.ReplaceAll(ip.To4().String(), ".", ",")
+		if _, err := c.cmd(2, "PORT %s,%d,%d", hostPart, port>>8, port&0xff); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.restart(offset, format, args...); err != nil {
+		return nil, err
+	}
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(c.config.Timeout))
+	server := c.conn.RemoteAddr().(*net.TCPAddr).IP
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			c.dead = true
+			return nil, err
+		}
+		// Anyone who finds the port could connect first and read or
+		// replace the data.
+		if conn.RemoteAddr().(*net.TCPAddr).IP.Equal(server) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
+
+// epsvPort parses a 229 reply such as "Entering Extended Passive Mode
+// (|||6446|)".
+func epsvPort(message string) int {
+	start, end := strings.IndexByte(message, '('), strings.LastIndexByte(message, ')')
+	if start < 0 || end < start+2 {
+		return 0
+	}
+	inner := message[start+1 : end]
+	parts := strings.Split(inner, inner[:1])
+	if len(parts) != 5 {
+		return 0
+	}
+	port, _ := strconv.Atoi(parts[3])
+	return port
+}
+
+// pasvPort parses a 227 reply such as "Entering Passive Mode
+// (192,168,1,2,19,46)".
+func pasvPort(message string) int {
+	start := strings.IndexByte(message, '(')
+	end := strings.LastIndexByte(message, ')')
+	if start < 0 || end < start {
+		return 0
+	}
+	parts := strings.Split(message[start+1:end], ",")
+	if len(parts) != 6 {
+		return 0
+	}
+	high, err1 := strconv.Atoi(strings.TrimSpace(parts[4]))
+	low, err2 := strconv.Atoi(strings.TrimSpace(parts[5]))
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return high<<8 | low
+}
+
+// dataConn is an open data connection. Closing it reads the server's
+// verdict on the transfer.
+type dataConn struct {
+	c    *Conn
+	conn net.Conn
+	ctx  context.Context
+	stop func() bool
+	eof  bool
+}
+
+func (d *dataConn) Read(p []byte) (int, error) {
+	d.conn.SetReadDeadline(time.Now().Add(d.c.config.Timeout))
+	n, err := d.conn.Read(p)
+	if err == io.EOF {
+		d.eof = true
+	} else if err != nil && d.ctx.Err() != nil {
+		err = context.Cause(d.ctx)
+	}
+	return n, err
+}
+
+func (d *dataConn) Write(p []byte) (int, error) {
+	d.conn.SetWriteDeadline(time.Now().Add(d.c.config.Timeout))
+	n, err := d.conn.Write(p)
+	if err != nil && d.ctx.Err() != nil {
+		err = context.Cause(d.ctx)
+	}
+	return n, err
+}
+
+func (d *dataConn) Close() error {
+	if d.conn == nil {
+		return nil
+	}
+	d.stop()
+	closeErr := d.conn.Close()
+	d.conn = nil
+	d.c.busy = false
+
+	// A failed transfer is answered with one of several errors, after which
+	// the connection stays usable.
+	_, err := d.c.response(2)
+	switch {
+	case d.ctx.Err() != nil:
+		return context.Cause(d.ctx)
+	case err != nil:
+		return err
+	}
+	return closeErr
+}
+
+func (c *Conn) Open(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	data, err := c.transfer(ctx, offset, "RETR %s", name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileReader{data}, nil
+}
+
+// fileReader ignores the server's complaint about a download closed before
+// its end.
+type fileReader struct {
+	*dataConn
+}
+
+func (r *fileReader) Close() error {
+	eof := r.eof
+	err := r.dataConn.Close()
+	if !eof && r.ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+// Create writes from offset with REST and STOR. Servers overwrite in place
+// from there, so a file longer than offset plus the data keeps its tail.
+func (c *Conn) Create(ctx context.Context, name string, offset int64) (io.WriteCloser, error) {
+	if offset > 0 && !c.Capabilities().ResumeUpload {
+		return nil, remotefs.ErrUnsupported
+	}
+	data, err := c.transfer(ctx, offset, "STOR %s", name)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *Conn) Mkdir(name string) error {
+	_, err := c.cmd(2, "MKD %s", name)
+	return err
+}
+
+func (c *Conn) MkdirAll(name string) error {
+	return remotefs.MkdirAll(c, name)
+}
+
+func (c *Conn) Rename(from, to string, replace bool) error {
+	if replace {
+		if err := c.Remove(to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if _, err := c.cmd(350, "RNFR %s", from); err != nil {
+		return err
+	}
+	_, err := c.cmd(2, "RNTO %s", to)
+	return err
+}
+
+func (c *Conn) Remove(name string) error {
+	_, err := c.cmd(2, "DELE %s", name)
+	return err
+}
+
+func (c *Conn) RemoveDirectory(name string) error {
+	_, err := c.cmd(2, "RMD %s", name)
+	return err
+}
+
+func (c *Conn) RemoveAll(name string) error {
+	return remotefs.RemoveAll(c, name)
+}
+
+func (c *Conn) Chmod(name string, mode fs.FileMode) error {
+	bits := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	_, err := c.cmd(2, "SITE CHMOD %o %s", bits, name)
+	return err
+}
+
+func (c *Conn) Chtimes(name string, modified time.Time) error {
+	if !c.feature("MFMT") {
+		return remotefs.ErrUnsupported
+	}
+	_, err := c.cmd(2, "MFMT %s %s", modified.UTC().Format("20060102150405"), name)
+	return err
+}
+
+func (c *Conn) Symlink(string, string) error {
+	return remotefs.ErrUnsupported
+}
+
+// parseFacts parses an MLSD line or an MLST entry: "type=file;size=5; name".
+func parseFacts(line string) (*remotefs.FileInfo, bool) {
+	facts, name, ok := strings.Cut(line, " ")
+	if !ok || name == "" || !strings.Contains(facts, "=") {
+		return nil, false
+	}
+
+	info := &remotefs.FileInfo{FileName: name}
+	for _, fact := range strings.Split(facts, ";") {
+		key, value, _ := strings.Cut(fact, "=")
+		switch strings.ToLower(key) {
+		case "type":
+			switch value = strings.ToLower(value); {
+			case value == "cdir" || value == "pdir":
+				return nil, false
+			case value == "dir":
+				info.FileMode |= fs.ModeDir
+			case value == "file":
+			case strings.HasPrefix(value, "os.unix=slink"), strings.HasPrefix(value, "os.unix=symlink"):
+				info.FileMode |= fs.ModeSymlink
+			default:
+				info.FileMode |= fs.ModeIrregular
+			}
+		case "size":
+			info.FileSize, _ = strconv.ParseInt(value, 10, 64)
+		case "modify":
+			if len(value) >= 14 {
+				info.Modified, _ = time.Parse("20060102150405", value[:14])
+			}
+		case "unix.mode":
+			if mode, err := strconv.ParseUint(value, 8, 32); err == nil {
+				info.FileMode |= fs.FileMode(mode).Perm()
+			}
+		}
+	}
+	return info, true
+}
+
+// parseList parses a line of the Unix ls -l style listing most servers
+// send for LIST, for servers without MLSD.
+func parseList(line string) (*remotefs.FileInfo, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 9 || len(fields[0]) != 10 {
+		return nil, false
+	}
+
+	// The name is what follows the eighth field, spaces and all.
+	rest := line
+	for i := 0; i < 8; i++ {
+		rest = strings.TrimLeft(rest, " ")
+		rest = rest[strings.IndexByte(rest, ' '):]
+	}
+	info := &remotefs.FileInfo{FileName: strings.TrimLeft(rest, " ")}
+
+	switch fields[0][0] {
+	case 'd':
+		info.FileMode |= fs.ModeDir
+	case 'l':
+		info.FileMode |= fs.ModeSymlink
+		info.FileName, _, _ = strings.Cut(info.FileName, " -> ")
+	case '-':
+	default:
+		info.FileMode |= fs.ModeIrregular
+	}
+	for i, c := range fields[0][1:] {
+		if c != '-' {
+			info.FileMode |= 1 << (8 - i)
+		}
+	}
+
+	info.FileSize, _ = strconv.ParseInt(fields[4], 10, 64)
+	stamp := fields[5] + " " + fields[6] + " " + fields[7]
+	if modified, err := time.Parse("Jan 2 2006", stamp); err == nil {
+		info.Modified = modified
+	} else if modified, err := time.Parse("Jan 2 15:04", stamp); err == nil {
+		// Recent files leave out the year.
+		now := time.Now().UTC()
+		info.Modified = modified.AddDate(now.Year(), 0, 0)
+		if info.Modified.After(now.AddDate(0, 0, 1)) {
+			info.Modified = info.Modified.AddDate(-1, 0, 0)
+		}
+	}
+	return info, true
+}
@@ -0,0 +1,257 @@
+package ftpfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/remotefs/ftpfs/ftptest"
+)
+
+func dialTest(t *testing.T, server *ftptest.Server, active bool) *Conn {
+	t.Helper()
+
+	config := Config{
+		Address:  server.Addr,
+		Username: server.User,
+		Password: server.Password,
+		TLS:      server.ClientTLS,
+		Active:   active,
+		Timeout:  5 * time.Second,
+	}
+	config.Implicit = server.ClientTLS != nil && !active
+	conn, err := Dial(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Expected to log in, got: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func writeFile(t *testing.T, fsys remotefs.FS, name string, offset int64, data string) {
+	t.Helper()
+
+	w, err := fsys.Create(context.Background(), name, offset)
+	if err != nil {
+		t.Fatalf("Expected to create %s, got: %v", name, err)
+	}
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected the upload of %s to finish, got: %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, fsys remotefs.FS, name string, offset int64) string {
+	t.Helper()
+
+	r, err := fsys.Open(context.Background(), name, offset)
+	if err != nil {
+		t.Fatalf("Expected to open %s, got: %v", name, err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Expected the download of %s to finish, got: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestConn_FileOperations(t *testing.T) {
+	cases := []struct {
+		name   string
+		mode   ftptest.TLSMode
+		active bool
+		noMLSD bool
+	}{
+		{name: "plain passive", mode: ftptest.NoTLS},
+		{name: "plain active", mode: ftptest.NoTLS, active: true},
+		{name: "explicit TLS active", mode: ftptest.ExplicitTLS, active: true},
+		{name: "implicit TLS passive", mode: ftptest.ImplicitTLS},
+		{name: "LIST fallback", mode: ftptest.NoTLS, noMLSD: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := ftptest.NewServer(t, tc.mode)
+			server.NoMLSD = tc.noMLSD
+			conn := dialTest(t, server, tc.active)
+
+			if err := conn.MkdirAll("site/assets"); err != nil {
+				t.Fatalf("Expected mkdir -p to succeed, got: %v", err)
+			}
+			writeFile(t, conn, "site/index.html", 0, "hello world")
+
+			if got := readFile(t, conn, "site/index.html", 6); got != "world" {
+				t.Errorf("Expected to read from the offset, got %q", got)
+			}
+
+			writeFile(t, conn, "site/index.html", 6, "there")
+			if got := readFile(t, conn, "/site/index.html", 0); got != "hello there" {
+				t.Errorf("Expected the resumed upload, got %q", got)
+			}
+
+			infos, err := conn.ReadDir("site")
+			if err != nil || len(infos) != 2 {
+				t.Fatalf("Expected two entries, got %d, %v", len(infos), err)
+			}
+			for _, info := range infos {
+				switch info.Name() {
+				case "assets":
+					if !info.IsDir() {
+						t.Errorf("Expected assets to be a directory")
+					}
+				case "index.html":
+					if info.Size() != 11 || info.ModTime().IsZero() {
+						t.Errorf("Expected the file's size and time, got %d, %v", info.Size(), info.ModTime())
+					}
+				default:
+					t.Errorf("Unexpected entry %q", info.Name())
+				}
+			}
+
+			modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			if err := conn.Chtimes("site/index.html", modified); err != nil {
+				t.Fatalf("Expected MFMT to succeed, got: %v", err)
+			}
+			if err := conn.Chmod("site/index.html", 0o600); err != nil {
+				t.Fatalf("Expected SITE CHMOD to succeed, got: %v", err)
+			}
+			// LIST only gives the day of older files.
+			info, err := conn.Stat("site/index.html")
+			if err != nil || !info.ModTime().Truncate(24*time.Hour).Equal(modified.Truncate(24*time.Hour)) || info.Mode().Perm() != 0o600 {
+				t.Errorf("Expected the new time and mode, got %+v, %v", info, err)
+			}
+
+			writeFile(t, conn, "site/old.html", 0, "old")
+			if err := conn.Rename("site/old.html", "site/index.html", true); err != nil {
+				t.Fatalf("Expected rename to replace, got: %v", err)
+			}
+			data, _ := os.ReadFile(filepath.Join(server.Root, "site", "index.html"))
+			if string(data) != "old" {
+				t.Errorf("Expected the renamed file, got %q", data)
+			}
+
+			if _, err := conn.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Expected fs.ErrNotExist, got: %v", err)
+			}
+			if err := conn.RemoveAll("site"); err != nil {
+				t.Fatalf("Expected recursive delete to succeed, got: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(server.Root, "site")); !os.IsNotExist(err) {
+				t.Errorf("Expected the tree to be gone, got: %v", err)
+			}
+			if !conn.Alive() {
+				t.Error("Expected the connection to still be alive")
+			}
+		})
+	}
+}
+
+func TestConn_DownloadClosedEarlyKeepsConnection(t *testing.T) {
+	server := ftptest.NewServer(t, ftptest.NoTLS)
+	os.WriteFile(filepath.Join(server.Root, "big.bin"), make([]byte, 8<<20), 0o644)
+	conn := dialTest(t, server, false)
+
+	r, err := conn.Open(context.Background(), "big.bin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadFull(r, make([]byte, 1024))
+	if _, err := conn.Stat("big.bin"); err == nil {
+		t.Error("Expected commands to be refused while a download is open")
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Expected closing a download early to succeed, got: %v", err)
+	}
+
+	if info, err := conn.Stat("big.bin"); err != nil || info.Size() != 8<<20 {
+		t.Errorf("Expected the connection to be usable, got %v", err)
+	}
+}
+
+func TestDial_AuthFailureAndServerHangup(t *testing.T) {
+	server := ftptest.NewServer(t, ftptest.ExplicitTLS)
+
+	_, err := Dial(context.Background(), Config{
+		Address:  server.Addr,
+		Username: server.User,
+		Password: "wrong",
+		TLS:      server.ClientTLS,
+		Timeout:  5 * time.Second,
+	})
+	if !errors.Is(err, remotefs.ErrAuthFailed) {
+		t.Errorf("Expected remotefs.ErrAuthFailed, got: %v", err)
+	}
+
+	policy := &remotefs.AddressPolicy{}
+	_, err = Dial(context.Background(), Config{Address: server.Addr, Timeout: 5 * time.Second, Control: policy.Control})
+	if !errors.Is(err, remotefs.ErrAddressBlocked) {
+		t.Errorf("Expected remotefs.ErrAddressBlocked for a loopback server, got: %v", err)
+	}
+
+	conn := dialTest(t, server, true)
+	conn.conn.Close()
+	if conn.Alive() {
+		t.Error("Expected a closed connection not to be alive")
+	}
+}
+
+func TestConn_ActiveModeRejectsOtherHosts(t *testing.T) {
+	server := ftptest.NewServer(t, ftptest.NoTLS)
+	var intruderClosed bool
+	server.BeforeActive = func(address string) {
+		dialer := net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.2")}, Timeout: 5 * time.Second}
+		intruder, err := dialer.Dial("tcp", address)
+		if err != nil {
+			t.Errorf("Expected the intruder to reach the listener, got: %v", err)
+			return
+		}
+		defer intruder.Close()
+		intruder.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err = intruder.Read(make([]byte, 1))
+		intruderClosed = err == io.EOF
+	}
+
+	conn := dialTest(t, server, true)
+	writeFile(t, conn, "data.txt", 0, "secret")
+	if got := readFile(t, conn, "data.txt", 0); got != "secret" {
+		t.Errorf("Expected the server's data connection to be used, got %q", got)
+	}
+	if !intruderClosed {
+		t.Error("Expected a connection from another host to be closed")
+	}
+}
+
+func TestParseList(t *testing.T) {
+	info, ok := parseList("drwxr-x---    2 owner group      4096 Mar  1  2024 My Documents")
+	if !ok || info.Name() != "My Documents" || !info.IsDir() || info.Mode().Perm() != 0o750 {
+		t.Errorf("Expected a directory with spaces in its name, got %+v", info)
+	}
+	if info.ModTime().Year() != 2024 {
+		t.Errorf("Expected the year from the listing, got %v", info.ModTime())
+	}
+
+	info, ok = parseList("lrwxrwxrwx    1 owner group        11 Jan  5 09:30 current -> releases/42")
+	if !ok || info.Name() != "current" || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Expected a link named current, got %+v", info)
+	}
+
+	if _, ok := parseList("total 12"); ok {
+		t.Error("Expected the total line to be skipped")
+	}
+	if !strings.Contains(quotedPath(`257 "/a ""b""" is current`), `/a "b"`) {
+		t.Error("Expected doubled quotes to be unescaped")
+	}
+}
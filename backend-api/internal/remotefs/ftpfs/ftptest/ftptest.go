@@ -0,0 +1,552 @@
+// Package ftptest runs an in-process FTP server over a temporary directory,
+// with optional explicit or implicit TLS and both passive and active data
+// connections, for end-to-end tests of the FTP backend.
+package ftptest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type TLSMode int
+
+const (
+	NoTLS TLSMode = iota
+	ExplicitTLS
+	ImplicitTLS
+)
+
+type Server struct {
+	Addr     string
+	Root     string
+	User     string
+	Password string
+	// ClientTLS trusts the server's certificate.
+	ClientTLS *tls.Config
+	// NoMLSD leaves MLST out of FEAT, so clients fall back to LIST.
+	NoMLSD bool
+	// BeforeActive, if set, is called with the client's address before the
+	// server connects to it for an active mode transfer.
+	BeforeActive func(address string)
+
+	mode        TLSMode
+	tls         *tls.Config
+	listener    net.Listener
+	connections atomic.Int64
+	wg          sync.WaitGroup
+}
+
+// NewServer starts a server on a loopback port, serving Root, a fresh
+// temporary directory, as /. The server stops when the test ends.
+func NewServer(t testing.TB, mode TLSMode) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		Root:     t.TempDir(),
+		User:     "tester",
+		Password: "correct horse",
+		mode:     mode,
+		listener: listener,
+	}
+	if mode != NoTLS {
+		s.tls, s.ClientTLS = certificate(t)
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+// Connections returns the number of control connections accepted so far.
+func (s *Server) Connections() int {
+	return int(s.connections.Load())
+}
+
+func certificate(t testing.TB) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ftptest"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	return server, &tls.Config{RootCAs: pool}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.connections.Add(1)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			if s.mode == ImplicitTLS {
+				conn = tls.Server(conn, s.tls)
+			}
+			(&session{server: s, cwd: "/"}).run(conn)
+		}()
+	}
+}
+
+type session struct {
+	server *Server
+	conn   net.Conn
+	r      *bufio.Reader
+
+	loggedIn   bool
+	user       string
+	cwd        string
+	private    bool
+	rest       int64
+	renameFrom string
+	passive    net.Listener
+	active     string
+}
+
+func (s *session) reply(code int, format string, args ...any) {
+	fmt.Fprintf(s.conn, "%d %s\r\n", code, fmt.Sprintf(format, args...))
+}
+
+func (s *session) run(conn net.Conn) {
+	s.conn, s.r = conn, bufio.NewReader(conn)
+	defer func() {
+		if s.passive != nil {
+			s.passive.Close()
+		}
+	}()
+
+	s.reply(220, "ftptest ready")
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(time.Minute))
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		command = strings.ToUpper(command)
+
+		if !s.loggedIn && command != "USER" && command != "PASS" && command != "AUTH" && command != "FEAT" && command != "QUIT" {
+			s.reply(530, "Please log in")
+			continue
+		}
+		if command == "QUIT" {
+			s.reply(221, "Bye")
+			return
+		}
+		s.handle(command, arg)
+	}
+}
+
+// resolve maps a client path onto the server's root.
+func (s *session) resolve(name string) (string, string) {
+	if !path.IsAbs(name) {
+		name = path.Join(s.cwd, name)
+	}
+	name = path.Clean("/" + name)
+	return name, filepath.Join(s.server.Root, filepath.FromSlash(name))
+}
+
+func (s *session) handle(command, arg string) {
+	rest := s.rest
+	s.rest = 0
+
+	switch command {
+	case "AUTH":
+		if s.server.mode != ExplicitTLS {
+			s.reply(502, "TLS not available")
+			return
+		}
+		s.reply(234, "Proceed with negotiation")
+		tlsConn := tls.Server(s.conn, s.server.tls)
+		if err := tlsConn.Handshake(); err != nil {
+			s.conn.Close()
+			return
+		}
+		s.conn, s.r = tlsConn, bufio.NewReader(tlsConn)
+	case "USER":
+		s.user = arg
+		s.reply(331, "Password required")
+	case "PASS":
+		if s.user != s.server.User || arg != s.server.Password {
+			s.reply(530, "Login incorrect")
+			return
+		}
+		s.loggedIn = true
+		s.reply(230, "Logged in")
+	case "PBSZ":
+		s.reply(200, "PBSZ=0")
+	case "PROT":
+		s.private = strings.EqualFold(arg, "P")
+		s.reply(200, "Protection set")
+	case "FEAT":
+		features := []string{"EPSV", "MFMT", "REST STREAM", "SIZE", "UTF8"}
+		if !s.server.NoMLSD {
+			features = append(features, "MLST type*;size*;modify*;UNIX.mode*;")
+		}
+		fmt.Fprintf(s.conn, "211-Features:\r\n")
+		for _, feature := range features {
+			fmt.Fprintf(s.conn, " %s\r\n", feature)
+		}
+		s.reply(211, "End")
+	case "OPTS", "TYPE", "NOOP":
+		s.reply(200, "OK")
+	case "SYST":
+		s.reply(215, "UNIX Type: L8")
+	case "PWD":
+		s.reply(257, "%q is the current directory", s.cwd)
+	case "CWD":
+		name, local := s.resolve(arg)
+		if info, err := os.Stat(local); err != nil || !info.IsDir() {
+			s.reply(550, "No such directory")
+			return
+		}
+		s.cwd = name
+		s.reply(250, "OK")
+	case "EPSV", "PASV":
+		if s.passive != nil {
+			s.passive.Close()
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			s.reply(425, "Cannot listen")
+			return
+		}
+		s.passive, s.active = listener, ""
+		port := listener.Addr().(*net.TCPAddr).Port
+		if command == "EPSV" {
+			s.reply(229, "Entering Extended Passive Mode (|||%d|)", port)
+		} else {
+			s.reply(227, "Entering Passive Mode (127,0,0,1,%d,%d)", port>>8, port&0xff)
+		}
+	case "PORT":
+		parts := strings.Split(arg, ",")
+		if len(parts) != 6 {
+			s.reply(501, "Bad PORT")
+			return
+		}
+		high, _ := strconv.Atoi(parts[4])
+		low, _ := strconv.Atoi(parts[5])
+		s.setActive(net.JoinHostPort(strings.Join(parts[:4], "."), strconv.Itoa(high<<8|low)))
+	case "EPRT":
+		var parts []string
+		if arg != "" {
+			parts = strings.Split(arg, arg[:1])
+		}
+		if len(parts) != 5 {
+			s.reply(501, "Bad EPRT")
+			return
+		}
+		s.setActive(net.JoinHostPort(parts[2], parts[3]))
+	case "REST":
+		offset, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || offset < 0 {
+			s.reply(501, "Bad offset")
+			return
+		}
+		s.rest = offset
+		s.reply(350, "Restarting at %d", offset)
+	case "MLSD", "LIST":
+		s.list(command, strings.TrimPrefix(arg, "-a "))
+	case "MLST":
+		name, local := s.resolve(arg)
+		info, err := os.Stat(local)
+		if err != nil {
+			s.reply(550, "No such file")
+			return
+		}
+		fmt.Fprintf(s.conn, "250-Listing %s\r\n %s %s\r\n", name, facts(info), name)
+		s.reply(250, "End")
+	case "SIZE":
+		_, local := s.resolve(arg)
+		info, err := os.Stat(local)
+		if err != nil || info.IsDir() {
+			s.reply(550, "No such file")
+			return
+		}
+		s.reply(213, "%d", info.Size())
+	case "MFMT":
+		stamp, name, _ := strings.Cut(arg, " ")
+		modified, err := time.Parse("20060102150405", stamp)
+		_, local := s.resolve(name)
+		if err != nil || os.Chtimes(local, modified, modified) != nil {
+			s.reply(550, "Cannot set time")
+			return
+		}
+		s.reply(213, "Modify=%s; %s", stamp, name)
+	case "RETR":
+		s.retrieve(arg, rest)
+	case "STOR", "APPE":
+		s.store(arg, rest, command == "APPE")
+	case "DELE":
+		_, local := s.resolve(arg)
+		if info, err := os.Lstat(local); err != nil || info.IsDir() {
+			s.reply(550, "No such file")
+			return
+		}
+		s.result(os.Remove(local), 250)
+	case "MKD":
+		name, local := s.resolve(arg)
+		if err := os.Mkdir(local, 0o755); err != nil {
+			s.reply(550, "Cannot create directory")
+			return
+		}
+		s.reply(257, "%q created", name)
+	case "RMD":
+		_, local := s.resolve(arg)
+		if info, err := os.Stat(local); err != nil || !info.IsDir() {
+			s.reply(550, "No such directory")
+			return
+		}
+		s.result(os.Remove(local), 250)
+	case "RNFR":
+		_, local := s.resolve(arg)
+		if _, err := os.Lstat(local); err != nil {
+			s.reply(550, "No such file")
+			return
+		}
+		s.renameFrom = local
+		s.reply(350, "Ready for RNTO")
+	case "RNTO":
+		_, local := s.resolve(arg)
+		if s.renameFrom == "" {
+			s.reply(503, "RNFR first")
+			return
+		}
+		if _, err := os.Lstat(local); err == nil {
+			s.reply(553, "Target exists")
+			return
+		}
+		s.result(os.Rename(s.renameFrom, local), 250)
+		s.renameFrom = ""
+	case "SITE":
+		fields := strings.SplitN(arg, " ", 3)
+		if len(fields) != 3 || !strings.EqualFold(fields[0], "CHMOD") {
+			s.reply(502, "Unknown SITE command")
+			return
+		}
+		mode, err := strconv.ParseUint(fields[1], 8, 32)
+		_, local := s.resolve(fields[2])
+		if err != nil {
+			s.reply(501, "Bad mode")
+			return
+		}
+		s.result(os.Chmod(local, fs.FileMode(mode).Perm()), 200)
+	case "ABOR":
+		s.reply(226, "Nothing to abort")
+	default:
+		s.reply(502, "Command not implemented")
+	}
+}
+
+func (s *session) result(err error, code int) {
+	if err != nil {
+		s.reply(550, "%s", err)
+		return
+	}
+	s.reply(code, "OK")
+}
+
+func (s *session) setActive(address string) {
+	if s.passive != nil {
+		s.passive.Close()
+		s.passive = nil
+	}
+	s.active = address
+	s.reply(200, "OK")
+}
+
+// data opens the data connection set up by the last PASV, EPSV, PORT or
+// EPRT, after telling the client it is about to.
+func (s *session) data() (net.Conn, bool) {
+	var conn net.Conn
+	var err error
+	switch {
+	case s.passive != nil:
+		s.reply(150, "Opening data connection")
+		s.passive.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+		conn, err = s.passive.Accept()
+		s.passive.Close()
+		s.passive = nil
+	case s.active != "":
+		s.reply(150, "Opening data connection")
+		if s.server.BeforeActive != nil {
+			s.server.BeforeActive(s.active)
+		}
+		conn, err = net.DialTimeout("tcp", s.active, 10*time.Second)
+		s.active = ""
+	default:
+		s.reply(425, "Use PASV or PORT first")
+		return nil, false
+	}
+	if err != nil {
+		s.reply(425, "Cannot open data connection")
+		return nil, false
+	}
+
+	if s.private {
+		tlsConn := tls.Server(conn, s.server.tls)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			s.reply(425, "TLS handshake failed")
+			return nil, false
+		}
+		conn = tlsConn
+	}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	return conn, true
+}
+
+func (s *session) list(command, arg string) {
+	_, local := s.resolve(arg)
+	entries, err := os.ReadDir(local)
+	if err != nil {
+		s.reply(550, "No such directory")
+		return
+	}
+
+	conn, ok := s.data()
+	if !ok {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if command == "MLSD" {
+			fmt.Fprintf(conn, "%s %s\r\n", facts(info), info.Name())
+			continue
+		}
+		kind := "-"
+		if info.IsDir() {
+			kind = "d"
+		}
+		fmt.Fprintf(conn, "%s%s 1 owner group %12d %s %s\r\n",
+			kind, info.Mode().Perm().String()[1:], info.Size(), info.ModTime().UTC().Format("Jan _2  2006"), info.Name())
+	}
+	conn.Close()
+	s.reply(226, "Transfer complete")
+}
+
+func facts(info fs.FileInfo) string {
+	kind := "file"
+	if info.IsDir() {
+		kind = "dir"
+	}
+	return fmt.Sprintf("type=%s;size=%d;modify=%s;UNIX.mode=%04o;",
+		kind, info.Size(), info.ModTime().UTC().Format("20060102150405"), info.Mode().Perm())
+}
+
+func (s *session) retrieve(name string, offset int64) {
+	_, local := s.resolve(name)
+	file, err := os.Open(local)
+	if err != nil {
+		s.reply(550, "No such file")
+		return
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		s.reply(550, "Not a plain file")
+		return
+	}
+
+	conn, ok := s.data()
+	if !ok {
+		return
+	}
+	_, err = io.Copy(conn, io.NewSectionReader(file, offset, 1<<62))
+	if closeErr := conn.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		s.reply(426, "Transfer aborted")
+		return
+	}
+	s.reply(226, "Transfer complete")
+}
+
+func (s *session) store(name string, offset int64, appending bool) {
+	_, local := s.resolve(name)
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case appending:
+		flags |= os.O_APPEND
+	case offset == 0:
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(local, flags, 0o644)
+	if err != nil {
+		s.reply(553, "Cannot create file")
+		return
+	}
+	defer file.Close()
+	if offset > 0 && !appending {
+		file.Seek(offset, io.SeekStart)
+	}
+
+	conn, ok := s.data()
+	if !ok {
+		return
+	}
+	_, err = io.Copy(file, conn)
+	conn.Close()
+	if err != nil {
+		s.reply(426, "Transfer aborted")
+		return
+	}
+	s.reply(226, "Transfer complete")
+}
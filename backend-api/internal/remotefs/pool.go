@@ -0,0 +1,180 @@
+package remotefs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Dialer opens a connection, within timeout.
+type Dialer func(ctx context.Context, timeout time.Duration) (Conn, error)
+
+// Session is a connection taken from a Pool. It must be given back with
+// Release.
+type Session struct {
+	Conn
+
+	pool     *Pool
+	key      string
+	lastUsed time.Time
+}
+
+// Release hands the session back to its pool, or closes it if the
+// connection has dropped.
+func (s *Session) Release() {
+	if !s.Alive() {
+		s.Close()
+		return
+	}
+	s.pool.put(s)
+}
+
+// Pool keeps idle connections per key, which callers derive from the user
+// and the server they log in to, so browsing a directory does not cost a
+// handshake per request.
+type Pool struct {
+	DialTimeout   time.Duration
+	IdleTimeout   time.Duration
+	MaxIdlePerKey int
+
+	mu     sync.Mutex
+	idle   map[string][]*Session
+	closed bool
+	stop   chan struct{}
+}
+
+// NewPool starts a pool that closes connections idle for longer than
+// idleTimeout.
+func NewPool(dialTimeout, idleTimeout time.Duration, maxIdlePerKey int) *Pool {
+	p := &Pool{
+		DialTimeout:   dialTimeout,
+		IdleTimeout:   idleTimeout,
+		MaxIdlePerKey: maxIdlePerKey,
+		idle:          map[string][]*Session{},
+		stop:          make(chan struct{}),
+	}
+	go p.evictLoop()
+	return p
+}
+
+// Get returns an idle session for key, or dials a new one. dial is only
+// called when dialing, so credentials are not looked up for reused
+// sessions.
+func (p *Pool) Get(ctx context.Context, key string, dial Dialer) (*Session, error) {
+	if session, err := p.take(key); session != nil || err != nil {
+		return session, err
+	}
+
+	conn, err := dial(ctx, p.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Conn: conn, pool: p, key: key}, nil
+}
+
+func (p *Pool) take(key string) (*Session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+
+	sessions := p.idle[key]
+	for len(sessions) > 0 {
+		session := sessions[len(sessions)-1]
+		sessions = sessions[:len(sessions)-1]
+		if session.Alive() {
+			p.setIdle(key, sessions)
+			return session, nil
+		}
+		go session.Close()
+	}
+	p.setIdle(key, sessions)
+	return nil, nil
+}
+
+func (p *Pool) put(session *Session) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sessions := p.idle[session.key]
+	if p.closed || len(sessions) >= p.MaxIdlePerKey {
+		go session.Close()
+		return
+	}
+
+	session.lastUsed = time.Now()
+	p.idle[session.key] = append(sessions, session)
+}
+
+func (p *Pool) setIdle(key string, sessions []*Session) {
+	if len(sessions) == 0 {
+		delete(p.idle, key)
+		return
+	}
+	p.idle[key] = sessions
+}
+
+// Idle returns the number of idle sessions.
+func (p *Pool) Idle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := 0
+	for _, sessions := range p.idle {
+		count += len(sessions)
+	}
+	return count
+}
+
+func (p *Pool) evictLoop() {
+	ticker := time.NewTicker(max(p.IdleTimeout/4, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.evict(now)
+		}
+	}
+}
+
+func (p *Pool) evict(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, sessions := range p.idle {
+		kept := sessions[:0]
+		for _, session := range sessions {
+			if !session.Alive() || now.Sub(session.lastUsed) > p.IdleTimeout {
+				go session.Close()
+				continue
+			}
+			kept = append(kept, session)
+		}
+		p.setIdle(key, kept)
+	}
+}
+
+// Close closes every idle session. Sessions in use are closed when they are
+// released.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.stop)
+
+	for key, sessions := range p.idle {
+		for _, session := range sessions {
+			session.Close()
+		}
+		delete(p.idle, key)
+	}
+}
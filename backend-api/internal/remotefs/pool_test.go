@@ -0,0 +1,99 @@
+package remotefs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeConn struct {
+	FS
+	dropped atomic.Bool
+	closed  atomic.Bool
+}
+
+func (c *fakeConn) Alive() bool {
+	return !c.dropped.Load() && !c.closed.Load()
+}
+
+func (c *fakeConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func countingDialer(dials *int) Dialer {
+	return func(context.Context, time.Duration) (Conn, error) {
+		*dials++
+		return &fakeConn{}, nil
+	}
+}
+
+func TestPool_ReusesIdleSessions(t *testing.T) {
+	pool := NewPool(5*time.Second, time.Minute, 2)
+	defer pool.Close()
+
+	var dials int
+	for i := 0; i < 3; i++ {
+		session, err := pool.Get(context.Background(), "user|host", countingDialer(&dials))
+		if err != nil {
+			t.Fatalf("Expected a session, got: %v", err)
+		}
+		session.Release()
+	}
+
+	if dials != 1 {
+		t.Errorf("Expected one connection, got %d", dials)
+	}
+	if pool.Idle() != 1 {
+		t.Errorf("Expected one idle session, got %d", pool.Idle())
+	}
+}
+
+func TestPool_SkipsDroppedAndExpiredSessions(t *testing.T) {
+	pool := NewPool(5*time.Second, time.Minute, 2)
+	defer pool.Close()
+
+	var dials int
+	session, err := pool.Get(context.Background(), "k", countingDialer(&dials))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Release()
+	session.Conn.(*fakeConn).dropped.Store(true)
+
+	session, err = pool.Get(context.Background(), "k", countingDialer(&dials))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Release()
+
+	if dials != 2 {
+		t.Errorf("Expected a new connection after the first dropped, got %d", dials)
+	}
+
+	pool.evict(time.Now().Add(2 * time.Minute))
+	if pool.Idle() != 0 {
+		t.Errorf("Expected the idle session to be evicted, got %d idle", pool.Idle())
+	}
+}
+
+func TestPool_ClosedPoolRefusesSessions(t *testing.T) {
+	pool := NewPool(5*time.Second, time.Minute, 2)
+
+	var dials int
+	session, _ := pool.Get(context.Background(), "k", countingDialer(&dials))
+	pool.Close()
+	session.Release()
+
+	conn := session.Conn.(*fakeConn)
+	for i := 0; i < 100 && !conn.closed.Load(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !conn.closed.Load() {
+		t.Error("Expected a session released after Close to be closed")
+	}
+	if _, err := pool.Get(context.Background(), "k", countingDialer(&dials)); err != ErrPoolClosed {
+		t.Errorf("Expected ErrPoolClosed, got: %v", err)
+	}
+}
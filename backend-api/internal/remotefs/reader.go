@@ -0,0 +1,129 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// Reader is a seekable view of a remote file of a known size. It opens the
+// file lazily at the offset of the first read, and again after a seek, so
+// serving a range costs one request.
+type Reader struct {
+	ctx  context.Context
+	fs   FS
+	name string
+	size int64
+
+	offset int64
+	r      io.ReadCloser
+	at     int64
+}
+
+func NewReader(ctx context.Context, fsys FS, name string, size int64) *Reader {
+	return &Reader{ctx: ctx, fs: fsys, name: name, size: size}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.r != nil && r.at != r.offset {
+		r.r.Close()
+		r.r = nil
+	}
+	if r.r == nil {
+		rc, err := r.fs.Open(r.ctx, r.name, r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.r, r.at = rc, r.offset
+	}
+
+	if remaining := r.size - r.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.r.Read(p)
+	r.offset += int64(n)
+	r.at += int64(n)
+	if err == io.EOF && r.offset < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("remotefs: negative offset")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *Reader) Close() error {
+	if r.r == nil {
+		return nil
+	}
+	err := r.r.Close()
+	r.r = nil
+	return err
+}
+
+// MkdirAll creates name and any missing parents one at a time, for backends
+// without a single call for it.
+func MkdirAll(fsys FS, name string) error {
+	info, err := fsys.Stat(name)
+	if err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+		return nil
+	}
+
+	if parent := path.Dir(name); parent != name && parent != "." && parent != "/" {
+		if err := MkdirAll(fsys, parent); err != nil {
+			return err
+		}
+	}
+	if err := fsys.Mkdir(name); err != nil {
+		// Someone else may have created it meanwhile.
+		if info, statErr := fsys.Stat(name); statErr == nil && info.IsDir() {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoveAll deletes a tree depth first, for backends without a single call
+// for it.
+func RemoveAll(fsys FS, name string) error {
+	info, err := fsys.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fsys.Remove(name)
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := RemoveAll(fsys, path.Join(name, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return fsys.RemoveDirectory(name)
+}
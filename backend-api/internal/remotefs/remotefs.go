@@ -0,0 +1,123 @@
+// Package remotefs is the file system interface the gateway, the transfer
+// engine and directory sync use, whatever the protocol of the server.
+// Backends report what their protocol cannot do in Capabilities, and
+// return ErrUnsupported when asked to do it anyway.
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrConnectFailed = errors.New("could not connect to the server")
+	ErrAuthFailed    = errors.New("authentication failed")
+	ErrPoolClosed    = errors.New("connection pool is closed")
+	ErrUnsupported   = errors.New("the server does not support this operation")
+)
+
+// Capabilities lists the optional features of a backend.
+type Capabilities struct {
+	// Chmod is set if permissions can be changed.
+	Chmod bool
+	// Chtimes is set if modification times can be set.
+	Chtimes bool
+	// Symlinks is set if links are reported as links rather than followed,
+	// and can be created.
+	Symlinks bool
+	// Ownership is set if files report a numeric owner and group.
+	Ownership bool
+	// ResumeUpload is set if Create can continue a file at an offset.
+	ResumeUpload bool
+	// RenameDirectories is set if directories can be renamed.
+	RenameDirectories bool
+	// Exec is set if the backend implements Execer.
+	Exec bool
+}
+
+// FS is a remote file system. Paths are slash-separated; relative ones
+// resolve against the directory the server starts the session in.
+type FS interface {
+	Capabilities() Capabilities
+	// RealPath resolves name to an absolute, clean path.
+	RealPath(name string) (string, error)
+	Stat(name string) (fs.FileInfo, error)
+	// Lstat is Stat without following a final symlink.
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.FileInfo, error)
+	// Open reads a file from offset.
+	Open(ctx context.Context, name string, offset int64) (io.ReadCloser, error)
+	// Create writes a file from offset, which must be at most the file's
+	// size. An offset of 0 creates the file or truncates it. Errors writing
+	// may only be reported by Close.
+	Create(ctx context.Context, name string, offset int64) (io.WriteCloser, error)
+	Mkdir(name string) error
+	MkdirAll(name string) error
+	// Rename moves from to to, replacing a file at to if replace is set.
+	Rename(from, to string, replace bool) error
+	// Remove deletes a file or link.
+	Remove(name string) error
+	// RemoveDirectory deletes an empty directory.
+	RemoveDirectory(name string) error
+	// RemoveAll deletes a whole tree.
+	RemoveAll(name string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, modified time.Time) error
+	// Symlink creates name as a symbolic link to target.
+	Symlink(target, name string) error
+}
+
+// Execer runs shell commands on the server.
+type Execer interface {
+	// Exec returns the standard output of command, which must succeed.
+	Exec(ctx context.Context, command string) ([]byte, error)
+	// Stream returns the standard output of command as it is written.
+	// Closing it after reading to the end reports whether the command
+	// succeeded; closing it earlier stops the command.
+	Stream(ctx context.Context, command string) (io.ReadCloser, error)
+}
+
+// AsExecer returns the Execer behind fsys, looking through a Session, if
+// its backend can run commands.
+func AsExecer(fsys FS) (Execer, bool) {
+	if session, ok := fsys.(*Session); ok {
+		fsys = session.Conn
+	}
+	execer, ok := fsys.(Execer)
+	return execer, ok && fsys.Capabilities().Exec
+}
+
+// Conn is an FS over a connection that a Pool can keep for reuse.
+type Conn interface {
+	FS
+	// Alive reports false once the connection has dropped.
+	Alive() bool
+	Close() error
+}
+
+// ServerError is a request the server refused or failed, as opposed to a
+// connection that could not be made or was lost.
+type ServerError struct {
+	// Code is the protocol's status code, such as an FTP reply code or an
+	// HTTP status.
+	Code    int
+	Message string
+	// Err is a standard error the failure corresponds to, such as
+	// fs.ErrNotExist, or nil.
+	Err error
+}
+
+func (e *ServerError) Error() string {
+	if e.Message == "" {
+		return "server error " + strconv.Itoa(e.Code)
+	}
+	return "server error " + strconv.Itoa(e.Code) + ": " + e.Message
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
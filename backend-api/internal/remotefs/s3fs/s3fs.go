@@ -0,0 +1,543 @@
+// Package s3fs is the backend of remotefs for S3-compatible object storage,
+// such as AWS S3, MinIO or Ceph. Keys are mapped to paths on "/", with
+// common prefixes as directories and empty "name/" objects as markers for
+// directories with nothing in them yet. Without a configured bucket, the
+// top level lists the buckets.
+//
+// Objects have no permissions or settable times and cannot be written in
+// part, and renaming is a copy followed by a delete, so only files can be
+// renamed.
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"livecode-api/internal/remotefs"
+)
+
+// partSize is the size of the parts of a multipart upload. Files smaller
+// than this are uploaded with a single PUT.
+const partSize = 16 << 20
+
+// Config describes how to reach and log in to a server.
+type Config struct {
+	// Endpoint is the server's host and port.
+	Endpoint string
+	// Secure uses HTTPS.
+	Secure bool
+	// PathStyle puts the bucket in the path rather than the host name,
+	// which servers without wildcard DNS need. It is the default for
+	// endpoints other than AWS.
+	PathStyle bool
+	// Region defaults to us-east-1, which MinIO and most other servers
+	// accept.
+	Region string
+	// AccessKey and SecretKey sign requests; an empty AccessKey sends them
+	// anonymously.
+	AccessKey string
+	SecretKey string
+	// Bucket is served as /. If it is empty, each bucket is a directory
+	// under /.
+	Bucket string
+	// Timeout bounds connecting and waiting for each response's headers.
+	Timeout time.Duration
+	// Control, if set, vets each connection before it is opened.
+	Control remotefs.DialControl
+}
+
+// Conn is an FS over HTTP. Unlike the FTP and SFTP backends it is safe for
+// concurrent use.
+type Conn struct {
+	config    Config
+	client    *minio.Client
+	core      minio.Core
+	transport *http.Transport
+}
+
+// Dial checks that the credentials are accepted and, if one is configured,
+// that the bucket exists. Errors wrap remotefs.ErrAuthFailed or
+// remotefs.ErrConnectFailed.
+func Dial(ctx context.Context, config Config) (*Conn, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	transport, err := minio.DefaultTransport(config.Secure)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+	// minio retries requests that could not connect, which is pointless for
+	// a blocked address, so the checks below stop at the first.
+	checkCtx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	dialer := &net.Dialer{Timeout: config.Timeout, ControlContext: config.Control}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if errors.Is(err, remotefs.ErrAddressBlocked) {
+			stop(err)
+		}
+		return conn, err
+	}
+	if config.Control != nil {
+		// A proxy would connect on our behalf, out of Control's reach.
+		transport.Proxy = nil
+	}
+	transport.ResponseHeaderTimeout = config.Timeout
+
+	lookup := minio.BucketLookupAuto
+	if config.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure:       config.Secure,
+		Region:       config.Region,
+		BucketLookup: lookup,
+		Transport:    transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+	c := &Conn{config: config, client: client, core: minio.Core{Client: client}, transport: transport}
+
+	if config.Bucket != "" {
+		var exists bool
+		exists, err = client.BucketExists(checkCtx, config.Bucket)
+		if err == nil && !exists {
+			return nil, fmt.Errorf("%w: bucket %q does not exist", remotefs.ErrConnectFailed, config.Bucket)
+		}
+	} else {
+		_, err = client.ListBuckets(checkCtx)
+	}
+	if cause := context.Cause(checkCtx); errors.Is(cause, remotefs.ErrAddressBlocked) {
+		err = cause
+	}
+	if err != nil {
+		if errors.Is(mapError(err), fs.ErrPermission) {
+			return nil, fmt.Errorf("%w: %w", remotefs.ErrAuthFailed, err)
+		}
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+	return c, nil
+}
+
+// mapError turns an S3 error response into a remotefs.ServerError. Errors
+// that are not responses, such as a lost connection, are returned as is.
+func mapError(err error) error {
+	resp := minio.ToErrorResponse(err)
+	if resp.StatusCode == 0 {
+		return err
+	}
+
+	var base error
+	switch resp.Code {
+	case "NoSuchKey", "NoSuchBucket", "NotFound":
+		base = fs.ErrNotExist
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		base = fs.ErrPermission
+	case "BucketAlreadyExists", "BucketAlreadyOwnedByYou":
+		base = fs.ErrExist
+	case "NotImplemented":
+		base = remotefs.ErrUnsupported
+	}
+	message := resp.Code
+	if resp.Message != "" {
+		message += ": " + resp.Message
+	}
+	return &remotefs.ServerError{Code: resp.StatusCode, Message: message, Err: base}
+}
+
+func (c *Conn) Capabilities() remotefs.Capabilities {
+	return remotefs.Capabilities{}
+}
+
+// Alive is always true; each request opens a connection if it needs one.
+func (c *Conn) Alive() bool {
+	return true
+}
+
+func (c *Conn) Close() error {
+	c.transport.CloseIdleConnections()
+	return nil
+}
+
+func (c *Conn) RealPath(name string) (string, error) {
+	return path.Join("/", name), nil
+}
+
+// locate returns the bucket and key of name. The key is empty for a bucket
+// and the bucket for /, when no bucket is configured.
+func (c *Conn) locate(name string) (string, string) {
+	resolved := strings.TrimPrefix(path.Join("/", name), "/")
+	if c.config.Bucket != "" {
+		return c.config.Bucket, resolved
+	}
+	bucket, key, _ := strings.Cut(resolved, "/")
+	return bucket, key
+}
+
+func directoryInfo(name string, modified time.Time) *remotefs.FileInfo {
+	return &remotefs.FileInfo{FileName: name, FileMode: fs.ModeDir | 0o755, Modified: modified}
+}
+
+func objectInfo(name string, object minio.ObjectInfo) *remotefs.FileInfo {
+	return &remotefs.FileInfo{FileName: name, FileSize: object.Size, FileMode: 0o644, Modified: object.LastModified}
+}
+
+func (c *Conn) Stat(name string) (fs.FileInfo, error) {
+	bucket, key := c.locate(name)
+	switch {
+	case bucket == "" || (key == "" && c.config.Bucket != ""):
+		return directoryInfo("/", time.Time{}), nil
+	case key == "":
+		exists, err := c.client.BucketExists(context.Background(), bucket)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		if !exists {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+		}
+		return directoryInfo(bucket, time.Time{}), nil
+	}
+
+	object, err := c.client.StatObject(context.Background(), bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		return objectInfo(path.Base(key), object), nil
+	}
+	if err = mapError(err); !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	entries, marker, err := c.list(bucket, key+"/", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 && marker == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	modified := time.Time{}
+	if marker != nil {
+		modified = marker.ModTime()
+	}
+	return directoryInfo(path.Base(key), modified), nil
+}
+
+// Lstat is Stat; object storage has no links.
+func (c *Conn) Lstat(name string) (fs.FileInfo, error) {
+	return c.Stat(name)
+}
+
+// list returns the entries directly under prefix, stopping after limit if
+// it is positive, and the prefix's own directory marker if there is one.
+func (c *Conn) list(bucket, prefix string, limit int) ([]fs.FileInfo, fs.FileInfo, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var entries []fs.FileInfo
+	var marker fs.FileInfo
+	for object := range c.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, nil, mapError(object.Err)
+		}
+		if object.Key == prefix {
+			marker = objectInfo(path.Base(prefix), object)
+			continue
+		}
+		name := strings.TrimPrefix(object.Key, prefix)
+		if dir, ok := strings.CutSuffix(name, "/"); ok {
+			entries = append(entries, directoryInfo(dir, object.LastModified))
+		} else {
+			entries = append(entries, objectInfo(name, object))
+		}
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+	return entries, marker, nil
+}
+
+func (c *Conn) ReadDir(name string) ([]fs.FileInfo, error) {
+	bucket, key := c.locate(name)
+	if bucket == "" {
+		buckets, err := c.client.ListBuckets(context.Background())
+		if err != nil {
+			return nil, mapError(err)
+		}
+		infos := make([]fs.FileInfo, 0, len(buckets))
+		for _, b := range buckets {
+			infos = append(infos, directoryInfo(b.Name, b.CreationDate))
+		}
+		return infos, nil
+	}
+
+	prefix := ""
+	if key != "" {
+		prefix = key + "/"
+	}
+	entries, marker, err := c.list(bucket, prefix, 0)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" && len(entries) == 0 && marker == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return entries, nil
+}
+
+func (c *Conn) Open(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	bucket, key := c.locate(name)
+	opts := minio.GetObjectOptions{}
+	if offset > 0 {
+		opts.SetRange(offset, 0)
+	}
+	body, _, _, err := c.core.GetObject(ctx, bucket, key, opts)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "InvalidRange" {
+			// The offset is the object's size.
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, mapError(err)
+	}
+	return body, nil
+}
+
+// Create uploads with a single PUT if the file turns out to be smaller than
+// a part, or streams a multipart upload otherwise.
+func (c *Conn) Create(ctx context.Context, name string, offset int64) (io.WriteCloser, error) {
+	if offset > 0 {
+		return nil, remotefs.ErrUnsupported
+	}
+	bucket, key := c.locate(name)
+	if key == "" {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: errors.New("is a directory")}
+	}
+	return &objectWriter{ctx: ctx, conn: c, bucket: bucket, key: key}, nil
+}
+
+type objectWriter struct {
+	ctx    context.Context
+	conn   *Conn
+	bucket string
+	key    string
+
+	buf  bytes.Buffer
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	if w.pw != nil {
+		return w.pw.Write(p)
+	}
+	w.buf.Write(p)
+	if w.buf.Len() < partSize {
+		return len(p), nil
+	}
+
+	pr, pw := io.Pipe()
+	w.pw, w.done = pw, make(chan error, 1)
+	go func() {
+		_, err := w.conn.client.PutObject(w.ctx, w.bucket, w.key, io.MultiReader(&w.buf, pr), -1, minio.PutObjectOptions{PartSize: partSize})
+		if err != nil {
+			err = mapError(err)
+		}
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return len(p), nil
+}
+
+func (w *objectWriter) Close() error {
+	if w.pw != nil {
+		w.pw.Close()
+		return <-w.done
+	}
+	_, err := w.conn.client.PutObject(w.ctx, w.bucket, w.key, bytes.NewReader(w.buf.Bytes()), int64(w.buf.Len()), minio.PutObjectOptions{PartSize: partSize})
+	if err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+// Mkdir creates a bucket at the top level, and a directory marker below it.
+func (c *Conn) Mkdir(name string) error {
+	bucket, key := c.locate(name)
+	if _, err := c.Stat(name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if key == "" {
+		err := c.client.MakeBucket(context.Background(), bucket, minio.MakeBucketOptions{Region: c.config.Region})
+		if err != nil {
+			return mapError(err)
+		}
+		return nil
+	}
+	_, err := c.client.PutObject(context.Background(), bucket, key+"/", bytes.NewReader(nil), 0, minio.PutObjectOptions{})
+	if err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+func (c *Conn) MkdirAll(name string) error {
+	return remotefs.MkdirAll(c, name)
+}
+
+// Rename copies a file on the server and deletes the original.
+func (c *Conn) Rename(from, to string, replace bool) error {
+	info, err := c.Stat(from)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return remotefs.ErrUnsupported
+	}
+	if !replace {
+		if _, err := c.Stat(to); err == nil {
+			return &fs.PathError{Op: "rename", Path: to, Err: fs.ErrExist}
+		}
+	}
+
+	fromBucket, fromKey := c.locate(from)
+	toBucket, toKey := c.locate(to)
+	if toKey == "" {
+		return &fs.PathError{Op: "rename", Path: to, Err: errors.New("is a directory")}
+	}
+	_, err = c.client.CopyObject(context.Background(),
+		minio.CopyDestOptions{Bucket: toBucket, Object: toKey},
+		minio.CopySrcOptions{Bucket: fromBucket, Object: fromKey})
+	if err != nil {
+		return mapError(err)
+	}
+	return c.removeObject(fromBucket, fromKey)
+}
+
+func (c *Conn) removeObject(bucket, key string) error {
+	if err := c.client.RemoveObject(context.Background(), bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+func (c *Conn) Remove(name string) error {
+	info, err := c.Stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return &fs.PathError{Op: "remove", Path: name, Err: errors.New("is a directory")}
+	}
+	bucket, key := c.locate(name)
+	return c.removeObject(bucket, key)
+}
+
+// RemoveDirectory deletes an empty bucket at the top level, and a directory
+// marker below it.
+func (c *Conn) RemoveDirectory(name string) error {
+	bucket, key := c.locate(name)
+	switch {
+	case bucket == "" || (key == "" && c.config.Bucket != ""):
+		return &fs.PathError{Op: "rmdir", Path: name, Err: fs.ErrPermission}
+	case key == "":
+		if err := c.client.RemoveBucket(context.Background(), bucket); err != nil {
+			return mapError(err)
+		}
+		return nil
+	}
+
+	entries, marker, err := c.list(bucket, key+"/", 1)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(entries) > 0:
+		return &fs.PathError{Op: "rmdir", Path: name, Err: errors.New("directory not empty")}
+	case marker == nil:
+		return &fs.PathError{Op: "rmdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return c.removeObject(bucket, key+"/")
+}
+
+// RemoveAll deletes every key under name in batches, then the bucket if
+// name is one.
+func (c *Conn) RemoveAll(name string) error {
+	info, err := c.Stat(name)
+	if err != nil {
+		return err
+	}
+	bucket, key := c.locate(name)
+	if !info.IsDir() {
+		return c.removeObject(bucket, key)
+	}
+	if bucket == "" {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prefix := ""
+	if key != "" {
+		prefix = key + "/"
+	}
+	objects := c.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	var listErr error
+	keys := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(keys)
+		for object := range objects {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			select {
+			case keys <- object:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for result := range c.client.RemoveObjects(ctx, bucket, keys, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			cancel()
+			return mapError(result.Err)
+		}
+	}
+	if listErr != nil {
+		return mapError(listErr)
+	}
+
+	if key == "" && c.config.Bucket == "" {
+		if err := c.client.RemoveBucket(context.Background(), bucket); err != nil {
+			return mapError(err)
+		}
+	}
+	return nil
+}
+
+func (c *Conn) Chmod(string, fs.FileMode) error {
+	return remotefs.ErrUnsupported
+}
+
+func (c *Conn) Chtimes(string, time.Time) error {
+	return remotefs.ErrUnsupported
+}
+
+func (c *Conn) Symlink(string, string) error {
+	return remotefs.ErrUnsupported
+}
@@ -0,0 +1,223 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/remotefs/s3fs/s3test"
+)
+
+func dialTest(t *testing.T, server *s3test.Server, bucket string) *Conn {
+	t.Helper()
+
+	endpoint, _ := url.Parse(server.URL)
+	conn, err := Dial(context.Background(), Config{
+		Endpoint:  endpoint.Host,
+		AccessKey: server.AccessKey,
+		SecretKey: server.SecretKey,
+		Bucket:    bucket,
+		Timeout:   5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Expected to connect, got: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func writeFile(t *testing.T, fsys remotefs.FS, name string, data []byte) {
+	t.Helper()
+
+	w, err := fsys.Create(context.Background(), name, 0)
+	if err != nil {
+		t.Fatalf("Expected to create %s, got: %v", name, err)
+	}
+	// Write in pieces, as a transfer does.
+	for len(data) > 0 {
+		n := min(len(data), 1<<20)
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected the upload of %s to finish, got: %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, fsys remotefs.FS, name string, offset int64) string {
+	t.Helper()
+
+	r, err := fsys.Open(context.Background(), name, offset)
+	if err != nil {
+		t.Fatalf("Expected to open %s, got: %v", name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func names(infos []fs.FileInfo) string {
+	var list []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() {
+			name += "/"
+		}
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func TestConn_FileOperations(t *testing.T) {
+	server := s3test.NewServer(t)
+	server.CreateBucket("site")
+	server.PageSize = 2
+	conn := dialTest(t, server, "site")
+
+	if err := conn.MkdirAll("assets/img"); err != nil {
+		t.Fatalf("Expected mkdir -p to succeed, got: %v", err)
+	}
+	writeFile(t, conn, "index.html", []byte("hello world"))
+	writeFile(t, conn, "/docs/a.txt", []byte("a"))
+	writeFile(t, conn, "docs/b.txt", []byte("b"))
+
+	if got := readFile(t, conn, "index.html", 6); got != "world" {
+		t.Errorf("Expected to read from the offset, got %q", got)
+	}
+	if got := readFile(t, conn, "index.html", 11); got != "" {
+		t.Errorf("Expected nothing at the end of the file, got %q", got)
+	}
+
+	infos, err := conn.ReadDir("/")
+	if err != nil || names(infos) != "assets/,docs/,index.html" {
+		t.Errorf("Expected the listing over several pages, got %q, %v", names(infos), err)
+	}
+	if infos, err := conn.ReadDir("assets/img"); err != nil || len(infos) != 0 {
+		t.Errorf("Expected an empty directory from its marker, got %q, %v", names(infos), err)
+	}
+	if info, err := conn.Stat("docs"); err != nil || !info.IsDir() {
+		t.Errorf("Expected a directory from its keys alone, got %+v, %v", info, err)
+	}
+	if info, err := conn.Stat("index.html"); err != nil || info.Size() != 11 || info.ModTime().IsZero() {
+		t.Errorf("Expected the file's size and time, got %+v, %v", info, err)
+	}
+	if _, err := conn.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got: %v", err)
+	}
+
+	if err := conn.Rename("docs/a.txt", "docs/b.txt", false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected fs.ErrExist without replace, got: %v", err)
+	}
+	if err := conn.Rename("docs/a.txt", "docs/b.txt", true); err != nil {
+		t.Fatalf("Expected rename to replace, got: %v", err)
+	}
+	if data, _ := server.Object("site", "docs/b.txt"); string(data) != "a" {
+		t.Errorf("Expected the renamed object, got %q", data)
+	}
+	if err := conn.Rename("docs", "papers", false); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected renaming a directory to be unsupported, got: %v", err)
+	}
+
+	if err := conn.RemoveDirectory("assets"); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	if err := conn.RemoveDirectory("assets/img"); err != nil {
+		t.Errorf("Expected the empty directory to be removed, got: %v", err)
+	}
+	if err := conn.RemoveAll("docs"); err != nil {
+		t.Fatalf("Expected recursive delete to succeed, got: %v", err)
+	}
+	if keys := strings.Join(server.Keys("site"), ","); keys != "assets/,index.html" {
+		t.Errorf("Expected only the other keys to be left, got %q", keys)
+	}
+}
+
+func TestConn_MultipartUpload(t *testing.T) {
+	server := s3test.NewServer(t)
+	server.CreateBucket("backups")
+	conn := dialTest(t, server, "backups")
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), (partSize+partSize/2)/16)
+	writeFile(t, conn, "dump.bin", data)
+
+	if got, _ := server.Object("backups", "dump.bin"); !bytes.Equal(got, data) {
+		t.Errorf("Expected %d bytes uploaded in parts, got %d", len(data), len(got))
+	}
+}
+
+func TestConn_BucketsAsDirectories(t *testing.T) {
+	server := s3test.NewServer(t)
+	server.CreateBucket("alpha")
+	conn := dialTest(t, server, "")
+
+	if err := conn.Mkdir("beta"); err != nil {
+		t.Fatalf("Expected a bucket to be created, got: %v", err)
+	}
+	writeFile(t, conn, "beta/notes.txt", []byte("notes"))
+
+	infos, err := conn.ReadDir("/")
+	if err != nil || names(infos) != "alpha/,beta/" {
+		t.Errorf("Expected the buckets, got %q, %v", names(infos), err)
+	}
+	if got := readFile(t, conn, "/beta/notes.txt", 0); got != "notes" {
+		t.Errorf("Expected the object in the bucket, got %q", got)
+	}
+
+	if err := conn.RemoveAll("beta"); err != nil {
+		t.Fatalf("Expected the bucket to be removed, got: %v", err)
+	}
+	if _, err := conn.Stat("beta"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the bucket to be gone, got: %v", err)
+	}
+}
+
+func TestConn_UnsupportedOperations(t *testing.T) {
+	server := s3test.NewServer(t)
+	server.CreateBucket("site")
+	conn := dialTest(t, server, "site")
+	writeFile(t, conn, "a.txt", []byte("a"))
+
+	if _, err := conn.Create(context.Background(), "a.txt", 1); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected resuming an upload to be unsupported, got: %v", err)
+	}
+	if err := conn.Chmod("a.txt", 0o600); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected chmod to be unsupported, got: %v", err)
+	}
+	if err := conn.Chtimes("a.txt", time.Now()); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected chtimes to be unsupported, got: %v", err)
+	}
+}
+
+func TestDial_Failures(t *testing.T) {
+	server := s3test.NewServer(t)
+	endpoint, _ := url.Parse(server.URL)
+
+	_, err := Dial(context.Background(), Config{Endpoint: endpoint.Host, AccessKey: "AKIAWRONG", SecretKey: "x"})
+	if !errors.Is(err, remotefs.ErrAuthFailed) {
+		t.Errorf("Expected remotefs.ErrAuthFailed, got: %v", err)
+	}
+	_, err = Dial(context.Background(), Config{Endpoint: endpoint.Host, AccessKey: server.AccessKey, SecretKey: "x", Bucket: "missing"})
+	if !errors.Is(err, remotefs.ErrConnectFailed) {
+		t.Errorf("Expected remotefs.ErrConnectFailed for a missing bucket, got: %v", err)
+	}
+
+	policy := &remotefs.AddressPolicy{}
+	_, err = Dial(context.Background(), Config{Endpoint: endpoint.Host, AccessKey: server.AccessKey, SecretKey: server.SecretKey, Control: policy.Control})
+	if !errors.Is(err, remotefs.ErrAddressBlocked) {
+		t.Errorf("Expected remotefs.ErrAddressBlocked for a loopback server, got: %v", err)
+	}
+}
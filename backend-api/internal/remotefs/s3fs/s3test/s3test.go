@@ -0,0 +1,489 @@
+// Package s3test runs an in-process, in-memory stand-in for an
+// S3-compatible server, for end-to-end tests of the S3 backend. It speaks
+// path-style requests for the calls the backend makes: listing buckets and
+// objects (ListObjectsV2, paged), HEAD, ranged GET, PUT, server-side copy,
+// single and multi-object DELETE, and multipart uploads. Signatures are not
+// checked, only the access key.
+package s3test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type object struct {
+	data     []byte
+	etag     string
+	modified time.Time
+}
+
+type upload struct {
+	bucket string
+	key    string
+	parts  map[int][]byte
+}
+
+type Server struct {
+	// URL is the server's endpoint, such as http://127.0.0.1:1234.
+	URL       string
+	AccessKey string
+	SecretKey string
+	// PageSize caps the keys returned per listing, to exercise paging.
+	PageSize int
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*object
+	uploads map[string]*upload
+	nextID  int
+}
+
+// NewServer starts a server with no buckets. It stops when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		AccessKey: "AKIATESTER",
+		SecretKey: "correct horse battery staple",
+		PageSize:  1000,
+		buckets:   map[string]map[string]*object{},
+		uploads:   map[string]*upload{},
+	}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+	return s
+}
+
+// CreateBucket adds an empty bucket.
+func (s *Server) CreateBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buckets[name] == nil {
+		s.buckets[name] = map[string]*object{}
+	}
+}
+
+// Object returns the contents of an object.
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	return obj.data, true
+}
+
+// Keys returns the keys in a bucket, sorted.
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.buckets[bucket])
+}
+
+func sortedKeys(objects map[string]*object) []string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newObject(data []byte) *object {
+	sum := md5.Sum(data)
+	return &object{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`, modified: time.Now().UTC().Truncate(time.Second)}
+}
+
+type s3Error struct {
+	XMLName    xml.Name `xml:"Error"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	BucketName string   `xml:"BucketName,omitempty"`
+	Key        string   `xml:"Key,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(s3Error{Code: code, Message: message})
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential="+s.AccessKey+"/") {
+		writeError(w, http.StatusForbidden, "InvalidAccessKeyId", "The access key does not exist.")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bucket == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" /")
+			return
+		}
+		s.listBuckets(w)
+		return
+	}
+	objects, exists := s.buckets[bucket]
+	if key == "" {
+		s.serveBucket(w, r, bucket, objects, exists, body)
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+	s.serveObject(w, r, bucket, key, objects, body)
+}
+
+// readBody decodes the aws-chunked bodies of streaming signatures.
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var body bytes.Buffer
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk size %q", sizeHex)
+		}
+		if size == 0 {
+			return body.Bytes(), nil
+		}
+		if _, err := io.CopyN(&body, reader, size); err != nil {
+			return nil, err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Server) listBuckets(w http.ResponseWriter) {
+	type bucketXML struct {
+		Name         string `xml:"Name"`
+		CreationDate string `xml:"CreationDate"`
+	}
+	var result struct {
+		XMLName xml.Name    `xml:"ListAllMyBucketsResult"`
+		Buckets []bucketXML `xml:"Buckets>Bucket"`
+	}
+	names := make([]string, 0, len(s.buckets))
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result.Buckets = append(result.Buckets, bucketXML{Name: name, CreationDate: time.Now().UTC().Format(time.RFC3339)})
+	}
+	writeXML(w, result)
+}
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *http.Request, bucket string, objects map[string]*object, exists bool, body []byte) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPut:
+		if exists {
+			writeError(w, http.StatusConflict, "BucketAlreadyOwnedByYou", "The bucket already exists.")
+			return
+		}
+		s.buckets[bucket] = map[string]*object{}
+	case !exists:
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+	case r.Method == http.MethodHead:
+	case r.Method == http.MethodDelete:
+		if len(objects) > 0 {
+			writeError(w, http.StatusConflict, "BucketNotEmpty", "The bucket is not empty.")
+			return
+		}
+		delete(s.buckets, bucket)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && query.Has("delete"):
+		s.deleteObjects(w, objects, body)
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		s.listObjects(w, bucket, objects, query)
+	case r.Method == http.MethodGet && query.Has("location"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+		}{})
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+func (s *Server) deleteObjects(w http.ResponseWriter, objects map[string]*object, body []byte) {
+	var request struct {
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	if err := xml.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	type deleted struct {
+		Key string `xml:"Key"`
+	}
+	var result struct {
+		XMLName xml.Name  `xml:"DeleteResult"`
+		Deleted []deleted `xml:"Deleted"`
+	}
+	for _, obj := range request.Objects {
+		delete(objects, obj.Key)
+		result.Deleted = append(result.Deleted, deleted{Key: obj.Key})
+	}
+	writeXML(w, result)
+}
+
+func (s *Server) listObjects(w http.ResponseWriter, bucket string, objects map[string]*object, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	after := query.Get("start-after")
+	if token := query.Get("continuation-token"); token != "" {
+		after = token
+	}
+	limit := s.PageSize
+	if maxKeys, err := strconv.Atoi(query.Get("max-keys")); err == nil && maxKeys < limit {
+		limit = maxKeys
+	}
+
+	type contentsXML struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int64  `xml:"Size"`
+		StorageClass string `xml:"StorageClass"`
+	}
+	type prefixXML struct {
+		Prefix string `xml:"Prefix"`
+	}
+	var result struct {
+		XMLName               xml.Name      `xml:"ListBucketResult"`
+		Name                  string        `xml:"Name"`
+		Prefix                string        `xml:"Prefix"`
+		Delimiter             string        `xml:"Delimiter,omitempty"`
+		MaxKeys               int           `xml:"MaxKeys"`
+		KeyCount              int           `xml:"KeyCount"`
+		IsTruncated           bool          `xml:"IsTruncated"`
+		NextContinuationToken string        `xml:"NextContinuationToken,omitempty"`
+		Contents              []contentsXML `xml:"Contents"`
+		CommonPrefixes        []prefixXML   `xml:"CommonPrefixes"`
+	}
+	result.Name, result.Prefix, result.Delimiter, result.MaxKeys = bucket, prefix, delimiter, limit
+
+	last := ""
+	for _, key := range sortedKeys(objects) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		entry := key
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				entry = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if entry <= after || entry == last {
+			continue
+		}
+		if result.KeyCount == limit {
+			result.IsTruncated = true
+			result.NextContinuationToken = last
+			break
+		}
+
+		last = entry
+		result.KeyCount++
+		if entry != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, prefixXML{Prefix: entry})
+			continue
+		}
+		obj := objects[key]
+		result.Contents = append(result.Contents, contentsXML{
+			Key:          key,
+			LastModified: obj.modified.Format("2006-01-02T15:04:05.000Z"),
+			ETag:         obj.etag,
+			Size:         int64(len(obj.data)),
+			StorageClass: "STANDARD",
+		})
+	}
+	writeXML(w, result)
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, bucket, key string, objects map[string]*object, body []byte) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.nextID++
+		id := strconv.Itoa(s.nextID)
+		s.uploads[id] = &upload{bucket: bucket, key: key, parts: map[int][]byte{}}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string   `xml:"Bucket"`
+			Key      string   `xml:"Key"`
+			UploadID string   `xml:"UploadId"`
+		}{Bucket: bucket, Key: key, UploadID: id})
+	case query.Has("uploadId"):
+		s.serveUpload(w, r, objects, query, body)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		sourceBucket, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+		src, ok := s.buckets[sourceBucket][sourceKey]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		obj := newObject(src.data)
+		objects[key] = obj
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string   `xml:"ETag"`
+			LastModified string   `xml:"LastModified"`
+		}{ETag: obj.etag, LastModified: obj.modified.Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		obj := newObject(body)
+		objects[key] = obj
+		w.Header().Set("ETag", obj.etag)
+	case r.Method == http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		obj, ok := objects[key]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Accept-Ranges", "bytes")
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+			return
+		}
+		s.serveRange(w, r, obj.data)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+// serveRange answers a GET with an open-ended or closed byte range, the
+// forms clients use to resume.
+func (s *Server) serveRange(w http.ResponseWriter, r *http.Request, data []byte) {
+	spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes=")
+	if !ok {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+		return
+	}
+
+	first, last, _ := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(first)
+	if err != nil || start >= len(data) {
+		writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable.")
+		return
+	}
+	end := len(data) - 1
+	if n, err := strconv.Atoi(last); err == nil && n < end {
+		end = n
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+	w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(data[start : end+1])
+}
+
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request, objects map[string]*object, query url.Values, body []byte) {
+	id := query.Get("uploadId")
+	up, ok := s.uploads[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		number, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+			return
+		}
+		up.parts[number] = body
+		w.Header().Set("ETag", newObject(body).etag)
+	case http.MethodDelete:
+		delete(s.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		var request struct {
+			Parts []struct {
+				PartNumber int `xml:"PartNumber"`
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &request); err != nil {
+			writeError(w, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		var data []byte
+		for _, part := range request.Parts {
+			p, ok := up.parts[part.PartNumber]
+			if !ok {
+				writeError(w, http.StatusBadRequest, "InvalidPart", "a part was not uploaded")
+				return
+			}
+			data = append(data, p...)
+		}
+		delete(s.uploads, id)
+		obj := newObject(data)
+		objects[up.key] = obj
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string   `xml:"Bucket"`
+			Key     string   `xml:"Key"`
+			ETag    string   `xml:"ETag"`
+		}{Bucket: up.bucket, Key: up.key, ETag: obj.etag})
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
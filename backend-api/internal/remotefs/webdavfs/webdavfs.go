@@ -0,0 +1,413 @@
+// Package webdavfs is the WebDAV backend of remotefs, as in RFC 4918. It
+// uses PROPFIND to list and stat, GET with a Range to read from an offset,
+// PUT to write, and MKCOL, MOVE and DELETE for the rest. WebDAV has no
+// permissions, no way to set times and no partial writes, so those are
+// reported as unsupported.
+package webdavfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"livecode-api/internal/remotefs"
+)
+
+// Config describes how to reach and log in to a server.
+type Config struct {
+	// URL is the root of the share, such as https://host/remote.php/dav.
+	// Relative paths resolve against its path.
+	URL      string
+	Username string
+	Password string
+	TLS      *tls.Config
+	// Timeout bounds connecting and waiting for each response's headers.
+	Timeout time.Duration
+	// Control, if set, vets each connection before it is opened.
+	Control remotefs.DialControl
+}
+
+// Conn is an FS over HTTP. Unlike the other backends it is safe for
+// concurrent use.
+type Conn struct {
+	config Config
+	base   *url.URL
+	client *http.Client
+}
+
+// Dial checks that the share exists and the credentials are accepted.
+// Errors wrap remotefs.ErrAuthFailed or remotefs.ErrConnectFailed.
+func Dial(ctx context.Context, config Config) (*Conn, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	base, err := url.Parse(config.URL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("%w: invalid URL %q", remotefs.ErrConnectFailed, config.URL)
+	}
+	base.Path = "/" + strings.Trim(base.Path, "/")
+	base.RawPath, base.RawQuery, base.Fragment = "", "", ""
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: config.Timeout, ControlContext: config.Control}).DialContext
+	if config.Control != nil {
+		// A proxy would connect on our behalf, out of Control's reach.
+		transport.Proxy = nil
+	}
+	transport.TLSClientConfig = config.TLS
+	transport.ResponseHeaderTimeout = config.Timeout
+	c := &Conn{
+		config: config,
+		base:   base,
+		client: &http.Client{
+			Transport: transport,
+			// MOVE and PROPFIND must not be replayed as GETs.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+
+	resp, err := c.do(ctx, "PROPFIND", base.Path, map[string]string{"Depth": "0"}, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w: %s", remotefs.ErrAuthFailed, resp.Status)
+	case resp.StatusCode != http.StatusMultiStatus:
+		return nil, fmt.Errorf("%w: PROPFIND %s: %s", remotefs.ErrConnectFailed, base.Path, resp.Status)
+	}
+	return c, nil
+}
+
+func (c *Conn) urlFor(name string) string {
+	u := *c.base
+	u.Path = name
+	return u.String()
+}
+
+func (c *Conn) do(ctx context.Context, method, name string, header map[string]string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.urlFor(name), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return c.client.Do(req)
+}
+
+// request runs a request that has no response body worth reading and
+// returns an error unless the status is one of ok.
+func (c *Conn) request(method, name string, header map[string]string, ok ...int) error {
+	resp, err := c.do(context.Background(), method, name, header, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+	return statusError(resp)
+}
+
+// statusError maps a failed response to a remotefs.ServerError.
+func statusError(resp *http.Response) error {
+	var err error
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusConflict:
+		// 409 is a missing parent collection.
+		err = fs.ErrNotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		err = fs.ErrPermission
+	case http.StatusMethodNotAllowed, http.StatusPreconditionFailed:
+		// 405 is MKCOL on an existing resource, 412 a MOVE that would
+		// overwrite one.
+		err = fs.ErrExist
+	case http.StatusNotImplemented:
+		err = remotefs.ErrUnsupported
+	}
+	return &remotefs.ServerError{Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode), Err: err}
+}
+
+func (c *Conn) Capabilities() remotefs.Capabilities {
+	return remotefs.Capabilities{RenameDirectories: true}
+}
+
+// Alive is always true; each request opens a connection if it needs one.
+func (c *Conn) Alive() bool {
+	return true
+}
+
+func (c *Conn) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func (c *Conn) RealPath(name string) (string, error) {
+	if path.IsAbs(name) {
+		return path.Clean(name), nil
+	}
+	return path.Join(c.base.Path, name), nil
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop>
+<D:resourcetype/><D:getcontentlength/><D:getlastmodified/>
+</D:prop></D:propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+			} `xml:"DAV: prop"`
+			Status string `xml:"DAV: status"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// propfind returns the entries of a PROPFIND at depth 0 or 1, keyed by
+// their clean path.
+func (c *Conn) propfind(name, depth string) (map[string]*remotefs.FileInfo, error) {
+	resolved, _ := c.RealPath(name)
+	resp, err := c.do(context.Background(), "PROPFIND", resolved, map[string]string{
+		"Depth":        depth,
+		"Content-Type": "application/xml; charset=utf-8",
+	}, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError(resp)
+	}
+
+	var status multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, &remotefs.ServerError{Code: resp.StatusCode, Message: "invalid PROPFIND response: " + err.Error()}
+	}
+	entries := make(map[string]*remotefs.FileInfo, len(status.Responses))
+	for _, response := range status.Responses {
+		href, err := url.Parse(response.Href)
+		if err != nil {
+			continue
+		}
+		entryPath := path.Clean("/" + href.Path)
+		info := &remotefs.FileInfo{FileName: path.Base(entryPath), FileMode: 0o644}
+		for _, propstat := range response.Propstat {
+			if !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			prop := propstat.Prop
+			if prop.ResourceType.Collection != nil {
+				info.FileMode = fs.ModeDir | 0o755
+			}
+			if size, err := strconv.ParseInt(prop.ContentLength, 10, 64); err == nil {
+				info.FileSize = size
+			}
+			if modified, err := http.ParseTime(prop.LastModified); err == nil {
+				info.Modified = modified
+			}
+		}
+		entries[entryPath] = info
+	}
+	return entries, nil
+}
+
+func (c *Conn) Stat(name string) (fs.FileInfo, error) {
+	resolved, _ := c.RealPath(name)
+	entries, err := c.propfind(resolved, "0")
+	if err != nil {
+		return nil, err
+	}
+	info, ok := entries[resolved]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	if resolved == "/" {
+		info.FileName = "/"
+	}
+	return info, nil
+}
+
+// Lstat is Stat; WebDAV has no links.
+func (c *Conn) Lstat(name string) (fs.FileInfo, error) {
+	return c.Stat(name)
+}
+
+func (c *Conn) ReadDir(name string) ([]fs.FileInfo, error) {
+	resolved, _ := c.RealPath(name)
+	entries, err := c.propfind(resolved, "1")
+	if err != nil {
+		return nil, err
+	}
+	self, ok := entries[resolved]
+	if !ok || !self.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	infos := make([]fs.FileInfo, 0, len(entries)-1)
+	for entryPath, info := range entries {
+		if entryPath != resolved {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// Open reads with a Range request, skipping the start of the body itself
+// if the server ignores the range.
+func (c *Conn) Open(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	resolved, _ := c.RealPath(name)
+	header := map[string]string{}
+	if offset > 0 {
+		header["Range"] = "bytes=" + strconv.FormatInt(offset, 10) + "-"
+	}
+	resp, err := c.do(ctx, http.MethodGet, resolved, header, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		if offset > 0 {
+			if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The offset is the file's size.
+		resp.Body.Close()
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	default:
+		resp.Body.Close()
+		return nil, statusError(resp)
+	}
+	return resp.Body, nil
+}
+
+// Create streams a PUT. Only whole files can be written.
+func (c *Conn) Create(ctx context.Context, name string, offset int64) (io.WriteCloser, error) {
+	if offset > 0 {
+		return nil, remotefs.ErrUnsupported
+	}
+	resolved, _ := c.RealPath(name)
+
+	pr, pw := io.Pipe()
+	w := &putWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		resp, err := c.do(ctx, http.MethodPut, resolved, nil, pr)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				err = statusError(resp)
+			}
+		}
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+// putWriter is the body of a PUT in progress. Close waits for the response.
+type putWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *putWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *putWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+func (c *Conn) Mkdir(name string) error {
+	resolved, _ := c.RealPath(name)
+	return c.request("MKCOL", resolved, nil, http.StatusCreated)
+}
+
+func (c *Conn) MkdirAll(name string) error {
+	return remotefs.MkdirAll(c, name)
+}
+
+func (c *Conn) Rename(from, to string, replace bool) error {
+	resolvedFrom, _ := c.RealPath(from)
+	resolvedTo, _ := c.RealPath(to)
+	overwrite := "F"
+	if replace {
+		overwrite = "T"
+	}
+	return c.request("MOVE", resolvedFrom, map[string]string{
+		"Destination": c.urlFor(resolvedTo),
+		"Overwrite":   overwrite,
+	}, http.StatusCreated, http.StatusNoContent)
+}
+
+func (c *Conn) Remove(name string) error {
+	info, err := c.Stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return &fs.PathError{Op: "remove", Path: name, Err: errors.New("is a directory")}
+	}
+	return c.RemoveAll(name)
+}
+
+// RemoveDirectory checks the collection is empty first, since DELETE
+// removes a collection with everything in it.
+func (c *Conn) RemoveDirectory(name string) error {
+	infos, err := c.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	if len(infos) > 0 {
+		return &fs.PathError{Op: "rmdir", Path: name, Err: errors.New("directory not empty")}
+	}
+	return c.RemoveAll(name)
+}
+
+func (c *Conn) RemoveAll(name string) error {
+	resolved, _ := c.RealPath(name)
+	return c.request(http.MethodDelete, resolved, nil, http.StatusOK, http.StatusNoContent)
+}
+
+func (c *Conn) Chmod(string, fs.FileMode) error {
+	return remotefs.ErrUnsupported
+}
+
+func (c *Conn) Chtimes(string, time.Time) error {
+	return remotefs.ErrUnsupported
+}
+
+func (c *Conn) Symlink(string, string) error {
+	return remotefs.ErrUnsupported
+}
@@ -0,0 +1,173 @@
+package webdavfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	"livecode-api/internal/remotefs"
+)
+
+func newTestServer(t *testing.T) (string, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.Dir(root),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "tester" || password != "correct horse" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/dav", root
+}
+
+func dialTest(t *testing.T, url string) *Conn {
+	t.Helper()
+
+	conn, err := Dial(context.Background(), Config{URL: url, Username: "tester", Password: "correct horse", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected to connect, got: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func writeFile(t *testing.T, fsys remotefs.FS, name, data string) {
+	t.Helper()
+
+	w, err := fsys.Create(context.Background(), name, 0)
+	if err != nil {
+		t.Fatalf("Expected to create %s, got: %v", name, err)
+	}
+	io.WriteString(w, data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected the upload of %s to finish, got: %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, fsys remotefs.FS, name string, offset int64) string {
+	t.Helper()
+
+	r, err := fsys.Open(context.Background(), name, offset)
+	if err != nil {
+		t.Fatalf("Expected to open %s, got: %v", name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestConn_FileOperations(t *testing.T) {
+	url, root := newTestServer(t)
+	conn := dialTest(t, url)
+
+	if err := conn.MkdirAll("site/assets"); err != nil {
+		t.Fatalf("Expected mkdir -p to succeed, got: %v", err)
+	}
+	writeFile(t, conn, "site/my page.html", "hello world")
+	if data, _ := os.ReadFile(filepath.Join(root, "site", "my page.html")); string(data) != "hello world" {
+		t.Errorf("Expected the file under the share's path, got %q", data)
+	}
+	if got := readFile(t, conn, "/dav/site/my page.html", 6); got != "world" {
+		t.Errorf("Expected to read from the offset, got %q", got)
+	}
+	if got := readFile(t, conn, "site/my page.html", 11); got != "" {
+		t.Errorf("Expected nothing at the end of the file, got %q", got)
+	}
+
+	infos, err := conn.ReadDir("site")
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("Expected two entries, got %d, %v", len(infos), err)
+	}
+	for _, info := range infos {
+		switch info.Name() {
+		case "assets":
+			if !info.IsDir() {
+				t.Error("Expected assets to be a directory")
+			}
+		case "my page.html":
+			if info.Size() != 11 || info.ModTime().IsZero() {
+				t.Errorf("Expected the file's size and time, got %d, %v", info.Size(), info.ModTime())
+			}
+		default:
+			t.Errorf("Unexpected entry %q", info.Name())
+		}
+	}
+
+	writeFile(t, conn, "site/old.html", "old")
+	if err := conn.Rename("site/old.html", "site/my page.html", false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected fs.ErrExist without replace, got: %v", err)
+	}
+	if err := conn.Rename("site/old.html", "site/my page.html", true); err != nil {
+		t.Fatalf("Expected rename to replace, got: %v", err)
+	}
+	if got := readFile(t, conn, "site/my page.html", 0); got != "old" {
+		t.Errorf("Expected the renamed file, got %q", got)
+	}
+
+	if err := conn.RemoveDirectory("site"); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	if _, err := conn.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got: %v", err)
+	}
+	if err := conn.RemoveAll("site"); err != nil {
+		t.Fatalf("Expected recursive delete to succeed, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "site")); !os.IsNotExist(err) {
+		t.Errorf("Expected the tree to be gone, got: %v", err)
+	}
+}
+
+func TestConn_UnsupportedOperations(t *testing.T) {
+	url, _ := newTestServer(t)
+	conn := dialTest(t, url)
+	writeFile(t, conn, "a.txt", "a")
+
+	if _, err := conn.Create(context.Background(), "a.txt", 1); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected resuming an upload to be unsupported, got: %v", err)
+	}
+	if err := conn.Chmod("a.txt", 0o600); !errors.Is(err, remotefs.ErrUnsupported) {
+		t.Errorf("Expected chmod to be unsupported, got: %v", err)
+	}
+	if conn.Capabilities().Chmod || conn.Capabilities().ResumeUpload {
+		t.Errorf("Expected capabilities to match, got %+v", conn.Capabilities())
+	}
+}
+
+func TestDial_Failures(t *testing.T) {
+	url, _ := newTestServer(t)
+
+	_, err := Dial(context.Background(), Config{URL: url, Username: "tester", Password: "wrong"})
+	if !errors.Is(err, remotefs.ErrAuthFailed) {
+		t.Errorf("Expected remotefs.ErrAuthFailed, got: %v", err)
+	}
+	_, err = Dial(context.Background(), Config{URL: "ftp://example.com"})
+	if !errors.Is(err, remotefs.ErrConnectFailed) {
+		t.Errorf("Expected remotefs.ErrConnectFailed, got: %v", err)
+	}
+	policy := &remotefs.AddressPolicy{}
+	_, err = Dial(context.Background(), Config{URL: url, Username: "tester", Password: "secret", Control: policy.Control})
+	if !errors.Is(err, remotefs.ErrAddressBlocked) {
+		t.Errorf("Expected remotefs.ErrAddressBlocked for a loopback server, got: %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package remotesearch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"livecode-api/internal/remotefs"
+)
+
+// maxRecord bounds a path read from find or grep.
+const maxRecord = 64 << 10
+
+// exec lists the tree with find and greps the files it lists in batches,
+// so the byte budget is checked before any file is read. It returns
+// errExecFailed if the commands are unavailable, such as on an SFTP-only
+// server or one without GNU find, and nothing was found yet.
+func (s *searcher) exec(execer remotefs.Execer) error {
+	find, err := execer.Stream(s.ctx, s.findCommand())
+	if err != nil {
+		return s.execFailed(err)
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			find.Close()
+		}
+	}()
+
+	r := bufio.NewReader(find)
+	listed := false
+	var batch []Match
+	batchArgs := 0
+
+	for {
+		record, err := readLine(r, 0, maxRecord)
+		if err == io.EOF && len(record) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			if s.ctx.Err() != nil {
+				return s.ctx.Err()
+			}
+			break
+		}
+
+		m, ok := parseFindRecord(string(record))
+		if !ok {
+			continue
+		}
+		listed = true
+		if s.content == nil {
+			if err := s.match(m); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ok, err = s.scan(m.Info)
+		if err != nil {
+			if grepErr := s.grep(execer, batch); grepErr != nil {
+				return grepErr
+			}
+			return err
+		}
+		if !ok {
+			continue
+		}
+		batch = append(batch, m)
+		batchArgs += len(quote(path.Join(m.Dir, m.Info.Name()))) + 1
+		if batchArgs >= maxBatchArgs {
+			if err := s.grep(execer, batch); err != nil {
+				return err
+			}
+			batch, batchArgs = nil, 0
+		}
+	}
+	if err := s.grep(execer, batch); err != nil {
+		return err
+	}
+
+	closed = true
+	// find also fails for directories it cannot read, after listing the
+	// others.
+	if err := find.Close(); err != nil && !listed {
+		return s.execFailed(err)
+	}
+	return nil
+}
+
+// grep searches the files in batch, which were already counted by scan.
+func (s *searcher) grep(execer remotefs.Execer, batch []Match) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	files := make(map[string]Match, len(batch))
+	names := make([]string, 0, len(batch))
+	for _, m := range batch {
+		name := path.Join(m.Dir, m.Info.Name())
+		files[name] = m
+		names = append(names, name)
+	}
+
+	output, err := execer.Stream(s.ctx, s.grepCommand(names))
+	if err != nil {
+		return s.execFailed(err)
+	}
+
+	// -Z ends the file name with a NUL instead of a colon, so names with
+	// colons in them cannot be mistaken for the line number.
+	r := bufio.NewReader(output)
+	for {
+		name, err := readLine(r, 0, maxRecord)
+		if err != nil {
+			break
+		}
+		line, err := readLine(r, '\n', maxLineLength+24)
+		number, text, found := strings.Cut(string(line), ":")
+		n, convErr := strconv.Atoi(number)
+		if m, ok := files[string(name)]; ok && found && convErr == nil {
+			m.Line, m.Text = n, lineText([]byte(text))
+			if err := s.match(m); err != nil {
+				output.Close()
+				return err
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if err := output.Close(); err != nil {
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+		if !s.grepWorks && s.summary.Results == 0 {
+			return s.execFailed(err)
+		}
+	}
+	s.grepWorks = true
+	return nil
+}
+
+// execFailed is what a failed command means for the search: its end if
+// the search was canceled, otherwise a reason to walk the tree instead.
+func (s *searcher) execFailed(err error) error {
+	if s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+	return fmt.Errorf("%w: %w", errExecFailed, err)
+}
+
+func (s *searcher) findCommand() string {
+	command := "find " + quote(s.query.Root) + " -mindepth 1 -maxdepth " + strconv.Itoa(s.query.MaxDepth)
+	if s.content != nil {
+		command += " -type f"
+	}
+	if s.query.Name != "" {
+		test := " -name "
+		if s.query.IgnoreCase {
+			test = " -iname "
+		}
+		command += test + quote(s.query.Name)
+	}
+	return command + ` -printf '%y %m %s %T@ %p\0'`
+}
+
+// grepCommand exits with 0 when nothing matched, so a failure means grep
+// did not run properly. -s and -I skip unreadable and binary files.
+func (s *searcher) grepCommand(names []string) string {
+	command := "grep -nHIsZ"
+	if s.query.IgnoreCase {
+		command += "i"
+	}
+	if s.query.Regexp {
+		command += "E"
+	} else {
+		command += "F"
+	}
+	command += " -e " + quote(s.query.Content) + " --"
+	for _, name := range names {
+		command += " " + quote(name)
+	}
+	return command + " || [ $? -eq 1 ]"
+}
+
+// parseFindRecord reads a "type mode size mtime path" record of
+// findCommand.
+func parseFindRecord(record string) (Match, bool) {
+	fields := strings.SplitN(record, " ", 5)
+	if len(fields) != 5 || fields[0] == "" {
+		return Match{}, false
+	}
+	perm, err := strconv.ParseUint(fields[1], 8, 32)
+	if err != nil {
+		return Match{}, false
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Match{}, false
+	}
+	modified, ok := parseFindTime(fields[3])
+	if !ok {
+		return Match{}, false
+	}
+
+	mode := fs.FileMode(perm) & fs.ModePerm
+	switch fields[0] {
+	case "f":
+	case "d":
+		mode |= fs.ModeDir
+	case "l":
+		mode |= fs.ModeSymlink
+	case "p":
+		mode |= fs.ModeNamedPipe
+	case "s":
+		mode |= fs.ModeSocket
+	case "c":
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case "b":
+		mode |= fs.ModeDevice
+	default:
+		mode |= fs.ModeIrregular
+	}
+
+	name := fields[4]
+	return Match{
+		Dir:  path.Dir(name),
+		Info: &remotefs.FileInfo{FileName: path.Base(name), FileSize: size, FileMode: mode, Modified: modified},
+	}, true
+}
+
+// parseFindTime reads find's %T@, seconds since the epoch with a fraction.
+func parseFindTime(value string) (time.Time, bool) {
+	seconds, fraction, _ := strings.Cut(value, ".")
+	sec, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var nsec int64
+	if fraction != "" {
+		fraction = (fraction + "000000000")[:9]
+		if nsec, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+			return time.Time{}, false
+		}
+	}
+	return time.Unix(sec, nsec), true
+}
+
+// quote makes s a single POSIX shell word.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
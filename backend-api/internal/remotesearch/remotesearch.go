@@ -0,0 +1,318 @@
+// Package remotesearch finds files on a remote server by name and by
+// content. Servers that can run commands search with find and grep; on the
+// others, and where those tools are missing, the tree is listed and read
+// over the file protocol.
+package remotesearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"livecode-api/internal/remotefs"
+)
+
+const (
+	MethodExec = "exec"
+	MethodWalk = "walk"
+
+	LimitResults = "max_results"
+	LimitBytes   = "max_bytes"
+	LimitTime    = "timeout"
+)
+
+const (
+	// maxLineLength is how much of a matching line is reported.
+	maxLineLength = 1000
+	// maxScanLine is how much of a line is searched; the rest is skipped.
+	maxScanLine = 1 << 20
+	// binaryPeek is how much of a file is checked for a NUL byte, which
+	// marks it as binary, as grep -I does.
+	binaryPeek = 8000
+	// maxBatchArgs bounds the file names passed to one grep, well below the
+	// 128 KiB Linux allows for the command.
+	maxBatchArgs = 64 << 10
+)
+
+// Query describes a search. Name and Content may not both be empty.
+type Query struct {
+	// Root is the absolute path of the directory searched.
+	Root string
+	// Name is a glob matched against base names. Empty matches any name.
+	Name string
+	// Content is searched for in regular files. Empty searches names only.
+	Content string
+	// Regexp treats Content as a regular expression instead of a literal.
+	Regexp     bool
+	IgnoreCase bool
+	// MaxDepth limits how far below Root the search goes; 1 searches
+	// Root's entries only.
+	MaxDepth   int
+	MaxResults int
+	// MaxBytes caps the file data content searches scan in total, and
+	// MaxFileSize the size of a file they scan at all.
+	MaxBytes    int64
+	MaxFileSize int64
+}
+
+// Match is a file whose name matched or, in a content search, a line of a
+// file that contains the content.
+type Match struct {
+	Dir  string
+	Info fs.FileInfo
+	// Line is the number of the matching line, from 1, or 0 for a name
+	// match.
+	Line int
+	Text string
+}
+
+type Summary struct {
+	Results      int
+	FilesScanned int
+	BytesScanned int64
+	Method       string
+	// Limit names the limit that ended the search early, or is empty.
+	Limit string
+}
+
+// ValidName reports whether name is a glob Query.Name accepts.
+func ValidName(name string) bool {
+	if strings.Contains(name, "/") {
+		return false
+	}
+	_, err := path.Match(name, "")
+	return err == nil
+}
+
+// ValidContent reports whether content is a valid pattern for Query.Content.
+func ValidContent(content string, isRegexp bool) bool {
+	if !isRegexp {
+		return true
+	}
+	_, err := regexp.Compile(content)
+	return err == nil
+}
+
+type limitError struct {
+	limit string
+}
+
+func (e *limitError) Error() string {
+	return "search limit reached: " + e.limit
+}
+
+var errExecFailed = errors.New("remote search command failed")
+
+type searcher struct {
+	ctx     context.Context
+	query   Query
+	emit    func(Match) error
+	name    string
+	content *regexp.Regexp
+	summary Summary
+	// grepWorks is set once a grep has succeeded, after which failures are
+	// put down to unreadable files rather than a missing or unusual grep.
+	grepWorks bool
+}
+
+// Search runs query on fsys, passing each match to emit. Reaching one of
+// the query's limits, or the context's deadline, ends the search early
+// with Summary.Limit set. Cancellation and errors from emit end it with
+// that error.
+func Search(ctx context.Context, fsys remotefs.FS, query Query, emit func(Match) error) (Summary, error) {
+	s := &searcher{ctx: ctx, query: query, emit: emit, name: query.Name}
+	if query.IgnoreCase {
+		s.name = strings.ToLower(s.name)
+	}
+	if query.Content != "" {
+		pattern := query.Content
+		if !query.Regexp {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if query.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		content, err := regexp.Compile(pattern)
+		if err != nil {
+			return Summary{}, err
+		}
+		s.content = content
+	}
+
+	var err error
+	if execer, ok := remotefs.AsExecer(fsys); ok {
+		s.summary.Method = MethodExec
+		err = s.exec(execer)
+	}
+	if s.summary.Method == "" || errors.Is(err, errExecFailed) {
+		s.summary = Summary{Method: MethodWalk}
+		err = s.walk(fsys)
+	}
+
+	var limitErr *limitError
+	switch {
+	case errors.As(err, &limitErr):
+		s.summary.Limit = limitErr.limit
+		err = nil
+	case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		s.summary.Limit = LimitTime
+		err = nil
+	case err != nil && ctx.Err() != nil:
+		err = context.Cause(ctx)
+	}
+	return s.summary, err
+}
+
+func (s *searcher) match(m Match) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if s.summary.Results >= s.query.MaxResults {
+		return &limitError{LimitResults}
+	}
+	if err := s.emit(m); err != nil {
+		return err
+	}
+	s.summary.Results++
+	return nil
+}
+
+func (s *searcher) nameMatches(name string) bool {
+	if s.name == "" {
+		return true
+	}
+	if s.query.IgnoreCase {
+		name = strings.ToLower(name)
+	}
+	ok, _ := path.Match(s.name, name)
+	return ok
+}
+
+// scan reserves info's size from the byte budget, reporting false for
+// files too large to scan at all.
+func (s *searcher) scan(info fs.FileInfo) (bool, error) {
+	if !info.Mode().IsRegular() || info.Size() > s.query.MaxFileSize {
+		return false, nil
+	}
+	if s.summary.BytesScanned+info.Size() > s.query.MaxBytes {
+		return false, &limitError{LimitBytes}
+	}
+	s.summary.BytesScanned += info.Size()
+	s.summary.FilesScanned++
+	return true, nil
+}
+
+// walk lists the tree breadth first, so shallow matches come first.
+// Directories and files that cannot be read are skipped, as find and grep
+// skip them.
+func (s *searcher) walk(fsys remotefs.FS) error {
+	type dir struct {
+		path  string
+		depth int
+	}
+	queue := []dir{{s.query.Root, 1}}
+
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+
+		infos, err := fsys.ReadDir(d.path)
+		if err != nil {
+			if d.path == s.query.Root || s.ctx.Err() != nil {
+				return err
+			}
+			continue
+		}
+		slices.SortFunc(infos, func(a, b fs.FileInfo) int { return strings.Compare(a.Name(), b.Name()) })
+
+		for _, info := range infos {
+			if info.IsDir() && d.depth < s.query.MaxDepth {
+				queue = append(queue, dir{path.Join(d.path, info.Name()), d.depth + 1})
+			}
+			if !s.nameMatches(info.Name()) {
+				continue
+			}
+			if s.content == nil {
+				if err := s.match(Match{Dir: d.path, Info: info}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			ok, err := s.scan(info)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if err := s.grepFile(fsys, d.path, info); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *searcher) grepFile(fsys remotefs.FS, dir string, info fs.FileInfo) error {
+	file, err := fsys.Open(s.ctx, path.Join(dir, info.Name()), 0)
+	if err != nil {
+		return s.ctx.Err()
+	}
+	defer file.Close()
+
+	r := bufio.NewReaderSize(io.LimitReader(file, info.Size()), 64<<10)
+	if head, _ := r.Peek(binaryPeek); bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	for number := 1; ; number++ {
+		line, err := readLine(r, '\n', maxScanLine)
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if s.content.Match(line) {
+			if err := s.match(Match{Dir: dir, Info: info, Line: number, Text: lineText(line)}); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			// A file that fails part way through is skipped like one that
+			// cannot be opened.
+			return s.ctx.Err()
+		}
+	}
+}
+
+// readLine reads up to and without delim, keeping at most max bytes of
+// it. It returns io.EOF with the last line, which may be empty.
+func readLine(r *bufio.Reader, delim byte, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice(delim)
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
+		}
+		if room := max - len(line); room > 0 {
+			line = append(line, chunk[:min(len(chunk), room)]...)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// lineText is the part of a matching line that is reported.
+func lineText(line []byte) string {
+	line = bytes.TrimRight(line[:min(len(line), maxLineLength)], "\r")
+	return strings.ToValidUTF8(string(line), "\uFFFD")
+}
@@ -0,0 +1,205 @@
+package remotesearch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"livecode-api/internal/sftpgw"
+	"livecode-api/internal/sftpgw/sftptest"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestTree serves a small tree over SFTP. With shell set, exec requests
+// run in a real shell, so find and grep see the same files.
+func newTestTree(t *testing.T, shell bool) (*sftpgw.Session, string) {
+	t.Helper()
+
+	server := sftptest.NewServer(t)
+	if shell {
+		server.Exec = func(command string) (string, uint32) {
+			output, err := exec.Command("sh", "-c", command).Output()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return string(output), uint32(exitErr.ExitCode())
+			}
+			return string(output), 0
+		}
+	}
+
+	files := map[string]string{
+		"etc/nginx/nginx.conf":         "user www;\nworker_processes auto;\n",
+		"etc/nginx/sites/default.conf": "server {\n  listen 80;\n  server_name example.com;\n}\n",
+		"etc/app.CONF":                 "listen: 8080\n",
+		"var/www/index.html":           "<h1>Listen here</h1>\n",
+		"var/www/logo.png":             "\x89PNG\x00\x00listen",
+		"deep/a/b/c/d/notes.conf":      "listen 443;\n",
+	}
+	for name, content := range files {
+		full := filepath.Join(server.Root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(full), 0o755)
+		os.WriteFile(full, []byte(content), 0o644)
+	}
+
+	session, err := sftpgw.Dial(context.Background(), sftpgw.Target{
+		Address:         server.Addr,
+		Username:        server.User,
+		Auth:            []ssh.AuthMethod{ssh.Password(server.Password)},
+		HostKeyCallback: ssh.FixedHostKey(server.HostKey),
+	}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to open SFTP session: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session, server.Root
+}
+
+func defaultQuery(root string) Query {
+	return Query{Root: root, MaxDepth: 10, MaxResults: 100, MaxBytes: 1 << 20, MaxFileSize: 1 << 20}
+}
+
+// collect runs the search and returns its matches as sorted
+// "path" or "path:line:text" strings, relative to the root.
+func collect(t *testing.T, session *sftpgw.Session, query Query) ([]string, Summary) {
+	t.Helper()
+
+	var matches []string
+	summary, err := Search(context.Background(), session, query, func(m Match) error {
+		name := strings.TrimPrefix(path.Join(m.Dir, m.Info.Name()), query.Root+"/")
+		if m.Line > 0 {
+			name += ":" + strconv.Itoa(m.Line) + ":" + m.Text
+		}
+		matches = append(matches, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the search to succeed, got: %v", err)
+	}
+	slices.Sort(matches)
+	return matches, summary
+}
+
+func TestSearch_NamesAndContent(t *testing.T) {
+	for _, method := range []string{MethodExec, MethodWalk} {
+		t.Run(method, func(t *testing.T) {
+			session, root := newTestTree(t, method == MethodExec)
+
+			query := defaultQuery(root)
+			query.Name = "*.conf"
+			query.IgnoreCase = true
+			matches, summary := collect(t, session, query)
+			want := []string{"deep/a/b/c/d/notes.conf", "etc/app.CONF", "etc/nginx/nginx.conf", "etc/nginx/sites/default.conf"}
+			if !slices.Equal(matches, want) || summary.Method != method {
+				t.Errorf("Expected %v by %s, got %v by %s", want, method, matches, summary.Method)
+			}
+
+			query = defaultQuery(root)
+			query.Content = "listen"
+			query.MaxDepth = 3
+			matches, summary = collect(t, session, query)
+			want = []string{"etc/app.CONF:1:listen: 8080"}
+			if !slices.Equal(matches, want) {
+				t.Errorf("Expected %v, got %v", want, matches)
+			}
+			if summary.FilesScanned != 4 || summary.Limit != "" {
+				t.Errorf("Expected the 4 files within the depth scanned, got %+v", summary)
+			}
+
+			query.Content = `listen\s+[0-9]+;`
+			query.Regexp = true
+			query.IgnoreCase = false
+			query.Name = "*.conf"
+			query.MaxDepth = 10
+			if matches, _ = collect(t, session, query); len(matches) != 2 {
+				t.Errorf("Expected two regexp matches, got %v", matches)
+			}
+		})
+	}
+}
+
+func TestSearch_Limits(t *testing.T) {
+	for _, method := range []string{MethodExec, MethodWalk} {
+		t.Run(method, func(t *testing.T) {
+			session, root := newTestTree(t, method == MethodExec)
+
+			query := defaultQuery(root)
+			query.Name = "*"
+			query.MaxResults = 3
+			matches, summary := collect(t, session, query)
+			if len(matches) != 3 || summary.Limit != LimitResults {
+				t.Errorf("Expected 3 results and the result limit, got %v, %+v", matches, summary)
+			}
+
+			query = defaultQuery(root)
+			query.Content = "listen"
+			query.Name = "*.conf"
+			query.MaxBytes = 30
+			_, summary = collect(t, session, query)
+			if summary.Limit != LimitBytes || summary.BytesScanned > 30 {
+				t.Errorf("Expected the byte limit, got %+v", summary)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+			defer cancel()
+			time.Sleep(time.Millisecond)
+			query = defaultQuery(root)
+			query.Name = "*"
+			summary, err := Search(ctx, session, query, func(Match) error { return nil })
+			if err != nil || summary.Limit != LimitTime {
+				t.Errorf("Expected the time limit, got %+v, %v", summary, err)
+			}
+
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+			if _, err := Search(ctx, session, query, func(Match) error { return nil }); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSearch_FallsBackWithoutTools(t *testing.T) {
+	server := sftptest.NewServer(t)
+	server.Exec = func(string) (string, uint32) { return "sh: find: not found\n", 127 }
+	os.WriteFile(filepath.Join(server.Root, "todo.txt"), []byte("fix the search\n"), 0o644)
+
+	session, err := sftpgw.Dial(context.Background(), sftpgw.Target{
+		Address:         server.Addr,
+		Username:        server.User,
+		Auth:            []ssh.AuthMethod{ssh.Password(server.Password)},
+		HostKeyCallback: ssh.FixedHostKey(server.HostKey),
+	}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to open SFTP session: %v", err)
+	}
+	defer session.Close()
+
+	query := defaultQuery(server.Root)
+	query.Content = "search"
+	matches, summary := collect(t, session, query)
+	if len(matches) != 1 || summary.Method != MethodWalk {
+		t.Errorf("Expected one match from walking, got %v by %s", matches, summary.Method)
+	}
+}
+
+func TestParseFindRecord(t *testing.T) {
+	m, ok := parseFindRecord("d 755 4096 1700000000.5000000000 /srv/my dir")
+	if !ok || m.Dir != "/srv" || m.Info.Name() != "my dir" || !m.Info.IsDir() || m.Info.Mode().Perm() != 0o755 {
+		t.Errorf("Expected a directory record, got %+v", m)
+	}
+	if !m.Info.ModTime().Equal(time.Unix(1700000000, 500000000)) {
+		t.Errorf("Expected the fractional time, got %v", m.Info.ModTime())
+	}
+	if _, ok := parseFindRecord("f 644 x 1 /a"); ok {
+		t.Error("Expected a record with a bad size to be rejected")
+	}
+}
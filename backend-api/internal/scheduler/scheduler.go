@@ -0,0 +1,159 @@
+// Package scheduler works out when cron-style recurring jobs are due and
+// polls for them on a loop. The jobs themselves live in a store, so any
+// server can run them.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// MissedSkip drops runs that were not started in time.
+	MissedSkip = "skip"
+	// MissedCatchUp starts one run for any that were missed.
+	MissedCatchUp = "catch_up"
+)
+
+var MissedPolicies = []string{MissedSkip, MissedCatchUp}
+
+// maxMissed bounds how many missed runs Due reports, for a schedule that
+// was not looked at for a long time.
+const maxMissed = 100
+
+var (
+	ErrInvalidExpression = errors.New("invalid cron expression")
+	ErrInvalidTimezone   = errors.New("invalid time zone")
+)
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule is a parsed cron expression in a time zone.
+type Schedule struct {
+	spec     cron.Schedule
+	location *time.Location
+}
+
+// Parse reads a five-field cron expression, or a descriptor such as
+// @daily, evaluated in timezone.
+func Parse(expression, timezone string) (*Schedule, error) {
+	// The time zone is its own setting, not part of the expression.
+	if strings.HasPrefix(expression, "TZ=") || strings.HasPrefix(expression, "CRON_TZ=") || strings.HasPrefix(expression, "@every") {
+		return nil, ErrInvalidExpression
+	}
+	spec, err := parser.Parse(expression)
+	if err != nil {
+		return nil, ErrInvalidExpression
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" || strings.EqualFold(timezone, "local") {
+		return nil, ErrInvalidTimezone
+	}
+	return &Schedule{spec: spec, location: location}, nil
+}
+
+// Next returns the first run after t, or the zero time if there is none.
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := s.spec.Next(t.In(s.location))
+	if next.IsZero() {
+		return next
+	}
+	return next.UTC()
+}
+
+// Decision is what to do about a schedule whose next run is due.
+type Decision struct {
+	// Run is the run to start, or zero if there is none.
+	Run time.Time
+	// Missed are runs that will not be started.
+	Missed []time.Time
+	// Next is when the schedule is due again, or zero if never.
+	Next time.Time
+}
+
+// Due decides what to do at now about a schedule whose next run was due at
+// next. A run is missed if now is more than grace after it. Of several due
+// runs only the latest is started; it is missed too if it is late and
+// policy is MissedSkip.
+func Due(s *Schedule, next, now time.Time, grace time.Duration, policy string) Decision {
+	due := []time.Time{next}
+	for t := s.Next(next); !t.IsZero() && !t.After(now); t = s.Next(t) {
+		due = append(due, t)
+		if len(due) > maxMissed+1 {
+			due = slices.Delete(due, 0, 1)
+		}
+	}
+
+	latest := due[len(due)-1]
+	decision := Decision{Missed: due[:len(due)-1], Next: s.Next(now)}
+	if now.Sub(latest) <= grace || policy == MissedCatchUp {
+		decision.Run = latest
+	} else {
+		decision.Missed = due
+	}
+	return decision
+}
+
+// Runner starts the runs that are due at now. It should return promptly
+// once ctx is done.
+type Runner func(ctx context.Context, now time.Time)
+
+// Scheduler calls a Runner every interval.
+type Scheduler struct {
+	run      Runner
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func New(run Runner, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		run:      run,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// Close stops the loop, interrupting runs that are being started, and
+// waits for it to finish.
+func (s *Scheduler) Close(ctx context.Context) error {
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(s.ctx, time.Now())
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
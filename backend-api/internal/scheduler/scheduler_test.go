@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, expression := range []string{"0 2 * * *", "*/15 9-17 * * mon-fri", "@daily", "0 0 1 jan,jul *"} {
+		if _, err := Parse(expression, "Europe/Berlin"); err != nil {
+			t.Errorf("Parse(%q): expected success, got %v", expression, err)
+		}
+	}
+	for _, expression := range []string{"", "* * * *", "0 0 0 * * *", "61 * * * *", "@every 1m", "CRON_TZ=UTC 0 2 * * *"} {
+		if _, err := Parse(expression, "UTC"); err != ErrInvalidExpression {
+			t.Errorf("Parse(%q): expected ErrInvalidExpression, got %v", expression, err)
+		}
+	}
+	for _, timezone := range []string{"", "Local", "Mars/Olympus"} {
+		if _, err := Parse("@daily", timezone); err != ErrInvalidTimezone {
+			t.Errorf("Parse with %q: expected ErrInvalidTimezone, got %v", timezone, err)
+		}
+	}
+}
+
+func TestSchedule_NextUsesTimezone(t *testing.T) {
+	schedule, err := Parse("0 2 * * *", "Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+	got := schedule.Next(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestDue(t *testing.T) {
+	schedule, _ := Parse("0 * * * *", "UTC")
+	at := func(hour, minute int) time.Time { return time.Date(2026, 10, 19, hour, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		policy string
+		run    time.Time
+		missed int
+	}{
+		{"on time", at(2, 1), MissedSkip, at(2, 0), 0},
+		{"late, skipped", at(2, 30), MissedSkip, time.Time{}, 1},
+		{"late, caught up", at(2, 30), MissedCatchUp, at(2, 0), 0},
+		{"down for hours, skipped", at(5, 30), MissedSkip, time.Time{}, 4},
+		{"down for hours, caught up", at(5, 30), MissedCatchUp, at(5, 0), 3},
+		{"back just in time", at(5, 2), MissedSkip, at(5, 0), 3},
+	}
+
+	for _, tt := range tests {
+		decision := Due(schedule, at(2, 0), tt.now, 5*time.Minute, tt.policy)
+		if !decision.Run.Equal(tt.run) || len(decision.Missed) != tt.missed {
+			t.Errorf("%s: expected run %v with %d missed, got %v with %d", tt.name, tt.run, tt.missed, decision.Run, len(decision.Missed))
+		}
+		if want := schedule.Next(tt.now); !decision.Next.Equal(want) {
+			t.Errorf("%s: expected next %v, got %v", tt.name, want, decision.Next)
+		}
+	}
+
+	decision := Due(schedule, at(0, 0).AddDate(0, -1, 0), at(0, 0), time.Minute, MissedSkip)
+	if len(decision.Missed) != maxMissed {
+		t.Errorf("Expected %d missed runs, got %d", maxMissed, len(decision.Missed))
+	}
+}
+
+func TestScheduler_RunsUntilClosed(t *testing.T) {
+	var calls atomic.Int32
+	s := New(func(ctx context.Context, now time.Time) {
+		calls.Add(1)
+	}, 10*time.Millisecond)
+
+	time.Sleep(55 * time.Millisecond)
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("Expected Close to succeed, got: %v", err)
+	}
+	n := calls.Load()
+	if n < 2 {
+		t.Errorf("Expected several runs, got %d", n)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if calls.Load() != n {
+		t.Error("Expected no runs after Close")
+	}
+}
@@ -0,0 +1,212 @@
+package sftpgw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"livecode-api/internal/remotefs"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+func (s *Session) Capabilities() remotefs.Capabilities {
+	return remotefs.Capabilities{
+		Chmod:             true,
+		Chtimes:           true,
+		Symlinks:          true,
+		Ownership:         true,
+		ResumeUpload:      true,
+		RenameDirectories: true,
+		Exec:              true,
+	}
+}
+
+func (s *Session) RealPath(name string) (string, error) {
+	return s.SFTP.RealPath(name)
+}
+
+func (s *Session) Stat(name string) (fs.FileInfo, error) {
+	return s.SFTP.Stat(name)
+}
+
+func (s *Session) Lstat(name string) (fs.FileInfo, error) {
+	return s.SFTP.Lstat(name)
+}
+
+func (s *Session) ReadDir(name string) ([]fs.FileInfo, error) {
+	return s.SFTP.ReadDir(name)
+}
+
+type fileReader struct {
+	ctx  context.Context
+	file *sftp.File
+	*Reader
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, context.Cause(r.ctx)
+	}
+	return r.Reader.Read(p)
+}
+
+func (r *fileReader) Close() error {
+	return r.file.Close()
+}
+
+func (s *Session) Open(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	file, err := s.SFTP.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	reader := NewReader(file, info.Size())
+	reader.Seek(offset, io.SeekStart)
+	return &fileReader{ctx: ctx, file: file, Reader: reader}, nil
+}
+
+// fileWriter sends data it is given as a whole with concurrent requests.
+type fileWriter struct {
+	*sftp.File
+}
+
+func (w fileWriter) ReadFrom(r io.Reader) (int64, error) {
+	return w.File.ReadFromWithConcurrency(r, 0)
+}
+
+func (s *Session) Create(ctx context.Context, name string, offset int64) (io.WriteCloser, error) {
+	if offset == 0 {
+		file, err := s.SFTP.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return nil, err
+		}
+		return fileWriter{file}, nil
+	}
+
+	file, err := s.SFTP.OpenFile(name, os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return fileWriter{file}, nil
+}
+
+func (s *Session) Mkdir(name string) error {
+	return s.SFTP.Mkdir(name)
+}
+
+func (s *Session) MkdirAll(name string) error {
+	return s.SFTP.MkdirAll(name)
+}
+
+// Rename replaces with the posix-rename extension where the server has it,
+// since plain SFTP renames refuse to overwrite.
+func (s *Session) Rename(from, to string, replace bool) error {
+	if !replace {
+		return s.SFTP.Rename(from, to)
+	}
+	if _, ok := s.SFTP.HasExtension("posix-rename@openssh.com"); ok {
+		return s.SFTP.PosixRename(from, to)
+	}
+	if err := s.SFTP.Remove(to); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.SFTP.Rename(from, to)
+}
+
+func (s *Session) Remove(name string) error {
+	return s.SFTP.Remove(name)
+}
+
+func (s *Session) RemoveDirectory(name string) error {
+	return s.SFTP.RemoveDirectory(name)
+}
+
+func (s *Session) RemoveAll(name string) error {
+	return s.SFTP.RemoveAll(name)
+}
+
+func (s *Session) Chmod(name string, mode fs.FileMode) error {
+	return s.SFTP.Chmod(name, mode)
+}
+
+func (s *Session) Chtimes(name string, modified time.Time) error {
+	return s.SFTP.Chtimes(name, time.Now(), modified)
+}
+
+func (s *Session) Symlink(target, name string) error {
+	return s.SFTP.Symlink(target, name)
+}
+
+// Exec runs command over an exec channel. SFTP-only servers refuse it.
+func (s *Session) Exec(ctx context.Context, command string) ([]byte, error) {
+	exec, err := s.SSH.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer exec.Close()
+	stop := context.AfterFunc(ctx, func() { exec.Close() })
+	defer stop()
+
+	return exec.Output(command)
+}
+
+// Stream runs command like Exec, handing over its output as it arrives.
+func (s *Session) Stream(ctx context.Context, command string) (io.ReadCloser, error) {
+	exec, err := s.SSH.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := exec.StdoutPipe()
+	if err != nil {
+		exec.Close()
+		return nil, err
+	}
+	if err := exec.Start(command); err != nil {
+		exec.Close()
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { exec.Close() })
+	return &commandOutput{Reader: stdout, exec: exec, stop: stop}, nil
+}
+
+type commandOutput struct {
+	io.Reader
+	exec *ssh.Session
+	stop func() bool
+	eof  bool
+}
+
+func (o *commandOutput) Read(p []byte) (int, error) {
+	n, err := o.Reader.Read(p)
+	if err == io.EOF {
+		o.eof = true
+	}
+	return n, err
+}
+
+func (o *commandOutput) Close() error {
+	defer o.stop()
+	if !o.eof {
+		return o.exec.Close()
+	}
+	defer o.exec.Close()
+	return o.exec.Wait()
+}
@@ -0,0 +1,67 @@
+package sftpgw
+
+import (
+	"errors"
+	"io"
+
+	"github.com/pkg/sftp"
+)
+
+const readAheadSize = 1 << 20
+
+// Reader reads a remote file in large chunks, which sftp.File fetches with
+// concurrent requests. Reading it with io.Copy's 32 KiB buffer directly
+// would cost a round trip per buffer.
+type Reader struct {
+	file   *sftp.File
+	size   int64
+	offset int64
+
+	buf   []byte
+	start int64
+	n     int
+}
+
+func NewReader(file *sftp.File, size int64) *Reader {
+	return &Reader{file: file, size: size}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.offset < r.start || r.offset >= r.start+int64(r.n) {
+		if r.buf == nil {
+			r.buf = make([]byte, readAheadSize)
+		}
+
+		n, err := r.file.ReadAt(r.buf, r.offset)
+		if n == 0 {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		r.start, r.n = r.offset, n
+	}
+
+	copied := copy(p, r.buf[r.offset-r.start:r.n])
+	r.offset += int64(copied)
+	return copied, nil
+}
+
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("sftpgw: negative offset")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
@@ -0,0 +1,91 @@
+// Package sftpgw opens SFTP sessions on behalf of users. Sessions are the
+// SFTP backend of remotefs.
+package sftpgw
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"livecode-api/internal/remotefs"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Target describes how to reach and log in to a server.
+type Target struct {
+	Address         string
+	Username        string
+	Auth            []ssh.AuthMethod
+	HostKeyCallback ssh.HostKeyCallback
+	// Control, if set, vets the connection before it is opened.
+	Control remotefs.DialControl
+}
+
+// Session is an SSH connection with an SFTP client on top.
+type Session struct {
+	SSH  *ssh.Client
+	SFTP *sftp.Client
+
+	done chan struct{}
+}
+
+// Dial connects to target. The timeout covers both the TCP connection and
+// the SSH handshake. Errors wrap remotefs.ErrAuthFailed or
+// remotefs.ErrConnectFailed, along with any error returned by the host key
+// callback.
+func Dial(ctx context.Context, target Target, timeout time.Duration) (*Session, error) {
+	dialer := net.Dialer{Timeout: timeout, ControlContext: target.Control}
+	conn, err := dialer.DialContext(ctx, "tcp", target.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, target.Address, &ssh.ClientConfig{
+		User:            target.Username,
+		Auth:            target.Auth,
+		HostKeyCallback: target.HostKeyCallback,
+		Timeout:         timeout,
+	})
+	if err != nil {
+		conn.Close()
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, fmt.Errorf("%w: %w", remotefs.ErrAuthFailed, err)
+		}
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	client := ssh.NewClient(sshConn, channels, requests)
+	sftpClient, err := sftp.NewClient(client, sftp.UseConcurrentWrites(true))
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%w: %w", remotefs.ErrConnectFailed, err)
+	}
+
+	session := &Session{SSH: client, SFTP: sftpClient, done: make(chan struct{})}
+	go func() {
+		sftpClient.Wait()
+		close(session.done)
+	}()
+	return session, nil
+}
+
+// Alive reports false once the connection has dropped.
+func (s *Session) Alive() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *Session) Close() error {
+	s.SFTP.Close()
+	return s.SSH.Close()
+}
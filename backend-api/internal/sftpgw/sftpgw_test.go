@@ -0,0 +1,104 @@
+package sftpgw
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/sftpgw/sftptest"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func passwordTarget(server *sftptest.Server, password string) func() (Target, error) {
+	return func() (Target, error) {
+		return Target{
+			Address:         server.Addr,
+			Username:        server.User,
+			Auth:            []ssh.AuthMethod{ssh.Password(password)},
+			HostKeyCallback: ssh.FixedHostKey(server.HostKey),
+		}, nil
+	}
+}
+
+func TestDial_AuthFailure(t *testing.T) {
+	server := sftptest.NewServer(t)
+	target, _ := passwordTarget(server, "wrong")()
+
+	_, err := Dial(context.Background(), target, 5*time.Second)
+	if !errors.Is(err, remotefs.ErrAuthFailed) {
+		t.Errorf("Expected remotefs.ErrAuthFailed, got: %v", err)
+	}
+}
+
+func TestDial_PublicKeyAndFileRoundTrip(t *testing.T) {
+	server := sftptest.NewServer(t)
+	session, err := Dial(context.Background(), Target{
+		Address:         server.Addr,
+		Username:        server.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(server.ClientKey)},
+		HostKeyCallback: ssh.FixedHostKey(server.HostKey),
+	}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected to connect, got: %v", err)
+	}
+	defer session.Close()
+
+	file, err := session.SFTP.Create("hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(file, "hello")
+	file.Close()
+
+	data, err := os.ReadFile(filepath.Join(server.Root, "hello.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Expected the file in the server root, got %q, %v", data, err)
+	}
+}
+
+func TestReader_SeeksAcrossChunks(t *testing.T) {
+	server := sftptest.NewServer(t)
+	data := make([]byte, 3*readAheadSize+123)
+	rand.Read(data)
+	if err := os.WriteFile(filepath.Join(server.Root, "big.bin"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	target, _ := passwordTarget(server, server.Password)()
+	session, err := Dial(context.Background(), target, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	file, err := session.SFTP.Open("big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader := NewReader(file, int64(len(data)))
+	if _, err := reader.Seek(readAheadSize+7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(got, data[readAheadSize+7:]) {
+		t.Errorf("Expected the tail of the file, got %d bytes, %v", len(got), err)
+	}
+
+	if _, err := reader.Seek(-10, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = io.ReadAll(reader)
+	if !bytes.Equal(got, data[len(data)-10:]) {
+		t.Errorf("Expected the last 10 bytes, got %x", got)
+	}
+}
@@ -0,0 +1,168 @@
+// Package sftptest runs an in-process SSH server with the SFTP subsystem,
+// serving a temporary directory, for end-to-end tests of the gateway.
+package sftptest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+type Server struct {
+	Addr     string
+	Root     string
+	User     string
+	Password string
+	HostKey  ssh.PublicKey
+	// ClientKey is accepted for public key authentication.
+	ClientKey ssh.Signer
+	// Exec answers exec requests with the command's output and exit status.
+	// Without it they are refused, as on an SFTP-only server.
+	Exec func(command string) (string, uint32)
+
+	listener    net.Listener
+	config      *ssh.ServerConfig
+	connections atomic.Int64
+	wg          sync.WaitGroup
+}
+
+// NewServer starts a server on a loopback port. Relative SFTP paths resolve
+// against Root, a fresh temporary directory. The server stops when the test
+// ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromSigner(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, err := ssh.NewSignerFromSigner(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		Addr:      listener.Addr().String(),
+		Root:      t.TempDir(),
+		User:      "tester",
+		Password:  "correct horse",
+		HostKey:   hostSigner.PublicKey(),
+		ClientKey: clientSigner,
+		listener:  listener,
+	}
+
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == s.User && string(password) == s.Password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == s.User && string(key.Marshal()) == string(s.ClientKey.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	s.config.AddHostKey(hostSigner)
+
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Connections returns the number of SSH connections accepted so far.
+func (s *Server) Connections() int {
+	return int(s.connections.Load())
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sshConn.Close()
+	s.connections.Add(1)
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.session(channel, channelRequests)
+	}
+}
+
+func (s *Server) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		if req.Type == "exec" && s.Exec != nil && len(req.Payload) >= 4 {
+			req.Reply(true, nil)
+			output, status := s.Exec(string(req.Payload[4:]))
+			channel.Write([]byte(output))
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+			return
+		}
+		if req.Type != "subsystem" || len(req.Payload) < 4 || string(req.Payload[4:]) != "sftp" {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+
+		server, err := sftp.NewServer(channel, sftp.WithServerWorkingDirectory(s.Root))
+		if err != nil {
+			return
+		}
+		server.Serve()
+		server.Close()
+		return
+	}
+}
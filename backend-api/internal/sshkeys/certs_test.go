@@ -0,0 +1,95 @@
+package sshkeys
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSignUserCertificate(t *testing.T) {
+	ca, err := Generate(AlgorithmEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKey, err := PublicKey(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey, _, err := ParsePublicKey(fixtureAuthorizedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	cert, err := SignUserCertificate(ca, userKey, UserCertificate{
+		Serial:      42,
+		KeyID:       "livecode:ada:42",
+		Principals:  []string{"deploy", "ops"},
+		ValidAfter:  now.Add(-5 * time.Minute),
+		ValidBefore: now.Add(4 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker := ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), caKey.Marshal())
+		},
+	}
+	if err := checker.CheckCert("ops", cert); err != nil {
+		t.Errorf("Expected the certificate to be valid for ops, got: %v", err)
+	}
+	if err := checker.CheckCert("root", cert); err == nil {
+		t.Error("Expected the certificate to be refused for an unlisted principal")
+	}
+	if _, ok := cert.Permissions.Extensions["permit-pty"]; !ok || cert.Serial != 42 {
+		t.Errorf("Unexpected certificate: serial %d, extensions %v", cert.Serial, cert.Permissions.Extensions)
+	}
+
+	checker.Clock = func() time.Time { return now.Add(5 * time.Hour) }
+	if err := checker.CheckCert("ops", cert); err == nil {
+		t.Error("Expected an expired certificate to be refused")
+	}
+}
+
+func TestKRL_Marshal(t *testing.T) {
+	ca, err := Generate(AlgorithmEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKey, err := PublicKey(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	krl := KRL{
+		Version:      7,
+		GeneratedAt:  time.Unix(1700000000, 0),
+		Comment:      "livecode",
+		Certificates: []RevokedCertificates{{CA: caKey, Serials: []uint64{9, 3, 9}}},
+	}
+	data := krl.Marshal()
+
+	if !bytes.HasPrefix(data, []byte("SSHKRL\n\x00\x00\x00\x00\x01")) {
+		t.Fatalf("Expected the KRL magic and format version, got: %x", data[:12])
+	}
+	if binary.BigEndian.Uint64(data[12:]) != 7 || binary.BigEndian.Uint64(data[20:]) != 1700000000 {
+		t.Error("Expected the KRL version and generation date in the header")
+	}
+
+	serials := binary.BigEndian.AppendUint32(nil, 16)
+	serials = binary.BigEndian.AppendUint64(serials, 3)
+	serials = binary.BigEndian.AppendUint64(serials, 9)
+	if !bytes.HasSuffix(data, serials) {
+		t.Error("Expected the serials sorted and deduplicated at the end of the KRL")
+	}
+
+	empty := KRL{Certificates: []RevokedCertificates{{CA: caKey}}}
+	if bytes.Contains(empty.Marshal(), caKey.Marshal()) {
+		t.Error("Expected a CA without revocations to be left out")
+	}
+}
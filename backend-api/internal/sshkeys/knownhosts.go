@@ -0,0 +1,133 @@
+package sshkeys
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var ErrInvalidKnownHosts = errors.New("invalid known_hosts line")
+
+// KnownHost is one host and key from a known_hosts file. Host is in the
+// file's form: "host", "[host]:port" or a hashed "|1|salt|hash".
+type KnownHost struct {
+	Host    string
+	Key     ssh.PublicKey
+	Comment string
+}
+
+func (h KnownHost) Hashed() bool {
+	return strings.HasPrefix(h.Host, "|")
+}
+
+// KnownHostAddress returns host and port in known_hosts form, which omits
+// the default port 22.
+func KnownHostAddress(host string, port int) string {
+	return knownhosts.Normalize(net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// HashKnownHost hashes an address in known_hosts form the way ssh-keygen -H
+// does, with a random salt.
+func HashKnownHost(address string) string {
+	return knownhosts.HashHostname(address)
+}
+
+// MatchKnownHost reports whether a known_hosts host, hashed or not, names
+// address.
+func MatchKnownHost(host, address string) bool {
+	if !strings.HasPrefix(host, "|") {
+		return host == address
+	}
+
+	salt, hash, ok := decodeHashedHost(host)
+	if !ok {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(address))
+	return hmac.Equal(mac.Sum(nil), hash)
+}
+
+func decodeHashedHost(host string) (salt, hash []byte, ok bool) {
+	parts := strings.Split(host, "|")
+	if len(parts) != 4 || parts[0] != "" || parts[1] != "1" {
+		return nil, nil, false
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil || len(salt) != sha1.Size {
+		return nil, nil, false
+	}
+	hash, err = base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(hash) != sha1.Size {
+		return nil, nil, false
+	}
+	return salt, hash, true
+}
+
+// ParseKnownHosts reads a known_hosts file, splitting lines that name
+// several hosts. Lines with a marker (@cert-authority, @revoked) and host
+// patterns with wildcards or negation cannot be stored as a single host key
+// and are counted in skipped instead.
+func ParseKnownHosts(data []byte) (hosts []KnownHost, skipped int, err error) {
+	for lineNumber, line := range bytes.Split(data, []byte("\n")) {
+		marker, names, key, comment, _, err := ssh.ParseKnownHosts(line)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: line %d: %v", ErrInvalidKnownHosts, lineNumber+1, err)
+		}
+		if marker != "" {
+			skipped++
+			continue
+		}
+
+		for _, name := range names {
+			if strings.HasPrefix(name, "|") {
+				if _, _, ok := decodeHashedHost(name); !ok {
+					skipped++
+					continue
+				}
+			} else if strings.ContainsAny(name, "*?!") {
+				skipped++
+				continue
+			} else {
+				name = knownhosts.Normalize(strings.ToLower(name))
+			}
+			hosts = append(hosts, KnownHost{Host: name, Key: key, Comment: comment})
+		}
+	}
+
+	return hosts, skipped, nil
+}
+
+// MarshalKnownHosts writes hosts as a known_hosts file. With hash set,
+// plain host names are hashed; already hashed ones are kept.
+func MarshalKnownHosts(hosts []KnownHost, hash bool) []byte {
+	var out bytes.Buffer
+	for _, host := range hosts {
+		name := host.Host
+		if hash && !host.Hashed() {
+			name = HashKnownHost(name)
+		}
+
+		out.WriteString(name + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(host.Key))))
+		if host.Comment != "" {
+			out.WriteString(" " + host.Comment)
+		}
+		out.WriteString("\n")
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,70 @@
+package sshkeys
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Hashed with: ssh-keygen -H, from entries for example.com,
+// [git.example.com]:2222 and 10.0.0.5.
+const fixtureKnownHostsHashed = `|1|BwNtGoILWVdj12FczMlGykJpHNI=|jaircWXAOPfUK4v/0vSBfurPSh8= ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIG6itiL9f8+ctvmo6yuQDoYezAvWWfhTvwyyaRXYaFIq
+|1|6YALrgZS4JXt7J5d2KVTZXHBZgM=|MLVU+V+QGOgY+NoNro/+9H6tIjI= ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIG6itiL9f8+ctvmo6yuQDoYezAvWWfhTvwyyaRXYaFIq
+|1|Gqj5R33UZhPoSw9zie7g7Weq4TY=|sJdDK/mo8N8LKVIUD0i4GkLYKHU= ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIG6itiL9f8+ctvmo6yuQDoYezAvWWfhTvwyyaRXYaFIq
+`
+
+func TestParseKnownHosts(t *testing.T) {
+	data := "# comment\n" +
+		"Example.com,[git.example.com]:2222 " + fixtureAuthorizedKey + " ada@laptop\n" +
+		"*.internal " + fixtureAuthorizedKey + "\n" +
+		"@revoked old.example.com " + fixtureAuthorizedKey + "\n" +
+		fixtureKnownHostsHashed
+
+	hosts, skipped, err := ParseKnownHosts([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 5 || skipped != 2 {
+		t.Fatalf("Expected 5 hosts and 2 skipped, got: %d, %d", len(hosts), skipped)
+	}
+	if hosts[0].Host != "example.com" || hosts[1].Host != "[git.example.com]:2222" || hosts[0].Comment != "ada@laptop" {
+		t.Errorf("Unexpected plain hosts: %+v", hosts[:2])
+	}
+
+	for i, address := range []string{"example.com", KnownHostAddress("git.example.com", 2222), KnownHostAddress("10.0.0.5", 22)} {
+		if !hosts[2+i].Hashed() || !MatchKnownHost(hosts[2+i].Host, address) {
+			t.Errorf("Expected hashed host %d to match %s", i, address)
+		}
+	}
+	if MatchKnownHost(hosts[2].Host, "example.org") {
+		t.Error("Expected a hashed host not to match another address")
+	}
+
+	if _, _, err := ParseKnownHosts([]byte("example.com ssh-ed25519 not-base64\n")); !errors.Is(err, ErrInvalidKnownHosts) {
+		t.Errorf("Expected ErrInvalidKnownHosts, got: %v", err)
+	}
+}
+
+func TestMarshalKnownHosts_Hash(t *testing.T) {
+	hosts, _, err := ParseKnownHosts([]byte("[git.example.com]:2222 " + fixtureAuthorizedKey + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := MarshalKnownHosts(hosts, false)
+	if string(plain) != "[git.example.com]:2222 "+fixtureAuthorizedKey+"\n" {
+		t.Errorf("Unexpected plain export: %q", plain)
+	}
+
+	hashed, _, err := ParseKnownHosts(MarshalKnownHosts(hosts, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashed) != 1 || !hashed[0].Hashed() || !MatchKnownHost(hashed[0].Host, "[git.example.com]:2222") {
+		t.Errorf("Expected a hashed entry matching the host, got: %+v", hashed)
+	}
+	if !bytes.Equal(hashed[0].Key.Marshal(), hosts[0].Key.Marshal()) || strings.Contains(hashed[0].Host, "example") {
+		t.Error("Expected the key kept and the host name hidden")
+	}
+}
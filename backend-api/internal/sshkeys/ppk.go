@@ -0,0 +1,471 @@
+package sshkeys
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	ppkHeaderPrefix = "PuTTY-User-Key-File-"
+	ppkLineLength   = 64
+
+	ppkCipherKeySize = 32
+	ppkIVSize        = aes.BlockSize
+	ppkMACKeySize    = 32
+
+	// Defaults match PuTTYgen's for new version 3 files.
+	ppkArgon2Memory      = 8192
+	ppkArgon2Passes      = 21
+	ppkArgon2Parallelism = 1
+	ppkArgon2SaltSize    = 16
+
+	// Imported files choose their own Argon2 cost; these bound the work a
+	// single upload can ask the server to do.
+	maxPPKArgon2Memory      = 65536
+	maxPPKArgon2Passes      = 256
+	maxPPKArgon2Parallelism = 16
+	maxPPKArgon2Work        = ppkArgon2Memory * maxPPKArgon2Passes
+	maxPPKLines             = 1024
+)
+
+var errInvalidPPK = fmt.Errorf("%w: malformed PuTTY key file", ErrInvalidPrivateKey)
+
+type ppkFile struct {
+	version    int
+	algorithm  string
+	encryption string
+	comment    string
+	public     []byte
+	private    []byte
+	mac        []byte
+
+	kdf         string
+	memory      uint32
+	passes      uint32
+	parallelism uint8
+	salt        []byte
+}
+
+type ppkReader struct {
+	scanner *bufio.Scanner
+	err     error
+}
+
+func (r *ppkReader) header(name string) string {
+	if r.err != nil {
+		return ""
+	}
+	if !r.scanner.Scan() {
+		r.err = errInvalidPPK
+		return ""
+	}
+
+	key, value, ok := cutPPKHeader(r.scanner.Text())
+	if !ok || key != name {
+		r.err = errInvalidPPK
+		return ""
+	}
+	return value
+}
+
+func cutPPKHeader(line string) (string, string, bool) {
+	key, value, ok := strings.Cut(strings.TrimRight(line, "\r"), ":")
+	return key, strings.TrimPrefix(value, " "), ok
+}
+
+func (r *ppkReader) number(name string, max int) int {
+	value := r.header(name)
+	if r.err != nil {
+		return 0
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > max {
+		r.err = errInvalidPPK
+		return 0
+	}
+	return n
+}
+
+func (r *ppkReader) blob(name string) []byte {
+	lines := r.number(name, maxPPKLines)
+
+	var encoded strings.Builder
+	for i := 0; i < lines && r.err == nil; i++ {
+		if !r.scanner.Scan() {
+			r.err = errInvalidPPK
+			break
+		}
+		encoded.WriteString(strings.TrimSpace(r.scanner.Text()))
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		r.err = errInvalidPPK
+	}
+	return decoded
+}
+
+func readPPK(data []byte) (*ppkFile, error) {
+	r := &ppkReader{scanner: bufio.NewScanner(bytes.NewReader(bytes.TrimSpace(data)))}
+	if !r.scanner.Scan() {
+		return nil, errInvalidPPK
+	}
+
+	first, algorithm, _ := cutPPKHeader(r.scanner.Text())
+	file := &ppkFile{algorithm: algorithm}
+	switch first {
+	case ppkHeaderPrefix + "2":
+		file.version = 2
+	case ppkHeaderPrefix + "3":
+		file.version = 3
+	default:
+		return nil, fmt.Errorf("%w: unsupported PuTTY key file version", ErrInvalidPrivateKey)
+	}
+
+	file.encryption = r.header("Encryption")
+	file.comment = r.header("Comment")
+	file.public = r.blob("Public-Lines")
+
+	if file.version == 3 && file.encryption != "none" {
+		file.kdf = r.header("Key-Derivation")
+		file.memory = uint32(r.number("Argon2-Memory", maxPPKArgon2Memory))
+		file.passes = uint32(r.number("Argon2-Passes", maxPPKArgon2Passes))
+		file.parallelism = uint8(r.number("Argon2-Parallelism", maxPPKArgon2Parallelism))
+		salt, err := hex.DecodeString(r.header("Argon2-Salt"))
+		if err != nil {
+			r.err = errInvalidPPK
+		}
+		file.salt = salt
+	}
+
+	file.private = r.blob("Private-Lines")
+	mac, err := hex.DecodeString(r.header("Private-MAC"))
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err != nil {
+		return nil, errInvalidPPK
+	}
+	file.mac = mac
+
+	if file.encryption != "none" && file.encryption != "aes256-cbc" {
+		return nil, fmt.Errorf("%w: unsupported PuTTY key encryption %q", ErrInvalidPrivateKey, file.encryption)
+	}
+	return file, nil
+}
+
+// keys derives the cipher key, IV and MAC key for the file. Unencrypted
+// files still carry a MAC, keyed with an empty passphrase.
+func (f *ppkFile) keys(passphrase []byte) (cipherKey, iv []byte, mac hash.Hash, err error) {
+	if f.version == 2 {
+		macKey := sha1.Sum(append([]byte("putty-private-key-file-mac-key"), passphrase...))
+		if f.encryption == "none" {
+			return nil, nil, hmac.New(sha1.New, macKey[:]), nil
+		}
+
+		var derived []byte
+		for counter := uint32(0); counter < 2; counter++ {
+			h := sha1.New()
+			binary.Write(h, binary.BigEndian, counter)
+			h.Write(passphrase)
+			derived = h.Sum(derived)
+		}
+		return derived[:ppkCipherKeySize], make([]byte, ppkIVSize), hmac.New(sha1.New, macKey[:]), nil
+	}
+
+	if f.encryption == "none" {
+		return nil, nil, hmac.New(sha256.New, nil), nil
+	}
+
+	if f.parallelism == 0 || f.passes == 0 || int(f.memory)*int(f.passes) > maxPPKArgon2Work {
+		return nil, nil, nil, fmt.Errorf("%w: Argon2 parameters out of range", ErrInvalidPrivateKey)
+	}
+
+	size := uint32(ppkCipherKeySize + ppkIVSize + ppkMACKeySize)
+	var derived []byte
+	switch f.kdf {
+	case "Argon2id":
+		derived = argon2.IDKey(passphrase, f.salt, f.passes, f.memory, f.parallelism, size)
+	case "Argon2i":
+		derived = argon2.Key(passphrase, f.salt, f.passes, f.memory, f.parallelism, size)
+	default:
+		return nil, nil, nil, fmt.Errorf("%w: unsupported key derivation %q", ErrInvalidPrivateKey, f.kdf)
+	}
+
+	return derived[:ppkCipherKeySize],
+		derived[ppkCipherKeySize : ppkCipherKeySize+ppkIVSize],
+		hmac.New(sha256.New, derived[ppkCipherKeySize+ppkIVSize:]),
+		nil
+}
+
+func (f *ppkFile) sign(mac hash.Hash, private []byte) []byte {
+	mac.Write(ssh.Marshal(struct {
+		Algorithm  string
+		Encryption string
+		Comment    string
+		Public     []byte
+		Private    []byte
+	}{f.algorithm, f.encryption, f.comment, f.public, private}))
+	return mac.Sum(nil)
+}
+
+func parsePPK(data, passphrase []byte) (crypto.Signer, string, error) {
+	file, err := readPPK(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	encrypted := file.encryption != "none"
+	if encrypted && len(passphrase) == 0 {
+		return nil, "", ErrPassphraseRequired
+	}
+	if !encrypted {
+		passphrase = nil
+	}
+
+	cipherKey, iv, mac, err := file.keys(passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+
+	private := file.private
+	if encrypted {
+		if len(private) == 0 || len(private)%aes.BlockSize != 0 {
+			return nil, "", errInvalidPPK
+		}
+		block, _ := aes.NewCipher(cipherKey)
+		private = make([]byte, len(file.private))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(private, file.private)
+	}
+
+	if !hmac.Equal(file.sign(mac, private), file.mac) {
+		if encrypted {
+			return nil, "", ErrIncorrectPassphrase
+		}
+		return nil, "", errInvalidPPK
+	}
+
+	key, err := ppkKey(file.algorithm, file.public, private)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, file.comment, nil
+}
+
+// ppkKey rebuilds a private key from PuTTY's public and private blobs and
+// checks that the two halves belong together.
+func ppkKey(algorithm string, public, private []byte) (crypto.Signer, error) {
+	var key crypto.Signer
+
+	switch algorithm {
+	case ssh.KeyAlgoED25519:
+		var priv struct {
+			Key  []byte
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &priv); err != nil || len(priv.Key) > ed25519.SeedSize {
+			return nil, errInvalidPPK
+		}
+		// PuTTY writes the seed as an unsigned little-endian integer, so
+		// trailing zero bytes are dropped.
+		seed := make([]byte, ed25519.SeedSize)
+		copy(seed, priv.Key)
+		key = ed25519.NewKeyFromSeed(seed)
+
+	case ssh.KeyAlgoRSA:
+		var pub struct {
+			Type string
+			E    *big.Int
+			N    *big.Int
+		}
+		var priv struct {
+			D, P, Q, Iqmp *big.Int
+			Rest          []byte `ssh:"rest"`
+		}
+		if ssh.Unmarshal(public, &pub) != nil || ssh.Unmarshal(private, &priv) != nil || !pub.E.IsInt64() {
+			return nil, errInvalidPPK
+		}
+		rsaKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: pub.N, E: int(pub.E.Int64())},
+			D:         priv.D,
+			Primes:    []*big.Int{priv.P, priv.Q},
+		}
+		if rsaKey.Validate() != nil {
+			return nil, errInvalidPPK
+		}
+		rsaKey.Precompute()
+		if _, err := signer(rsaKey); err != nil {
+			return nil, err
+		}
+		key = rsaKey
+
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		var pub struct {
+			Type  string
+			Curve string
+			Q     []byte
+		}
+		var priv struct {
+			D    *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if ssh.Unmarshal(public, &pub) != nil || ssh.Unmarshal(private, &priv) != nil {
+			return nil, errInvalidPPK
+		}
+		curve, exchange := ecdsaCurve(algorithm)
+		if priv.D.Sign() <= 0 || priv.D.BitLen() > curve.Params().BitSize {
+			return nil, errInvalidPPK
+		}
+		scalar := priv.D.FillBytes(make([]byte, (curve.Params().BitSize+7)/8))
+		ecdhKey, err := exchange.NewPrivateKey(scalar)
+		if err != nil || !bytes.Equal(ecdhKey.PublicKey().Bytes(), pub.Q) {
+			return nil, errInvalidPPK
+		}
+		x, y := elliptic.Unmarshal(curve, pub.Q)
+		key = &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: priv.D}
+
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+
+	sshKey, err := PublicKey(key)
+	if err != nil || !bytes.Equal(sshKey.Marshal(), public) {
+		return nil, errInvalidPPK
+	}
+	return key, nil
+}
+
+func ecdsaCurve(algorithm string) (elliptic.Curve, ecdh.Curve) {
+	switch algorithm {
+	case ssh.KeyAlgoECDSA384:
+		return elliptic.P384(), ecdh.P384()
+	case ssh.KeyAlgoECDSA521:
+		return elliptic.P521(), ecdh.P521()
+	default:
+		return elliptic.P256(), ecdh.P256()
+	}
+}
+
+func ppkPrivateBlob(key crypto.Signer) ([]byte, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		seed := bytes.TrimRight(k.Seed(), "\x00")
+		return ssh.Marshal(struct{ Key []byte }{seed}), nil
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, ErrUnsupportedKeyType
+		}
+		k.Precompute()
+		return ssh.Marshal(struct{ D, P, Q, Iqmp *big.Int }{k.D, k.Primes[0], k.Primes[1], k.Precomputed.Qinv}), nil
+	case *ecdsa.PrivateKey:
+		return ssh.Marshal(struct{ D *big.Int }{k.D}), nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+// MarshalPPK encodes key as a version 3 PuTTY key file, encrypted with
+// passphrase unless it is empty.
+func MarshalPPK(key crypto.Signer, comment string, passphrase []byte) ([]byte, error) {
+	sshKey, err := PublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	private, err := ppkPrivateBlob(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &ppkFile{
+		version:    3,
+		algorithm:  sshKey.Type(),
+		encryption: "none",
+		comment:    comment,
+		public:     sshKey.Marshal(),
+	}
+
+	if len(passphrase) > 0 {
+		file.encryption = "aes256-cbc"
+		file.kdf = "Argon2id"
+		file.memory = ppkArgon2Memory
+		file.passes = ppkArgon2Passes
+		file.parallelism = ppkArgon2Parallelism
+		file.salt = make([]byte, ppkArgon2SaltSize)
+		if _, err := rand.Read(file.salt); err != nil {
+			return nil, err
+		}
+
+		// Like PuTTY, pad to the block size with bytes of the blob's SHA-1.
+		if padding := (aes.BlockSize - len(private)%aes.BlockSize) % aes.BlockSize; padding > 0 {
+			digest := sha1.Sum(private)
+			private = append(private, digest[:padding]...)
+		}
+	}
+
+	cipherKey, iv, mac, err := file.keys(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	file.mac = file.sign(mac, private)
+
+	file.private = private
+	if file.encryption != "none" {
+		block, _ := aes.NewCipher(cipherKey)
+		file.private = make([]byte, len(private))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(file.private, private)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s%d: %s\n", ppkHeaderPrefix, file.version, file.algorithm)
+	fmt.Fprintf(&out, "Encryption: %s\n", file.encryption)
+	fmt.Fprintf(&out, "Comment: %s\n", file.comment)
+	writePPKBlob(&out, "Public-Lines", file.public)
+	if file.encryption != "none" {
+		fmt.Fprintf(&out, "Key-Derivation: %s\n", file.kdf)
+		fmt.Fprintf(&out, "Argon2-Memory: %d\n", file.memory)
+		fmt.Fprintf(&out, "Argon2-Passes: %d\n", file.passes)
+		fmt.Fprintf(&out, "Argon2-Parallelism: %d\n", file.parallelism)
+		fmt.Fprintf(&out, "Argon2-Salt: %x\n", file.salt)
+	}
+	writePPKBlob(&out, "Private-Lines", file.private)
+	fmt.Fprintf(&out, "Private-MAC: %x\n", file.mac)
+	return out.Bytes(), nil
+}
+
+func writePPKBlob(out *bytes.Buffer, name string, blob []byte) {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	lines := (len(encoded) + ppkLineLength - 1) / ppkLineLength
+	fmt.Fprintf(out, "%s: %d\n", name, lines)
+	for len(encoded) > 0 {
+		n := min(len(encoded), ppkLineLength)
+		out.WriteString(encoded[:n] + "\n")
+		encoded = encoded[n:]
+	}
+}
@@ -0,0 +1,228 @@
+// Package sshkeys parses, generates and converts SSH keys: OpenSSH and
+// RFC 4716 public keys, OpenSSH and PEM private keys, and PuTTY .ppk files.
+package sshkeys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	AlgorithmEd25519 = "ed25519"
+	AlgorithmRSA     = "rsa"
+
+	FormatOpenSSH = "openssh"
+	FormatPPK     = "ppk"
+
+	DefaultRSABits = 4096
+	MinRSABits     = 2048
+	MaxRSABits     = 8192
+)
+
+// SupportedTypes lists the public key types that can be registered.
+var SupportedTypes = []string{
+	ssh.KeyAlgoED25519, ssh.KeyAlgoRSA, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256,
+}
+
+var (
+	ErrInvalidPublicKey    = errors.New("sshkeys: not an OpenSSH or RFC 4716 public key")
+	ErrInvalidPrivateKey   = errors.New("sshkeys: not a supported private key")
+	ErrUnsupportedKeyType  = errors.New("sshkeys: unsupported key type")
+	ErrKeyTooWeak          = errors.New("sshkeys: RSA keys must have at least 2048 bits")
+	ErrPassphraseRequired  = errors.New("sshkeys: private key is passphrase protected")
+	ErrIncorrectPassphrase = errors.New("sshkeys: incorrect passphrase")
+)
+
+// Info describes a public key the way clients display it.
+type Info struct {
+	Type              string
+	Bits              int
+	FingerprintSHA256 string
+	FingerprintMD5    string
+	// AuthorizedKey is the key in authorized_keys form, without a comment.
+	AuthorizedKey string
+}
+
+// Describe returns the details of key, rejecting DSA keys, certificates and
+// RSA keys shorter than MinRSABits.
+func Describe(key ssh.PublicKey) (Info, error) {
+	info := Info{
+		Type:              key.Type(),
+		FingerprintSHA256: ssh.FingerprintSHA256(key),
+		FingerprintMD5:    "MD5:" + ssh.FingerprintLegacyMD5(key),
+		AuthorizedKey:     strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+	}
+
+	switch key.Type() {
+	case ssh.KeyAlgoED25519, ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256:
+		info.Bits = 256
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		info.Bits = key.(ssh.CryptoPublicKey).CryptoPublicKey().(*ecdsa.PublicKey).Curve.Params().BitSize
+	case ssh.KeyAlgoRSA:
+		info.Bits = key.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey).N.BitLen()
+		if info.Bits < MinRSABits {
+			return Info{}, ErrKeyTooWeak
+		}
+	default:
+		return Info{}, ErrUnsupportedKeyType
+	}
+
+	return info, nil
+}
+
+// ParsePublicKey accepts an authorized_keys line or an RFC 4716 block, the
+// format PuTTYgen saves public keys in, and returns the key and its comment.
+func ParsePublicKey(text string) (ssh.PublicKey, string, error) {
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, rfc4716Begin) {
+		return parseRFC4716(text)
+	}
+
+	key, comment, options, rest, err := ssh.ParseAuthorizedKey([]byte(text))
+	if err != nil || len(options) > 0 || len(bytes.TrimSpace(rest)) > 0 {
+		return nil, "", ErrInvalidPublicKey
+	}
+	return key, comment, nil
+}
+
+const (
+	rfc4716Begin = "---- BEGIN SSH2 PUBLIC KEY ----"
+	rfc4716End   = "---- END SSH2 PUBLIC KEY ----"
+)
+
+func parseRFC4716(text string) (ssh.PublicKey, string, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) < 3 || strings.TrimSpace(lines[len(lines)-1]) != rfc4716End {
+		return nil, "", ErrInvalidPublicKey
+	}
+	lines = lines[1 : len(lines)-1]
+
+	var comment string
+	var body strings.Builder
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		name, value, isHeader := strings.Cut(line, ":")
+		if !isHeader || body.Len() > 0 {
+			body.WriteString(line)
+			continue
+		}
+
+		for strings.HasSuffix(value, `\`) && i+1 < len(lines) {
+			i++
+			value = strings.TrimSuffix(value, `\`) + strings.TrimSpace(lines[i])
+		}
+		if strings.EqualFold(name, "Comment") {
+			comment = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil {
+		return nil, "", ErrInvalidPublicKey
+	}
+
+	key, err := ssh.ParsePublicKey(blob)
+	if err != nil {
+		return nil, "", ErrInvalidPublicKey
+	}
+	return key, comment, nil
+}
+
+// Generate creates a key pair. bits only applies to RSA and defaults to
+// DefaultRSABits.
+func Generate(algorithm string, bits int) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case AlgorithmRSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+		if bits < MinRSABits || bits > MaxRSABits {
+			return nil, ErrKeyTooWeak
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+// PublicKey returns the SSH public key of a private key.
+func PublicKey(key crypto.Signer) (ssh.PublicKey, error) {
+	return ssh.NewPublicKey(key.Public())
+}
+
+// ParsePrivateKey reads an OpenSSH, PEM or PuTTY private key. The comment is
+// only recovered from .ppk files. passphrase is ignored for keys that are
+// not encrypted.
+func ParsePrivateKey(data, passphrase []byte) (crypto.Signer, string, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(ppkHeaderPrefix)) {
+		return parsePPK(data, passphrase)
+	}
+
+	raw, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if len(passphrase) == 0 {
+			return nil, "", ErrPassphraseRequired
+		}
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, "", ErrIncorrectPassphrase
+		}
+	}
+	if err != nil {
+		return nil, "", ErrInvalidPrivateKey
+	}
+
+	key, err := signer(raw)
+	return key, "", err
+}
+
+func signer(raw any) (crypto.Signer, error) {
+	switch key := raw.(type) {
+	case *ed25519.PrivateKey:
+		return *key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < MinRSABits {
+			return nil, ErrKeyTooWeak
+		}
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+// MarshalOpenSSH encodes key in the OpenSSH private key format, encrypted
+// with passphrase unless it is empty.
+func MarshalOpenSSH(key crypto.Signer, comment string, passphrase []byte) ([]byte, error) {
+	var block *pem.Block
+	var err error
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, comment, passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, comment)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
@@ -0,0 +1,190 @@
+package syncplan
+
+import (
+	"path"
+	"strings"
+)
+
+// Pattern is one line of a .gitignore-style file, or an include or exclude
+// glob. Patterns without a slash match a name at any depth; others are
+// relative to the directory the pattern belongs to. "**" matches any
+// number of directories, and a trailing slash matches directories only.
+type Pattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ParsePattern parses a pattern, reporting false for blank lines, comments
+// and patterns that are not valid globs.
+func ParsePattern(line string) (Pattern, bool) {
+	line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Pattern{}, false
+	}
+
+	var p Pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+
+	p.segments = strings.Split(line, "/")
+	for _, segment := range p.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return Pattern{}, false
+		}
+	}
+	return p, true
+}
+
+// Match reports whether name, a slash-separated path relative to the
+// pattern's directory, matches.
+func (p Pattern) Match(name string, dir bool) bool {
+	if p.dirOnly && !dir {
+		return false
+	}
+	parts := strings.Split(name, "/")
+	if !p.anchored {
+		ok, _ := path.Match(p.segments[0], parts[len(parts)-1])
+		return ok
+	}
+	return matchSegments(p.segments, parts)
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// A trailing "**" matches what is inside, not the directory.
+			if len(pattern) == 1 {
+				return len(parts) > 0
+			}
+			for skip := 0; skip <= len(parts); skip++ {
+				if matchSegments(pattern[1:], parts[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// Rules holds the patterns of ignore files, by the directory they are in.
+type Rules struct {
+	dirs map[string][]Pattern
+}
+
+func NewRules() *Rules {
+	return &Rules{dirs: map[string][]Pattern{}}
+}
+
+// Add adds the patterns of an ignore file in dir, a path relative to the
+// root of the sync ("" for the root itself).
+func (r *Rules) Add(dir, content string) {
+	for _, line := range strings.Split(content, "\n") {
+		if p, ok := ParsePattern(line); ok {
+			r.dirs[dir] = append(r.dirs[dir], p)
+		}
+	}
+}
+
+// Ignored applies the ignore files from the root down to name's directory.
+// As in git, the last matching pattern wins.
+func (r *Rules) Ignored(name string, dir bool) bool {
+	ignored := false
+	base := ""
+	rest := name
+	for {
+		for _, p := range r.dirs[base] {
+			if p.Match(rest, dir) {
+				ignored = !p.negate
+			}
+		}
+		first, remaining, found := strings.Cut(rest, "/")
+		if !found {
+			return ignored
+		}
+		base = path.Join(base, first)
+		rest = remaining
+	}
+}
+
+// Filter decides which paths take part in a sync.
+type Filter struct {
+	Include []Pattern
+	Exclude []Pattern
+	Rules   *Rules
+
+	skippedDirs map[string]bool
+}
+
+// Skip reports whether name is left out of the sync, either itself or
+// because one of its parent directories is. Include patterns only apply to
+// files, so directories are always searched for matching files.
+func (f *Filter) Skip(name string, dir bool) bool {
+	if parent := path.Dir(name); parent != "." && f.skipDir(parent) {
+		return true
+	}
+	if dir {
+		return f.skipDir(name)
+	}
+	if f.excluded(name, false) {
+		return true
+	}
+	if len(f.Include) == 0 {
+		return false
+	}
+	for _, p := range f.Include {
+		if p.Match(name, false) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Filter) skipDir(name string) bool {
+	if f.skippedDirs == nil {
+		f.skippedDirs = map[string]bool{}
+	}
+	if skipped, ok := f.skippedDirs[name]; ok {
+		return skipped
+	}
+
+	skipped := f.excluded(name, true)
+	if parent := path.Dir(name); !skipped && parent != "." {
+		skipped = f.skipDir(parent)
+	}
+	f.skippedDirs[name] = skipped
+	return skipped
+}
+
+func (f *Filter) excluded(name string, dir bool) bool {
+	for _, p := range f.Exclude {
+		if p.Match(name, dir) {
+			return true
+		}
+	}
+	return f.Rules != nil && f.Rules.Ignored(name, dir)
+}
@@ -0,0 +1,116 @@
+package syncplan
+
+import "testing"
+
+func pattern(t *testing.T, line string) Pattern {
+	t.Helper()
+	p, ok := ParsePattern(line)
+	if !ok {
+		t.Fatalf("Expected %q to parse", line)
+	}
+	return p
+}
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		dir     bool
+		want    bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.log.txt", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"/build", "build", false, true},
+		{"/build", "src/build", false, false},
+		{"docs/*.md", "docs/intro.md", false, true},
+		{"docs/*.md", "docs/api/intro.md", false, false},
+		{"docs/**/*.md", "docs/intro.md", false, true},
+		{"docs/**/*.md", "docs/api/v1/intro.md", false, true},
+		{"**/cache", "a/b/cache", true, true},
+		{"vendor/**", "vendor/x/y.go", false, true},
+		{"vendor/**", "vendor", true, false},
+		{"file?.txt", "file1.txt", false, true},
+	}
+
+	for _, tt := range tests {
+		if got := pattern(t, tt.pattern).Match(tt.name, tt.dir); got != tt.want {
+			t.Errorf("%q matching %q (dir %v): expected %v, got %v", tt.pattern, tt.name, tt.dir, tt.want, got)
+		}
+	}
+}
+
+func TestParsePattern_SkipsBlankCommentsAndInvalid(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "/", "[", "a/[b"} {
+		if _, ok := ParsePattern(line); ok {
+			t.Errorf("Expected %q to be skipped", line)
+		}
+	}
+	if p := pattern(t, `\#notes`); !p.Match("#notes", false) {
+		t.Error("Expected an escaped # to match literally")
+	}
+}
+
+func TestRules_NestedFilesAndNegation(t *testing.T) {
+	rules := NewRules()
+	rules.Add("", "*.log\nnode_modules/\n")
+	rules.Add("app", "!keep.log\n/local.env\n")
+
+	tests := []struct {
+		name string
+		dir  bool
+		want bool
+	}{
+		{"debug.log", false, true},
+		{"app/debug.log", false, true},
+		{"app/keep.log", false, false},
+		{"keep.log", false, true},
+		{"app/local.env", false, true},
+		{"app/sub/local.env", false, false},
+		{"local.env", false, false},
+		{"app/node_modules", true, true},
+		{"app/main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := rules.Ignored(tt.name, tt.dir); got != tt.want {
+			t.Errorf("Ignored(%q): expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestFilter_Skip(t *testing.T) {
+	rules := NewRules()
+	rules.Add("", "tmp/\n")
+	filter := &Filter{
+		Include: []Pattern{pattern(t, "*.go"), pattern(t, "go.mod")},
+		Exclude: []Pattern{pattern(t, "vendor/"), pattern(t, "*_test.go")},
+		Rules:   rules,
+	}
+
+	tests := []struct {
+		name string
+		dir  bool
+		want bool
+	}{
+		{"main.go", false, false},
+		{"go.mod", false, false},
+		{"README.md", false, true},
+		{"main_test.go", false, true},
+		{"cmd", true, false},
+		{"cmd/tool/main.go", false, false},
+		{"vendor", true, true},
+		{"vendor/pkg/lib.go", false, true},
+		{"tmp/scratch.go", false, true},
+		{"cmd/tmp", true, true},
+	}
+
+	for _, tt := range tests {
+		if got := filter.Skip(tt.name, tt.dir); got != tt.want {
+			t.Errorf("Skip(%q): expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package syncplan compares a local and a remote directory tree and works
+// out what a sync between them has to do.
+package syncplan
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DirectionUpload   = "upload"
+	DirectionDownload = "download"
+	DirectionBoth     = "both"
+
+	CriteriaModTime  = "mtime"
+	CriteriaSize     = "size"
+	CriteriaChecksum = "checksum"
+
+	ActionUpload       = "upload"
+	ActionDownload     = "download"
+	ActionDeleteRemote = "delete_remote"
+	ActionDeleteLocal  = "delete_local"
+	ActionMkdirRemote  = "mkdir_remote"
+	ActionMkdirLocal   = "mkdir_local"
+	ActionConflict     = "conflict"
+
+	ReasonMissing      = "missing"
+	ReasonChanged      = "changed"
+	ReasonExtraneous   = "extraneous"
+	ReasonTypeMismatch = "type_mismatch"
+	ReasonTargetNewer  = "target_newer"
+	ReasonSameTime     = "same_time"
+)
+
+// ModTimeTolerance absorbs the rounding of modification times by file
+// systems and by SFTP, which only carries whole seconds.
+const ModTimeTolerance = 2 * time.Second
+
+var ErrTooManyActions = errors.New("sync needs too many changes")
+
+// Entry is a file or directory, with a slash-separated path relative to
+// the root of the sync.
+type Entry struct {
+	Path    string
+	Dir     bool
+	Size    int64
+	ModTime time.Time
+	// SHA256 is the hex digest of a file. It is only needed for checksum
+	// comparisons, and is filled in on demand for remote files.
+	SHA256 string
+}
+
+type Options struct {
+	Direction string
+	Criteria  string
+	// Mirror copies files that differ even when the target's copy is newer.
+	Mirror bool
+	// Delete removes what the target has and the source does not.
+	Delete     bool
+	MaxActions int
+}
+
+type Action struct {
+	Path   string
+	Type   string
+	Reason string
+	Dir    bool
+	Local  *Entry
+	Remote *Entry
+}
+
+// Hasher returns the SHA-256 digest of a remote file.
+type Hasher func(path string) (string, error)
+
+// Plan works out the actions that bring the target up to date with the
+// source, or both sides up to date with each other. Entries must already
+// be filtered. Paths present on both sides with the same content produce
+// no action.
+func Plan(local, remote []Entry, opts Options, hash Hasher) ([]Action, error) {
+	localByPath := make(map[string]*Entry, len(local))
+	remoteByPath := make(map[string]*Entry, len(remote))
+	paths := make([]string, 0, len(local)+len(remote))
+
+	for i := range local {
+		localByPath[local[i].Path] = &local[i]
+		paths = append(paths, local[i].Path)
+	}
+	for i := range remote {
+		if _, ok := localByPath[remote[i].Path]; !ok {
+			paths = append(paths, remote[i].Path)
+		}
+		remoteByPath[remote[i].Path] = &remote[i]
+	}
+	// Sorting by segments puts every directory right before its contents.
+	slices.SortFunc(paths, func(a, b string) int {
+		return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/"))
+	})
+
+	actions := []Action{}
+	settled := ""
+	for _, name := range paths {
+		// Nothing more to do inside a directory that is deleted or in
+		// conflict as a whole.
+		if settled != "" && strings.HasPrefix(name, settled+"/") {
+			continue
+		}
+		settled = ""
+
+		l, r := localByPath[name], remoteByPath[name]
+		action, err := decide(l, r, opts, hash)
+		if err != nil {
+			return nil, err
+		}
+		if action == nil {
+			continue
+		}
+
+		action.Path = name
+		action.Local, action.Remote = l, r
+		if action.Dir && (action.Type == ActionDeleteRemote || action.Type == ActionDeleteLocal || action.Type == ActionConflict) {
+			settled = name
+		}
+
+		actions = append(actions, *action)
+		if opts.MaxActions > 0 && len(actions) > opts.MaxActions {
+			return nil, ErrTooManyActions
+		}
+	}
+	return actions, nil
+}
+
+func decide(l, r *Entry, opts Options, hash Hasher) (*Action, error) {
+	upload := opts.Direction == DirectionUpload || opts.Direction == DirectionBoth
+	download := opts.Direction == DirectionDownload || opts.Direction == DirectionBoth
+
+	switch {
+	case r == nil && upload:
+		return copyAction(ActionUpload, ActionMkdirRemote, l.Dir), nil
+	case r == nil && opts.Delete:
+		return &Action{Type: ActionDeleteLocal, Reason: ReasonExtraneous, Dir: l.Dir}, nil
+	case l == nil && download:
+		return copyAction(ActionDownload, ActionMkdirLocal, r.Dir), nil
+	case l == nil && opts.Delete:
+		return &Action{Type: ActionDeleteRemote, Reason: ReasonExtraneous, Dir: r.Dir}, nil
+	case l == nil || r == nil:
+		return nil, nil
+	case l.Dir != r.Dir:
+		return &Action{Type: ActionConflict, Reason: ReasonTypeMismatch, Dir: true}, nil
+	case l.Dir:
+		return nil, nil
+	}
+
+	differ, err := differs(l, r, opts.Criteria, hash)
+	if err != nil || !differ {
+		return nil, err
+	}
+
+	age := l.ModTime.Sub(r.ModTime)
+	localNewer, remoteNewer := age > ModTimeTolerance, age < -ModTimeTolerance
+
+	switch {
+	case opts.Direction == DirectionBoth && localNewer:
+		return &Action{Type: ActionUpload, Reason: ReasonChanged}, nil
+	case opts.Direction == DirectionBoth && remoteNewer:
+		return &Action{Type: ActionDownload, Reason: ReasonChanged}, nil
+	case opts.Direction == DirectionBoth:
+		return &Action{Type: ActionConflict, Reason: ReasonSameTime}, nil
+	case upload && remoteNewer && !opts.Mirror, download && localNewer && !opts.Mirror:
+		return &Action{Type: ActionConflict, Reason: ReasonTargetNewer}, nil
+	case upload:
+		return &Action{Type: ActionUpload, Reason: ReasonChanged}, nil
+	default:
+		return &Action{Type: ActionDownload, Reason: ReasonChanged}, nil
+	}
+}
+
+func copyAction(file, dir string, isDir bool) *Action {
+	if isDir {
+		return &Action{Type: dir, Reason: ReasonMissing, Dir: true}
+	}
+	return &Action{Type: file, Reason: ReasonMissing}
+}
+
+func differs(l, r *Entry, criteria string, hash Hasher) (bool, error) {
+	if l.Size != r.Size {
+		return true, nil
+	}
+
+	switch criteria {
+	case CriteriaSize:
+		return false, nil
+	case CriteriaChecksum:
+		if r.SHA256 == "" {
+			sum, err := hash(r.Path)
+			if err != nil {
+				return false, err
+			}
+			r.SHA256 = sum
+		}
+		return !strings.EqualFold(l.SHA256, r.SHA256), nil
+	default:
+		age := l.ModTime.Sub(r.ModTime)
+		return age > ModTimeTolerance || age < -ModTimeTolerance, nil
+	}
+}
@@ -0,0 +1,161 @@
+package syncplan
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func file(name string, size int64, age time.Duration) Entry {
+	return Entry{Path: name, Size: size, ModTime: base.Add(age)}
+}
+
+func dir(name string) Entry {
+	return Entry{Path: name, Dir: true, ModTime: base}
+}
+
+func summary(actions []Action) map[string]string {
+	result := map[string]string{}
+	for _, action := range actions {
+		result[action.Path] = action.Type + ":" + action.Reason
+	}
+	return result
+}
+
+func expectActions(t *testing.T, actions []Action, want map[string]string) {
+	t.Helper()
+	got := summary(actions)
+	if len(got) != len(want) {
+		t.Errorf("Expected %d actions %v, got %v", len(want), want, got)
+	}
+	for name, action := range want {
+		if got[name] != action {
+			t.Errorf("Expected %s for %s, got %q", action, name, got[name])
+		}
+	}
+}
+
+func noHash(string) (string, error) {
+	return "", errors.New("unexpected hash")
+}
+
+func TestPlan_UploadWithDelete(t *testing.T) {
+	local := []Entry{
+		dir("src"),
+		file("src/new.go", 10, 0),
+		file("src/same.go", 20, 0),
+		file("src/edited.go", 30, time.Minute),
+		file("src/older.go", 40, -time.Minute),
+		dir("empty"),
+	}
+	remote := []Entry{
+		dir("src"),
+		file("src/same.go", 20, time.Second),
+		file("src/edited.go", 31, 0),
+		file("src/older.go", 41, 0),
+		file("src/stale.go", 5, 0),
+		dir("old"),
+		file("old/a.txt", 1, 0),
+		file("old/b.txt", 1, 0),
+	}
+
+	actions, err := Plan(local, remote, Options{Direction: DirectionUpload, Criteria: CriteriaModTime, Delete: true}, noHash)
+	if err != nil {
+		t.Fatalf("Expected a plan, got: %v", err)
+	}
+	expectActions(t, actions, map[string]string{
+		"empty":         "mkdir_remote:missing",
+		"old":           "delete_remote:extraneous",
+		"src/edited.go": "upload:changed",
+		"src/new.go":    "upload:missing",
+		"src/older.go":  "conflict:target_newer",
+		"src/stale.go":  "delete_remote:extraneous",
+	})
+
+	if actions[0].Path != "empty" || actions[1].Path != "old" {
+		t.Errorf("Expected actions in tree order, got %v", summary(actions))
+	}
+}
+
+func TestPlan_MirrorDownload(t *testing.T) {
+	local := []Entry{file("a.txt", 1, time.Hour), file("extra.txt", 1, 0)}
+	remote := []Entry{file("a.txt", 2, 0), dir("docs"), file("docs/b.txt", 3, 0)}
+
+	actions, err := Plan(local, remote, Options{Direction: DirectionDownload, Mirror: true}, noHash)
+	if err != nil {
+		t.Fatalf("Expected a plan, got: %v", err)
+	}
+	expectActions(t, actions, map[string]string{
+		"a.txt":      "download:changed",
+		"docs":       "mkdir_local:missing",
+		"docs/b.txt": "download:missing",
+	})
+}
+
+func TestPlan_BothDirections(t *testing.T) {
+	local := []Entry{file("mine.txt", 1, time.Minute), file("theirs.txt", 1, 0), file("tie.txt", 1, 0), file("only-local.txt", 1, 0)}
+	remote := []Entry{file("mine.txt", 2, 0), file("theirs.txt", 2, time.Minute), file("tie.txt", 2, time.Second), file("only-remote.txt", 1, 0)}
+
+	actions, err := Plan(local, remote, Options{Direction: DirectionBoth, Criteria: CriteriaSize}, noHash)
+	if err != nil {
+		t.Fatalf("Expected a plan, got: %v", err)
+	}
+	expectActions(t, actions, map[string]string{
+		"mine.txt":        "upload:changed",
+		"theirs.txt":      "download:changed",
+		"tie.txt":         "conflict:same_time",
+		"only-local.txt":  "upload:missing",
+		"only-remote.txt": "download:missing",
+	})
+}
+
+func TestPlan_TypeMismatchSkipsContents(t *testing.T) {
+	local := []Entry{file("data", 1, 0)}
+	remote := []Entry{dir("data"), file("data/x", 1, 0)}
+
+	actions, _ := Plan(local, remote, Options{Direction: DirectionUpload, Delete: true}, noHash)
+	expectActions(t, actions, map[string]string{"data": "conflict:type_mismatch"})
+}
+
+func TestPlan_Checksum(t *testing.T) {
+	local := []Entry{
+		{Path: "same", Size: 3, ModTime: base, SHA256: "AAA"},
+		{Path: "changed", Size: 3, ModTime: base, SHA256: "bbb"},
+		{Path: "resized", Size: 4, ModTime: base},
+	}
+	remote := []Entry{file("same", 3, time.Hour), file("changed", 3, 0), file("resized", 3, 0)}
+
+	hashed := []string{}
+	hash := func(name string) (string, error) {
+		hashed = append(hashed, name)
+		return map[string]string{"same": "aaa", "changed": "ccc"}[name], nil
+	}
+
+	actions, err := Plan(local, remote, Options{Direction: DirectionUpload, Criteria: CriteriaChecksum, Mirror: true}, hash)
+	if err != nil {
+		t.Fatalf("Expected a plan, got: %v", err)
+	}
+	expectActions(t, actions, map[string]string{"changed": "upload:changed", "resized": "upload:changed"})
+	if len(hashed) != 2 {
+		t.Errorf("Expected only same-size files to be hashed, got %v", hashed)
+	}
+
+	remote = []Entry{file("same", 3, 0)}
+	failing := func(string) (string, error) { return "", errors.New("read failed") }
+	if _, err := Plan(local, remote, Options{Direction: DirectionUpload, Criteria: CriteriaChecksum}, failing); err == nil {
+		t.Error("Expected hashing errors to be returned")
+	}
+}
+
+func TestPlan_MaxActions(t *testing.T) {
+	local := []Entry{file("a", 1, 0), file("b", 1, 0), file("c", 1, 0)}
+
+	if _, err := Plan(local, nil, Options{Direction: DirectionUpload, MaxActions: 2}, noHash); !errors.Is(err, ErrTooManyActions) {
+		t.Errorf("Expected ErrTooManyActions, got %v", err)
+	}
+	if _, err := Plan(local, nil, Options{Direction: DirectionUpload, MaxActions: 3}, noHash); err != nil {
+		t.Errorf("Expected a plan at the limit, got %v", err)
+	}
+}
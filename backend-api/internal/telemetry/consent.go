@@ -0,0 +1,267 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"livecode-api/middleware"
+
+	"go.uber.org/zap"
+)
+
+// ErrInstallSecret rejects a consent change without the secret issued to
+// the install.
+var ErrInstallSecret = errors.New("install secret missing or incorrect")
+
+type Consent struct {
+	InstallID string    `json:"install_id"`
+	Usage     bool      `json:"usage"`
+	Errors    bool      `json:"errors"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// SecretHash is the SHA-256 of the install's secret, or nil for an
+	// install that has not been issued one yet.
+	SecretHash []byte `json:"-"`
+}
+
+type ConsentUpdate struct {
+	Usage  *bool `json:"usage" binding:"required"`
+	Errors *bool `json:"errors" binding:"required"`
+}
+
+type ConsentResponse struct {
+	Success bool    `json:"success"`
+	Consent Consent `json:"consent"`
+	// InstallSecret is only sent when it is issued.
+	InstallSecret string `json:"install_secret,omitempty" description:"Issued on the install's first consent update and not shown again. Send it as X-Install-Secret to change consent later"`
+}
+
+type ConsentStore interface {
+	// LoadConsent returns the consent recorded for an install, reporting
+	// false if there is none.
+	LoadConsent(ctx context.Context, installID string) (Consent, bool, error)
+	// LoadRecentConsents returns up to limit installs, most recently
+	// updated first.
+	LoadRecentConsents(ctx context.Context, limit int) ([]Consent, error)
+	// SaveConsent records consent, including its SecretHash, if the stored
+	// secret hash is still previousHash. It reports false if it is not.
+	SaveConsent(ctx context.Context, consent Consent, userID string, previousHash []byte) (bool, error)
+}
+
+type ConsentCacheConfig struct {
+	TTL        time.Duration
+	MaxEntries int
+}
+
+type cachedConsent struct {
+	consent Consent
+	found   bool
+	expires time.Time
+}
+
+// ConsentRegistry answers opt-in lookups from a bounded cache in front of
+// the store. A change made on another server shows up once the cached
+// entry expires.
+type ConsentRegistry struct {
+	mu      sync.Mutex
+	cache   map[string]cachedConsent
+	pending map[string]bool
+	refresh chan string
+	done    chan struct{}
+	store   ConsentStore
+	cfg     ConsentCacheConfig
+	now     func() time.Time
+}
+
+func NewConsentRegistry(store ConsentStore, cfg ConsentCacheConfig) *ConsentRegistry {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 30 * time.Second
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+	r := &ConsentRegistry{
+		cache:   make(map[string]cachedConsent),
+		pending: make(map[string]bool),
+		refresh: make(chan string, 1024),
+		done:    make(chan struct{}),
+		store:   store,
+		cfg:     cfg,
+		now:     time.Now,
+	}
+	go r.refreshLoop()
+	return r
+}
+
+// Preload fills the cache with the most recently updated installs, so
+// their events are not turned away while the server warms up.
+func (r *ConsentRegistry) Preload(ctx context.Context) error {
+	consents, err := r.store.LoadRecentConsents(ctx, r.cfg.MaxEntries)
+	if err != nil {
+		return err
+	}
+	for _, consent := range consents {
+		r.remember(consent.InstallID, cachedConsent{consent: consent, found: true})
+	}
+	return nil
+}
+
+// Set records consent for an install. The first update issues the
+// install's secret, which Set returns; later ones must present it.
+func (r *ConsentRegistry) Set(ctx context.Context, consent Consent, secret, userID string) (string, error) {
+	current, found, err := r.store.LoadConsent(ctx, consent.InstallID)
+	if err != nil {
+		return "", err
+	}
+
+	var issued string
+	if found && current.SecretHash != nil {
+		sum := sha256.Sum256([]byte(secret))
+		if secret == "" || subtle.ConstantTimeCompare(sum[:], current.SecretHash) != 1 {
+			return "", ErrInstallSecret
+		}
+		consent.SecretHash = current.SecretHash
+	} else {
+		if issued, err = newInstallSecret(); err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256([]byte(issued))
+		consent.SecretHash = sum[:]
+	}
+
+	consent.UpdatedAt = r.now().UTC()
+	saved, err := r.store.SaveConsent(ctx, consent, userID, current.SecretHash)
+	if err != nil {
+		return "", err
+	}
+	if !saved {
+		// Another request issued the secret first.
+		return "", ErrInstallSecret
+	}
+
+	r.remember(consent.InstallID, cachedConsent{consent: consent, found: true})
+	return issued, nil
+}
+
+// Get returns the consent recorded for an install, reporting false if
+// there is none.
+func (r *ConsentRegistry) Get(ctx context.Context, installID string) (Consent, bool, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[installID]
+	r.mu.Unlock()
+	if ok && r.now().Before(cached.expires) {
+		return cached.consent, cached.found, nil
+	}
+
+	consent, found, err := r.store.LoadConsent(ctx, installID)
+	if err != nil {
+		return Consent{}, false, err
+	}
+	r.remember(installID, cachedConsent{consent: consent, found: found})
+	return consent, found, nil
+}
+
+// Allows reports whether the install has opted in to events of eventType.
+// It never waits for the store: an install missing from the cache is
+// treated as opted out, and an expired entry is still used, while either
+// is reloaded in the background.
+func (r *ConsentRegistry) Allows(installID, eventType string) bool {
+	r.mu.Lock()
+	cached, ok := r.cache[installID]
+	if !ok || !r.now().Before(cached.expires) {
+		r.queueRefresh(installID)
+	}
+	r.mu.Unlock()
+
+	if !ok || !cached.found {
+		return false
+	}
+	if eventType == EventTypeError {
+		return cached.consent.Errors
+	}
+	return cached.consent.Usage
+}
+
+// queueRefresh asks for the install to be reloaded, unless it already is
+// or the queue is full. r.mu must be held.
+func (r *ConsentRegistry) queueRefresh(installID string) {
+	if r.pending[installID] {
+		return
+	}
+	select {
+	case r.refresh <- installID:
+		r.pending[installID] = true
+	default:
+	}
+}
+
+func (r *ConsentRegistry) refreshLoop() {
+	for {
+		var installID string
+		select {
+		case <-r.done:
+			return
+		case installID = <-r.refresh:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		consent, found, err := r.store.LoadConsent(ctx, installID)
+		cancel()
+
+		if err != nil {
+			middleware.Logger.Warn("telemetry_consent_refresh_failed",
+				zap.String("install_id", installID),
+				zap.Error(err),
+			)
+		} else {
+			r.remember(installID, cachedConsent{consent: consent, found: found})
+		}
+
+		r.mu.Lock()
+		delete(r.pending, installID)
+		r.mu.Unlock()
+	}
+}
+
+// Close stops reloading installs in the background.
+func (r *ConsentRegistry) Close(ctx context.Context) error {
+	close(r.done)
+	return nil
+}
+
+func (r *ConsentRegistry) remember(installID string, entry cachedConsent) {
+	now := r.now()
+	entry.expires = now.Add(r.cfg.TTL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.cache[installID]; !ok && len(r.cache) >= r.cfg.MaxEntries {
+		for id, cached := range r.cache {
+			if !now.Before(cached.expires) {
+				delete(r.cache, id)
+			}
+		}
+		// Still full: drop any entry, which only costs a lookup later.
+		for id := range r.cache {
+			if len(r.cache) < r.cfg.MaxEntries {
+				break
+			}
+			delete(r.cache, id)
+		}
+	}
+	r.cache[installID] = entry
+}
+
+func newInstallSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
@@ -0,0 +1,82 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+	ErrBodyTooLarge        = errors.New("decompressed body exceeds size limit")
+)
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+type decodedBody struct {
+	io.Reader
+	close func()
+}
+
+func (d decodedBody) Close() error {
+	if d.close != nil {
+		d.close()
+	}
+	return nil
+}
+
+// NewBodyReader undoes the request's Content-Encoding and caps the number of
+// decompressed bytes, so a small compressed body cannot expand unbounded.
+func NewBodyReader(body io.Reader, contentEncoding string, maxDecodedBytes int64) (io.ReadCloser, error) {
+	encoding := strings.ToLower(strings.TrimSpace(contentEncoding))
+
+	switch encoding {
+	case "", "identity":
+		return decodedBody{Reader: &limitedReader{r: body, remaining: maxDecodedBytes}}, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		return decodedBody{
+			Reader: &limitedReader{r: gz, remaining: maxDecodedBytes},
+			close:  func() { gz.Close() },
+		}, nil
+	case "zstd":
+		zr, err := zstd.NewReader(body, zstd.WithDecoderMaxMemory(uint64(maxDecodedBytes)), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd stream: %w", err)
+		}
+		return decodedBody{
+			Reader: &limitedReader{r: zr, remaining: maxDecodedBytes},
+			close:  zr.Close,
+		}, nil
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+}
@@ -0,0 +1,205 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	EventTypeError        = "error"
+	EventTypePerformance  = "performance"
+	EventTypeFeatureUsage = "feature_usage"
+)
+
+const (
+	maxLineBytes    = 64 << 10
+	maxPayloadBytes = 16 << 10
+)
+
+var supportedSchemaVersions = map[string][]int{
+	EventTypeError:        {1},
+	EventTypePerformance:  {1},
+	EventTypeFeatureUsage: {1},
+}
+
+var ErrTooManyEvents = errors.New("batch exceeds maximum event count")
+
+type Event struct {
+	SchemaVersion int             `json:"schema_version"`
+	Type          string          `json:"type"`
+	Name          string          `json:"name"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	AppVersion    string          `json:"app_version"`
+	OS            string          `json:"os"`
+	SessionID     string          `json:"session_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+
+	InstallID  string    `json:"-"`
+	UserID     string    `json:"-"`
+	SampleRate float64   `json:"-"`
+	ReceivedAt time.Time `json:"-"`
+}
+
+type ErrorPayload struct {
+	ErrorType    string          `json:"error_type"`
+	ErrorMessage string          `json:"error_message"`
+	StackTrace   string          `json:"stack_trace,omitempty"`
+	RequestID    string          `json:"request_id,omitempty"`
+	Breadcrumbs  json.RawMessage `json:"breadcrumbs,omitempty"`
+}
+
+type PerformancePayload struct {
+	DurationMs float64           `json:"duration_ms"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+type FeatureUsagePayload struct {
+	Action string            `json:"action"`
+	Tags   map[string]string `json:"tags,omitempty"`
+}
+
+type LineError struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+func DecodeBatch(r io.Reader, maxEvents int) ([]Event, []LineError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+
+	events := []Event{}
+	lineErrors := []LineError{}
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		if len(events)+len(lineErrors) >= maxEvents {
+			return nil, nil, ErrTooManyEvents
+		}
+
+		var event Event
+		if err := json.Unmarshal(raw, &event); err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Reason: "invalid JSON"})
+			continue
+		}
+
+		if err := event.Validate(); err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Reason: err.Error()})
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, nil, fmt.Errorf("line %d exceeds %d bytes", line+1, maxLineBytes)
+		}
+		return nil, nil, err
+	}
+
+	return events, lineErrors, nil
+}
+
+func (e *Event) Validate() error {
+	versions, ok := supportedSchemaVersions[e.Type]
+	if !ok {
+		return fmt.Errorf("unsupported event type %q", e.Type)
+	}
+
+	if !containsInt(versions, e.SchemaVersion) {
+		return fmt.Errorf("unsupported schema_version %d for type %s", e.SchemaVersion, e.Type)
+	}
+
+	if e.Name == "" || len(e.Name) > 100 {
+		return errors.New("name is required and must not exceed 100 characters")
+	}
+
+	if e.OccurredAt.IsZero() || e.OccurredAt.After(time.Now().Add(24*time.Hour)) {
+		return errors.New("occurred_at is missing or in the future")
+	}
+
+	if e.AppVersion == "" || len(e.AppVersion) > 50 {
+		return errors.New("app_version is required and must not exceed 50 characters")
+	}
+
+	if e.OS == "" || len(e.OS) > 20 {
+		return errors.New("os is required and must not exceed 20 characters")
+	}
+
+	if len(e.SessionID) > 64 {
+		return errors.New("session_id must not exceed 64 characters")
+	}
+
+	if len(e.Payload) > maxPayloadBytes {
+		return fmt.Errorf("payload exceeds %d bytes", maxPayloadBytes)
+	}
+
+	if strings.Contains(string(e.Payload), `\u0000`) || strings.ContainsRune(e.Name, 0) {
+		return errors.New("invalid characters detected")
+	}
+
+	return e.validatePayload()
+}
+
+func (e *Event) validatePayload() error {
+	if len(e.Payload) == 0 {
+		e.Payload = json.RawMessage("{}")
+	}
+
+	switch e.Type {
+	case EventTypeError:
+		var payload ErrorPayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return errors.New("error payload is malformed")
+		}
+		if payload.ErrorType == "" || len(payload.ErrorType) > 100 {
+			return errors.New("payload.error_type is required and must not exceed 100 characters")
+		}
+		if payload.ErrorMessage == "" || len(payload.ErrorMessage) > 1000 {
+			return errors.New("payload.error_message is required and must not exceed 1000 characters")
+		}
+		if len(payload.StackTrace) > 20000 {
+			return errors.New("payload.stack_trace must not exceed 20000 characters")
+		}
+	case EventTypePerformance:
+		var payload PerformancePayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return errors.New("performance payload is malformed")
+		}
+		if payload.DurationMs < 0 {
+			return errors.New("payload.duration_ms must not be negative")
+		}
+	case EventTypeFeatureUsage:
+		var payload FeatureUsagePayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return errors.New("feature usage payload is malformed")
+		}
+		if len(payload.Action) > 100 {
+			return errors.New("payload.action must not exceed 100 characters")
+		}
+	}
+
+	return nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
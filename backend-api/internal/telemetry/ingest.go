@@ -0,0 +1,65 @@
+package telemetry
+
+import (
+	"time"
+
+	"livecode-api/internal/metrics"
+)
+
+type Ingestor struct {
+	Sampler  *Sampler
+	Consents *ConsentRegistry
+	Sink     *Sink
+}
+
+type IngestResult struct {
+	Accepted     int         `json:"accepted"`
+	SampledOut   int         `json:"sampled_out"`
+	NotConsented int         `json:"not_consented"`
+	Dropped      int         `json:"dropped"`
+	Rejected     []LineError `json:"rejected"`
+}
+
+type IngestResponse struct {
+	Success bool         `json:"success"`
+	Result  IngestResult `json:"result"`
+}
+
+func (i *Ingestor) Ingest(events []Event, rejected []LineError, installID, userID string) IngestResult {
+	result := IngestResult{Rejected: rejected}
+	receivedAt := time.Now().UTC()
+
+	for range rejected {
+		metrics.TelemetryEventsTotal.WithLabelValues("unknown", "invalid").Inc()
+	}
+
+	for _, event := range events {
+		event.InstallID = installID
+		event.UserID = userID
+		event.ReceivedAt = receivedAt
+		event.SampleRate = i.Sampler.Rate(event.Type)
+
+		if !i.Consents.Allows(installID, event.Type) {
+			result.NotConsented++
+			metrics.TelemetryEventsTotal.WithLabelValues(event.Type, "not_consented").Inc()
+			continue
+		}
+
+		if !i.Sampler.Keep(event) {
+			result.SampledOut++
+			metrics.TelemetryEventsTotal.WithLabelValues(event.Type, "sampled_out").Inc()
+			continue
+		}
+
+		if !i.Sink.Enqueue(event) {
+			result.Dropped++
+			metrics.TelemetryEventsTotal.WithLabelValues(event.Type, "dropped").Inc()
+			continue
+		}
+
+		result.Accepted++
+		metrics.TelemetryEventsTotal.WithLabelValues(event.Type, "accepted").Inc()
+	}
+
+	return result
+}
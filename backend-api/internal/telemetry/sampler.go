@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+type Sampler struct {
+	rates map[string]float64
+}
+
+func NewSampler(rates map[string]float64) *Sampler {
+	normalized := map[string]float64{
+		EventTypeError:        1,
+		EventTypePerformance:  1,
+		EventTypeFeatureUsage: 1,
+	}
+
+	for eventType, rate := range rates {
+		if rate < 0 {
+			rate = 0
+		}
+		if rate > 1 {
+			rate = 1
+		}
+		normalized[eventType] = rate
+	}
+
+	return &Sampler{rates: normalized}
+}
+
+func (s *Sampler) Rate(eventType string) float64 {
+	rate, ok := s.rates[eventType]
+	if !ok {
+		return 1
+	}
+	return rate
+}
+
+// Keep hashes the event identity instead of drawing a random number so that a
+// client retrying the same batch gets the same sampling decision.
+func (s *Sampler) Keep(event Event) bool {
+	rate := s.Rate(event.Type)
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(event.InstallID))
+	h.Write([]byte{0})
+	h.Write([]byte(event.Type))
+	h.Write([]byte{0})
+	h.Write([]byte(event.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(event.OccurredAt.UnixNano(), 10)))
+
+	return float64(h.Sum64()%1_000_000)/1_000_000 < rate
+}
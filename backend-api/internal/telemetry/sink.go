@@ -0,0 +1,153 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"livecode-api/internal/metrics"
+	"livecode-api/middleware"
+
+	"go.uber.org/zap"
+)
+
+type Writer interface {
+	WriteEvents(ctx context.Context, events []Event) error
+}
+
+type SinkConfig struct {
+	BufferSize    int
+	BatchSize     int
+	Workers       int
+	FlushInterval time.Duration
+}
+
+type Sink struct {
+	cfg    SinkConfig
+	writer Writer
+	events chan Event
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewSink(writer Writer, cfg SinkConfig) *Sink {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 2 * time.Second
+	}
+
+	s := &Sink{
+		cfg:    cfg,
+		writer: writer,
+		events: make(chan Event, cfg.BufferSize),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		s.wg.Add(1)
+		go s.run()
+	}
+
+	return s
+}
+
+func (s *Sink) Enqueue(event Event) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return false
+	}
+
+	select {
+	case s.events <- event:
+		metrics.TelemetrySinkQueueDepth.Set(float64(len(s.events)))
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Sink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Sink) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, s.cfg.BatchSize)
+
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+
+			batch = append(batch, event)
+			if len(batch) >= s.cfg.BatchSize {
+				s.flush(batch)
+				batch = make([]Event, 0, s.cfg.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = make([]Event, 0, s.cfg.BatchSize)
+			}
+		}
+	}
+}
+
+func (s *Sink) flush(batch []Event) {
+	metrics.TelemetrySinkQueueDepth.Set(float64(len(s.events)))
+
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := s.writer.WriteEvents(ctx, batch)
+	metrics.TelemetrySinkFlushDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		metrics.TelemetrySinkWriteFailuresTotal.Add(float64(len(batch)))
+		middleware.Logger.Error("telemetry_sink_write_failed",
+			zap.Int("events", len(batch)),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,272 @@
+package telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const sampleBatch = `{"schema_version":1,"type":"performance","name":"sftp.list","occurred_at":"2026-01-02T10:00:00Z","app_version":"1.4.0","os":"windows","payload":{"duration_ms":120}}
+{"schema_version":1,"type":"feature_usage","name":"sync","occurred_at":"2026-01-02T10:00:01Z","app_version":"1.4.0","os":"windows","payload":{"action":"dry_run"}}
+{"schema_version":9,"type":"performance","name":"future","occurred_at":"2026-01-02T10:00:02Z","app_version":"1.4.0","os":"windows"}
+not json
+`
+
+func TestDecodeBatch_CompressedBodies(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(sampleBatch))
+	gz.Close()
+
+	encoder, _ := zstd.NewWriter(nil)
+	zstdBody := encoder.EncodeAll([]byte(sampleBatch), nil)
+
+	cases := map[string][]byte{
+		"":     []byte(sampleBatch),
+		"gzip": gzipped.Bytes(),
+		"zstd": zstdBody,
+	}
+
+	for encoding, body := range cases {
+		reader, err := NewBodyReader(bytes.NewReader(body), encoding, 1<<20)
+		if err != nil {
+			t.Fatalf("%q: expected no error, got: %v", encoding, err)
+		}
+
+		events, rejected, err := DecodeBatch(reader, 100)
+		reader.Close()
+
+		if err != nil {
+			t.Fatalf("%q: expected no error, got: %v", encoding, err)
+		}
+
+		if len(events) != 2 {
+			t.Errorf("%q: expected 2 events, got: %d", encoding, len(events))
+		}
+
+		if len(rejected) != 2 || rejected[0].Line != 3 || rejected[1].Line != 4 {
+			t.Errorf("%q: expected lines 3 and 4 rejected, got: %+v", encoding, rejected)
+		}
+	}
+}
+
+func TestNewBodyReader_LimitsDecompressedSize(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(bytes.Repeat([]byte("a"), 1<<20))
+	gz.Close()
+
+	reader, err := NewBodyReader(bytes.NewReader(gzipped.Bytes()), "gzip", 1024)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	_, err = io.ReadAll(reader)
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge, got: %v", err)
+	}
+
+	if _, err := NewBodyReader(strings.NewReader(""), "br", 1024); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got: %v", err)
+	}
+}
+
+type memoryStore struct {
+	mu       sync.Mutex
+	consents map[string]Consent
+	loads    int
+	written  []Event
+}
+
+func (m *memoryStore) LoadConsent(ctx context.Context, installID string) (Consent, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loads++
+	consent, ok := m.consents[installID]
+	return consent, ok, nil
+}
+
+func (m *memoryStore) SaveConsent(ctx context.Context, consent Consent, userID string, previousHash []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !bytes.Equal(m.consents[consent.InstallID].SecretHash, previousHash) {
+		return false, nil
+	}
+	if m.consents == nil {
+		m.consents = make(map[string]Consent)
+	}
+	m.consents[consent.InstallID] = consent
+	return true, nil
+}
+
+func (m *memoryStore) LoadRecentConsents(ctx context.Context, limit int) ([]Consent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var consents []Consent
+	for _, consent := range m.consents {
+		consents = append(consents, consent)
+	}
+	return consents[:min(limit, len(consents))], nil
+}
+
+func (m *memoryStore) setConsent(consent Consent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.consents[consent.InstallID] = consent
+}
+
+func (m *memoryStore) loadCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.loads
+}
+
+func (m *memoryStore) WriteEvents(ctx context.Context, events []Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.written = append(m.written, events...)
+	return nil
+}
+
+func TestIngestor_RespectsConsentAndSampling(t *testing.T) {
+	store := &memoryStore{consents: map[string]Consent{
+		"usage-install":       {InstallID: "usage-install", Usage: true, Errors: true},
+		"errors-only-install": {InstallID: "errors-only-install", Usage: false, Errors: true},
+	}}
+
+	consents := NewConsentRegistry(store, ConsentCacheConfig{})
+	defer consents.Close(context.Background())
+	if err := consents.Preload(context.Background()); err != nil {
+		t.Fatalf("Failed to preload consents: %v", err)
+	}
+
+	ingestor := &Ingestor{
+		Sampler:  NewSampler(map[string]float64{EventTypeFeatureUsage: 0}),
+		Consents: consents,
+		Sink:     NewSink(store, SinkConfig{BufferSize: 10, FlushInterval: time.Hour}),
+	}
+
+	events := []Event{
+		{Type: EventTypePerformance, Name: "a", OccurredAt: time.Now()},
+		{Type: EventTypeFeatureUsage, Name: "b", OccurredAt: time.Now()},
+	}
+
+	result := ingestor.Ingest(events, nil, "usage-install", "")
+	if result.Accepted != 1 || result.SampledOut != 1 {
+		t.Errorf("Expected 1 accepted and 1 sampled out, got: %+v", result)
+	}
+
+	result = ingestor.Ingest(events, nil, "errors-only-install", "")
+	if result.NotConsented != 2 {
+		t.Errorf("Expected 2 events without consent, got: %+v", result)
+	}
+
+	result = ingestor.Ingest(events, nil, "unknown-install", "")
+	if result.NotConsented != 2 {
+		t.Errorf("Expected unknown installs to be treated as opted out, got: %+v", result)
+	}
+
+	if err := ingestor.Sink.Close(context.Background()); err != nil {
+		t.Fatalf("Expected sink to drain, got: %v", err)
+	}
+
+	if len(store.written) != 1 || store.written[0].InstallID != "usage-install" {
+		t.Errorf("Expected one event written for usage-install, got: %+v", store.written)
+	}
+
+	if ingestor.Sink.Enqueue(events[0]) {
+		t.Error("Expected enqueue after close to be refused")
+	}
+}
+
+func TestConsentRegistry_RequiresIssuedSecret(t *testing.T) {
+	ctx := context.Background()
+	consents := NewConsentRegistry(&memoryStore{}, ConsentCacheConfig{})
+	defer consents.Close(ctx)
+
+	secret, err := consents.Set(ctx, Consent{InstallID: "install", Usage: true}, "", "")
+	if err != nil || secret == "" {
+		t.Fatalf("Expected a secret on first contact, got: %q, %v", secret, err)
+	}
+
+	if _, err := consents.Set(ctx, Consent{InstallID: "install"}, "", ""); !errors.Is(err, ErrInstallSecret) {
+		t.Errorf("Expected a missing secret to be rejected, got: %v", err)
+	}
+
+	if _, err := consents.Set(ctx, Consent{InstallID: "install"}, "wrong", ""); !errors.Is(err, ErrInstallSecret) {
+		t.Errorf("Expected a wrong secret to be rejected, got: %v", err)
+	}
+
+	issued, err := consents.Set(ctx, Consent{InstallID: "install", Errors: true}, secret, "")
+	if err != nil || issued != "" {
+		t.Fatalf("Expected the secret to be accepted without a new one, got: %q, %v", issued, err)
+	}
+
+	if !consents.Allows("install", EventTypeError) || consents.Allows("install", EventTypePerformance) {
+		t.Error("Expected the latest consent to apply")
+	}
+}
+
+// eventually waits for the registry's background refresh.
+func eventually(t *testing.T, condition func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConsentRegistry_LookupsNeverWaitForTheStore(t *testing.T) {
+	store := &memoryStore{consents: map[string]Consent{
+		"a": {InstallID: "a", Usage: true},
+	}}
+	consents := NewConsentRegistry(store, ConsentCacheConfig{TTL: time.Minute, MaxEntries: 2})
+	defer consents.Close(context.Background())
+
+	var clock sync.Mutex
+	now := time.Now()
+	consents.now = func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return now
+	}
+
+	if consents.Allows("a", EventTypePerformance) {
+		t.Error("Expected an uncached install to be treated as opted out")
+	}
+	if !eventually(t, func() bool { return consents.Allows("a", EventTypePerformance) }) {
+		t.Fatal("Expected the install to be loaded in the background")
+	}
+
+	store.setConsent(Consent{InstallID: "a"})
+	if !consents.Allows("a", EventTypePerformance) || store.loadCount() != 1 {
+		t.Errorf("Expected the cached consent to be used, got %d loads", store.loadCount())
+	}
+
+	clock.Lock()
+	now = now.Add(2 * time.Minute)
+	clock.Unlock()
+	if !eventually(t, func() bool { return !consents.Allows("a", EventTypePerformance) }) {
+		t.Error("Expected a change from another server to apply after the TTL")
+	}
+
+	for _, id := range []string{"b", "c", "d"} {
+		consents.Allows(id, EventTypePerformance)
+	}
+	eventually(t, func() bool { return store.loadCount() == 5 })
+	consents.mu.Lock()
+	defer consents.mu.Unlock()
+	if len(consents.cache) > 2 {
+		t.Errorf("Expected at most 2 cached installs, got: %d", len(consents.cache))
+	}
+}
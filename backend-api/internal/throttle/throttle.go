@@ -0,0 +1,134 @@
+// Package throttle limits the bandwidth of transfers with token buckets
+// whose rates can change while they are in use, following time-of-day
+// schedules.
+package throttle
+
+import (
+	"context"
+	"io"
+	"slices"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// burst is the most a bucket hands out at once, and so the size of the
+// reads a throttled Reader makes.
+const burst = 64 << 10
+
+// Bucket is a token bucket of bytes. A rate of 0 means no limit.
+type Bucket struct {
+	limiter *rate.Limiter
+}
+
+func NewBucket(bytesPerSecond int64) *Bucket {
+	b := &Bucket{limiter: rate.NewLimiter(rate.Inf, burst)}
+	b.SetRate(bytesPerSecond)
+	return b
+}
+
+// SetRate changes the rate. Reads already waiting keep the delay they were
+// given; later ones use the new rate.
+func (b *Bucket) SetRate(bytesPerSecond int64) {
+	limit := rate.Inf
+	if bytesPerSecond > 0 {
+		limit = rate.Limit(bytesPerSecond)
+	}
+	if b.limiter.Limit() != limit {
+		b.limiter.SetLimit(limit)
+	}
+}
+
+// Rate returns the rate in bytes per second, or 0 if there is no limit.
+func (b *Bucket) Rate() int64 {
+	limit := b.limiter.Limit()
+	if limit == rate.Inf {
+		return 0
+	}
+	return int64(limit)
+}
+
+type reader struct {
+	ctx     context.Context
+	r       io.Reader
+	buckets []*Bucket
+}
+
+// Reader limits reads from r to the rate of the slowest of buckets. Nil
+// buckets are ignored.
+func Reader(ctx context.Context, r io.Reader, buckets ...*Bucket) io.Reader {
+	active := make([]*Bucket, 0, len(buckets))
+	for _, b := range buckets {
+		if b != nil {
+			active = append(active, b)
+		}
+	}
+	return &reader{ctx: ctx, r: r, buckets: active}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.r.Read(p)
+	for _, b := range r.buckets {
+		if n == 0 {
+			break
+		}
+		if waitErr := b.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, context.Cause(r.ctx)
+		}
+	}
+	return n, err
+}
+
+// Window caps bandwidth during part of the day. A window whose End is
+// before its Start runs past midnight, into the next day.
+type Window struct {
+	// Days has the weekdays the window starts on. Empty means every day.
+	Days  []time.Weekday
+	Start time.Duration
+	End   time.Duration
+	Limit int64
+}
+
+// Schedule is a limit that changes with the time of day.
+type Schedule struct {
+	Location *time.Location
+	// Default applies outside the windows.
+	Default int64
+	Windows []Window
+}
+
+// Limit returns the limit at t: that of the first window t falls in, or
+// the default.
+func (s Schedule) Limit(t time.Time) int64 {
+	if s.Location != nil {
+		t = t.In(s.Location)
+	}
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, w := range s.Windows {
+		switch {
+		case w.Start < w.End:
+			if w.on(today) && now >= w.Start && now < w.End {
+				return w.Limit
+			}
+		case w.Start > w.End:
+			if (w.on(today) && now >= w.Start) || (w.on(yesterday) && now < w.End) {
+				return w.Limit
+			}
+		default:
+			if w.on(today) {
+				return w.Limit
+			}
+		}
+	}
+	return s.Default
+}
+
+func (w Window) on(day time.Weekday) bool {
+	return len(w.Days) == 0 || slices.Contains(w.Days, day)
+}
@@ -0,0 +1,81 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Limit(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+	schedule := Schedule{
+		Location: berlin,
+		Default:  0,
+		Windows: []Window{
+			{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Start: 9 * time.Hour, End: 18 * time.Hour, Limit: 1 << 20},
+			{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 2 * time.Hour, Limit: 5 << 20},
+		},
+	}
+
+	tests := []struct {
+		at   string
+		want int64
+	}{
+		{"2026-10-19T10:00:00+02:00", 1 << 20}, // Monday, business hours
+		{"2026-10-19T18:00:00+02:00", 0},
+		{"2026-10-19T08:00:00Z", 1 << 20}, // 10:00 in Berlin
+		{"2026-10-18T10:00:00+02:00", 0},  // Sunday
+		{"2026-10-23T23:00:00+02:00", 5 << 20},
+		{"2026-10-24T01:30:00+02:00", 5 << 20}, // Saturday, but the window started on Friday
+		{"2026-10-25T01:30:00+02:00", 0},
+	}
+
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := schedule.Limit(at); got != tt.want {
+			t.Errorf("Limit(%s): expected %d, got %d", tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestReader_Throttles(t *testing.T) {
+	bucket := NewBucket(256 << 10)
+	data := bytes.Repeat([]byte("x"), 192<<10)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, Reader(context.Background(), bytes.NewReader(data), bucket, nil))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("Expected all data, got %d bytes: %v", n, err)
+	}
+	// The first 64 KiB come from the full bucket, the rest at 256 KiB/s.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the copy to be throttled, took %v", elapsed)
+	}
+
+	bucket.SetRate(0)
+	if bucket.Rate() != 0 {
+		t.Errorf("Expected no limit, got %d", bucket.Rate())
+	}
+	start = time.Now()
+	io.Copy(io.Discard, Reader(context.Background(), bytes.NewReader(data), bucket))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected an unthrottled copy, took %v", elapsed)
+	}
+}
+
+func TestReader_StopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bucket := NewBucket(1024)
+	r := Reader(ctx, bytes.NewReader(make([]byte, 1<<20)), bucket)
+
+	io.CopyN(io.Discard, r, 64<<10)
+	cancel()
+	if _, err := r.Read(make([]byte, 1024)); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
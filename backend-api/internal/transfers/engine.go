@@ -0,0 +1,347 @@
+// Package transfers runs queued file-transfer jobs on a pool of workers.
+// Jobs live in a Store, so they survive restarts and can be claimed by any
+// server; the engine only decides what runs when.
+package transfers
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"livecode-api/middleware"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInterrupted stops a job that was paused or canceled while running.
+	ErrInterrupted = errors.New("transfer interrupted")
+	// ErrShutdown stops jobs when the engine closes. They are queued again.
+	ErrShutdown = errors.New("server shutting down")
+)
+
+type Job struct {
+	ID     string
+	UserID string
+	// Attempts counts the current run.
+	Attempts int
+}
+
+type Store interface {
+	// Claim marks up to limit due jobs as running and returns them, never
+	// letting a user have more than perUser jobs running.
+	Claim(ctx context.Context, limit, perUser int) ([]Job, error)
+	// Heartbeat marks jobs as still running on this server.
+	Heartbeat(ctx context.Context, ids []string) error
+	// RequeueStale queues running jobs whose last heartbeat is before
+	// cutoff, left behind by a server that did not shut down cleanly. Jobs
+	// that have already had maxAttempts fail instead.
+	RequeueStale(ctx context.Context, cutoff time.Time, maxAttempts int) (requeued, failed int, err error)
+	// Requeue puts a job back in the queue without counting the attempt.
+	Requeue(ctx context.Context, job Job, reason string) error
+	Retry(ctx context.Context, job Job, cause error, at time.Time) error
+	Fail(ctx context.Context, job Job, cause error) error
+	Complete(ctx context.Context, job Job) error
+}
+
+// Runner carries out a job. It should return promptly once ctx is done.
+type Runner func(ctx context.Context, job Job) error
+
+type Config struct {
+	Workers           int
+	PerUser           int
+	MaxAttempts       int
+	PollInterval      time.Duration
+	HeartbeatInterval time.Duration
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that retrying will not fix.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	return errors.As(err, &permanentError{})
+}
+
+type Engine struct {
+	cfg   Config
+	store Store
+	run   Runner
+
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc
+	closed  bool
+
+	wake     chan struct{}
+	stop     chan struct{}
+	loopDone chan struct{}
+	jobs     sync.WaitGroup
+}
+
+func NewEngine(store Store, run Runner, cfg Config) *Engine {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.PerUser <= 0 {
+		cfg.PerUser = 2
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 15 * time.Second
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = 5 * time.Second
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = 5 * time.Minute
+	}
+
+	e := &Engine{
+		cfg:      cfg,
+		store:    store,
+		run:      run,
+		running:  map[string]context.CancelCauseFunc{},
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		loopDone: make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+// Notify wakes the engine to look for queued jobs without waiting for the
+// next poll.
+func (e *Engine) Notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Interrupt stops a job running on this server, after its status has been
+// changed in the store. It reports whether the job was running here.
+func (e *Engine) Interrupt(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cancel, ok := e.running[id]
+	if ok {
+		cancel(ErrInterrupted)
+	}
+	return ok
+}
+
+// Running returns the number of jobs running on this server.
+func (e *Engine) Running() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.running)
+}
+
+// Close stops claiming jobs, interrupts the running ones and waits for them
+// to be queued again.
+func (e *Engine) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	close(e.stop)
+	e.mu.Unlock()
+
+	<-e.loopDone
+
+	e.mu.Lock()
+	for _, cancel := range e.running {
+		cancel(ErrShutdown)
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Engine) loop() {
+	defer close(e.loopDone)
+
+	poll := time.NewTicker(e.cfg.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(e.cfg.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	e.requeueStale()
+	for {
+		e.dispatch()
+
+		select {
+		case <-e.stop:
+			return
+		case <-e.wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			e.heartbeat()
+			e.requeueStale()
+		}
+	}
+}
+
+func (e *Engine) dispatch() {
+	free := e.cfg.Workers - e.Running()
+	if free <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	jobs, err := e.store.Claim(ctx, free, e.cfg.PerUser)
+	if err != nil {
+		middleware.Logger.Error("transfer_claim_failed",
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, job := range jobs {
+		e.start(job)
+	}
+}
+
+func (e *Engine) start(job Job) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	e.mu.Lock()
+	e.running[job.ID] = cancel
+	e.jobs.Add(1)
+	e.mu.Unlock()
+
+	go func() {
+		defer e.jobs.Done()
+
+		err := e.run(ctx, job)
+		e.finish(ctx, job, err)
+
+		e.mu.Lock()
+		delete(e.running, job.ID)
+		e.mu.Unlock()
+		cancel(nil)
+		e.Notify()
+	}()
+}
+
+func (e *Engine) finish(jobCtx context.Context, job Job, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cause := context.Cause(jobCtx)
+	switch {
+	case err == nil:
+		err = e.store.Complete(ctx, job)
+	case errors.Is(cause, ErrShutdown):
+		err = e.store.Requeue(ctx, job, ErrShutdown.Error())
+	case errors.Is(cause, ErrInterrupted), errors.Is(err, ErrInterrupted):
+		return
+	case IsPermanent(err) || job.Attempts >= e.cfg.MaxAttempts:
+		middleware.Logger.Warn("transfer_failed",
+			zap.String("transfer_id", job.ID),
+			zap.Int("attempts", job.Attempts),
+			zap.Error(err),
+		)
+		err = e.store.Fail(ctx, job, err)
+	default:
+		delay := e.backoff(job.Attempts)
+		middleware.Logger.Info("transfer_retry_scheduled",
+			zap.String("transfer_id", job.ID),
+			zap.Int("attempts", job.Attempts),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+		err = e.store.Retry(ctx, job, err, time.Now().Add(delay))
+	}
+
+	if err != nil {
+		middleware.Logger.Error("transfer_finish_failed",
+			zap.String("transfer_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// backoff doubles the delay with every attempt, up to BackoffMax, and picks
+// a random point in its upper half so retries of many jobs spread out.
+func (e *Engine) backoff(attempts int) time.Duration {
+	delay := e.cfg.BackoffMax
+	if attempts < 30 {
+		delay = min(e.cfg.BackoffBase<<max(attempts-1, 0), e.cfg.BackoffMax)
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (e *Engine) heartbeat() {
+	e.mu.Lock()
+	ids := make([]string, 0, len(e.running))
+	for id := range e.running {
+		ids = append(ids, id)
+	}
+	e.mu.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := e.store.Heartbeat(ctx, ids); err != nil {
+		middleware.Logger.Error("transfer_heartbeat_failed",
+			zap.Error(err),
+		)
+	}
+}
+
+func (e *Engine) requeueStale() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	requeued, failed, err := e.store.RequeueStale(ctx, time.Now().Add(-4*e.cfg.HeartbeatInterval), e.cfg.MaxAttempts)
+	if err != nil {
+		middleware.Logger.Error("transfer_requeue_failed",
+			zap.Error(err),
+		)
+		return
+	}
+	if requeued > 0 || failed > 0 {
+		middleware.Logger.Warn("transfer_stale_jobs_requeued",
+			zap.Int("count", requeued),
+			zap.Int("failed", failed),
+		)
+	}
+}
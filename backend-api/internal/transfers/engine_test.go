@@ -0,0 +1,246 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"livecode-api/middleware"
+
+	"go.uber.org/zap"
+)
+
+type memoryJob struct {
+	job    Job
+	status string
+	due    time.Time
+	events []string
+}
+
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs []*memoryJob
+}
+
+func (s *memoryStore) add(id, userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, &memoryJob{job: Job{ID: id, UserID: userID}, status: "queued"})
+}
+
+func (s *memoryStore) find(id string) *memoryJob {
+	for _, job := range s.jobs {
+		if job.job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) status(id string) (string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.find(id)
+	return job.status, append([]string(nil), job.events...)
+}
+
+func (s *memoryStore) move(id, status string) {
+	job := s.find(id)
+	job.status = status
+	job.events = append(job.events, status)
+}
+
+func (s *memoryStore) Claim(_ context.Context, limit, perUser int) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := map[string]int{}
+	for _, job := range s.jobs {
+		if job.status == "running" {
+			running[job.job.UserID]++
+		}
+	}
+
+	claimed := []Job{}
+	for _, job := range s.jobs {
+		if len(claimed) == limit {
+			break
+		}
+		if job.status != "queued" || job.due.After(time.Now()) || running[job.job.UserID] >= perUser {
+			continue
+		}
+		running[job.job.UserID]++
+		job.job.Attempts++
+		s.move(job.job.ID, "running")
+		claimed = append(claimed, job.job)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) Heartbeat(context.Context, []string) error { return nil }
+
+func (s *memoryStore) RequeueStale(context.Context, time.Time, int) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (s *memoryStore) Requeue(_ context.Context, job Job, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.find(job.ID).job.Attempts--
+	s.move(job.ID, "queued")
+	return nil
+}
+
+func (s *memoryStore) Retry(_ context.Context, job Job, _ error, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.find(job.ID).due = at
+	s.move(job.ID, "queued")
+	return nil
+}
+
+func (s *memoryStore) Fail(_ context.Context, job Job, _ error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.move(job.ID, "failed")
+	return nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.move(job.ID, "completed")
+	return nil
+}
+
+func waitForStatus(t *testing.T, store *memoryStore, id, want string) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, events := store.status(id); status == want {
+			return events
+		}
+		time.Sleep(time.Millisecond)
+	}
+	status, events := store.status(id)
+	t.Fatalf("Expected %s to be %s, got %s after %v", id, want, status, events)
+	return nil
+}
+
+func newTestEngine(t *testing.T, store Store, run Runner) *Engine {
+	middleware.Logger = zap.NewNop()
+	engine := NewEngine(store, run, Config{
+		Workers:      4,
+		PerUser:      1,
+		MaxAttempts:  3,
+		PollInterval: 5 * time.Millisecond,
+		BackoffBase:  time.Millisecond,
+		BackoffMax:   2 * time.Millisecond,
+	})
+	t.Cleanup(func() { engine.Close(context.Background()) })
+	return engine
+}
+
+func TestEngine_RetriesUntilSuccess(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a", "u1")
+
+	newTestEngine(t, store, func(_ context.Context, job Job) error {
+		if job.Attempts < 3 {
+			return errors.New("connection reset")
+		}
+		return nil
+	})
+
+	events := waitForStatus(t, store, "a", "completed")
+	want := []string{"running", "queued", "running", "queued", "running", "completed"}
+	if len(events) != len(want) {
+		t.Errorf("Expected history %v, got %v", want, events)
+	}
+}
+
+func TestEngine_FailsPermanentErrorsAndExhaustedAttempts(t *testing.T) {
+	store := &memoryStore{}
+	store.add("permanent", "u1")
+	store.add("flaky", "u2")
+
+	newTestEngine(t, store, func(_ context.Context, job Job) error {
+		if job.ID == "permanent" {
+			return Permanent(errors.New("permission denied"))
+		}
+		return errors.New("timeout")
+	})
+
+	if events := waitForStatus(t, store, "permanent", "failed"); len(events) != 2 {
+		t.Errorf("Expected a single attempt, got %v", events)
+	}
+	if events := waitForStatus(t, store, "flaky", "failed"); len(events) != 6 {
+		t.Errorf("Expected three attempts, got %v", events)
+	}
+}
+
+func TestEngine_LimitsJobsPerUser(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a1", "a")
+	store.add("a2", "a")
+	store.add("b1", "b")
+
+	release := make(chan struct{})
+	newTestEngine(t, store, func(ctx context.Context, _ Job) error {
+		<-release
+		return nil
+	})
+
+	waitForStatus(t, store, "b1", "running")
+	time.Sleep(20 * time.Millisecond)
+	if status, _ := store.status("a2"); status != "queued" {
+		t.Errorf("Expected a2 to wait for a1, got %s", status)
+	}
+
+	close(release)
+	waitForStatus(t, store, "a2", "completed")
+}
+
+func TestEngine_InterruptAndShutdown(t *testing.T) {
+	store := &memoryStore{}
+	store.add("paused", "u1")
+	store.add("busy", "u2")
+
+	engine := newTestEngine(t, store, func(ctx context.Context, _ Job) error {
+		<-ctx.Done()
+		return context.Cause(ctx)
+	})
+
+	waitForStatus(t, store, "paused", "running")
+	waitForStatus(t, store, "busy", "running")
+
+	if !engine.Interrupt("paused") {
+		t.Fatal("Expected the paused job to be running here")
+	}
+	if err := engine.Close(context.Background()); err != nil {
+		t.Fatalf("Expected a clean shutdown, got: %v", err)
+	}
+
+	if status, _ := store.status("paused"); status != "running" {
+		t.Errorf("Expected an interrupted job to be left to whoever paused it, got %s", status)
+	}
+	if status, _ := store.status("busy"); status != "queued" {
+		t.Errorf("Expected the job to be queued again on shutdown, got %s", status)
+	}
+	if engine.Running() != 0 {
+		t.Errorf("Expected no running jobs, got %d", engine.Running())
+	}
+}
+
+func TestEngine_BackoffGrowsUpToMax(t *testing.T) {
+	engine := &Engine{cfg: Config{BackoffBase: time.Second, BackoffMax: time.Minute}}
+
+	for attempts, limit := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 10: time.Minute, 100: time.Minute} {
+		delay := engine.backoff(attempts)
+		if delay < limit/2 || delay > limit {
+			t.Errorf("Expected backoff after %d attempts within [%v, %v], got %v", attempts, limit/2, limit, delay)
+		}
+	}
+}
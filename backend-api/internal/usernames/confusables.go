@@ -0,0 +1,44 @@
+package usernames
+
+import "strings"
+
+// prototypes folds characters that render like a Latin letter or digit onto
+// it. It is the subset of Unicode's confusables.txt that survives NFKC case
+// folding and matters for handles: digits standing in for letters, and the
+// Cyrillic, Greek and IPA lookalikes of lowercase Latin. i joins l and 1
+// because all three are a single vertical stroke in many fonts.
+var prototypes = map[rune]rune{
+	'0': 'o',
+	'1': 'l',
+	'i': 'l',
+
+	'ı': 'l', 'ȷ': 'j', 'ɑ': 'a', 'ɡ': 'g', 'ɩ': 'l', 'ɪ': 'l', 'ʏ': 'y', 'ℓ': 'l',
+
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'l', 'ї': 'l', 'ј': 'j',
+	'к': 'k', 'ӏ': 'l', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'ѕ': 's',
+	'т': 't', 'у': 'y', 'х': 'x', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ү': 'y',
+
+	'α': 'a', 'β': 'b', 'γ': 'y', 'ι': 'l', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'σ': 'o', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+}
+
+// sequences are multi-letter lookalikes, applied after single characters.
+var sequences = strings.NewReplacer("rn", "m", "vv", "w")
+
+// Skeleton reduces a normalised handle to the form two handles share when
+// they read the same: the @ and separators are dropped and lookalike
+// characters mapped to their prototype. migrations/000007 computes the same
+// skeleton in SQL for the ASCII handles that existed before it.
+func Skeleton(handle string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimPrefix(handle, Prefix) {
+		if isSeparator(r) {
+			continue
+		}
+		if prototype, ok := prototypes[r]; ok {
+			r = prototype
+		}
+		b.WriteRune(r)
+	}
+	return sequences.Replace(b.String())
+}
@@ -0,0 +1,101 @@
+package usernames
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed reserved.txt
+var reservedNames string
+
+// List is a set of handles matched by skeleton, so a listed "@admin" also
+// covers "@adm1n" and "@a.d.m.i.n".
+type List struct {
+	skeletons map[string]bool
+}
+
+func NewList(names ...string) *List {
+	list := &List{skeletons: map[string]bool{}}
+	list.Add(names...)
+	return list
+}
+
+// DefaultReserved returns the built-in reserved names: routes, roles, and
+// the product's own name.
+func DefaultReserved() *List {
+	list := NewList()
+	_ = list.read(strings.NewReader(reservedNames))
+	return list
+}
+
+func (l *List) Add(names ...string) {
+	for _, name := range names {
+		if skeleton := Skeleton(Normalize(name)); skeleton != "" {
+			l.skeletons[skeleton] = true
+		}
+	}
+}
+
+// LoadFile adds one name per line from path. Blank lines and lines starting
+// with # are skipped.
+func (l *List) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return l.read(file)
+}
+
+func (l *List) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l.Add(line)
+	}
+	return scanner.Err()
+}
+
+func (l *List) Contains(handle string) bool {
+	return l != nil && l.skeletons[Skeleton(handle)]
+}
+
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.skeletons)
+}
+
+// Filter is the hook for content moderation of handles, such as a profanity
+// filter.
+type Filter interface {
+	Blocked(handle string) bool
+}
+
+// WordFilter blocks handles whose skeleton contains any of its words, so
+// separators and lookalike characters don't get a word past it.
+type WordFilter struct {
+	words *List
+}
+
+func NewWordFilter(words *List) *WordFilter {
+	return &WordFilter{words: words}
+}
+
+func (f *WordFilter) Blocked(handle string) bool {
+	skeleton := Skeleton(handle)
+	for word := range f.words.skeletons {
+		if strings.Contains(skeleton, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package usernames
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/models"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	Prefix = "@"
+
+	// MaxHandleLength caps MaxLength so handles fit the users.username column.
+	MaxHandleLength = 32
+)
+
+// Policy is the per-deployment username policy. Lengths are in characters
+// and exclude the @.
+type Policy struct {
+	MinLength       int
+	MaxLength       int
+	AllowUnderscore bool
+	AllowDot        bool
+	AllowUnicode    bool
+	Reserved        *List
+	Filter          Filter
+}
+
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MinLength: 3,
+		MaxLength: 16,
+		Reserved:  DefaultReserved(),
+	}
+}
+
+func (p *Policy) Validate() error {
+	switch {
+	case p.MinLength < 1:
+		return errors.New("minimum username length must be at least 1")
+	case p.MaxLength < p.MinLength:
+		return errors.New("maximum username length must not be below the minimum")
+	case p.MaxLength > MaxHandleLength:
+		return errors.New("maximum username length must not exceed 32")
+	}
+	return nil
+}
+
+// Normalize returns the canonical form handles are compared and stored in:
+// trimmed, NFKC normalised and case folded, so "@Ada" and the full-width
+// "＠ａｄａ" are the same handle.
+func Normalize(handle string) string {
+	return norm.NFKC.String(cases.Fold().String(strings.TrimSpace(handle)))
+}
+
+// Check validates a normalised handle against the policy. It does not look
+// at existing accounts; compare Skeleton against theirs for that.
+func (p *Policy) Check(handle string) []models.FieldError {
+	if utf8.RuneCountInString(handle) > p.MaxLength+len(Prefix) {
+		return []models.FieldError{apierror.Field("username", apierror.FieldTooLong, map[string]any{"max": p.MaxLength + len(Prefix)})}
+	}
+
+	if !p.wellFormed(handle) {
+		if separators := p.separators(); len(separators) > 0 {
+			return []models.FieldError{apierror.Field("username", apierror.FieldInvalidUsernameSeparated,
+				map[string]any{"prefix": Prefix, "min": p.MinLength, "max": p.MaxLength, "separators": separators})}
+		}
+		return []models.FieldError{apierror.Field("username", apierror.FieldInvalidUsername,
+			map[string]any{"prefix": Prefix, "min": p.MinLength, "max": p.MaxLength})}
+	}
+
+	if mixedScripts(handle) {
+		return []models.FieldError{apierror.Field("username", apierror.FieldUsernameMixedScripts, nil)}
+	}
+
+	if p.Reserved.Contains(handle) {
+		return []models.FieldError{apierror.Field("username", apierror.FieldUsernameReserved, nil)}
+	}
+
+	if p.Filter != nil && p.Filter.Blocked(handle) {
+		return []models.FieldError{apierror.Field("username", apierror.FieldUsernameNotAllowed, nil)}
+	}
+
+	return nil
+}
+
+func (p *Policy) wellFormed(handle string) bool {
+	name, ok := strings.CutPrefix(handle, Prefix)
+	if !ok {
+		return false
+	}
+
+	length := utf8.RuneCountInString(name)
+	if length < p.MinLength || length > p.MaxLength {
+		return false
+	}
+
+	previousSeparator := true
+	for _, r := range name {
+		switch {
+		case isSeparator(r):
+			if previousSeparator || !p.allowsSeparator(r) {
+				return false
+			}
+			previousSeparator = true
+			continue
+		case r < utf8.RuneSelf:
+			if !('a' <= r && r <= 'z' || '0' <= r && r <= '9') {
+				return false
+			}
+		case !p.AllowUnicode || !(unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)):
+			return false
+		}
+		previousSeparator = false
+	}
+
+	return !previousSeparator
+}
+
+func (p *Policy) separators() []string {
+	separators := []string{}
+	if p.AllowUnderscore {
+		separators = append(separators, "_")
+	}
+	if p.AllowDot {
+		separators = append(separators, ".")
+	}
+	return separators
+}
+
+func (p *Policy) allowsSeparator(r rune) bool {
+	return r == '_' && p.AllowUnderscore || r == '.' && p.AllowDot
+}
+
+func isSeparator(r rune) bool {
+	return r == '_' || r == '.'
+}
+
+// japanese lists the scripts that legitimately share one Japanese handle.
+var japanese = map[string]bool{"Han": true, "Hiragana": true, "Katakana": true}
+
+// mixedScripts reports whether the letters of handle come from more than one
+// script, the usual shape of a spoofed name such as a Latin handle with one
+// Cyrillic letter swapped in.
+func mixedScripts(handle string) bool {
+	seen := map[string]bool{}
+	for _, r := range handle {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		seen[script(r)] = true
+	}
+
+	if len(seen) <= 1 {
+		return false
+	}
+	for name := range seen {
+		if !japanese[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func script(r rune) string {
+	if r < utf8.RuneSelf {
+		return "Latin"
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
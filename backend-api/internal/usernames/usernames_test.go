@@ -0,0 +1,89 @@
+package usernames
+
+import (
+	"testing"
+
+	"livecode-api/internal/apierror"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		" @Ada ":   "@ada",
+		"＠ａｄａ":     "@ada",
+		"@STRASSE": "@strasse",
+		"@Straße":  "@strasse",
+	}
+
+	for input, expected := range cases {
+		if got := Normalize(input); got != expected {
+			t.Errorf("Normalize(%q): expected %q, got: %q", input, expected, got)
+		}
+	}
+}
+
+func TestSkeleton_Confusables(t *testing.T) {
+	same := [][2]string{
+		{"@paypal", "@paypa1"},
+		{"@paypal", "@pаypal"},
+		{"@modern", "@modem"},
+		{"@bowl", "@bovvl"},
+		{"@ada.lovelace", "@ada_lovelace"},
+		{"@adalovelace", "@ada.lovelace"},
+		{"@oscar", "@0scar"},
+	}
+	for _, pair := range same {
+		if Skeleton(pair[0]) != Skeleton(pair[1]) {
+			t.Errorf("Expected %q and %q to share a skeleton, got: %q and %q", pair[0], pair[1], Skeleton(pair[0]), Skeleton(pair[1]))
+		}
+	}
+
+	if Skeleton("@ada") == Skeleton("@eda") {
+		t.Error("Expected different handles to have different skeletons")
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	strict := DefaultPolicy()
+
+	relaxed := DefaultPolicy()
+	relaxed.AllowUnderscore = true
+	relaxed.AllowDot = true
+	relaxed.AllowUnicode = true
+	relaxed.Filter = NewWordFilter(NewList("darn"))
+
+	cases := []struct {
+		name   string
+		policy *Policy
+		handle string
+		code   apierror.Code
+	}{
+		{"valid", strict, "@ada77", ""},
+		{"missing prefix", strict, "ada77", apierror.FieldInvalidUsername},
+		{"too short", strict, "@ad", apierror.FieldInvalidUsername},
+		{"too long", strict, "@abcdefghijklmnopq", apierror.FieldTooLong},
+		{"separator not allowed", strict, "@ada_l", apierror.FieldInvalidUsername},
+		{"unicode not allowed", strict, "@ådå", apierror.FieldInvalidUsername},
+		{"reserved", strict, "@admin", apierror.FieldUsernameReserved},
+		{"reserved lookalike", strict, "@adm1n", apierror.FieldUsernameReserved},
+		{"separators", relaxed, "@ada.love_lace", ""},
+		{"leading separator", relaxed, "@.ada", apierror.FieldInvalidUsernameSeparated},
+		{"trailing separator", relaxed, "@ada_", apierror.FieldInvalidUsernameSeparated},
+		{"doubled separator", relaxed, "@ada..l", apierror.FieldInvalidUsernameSeparated},
+		{"reserved with separators", relaxed, "@live.code", apierror.FieldUsernameReserved},
+		{"unicode", relaxed, "@ștefan", ""},
+		{"cyrillic", relaxed, "@иван", ""},
+		{"japanese", relaxed, "@ひらがなカタカナ漢字", ""},
+		{"mixed scripts", relaxed, "@pаypal", apierror.FieldUsernameMixedScripts},
+		{"filtered", relaxed, "@d.a.r.n.it", apierror.FieldUsernameNotAllowed},
+	}
+
+	for _, tc := range cases {
+		errors := tc.policy.Check(tc.handle)
+		switch {
+		case tc.code == "" && len(errors) > 0:
+			t.Errorf("%s: expected %q to pass, got: %v", tc.name, tc.handle, errors)
+		case tc.code != "" && (len(errors) != 1 || errors[0].Code != string(tc.code)):
+			t.Errorf("%s: expected %q to fail with %s, got: %v", tc.name, tc.handle, tc.code, errors)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package vault
+
+import "crypto/rand"
+
+// WrappedKey is a user's data key encrypted under a master key.
+type WrappedKey struct {
+	MasterKeyID string
+	Nonce       []byte
+	Ciphertext  []byte
+}
+
+// Keyring holds the current master key, which wraps new data keys, and the
+// previous ones, which are only used to unwrap data keys until they are
+// rewrapped.
+type Keyring struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+func NewKeyring(current []byte, previous ...[]byte) (*Keyring, error) {
+	if len(current) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	keyring := &Keyring{currentID: KeyID(current), keys: map[string][]byte{}}
+	for _, key := range append([][]byte{current}, previous...) {
+		if len(key) != KeySize {
+			return nil, ErrInvalidKey
+		}
+		keyring.keys[KeyID(key)] = key
+	}
+	return keyring, nil
+}
+
+func (k *Keyring) CurrentID() string {
+	return k.currentID
+}
+
+// NewDataKey generates a data key for userID and returns it together with
+// its wrapped form for storage.
+func (k *Keyring) NewDataKey(userID string) ([]byte, WrappedKey, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, WrappedKey{}, err
+	}
+
+	wrapped, err := k.wrap(userID, dataKey)
+	if err != nil {
+		return nil, WrappedKey{}, err
+	}
+	return dataKey, wrapped, nil
+}
+
+func (k *Keyring) Unwrap(userID string, wrapped WrappedKey) ([]byte, error) {
+	masterKey, ok := k.keys[wrapped.MasterKeyID]
+	if !ok {
+		return nil, ErrUnknownMasterKey
+	}
+	return Open(masterKey, wrapped.Nonce, wrapped.Ciphertext, dataKeyAssociatedData(userID))
+}
+
+// Rewrap re-encrypts a data key under the current master key. Secrets
+// encrypted with the data key are untouched. It reports false when the key
+// is already wrapped by the current master key.
+func (k *Keyring) Rewrap(userID string, wrapped WrappedKey) (WrappedKey, bool, error) {
+	if wrapped.MasterKeyID == k.currentID {
+		return wrapped, false, nil
+	}
+
+	dataKey, err := k.Unwrap(userID, wrapped)
+	if err != nil {
+		return WrappedKey{}, false, err
+	}
+	defer clear(dataKey)
+
+	rewrapped, err := k.wrap(userID, dataKey)
+	if err != nil {
+		return WrappedKey{}, false, err
+	}
+	return rewrapped, true, nil
+}
+
+func (k *Keyring) wrap(userID string, dataKey []byte) (WrappedKey, error) {
+	nonce, ciphertext, err := Seal(k.keys[k.currentID], dataKey, dataKeyAssociatedData(userID))
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	return WrappedKey{MasterKeyID: k.currentID, Nonce: nonce, Ciphertext: ciphertext}, nil
+}
@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	KeySize = 32
+
+	// MaxSecretSize bounds one stored secret; large enough for any private
+	// key in OpenSSH or PuTTY format.
+	MaxSecretSize = 16 << 10
+
+	EncryptionServer = "server"
+	EncryptionClient = "client"
+)
+
+var (
+	ErrInvalidKey       = errors.New("master key must be 32 bytes, base64 or hex encoded")
+	ErrUnknownMasterKey = errors.New("data key was wrapped by a master key that is not configured")
+	ErrDecrypt          = errors.New("ciphertext could not be authenticated")
+)
+
+// ParseKey decodes a master key given as standard or URL-safe base64, or as
+// hex.
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+
+	for _, decode := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if key, err := decode(encoded); err == nil && len(key) == KeySize {
+			return key, nil
+		}
+	}
+	return nil, ErrInvalidKey
+}
+
+// KeyID names a master key without revealing it, so wrapped data keys record
+// which master key can unwrap them.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("livecode-vault-key-id:"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AssociatedData binds a secret's ciphertext to its owner and record, so a
+// ciphertext copied to another user or row fails to decrypt. Clients
+// encrypting in zero-knowledge mode must use the same value.
+func AssociatedData(userID, itemID string) []byte {
+	return []byte("livecode-vault:v1:" + userID + ":" + itemID)
+}
+
+func dataKeyAssociatedData(userID string) []byte {
+	return []byte("livecode-vault-dek:v1:" + userID)
+}
+
+// Seal encrypts plaintext with AES-256-GCM under key and returns the random
+// nonce and the ciphertext.
+func Seal(key, plaintext, associatedData []byte) (nonce, ciphertext []byte, err error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, associatedData), nil
+}
+
+func Open(key, nonce, ciphertext, associatedData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, associatedData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
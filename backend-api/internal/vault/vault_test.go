@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(7)
+
+	for _, encoded := range []string{
+		base64.StdEncoding.EncodeToString(key),
+		base64.RawURLEncoding.EncodeToString(key) + "\n",
+		hex.EncodeToString(key),
+	} {
+		parsed, err := ParseKey(encoded)
+		if err != nil || !bytes.Equal(parsed, key) {
+			t.Errorf("ParseKey(%q): expected the key back, got: %x, %v", encoded, parsed, err)
+		}
+	}
+
+	if _, err := ParseKey(base64.StdEncoding.EncodeToString(key[:16])); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected a short key to be rejected, got: %v", err)
+	}
+}
+
+func TestSealAndOpen_BindAssociatedData(t *testing.T) {
+	key := testKey(1)
+
+	nonce, ciphertext, err := Seal(key, []byte("hunter2"), AssociatedData("user-1", "item-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := Open(key, nonce, ciphertext, AssociatedData("user-1", "item-1"))
+	if err != nil || string(plaintext) != "hunter2" {
+		t.Fatalf("Expected round trip, got: %q, %v", plaintext, err)
+	}
+
+	for name, associatedData := range map[string][]byte{
+		"other user": AssociatedData("user-2", "item-1"),
+		"other item": AssociatedData("user-1", "item-2"),
+	} {
+		if _, err := Open(key, nonce, ciphertext, associatedData); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: expected decryption to fail, got: %v", name, err)
+		}
+	}
+}
+
+func TestKeyring_RewrapRotatesMasterKey(t *testing.T) {
+	oldKeyring, _ := NewKeyring(testKey(1))
+	dataKey, wrapped, err := oldKeyring.NewDataKey("user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := NewKeyring(testKey(2), testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrapped, changed, err := rotated.Rewrap("user-1", wrapped)
+	if err != nil || !changed {
+		t.Fatalf("Expected the data key to be rewrapped, got: %v, %v", changed, err)
+	}
+	if rewrapped.MasterKeyID != rotated.CurrentID() || rewrapped.MasterKeyID == wrapped.MasterKeyID {
+		t.Errorf("Expected the new master key ID, got: %s", rewrapped.MasterKeyID)
+	}
+
+	if _, changed, _ := rotated.Rewrap("user-1", rewrapped); changed {
+		t.Error("Expected a current data key to be left alone")
+	}
+
+	newOnly, _ := NewKeyring(testKey(2))
+	unwrapped, err := newOnly.Unwrap("user-1", rewrapped)
+	if err != nil || !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("Expected the same data key under the new master key, got: %v", err)
+	}
+
+	if _, err := newOnly.Unwrap("user-1", wrapped); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Expected the retired master key to be unknown, got: %v", err)
+	}
+	if _, err := rotated.Unwrap("user-2", rewrapped); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected a data key to be bound to its user, got: %v", err)
+	}
+}
@@ -2,16 +2,39 @@ package main
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unicode"
 
 	"livecode-api/config"
 	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/alerting"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/emails"
+	"livecode-api/internal/events"
+	"livecode-api/internal/metrics"
+	"livecode-api/internal/openapi"
+	"livecode-api/internal/passwords"
+	"livecode-api/internal/pow"
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/scheduler"
+	"livecode-api/internal/telemetry"
+	"livecode-api/internal/transfers"
+	"livecode-api/internal/usernames"
+	"livecode-api/internal/vault"
 	"livecode-api/middleware"
+	"livecode-api/models"
 	"livecode-api/routes"
 
 	"github.com/gin-gonic/gin"
@@ -20,11 +43,74 @@ import (
 	"go.uber.org/zap"
 )
 
+const apiVersion = "1.0.0"
+
 type Config struct {
-	DatabaseURL string
-	Port        string
-	GinMode     string
-	JWTSecret   string
+	DatabaseURL              string
+	Port                     string
+	GinMode                  string
+	JWTSecret                string
+	RequestIDHeaders         []string
+	ClientIssueAlert         handlers.ClientIssueAlertPolicy
+	Telemetry                TelemetryConfig
+	OpenAPIValidateResponses bool
+	PasswordPolicy           *passwords.Policy
+	UsernamePolicy           *usernames.Policy
+	EmailPolicy              *emails.Policy
+	ProofOfWork              ProofOfWorkConfig
+	Vault                    *vault.Keyring
+	SSHCertificates          SSHCertificateConfig
+	SFTP                     SFTPConfig
+	Transfers                TransferConfig
+	Events                   EventsConfig
+	Sync                     handlers.SyncPolicy
+	Search                   handlers.SearchPolicy
+	Archives                 handlers.ArchivePolicy
+	Schedules                ScheduleConfig
+}
+
+type SSHCertificateConfig struct {
+	TTL    time.Duration
+	MaxTTL time.Duration
+}
+
+type SFTPConfig struct {
+	DialTimeout    time.Duration
+	IdleTimeout    time.Duration
+	MaxUploadBytes int64
+	Addresses      remotefs.AddressPolicy
+}
+
+type TransferConfig struct {
+	Engine     transfers.Config
+	Policy     handlers.TransferPolicy
+	StagingDir string
+	Bandwidth  int64
+}
+
+type ScheduleConfig struct {
+	Interval time.Duration
+	Grace    time.Duration
+}
+
+type EventsConfig struct {
+	Hub       events.Config
+	Heartbeat time.Duration
+}
+
+type ProofOfWorkConfig struct {
+	Key           []byte
+	TTL           time.Duration
+	Difficulty    int
+	MaxDifficulty int
+	HalfLife      time.Duration
+	Enforced      []string
+}
+
+type TelemetryConfig struct {
+	SampleRates map[string]float64
+	Sink        telemetry.SinkConfig
+	Consents    telemetry.ConsentCacheConfig
 }
 
 func getEnvOrSecret(envKey, secretPath string) string {
@@ -49,11 +135,44 @@ func getEnvOrSecret(envKey, secretPath string) string {
 	return ""
 }
 
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
 func main() {
 	middleware.InitLogger()
 
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	cfg := loadConfig()
 	config.Init(cfg.JWTSecret)
+	middleware.SetRequestIDHeaders(cfg.RequestIDHeaders)
+	middleware.SetPasswordPolicy(cfg.PasswordPolicy)
+	middleware.SetUsernamePolicy(cfg.UsernamePolicy)
+	middleware.SetEmailPolicy(cfg.EmailPolicy)
+	handlers.SetClientIssueAlertPolicy(cfg.ClientIssueAlert)
+	handlers.SetVaultKeyring(cfg.Vault)
+	handlers.SetSyncPolicy(cfg.Sync)
+	handlers.SetSearchPolicy(cfg.Search)
+	handlers.SetArchivePolicy(cfg.Archives)
+
+	remotePool := remotefs.NewPool(cfg.SFTP.DialTimeout, cfg.SFTP.IdleTimeout, 2)
+	handlers.SetRemotePool(remotePool)
+	handlers.SetRemoteAddressPolicy(&cfg.SFTP.Addresses)
 
 	if err := database.Connect(cfg.DatabaseURL); err != nil {
 		middleware.Logger.Fatal("database connection failed",
@@ -68,9 +187,21 @@ func main() {
 		)
 	}
 
-	router := setupRouter()
+	telemetryIngestor := setupTelemetry(cfg.Telemetry)
+
+	eventHub := events.NewHub(cfg.Events.Hub)
+	handlers.SetEventHub(eventHub)
+	eventRelay := setupEventRelay(cfg.DatabaseURL, eventHub)
 
-	runServer(router, cfg.Port)
+	router := setupRouter(cfg, telemetryIngestor, eventHub)
+
+	transferEngine, stagingExpiry := setupTransfers(cfg.Transfers)
+	schedules := setupSchedules(cfg.Schedules)
+
+	runServer(router, cfg.Port, eventHub, eventRelay.Close, telemetryIngestor.Sink.Close, telemetryIngestor.Consents.Close, schedules.Close, stagingExpiry.Close, transferEngine.Close, func(context.Context) error {
+		remotePool.Close()
+		return nil
+	})
 }
 
 func loadConfig() *Config {
@@ -83,6 +214,127 @@ func loadConfig() *Config {
 	port := os.Getenv("PORT")
 	ginMode := getEnvOrSecret("GIN_MODE", "/run/secrets/gin_mode")
 	jwtSecret := getEnvOrSecret("JWT_SECRET", "/run/secrets/jwt_secret")
+	requestIDHeaders := os.Getenv("REQUEST_ID_HEADERS")
+	alertWebhookURL := getEnvOrSecret("CLIENT_ERROR_ALERT_WEBHOOK_URL", "/run/secrets/client_error_alert_webhook_url")
+	alertThreshold := envInt("CLIENT_ERROR_ALERT_THRESHOLD", 25)
+	alertWindowMinutes := envInt("CLIENT_ERROR_ALERT_WINDOW_MINUTES", 15)
+	openAPIValidateResponses := os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true"
+	passwordBreachDataset := os.Getenv("PASSWORD_BREACH_DATASET_DIR")
+	passwordPolicy := passwords.DefaultPolicy()
+	passwordPolicy.MinLength = envInt("PASSWORD_MIN_LENGTH", passwordPolicy.MinLength)
+	passwordPolicy.MaxLength = envInt("PASSWORD_MAX_LENGTH", passwordPolicy.MaxLength)
+	passwordPolicy.RequiredClasses = envInt("PASSWORD_REQUIRED_CLASSES", passwordPolicy.RequiredClasses)
+	passwordPolicy.MinScore = envInt("PASSWORD_MIN_SCORE", passwordPolicy.MinScore)
+	passwordPolicy.RejectUserContext = os.Getenv("PASSWORD_REJECT_USER_CONTEXT") != "false"
+	passwordPolicy.BreachThreshold = envInt("PASSWORD_BREACH_THRESHOLD", passwordPolicy.BreachThreshold)
+	usernameReservedFile := os.Getenv("USERNAME_RESERVED_FILE")
+	usernameBlockedWordsFile := os.Getenv("USERNAME_BLOCKED_WORDS_FILE")
+	usernamePolicy := usernames.DefaultPolicy()
+	usernamePolicy.MinLength = envInt("USERNAME_MIN_LENGTH", usernamePolicy.MinLength)
+	usernamePolicy.MaxLength = envInt("USERNAME_MAX_LENGTH", usernamePolicy.MaxLength)
+	usernamePolicy.AllowUnderscore = os.Getenv("USERNAME_ALLOW_UNDERSCORE") == "true"
+	usernamePolicy.AllowDot = os.Getenv("USERNAME_ALLOW_DOT") == "true"
+	usernamePolicy.AllowUnicode = os.Getenv("USERNAME_ALLOW_UNICODE") == "true"
+	powSecret := getEnvOrSecret("POW_SECRET", "/run/secrets/pow_secret")
+	powEnforce := os.Getenv("POW_ENFORCE")
+	powConfig := ProofOfWorkConfig{
+		TTL:           time.Duration(envInt("POW_TTL_SECONDS", 120)) * time.Second,
+		Difficulty:    envInt("POW_DIFFICULTY", 18),
+		MaxDifficulty: envInt("POW_MAX_DIFFICULTY", 24),
+		HalfLife:      time.Duration(envInt("POW_HALF_LIFE_MINUTES", 10)) * time.Minute,
+	}
+	vaultMasterKey := getEnvOrSecret("VAULT_MASTER_KEY", "/run/secrets/vault_master_key")
+	vaultPreviousKeys := getEnvOrSecret("VAULT_PREVIOUS_MASTER_KEYS", "/run/secrets/vault_previous_master_keys")
+	sshCertConfig := SSHCertificateConfig{
+		TTL:    time.Duration(envInt("SSH_CERT_TTL_MINUTES", 240)) * time.Minute,
+		MaxTTL: time.Duration(envInt("SSH_CERT_MAX_TTL_MINUTES", 480)) * time.Minute,
+	}
+	sftpConfig := SFTPConfig{
+		DialTimeout:    time.Duration(envInt("SFTP_DIAL_TIMEOUT_SECONDS", 15)) * time.Second,
+		IdleTimeout:    time.Duration(envInt("SFTP_IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+		MaxUploadBytes: int64(envInt("SFTP_MAX_UPLOAD_MB", 4096)) << 20,
+	}
+	remoteAllowedNetworks := os.Getenv("REMOTE_ALLOWED_NETWORKS")
+	transferConfig := TransferConfig{
+		Engine: transfers.Config{
+			Workers:     envInt("TRANSFER_WORKERS", 4),
+			PerUser:     envInt("TRANSFER_PER_USER", 2),
+			MaxAttempts: envInt("TRANSFER_MAX_ATTEMPTS", 5),
+		},
+		Policy: handlers.TransferPolicy{
+			MaxSize:         int64(envInt("TRANSFER_MAX_SIZE_MB", 10240)) << 20,
+			MaxStagingBytes: int64(envInt("TRANSFER_USER_STAGING_MB", 20480)) << 20,
+			StagingTTL:      time.Duration(envInt("TRANSFER_STAGING_TTL_HOURS", 24)) * time.Hour,
+		},
+		StagingDir: os.Getenv("TRANSFER_STAGING_DIR"),
+		Bandwidth:  int64(envInt("TRANSFER_BANDWIDTH_KBPS", 0)) << 10,
+	}
+	scheduleConfig := ScheduleConfig{
+		Interval: time.Duration(envInt("SCHEDULE_POLL_SECONDS", 30)) * time.Second,
+		Grace:    time.Duration(envInt("SCHEDULE_GRACE_MINUTES", 5)) * time.Minute,
+	}
+	eventsConfig := EventsConfig{
+		Hub: events.Config{
+			BufferSize:     envInt("EVENTS_BUFFER_SIZE", 256),
+			MaxSubscribers: envInt("EVENTS_MAX_STREAMS_PER_USER", 8),
+			Retention:      time.Duration(envInt("EVENTS_RETENTION_MINUTES", 10)) * time.Minute,
+		},
+		Heartbeat: time.Duration(envInt("EVENTS_HEARTBEAT_SECONDS", 25)) * time.Second,
+	}
+	syncPolicy := handlers.SyncPolicy{
+		MaxEntries:       envInt("SYNC_MAX_ENTRIES", 100000),
+		MaxActions:       envInt("SYNC_MAX_ACTIONS", 5000),
+		MaxChecksumBytes: int64(envInt("SYNC_MAX_CHECKSUM_MB", 1024)) << 20,
+		PlanTTL:          time.Duration(envInt("SYNC_PLAN_TTL_MINUTES", 60)) * time.Minute,
+	}
+
+	archivePolicy := handlers.ArchivePolicy{
+		MaxEntries:        envInt("ARCHIVE_MAX_ENTRIES", 100000),
+		MaxExtractedBytes: int64(envInt("ARCHIVE_MAX_EXTRACTED_MB", 10240)) << 20,
+	}
+
+	searchPolicy := handlers.SearchPolicy{
+		MaxResults:  envInt("SEARCH_MAX_RESULTS", 1000),
+		MaxDepth:    envInt("SEARCH_MAX_DEPTH", 20),
+		Timeout:     time.Duration(envInt("SEARCH_TIMEOUT_SECONDS", 120)) * time.Second,
+		MaxBytes:    int64(envInt("SEARCH_MAX_SCAN_MB", 1024)) << 20,
+		MaxFileSize: int64(envInt("SEARCH_MAX_FILE_MB", 64)) << 20,
+	}
+	// Any server may run a job or receive its next chunk, so in a deployment
+	// staged data has to live on storage every instance shares.
+	if transferConfig.StagingDir == "" && os.Getenv("DOCKER_ENV") == "true" {
+		middleware.Logger.Fatal("TRANSFER_STAGING_DIR must be set to a persistent directory shared by all instances")
+	}
+	if transferConfig.StagingDir == "" {
+		transferConfig.StagingDir = filepath.Join(os.TempDir(), "livecode-transfers")
+		middleware.Logger.Warn("transfer data is staged in the temp dir, which only works for a single instance",
+			zap.String("dir", transferConfig.StagingDir),
+		)
+	}
+	emailDisposableFile := os.Getenv("EMAIL_DISPOSABLE_DOMAINS_FILE")
+	emailPolicy := emails.DefaultPolicy()
+	emailPolicy.ProviderRules = os.Getenv("EMAIL_PROVIDER_RULES") != "false"
+	if os.Getenv("EMAIL_CHECK_MX") == "true" {
+		emailPolicy.Resolver = net.DefaultResolver
+		emailPolicy.LookupTimeout = time.Duration(envInt("EMAIL_MX_TIMEOUT_MS", 3000)) * time.Millisecond
+	}
+	telemetryConfig := TelemetryConfig{
+		SampleRates: map[string]float64{
+			telemetry.EventTypeError:        envFloat("TELEMETRY_SAMPLE_RATE_ERROR", 1),
+			telemetry.EventTypePerformance:  envFloat("TELEMETRY_SAMPLE_RATE_PERFORMANCE", 0.25),
+			telemetry.EventTypeFeatureUsage: envFloat("TELEMETRY_SAMPLE_RATE_FEATURE_USAGE", 1),
+		},
+		Sink: telemetry.SinkConfig{
+			BufferSize:    envInt("TELEMETRY_BUFFER_SIZE", 10000),
+			BatchSize:     envInt("TELEMETRY_BATCH_SIZE", 500),
+			Workers:       envInt("TELEMETRY_WORKERS", 2),
+			FlushInterval: time.Duration(envInt("TELEMETRY_FLUSH_INTERVAL_MS", 2000)) * time.Millisecond,
+		},
+		Consents: telemetry.ConsentCacheConfig{
+			TTL:        time.Duration(envInt("TELEMETRY_CONSENT_CACHE_SECONDS", 30)) * time.Second,
+			MaxEntries: envInt("TELEMETRY_CONSENT_CACHE_SIZE", 10000),
+		},
+	}
 
 	if os.Getenv("DOCKER_ENV") == "true" && databaseURL == "" {
 		pgUser := os.Getenv("POSTGRES_USER")
@@ -102,6 +354,9 @@ func loadConfig() *Config {
 	if port == "" {
 		port = "3000"
 	}
+	if requestIDHeaders == "" {
+		requestIDHeaders = "X-Request-ID,X-Correlation-ID"
+	}
 	if ginMode != "" {
 		gin.SetMode(ginMode)
 	}
@@ -110,89 +365,486 @@ func loadConfig() *Config {
 		middleware.Logger.Fatal("DATABASE_URL is required")
 	}
 
+	if passwordBreachDataset != "" {
+		store, err := passwords.OpenRangeDirectory(passwordBreachDataset)
+		if err != nil {
+			middleware.Logger.Fatal("password breach dataset unavailable",
+				zap.String("dir", passwordBreachDataset),
+				zap.Error(err),
+			)
+		}
+		passwordPolicy.Breaches = store
+	}
+	if err := passwordPolicy.Validate(); err != nil {
+		middleware.Logger.Fatal("invalid password policy",
+			zap.Error(err),
+		)
+	}
+
+	if usernameReservedFile != "" {
+		if err := usernamePolicy.Reserved.LoadFile(usernameReservedFile); err != nil {
+			middleware.Logger.Fatal("username reserved list unavailable",
+				zap.String("file", usernameReservedFile),
+				zap.Error(err),
+			)
+		}
+	}
+	if usernameBlockedWordsFile != "" {
+		words := usernames.NewList()
+		if err := words.LoadFile(usernameBlockedWordsFile); err != nil {
+			middleware.Logger.Fatal("username blocked words unavailable",
+				zap.String("file", usernameBlockedWordsFile),
+				zap.Error(err),
+			)
+		}
+		usernamePolicy.Filter = usernames.NewWordFilter(words)
+	}
+	if err := usernamePolicy.Validate(); err != nil {
+		middleware.Logger.Fatal("invalid username policy",
+			zap.Error(err),
+		)
+	}
+
+	if powSecret != "" {
+		powConfig.Key = []byte(powSecret)
+	} else {
+		mac := hmac.New(sha256.New, []byte(jwtSecret))
+		mac.Write([]byte("livecode proof-of-work"))
+		powConfig.Key = mac.Sum(nil)
+	}
+	for _, scope := range strings.Split(powEnforce, ",") {
+		if scope = strings.TrimSpace(scope); scope == "" {
+			continue
+		}
+		if !slices.Contains(pow.Scopes, scope) {
+			middleware.Logger.Fatal("unknown proof-of-work scope",
+				zap.String("scope", scope),
+				zap.Strings("allowed", pow.Scopes),
+			)
+		}
+		powConfig.Enforced = append(powConfig.Enforced, scope)
+	}
+	if powConfig.Difficulty < 1 || powConfig.MaxDifficulty < powConfig.Difficulty || powConfig.MaxDifficulty > 32 {
+		middleware.Logger.Fatal("invalid proof-of-work difficulty",
+			zap.Int("difficulty", powConfig.Difficulty),
+			zap.Int("max_difficulty", powConfig.MaxDifficulty),
+		)
+	}
+
+	var vaultKeyring *vault.Keyring
+	if vaultMasterKey != "" {
+		keyring, err := loadVaultKeyring(vaultMasterKey, vaultPreviousKeys)
+		if err != nil {
+			middleware.Logger.Fatal("invalid vault master key",
+				zap.Error(err),
+			)
+		}
+		vaultKeyring = keyring
+	} else if vaultPreviousKeys != "" {
+		middleware.Logger.Fatal("VAULT_PREVIOUS_MASTER_KEYS requires VAULT_MASTER_KEY")
+	}
+
+	allowedNetworks, err := remotefs.ParseAllowedNetworks(remoteAllowedNetworks)
+	if err != nil {
+		middleware.Logger.Fatal("invalid REMOTE_ALLOWED_NETWORKS",
+			zap.Error(err),
+		)
+	}
+	sftpConfig.Addresses.Allowed = allowedNetworks
+
+	if sshCertConfig.TTL < 5*time.Minute || sshCertConfig.MaxTTL < sshCertConfig.TTL {
+		middleware.Logger.Fatal("invalid ssh certificate lifetime",
+			zap.Duration("ttl", sshCertConfig.TTL),
+			zap.Duration("max_ttl", sshCertConfig.MaxTTL),
+		)
+	}
+
+	if emailDisposableFile != "" {
+		if err := emailPolicy.Disposable.LoadFile(emailDisposableFile); err != nil {
+			middleware.Logger.Fatal("email disposable domain list unavailable",
+				zap.String("file", emailDisposableFile),
+				zap.Error(err),
+			)
+		}
+	}
+
 	middleware.Logger.Info("configuration loaded",
 		zap.String("port", port),
 		zap.String("gin_mode", ginMode),
+		zap.String("request_id_headers", requestIDHeaders),
+		zap.Int("client_error_alert_threshold", alertThreshold),
+		zap.Bool("openapi_validate_responses", openAPIValidateResponses),
+		zap.Int("password_min_length", passwordPolicy.MinLength),
+		zap.Int("password_min_score", passwordPolicy.MinScore),
+		zap.Bool("password_breach_dataset", passwordPolicy.Breaches != nil),
+		zap.Int("username_reserved_names", usernamePolicy.Reserved.Len()),
+		zap.Bool("username_filter", usernamePolicy.Filter != nil),
+		zap.Bool("email_provider_rules", emailPolicy.ProviderRules),
+		zap.Int("email_disposable_domains", emailPolicy.Disposable.Len()),
+		zap.Bool("email_check_mx", emailPolicy.Resolver != nil),
+		zap.Strings("pow_enforced", powConfig.Enforced),
+		zap.Int("pow_difficulty", powConfig.Difficulty),
+		zap.Bool("vault_server_encryption", vaultKeyring != nil),
+		zap.Duration("ssh_cert_ttl", sshCertConfig.TTL),
+		zap.Duration("sftp_idle_timeout", sftpConfig.IdleTimeout),
+		zap.Int64("sftp_max_upload_bytes", sftpConfig.MaxUploadBytes),
+		zap.Int("transfer_workers", transferConfig.Engine.Workers),
+		zap.Int("transfer_per_user", transferConfig.Engine.PerUser),
+		zap.String("transfer_staging_dir", transferConfig.StagingDir),
+		zap.Int64("transfer_bandwidth", transferConfig.Bandwidth),
+		zap.Duration("schedule_grace", scheduleConfig.Grace),
+		zap.Int("events_buffer_size", eventsConfig.Hub.BufferSize),
+		zap.Duration("events_heartbeat", eventsConfig.Heartbeat),
+		zap.Bool("client_error_alert_webhook", alertWebhookURL != ""),
 		zap.Bool("jwt_from_secret_file", os.Getenv("JWT_SECRET_FILE") != ""),
 		zap.Bool("database_from_secrets", os.Getenv("DOCKER_ENV") == "true"),
 	)
 
+	var alertNotifier alerting.Notifier = alerting.LogNotifier{}
+	if alertWebhookURL != "" {
+		alertNotifier = alerting.MultiNotifier{alerting.LogNotifier{}, alerting.NewWebhookNotifier(alertWebhookURL)}
+	}
+
 	return &Config{
-		DatabaseURL: databaseURL,
-		Port:        port,
-		GinMode:     ginMode,
-		JWTSecret:   jwtSecret,
+		DatabaseURL:      databaseURL,
+		Port:             port,
+		GinMode:          ginMode,
+		JWTSecret:        jwtSecret,
+		RequestIDHeaders: strings.Split(requestIDHeaders, ","),
+		ClientIssueAlert: handlers.ClientIssueAlertPolicy{
+			Threshold: alertThreshold,
+			Window:    time.Duration(alertWindowMinutes) * time.Minute,
+			Notifier:  alertNotifier,
+		},
+		Telemetry:                telemetryConfig,
+		OpenAPIValidateResponses: openAPIValidateResponses,
+		PasswordPolicy:           passwordPolicy,
+		UsernamePolicy:           usernamePolicy,
+		EmailPolicy:              emailPolicy,
+		ProofOfWork:              powConfig,
+		Vault:                    vaultKeyring,
+		SSHCertificates:          sshCertConfig,
+		SFTP:                     sftpConfig,
+		Transfers:                transferConfig,
+		Events:                   eventsConfig,
+		Sync:                     syncPolicy,
+		Search:                   searchPolicy,
+		Archives:                 archivePolicy,
+		Schedules:                scheduleConfig,
+	}
+}
+
+// loadVaultKeyring parses the current master key and any previous ones,
+// separated by commas or whitespace.
+func loadVaultKeyring(current, previous string) (*vault.Keyring, error) {
+	currentKey, err := vault.ParseKey(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var previousKeys [][]byte
+	for _, encoded := range strings.FieldsFunc(previous, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		key, err := vault.ParseKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		previousKeys = append(previousKeys, key)
 	}
+
+	return vault.NewKeyring(currentKey, previousKeys...)
 }
 
-func setupRouter() *gin.Engine {
+func setupTelemetry(cfg TelemetryConfig) *telemetry.Ingestor {
+	store := handlers.TelemetryStore{DB: database.DB}
+	consents := telemetry.NewConsentRegistry(store, cfg.Consents)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := consents.Preload(ctx); err != nil {
+		middleware.Logger.Fatal("telemetry consent loading failed",
+			zap.Error(err),
+		)
+	}
+
+	return &telemetry.Ingestor{
+		Sampler:  telemetry.NewSampler(cfg.SampleRates),
+		Consents: consents,
+		Sink:     telemetry.NewSink(store, cfg.Sink),
+	}
+}
+
+// setupEventRelay shares events between servers, so a stream sees the
+// events of jobs that another server runs.
+func setupEventRelay(databaseURL string, hub *events.Hub) *events.PostgresRelay {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	relay, err := events.StartPostgresRelay(ctx, database.DB, databaseURL, hub)
+	if err != nil {
+		middleware.Logger.Fatal("event relay failed to start",
+			zap.Error(err),
+		)
+	}
+	return relay
+}
+
+// setupTransfers starts the transfer workers. Jobs left running by a server
+// that did not shut down cleanly are picked up again once their heartbeat
+// goes stale. Uploads abandoned before all their data arrived are removed
+// by a second loop.
+func setupTransfers(cfg TransferConfig) (*transfers.Engine, *scheduler.Scheduler) {
+	if err := os.MkdirAll(cfg.StagingDir, 0o700); err != nil {
+		middleware.Logger.Fatal("transfer staging directory unavailable",
+			zap.String("dir", cfg.StagingDir),
+			zap.Error(err),
+		)
+	}
+
+	store := handlers.TransferStore{DB: database.DB}
+	engine := transfers.NewEngine(store, store.Run, cfg.Engine)
+	handlers.SetTransferEngine(engine, cfg.StagingDir)
+	handlers.SetTransferPolicy(cfg.Policy)
+	handlers.SetTransferBandwidth(cfg.Bandwidth)
+	return engine, scheduler.New(store.ExpireStaging, 10*time.Minute)
+}
+
+// setupSchedules starts polling for recurring transfers and syncs that are
+// due. Every server polls; each due run is started by one of them.
+func setupSchedules(cfg ScheduleConfig) *scheduler.Scheduler {
+	store := handlers.ScheduleStore{DB: database.DB, Grace: cfg.Grace}
+	return scheduler.New(store.RunDue, cfg.Interval)
+}
+
+func setupRouter(cfg *Config, telemetryIngestor *telemetry.Ingestor, eventHub *events.Hub) *gin.Engine {
+	router, _ := buildRouter(cfg, telemetryIngestor, eventHub)
+	return router
+}
+
+func buildRouter(cfg *Config, telemetryIngestor *telemetry.Ingestor, eventHub *events.Hub) (*gin.Engine, *openapi.Spec) {
 	router := gin.Default()
+	apiSpec := openapi.NewSpec(openapi.Info{
+		Title:   "LiveCode API",
+		Version: apiVersion,
+		Description: "Clients that send `Accept: " + apierror.MediaTypeV2 + "` receive errors as RFC 9457 " +
+			"problem documents with stable codes and the status codes listed here. Other clients keep the legacy error bodies.",
+	})
+	validateRequest := apiSpec.ValidateRequests()
 
 	router.Use(middleware.PrometheusMiddleware())
 	router.Use(middleware.RequestLogger())
-
-	refreshTokenLimiter := middleware.NewRateLimiter(3, 3)
-	authLimiter := middleware.NewRateLimiter(5, 5)
-	checkFieldLimiter := middleware.NewRateLimiter(10, 10)
-	clientMonitoringLimiter := middleware.NewRateLimiter(2, 2)
-
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.Use(middleware.Localize())
+
+	refreshTokenLimiter := middleware.NewRateLimiter("refresh_token", 3, 3)
+	authLimiter := middleware.NewRateLimiter("auth", 5, 5)
+	checkFieldLimiter := middleware.NewRateLimiter("check_field", 10, 10)
+	challengeLimiter := middleware.NewRateLimiter("pow_challenge", 30, 10)
+	passwordStrengthLimiter := middleware.NewRateLimiter("password_strength", 60, 10)
+	usernameChangeLimiter := middleware.NewRateLimiter("username_change", 5, 5)
+	connectionsLimiter := middleware.NewRateLimiter("connections", 60, 20)
+	vaultLimiter := middleware.NewRateLimiter("vault", 60, 20)
+	sshKeysLimiter := middleware.NewRateLimiter("ssh_keys", 60, 20)
+	sshKeyCryptoLimiter := middleware.NewRateLimiter("ssh_key_crypto", 10, 3)
+	sshCALimiter := middleware.NewRateLimiter("ssh_ca", 60, 20)
+	sshCertificatesLimiter := middleware.NewRateLimiter("ssh_certificates", 30, 10)
+	knownHostsLimiter := middleware.NewRateLimiter("known_hosts", 120, 30)
+	sftpLimiter := middleware.NewRateLimiter("sftp", 300, 60)
+	transfersLimiter := middleware.NewRateLimiter("transfers", 300, 60)
+	eventsLimiter := middleware.NewRateLimiter("events", 30, 10)
+	syncLimiter := middleware.NewRateLimiter("sync", 30, 10)
+	searchLimiter := middleware.NewRateLimiter("remote_search", 30, 10)
+	schedulesLimiter := middleware.NewRateLimiter("schedules", 60, 20)
+	clientMonitoringLimiter := middleware.NewRateLimiter("client_monitoring", 2, 2)
+	telemetryLimiter := middleware.NewRateLimiter("telemetry_batch", 30, 10)
+	telemetryConsentLimiter := middleware.NewRateLimiter("telemetry_consent", 10, 5)
+
+	powDifficulty := pow.NewAdaptive(cfg.ProofOfWork.Difficulty, cfg.ProofOfWork.MaxDifficulty, cfg.ProofOfWork.HalfLife)
+	powIssuer := pow.NewIssuer(cfg.ProofOfWork.Key, cfg.ProofOfWork.TTL, powDifficulty, cfg.ProofOfWork.Enforced)
+	authLimiter.Notify(powDifficulty)
+	checkFieldLimiter.Notify(powDifficulty)
+	challengeLimiter.Notify(powDifficulty)
+
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{Registry: metrics.Registry})))
 	router.GET("/health", healthCheck)
 
 	v1 := router.Group("/api/v1")
+	if cfg.OpenAPIValidateResponses {
+		v1.Use(apiSpec.ValidateResponses())
+	}
 	{
+		v1.GET("/openapi.json", apiSpec.Handler())
+		v1.GET("/i18n/catalog", validateRequest, routes.GetMessageCatalog)
+
 		authRoutes := v1.Group("/auth")
+		authRoutes.Use(validateRequest)
 		{
 			authRoutes.POST("/refresh", refreshTokenLimiter.Limit(), middleware.ValidateRefreshToken(), routes.RefreshToken)
-			authRoutes.POST("/register", authLimiter.Limit(), middleware.ValidateRegisterInput(), routes.Register)
+			authRoutes.GET("/challenge", challengeLimiter.Limit(), routes.IssueChallenge(powIssuer))
+			authRoutes.POST("/register", authLimiter.Limit(), powIssuer.Require(pow.ScopeRegister), middleware.ValidateRegisterInput(), routes.Register)
 			authRoutes.POST("/login", authLimiter.Limit(), middleware.ValidateLoginInput(), routes.Login)
-			authRoutes.GET("/check-field", checkFieldLimiter.Limit(), middleware.ValidateCheckFieldAvailable(), routes.CheckFieldAvailable)
+			authRoutes.GET("/check-field", checkFieldLimiter.Limit(), powIssuer.Require(pow.ScopeCheckField), middleware.ValidateCheckFieldAvailable(), routes.CheckFieldAvailable)
+			authRoutes.POST("/password/strength", passwordStrengthLimiter.Limit(), routes.PasswordStrength)
 		}
 
 		clientMonitoringRoutes := v1.Group("/monitoring")
-		clientMonitoringRoutes.Use(clientMonitoringLimiter.Limit(), middleware.ValidateClientErrorLog())
+		clientMonitoringRoutes.Use(middleware.OptionalAuth(), validateRequest)
 		{
-			clientMonitoringRoutes.POST("/client-errors", routes.LogClientError)
+			clientMonitoringRoutes.POST("/client-errors", clientMonitoringLimiter.Limit(), middleware.ValidateClientErrorLog(), routes.LogClientError)
+			clientMonitoringRoutes.POST("/events", telemetryLimiter.Limit(), routes.IngestTelemetry(telemetryIngestor))
+			clientMonitoringRoutes.GET("/installs/:install_id/consent", telemetryConsentLimiter.Limit(), routes.GetTelemetryConsent(telemetryIngestor))
+			clientMonitoringRoutes.PUT("/installs/:install_id/consent", telemetryConsentLimiter.Limit(), routes.UpdateTelemetryConsent(telemetryIngestor))
+		}
+
+		sshCARoutes := v1.Group("/ssh-ca")
+		sshCARoutes.Use(sshCALimiter.Limit(), validateRequest)
+		{
+			sshCARoutes.GET("", routes.ListSSHCertificateAuthorities)
+			sshCARoutes.GET("/trusted-keys", routes.GetSSHTrustedKeys)
+			sshCARoutes.GET("/krl", routes.GetSSHKeyRevocationList)
 		}
 
 		protectedRoutes := v1.Group("")
-		protectedRoutes.Use(middleware.AuthMiddleware())
+		protectedRoutes.Use(middleware.AuthMiddleware(), validateRequest)
 		{
 			protectedRoutes.GET("/profile", routes.GetProfile)
+			protectedRoutes.PUT("/profile/username", usernameChangeLimiter.Limit(), middleware.ValidateUsernameChange(), routes.ChangeUsername)
+			protectedRoutes.GET("/connections", connectionsLimiter.Limit(), routes.ListConnections)
+			protectedRoutes.POST("/connections", connectionsLimiter.Limit(), middleware.ValidateConnectionInput(), routes.CreateConnection)
+			protectedRoutes.GET("/connections/sync", connectionsLimiter.Limit(), routes.SyncConnections)
+			protectedRoutes.GET("/connections/:id", connectionsLimiter.Limit(), routes.GetConnection)
+			protectedRoutes.PUT("/connections/:id", connectionsLimiter.Limit(), middleware.ValidateConnectionInput(), routes.UpdateConnection)
+			protectedRoutes.DELETE("/connections/:id", connectionsLimiter.Limit(), routes.DeleteConnection)
+			protectedRoutes.GET("/vault", vaultLimiter.Limit(), routes.GetVaultSettings)
+			protectedRoutes.PUT("/vault", vaultLimiter.Limit(), middleware.ValidateVaultSettings(), routes.UpdateVaultSettings)
+			protectedRoutes.GET("/vault/items", vaultLimiter.Limit(), routes.ListVaultItems)
+			protectedRoutes.POST("/vault/items", vaultLimiter.Limit(), middleware.ValidateVaultItemInput(), routes.CreateVaultItem)
+			protectedRoutes.GET("/vault/items/:id", vaultLimiter.Limit(), routes.GetVaultItem)
+			protectedRoutes.PUT("/vault/items/:id", vaultLimiter.Limit(), middleware.ValidateVaultItemInput(), routes.UpdateVaultItem)
+			protectedRoutes.DELETE("/vault/items/:id", vaultLimiter.Limit(), routes.DeleteVaultItem)
+			protectedRoutes.GET("/ssh-keys", sshKeysLimiter.Limit(), routes.ListSSHKeys)
+			protectedRoutes.POST("/ssh-keys", sshKeysLimiter.Limit(), middleware.ValidateSSHKeyInput(), routes.CreateSSHKey)
+			protectedRoutes.POST("/ssh-keys/generate", sshKeyCryptoLimiter.Limit(), middleware.ValidateSSHKeyGenerate(), routes.GenerateSSHKey)
+			protectedRoutes.POST("/ssh-keys/import", sshKeyCryptoLimiter.Limit(), middleware.ValidateSSHKeyImport(), routes.ImportSSHKey)
+			protectedRoutes.GET("/ssh-keys/:id", sshKeysLimiter.Limit(), routes.GetSSHKey)
+			protectedRoutes.POST("/ssh-keys/:id/export", sshKeyCryptoLimiter.Limit(), routes.ExportSSHKey)
+			protectedRoutes.DELETE("/ssh-keys/:id", sshKeysLimiter.Limit(), routes.DeleteSSHKey)
+			protectedRoutes.GET("/teams", sshCertificatesLimiter.Limit(), routes.ListMyTeams)
+			protectedRoutes.GET("/ssh-certificates", sshCertificatesLimiter.Limit(), routes.ListSSHCertificates)
+			protectedRoutes.POST("/ssh-certificates", sshCertificatesLimiter.Limit(),
+				middleware.ValidateSSHCertificateRequest(cfg.SSHCertificates.TTL, cfg.SSHCertificates.MaxTTL), routes.IssueSSHCertificate)
+			protectedRoutes.POST("/ssh-certificates/:serial/revoke", sshCertificatesLimiter.Limit(), routes.RevokeSSHCertificate)
+			protectedRoutes.GET("/known-hosts", knownHostsLimiter.Limit(), routes.ListKnownHosts)
+			protectedRoutes.POST("/known-hosts", knownHostsLimiter.Limit(), middleware.ValidateKnownHostInput(), routes.TrustKnownHost)
+			protectedRoutes.POST("/known-hosts/verify", knownHostsLimiter.Limit(), middleware.ValidateKnownHostInput(), routes.VerifyKnownHost)
+			protectedRoutes.POST("/known-hosts/import", sshKeysLimiter.Limit(), middleware.ValidateKnownHostsImport(), routes.ImportKnownHosts)
+			protectedRoutes.GET("/known-hosts/export", sshKeysLimiter.Limit(), routes.ExportKnownHosts)
+			protectedRoutes.DELETE("/known-hosts/:id", knownHostsLimiter.Limit(), routes.DeleteKnownHost)
+
+			protectedRoutes.GET("/connections/:id/sftp/list", sftpLimiter.Limit(), routes.ListRemoteFiles)
+			protectedRoutes.GET("/connections/:id/sftp/stat", sftpLimiter.Limit(), routes.StatRemoteFile)
+			protectedRoutes.GET("/connections/:id/sftp/download", sftpLimiter.Limit(), routes.DownloadRemoteFile)
+			protectedRoutes.PUT("/connections/:id/sftp/upload", sftpLimiter.Limit(), routes.UploadRemoteFile(cfg.SFTP.MaxUploadBytes))
+			protectedRoutes.POST("/connections/:id/sftp/mkdir", sftpLimiter.Limit(), middleware.ValidateRemoteMkdir(), routes.MakeRemoteDirectory)
+			protectedRoutes.POST("/connections/:id/sftp/rename", sftpLimiter.Limit(), middleware.ValidateRemoteRename(), routes.RenameRemoteFile)
+			protectedRoutes.POST("/connections/:id/sftp/delete", sftpLimiter.Limit(), middleware.ValidateRemoteDelete(), routes.DeleteRemoteFile)
+			protectedRoutes.POST("/connections/:id/sftp/chmod", sftpLimiter.Limit(), middleware.ValidateRemoteChmod(), routes.ChmodRemoteFile)
+			protectedRoutes.POST("/connections/:id/sftp/search", searchLimiter.Limit(), middleware.ValidateRemoteSearch(), routes.SearchRemoteFiles(cfg.Events.Heartbeat))
+
+			protectedRoutes.GET("/transfers", transfersLimiter.Limit(), routes.ListTransfers)
+			protectedRoutes.POST("/transfers", transfersLimiter.Limit(), middleware.ValidateTransferRequest(cfg.Transfers.Policy.MaxSize), routes.CreateTransfer)
+			protectedRoutes.GET("/transfers/:id", transfersLimiter.Limit(), routes.GetTransfer)
+			protectedRoutes.PUT("/transfers/:id/data", transfersLimiter.Limit(), routes.WriteTransferData)
+			protectedRoutes.GET("/transfers/:id/data", transfersLimiter.Limit(), routes.ReadTransferData)
+			protectedRoutes.POST("/transfers/:id/pause", transfersLimiter.Limit(), routes.PauseTransfer)
+			protectedRoutes.POST("/transfers/:id/resume", transfersLimiter.Limit(), routes.ResumeTransfer)
+			protectedRoutes.POST("/transfers/:id/cancel", transfersLimiter.Limit(), routes.CancelTransfer)
+			protectedRoutes.PUT("/transfers/:id/bandwidth", transfersLimiter.Limit(), middleware.ValidateTransferBandwidth(), routes.SetTransferBandwidth)
+			protectedRoutes.DELETE("/transfers/:id", transfersLimiter.Limit(), routes.DeleteTransfer)
+			protectedRoutes.GET("/bandwidth", transfersLimiter.Limit(), routes.GetBandwidthSettings)
+			protectedRoutes.PUT("/bandwidth", transfersLimiter.Limit(), middleware.ValidateBandwidthSettings(), routes.UpdateBandwidthSettings)
+			protectedRoutes.POST("/sync/plan", syncLimiter.Limit(), middleware.ValidateSyncRequest(cfg.Transfers.Policy.MaxSize), routes.PlanSync)
+			protectedRoutes.GET("/sync/plans/:id", syncLimiter.Limit(), routes.GetSyncPlan)
+			protectedRoutes.POST("/sync/plans/:id/execute", syncLimiter.Limit(), routes.ExecuteSyncPlan)
+			protectedRoutes.GET("/schedules", schedulesLimiter.Limit(), routes.ListSchedules)
+			protectedRoutes.POST("/schedules", schedulesLimiter.Limit(), middleware.ValidateScheduleRequest(cfg.Transfers.Policy.MaxSize), routes.CreateSchedule)
+			protectedRoutes.GET("/schedules/:id", schedulesLimiter.Limit(), routes.GetSchedule)
+			protectedRoutes.PUT("/schedules/:id", schedulesLimiter.Limit(), middleware.ValidateScheduleRequest(cfg.Transfers.Policy.MaxSize), routes.UpdateSchedule)
+			protectedRoutes.DELETE("/schedules/:id", schedulesLimiter.Limit(), routes.DeleteSchedule)
+			protectedRoutes.GET("/schedules/:id/runs", schedulesLimiter.Limit(), routes.ListScheduleRuns)
+
+			protectedRoutes.GET("/events", eventsLimiter.Limit(), routes.StreamEvents(eventHub, cfg.Events.Heartbeat))
+		}
+
+		adminRoutes := v1.Group("/admin")
+		adminRoutes.Use(middleware.AuthMiddleware(), middleware.RequireRole(models.RoleAdmin), validateRequest)
+		{
+			adminRoutes.GET("/client-issues", routes.ListClientIssues)
+			adminRoutes.GET("/client-issues/:id", routes.GetClientIssue)
+			adminRoutes.PATCH("/client-issues/:id", routes.UpdateClientIssueStatus)
+			adminRoutes.GET("/teams", routes.ListTeams)
+			adminRoutes.POST("/teams", middleware.ValidateTeamInput(), routes.CreateTeam)
+			adminRoutes.GET("/teams/:id", routes.GetTeam)
+			adminRoutes.PUT("/teams/:id", middleware.ValidateTeamInput(), routes.UpdateTeam)
+			adminRoutes.DELETE("/teams/:id", routes.DeleteTeam)
+			adminRoutes.GET("/teams/:id/members", routes.ListTeamMembers)
+			adminRoutes.PUT("/teams/:id/members/:user_id", routes.AddTeamMember)
+			adminRoutes.DELETE("/teams/:id/members/:user_id", routes.RemoveTeamMember)
+			adminRoutes.POST("/ssh-ca", sshKeyCryptoLimiter.Limit(), middleware.ValidateSSHCertificateAuthority(), routes.CreateSSHCertificateAuthority)
+			adminRoutes.GET("/ssh-certificates", routes.AdminListSSHCertificates)
+			adminRoutes.POST("/ssh-certificates/:serial/revoke", routes.AdminRevokeSSHCertificate)
+			adminRoutes.GET("/teams/:id/known-hosts", routes.ListTeamKnownHosts)
+			adminRoutes.POST("/teams/:id/known-hosts", middleware.ValidateKnownHostInput(), routes.PinKnownHost)
+			adminRoutes.DELETE("/teams/:id/known-hosts/:host_id", routes.UnpinKnownHost)
+			adminRoutes.GET("/known-host-events", routes.ListKnownHostEvents)
+			adminRoutes.GET("/bandwidth", routes.GetServerBandwidthSettings)
+			adminRoutes.PUT("/bandwidth", middleware.ValidateBandwidthSettings(), routes.UpdateServerBandwidthSettings)
 		}
 	}
 
-	return router
+	undocumented, err := apiSpec.Build(router.Routes(), routes.APIOperations())
+	if err != nil {
+		middleware.Logger.Fatal("openapi spec generation failed",
+			zap.Error(err),
+		)
+	}
+	if len(undocumented) > 0 {
+		middleware.Logger.Warn("routes missing from openapi spec",
+			zap.Strings("routes", undocumented),
+		)
+	}
+
+	return router, apiSpec
 }
 
 func healthCheck(c *gin.Context) {
 	if err := database.DB.Ping(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status":   "error",
-			"database": "disconnected",
-			"error":    err.Error(),
-		})
+		apierror.Write(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeServiceUnavailable, "Database is unreachable.").
+			WithLegacy(http.StatusServiceUnavailable, gin.H{
+				"status":   "error",
+				"database": "disconnected",
+				"error":    err.Error(),
+			}))
 		return
 	}
 
 	if err := database.CheckMigrationsApplied(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status":     "error",
-			"database":   "connected",
-			"migrations": "not_applied",
-			"error":      err.Error(),
-		})
+		apierror.Write(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeServiceUnavailable, "Database migrations are not applied.").
+			WithLegacy(http.StatusServiceUnavailable, gin.H{
+				"status":     "error",
+				"database":   "connected",
+				"migrations": "not_applied",
+				"error":      err.Error(),
+			}))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":     "ok",
-		"database":   "connected",
-		"migrations": "applied",
+	c.JSON(http.StatusOK, models.HealthStatus{
+		Status:     "ok",
+		Database:   "connected",
+		Migrations: "applied",
 	})
 }
 
-func runServer(router *gin.Engine, port string) {
+func runServer(router *gin.Engine, port string, eventHub *events.Hub, shutdownHooks ...func(context.Context) error) {
 	srv := &http.Server{
 		Addr:         ":" + port,
 		Handler:      router,
@@ -200,6 +852,9 @@ func runServer(router *gin.Engine, port string) {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	// Event streams never go idle, so Shutdown would wait for them until
+	// it times out.
+	srv.RegisterOnShutdown(eventHub.Close)
 
 	go func() {
 		middleware.Logger.Info("server starting",
@@ -228,5 +883,13 @@ func runServer(router *gin.Engine, port string) {
 		)
 	}
 
+	for _, hook := range shutdownHooks {
+		if err := hook(ctx); err != nil {
+			middleware.Logger.Error("shutdown hook failed",
+				zap.Error(err),
+			)
+		}
+	}
+
 	middleware.Logger.Info("server exited gracefully")
 }
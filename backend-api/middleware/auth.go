@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/metrics"
+	"livecode-api/models"
 	"livecode-api/utils"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func AuthMiddleware() gin.HandlerFunc {
@@ -13,40 +17,94 @@ func AuthMiddleware() gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "Authorization header required.",
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeAuthRequired, "Authorization header required."))
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "Invalid authorization format. Expected 'Bearer <token>'.",
-			})
-			c.Abort()
+		tokenString, ok := bearerToken(authHeader)
+		if !ok {
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeAuthMalformedHeader, "Invalid authorization format. Expected 'Bearer <token>'."))
 			return
 		}
 
-		tokenString := parts[1]
+		if !authenticate(c, tokenString) {
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeAuthInvalidToken, "Invalid or expired token."))
+			return
+		}
+
+		c.Next()
+	}
+}
 
-		_, claims, err := utils.VerifyJWT(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "Invalid or expired token.",
-			})
-			c.Abort()
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
 
-		c.Set("user_id", claims["user_id"])
-		c.Set("username", claims["username"])
-		c.Set("email", claims["email"])
+		tokenString, ok := bearerToken(authHeader)
+		if !ok || !authenticate(c, tokenString) {
+			GetLogger(c).Debug("optional_auth_ignored_invalid_token")
+		}
 
 		c.Next()
 	}
 }
+
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		GetLogger(c).Warn("role_check_failed",
+			zap.String("role", role),
+			zap.Strings("required_roles", roles),
+			zap.String("path", c.Request.URL.Path),
+		)
+		apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeAuthForbidden, "You do not have permission to access this resource."))
+	}
+}
+
+func bearerToken(authHeader string) (string, bool) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+func authenticate(c *gin.Context, tokenString string) bool {
+	_, claims, err := utils.VerifyJWT(tokenString)
+	if err != nil {
+		return false
+	}
+	if utils.TokenType(claims) != utils.TokenTypeAccess {
+		metrics.JWTVerificationFailuresTotal.WithLabelValues("wrong_type").Inc()
+		return false
+	}
+
+	userID, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	c.Set("user_id", userID)
+	c.Set("username", claims["username"])
+	c.Set("email", claims["email"])
+	c.Set("role", role)
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		c.Set("token_expires_at", expiresAt.Time)
+	}
+
+	return true
+}
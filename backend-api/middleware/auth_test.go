@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"livecode-api/config"
+	"livecode-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func TestAuthMiddleware_AcceptsOnlyAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Logger = zap.NewNop()
+	config.Init("test-secret")
+
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("user_id"))
+	})
+
+	tokens, err := utils.GenerateTokenPair("u1", "tester", "tester@example.com", "user")
+	if err != nil {
+		t.Fatalf("Failed to generate tokens: %v", err)
+	}
+	sign := func(claims jwt.MapClaims) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	cases := map[string]struct {
+		token string
+		want  int
+	}{
+		"access token":                   {tokens.AccessToken, http.StatusOK},
+		"refresh token":                  {tokens.RefreshToken, http.StatusUnauthorized},
+		"access token without typ":       {sign(jwt.MapClaims{"user_id": "u1", "username": "tester", "exp": exp}), http.StatusOK},
+		"refresh token without typ":      {sign(jwt.MapClaims{"user_id": "u1", "exp": exp}), http.StatusUnauthorized},
+		"refresh token posing as access": {sign(jwt.MapClaims{"user_id": "u1", "username": "tester", "typ": "refresh", "exp": exp}), http.StatusUnauthorized},
+	}
+	for name, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected status %d, got: %d", name, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"livecode-api/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Localize negotiates the response language from Accept-Language and stores
+// it under i18n.ContextKey for apierror and handlers that render messages.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.Default().Negotiate(c.GetHeader("Accept-Language"))
+
+		c.Set(i18n.ContextKey, locale)
+		c.Header("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"livecode-api/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestLocalize_NegotiatesErrorLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Logger = zap.NewNop()
+
+	router := gin.New()
+	router.Use(Localize())
+	router.GET("/check", ValidateCheckFieldAvailable())
+
+	req := httptest.NewRequest(http.MethodGet, "/check?field=email&value=nope", nil)
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.5")
+	req.Header.Set("Accept", apierror.MediaTypeV2)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Language"); got != "de" {
+		t.Errorf("Expected Content-Language de, got: %s", got)
+	}
+	if got := rec.Header().Values("Vary"); len(got) != 2 {
+		t.Errorf("Expected Vary on Accept-Language and Accept, got: %v", got)
+	}
+
+	var problem apierror.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+
+	if problem.Detail != "Validierung fehlgeschlagen" || len(problem.Errors) != 1 ||
+		problem.Errors[0].Message != "Ungültiges E-Mail-Format" {
+		t.Errorf("Expected German problem, got: %+v", problem)
+	}
+}
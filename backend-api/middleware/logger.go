@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -29,13 +28,15 @@ func InitLogger() {
 
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		correlationID := uuid.New().String()
+		correlationID, inbound := resolveRequestID(c)
 		c.Set("correlation_id", correlationID)
+		c.Header(ResponseRequestIDHeader(), correlationID)
 
 		start := time.Now()
 
 		Logger.Info("incoming_request",
 			zap.String("correlation_id", correlationID),
+			zap.Bool("correlation_id_inbound", inbound),
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.String("ip", c.ClientIP()),
@@ -46,20 +47,34 @@ func RequestLogger() gin.HandlerFunc {
 
 		duration := time.Since(start)
 
-		Logger.Info("request_completed",
+		fields := []zap.Field{
 			zap.String("correlation_id", correlationID),
 			zap.Int("status", c.Writer.Status()),
 			zap.Duration("duration", duration),
 			zap.Int("response_size", c.Writer.Size()),
-		)
+		}
+		if userID := c.GetString("user_id"); userID != "" {
+			fields = append(fields, zap.String("user_id", userID))
+		}
+
+		Logger.Info("request_completed", fields...)
 	}
 }
 
 func GetLogger(c *gin.Context) *zap.Logger {
-	correlationID, exists := c.Get("correlation_id")
-	if !exists {
+	fields := []zap.Field{}
+
+	if correlationID := c.GetString("correlation_id"); correlationID != "" {
+		fields = append(fields, zap.String("correlation_id", correlationID))
+	}
+
+	if userID := c.GetString("user_id"); userID != "" {
+		fields = append(fields, zap.String("user_id", userID))
+	}
+
+	if len(fields) == 0 {
 		return Logger
 	}
 
-	return Logger.With(zap.String("correlation_id", correlationID.(string)))
+	return Logger.With(fields...)
 }
@@ -5,6 +5,9 @@ import (
 	"sync"
 	"time"
 
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/metrics"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
@@ -15,17 +18,25 @@ type visitor struct {
 	lastSeen time.Time
 }
 
+// AbuseObserver is told about every request a RateLimiter rejects.
+type AbuseObserver interface {
+	ObserveAbuse(limiter, ip string)
+}
+
 type RateLimiter struct {
-	visitors map[string]*visitor
-	mu       sync.RWMutex
-	rate     rate.Limit
-	burst    int
+	name      string
+	visitors  map[string]*visitor
+	mu        sync.RWMutex
+	rate      rate.Limit
+	burst     int
+	observers []AbuseObserver
 }
 
-func NewRateLimiter(requestsPerMinute int, b int) *RateLimiter {
+func NewRateLimiter(name string, requestsPerMinute int, b int) *RateLimiter {
 	r := rate.Limit(float64(requestsPerMinute) / 60.0)
 
 	rl := &RateLimiter{
+		name:     name,
 		visitors: make(map[string]*visitor),
 		rate:     r,
 		burst:    b,
@@ -35,6 +46,13 @@ func NewRateLimiter(requestsPerMinute int, b int) *RateLimiter {
 	return rl
 }
 
+// Notify registers observer for this limiter's rejections. It must be
+// called before the limiter serves requests.
+func (rl *RateLimiter) Notify(observer AbuseObserver) *RateLimiter {
+	rl.observers = append(rl.observers, observer)
+	return rl
+}
+
 func (rl *RateLimiter) getVisitor(ip string) *rate.Limiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
@@ -70,16 +88,18 @@ func (rl *RateLimiter) Limit() gin.HandlerFunc {
 		limiter := rl.getVisitor(ip)
 
 		if !limiter.Allow() {
+			metrics.RateLimitRejectionsTotal.WithLabelValues(rl.name).Inc()
+			for _, observer := range rl.observers {
+				observer.ObserveAbuse(rl.name, ip)
+			}
+
 			Logger.Warn("rate_limit_exceeded",
+				zap.String("limiter", rl.name),
 				zap.String("ip", ip),
 				zap.String("path", c.Request.URL.Path),
 				zap.String("method", c.Request.Method),
 			)
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"success": false,
-				"message": "Rate limit exceeded. Please try again later.",
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "Rate limit exceeded. Please try again later."))
 			return
 		}
 
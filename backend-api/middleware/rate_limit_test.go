@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"livecode-api/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestRateLimiter_CountsRejectionsPerLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Logger = zap.NewNop()
+
+	limiter := NewRateLimiter("test_limiter", 1, 1)
+	router := gin.New()
+	router.GET("/limited", limiter.Limit(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	before := testutil.ToFloat64(metrics.RateLimitRejectionsTotal.WithLabelValues("test_limiter"))
+
+	statuses := []int{}
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/limited", nil))
+		statuses = append(statuses, rec.Code)
+	}
+
+	if statuses[0] != http.StatusOK {
+		t.Errorf("Expected first request to pass, got: %d", statuses[0])
+	}
+
+	if statuses[1] != http.StatusTooManyRequests || statuses[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected subsequent requests to be limited, got: %v", statuses)
+	}
+
+	after := testutil.ToFloat64(metrics.RateLimitRejectionsTotal.WithLabelValues("test_limiter"))
+	if after-before != 2 {
+		t.Errorf("Expected 2 rejections recorded, got: %v", after-before)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	requestIDHeaders = []string{"X-Request-ID"}
+	requestIDRegex   = regexp.MustCompile(`^[A-Za-z0-9._:-]{8,128}$`)
+)
+
+func SetRequestIDHeaders(headers []string) {
+	accepted := []string{}
+	for _, header := range headers {
+		header = strings.TrimSpace(header)
+		if header != "" {
+			accepted = append(accepted, http.CanonicalHeaderKey(header))
+		}
+	}
+
+	if len(accepted) > 0 {
+		requestIDHeaders = accepted
+	}
+}
+
+func ResponseRequestIDHeader() string {
+	return requestIDHeaders[0]
+}
+
+func IsValidRequestID(id string) bool {
+	return requestIDRegex.MatchString(id)
+}
+
+func resolveRequestID(c *gin.Context) (string, bool) {
+	for _, header := range requestIDHeaders {
+		id := strings.TrimSpace(c.GetHeader(header))
+		if id == "" {
+			continue
+		}
+
+		if IsValidRequestID(id) {
+			return id, true
+		}
+	}
+
+	return uuid.New().String(), false
+}
+
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("correlation_id")
+}
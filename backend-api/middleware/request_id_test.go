@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"livecode-api/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func newRequestIDTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	Logger = zap.NewNop()
+
+	router := gin.New()
+	router.Use(RequestLogger())
+	router.GET("/fail", func(c *gin.Context) {
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Something went wrong."))
+	})
+	return router
+}
+
+func TestRequestLogger_PropagatesInboundID(t *testing.T) {
+	SetRequestIDHeaders([]string{"X-Request-ID", "X-Correlation-ID"})
+	router := newRequestIDTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("X-Correlation-ID", "client-1234abcd")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Request-ID"); got != "client-1234abcd" {
+		t.Errorf("Expected response header 'client-1234abcd', got: %s", got)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+
+	if body["request_id"] != "client-1234abcd" {
+		t.Errorf("Expected request_id 'client-1234abcd' in body, got: %v", body["request_id"])
+	}
+}
+
+func TestRequestLogger_RejectsInvalidInboundID(t *testing.T) {
+	SetRequestIDHeaders([]string{"X-Request-ID"})
+	router := newRequestIDTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	got := rec.Header().Get("X-Request-ID")
+	if got == "" || got == "bad id\nwith newline" {
+		t.Fatalf("Expected a generated request ID, got: %q", got)
+	}
+
+	if !IsValidRequestID(got) {
+		t.Errorf("Expected generated ID to be valid, got: %s", got)
+	}
+}
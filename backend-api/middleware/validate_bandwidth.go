@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minBandwidthLimit keeps a limit from stalling transfers altogether.
+const minBandwidthLimit = 1024
+
+var bandwidthDays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// ValidateBandwidthSettings checks a user's or the server's limits.
+func ValidateBandwidthSettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.BandwidthSettings
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		var fields []models.FieldError
+		fields = append(fields, bandwidthLimitField("limit", payload.Limit)...)
+		if payload.Timezone == "" {
+			payload.Timezone = "UTC"
+		}
+		if !validTimezone(payload.Timezone) {
+			fields = append(fields, apierror.Field("timezone", apierror.FieldInvalidFormat, nil))
+		}
+
+		if len(payload.Windows) > 20 {
+			fields = append(fields, apierror.Field("windows", apierror.FieldTooManyItems, map[string]any{"max": 20}))
+			payload.Windows = nil
+		}
+		for i, window := range payload.Windows {
+			field := fmt.Sprintf("windows[%d]", i)
+			if slices.ContainsFunc(window.Days, func(day string) bool { return !slices.Contains(bandwidthDays, day) }) {
+				fields = append(fields, apierror.Field(field+".days", apierror.FieldNotAllowed, map[string]any{"allowed": bandwidthDays}))
+			}
+			if _, err := time.Parse("15:04", window.Start); err != nil {
+				fields = append(fields, apierror.Field(field+".start", apierror.FieldInvalidFormat, nil))
+			}
+			if _, err := time.Parse("15:04", window.End); err != nil {
+				fields = append(fields, apierror.Field(field+".end", apierror.FieldInvalidFormat, nil))
+			}
+			fields = append(fields, bandwidthLimitField(field+".limit", window.Limit)...)
+		}
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func ValidateTransferBandwidth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.TransferBandwidthRequest
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		if fields := bandwidthLimitField("limit", payload.Limit); len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func bandwidthLimitField(field string, limit *int64) []models.FieldError {
+	if limit != nil && *limit < minBandwidthLimit {
+		return []models.FieldError{apierror.Field(field, apierror.FieldTooSmall, map[string]any{"min": minBandwidthLimit})}
+	}
+	return nil
+}
+
+// validTimezone accepts IANA time zone names. Local would depend on the
+// server's settings.
+func validTimezone(name string) bool {
+	if name == "" || strings.EqualFold(name, "local") {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
@@ -1,31 +1,59 @@
 package middleware
 
 import (
+	"errors"
+	"livecode-api/internal/apierror"
 	"livecode-api/models"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
+const maxClientErrorBodyBytes = 256 << 10
+
 func ValidateClientErrorLog() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var payload models.ClientErrorLog
 
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxClientErrorBodyBytes)
+
 		if err := c.ShouldBindJSON(&payload); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Invalid request format",
-			})
-			c.Abort()
+			var maxBytesErr *http.MaxBytesError
+			var validationErrs validator.ValidationErrors
+
+			switch {
+			case errors.As(err, &maxBytesErr):
+				apierror.Abort(c, apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "Invalid request format").
+					WithLegacy(http.StatusBadRequest, nil))
+			case errors.As(err, &validationErrs):
+				apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid request format"))
+			default:
+				apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid request format"))
+			}
 			return
 		}
 
 		if len(payload.ErrorMessage) > 1000 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Error message too long (max 1000 characters)",
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Error message too long (max 1000 characters)").
+				WithFields(apierror.Field("error_message", apierror.FieldTooLong, map[string]any{"max": 1000})))
+			return
+		}
+
+		if payload.Timestamp.After(time.Now().Add(24 * time.Hour)) {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Timestamp must not be in the future").
+				WithFields(apierror.Field("timestamp", apierror.FieldInFuture, nil)))
+			return
+		}
+
+		if payload.RequestID != "" && !IsValidRequestID(payload.RequestID) {
+			payload.RequestID = ""
+		}
+
+		if clientErrorContainsNullBytes(payload) {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid characters detected").
+				WithFields(invalidCharacters("general")))
 			return
 		}
 
@@ -33,3 +61,22 @@ func ValidateClientErrorLog() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func clientErrorContainsNullBytes(payload models.ClientErrorLog) bool {
+	fields := []string{payload.ErrorType, payload.ErrorMessage, payload.StackTrace, payload.AppVersion, payload.OS}
+
+	for _, breadcrumb := range payload.Breadcrumbs {
+		fields = append(fields, breadcrumb.Category, breadcrumb.Level, breadcrumb.Message)
+		for key, value := range breadcrumb.Data {
+			fields = append(fields, key, value)
+		}
+	}
+
+	for _, field := range fields {
+		if containsNullBytes(field) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/idna"
+)
+
+const maxConnectionBodyBytes = 64 << 10
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+var connectionProtocols = []string{
+	models.ProtocolSFTP, models.ProtocolSCP, models.ProtocolFTP, models.ProtocolFTPS, models.ProtocolWebDAV, models.ProtocolS3,
+}
+
+// connectionAuthMethods lists the auth methods each protocol supports; the
+// first is the default.
+var connectionAuthMethods = map[string][]string{
+	models.ProtocolSFTP:   {models.AuthMethodPassword, models.AuthMethodPublicKey, models.AuthMethodAgent, models.AuthMethodKeyboardInteractive},
+	models.ProtocolSCP:    {models.AuthMethodPassword, models.AuthMethodPublicKey, models.AuthMethodAgent, models.AuthMethodKeyboardInteractive},
+	models.ProtocolFTP:    {models.AuthMethodPassword, models.AuthMethodAnonymous},
+	models.ProtocolFTPS:   {models.AuthMethodPassword, models.AuthMethodAnonymous},
+	models.ProtocolWebDAV: {models.AuthMethodPassword, models.AuthMethodAnonymous},
+	models.ProtocolS3:     {models.AuthMethodAccessKey, models.AuthMethodAnonymous},
+}
+
+// ValidateConnectionInput normalises a saved connection and applies the
+// checks the request schema cannot express: per-protocol auth methods and
+// options, default ports, host names, folder paths and tags.
+func ValidateConnectionInput() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.ConnectionInput
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxConnectionBodyBytes)
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+			return
+		}
+
+		if fields := normalizeConnection(&payload); len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func normalizeConnection(payload *models.ConnectionInput) []models.FieldError {
+	var fields []models.FieldError
+
+	for _, field := range []struct{ name, value string }{
+		{"name", payload.Name}, {"host", payload.Host}, {"username", payload.Username},
+		{"remote_directory", payload.RemoteDirectory}, {"local_directory", payload.LocalDirectory},
+		{"folder", payload.Folder}, {"notes", payload.Notes},
+	} {
+		if containsNullBytes(field.value) || !utf8.ValidString(field.value) {
+			fields = append(fields, invalidCharacters(field.name))
+		}
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		fields = append(fields, apierror.Field("name", apierror.FieldRequired, nil))
+	} else if strings.ContainsFunc(payload.Name, unicode.IsControl) {
+		fields = append(fields, invalidCharacters("name"))
+	}
+
+	methods, ok := connectionAuthMethods[payload.Protocol]
+	if !ok {
+		return append(fields, apierror.Field("protocol", apierror.FieldNotAllowed, map[string]any{"allowed": connectionProtocols}))
+	}
+
+	host, ok := normalizeHost(payload.Host)
+	if !ok {
+		fields = append(fields, apierror.Field("host", apierror.FieldInvalidFormat, nil))
+	}
+	payload.Host = host
+
+	if payload.AuthMethod == "" {
+		payload.AuthMethod = methods[0]
+	} else if !slices.Contains(methods, payload.AuthMethod) {
+		fields = append(fields, apierror.Field("auth_method", apierror.FieldNotAllowed, map[string]any{"allowed": methods}))
+	}
+
+	payload.Username = strings.TrimSpace(payload.Username)
+	if payload.AuthMethod == models.AuthMethodAnonymous {
+		payload.Username = ""
+	}
+
+	payload.Options = connectionOptions(payload.Protocol, payload.Options)
+	if payload.Port == 0 {
+		payload.Port = defaultPort(payload.Protocol, payload.Options)
+	}
+
+	payload.RemoteDirectory = strings.TrimSpace(payload.RemoteDirectory)
+	if payload.RemoteDirectory != "" && !strings.HasPrefix(payload.RemoteDirectory, "/") {
+		fields = append(fields, apierror.Field("remote_directory", apierror.FieldInvalidFormat, nil))
+	}
+	payload.LocalDirectory = strings.TrimSpace(payload.LocalDirectory)
+
+	folder, ok := normalizeFolder(payload.Folder)
+	if !ok {
+		fields = append(fields, apierror.Field("folder", apierror.FieldInvalidFormat, nil))
+	}
+	payload.Folder = folder
+
+	payload.Tags = normalizeTags(payload.Tags)
+	for _, tag := range payload.Tags {
+		if containsNullBytes(tag) || strings.ContainsFunc(tag, unicode.IsControl) {
+			fields = append(fields, invalidCharacters("tags"))
+			break
+		}
+	}
+
+	payload.Color = strings.ToLower(strings.TrimSpace(payload.Color))
+	if payload.Color != "" && !colorPattern.MatchString(payload.Color) {
+		fields = append(fields, apierror.Field("color", apierror.FieldInvalidFormat, nil))
+	}
+
+	return fields
+}
+
+// normalizeHost lower-cases host names and converts them to their IDNA ASCII
+// form. IP addresses, with or without IPv6 brackets, are kept as they are.
+func normalizeHost(host string) (string, bool) {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")); ip != nil {
+		return ip.String(), true
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil || ascii == "" || len(ascii) > 253 {
+		return host, false
+	}
+	return ascii, true
+}
+
+// normalizeFolder trims each segment of a slash-separated folder path and
+// rejects empty segments, so " Clients / Acme/" becomes "Clients/Acme".
+func normalizeFolder(folder string) (string, bool) {
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	if folder == "" {
+		return "", true
+	}
+
+	segments := strings.Split(folder, "/")
+	for i, segment := range segments {
+		segments[i] = strings.TrimSpace(segment)
+		if segments[i] == "" || strings.ContainsFunc(segments[i], unicode.IsControl) {
+			return folder, false
+		}
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// normalizeTags trims tags and drops empty and case-insensitive duplicates,
+// keeping the first spelling.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// connectionOptions keeps only the options that apply to protocol and fills
+// in their defaults.
+func connectionOptions(protocol string, options models.ConnectionOptions) models.ConnectionOptions {
+	switch protocol {
+	case models.ProtocolFTP:
+		return models.ConnectionOptions{ActiveMode: options.ActiveMode}
+	case models.ProtocolFTPS:
+		mode := options.FTPSMode
+		if mode == "" {
+			mode = "explicit"
+		}
+		return models.ConnectionOptions{FTPSMode: mode, ActiveMode: options.ActiveMode}
+	case models.ProtocolWebDAV:
+		scheme := options.WebDAVScheme
+		if scheme == "" {
+			scheme = "https"
+		}
+		return models.ConnectionOptions{WebDAVScheme: scheme}
+	case models.ProtocolS3:
+		scheme := options.S3Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+		return models.ConnectionOptions{
+			Bucket:    strings.TrimSpace(options.Bucket),
+			Region:    strings.TrimSpace(options.Region),
+			PathStyle: options.PathStyle,
+			S3Scheme:  scheme,
+		}
+	}
+	return models.ConnectionOptions{}
+}
+
+func defaultPort(protocol string, options models.ConnectionOptions) int {
+	switch protocol {
+	case models.ProtocolFTP:
+		return 21
+	case models.ProtocolFTPS:
+		if options.FTPSMode == "implicit" {
+			return 990
+		}
+		return 21
+	case models.ProtocolWebDAV:
+		if options.WebDAVScheme == "http" {
+			return 80
+		}
+		return 443
+	case models.ProtocolS3:
+		if options.S3Scheme == "http" {
+			return 80
+		}
+		return 443
+	}
+	return 22
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"testing"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/models"
+)
+
+func TestNormalizeConnection_AppliesDefaults(t *testing.T) {
+	payload := models.ConnectionInput{
+		Name:     "  Staging ",
+		Protocol: models.ProtocolFTPS,
+		Host:     "Files.Bücher.Example.",
+		Folder:   " Clients / Acme/",
+		Tags:     []string{"web", " Web", "", "prod"},
+		Color:    "#2F80ED",
+		Options:  models.ConnectionOptions{FTPSMode: "implicit", Bucket: "dropped"},
+	}
+
+	if fields := normalizeConnection(&payload); len(fields) > 0 {
+		t.Fatalf("Expected no errors, got: %v", fields)
+	}
+
+	if payload.Name != "Staging" || payload.Host != "files.xn--bcher-kva.example" || payload.Port != 990 {
+		t.Errorf("Unexpected name, host or port: %q %q %d", payload.Name, payload.Host, payload.Port)
+	}
+	if payload.AuthMethod != models.AuthMethodPassword {
+		t.Errorf("Expected default auth method password, got: %q", payload.AuthMethod)
+	}
+	if payload.Folder != "Clients/Acme" || payload.Color != "#2f80ed" {
+		t.Errorf("Unexpected folder or color: %q %q", payload.Folder, payload.Color)
+	}
+	if len(payload.Tags) != 2 || payload.Tags[0] != "web" || payload.Tags[1] != "prod" {
+		t.Errorf("Expected deduplicated tags, got: %v", payload.Tags)
+	}
+	if payload.Options.Bucket != "" {
+		t.Errorf("Expected S3 options to be dropped for FTPS, got: %+v", payload.Options)
+	}
+}
+
+func TestNormalizeConnection_RejectsInvalidFields(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload models.ConnectionInput
+		field   string
+		code    apierror.Code
+	}{
+		{"unsupported auth", models.ConnectionInput{Name: "a", Protocol: models.ProtocolS3, Host: "s3.example.com", AuthMethod: models.AuthMethodPublicKey}, "auth_method", apierror.FieldNotAllowed},
+		{"bad host", models.ConnectionInput{Name: "a", Protocol: models.ProtocolSFTP, Host: "exa mple.com"}, "host", apierror.FieldInvalidFormat},
+		{"relative remote dir", models.ConnectionInput{Name: "a", Protocol: models.ProtocolSFTP, Host: "[2001:db8::1]", RemoteDirectory: "var/www"}, "remote_directory", apierror.FieldInvalidFormat},
+		{"empty folder segment", models.ConnectionInput{Name: "a", Protocol: models.ProtocolSFTP, Host: "example.com", Folder: "a//b"}, "folder", apierror.FieldInvalidFormat},
+		{"blank name", models.ConnectionInput{Name: "  ", Protocol: models.ProtocolSFTP, Host: "example.com"}, "name", apierror.FieldRequired},
+		{"null byte", models.ConnectionInput{Name: "a", Protocol: models.ProtocolSFTP, Host: "example.com", Notes: "x\x00"}, "notes", apierror.FieldInvalidCharacters},
+	}
+
+	for _, tc := range cases {
+		fields := normalizeConnection(&tc.payload)
+		if len(fields) != 1 || fields[0].Field != tc.field || fields[0].Code != string(tc.code) {
+			t.Errorf("%s: expected %s on %s, got: %v", tc.name, tc.code, tc.field, fields)
+		}
+	}
+}
+
+func TestNormalizeConnection_S3SchemeDefaultsPort(t *testing.T) {
+	payload := models.ConnectionInput{
+		Name:     "Local MinIO",
+		Protocol: models.ProtocolS3,
+		Host:     "minio.internal",
+		Options:  models.ConnectionOptions{S3Scheme: "http", FTPSMode: "implicit"},
+	}
+
+	if fields := normalizeConnection(&payload); len(fields) > 0 {
+		t.Fatalf("Expected no errors, got: %v", fields)
+	}
+	if payload.Port != 80 || payload.Options.S3Scheme != "http" || payload.Options.FTPSMode != "" {
+		t.Errorf("Expected port 80 over http without FTPS options, got %d, %+v", payload.Port, payload.Options)
+	}
+
+	payload = models.ConnectionInput{Name: "AWS", Protocol: models.ProtocolS3, Host: "s3.amazonaws.com"}
+	normalizeConnection(&payload)
+	if payload.Port != 443 || payload.Options.S3Scheme != "https" {
+		t.Errorf("Expected https on 443 by default, got %d, %+v", payload.Port, payload.Options)
+	}
+}
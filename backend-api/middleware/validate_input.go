@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
-	"regexp"
 	"strings"
+	"unicode/utf8"
 
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/emails"
+	"livecode-api/internal/passwords"
+	"livecode-api/internal/usernames"
 	"livecode-api/models"
 
 	"github.com/gin-gonic/gin"
@@ -12,12 +17,9 @@ import (
 )
 
 var (
-	emailRegex    = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
-	usernameRegex = regexp.MustCompile(`^@[a-z0-9]{3,16}$`)
-	lowerRegex    = regexp.MustCompile(`[a-z]`)
-	upperRegex    = regexp.MustCompile(`[A-Z]`)
-	digitRegex    = regexp.MustCompile(`\d`)
-	specialRegex  = regexp.MustCompile(`[^A-Za-z0-9]`)
+	emailPolicy    = emails.DefaultPolicy()
+	passwordPolicy = passwords.DefaultPolicy()
+	usernamePolicy = usernames.DefaultPolicy()
 )
 
 func ValidateCheckFieldAvailable() gin.HandlerFunc {
@@ -30,25 +32,29 @@ func ValidateCheckFieldAvailable() gin.HandlerFunc {
 				zap.String("field", field),
 				zap.String("value_empty", value),
 			)
-			c.JSON(http.StatusBadRequest, gin.H{"available": nil})
-			c.Abort()
+			missing := []models.FieldError{}
+			if field == "" {
+				missing = append(missing, apierror.Field("field", apierror.FieldRequired, nil))
+			}
+			if value == "" {
+				missing = append(missing, apierror.Field("value", apierror.FieldRequired, nil))
+			}
+			abortCheckField(c, missing...)
 			return
 		}
 
-		value = strings.TrimSpace(strings.ToLower(value))
-
 		var validationErr *models.FieldError
 		switch field {
 		case "email":
-			validationErr = ValidateEmail(value)
+			value, validationErr = ValidateEmail(c, value)
 		case "username":
+			value = usernames.Normalize(value)
 			validationErr = ValidateUsername(value)
 		default:
 			GetLogger(c).Warn("check_field_invalid_field_type",
 				zap.String("field", field),
 			)
-			c.JSON(http.StatusBadRequest, gin.H{"available": nil})
-			c.Abort()
+			abortCheckField(c, apierror.Field("field", apierror.FieldNotAllowed, map[string]any{"allowed": []string{"email", "username"}}))
 			return
 		}
 
@@ -57,8 +63,7 @@ func ValidateCheckFieldAvailable() gin.HandlerFunc {
 				zap.String("field", field),
 				zap.String("error", validationErr.Message),
 			)
-			c.JSON(http.StatusBadRequest, gin.H{"available": nil})
-			c.Abort()
+			abortCheckField(c, *validationErr)
 			return
 		}
 
@@ -66,8 +71,7 @@ func ValidateCheckFieldAvailable() gin.HandlerFunc {
 			GetLogger(c).Warn("check_field_null_bytes",
 				zap.String("field", field),
 			)
-			c.JSON(http.StatusBadRequest, gin.H{"available": nil})
-			c.Abort()
+			abortCheckField(c, invalidCharacters("value"))
 			return
 		}
 
@@ -77,29 +81,50 @@ func ValidateCheckFieldAvailable() gin.HandlerFunc {
 	}
 }
 
+func abortCheckField(c *gin.Context, fields ...models.FieldError) {
+	apierror.Abort(c, apierror.Validation(fields...).WithLegacy(http.StatusBadRequest, gin.H{"available": nil}))
+}
+
+func SetPasswordPolicy(policy *passwords.Policy) {
+	passwordPolicy = policy
+}
+
+func PasswordPolicy() *passwords.Policy {
+	return passwordPolicy
+}
+
+func SetEmailPolicy(policy *emails.Policy) {
+	emailPolicy = policy
+}
+
+func EmailPolicy() *emails.Policy {
+	return emailPolicy
+}
+
+func SetUsernamePolicy(policy *usernames.Policy) {
+	usernamePolicy = policy
+}
+
+func UsernamePolicy() *usernames.Policy {
+	return usernamePolicy
+}
+
 func ValidateRegisterInput() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var payload struct {
-			Email    string `json:"email"`
-			Username string `json:"username"`
-			Password string `json:"password"`
-		}
+		var payload models.RegisterRequest
 
-		if err := c.ShouldBindJSON(&payload); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Invalid JSON format",
-			})
-			c.Abort()
+		if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
 			return
 		}
 
-		payload.Email = strings.TrimSpace(strings.ToLower(payload.Email))
-		payload.Username = strings.TrimSpace(strings.ToLower(payload.Username))
+		payload.Username = usernames.Normalize(payload.Username)
 
 		errors := []models.FieldError{}
 
-		if emailErr := ValidateEmail(payload.Email); emailErr != nil {
+		email, emailErr := ValidateEmail(c, payload.Email)
+		payload.Email = email
+		if emailErr != nil {
 			errors = append(errors, *emailErr)
 		}
 
@@ -107,23 +132,18 @@ func ValidateRegisterInput() gin.HandlerFunc {
 			errors = append(errors, *usernameErr)
 		}
 
-		passwordErrors := ValidatePassword(payload.Password)
+		passwordErrors := ValidatePassword(c, payload.Password, passwords.UserContext{
+			Username: payload.Username,
+			Email:    payload.Email,
+		})
 		errors = append(errors, passwordErrors...)
 
 		if containsNullBytes(payload.Email) || containsNullBytes(payload.Username) || containsNullBytes(payload.Password) {
-			errors = append(errors, models.FieldError{
-				Field:   "general",
-				Message: "Invalid characters detected",
-			})
+			errors = append(errors, invalidCharacters("general"))
 		}
 
 		if len(errors) > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Validation failed",
-				"errors":  errors,
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.Validation(errors...))
 			return
 		}
 
@@ -132,68 +152,72 @@ func ValidateRegisterInput() gin.HandlerFunc {
 	}
 }
 
-func ValidateLoginInput() gin.HandlerFunc {
+func ValidateUsernameChange() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var payload struct {
-			Identifier string `json:"identifier"`
-			Password   string `json:"password"`
+		var payload models.ChangeUsernameRequest
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+			return
+		}
+
+		payload.Username = usernames.Normalize(payload.Username)
+
+		if containsNullBytes(payload.Username) {
+			apierror.Abort(c, apierror.Validation(invalidCharacters("username")))
+			return
 		}
 
-		if err := c.ShouldBindJSON(&payload); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Invalid JSON format",
-			})
-			c.Abort()
+		if usernameErr := ValidateUsername(payload.Username); usernameErr != nil {
+			apierror.Abort(c, apierror.Validation(*usernameErr))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func ValidateLoginInput() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.LoginRequest
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
 			return
 		}
 
 		payload.Identifier = strings.TrimSpace(strings.ToLower(payload.Identifier))
+		if strings.HasPrefix(payload.Identifier, usernames.Prefix) {
+			payload.Identifier = usernames.Normalize(payload.Identifier)
+		} else if email, err := emails.Normalize(payload.Identifier); err == nil {
+			payload.Identifier = email
+		}
 
 		errors := []models.FieldError{}
 
 		if len(payload.Identifier) == 0 {
-			errors = append(errors, models.FieldError{
-				Field:   "identifier",
-				Message: "Email or username is required",
-			})
+			errors = append(errors, apierror.Field("identifier", apierror.FieldRequired, nil))
 		}
 
 		if len(payload.Identifier) > 255 {
-			errors = append(errors, models.FieldError{
-				Field:   "identifier",
-				Message: "Email or username must not exceed 255 characters",
-			})
+			errors = append(errors, apierror.Field("identifier", apierror.FieldTooLong, map[string]any{"max": 255}))
 		}
 
 		if len(payload.Password) == 0 {
-			errors = append(errors, models.FieldError{
-				Field:   "password",
-				Message: "Password is required",
-			})
+			errors = append(errors, apierror.Field("password", apierror.FieldRequired, nil))
 		}
 
-		if len(payload.Password) > 72 {
-			errors = append(errors, models.FieldError{
-				Field:   "password",
-				Message: "Password must not exceed 72 characters",
-			})
+		if utf8.RuneCountInString(payload.Password) > passwords.MaxInputLength {
+			errors = append(errors, apierror.Field("password", apierror.FieldTooLong, map[string]any{"max": passwords.MaxInputLength}))
 		}
 
 		if containsNullBytes(payload.Identifier) || containsNullBytes(payload.Password) {
-			errors = append(errors, models.FieldError{
-				Field:   "general",
-				Message: "Invalid characters detected",
-			})
+			errors = append(errors, invalidCharacters("general"))
 		}
 
 		if len(errors) > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Validation failed",
-				"errors":  errors,
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.Validation(errors...))
 			return
 		}
 
@@ -202,74 +226,45 @@ func ValidateLoginInput() gin.HandlerFunc {
 	}
 }
 
+func invalidCharacters(field string) models.FieldError {
+	return apierror.Field(field, apierror.FieldInvalidCharacters, nil)
+}
+
 func containsNullBytes(s string) bool {
 	return strings.Contains(s, "\x00")
 }
 
-func ValidateEmail(email string) *models.FieldError {
-	if len(email) > 255 {
-		return &models.FieldError{
-			Field:   "email",
-			Message: "Email must not exceed 255 characters",
-		}
+// ValidateEmail checks email against the email policy and returns it
+// normalised. A failing MX lookup is logged and the address accepted.
+func ValidateEmail(c *gin.Context, email string) (string, *models.FieldError) {
+	normalized, errors, err := emailPolicy.Check(c.Request.Context(), email)
+	if err != nil {
+		GetLogger(c).Error("email_mx_lookup_failed",
+			zap.Error(err),
+		)
 	}
 
-	if !emailRegex.MatchString(email) {
-		return &models.FieldError{
-			Field:   "email",
-			Message: "Invalid email format",
-		}
+	if len(errors) > 0 {
+		return normalized, &errors[0]
 	}
-
-	return nil
+	return normalized, nil
 }
 
+// ValidateUsername checks a handle already passed through usernames.Normalize.
 func ValidateUsername(username string) *models.FieldError {
-	if len(username) > 17 {
-		return &models.FieldError{
-			Field:   "username",
-			Message: "Username must not exceed 17 characters",
-		}
-	}
-
-	if !usernameRegex.MatchString(username) {
-		return &models.FieldError{
-			Field:   "username",
-			Message: "Username must start with @ and contain 3-16 lowercase letters or digits",
-		}
+	if errors := usernamePolicy.Check(username); len(errors) > 0 {
+		return &errors[0]
 	}
-
 	return nil
 }
 
-func ValidatePassword(password string) []models.FieldError {
-	errors := []models.FieldError{}
-
-	if len(password) < 8 {
-		errors = append(errors, models.FieldError{
-			Field:   "password",
-			Message: "Password must be at least 8 characters",
-		})
-	}
-
-	if len(password) > 72 {
-		errors = append(errors, models.FieldError{
-			Field:   "password",
-			Message: "Password must not exceed 72 characters",
-		})
-	}
-
-	hasLower := lowerRegex.MatchString(password)
-	hasUpper := upperRegex.MatchString(password)
-	hasDigit := digitRegex.MatchString(password)
-	hasSpecial := specialRegex.MatchString(password)
-
-	if !hasLower || !hasUpper || !hasDigit || !hasSpecial {
-		errors = append(errors, models.FieldError{
-			Field:   "password",
-			Message: "Password must include lowercase, uppercase, number and special character",
-		})
+func ValidatePassword(c *gin.Context, password string, user passwords.UserContext) []models.FieldError {
+	evaluation, err := passwordPolicy.Evaluate(c.Request.Context(), password, user)
+	if err != nil {
+		GetLogger(c).Error("password_breach_lookup_failed",
+			zap.Error(err),
+		)
 	}
 
-	return errors
+	return evaluation.Errors
 }
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxKnownHostsBodyBytes = 1 << 20
+
+// ValidateKnownHostInput normalises the host the way saved connections do
+// and resolves it to its known_hosts address. It serves both trusting and
+// verifying a host key.
+func ValidateKnownHostInput() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.KnownHostInput
+		if !decodeSSHKeyBody(c, &payload) {
+			return
+		}
+
+		var fields []models.FieldError
+
+		host, ok := normalizeHost(payload.Host)
+		if !ok || strings.ContainsAny(host, "|*?!,") {
+			fields = append(fields, apierror.Field("host", apierror.FieldInvalidFormat, nil))
+		}
+		if payload.Port == 0 {
+			payload.Port = 22
+		}
+
+		key, _, err := sshkeys.ParsePublicKey(payload.PublicKey)
+		var info sshkeys.Info
+		if err == nil {
+			info, err = sshkeys.Describe(key)
+		}
+		if err != nil {
+			fields = append(fields, SSHKeyFieldError("public_key", err))
+		}
+
+		payload.Comment = strings.TrimSpace(payload.Comment)
+		if strings.ContainsFunc(payload.Comment, unicode.IsControl) {
+			fields = append(fields, invalidCharacters("comment"))
+		}
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", models.KnownHostKey{
+			Address:   sshkeys.KnownHostAddress(host, payload.Port),
+			PublicKey: info.AuthorizedKey,
+			Comment:   payload.Comment,
+			Replace:   payload.Replace,
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+		c.Next()
+	}
+}
+
+// ValidateKnownHostsImport only limits the body; the file itself is parsed
+// by the handler.
+func ValidateKnownHostsImport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.KnownHostsImportRequest
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxKnownHostsBodyBytes)
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				apierror.Abort(c, apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "The known_hosts file is too large."))
+				return
+			}
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+			return
+		}
+
+		if containsNullBytes(payload.KnownHosts) {
+			apierror.Abort(c, apierror.Validation(invalidCharacters("known_hosts")))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
@@ -3,15 +3,16 @@ package middleware
 import (
 	"net/http"
 
+	"livecode-api/internal/apierror"
+	"livecode-api/models"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 func ValidateRefreshToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			RefreshToken string `json:"refresh_token" binding:"required,max=500"`
-		}
+		var req models.RefreshTokenRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
 			GetLogger(c).Warn("refresh_token_invalid_payload",
@@ -19,11 +20,7 @@ func ValidateRefreshToken() gin.HandlerFunc {
 				zap.String("ip", c.ClientIP()),
 			)
 
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "Invalid refresh token format.",
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid refresh token format."))
 			return
 		}
 
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/remotesearch"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxRemoteFileBodyBytes = 64 << 10
+
+// CleanRemotePath cleans a path on a remote server. Relative paths are
+// kept relative, so they resolve against the login directory.
+func CleanRemotePath(name string) (string, bool) {
+	if name == "" || containsNullBytes(name) {
+		return "", false
+	}
+	return path.Clean(name), true
+}
+
+// remotePathField cleans a path from a request body and reports it under
+// field when it is invalid. Operations that remove or replace name also
+// refuse the root and login directories.
+func remotePathField(field string, name *string, destructive bool) []models.FieldError {
+	cleaned, ok := CleanRemotePath(*name)
+	if !ok {
+		return []models.FieldError{invalidCharacters(field)}
+	}
+	if destructive && (cleaned == "/" || cleaned == ".") {
+		return []models.FieldError{apierror.Field(field, apierror.FieldRemotePathProtected, nil)}
+	}
+
+	*name = cleaned
+	return nil
+}
+
+func decodeRemoteFileBody(c *gin.Context, payload any) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRemoteFileBodyBytes)
+
+	if err := json.NewDecoder(c.Request.Body).Decode(payload); err != nil {
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+		return false
+	}
+	return true
+}
+
+func ValidateRemoteMkdir() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.RemoteMkdirRequest
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		if fields := remotePathField("path", &payload.Path, false); len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func ValidateRemoteRename() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.RemoteRenameRequest
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		fields := remotePathField("from", &payload.From, true)
+		fields = append(fields, remotePathField("to", &payload.To, true)...)
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func ValidateRemoteDelete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.RemoteDeleteRequest
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		if fields := remotePathField("path", &payload.Path, true); len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+// ValidateRemoteChmod parses the octal mode, including the setuid, setgid
+// and sticky bits.
+func ValidateRemoteChmod() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.RemoteChmodRequest
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		fields := remotePathField("path", &payload.Path, false)
+		mode, err := strconv.ParseUint(payload.Mode, 8, 32)
+		if err != nil || mode > 0o7777 {
+			fields = append(fields, apierror.Field("mode", apierror.FieldInvalidFormat, nil))
+		}
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", models.RemoteChmod{Path: payload.Path, Mode: uint32(mode)})
+		c.Next()
+	}
+}
+
+// ValidateRemoteSearch checks the globs and patterns of a search, which
+// needs a name, content or both.
+func ValidateRemoteSearch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.RemoteSearchRequest
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		var fields []models.FieldError
+		if payload.Path != "" {
+			fields = append(fields, remotePathField("path", &payload.Path, false)...)
+		}
+		if payload.Name == "" && payload.Content == "" {
+			fields = append(fields,
+				apierror.Field("name", apierror.FieldRequired, nil),
+				apierror.Field("content", apierror.FieldRequired, nil))
+		}
+		if containsNullBytes(payload.Name) {
+			fields = append(fields, invalidCharacters("name"))
+		} else if !remotesearch.ValidName(payload.Name) {
+			fields = append(fields, apierror.Field("name", apierror.FieldInvalidFormat, nil))
+		}
+		if containsNullBytes(payload.Content) || strings.Contains(payload.Content, "\n") {
+			fields = append(fields, invalidCharacters("content"))
+		} else if !remotesearch.ValidContent(payload.Content, payload.Regexp) {
+			fields = append(fields, apierror.Field("content", apierror.FieldInvalidFormat, nil))
+		}
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/scheduler"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ValidateScheduleRequest checks a recurring job. A sync schedule carries
+// the client's file list, so the body is read like a sync request.
+func ValidateScheduleRequest(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.ScheduleRequest
+		if !decodeSyncBody(c, &payload) {
+			return
+		}
+
+		var fields []models.FieldError
+
+		payload.Name = strings.TrimSpace(payload.Name)
+		switch {
+		case payload.Name == "":
+			fields = append(fields, apierror.Field("name", apierror.FieldRequired, nil))
+		case utf8.RuneCountInString(payload.Name) > 100:
+			fields = append(fields, apierror.Field("name", apierror.FieldTooLong, map[string]any{"max": 100}))
+		case containsNullBytes(payload.Name):
+			fields = append(fields, invalidCharacters("name"))
+		}
+
+		if payload.Timezone == "" {
+			payload.Timezone = "UTC"
+		}
+		payload.Cron = strings.TrimSpace(payload.Cron)
+		_, err := scheduler.Parse(payload.Cron, payload.Timezone)
+		switch {
+		case errors.Is(err, scheduler.ErrInvalidExpression):
+			fields = append(fields, apierror.Field("cron", apierror.FieldInvalidFormat, nil))
+		case errors.Is(err, scheduler.ErrInvalidTimezone):
+			fields = append(fields, apierror.Field("timezone", apierror.FieldInvalidFormat, nil))
+		}
+
+		switch payload.MissedRuns {
+		case "":
+			payload.MissedRuns = scheduler.MissedSkip
+		case scheduler.MissedSkip, scheduler.MissedCatchUp:
+		default:
+			fields = append(fields, apierror.Field("missed_runs", apierror.FieldNotAllowed, map[string]any{"allowed": scheduler.MissedPolicies}))
+		}
+
+		if payload.Enabled == nil {
+			enabled := true
+			payload.Enabled = &enabled
+		}
+
+		switch {
+		case payload.Transfer == nil && payload.Sync == nil:
+			fields = append(fields, apierror.Field("transfer", apierror.FieldRequired, nil))
+		case payload.Transfer != nil && payload.Sync != nil:
+			fields = append(fields, apierror.Field("transfer", apierror.FieldExclusive, map[string]any{"other": "sync"}))
+		case payload.Transfer != nil:
+			// Uploads need data from the client, which is not there when
+			// the schedule runs.
+			if payload.Transfer.Direction != models.TransferDownload {
+				fields = append(fields, apierror.Field("transfer.direction", apierror.FieldNotAllowed, map[string]any{"allowed": []string{"download"}}))
+			}
+			if _, err := uuid.Parse(payload.Transfer.ConnectionID); err != nil {
+				fields = append(fields, apierror.Field("transfer.connection_id", apierror.FieldInvalidFormat, nil))
+			}
+			fields = append(fields, prefixFields("transfer.", transferRequestFields(payload.Transfer, maxSize))...)
+		default:
+			fields = append(fields, prefixFields("sync.", syncRequestFields(payload.Sync, maxSize))...)
+		}
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func prefixFields(prefix string, fields []models.FieldError) []models.FieldError {
+	for i := range fields {
+		fields[i].Field = prefix + fields[i].Field
+	}
+	return fields
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxTeamPrincipals = 20
+
+var principalPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// ValidateTeamInput trims the team name and deduplicates its principals,
+// which must be valid Unix account names.
+func ValidateTeamInput() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.TeamInput
+		if !decodeSSHKeyBody(c, &payload) {
+			return
+		}
+
+		var fields []models.FieldError
+		if field, ok := normalizeName(&payload.Name); !ok {
+			fields = append(fields, field)
+		}
+
+		principals := []string{}
+		for _, principal := range payload.Principals {
+			principal = strings.TrimSpace(principal)
+			if !principalPattern.MatchString(principal) {
+				fields = append(fields, apierror.Field("principals", apierror.FieldInvalidFormat, nil))
+				break
+			}
+			if !slices.Contains(principals, principal) {
+				principals = append(principals, principal)
+			}
+		}
+		if len(principals) > maxTeamPrincipals {
+			fields = append(fields, apierror.Field("principals", apierror.FieldTooManyItems, map[string]any{"max": maxTeamPrincipals}))
+		}
+		payload.Principals = principals
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func ValidateSSHCertificateAuthority() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.SSHCertificateAuthorityRequest
+		if !decodeSSHKeyBody(c, &payload) {
+			return
+		}
+
+		if field, ok := normalizeName(&payload.Name); !ok {
+			apierror.Abort(c, apierror.Validation(field))
+			return
+		}
+		if payload.Algorithm == "" {
+			payload.Algorithm = sshkeys.AlgorithmEd25519
+		}
+		if payload.Algorithm != sshkeys.AlgorithmRSA {
+			payload.Bits = 0
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+// ValidateSSHCertificateRequest checks that exactly one of ssh_key_id and
+// public_key is given and resolves the certificate lifetime against the
+// server's default and maximum.
+func ValidateSSHCertificateRequest(defaultTTL, maxTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.SSHCertificateRequest
+		if !decodeSSHKeyBody(c, &payload) {
+			return
+		}
+
+		issue := models.SSHCertificateIssue{
+			SSHKeyID:  payload.SSHKeyID,
+			ValidFor:  defaultTTL,
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+
+		var fields []models.FieldError
+		switch {
+		case payload.SSHKeyID != "" && payload.PublicKey != "":
+			fields = append(fields, apierror.Field("public_key", apierror.FieldExclusive, map[string]any{"other": "ssh_key_id"}))
+		case payload.PublicKey != "":
+			key, _, err := sshkeys.ParsePublicKey(payload.PublicKey)
+			var info sshkeys.Info
+			if err == nil {
+				info, err = sshkeys.Describe(key)
+			}
+			if err != nil {
+				fields = append(fields, SSHKeyFieldError("public_key", err))
+			}
+			issue.PublicKey = info.AuthorizedKey
+		case payload.SSHKeyID == "":
+			fields = append(fields, apierror.Field("ssh_key_id", apierror.FieldRequired, nil))
+		}
+
+		if payload.ValidForMinutes > 0 {
+			issue.ValidFor = time.Duration(payload.ValidForMinutes) * time.Minute
+			if issue.ValidFor > maxTTL {
+				fields = append(fields, apierror.Field("valid_for_minutes", apierror.FieldTooLarge, map[string]any{"max": int(maxTTL.Minutes())}))
+			}
+		}
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", issue)
+		c.Next()
+	}
+}
+
+func normalizeName(name *string) (models.FieldError, bool) {
+	*name = strings.TrimSpace(*name)
+	if containsNullBytes(*name) || strings.ContainsFunc(*name, unicode.IsControl) {
+		return invalidCharacters("name"), false
+	}
+	if *name == "" {
+		return apierror.Field("name", apierror.FieldRequired, nil), false
+	}
+	return models.FieldError{}, true
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxSSHKeyBodyBytes = 128 << 10
+
+// ValidateSSHKeyInput checks that public_key parses as a supported key and
+// stores it in authorized_keys form, taking the comment from the key when
+// none is given.
+func ValidateSSHKeyInput() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.SSHKeyInput
+		if !decodeSSHKeyBody(c, &payload) {
+			return
+		}
+
+		key, comment, err := sshkeys.ParsePublicKey(payload.PublicKey)
+		var info sshkeys.Info
+		if err == nil {
+			info, err = sshkeys.Describe(key)
+		}
+		if err != nil {
+			apierror.Abort(c, apierror.Validation(SSHKeyFieldError("public_key", err)))
+			return
+		}
+
+		payload.PublicKey = info.AuthorizedKey
+		if payload.Comment == "" {
+			payload.Comment = comment
+		}
+
+		if fields := normalizeSSHKeyText(&payload.Name, &payload.Comment); len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+func ValidateSSHKeyGenerate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.SSHKeyGenerateRequest
+		if !decodeSSHKeyBody(c, &payload) {
+			return
+		}
+
+		if payload.Algorithm != sshkeys.AlgorithmRSA {
+			payload.Bits = 0
+		}
+		if fields := normalizeSSHKeyText(&payload.Name, &payload.Comment); len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+		if payload.Comment == "" {
+			payload.Comment = payload.Name
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+// ValidateSSHKeyImport only checks the name and comment. The private key is
+// parsed by the handler, since decrypting it can be expensive.
+func ValidateSSHKeyImport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.SSHKeyImportRequest
+		if !decodeSSHKeyBody(c, &payload) {
+			return
+		}
+
+		if fields := normalizeSSHKeyText(&payload.Name, &payload.Comment); len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+// SSHKeyFieldError describes why a key in field could not be used.
+func SSHKeyFieldError(field string, err error) models.FieldError {
+	switch {
+	case errors.Is(err, sshkeys.ErrUnsupportedKeyType):
+		return apierror.Field(field, apierror.FieldNotAllowed, map[string]any{"allowed": sshkeys.SupportedTypes})
+	case errors.Is(err, sshkeys.ErrKeyTooWeak):
+		return apierror.Field(field, apierror.FieldSSHKeyTooWeak, map[string]any{"min": sshkeys.MinRSABits})
+	case errors.Is(err, sshkeys.ErrPassphraseRequired):
+		return apierror.Field("passphrase", apierror.FieldRequired, nil)
+	case errors.Is(err, sshkeys.ErrIncorrectPassphrase):
+		return apierror.Field("passphrase", apierror.FieldIncorrect, nil)
+	default:
+		return apierror.Field(field, apierror.FieldInvalidFormat, nil)
+	}
+}
+
+func decodeSSHKeyBody(c *gin.Context, payload any) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSSHKeyBodyBytes)
+
+	if err := json.NewDecoder(c.Request.Body).Decode(payload); err != nil {
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+		return false
+	}
+	return true
+}
+
+// normalizeSSHKeyText trims the name and comment. Comments end up on
+// authorized_keys lines and in .ppk headers, so they must be a single line.
+func normalizeSSHKeyText(name, comment *string) []models.FieldError {
+	var fields []models.FieldError
+
+	*name = strings.TrimSpace(*name)
+	if containsNullBytes(*name) {
+		fields = append(fields, invalidCharacters("name"))
+	} else if *name == "" {
+		fields = append(fields, apierror.Field("name", apierror.FieldRequired, nil))
+	}
+
+	*comment = strings.TrimSpace(*comment)
+	if strings.IndexFunc(*comment, unicode.IsControl) >= 0 {
+		fields = append(fields, invalidCharacters("comment"))
+	}
+
+	return fields
+}
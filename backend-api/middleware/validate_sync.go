@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/syncplan"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxSyncBodyBytes    = 32 << 20
+	maxSyncLocalEntries = 100000
+)
+
+// ValidateSyncRequest checks a sync request. The file list can be far
+// larger than the OpenAPI validator reads, so the whole body is checked
+// here.
+func ValidateSyncRequest(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.SyncRequest
+		if !decodeSyncBody(c, &payload) {
+			return
+		}
+
+		fields := syncRequestFields(&payload, maxSize)
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+// decodeSyncBody reads a body that carries a file list. Large lists can
+// take longer to send than the server's read timeout, which is sized for
+// small JSON requests.
+func decodeSyncBody(c *gin.Context, payload any) bool {
+	http.NewResponseController(c.Writer).SetReadDeadline(time.Now().Add(2 * time.Minute))
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSyncBodyBytes)
+
+	if err := json.NewDecoder(c.Request.Body).Decode(payload); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.Abort(c, apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "The file list is too large."))
+			return false
+		}
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+		return false
+	}
+	return true
+}
+
+// syncRequestFields checks a sync request, reporting at most about 50
+// problems.
+func syncRequestFields(payload *models.SyncRequest, maxSize int64) []models.FieldError {
+	var fields []models.FieldError
+
+	if _, err := uuid.Parse(payload.ConnectionID); err != nil {
+		fields = append(fields, apierror.Field("connection_id", apierror.FieldInvalidFormat, nil))
+	}
+	fields = append(fields, remotePathField("remote_path", &payload.RemotePath, payload.Delete)...)
+
+	switch payload.Direction {
+	case syncplan.DirectionUpload, syncplan.DirectionDownload:
+	case syncplan.DirectionBoth:
+		if payload.Delete {
+			fields = append(fields, apierror.Field("delete", apierror.FieldExclusive, map[string]any{"other": "direction"}))
+		}
+		if payload.Mirror {
+			fields = append(fields, apierror.Field("mirror", apierror.FieldExclusive, map[string]any{"other": "direction"}))
+		}
+	default:
+		fields = append(fields, apierror.Field("direction", apierror.FieldNotAllowed, map[string]any{"allowed": []string{"upload", "download", "both"}}))
+	}
+
+	switch payload.Criteria {
+	case "", syncplan.CriteriaModTime, syncplan.CriteriaSize, syncplan.CriteriaChecksum:
+	default:
+		fields = append(fields, apierror.Field("criteria", apierror.FieldNotAllowed, map[string]any{"allowed": []string{"mtime", "size", "checksum"}}))
+	}
+
+	fields = append(fields, syncGlobFields("include", payload.Include)...)
+	fields = append(fields, syncGlobFields("exclude", payload.Exclude)...)
+
+	if len(payload.IgnoreFiles) > 10 {
+		fields = append(fields, apierror.Field("ignore_files", apierror.FieldTooManyItems, map[string]any{"max": 10}))
+	}
+	for i, name := range payload.IgnoreFiles {
+		if name == "" || strings.Contains(name, "/") || name == "." || name == ".." || containsNullBytes(name) {
+			fields = append(fields, invalidCharacters(fmt.Sprintf("ignore_files[%d]", i)))
+		}
+	}
+
+	if len(payload.LocalIgnoreFiles) > 100 {
+		fields = append(fields, apierror.Field("local_ignore_files", apierror.FieldTooManyItems, map[string]any{"max": 100}))
+	}
+	for name, content := range payload.LocalIgnoreFiles {
+		if !validSyncPath(name) || containsNullBytes(content) {
+			fields = append(fields, invalidCharacters("local_ignore_files"))
+			break
+		}
+	}
+
+	if payload.Local == nil {
+		fields = append(fields, apierror.Field("local", apierror.FieldRequired, nil))
+	}
+	if len(payload.Local) > maxSyncLocalEntries {
+		fields = append(fields, apierror.Field("local", apierror.FieldTooManyItems, map[string]any{"max": maxSyncLocalEntries}))
+		payload.Local = nil
+	}
+
+	seen := make(map[string]bool, len(payload.Local))
+	for i, entry := range payload.Local {
+		field := fmt.Sprintf("local[%d]", i)
+		switch {
+		case !validSyncPath(entry.Path):
+			fields = append(fields, invalidCharacters(field+".path"))
+		case seen[entry.Path]:
+			fields = append(fields, apierror.Field(field+".path", apierror.FieldSyncDuplicatePath, nil))
+		}
+		seen[entry.Path] = true
+
+		switch entry.Type {
+		case models.RemoteFileTypeDirectory:
+			payload.Local[i].Size, payload.Local[i].SHA256 = 0, ""
+		case models.RemoteFileTypeFile:
+			if entry.Size < 0 {
+				fields = append(fields, apierror.Field(field+".size", apierror.FieldTooSmall, map[string]any{"min": 0}))
+			} else if entry.Size > maxSize {
+				fields = append(fields, apierror.Field(field+".size", apierror.FieldTooLarge, map[string]any{"max": maxSize}))
+			}
+			if entry.SHA256 != "" && !validSHA256(entry.SHA256) {
+				fields = append(fields, apierror.Field(field+".sha256", apierror.FieldInvalidFormat, nil))
+			} else if entry.SHA256 == "" && payload.Criteria == syncplan.CriteriaChecksum {
+				fields = append(fields, apierror.Field(field+".sha256", apierror.FieldRequired, nil))
+			}
+		default:
+			fields = append(fields, apierror.Field(field+".type", apierror.FieldNotAllowed, map[string]any{"allowed": []string{"file", "directory"}}))
+		}
+
+		// Only report the first few, a broken client would send thousands.
+		if len(fields) >= 50 {
+			break
+		}
+	}
+	return fields
+}
+
+func syncGlobFields(field string, globs []string) []models.FieldError {
+	if len(globs) > 100 {
+		return []models.FieldError{apierror.Field(field, apierror.FieldTooManyItems, map[string]any{"max": 100})}
+	}
+	var fields []models.FieldError
+	for i, glob := range globs {
+		if _, ok := syncplan.ParsePattern(glob); !ok || strings.HasPrefix(glob, "!") || containsNullBytes(glob) {
+			fields = append(fields, apierror.Field(fmt.Sprintf("%s[%d]", field, i), apierror.FieldInvalidFormat, nil))
+		}
+	}
+	return fields
+}
+
+// validSyncPath reports whether name is a clean, slash-separated path
+// inside the synced directory.
+func validSyncPath(name string) bool {
+	return name != "" && len(name) <= 4096 && !containsNullBytes(name) && path.Clean(name) == name &&
+		!path.IsAbs(name) && name != "." && name != ".." && !strings.HasPrefix(name, "../")
+}
+
+func validSHA256(sum string) bool {
+	decoded, err := hex.DecodeString(sum)
+	return err == nil && len(decoded) == 32
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"strings"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/archive"
+	"livecode-api/internal/checksum"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateTransferRequest checks a new transfer. Uploads must declare their
+// size up front, so the server knows when all the data has been staged.
+func ValidateTransferRequest(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.TransferRequest
+		if !decodeRemoteFileBody(c, &payload) {
+			return
+		}
+
+		fields := transferRequestFields(&payload, maxSize)
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+// transferRequestFields checks a transfer request and fills in defaults.
+func transferRequestFields(payload *models.TransferRequest, maxSize int64) []models.FieldError {
+	fields := remotePathField("remote_path", &payload.RemotePath, true)
+	switch {
+	case payload.Direction == models.TransferDownload:
+		payload.Size = nil
+		payload.ModifiedAt = nil
+	case payload.Size == nil:
+		fields = append(fields, apierror.Field("size", apierror.FieldRequired, nil))
+	case *payload.Size > maxSize:
+		fields = append(fields, apierror.Field("size", apierror.FieldTooLarge, map[string]any{"max": maxSize}))
+	}
+	if payload.ChecksumAlgorithm == "" {
+		payload.ChecksumAlgorithm = checksum.SHA256
+	}
+	payload.ExpectedChecksum = strings.ToLower(payload.ExpectedChecksum)
+	switch size := checksum.Size(payload.ChecksumAlgorithm); {
+	case size == 0:
+		fields = append(fields, apierror.Field("checksum_algorithm", apierror.FieldNotAllowed, map[string]any{"allowed": checksum.Algorithms}))
+	case payload.ExpectedChecksum != "" && len(payload.ExpectedChecksum) != size:
+		fields = append(fields, apierror.Field("expected_checksum", apierror.FieldInvalidFormat, nil))
+	}
+	if payload.Symlinks == "" {
+		payload.Symlinks = archive.SymlinksSkip
+	}
+	if payload.BandwidthLimit != nil && *payload.BandwidthLimit < minBandwidthLimit {
+		fields = append(fields, apierror.Field("bandwidth_limit", apierror.FieldTooSmall, map[string]any{"min": minBandwidthLimit}))
+	}
+	return fields
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/vault"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxVaultBodyBytes = 64 << 10
+	gcmNonceSize      = 12
+	gcmTagSize        = 16
+	minKDFSaltSize    = 16
+	maxKDFSaltSize    = 64
+	maxKeyCheckSize   = 256
+)
+
+// ValidateVaultItemInput checks that an item carries a plaintext secret for
+// server encryption, or a well-formed nonce and ciphertext for client
+// encryption. Whatever the other mode would use is dropped.
+func ValidateVaultItemInput() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.VaultItemInput
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxVaultBodyBytes)
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+			return
+		}
+
+		payload.Label = strings.TrimSpace(payload.Label)
+		if containsNullBytes(payload.Label) {
+			apierror.Abort(c, apierror.Validation(invalidCharacters("label")))
+			return
+		}
+
+		var fields []models.FieldError
+		switch payload.Encryption {
+		case vault.EncryptionServer:
+			payload.Nonce, payload.Ciphertext = nil, nil
+			switch {
+			case payload.Secret == "":
+				fields = append(fields, apierror.Field("secret", apierror.FieldRequired, nil))
+			case len(payload.Secret) > vault.MaxSecretSize:
+				fields = append(fields, apierror.Field("secret", apierror.FieldTooLong, map[string]any{"max": vault.MaxSecretSize}))
+			}
+		case vault.EncryptionClient:
+			payload.Secret = ""
+			if len(payload.Nonce) != gcmNonceSize {
+				fields = append(fields, apierror.Field("nonce", apierror.FieldInvalidFormat, nil))
+			}
+			switch {
+			case len(payload.Ciphertext) == 0:
+				fields = append(fields, apierror.Field("ciphertext", apierror.FieldRequired, nil))
+			case len(payload.Ciphertext) < gcmTagSize:
+				fields = append(fields, apierror.Field("ciphertext", apierror.FieldInvalidFormat, nil))
+			case len(payload.Ciphertext) > vault.MaxSecretSize+gcmTagSize:
+				fields = append(fields, apierror.Field("ciphertext", apierror.FieldTooLong, map[string]any{"max": vault.MaxSecretSize + gcmTagSize}))
+			}
+		default:
+			fields = append(fields, apierror.Field("encryption", apierror.FieldNotAllowed, map[string]any{"allowed": []string{vault.EncryptionServer, vault.EncryptionClient}}))
+		}
+
+		if len(fields) > 0 {
+			apierror.Abort(c, apierror.Validation(fields...))
+			return
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
+
+// ValidateVaultSettings checks the sizes of the key derivation salt and key
+// check, which the request schema cannot express for binary fields.
+func ValidateVaultSettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.VaultSettingsRequest
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxVaultBodyBytes)
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+			apierror.Abort(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+			return
+		}
+
+		if payload.KDF != nil {
+			var fields []models.FieldError
+			if len(payload.KDF.Salt) < minKDFSaltSize || len(payload.KDF.Salt) > maxKDFSaltSize {
+				fields = append(fields, apierror.Field("kdf.salt", apierror.FieldInvalidFormat, nil))
+			}
+			if len(payload.KDF.KeyCheck) > maxKeyCheckSize {
+				fields = append(fields, apierror.Field("kdf.key_check", apierror.FieldTooLong, map[string]any{"max": maxKeyCheckSize}))
+			}
+			if len(fields) > 0 {
+				apierror.Abort(c, apierror.Validation(fields...))
+				return
+			}
+		}
+
+		c.Set("validated_payload", payload)
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+CREATE TABLE public.refresh_tokens (
+  id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  family_id uuid NOT NULL,
+  expires_at timestamptz(6) NOT NULL,
+  used_at timestamptz(6),
+  revoked_at timestamptz(6),
+  created_at timestamptz(6) DEFAULT now()
+);
+
+-- Primary key
+ALTER TABLE public.refresh_tokens
+    ADD CONSTRAINT refresh_tokens_pkey PRIMARY KEY (id);
+
+-- Foreign keys
+ALTER TABLE public.refresh_tokens
+    ADD CONSTRAINT refresh_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+-- Indexes
+CREATE INDEX refresh_tokens_family_id_idx ON public.refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON public.refresh_tokens (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE public.users DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE public.users DROP COLUMN IF EXISTS role;
\ No newline at end of file
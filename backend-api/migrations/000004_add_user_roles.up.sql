@@ -0,0 +1,5 @@
+ALTER TABLE public.users
+    ADD COLUMN role varchar(16) NOT NULL DEFAULT 'user';
+
+ALTER TABLE public.users
+    ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
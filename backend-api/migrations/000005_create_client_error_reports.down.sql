@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.client_error_reports CASCADE;
+DROP TABLE IF EXISTS public.client_issue_versions CASCADE;
+DROP TABLE IF EXISTS public.client_issues CASCADE;
\ No newline at end of file
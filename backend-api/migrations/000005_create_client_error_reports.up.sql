@@ -0,0 +1,74 @@
+CREATE TABLE public.client_issues (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  fingerprint varchar(64) NOT NULL,
+  error_type varchar(100) NOT NULL,
+  title varchar(300) NOT NULL,
+  status varchar(16) NOT NULL DEFAULT 'open',
+  event_count bigint NOT NULL DEFAULT 0,
+  first_seen_at timestamptz(6) NOT NULL DEFAULT now(),
+  last_seen_at timestamptz(6) NOT NULL DEFAULT now(),
+  last_alerted_at timestamptz(6),
+  created_at timestamptz(6) DEFAULT now(),
+  updated_at timestamptz(6) DEFAULT now()
+);
+
+CREATE TABLE public.client_issue_versions (
+  issue_id uuid NOT NULL,
+  app_version varchar(50) NOT NULL,
+  event_count bigint NOT NULL DEFAULT 0,
+  first_seen_at timestamptz(6) NOT NULL DEFAULT now(),
+  last_seen_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.client_error_reports (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  issue_id uuid NOT NULL,
+  user_id uuid,
+  request_id varchar(128),
+  error_type varchar(100) NOT NULL,
+  error_message varchar(1000) NOT NULL,
+  stack_trace text,
+  breadcrumbs jsonb NOT NULL DEFAULT '[]'::jsonb,
+  app_version varchar(50) NOT NULL,
+  os varchar(20) NOT NULL,
+  occurred_at timestamptz(6) NOT NULL,
+  received_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+-- Primary keys
+ALTER TABLE public.client_issues
+    ADD CONSTRAINT client_issues_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.client_issue_versions
+    ADD CONSTRAINT client_issue_versions_pkey PRIMARY KEY (issue_id, app_version);
+
+ALTER TABLE public.client_error_reports
+    ADD CONSTRAINT client_error_reports_pkey PRIMARY KEY (id);
+
+-- Unique constraints
+ALTER TABLE public.client_issues
+    ADD CONSTRAINT client_issues_fingerprint_key UNIQUE (fingerprint);
+
+-- Check constraints
+ALTER TABLE public.client_issues
+    ADD CONSTRAINT client_issues_status_check CHECK (status IN ('open', 'resolved', 'ignored', 'regressed'));
+
+-- Foreign keys
+ALTER TABLE public.client_issue_versions
+    ADD CONSTRAINT client_issue_versions_issue_id_fkey FOREIGN KEY (issue_id) REFERENCES public.client_issues (id) ON DELETE CASCADE;
+
+ALTER TABLE public.client_error_reports
+    ADD CONSTRAINT client_error_reports_issue_id_fkey FOREIGN KEY (issue_id) REFERENCES public.client_issues (id) ON DELETE CASCADE;
+
+ALTER TABLE public.client_error_reports
+    ADD CONSTRAINT client_error_reports_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE SET NULL;
+
+-- Indexes
+CREATE INDEX client_issues_last_seen_at_idx ON public.client_issues (last_seen_at DESC);
+CREATE INDEX client_error_reports_issue_id_received_at_idx ON public.client_error_reports (issue_id, received_at DESC);
+
+-- Keep updated_at current
+CREATE TRIGGER update_client_issues_updated_at
+    BEFORE UPDATE ON public.client_issues
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.telemetry_events CASCADE;
+DROP TABLE IF EXISTS public.telemetry_installs CASCADE;
\ No newline at end of file
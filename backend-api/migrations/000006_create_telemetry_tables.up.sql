@@ -0,0 +1,48 @@
+CREATE TABLE public.telemetry_installs (
+  install_id uuid NOT NULL,
+  user_id uuid,
+  usage_opt_in bool NOT NULL DEFAULT false,
+  errors_opt_in bool NOT NULL DEFAULT false,
+  created_at timestamptz(6) DEFAULT now(),
+  updated_at timestamptz(6) DEFAULT now()
+);
+
+CREATE TABLE public.telemetry_events (
+  id bigserial NOT NULL,
+  install_id uuid NOT NULL,
+  user_id uuid,
+  event_type varchar(32) NOT NULL,
+  name varchar(100) NOT NULL,
+  schema_version smallint NOT NULL,
+  sample_rate real NOT NULL DEFAULT 1,
+  app_version varchar(50) NOT NULL,
+  os varchar(20) NOT NULL,
+  session_id varchar(64),
+  occurred_at timestamptz(6) NOT NULL,
+  received_at timestamptz(6) NOT NULL DEFAULT now(),
+  payload jsonb NOT NULL DEFAULT '{}'::jsonb
+);
+
+-- Primary keys
+ALTER TABLE public.telemetry_installs
+    ADD CONSTRAINT telemetry_installs_pkey PRIMARY KEY (install_id);
+
+ALTER TABLE public.telemetry_events
+    ADD CONSTRAINT telemetry_events_pkey PRIMARY KEY (id);
+
+-- Foreign keys
+ALTER TABLE public.telemetry_installs
+    ADD CONSTRAINT telemetry_installs_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE SET NULL;
+
+ALTER TABLE public.telemetry_events
+    ADD CONSTRAINT telemetry_events_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE SET NULL;
+
+-- Indexes
+CREATE INDEX telemetry_events_type_occurred_at_idx ON public.telemetry_events (event_type, occurred_at DESC);
+CREATE INDEX telemetry_events_install_id_idx ON public.telemetry_events (install_id);
+
+-- Keep updated_at current
+CREATE TRIGGER update_telemetry_installs_updated_at
+    BEFORE UPDATE ON public.telemetry_installs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_username_skeleton_idx;
+
+ALTER TABLE public.users DROP COLUMN IF EXISTS username_skeleton;
+
+ALTER TABLE public.users
+    ALTER COLUMN username TYPE varchar(17);
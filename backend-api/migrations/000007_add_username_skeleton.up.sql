@@ -0,0 +1,14 @@
+ALTER TABLE public.users
+    ALTER COLUMN username TYPE varchar(33);
+
+ALTER TABLE public.users
+    ADD COLUMN username_skeleton varchar(33);
+
+-- Same as usernames.Skeleton for the ASCII handles allowed until now
+UPDATE public.users
+    SET username_skeleton = replace(replace(translate(substr(username, 2), '01i._', 'oll'), 'rn', 'm'), 'vv', 'w');
+
+ALTER TABLE public.users
+    ALTER COLUMN username_skeleton SET NOT NULL;
+
+CREATE INDEX users_username_skeleton_idx ON public.users (username_skeleton);
@@ -0,0 +1,3 @@
+ALTER TABLE public.users DROP CONSTRAINT IF EXISTS users_canonical_email_key;
+
+ALTER TABLE public.users DROP COLUMN IF EXISTS canonical_email;
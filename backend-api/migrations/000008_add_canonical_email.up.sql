@@ -0,0 +1,26 @@
+ALTER TABLE public.users
+    ADD COLUMN canonical_email varchar(255);
+
+-- Same provider rules as emails.Canonical
+UPDATE public.users
+    SET canonical_email = CASE
+        WHEN split_part(email, '@', 2) IN ('gmail.com', 'googlemail.com')
+            THEN coalesce(nullif(replace(split_part(split_part(email, '@', 1), '+', 1), '.', ''), '') || '@gmail.com', email)
+        WHEN split_part(email, '@', 2) IN ('outlook.com', 'hotmail.com', 'live.com', 'icloud.com', 'me.com', 'fastmail.com', 'proton.me', 'protonmail.com')
+            THEN coalesce(nullif(split_part(split_part(email, '@', 1), '+', 1), '') || '@' || split_part(email, '@', 2), email)
+        ELSE email
+    END;
+
+-- Accounts that already share a mailbox keep their own address; the oldest
+-- one keeps the canonical address.
+UPDATE public.users AS newer
+    SET canonical_email = newer.email
+    FROM public.users AS older
+    WHERE older.canonical_email = newer.canonical_email
+      AND (coalesce(older.created_at, '-infinity'), older.id) < (coalesce(newer.created_at, '-infinity'), newer.id);
+
+ALTER TABLE public.users
+    ALTER COLUMN canonical_email SET NOT NULL;
+
+ALTER TABLE public.users
+    ADD CONSTRAINT users_canonical_email_key UNIQUE (canonical_email);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.connections CASCADE;
+DROP SEQUENCE IF EXISTS public.connections_change_seq;
@@ -0,0 +1,55 @@
+CREATE SEQUENCE public.connections_change_seq;
+
+CREATE TABLE public.connections (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name varchar(100) NOT NULL,
+  protocol varchar(8) NOT NULL,
+  host varchar(255) NOT NULL,
+  port integer NOT NULL,
+  username varchar(255) NOT NULL DEFAULT '',
+  auth_method varchar(32) NOT NULL,
+  remote_directory varchar(1024) NOT NULL DEFAULT '',
+  local_directory varchar(1024) NOT NULL DEFAULT '',
+  folder varchar(255) NOT NULL DEFAULT '',
+  tags jsonb NOT NULL DEFAULT '[]'::jsonb,
+  color varchar(7) NOT NULL DEFAULT '',
+  notes varchar(4000) NOT NULL DEFAULT '',
+  options jsonb NOT NULL DEFAULT '{}'::jsonb,
+  version integer NOT NULL DEFAULT 1,
+  change_seq bigint NOT NULL DEFAULT nextval('public.connections_change_seq'),
+  deleted_at timestamptz(6),
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+ALTER SEQUENCE public.connections_change_seq OWNED BY public.connections.change_seq;
+
+-- Primary keys
+ALTER TABLE public.connections
+    ADD CONSTRAINT connections_pkey PRIMARY KEY (id);
+
+-- Check constraints
+ALTER TABLE public.connections
+    ADD CONSTRAINT connections_protocol_check CHECK (protocol IN ('sftp', 'scp', 'ftp', 'ftps', 'webdav', 's3'));
+
+ALTER TABLE public.connections
+    ADD CONSTRAINT connections_port_check CHECK (port BETWEEN 1 AND 65535);
+
+ALTER TABLE public.connections
+    ADD CONSTRAINT connections_version_check CHECK (version >= 1);
+
+-- Foreign keys
+ALTER TABLE public.connections
+    ADD CONSTRAINT connections_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+-- Indexes
+CREATE UNIQUE INDEX connections_user_id_folder_name_key ON public.connections (user_id, lower(folder), lower(name))
+    WHERE deleted_at IS NULL;
+CREATE INDEX connections_user_id_change_seq_idx ON public.connections (user_id, change_seq);
+
+-- Keep updated_at current
+CREATE TRIGGER update_connections_updated_at
+    BEFORE UPDATE ON public.connections
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
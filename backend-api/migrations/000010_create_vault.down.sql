@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.vault_items CASCADE;
+DROP TABLE IF EXISTS public.vaults CASCADE;
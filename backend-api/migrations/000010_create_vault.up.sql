@@ -0,0 +1,70 @@
+CREATE TABLE public.vaults (
+  user_id uuid NOT NULL,
+  master_key_id varchar(16),
+  data_key_nonce bytea,
+  data_key bytea,
+  zero_knowledge boolean NOT NULL DEFAULT false,
+  kdf jsonb,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.vault_items (
+  id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  connection_id uuid,
+  kind varchar(32) NOT NULL,
+  label varchar(100) NOT NULL DEFAULT '',
+  encryption varchar(8) NOT NULL,
+  nonce bytea NOT NULL,
+  ciphertext bytea NOT NULL,
+  version integer NOT NULL DEFAULT 1,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+-- Primary keys
+ALTER TABLE public.vaults
+    ADD CONSTRAINT vaults_pkey PRIMARY KEY (user_id);
+
+ALTER TABLE public.vault_items
+    ADD CONSTRAINT vault_items_pkey PRIMARY KEY (id);
+
+-- Check constraints
+ALTER TABLE public.vaults
+    ADD CONSTRAINT vaults_data_key_check CHECK ((master_key_id IS NULL) = (data_key IS NULL) AND (data_key IS NULL) = (data_key_nonce IS NULL));
+
+ALTER TABLE public.vaults
+    ADD CONSTRAINT vaults_zero_knowledge_check CHECK (NOT zero_knowledge OR kdf IS NOT NULL);
+
+ALTER TABLE public.vault_items
+    ADD CONSTRAINT vault_items_kind_check CHECK (kind IN ('password', 'private_key', 'passphrase', 'secret_access_key'));
+
+ALTER TABLE public.vault_items
+    ADD CONSTRAINT vault_items_encryption_check CHECK (encryption IN ('server', 'client'));
+
+-- Foreign keys
+ALTER TABLE public.vaults
+    ADD CONSTRAINT vaults_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.vault_items
+    ADD CONSTRAINT vault_items_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.vault_items
+    ADD CONSTRAINT vault_items_connection_id_fkey FOREIGN KEY (connection_id) REFERENCES public.connections (id) ON DELETE CASCADE;
+
+-- Indexes
+CREATE UNIQUE INDEX vault_items_connection_id_kind_key ON public.vault_items (connection_id, kind)
+    WHERE connection_id IS NOT NULL;
+CREATE INDEX vault_items_user_id_idx ON public.vault_items (user_id);
+
+-- Keep updated_at current
+CREATE TRIGGER update_vaults_updated_at
+    BEFORE UPDATE ON public.vaults
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_vault_items_updated_at
+    BEFORE UPDATE ON public.vault_items
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.ssh_keys CASCADE;
@@ -0,0 +1,41 @@
+CREATE TABLE public.ssh_keys (
+  id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  name varchar(100) NOT NULL,
+  key_type varchar(64) NOT NULL,
+  bits integer NOT NULL,
+  public_key text NOT NULL,
+  comment varchar(255) NOT NULL DEFAULT '',
+  fingerprint_sha256 varchar(64) NOT NULL,
+  fingerprint_md5 varchar(64) NOT NULL,
+  source varchar(16) NOT NULL,
+  vault_item_id uuid,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+-- Primary key
+ALTER TABLE public.ssh_keys
+    ADD CONSTRAINT ssh_keys_pkey PRIMARY KEY (id);
+
+-- Check constraints
+ALTER TABLE public.ssh_keys
+    ADD CONSTRAINT ssh_keys_source_check CHECK (source IN ('registered', 'generated', 'imported'));
+
+-- Foreign keys
+ALTER TABLE public.ssh_keys
+    ADD CONSTRAINT ssh_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.ssh_keys
+    ADD CONSTRAINT ssh_keys_vault_item_id_fkey FOREIGN KEY (vault_item_id) REFERENCES public.vault_items (id) ON DELETE SET NULL;
+
+-- Indexes
+ALTER TABLE public.ssh_keys
+    ADD CONSTRAINT ssh_keys_user_id_fingerprint_key UNIQUE (user_id, fingerprint_sha256);
+CREATE INDEX ssh_keys_vault_item_id_idx ON public.ssh_keys (vault_item_id);
+
+-- Keep updated_at current
+CREATE TRIGGER update_ssh_keys_updated_at
+    BEFORE UPDATE ON public.ssh_keys
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS public.ssh_certificates CASCADE;
+DROP TABLE IF EXISTS public.ssh_certificate_authorities CASCADE;
+DROP TABLE IF EXISTS public.team_members CASCADE;
+DROP TABLE IF EXISTS public.teams CASCADE;
@@ -0,0 +1,105 @@
+CREATE TABLE public.teams (
+  id uuid NOT NULL,
+  name varchar(100) NOT NULL,
+  principals jsonb NOT NULL DEFAULT '[]'::jsonb,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.team_members (
+  team_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  created_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.ssh_certificate_authorities (
+  id uuid NOT NULL,
+  name varchar(100) NOT NULL,
+  key_type varchar(64) NOT NULL,
+  public_key text NOT NULL,
+  fingerprint_sha256 varchar(64) NOT NULL,
+  master_key_id varchar(16) NOT NULL,
+  data_key_nonce bytea NOT NULL,
+  data_key bytea NOT NULL,
+  nonce bytea NOT NULL,
+  ciphertext bytea NOT NULL,
+  active boolean NOT NULL DEFAULT true,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  retired_at timestamptz(6)
+);
+
+CREATE SEQUENCE public.ssh_certificates_serial_seq;
+
+CREATE TABLE public.ssh_certificates (
+  serial bigint NOT NULL DEFAULT nextval('public.ssh_certificates_serial_seq'),
+  ca_id uuid NOT NULL,
+  user_id uuid,
+  ssh_key_id uuid,
+  key_fingerprint varchar(64) NOT NULL,
+  key_id varchar(255) NOT NULL,
+  principals jsonb NOT NULL,
+  valid_after timestamptz(6) NOT NULL,
+  valid_before timestamptz(6) NOT NULL,
+  issued_at timestamptz(6) NOT NULL DEFAULT now(),
+  client_ip varchar(45),
+  user_agent text,
+  revoked_at timestamptz(6),
+  revoked_by uuid,
+  revocation_reason varchar(255)
+);
+
+ALTER SEQUENCE public.ssh_certificates_serial_seq OWNED BY public.ssh_certificates.serial;
+
+-- Primary keys
+ALTER TABLE public.teams
+    ADD CONSTRAINT teams_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.team_members
+    ADD CONSTRAINT team_members_pkey PRIMARY KEY (team_id, user_id);
+
+ALTER TABLE public.ssh_certificate_authorities
+    ADD CONSTRAINT ssh_certificate_authorities_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.ssh_certificates
+    ADD CONSTRAINT ssh_certificates_pkey PRIMARY KEY (serial);
+
+-- Check constraints
+ALTER TABLE public.ssh_certificate_authorities
+    ADD CONSTRAINT ssh_certificate_authorities_retired_check CHECK (NOT (active AND retired_at IS NOT NULL));
+
+ALTER TABLE public.ssh_certificates
+    ADD CONSTRAINT ssh_certificates_validity_check CHECK (valid_before > valid_after);
+
+-- Foreign keys
+ALTER TABLE public.team_members
+    ADD CONSTRAINT team_members_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams (id) ON DELETE CASCADE;
+
+ALTER TABLE public.team_members
+    ADD CONSTRAINT team_members_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+-- Certificates outlive their user and key so the audit trail stays complete.
+ALTER TABLE public.ssh_certificates
+    ADD CONSTRAINT ssh_certificates_ca_id_fkey FOREIGN KEY (ca_id) REFERENCES public.ssh_certificate_authorities (id);
+
+ALTER TABLE public.ssh_certificates
+    ADD CONSTRAINT ssh_certificates_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE SET NULL;
+
+ALTER TABLE public.ssh_certificates
+    ADD CONSTRAINT ssh_certificates_ssh_key_id_fkey FOREIGN KEY (ssh_key_id) REFERENCES public.ssh_keys (id) ON DELETE SET NULL;
+
+ALTER TABLE public.ssh_certificates
+    ADD CONSTRAINT ssh_certificates_revoked_by_fkey FOREIGN KEY (revoked_by) REFERENCES public.users (id) ON DELETE SET NULL;
+
+-- Indexes
+ALTER TABLE public.teams
+    ADD CONSTRAINT teams_name_key UNIQUE (name);
+CREATE INDEX team_members_user_id_idx ON public.team_members (user_id);
+CREATE UNIQUE INDEX ssh_certificate_authorities_active_idx ON public.ssh_certificate_authorities (active) WHERE active;
+CREATE INDEX ssh_certificates_user_id_idx ON public.ssh_certificates (user_id, issued_at DESC);
+CREATE INDEX ssh_certificates_revoked_idx ON public.ssh_certificates (ca_id, serial) WHERE revoked_at IS NOT NULL;
+
+-- Keep updated_at current
+CREATE TRIGGER update_teams_updated_at
+    BEFORE UPDATE ON public.teams
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.known_host_events CASCADE;
+DROP TABLE IF EXISTS public.known_hosts CASCADE;
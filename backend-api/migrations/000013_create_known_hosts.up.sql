@@ -0,0 +1,70 @@
+CREATE TABLE public.known_hosts (
+  id uuid NOT NULL,
+  user_id uuid,
+  team_id uuid,
+  host varchar(255) NOT NULL,
+  key_type varchar(64) NOT NULL,
+  public_key text NOT NULL,
+  fingerprint_sha256 varchar(64) NOT NULL,
+  comment varchar(255) NOT NULL DEFAULT '',
+  first_seen_by uuid,
+  first_seen_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.known_host_events (
+  id uuid NOT NULL,
+  event varchar(16) NOT NULL,
+  user_id uuid,
+  team_id uuid,
+  host varchar(255) NOT NULL,
+  key_type varchar(64) NOT NULL,
+  expected_fingerprint varchar(64),
+  presented_fingerprint varchar(64),
+  client_ip varchar(45),
+  user_agent text,
+  created_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+-- Primary keys
+ALTER TABLE public.known_hosts
+    ADD CONSTRAINT known_hosts_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.known_host_events
+    ADD CONSTRAINT known_host_events_pkey PRIMARY KEY (id);
+
+-- Check constraints
+ALTER TABLE public.known_hosts
+    ADD CONSTRAINT known_hosts_owner_check CHECK ((user_id IS NULL) <> (team_id IS NULL));
+
+ALTER TABLE public.known_host_events
+    ADD CONSTRAINT known_host_events_event_check CHECK (event IN ('key_changed', 'key_replaced', 'pin_added', 'pin_replaced', 'pin_removed'));
+
+-- Foreign keys
+ALTER TABLE public.known_hosts
+    ADD CONSTRAINT known_hosts_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.known_hosts
+    ADD CONSTRAINT known_hosts_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams (id) ON DELETE CASCADE;
+
+ALTER TABLE public.known_hosts
+    ADD CONSTRAINT known_hosts_first_seen_by_fkey FOREIGN KEY (first_seen_by) REFERENCES public.users (id) ON DELETE SET NULL;
+
+ALTER TABLE public.known_host_events
+    ADD CONSTRAINT known_host_events_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE SET NULL;
+
+ALTER TABLE public.known_host_events
+    ADD CONSTRAINT known_host_events_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams (id) ON DELETE SET NULL;
+
+-- Indexes
+ALTER TABLE public.known_hosts
+    ADD CONSTRAINT known_hosts_user_id_host_key_type_key UNIQUE (user_id, host, key_type);
+ALTER TABLE public.known_hosts
+    ADD CONSTRAINT known_hosts_team_id_host_key_type_key UNIQUE (team_id, host, key_type);
+CREATE INDEX known_host_events_created_at_idx ON public.known_host_events (created_at DESC);
+
+-- Keep updated_at current
+CREATE TRIGGER update_known_hosts_updated_at
+    BEFORE UPDATE ON public.known_hosts
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.transfer_events CASCADE;
+DROP TABLE IF EXISTS public.transfers CASCADE;
@@ -0,0 +1,67 @@
+CREATE TABLE public.transfers (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  connection_id uuid NOT NULL,
+  direction varchar(8) NOT NULL,
+  remote_path text NOT NULL,
+  overwrite boolean NOT NULL DEFAULT false,
+  status varchar(16) NOT NULL,
+  size bigint,
+  staged_bytes bigint NOT NULL DEFAULT 0,
+  transferred_bytes bigint NOT NULL DEFAULT 0,
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamptz(6) NOT NULL DEFAULT now(),
+  heartbeat_at timestamptz(6),
+  last_error text,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now(),
+  started_at timestamptz(6),
+  finished_at timestamptz(6)
+);
+
+CREATE TABLE public.transfer_events (
+  id bigserial NOT NULL,
+  transfer_id uuid NOT NULL,
+  status varchar(16) NOT NULL,
+  detail text,
+  created_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+-- Primary keys
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.transfer_events
+    ADD CONSTRAINT transfer_events_pkey PRIMARY KEY (id);
+
+-- Check constraints
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_direction_check CHECK (direction IN ('upload', 'download'));
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_status_check CHECK (status IN ('staging', 'queued', 'running', 'paused', 'completed', 'failed', 'canceled'));
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_bytes_check CHECK (staged_bytes >= 0 AND transferred_bytes >= 0 AND (size IS NULL OR (staged_bytes <= size AND transferred_bytes <= size)));
+
+-- Foreign keys
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_connection_id_fkey FOREIGN KEY (connection_id) REFERENCES public.connections (id) ON DELETE CASCADE;
+
+ALTER TABLE public.transfer_events
+    ADD CONSTRAINT transfer_events_transfer_id_fkey FOREIGN KEY (transfer_id) REFERENCES public.transfers (id) ON DELETE CASCADE;
+
+-- Indexes
+CREATE INDEX transfers_user_id_created_at_idx ON public.transfers (user_id, created_at DESC);
+CREATE INDEX transfers_queue_idx ON public.transfers (next_attempt_at, created_at) WHERE status = 'queued';
+CREATE INDEX transfers_running_idx ON public.transfers (user_id) WHERE status = 'running';
+CREATE INDEX transfer_events_transfer_id_idx ON public.transfer_events (transfer_id, id);
+
+-- Keep updated_at current
+CREATE TRIGGER update_transfers_updated_at
+    BEFORE UPDATE ON public.transfers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
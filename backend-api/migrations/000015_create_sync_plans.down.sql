@@ -0,0 +1,15 @@
+DELETE FROM public.transfers WHERE direction = 'delete';
+
+ALTER TABLE public.transfers
+    DROP CONSTRAINT transfers_direction_check;
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_direction_check CHECK (direction IN ('upload', 'download'));
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS modified_at;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS sync_plan_id;
+
+DROP TABLE IF EXISTS public.sync_plans CASCADE;
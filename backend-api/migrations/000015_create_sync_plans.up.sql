@@ -0,0 +1,52 @@
+CREATE TABLE public.sync_plans (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  connection_id uuid NOT NULL,
+  remote_path text NOT NULL,
+  direction varchar(8) NOT NULL,
+  criteria varchar(8) NOT NULL,
+  mirror boolean NOT NULL DEFAULT false,
+  delete_extraneous boolean NOT NULL DEFAULT false,
+  actions jsonb NOT NULL,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  expires_at timestamptz(6) NOT NULL,
+  executed_at timestamptz(6)
+);
+
+ALTER TABLE public.transfers
+    ADD COLUMN sync_plan_id uuid;
+
+ALTER TABLE public.transfers
+    ADD COLUMN modified_at timestamptz(6);
+
+-- Primary keys
+ALTER TABLE public.sync_plans
+    ADD CONSTRAINT sync_plans_pkey PRIMARY KEY (id);
+
+-- Check constraints
+ALTER TABLE public.sync_plans
+    ADD CONSTRAINT sync_plans_direction_check CHECK (direction IN ('upload', 'download', 'both'));
+
+ALTER TABLE public.sync_plans
+    ADD CONSTRAINT sync_plans_criteria_check CHECK (criteria IN ('mtime', 'size', 'checksum'));
+
+-- Remote deletes planned by a sync run as transfer jobs too
+ALTER TABLE public.transfers
+    DROP CONSTRAINT transfers_direction_check;
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_direction_check CHECK (direction IN ('upload', 'download', 'delete'));
+
+-- Foreign keys
+ALTER TABLE public.sync_plans
+    ADD CONSTRAINT sync_plans_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.sync_plans
+    ADD CONSTRAINT sync_plans_connection_id_fkey FOREIGN KEY (connection_id) REFERENCES public.connections (id) ON DELETE CASCADE;
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_sync_plan_id_fkey FOREIGN KEY (sync_plan_id) REFERENCES public.sync_plans (id) ON DELETE SET NULL;
+
+-- Indexes
+CREATE INDEX sync_plans_user_id_created_at_idx ON public.sync_plans (user_id, created_at DESC);
+CREATE INDEX transfers_sync_plan_id_idx ON public.transfers (sync_plan_id) WHERE sync_plan_id IS NOT NULL;
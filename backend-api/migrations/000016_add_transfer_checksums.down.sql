@@ -0,0 +1,20 @@
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS checksum_mismatches;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS verified_at;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS verified_by;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS destination_checksum;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS source_checksum;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS expected_checksum;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS checksum_algorithm;
@@ -0,0 +1,27 @@
+ALTER TABLE public.transfers
+    ADD COLUMN checksum_algorithm varchar(8) NOT NULL DEFAULT 'sha256';
+
+ALTER TABLE public.transfers
+    ADD COLUMN expected_checksum text;
+
+ALTER TABLE public.transfers
+    ADD COLUMN source_checksum text;
+
+ALTER TABLE public.transfers
+    ADD COLUMN destination_checksum text;
+
+ALTER TABLE public.transfers
+    ADD COLUMN verified_by varchar(8);
+
+ALTER TABLE public.transfers
+    ADD COLUMN verified_at timestamptz(6);
+
+ALTER TABLE public.transfers
+    ADD COLUMN checksum_mismatches integer NOT NULL DEFAULT 0;
+
+-- Check constraints
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_checksum_algorithm_check CHECK (checksum_algorithm IN ('sha256', 'blake3', 'xxh64'));
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_verified_by_check CHECK (verified_by IN ('exec', 'readback'));
@@ -0,0 +1,6 @@
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS bandwidth_limit;
+
+DROP TABLE IF EXISTS public.transfer_schedule_runs CASCADE;
+DROP TABLE IF EXISTS public.transfer_schedules CASCADE;
+DROP TABLE IF EXISTS public.bandwidth_limits CASCADE;
@@ -0,0 +1,95 @@
+CREATE TABLE public.bandwidth_limits (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  user_id uuid,
+  bytes_per_second bigint,
+  timezone varchar(64) NOT NULL DEFAULT 'UTC',
+  windows jsonb NOT NULL DEFAULT '[]',
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.transfer_schedules (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  name varchar(100) NOT NULL,
+  cron varchar(100) NOT NULL,
+  timezone varchar(64) NOT NULL,
+  missed_runs varchar(8) NOT NULL,
+  enabled boolean NOT NULL DEFAULT true,
+  kind varchar(8) NOT NULL,
+  request jsonb NOT NULL,
+  next_run_at timestamptz(6),
+  last_run_at timestamptz(6),
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  updated_at timestamptz(6) NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.transfer_schedule_runs (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  schedule_id uuid NOT NULL,
+  scheduled_for timestamptz(6) NOT NULL,
+  status varchar(8) NOT NULL,
+  detail text,
+  transfer_id uuid,
+  sync_plan_id uuid,
+  created_at timestamptz(6) NOT NULL DEFAULT now(),
+  finished_at timestamptz(6)
+);
+
+ALTER TABLE public.transfers
+    ADD COLUMN bandwidth_limit bigint;
+
+-- Primary keys
+ALTER TABLE public.bandwidth_limits
+    ADD CONSTRAINT bandwidth_limits_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.transfer_schedules
+    ADD CONSTRAINT transfer_schedules_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.transfer_schedule_runs
+    ADD CONSTRAINT transfer_schedule_runs_pkey PRIMARY KEY (id);
+
+-- Check constraints
+ALTER TABLE public.bandwidth_limits
+    ADD CONSTRAINT bandwidth_limits_bytes_per_second_check CHECK (bytes_per_second > 0);
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_bandwidth_limit_check CHECK (bandwidth_limit > 0);
+
+ALTER TABLE public.transfer_schedules
+    ADD CONSTRAINT transfer_schedules_missed_runs_check CHECK (missed_runs IN ('skip', 'catch_up'));
+
+ALTER TABLE public.transfer_schedules
+    ADD CONSTRAINT transfer_schedules_kind_check CHECK (kind IN ('transfer', 'sync'));
+
+ALTER TABLE public.transfer_schedule_runs
+    ADD CONSTRAINT transfer_schedule_runs_status_check CHECK (status IN ('pending', 'started', 'skipped', 'failed'));
+
+-- Foreign keys
+ALTER TABLE public.bandwidth_limits
+    ADD CONSTRAINT bandwidth_limits_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.transfer_schedules
+    ADD CONSTRAINT transfer_schedules_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE CASCADE;
+
+ALTER TABLE public.transfer_schedule_runs
+    ADD CONSTRAINT transfer_schedule_runs_schedule_id_fkey FOREIGN KEY (schedule_id) REFERENCES public.transfer_schedules (id) ON DELETE CASCADE;
+
+ALTER TABLE public.transfer_schedule_runs
+    ADD CONSTRAINT transfer_schedule_runs_transfer_id_fkey FOREIGN KEY (transfer_id) REFERENCES public.transfers (id) ON DELETE SET NULL;
+
+ALTER TABLE public.transfer_schedule_runs
+    ADD CONSTRAINT transfer_schedule_runs_sync_plan_id_fkey FOREIGN KEY (sync_plan_id) REFERENCES public.sync_plans (id) ON DELETE SET NULL;
+
+-- Indexes
+-- One row per user, and one with no user for the server-wide limit
+CREATE UNIQUE INDEX bandwidth_limits_user_id_idx ON public.bandwidth_limits (user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX bandwidth_limits_global_idx ON public.bandwidth_limits ((true)) WHERE user_id IS NULL;
+CREATE INDEX transfer_schedules_user_id_idx ON public.transfer_schedules (user_id, created_at);
+CREATE INDEX transfer_schedules_due_idx ON public.transfer_schedules (next_run_at) WHERE enabled;
+CREATE INDEX transfer_schedule_runs_schedule_id_idx ON public.transfer_schedule_runs (schedule_id, scheduled_for DESC);
+
+-- Keep updated_at current
+CREATE TRIGGER update_transfer_schedules_updated_at
+    BEFORE UPDATE ON public.transfer_schedules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,8 @@
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS archive_entries;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS symlinks;
+
+ALTER TABLE public.transfers
+    DROP COLUMN IF EXISTS archive;
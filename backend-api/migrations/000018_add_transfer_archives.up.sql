@@ -0,0 +1,15 @@
+ALTER TABLE public.transfers
+    ADD COLUMN archive varchar(8);
+
+ALTER TABLE public.transfers
+    ADD COLUMN symlinks varchar(8) NOT NULL DEFAULT 'skip';
+
+ALTER TABLE public.transfers
+    ADD COLUMN archive_entries integer NOT NULL DEFAULT 0;
+
+-- Check constraints
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_archive_check CHECK (archive IN ('zip', 'tar.gz'));
+
+ALTER TABLE public.transfers
+    ADD CONSTRAINT transfers_symlinks_check CHECK (symlinks IN ('skip', 'reject', 'create'));
@@ -0,0 +1,2 @@
+ALTER TABLE public.telemetry_installs
+    DROP COLUMN IF EXISTS secret_hash;
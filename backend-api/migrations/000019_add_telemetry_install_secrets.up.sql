@@ -0,0 +1,2 @@
+ALTER TABLE public.telemetry_installs
+    ADD COLUMN secret_hash bytea;
@@ -0,0 +1 @@
+CREATE SEQUENCE public.event_ids;
@@ -0,0 +1,14 @@
+CREATE INDEX IF NOT EXISTS vault_items_user_id_idx ON public.vault_items (user_id);
+
+ALTER TABLE public.ssh_keys
+    DROP CONSTRAINT ssh_keys_vault_item_id_fkey;
+
+ALTER TABLE public.vault_items
+    DROP CONSTRAINT vault_items_pkey;
+
+ALTER TABLE public.vault_items
+    ADD CONSTRAINT vault_items_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.ssh_keys
+    ADD CONSTRAINT ssh_keys_vault_item_id_fkey FOREIGN KEY (vault_item_id)
+    REFERENCES public.vault_items (id) ON DELETE SET NULL;
@@ -0,0 +1,15 @@
+-- Item IDs are chosen by clients, so they are unique per user only.
+ALTER TABLE public.ssh_keys
+    DROP CONSTRAINT ssh_keys_vault_item_id_fkey;
+
+ALTER TABLE public.vault_items
+    DROP CONSTRAINT vault_items_pkey;
+
+ALTER TABLE public.vault_items
+    ADD CONSTRAINT vault_items_pkey PRIMARY KEY (user_id, id);
+
+ALTER TABLE public.ssh_keys
+    ADD CONSTRAINT ssh_keys_vault_item_id_fkey FOREIGN KEY (user_id, vault_item_id)
+    REFERENCES public.vault_items (user_id, id) ON DELETE SET NULL (vault_item_id);
+
+DROP INDEX IF EXISTS public.vault_items_user_id_idx;
@@ -0,0 +1,16 @@
+package models
+
+type ErrorResponse struct {
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty"`
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type ValidationErrorResponse struct {
+	Success   bool         `json:"success"`
+	Message   string       `json:"message"`
+	Code      string       `json:"code,omitempty"`
+	Errors    []FieldError `json:"errors"`
+	RequestID string       `json:"request_id,omitempty"`
+}
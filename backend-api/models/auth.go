@@ -8,51 +8,124 @@ type User struct {
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"`
 	IsOAuth      bool      `json:"is_oauth" db:"is_oauth"`
+	Role         string    `json:"role" db:"role"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Username string `json:"username" binding:"required,min=3,max=17"`
-	Password string `json:"password" binding:"required,min=8"`
+	Email    string `json:"email" binding:"required,email,max=255" example:"ada@example.com"`
+	Username string `json:"username" binding:"required,max=33" example:"@ada" description:"Must start with @; the allowed characters and length follow the deployment's username policy"`
+	Password string `json:"password" binding:"required,max=1024" description:"Checked against the deployment's password policy; see POST /api/v1/auth/password/strength"`
 }
 
 type RegisterResponse struct {
 	Success      bool         `json:"success"`
 	FieldErrors  []FieldError `json:"field_errors,omitempty"`
 	Message      string       `json:"message"`
+	Code         string       `json:"code,omitempty"`
 	AccessToken  *string      `json:"access_token,omitempty"`
 	RefreshToken *string      `json:"refresh_token,omitempty"`
 	User         *UserData    `json:"user,omitempty"`
+	RequestID    string       `json:"request_id,omitempty"`
 }
 
 type FieldError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
+	Field   string         `json:"field"`
+	Code    string         `json:"code,omitempty"`
+	Message string         `json:"message"`
+	Params  map[string]any `json:"params,omitempty"`
 }
 
 type UserData struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	Role     string `json:"role"`
 }
 
 type LoginRequest struct {
-	Identifier string `json:"identifier" binding:"required"`
-	Password   string `json:"password" binding:"required"`
+	Identifier string `json:"identifier" binding:"required,max=255" description:"Email address or @username"`
+	Password   string `json:"password" binding:"required,max=1024"`
 }
 
 type LoginResponse struct {
 	Success      bool      `json:"success"`
 	Message      string    `json:"message"`
+	Code         string    `json:"code,omitempty"`
 	AccessToken  string    `json:"access_token,omitempty"`
 	RefreshToken string    `json:"refresh_token,omitempty"`
 	User         *UserData `json:"user,omitempty"`
+	RequestID    string    `json:"request_id,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required,max=500"`
 }
 
 type RefreshTokenResponse struct {
-	Success     bool   `json:"success"`
-	Message     string `json:"message"`
-	AccessToken string `json:"access_token,omitempty"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	Code         string `json:"code,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	RequestID    string `json:"request_id,omitempty"`
 }
+
+type PasswordStrengthRequest struct {
+	Password string `json:"password" binding:"required,max=1024"`
+	Username string `json:"username,omitempty" binding:"omitempty,max=33" description:"Rejects passwords built from the username being registered"`
+	Email    string `json:"email,omitempty" binding:"omitempty,max=255" description:"Rejects passwords built from the email being registered"`
+}
+
+type PasswordHint struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PasswordStrengthResponse struct {
+	Success      bool           `json:"success"`
+	Acceptable   bool           `json:"acceptable"`
+	Score        int            `json:"score" binding:"min=0,max=4"`
+	MinScore     int            `json:"min_score"`
+	GuessesLog10 float64        `json:"guesses_log10"`
+	Warning      *PasswordHint  `json:"warning"`
+	Suggestions  []PasswordHint `json:"suggestions"`
+	Errors       []FieldError   `json:"errors"`
+}
+
+type CheckFieldQuery struct {
+	Field string `form:"field" binding:"required,oneof=email username"`
+	Value string `form:"value" binding:"required,max=255"`
+}
+
+type CheckFieldResponse struct {
+	Available *bool `json:"available"`
+}
+
+type ChangeUsernameRequest struct {
+	Username string `json:"username" binding:"required,max=33" example:"@ada.lovelace"`
+}
+
+type ChangeUsernameResponse struct {
+	Success      bool         `json:"success"`
+	FieldErrors  []FieldError `json:"field_errors,omitempty"`
+	Message      string       `json:"message"`
+	Code         string       `json:"code,omitempty"`
+	AccessToken  string       `json:"access_token,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	User         *UserData    `json:"user,omitempty"`
+	RequestID    string       `json:"request_id,omitempty"`
+}
+
+type ProfileResponse struct {
+	Success   bool      `json:"success"`
+	Message   string    `json:"message"`
+	User      *UserData `json:"user,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
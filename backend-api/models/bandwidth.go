@@ -0,0 +1,28 @@
+package models
+
+// BandwidthSettings caps the bandwidth of transfers, in bytes per second.
+// A null limit means no limit.
+type BandwidthSettings struct {
+	Limit    *int64            `json:"limit" binding:"omitempty,min=1024" description:"Limit outside the windows"`
+	Timezone string            `json:"timezone,omitempty" binding:"omitempty,max=64" example:"Europe/Berlin" description:"Time zone of the windows (default UTC)"`
+	Windows  []BandwidthWindow `json:"windows" binding:"omitempty,max=20,dive"`
+}
+
+// BandwidthWindow applies a different limit during part of the day. A
+// window that ends before it starts runs past midnight.
+type BandwidthWindow struct {
+	Days  []string `json:"days,omitempty" binding:"omitempty,max=7,dive,oneof=mon tue wed thu fri sat sun" example:"mon" description:"Days the window starts on (default every day)"`
+	Start string   `json:"start" binding:"required" example:"09:00"`
+	End   string   `json:"end" binding:"required" example:"18:00"`
+	Limit *int64   `json:"limit" binding:"omitempty,min=1024"`
+}
+
+type BandwidthResponse struct {
+	Success      bool               `json:"success"`
+	Settings     *BandwidthSettings `json:"settings"`
+	CurrentLimit *int64             `json:"current_limit" description:"The limit that applies now"`
+}
+
+type TransferBandwidthRequest struct {
+	Limit *int64 `json:"limit" binding:"omitempty,min=1024" description:"Bytes per second, or null for no limit of its own"`
+}
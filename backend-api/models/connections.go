@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+const (
+	ProtocolSFTP   = "sftp"
+	ProtocolSCP    = "scp"
+	ProtocolFTP    = "ftp"
+	ProtocolFTPS   = "ftps"
+	ProtocolWebDAV = "webdav"
+	ProtocolS3     = "s3"
+
+	AuthMethodPassword            = "password"
+	AuthMethodPublicKey           = "public_key"
+	AuthMethodAgent               = "agent"
+	AuthMethodKeyboardInteractive = "keyboard_interactive"
+	AuthMethodAccessKey           = "access_key"
+	AuthMethodAnonymous           = "anonymous"
+)
+
+// Connection is a saved site. Secrets are never stored with it; the desktop
+// app keeps passwords and keys in the OS keychain.
+type Connection struct {
+	ID              string            `json:"id" format:"uuid"`
+	Name            string            `json:"name"`
+	Protocol        string            `json:"protocol"`
+	Host            string            `json:"host"`
+	Port            int               `json:"port"`
+	Username        string            `json:"username"`
+	AuthMethod      string            `json:"auth_method"`
+	RemoteDirectory string            `json:"remote_directory"`
+	LocalDirectory  string            `json:"local_directory"`
+	Folder          string            `json:"folder"`
+	Tags            []string          `json:"tags"`
+	Color           string            `json:"color"`
+	Notes           string            `json:"notes"`
+	Options         ConnectionOptions `json:"options"`
+	Version         int               `json:"version"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// ConnectionOptions holds protocol-specific settings. Options that do not
+// apply to the connection's protocol are dropped on save.
+type ConnectionOptions struct {
+	FTPSMode     string `json:"ftps_mode,omitempty" binding:"omitempty,oneof=explicit implicit" description:"FTPS only; defaults to explicit"`
+	ActiveMode   bool   `json:"active_mode,omitempty" description:"FTP and FTPS only; passive mode is used otherwise"`
+	WebDAVScheme string `json:"webdav_scheme,omitempty" binding:"omitempty,oneof=https http" description:"WebDAV only; defaults to https"`
+	Bucket       string `json:"bucket,omitempty" binding:"max=63" description:"S3 only"`
+	Region       string `json:"region,omitempty" binding:"max=64" description:"S3 only"`
+	PathStyle    bool   `json:"path_style,omitempty" description:"S3 only; use path-style instead of virtual-hosted bucket URLs"`
+	S3Scheme     string `json:"s3_scheme,omitempty" binding:"omitempty,oneof=https http" description:"S3 only; defaults to https"`
+}
+
+type ConnectionInput struct {
+	Name            string            `json:"name" binding:"required,max=100" example:"Production web"`
+	Protocol        string            `json:"protocol" binding:"required,oneof=sftp scp ftp ftps webdav s3"`
+	Host            string            `json:"host" binding:"required,max=255" example:"files.example.com"`
+	Port            int               `json:"port,omitempty" binding:"omitempty,min=1,max=65535" description:"Defaults to the protocol's standard port"`
+	Username        string            `json:"username,omitempty" binding:"max=255"`
+	AuthMethod      string            `json:"auth_method,omitempty" binding:"omitempty,oneof=password public_key agent keyboard_interactive access_key anonymous" description:"Defaults to password, or access_key for S3"`
+	RemoteDirectory string            `json:"remote_directory,omitempty" binding:"max=1024" example:"/var/www"`
+	LocalDirectory  string            `json:"local_directory,omitempty" binding:"max=1024"`
+	Folder          string            `json:"folder,omitempty" binding:"max=255" example:"Clients/Acme" description:"Slash-separated folder path"`
+	Tags            []string          `json:"tags,omitempty" binding:"max=20,dive,max=50"`
+	Color           string            `json:"color,omitempty" binding:"max=7" example:"#2f80ed"`
+	Notes           string            `json:"notes,omitempty" binding:"max=4000"`
+	Options         ConnectionOptions `json:"options,omitempty"`
+	Version         int               `json:"version,omitempty" binding:"omitempty,min=1" description:"Required on update: the version the change was made against"`
+}
+
+type ConnectionFilter struct {
+	Folder string
+	Tag    string
+}
+
+type ConnectionListQuery struct {
+	Folder string `form:"folder" binding:"omitempty,max=255" description:"Only connections in this folder or its subfolders"`
+	Tag    string `form:"tag" binding:"omitempty,max=50"`
+}
+
+type ConnectionDeleteQuery struct {
+	Version int `form:"version" binding:"omitempty,min=1" description:"Only delete if the connection is still at this version"`
+}
+
+type ConnectionSyncQuery struct {
+	Since string `form:"since" binding:"omitempty,max=20" description:"Cursor from the previous sync; omit for a full sync"`
+}
+
+type ConnectionListResponse struct {
+	Success     bool         `json:"success"`
+	Connections []Connection `json:"connections"`
+}
+
+type ConnectionResponse struct {
+	Success    bool        `json:"success"`
+	Connection *Connection `json:"connection"`
+}
+
+type ConnectionSyncResponse struct {
+	Success     bool         `json:"success"`
+	Connections []Connection `json:"connections" description:"Connections created or changed since the cursor"`
+	Deleted     []string     `json:"deleted" description:"IDs of connections deleted since the cursor"`
+	Cursor      string       `json:"cursor" description:"Pass as since on the next sync"`
+}
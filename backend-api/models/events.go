@@ -0,0 +1,29 @@
+package models
+
+// TransferProgressEvent is sent while a transfer's data is copied.
+type TransferProgressEvent struct {
+	TransferID       string  `json:"transfer_id"`
+	TransferredBytes int64   `json:"transferred_bytes"`
+	Size             *int64  `json:"size"`
+	Progress         float64 `json:"progress"`
+}
+
+// TransferStatusEvent is sent when a transfer's status changes.
+type TransferStatusEvent struct {
+	TransferID string  `json:"transfer_id"`
+	Status     string  `json:"status"`
+	Detail     *string `json:"detail"`
+}
+
+// SessionRevokedEvent is sent when one of the user's sessions is revoked.
+// Clients refresh their tokens right away to find out whether it was theirs.
+type SessionRevokedEvent struct {
+	Reason string `json:"reason"`
+}
+
+// NotificationEvent is a message for the user. Clients look up the text
+// for Kind and fill in Params.
+type NotificationEvent struct {
+	Kind   string         `json:"kind"`
+	Params map[string]any `json:"params,omitempty"`
+}
@@ -0,0 +1,139 @@
+package models
+
+import "time"
+
+const (
+	KnownHostTrusted = "trusted"
+	KnownHostUnknown = "unknown"
+	KnownHostChanged = "changed"
+)
+
+const (
+	KnownHostEventKeyChanged  = "key_changed"
+	KnownHostEventKeyReplaced = "key_replaced"
+	KnownHostEventPinAdded    = "pin_added"
+	KnownHostEventPinReplaced = "pin_replaced"
+	KnownHostEventPinRemoved  = "pin_removed"
+)
+
+// KnownHost is a trusted host key, either the user's own or pinned for a
+// team by an admin.
+type KnownHost struct {
+	ID                string    `json:"id" format:"uuid"`
+	Host              string    `json:"host" example:"[git.example.com]:2222" description:"The host in known_hosts form; the port is omitted when it is 22, and imported hashed entries keep their |1| hash"`
+	Hashed            bool      `json:"hashed"`
+	Type              string    `json:"type" example:"ssh-ed25519"`
+	PublicKey         string    `json:"public_key"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256"`
+	Comment           string    `json:"comment"`
+	TeamID            *string   `json:"team_id,omitempty" format:"uuid" description:"Set for keys pinned for a team"`
+	FirstSeenBy       *string   `json:"first_seen_by" format:"uuid"`
+	FirstSeenAt       time.Time `json:"first_seen_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type KnownHostInput struct {
+	Host      string `json:"host" binding:"required,max=253"`
+	Port      int    `json:"port,omitempty" binding:"omitempty,min=1,max=65535" description:"Defaults to 22"`
+	PublicKey string `json:"public_key" binding:"required,max=16384" description:"The host key as an authorized_keys line"`
+	Comment   string `json:"comment,omitempty" binding:"max=255"`
+	Replace   bool   `json:"replace,omitempty" description:"Replace a different key of the same type already trusted for the host"`
+}
+
+type KnownHostVerifyRequest struct {
+	Host      string `json:"host" binding:"required,max=253"`
+	Port      int    `json:"port,omitempty" binding:"omitempty,min=1,max=65535" description:"Defaults to 22"`
+	PublicKey string `json:"public_key" binding:"required,max=16384" description:"The key the host presented"`
+}
+
+// KnownHostKey is a validated KnownHostInput with the audit details of the
+// request.
+type KnownHostKey struct {
+	Address   string
+	PublicKey string
+	Comment   string
+	Replace   bool
+	ClientIP  string
+	UserAgent string
+}
+
+type KnownHostVerification struct {
+	Status      string      `json:"status" description:"trusted, unknown or changed"`
+	Pinned      bool        `json:"pinned" description:"Whether a team pin decided the status"`
+	Host        string      `json:"host" example:"[git.example.com]:2222"`
+	Fingerprint string      `json:"fingerprint_sha256" description:"Fingerprint of the presented key"`
+	Expected    []KnownHost `json:"expected" description:"The keys trusted for the host"`
+}
+
+type KnownHostsImportRequest struct {
+	KnownHosts string `json:"known_hosts" binding:"required,max=524288" description:"An OpenSSH known_hosts file; hashed host names are kept hashed"`
+}
+
+type KnownHostsImportResult struct {
+	Imported  int      `json:"imported"`
+	Unchanged int      `json:"unchanged"`
+	Skipped   int      `json:"skipped" description:"Lines with markers or host patterns, which are not imported"`
+	Changed   []string `json:"changed" description:"Hosts whose key differs from the trusted one; they keep the trusted key"`
+}
+
+type KnownHostsExportQuery struct {
+	Hash bool `form:"hash" description:"Hash host names as ssh-keygen -H does"`
+}
+
+type KnownHostEvent struct {
+	ID                   string    `json:"id" format:"uuid"`
+	Event                string    `json:"event" description:"key_changed, key_replaced, pin_added, pin_replaced or pin_removed"`
+	UserID               *string   `json:"user_id" format:"uuid"`
+	TeamID               *string   `json:"team_id" format:"uuid"`
+	Host                 string    `json:"host"`
+	Type                 string    `json:"type"`
+	ExpectedFingerprint  *string   `json:"expected_fingerprint"`
+	PresentedFingerprint *string   `json:"presented_fingerprint"`
+	ClientIP             string    `json:"client_ip,omitempty"`
+	UserAgent            string    `json:"user_agent,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+type KnownHostEventFilter struct {
+	UserID string
+	TeamID string
+	Event  string
+	Limit  int
+	Offset int
+}
+
+type KnownHostEventListQuery struct {
+	UserID string `form:"user_id" binding:"omitempty,uuid"`
+	TeamID string `form:"team_id" binding:"omitempty,uuid"`
+	Event  string `form:"event" binding:"omitempty,oneof=key_changed key_replaced pin_added pin_replaced pin_removed"`
+	Limit  int    `form:"limit" description:"Page size, clamped to 1-200 (default 50)"`
+	Offset int    `form:"offset" description:"Number of events to skip (default 0)"`
+}
+
+type KnownHostListResponse struct {
+	Success bool        `json:"success"`
+	Hosts   []KnownHost `json:"hosts"`
+}
+
+type KnownHostResponse struct {
+	Success bool       `json:"success"`
+	Host    *KnownHost `json:"host"`
+}
+
+type KnownHostVerifyResponse struct {
+	Success      bool                  `json:"success"`
+	Verification KnownHostVerification `json:"verification"`
+}
+
+type KnownHostsImportResponse struct {
+	Success bool                   `json:"success"`
+	Result  KnownHostsImportResult `json:"result"`
+}
+
+type KnownHostEventListResponse struct {
+	Success bool             `json:"success"`
+	Events  []KnownHostEvent `json:"events"`
+	Total   int              `json:"total"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
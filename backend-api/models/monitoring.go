@@ -1,9 +1,120 @@
 package models
 
+import "time"
+
 type ClientErrorLog struct {
-	Timestamp    string `json:"timestamp" binding:"required"`
-	ErrorType    string `json:"error_type" binding:"required,max=100"`
-	ErrorMessage string `json:"error_message" binding:"required,max=1000"`
-	AppVersion   string `json:"app_version" binding:"required,max=50"`
-	OS           string `json:"os" binding:"required,max=20"`
+	Timestamp    time.Time          `json:"timestamp" binding:"required"`
+	ErrorType    string             `json:"error_type" binding:"required,max=100"`
+	ErrorMessage string             `json:"error_message" binding:"required,max=1000"`
+	StackTrace   string             `json:"stack_trace,omitempty" binding:"max=20000"`
+	Breadcrumbs  []ClientBreadcrumb `json:"breadcrumbs,omitempty" binding:"max=100,dive"`
+	AppVersion   string             `json:"app_version" binding:"required,max=50"`
+	OS           string             `json:"os" binding:"required,max=20"`
+	RequestID    string             `json:"request_id,omitempty" binding:"max=128"`
+}
+
+type ClientBreadcrumb struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Category  string            `json:"category" binding:"max=50"`
+	Level     string            `json:"level,omitempty" binding:"max=20"`
+	Message   string            `json:"message" binding:"max=500"`
+	Data      map[string]string `json:"data,omitempty" binding:"max=20"`
+}
+
+type ClientIssue struct {
+	ID            string               `json:"id"`
+	Fingerprint   string               `json:"fingerprint"`
+	ErrorType     string               `json:"error_type"`
+	Title         string               `json:"title"`
+	Status        string               `json:"status"`
+	EventCount    int64                `json:"event_count"`
+	FirstSeenAt   time.Time            `json:"first_seen_at"`
+	LastSeenAt    time.Time            `json:"last_seen_at"`
+	LastAlertedAt *time.Time           `json:"last_alerted_at,omitempty"`
+	Versions      []ClientIssueVersion `json:"versions,omitempty"`
+	Reports       []ClientErrorReport  `json:"reports,omitempty"`
+}
+
+type ClientIssueVersion struct {
+	AppVersion  string    `json:"app_version"`
+	EventCount  int64     `json:"event_count"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+type ClientErrorReport struct {
+	ID           string             `json:"id"`
+	IssueID      string             `json:"issue_id"`
+	UserID       *string            `json:"user_id,omitempty"`
+	RequestID    string             `json:"request_id,omitempty"`
+	ErrorType    string             `json:"error_type"`
+	ErrorMessage string             `json:"error_message"`
+	StackTrace   string             `json:"stack_trace,omitempty"`
+	Breadcrumbs  []ClientBreadcrumb `json:"breadcrumbs"`
+	AppVersion   string             `json:"app_version"`
+	OS           string             `json:"os"`
+	OccurredAt   time.Time          `json:"occurred_at"`
+	ReceivedAt   time.Time          `json:"received_at"`
+}
+
+type ClientIssueFilter struct {
+	Status     string
+	AppVersion string
+	Limit      int
+	Offset     int
+}
+
+type ClientIssueListQuery struct {
+	Status     string `form:"status" binding:"omitempty,oneof=open resolved ignored regressed"`
+	AppVersion string `form:"app_version" binding:"omitempty,max=50"`
+	Limit      int    `form:"limit" description:"Page size, clamped to 1-200 (default 50)"`
+	Offset     int    `form:"offset" description:"Number of issues to skip (default 0)"`
+}
+
+type ClientIssueQuery struct {
+	Reports int `form:"reports" description:"Number of recent reports to include, clamped to 0-100 (default 20)"`
+}
+
+type ClientIssueStatusUpdate struct {
+	Status string `json:"status" binding:"required,oneof=open resolved ignored regressed"`
+}
+
+type ClientIssueListResponse struct {
+	Success bool          `json:"success"`
+	Issues  []ClientIssue `json:"issues"`
+	Total   int           `json:"total"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
+
+type ClientIssueResponse struct {
+	Success bool         `json:"success"`
+	Issue   *ClientIssue `json:"issue"`
 }
+
+type ClientErrorAccepted struct {
+	Success  bool   `json:"success"`
+	ReportID string `json:"report_id"`
+	IssueID  string `json:"issue_id"`
+}
+
+type HealthStatus struct {
+	Status     string `json:"status"`
+	Database   string `json:"database"`
+	Migrations string `json:"migrations,omitempty"`
+	Error      string `json:"error,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+type ClientErrorReceipt struct {
+	ReportID string
+	Issue    ClientIssue
+	NewIssue bool
+}
+
+const (
+	ClientIssueStatusOpen      = "open"
+	ClientIssueStatusResolved  = "resolved"
+	ClientIssueStatusIgnored   = "ignored"
+	ClientIssueStatusRegressed = "regressed"
+)
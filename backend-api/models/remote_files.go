@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+const (
+	RemoteFileTypeFile      = "file"
+	RemoteFileTypeDirectory = "directory"
+	RemoteFileTypeSymlink   = "symlink"
+	RemoteFileTypeOther     = "other"
+)
+
+// RemoteFile is a file or directory on a connection's server.
+type RemoteFile struct {
+	Name        string    `json:"name"`
+	Path        string    `json:"path" example:"/var/www/index.html"`
+	Type        string    `json:"type" description:"file, directory, symlink or other"`
+	Size        int64     `json:"size"`
+	Mode        string    `json:"mode" example:"0644" description:"Permission bits in octal"`
+	Permissions string    `json:"permissions" example:"-rw-r--r--"`
+	ModifiedAt  time.Time `json:"modified_at"`
+	UID         *uint32   `json:"uid,omitempty"`
+	GID         *uint32   `json:"gid,omitempty"`
+}
+
+type RemoteListQuery struct {
+	Path string `form:"path" binding:"omitempty,max=4096" description:"Defaults to the connection's remote directory, or the login directory"`
+}
+
+type RemotePathQuery struct {
+	Path string `form:"path" binding:"required,max=4096"`
+}
+
+type RemoteDownloadQuery struct {
+	Path    string `form:"path" binding:"required,max=4096"`
+	Archive string `form:"archive" binding:"omitempty,oneof=zip tar.gz" description:"Download a directory as an archive of this format, built while it is sent"`
+}
+
+type RemoteUploadQuery struct {
+	Path      string `form:"path" binding:"required,max=4096"`
+	Overwrite bool   `form:"overwrite" description:"Replace an existing file"`
+	Offset    int64  `form:"offset" binding:"omitempty,min=0" description:"Resume an upload: the body is written from this offset of the existing file, which is truncated to it first"`
+}
+
+type RemoteMkdirRequest struct {
+	Path    string `json:"path" binding:"required,max=4096"`
+	Parents bool   `json:"parents,omitempty" description:"Create missing parent directories, and succeed if the directory exists"`
+}
+
+type RemoteRenameRequest struct {
+	From      string `json:"from" binding:"required,max=4096"`
+	To        string `json:"to" binding:"required,max=4096"`
+	Overwrite bool   `json:"overwrite,omitempty" description:"Replace an existing file at the new path"`
+}
+
+type RemoteDeleteRequest struct {
+	Path      string `json:"path" binding:"required,max=4096"`
+	Recursive bool   `json:"recursive,omitempty" description:"Delete a directory with its contents"`
+}
+
+type RemoteChmodRequest struct {
+	Path string `json:"path" binding:"required,max=4096"`
+	Mode string `json:"mode" binding:"required,max=5" example:"0755" description:"Permission bits in octal"`
+}
+
+// RemoteChmod is a validated RemoteChmodRequest.
+type RemoteChmod struct {
+	Path string
+	Mode uint32
+}
+
+// RemoteCapabilities lists the optional features of a connection's
+// protocol. Requests for a missing one fail with
+// remote.operation_unsupported.
+type RemoteCapabilities struct {
+	Chmod             bool `json:"chmod" description:"Permissions can be changed"`
+	Chtimes           bool `json:"chtimes" description:"Modification times are kept by transfers"`
+	Symlinks          bool `json:"symlinks" description:"Links are listed as links, and extracted archives can create them"`
+	Ownership         bool `json:"ownership" description:"Files report uid and gid"`
+	ResumeUpload      bool `json:"resume_upload" description:"Uploads can resume with an offset"`
+	RenameDirectories bool `json:"rename_directories"`
+	Exec              bool `json:"exec" description:"The server can run commands, such as checksums"`
+}
+
+type RemoteFileListResponse struct {
+	Success      bool               `json:"success"`
+	Path         string             `json:"path" description:"The listed directory, resolved to an absolute path"`
+	Files        []RemoteFile       `json:"files"`
+	Capabilities RemoteCapabilities `json:"capabilities"`
+}
+
+type RemoteFileResponse struct {
+	Success bool        `json:"success"`
+	File    *RemoteFile `json:"file"`
+}
+
+// RemoteSearchRequest searches a directory tree by name, content or both.
+// Limits above the server's are lowered to them.
+type RemoteSearchRequest struct {
+	Path           string `json:"path,omitempty" binding:"max=4096" description:"Defaults to the connection's remote directory, or the login directory"`
+	Name           string `json:"name,omitempty" binding:"max=255" example:"*.conf" description:"Glob matched against the names of files and directories"`
+	Content        string `json:"content,omitempty" binding:"max=1024" example:"server_name" description:"Text to find in files. With name, only files whose names match are searched"`
+	Regexp         bool   `json:"regexp,omitempty" description:"Treat content as an extended regular expression"`
+	IgnoreCase     bool   `json:"ignore_case,omitempty" description:"Match name and content regardless of case"`
+	MaxDepth       int    `json:"max_depth,omitempty" binding:"omitempty,min=1" description:"How many levels below path to search; 1 searches its entries only"`
+	MaxResults     int    `json:"max_results,omitempty" binding:"omitempty,min=1"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" binding:"omitempty,min=1"`
+}
+
+// RemoteSearchMatch is sent for each result: a file whose name matched or,
+// in a content search, a line that contains the content.
+type RemoteSearchMatch struct {
+	File RemoteFile `json:"file"`
+	Line int        `json:"line,omitempty" description:"Number of the matching line, from 1"`
+	Text string     `json:"text,omitempty" description:"The matching line, cut to 1000 bytes"`
+}
+
+// RemoteSearchSummary is sent when a search ends.
+type RemoteSearchSummary struct {
+	Path         string `json:"path" description:"The searched directory, resolved to an absolute path"`
+	Results      int    `json:"results"`
+	FilesScanned int    `json:"files_scanned" description:"Files whose content was searched"`
+	BytesScanned int64  `json:"bytes_scanned"`
+	Method       string `json:"method" description:"exec if the server ran find and grep, walk if the files were listed and read over the file protocol"`
+	Limit        string `json:"limit,omitempty" description:"Set if the search stopped early: max_results, max_bytes or timeout"`
+}
+
+// RemoteSearchError is sent when a search fails after its stream started.
+type RemoteSearchError struct {
+	Code   string `json:"code" example:"remote.failed"`
+	Detail string `json:"detail"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+const (
+	ScheduleKindTransfer = "transfer"
+	ScheduleKindSync     = "sync"
+
+	ScheduleRunPending = "pending"
+	ScheduleRunStarted = "started"
+	ScheduleRunSkipped = "skipped"
+	ScheduleRunFailed  = "failed"
+)
+
+// ScheduleRequest creates or replaces a recurring job, which queues either
+// a download or a sync on every run.
+type ScheduleRequest struct {
+	Name       string           `json:"name" binding:"required,max=100" example:"Nightly backup"`
+	Cron       string           `json:"cron" binding:"required,max=100" example:"0 2 * * *" description:"Five-field cron expression, or @hourly, @daily, @weekly, @monthly or @yearly"`
+	Timezone   string           `json:"timezone,omitempty" binding:"omitempty,max=64" example:"Europe/Berlin" description:"Time zone of the cron expression (default UTC)"`
+	MissedRuns string           `json:"missed_runs,omitempty" binding:"omitempty,oneof=skip catch_up" description:"What to do about runs the server could not start on time: skip them (the default) or start one run to catch up"`
+	Enabled    *bool            `json:"enabled,omitempty" description:"Default true"`
+	Transfer   *TransferRequest `json:"transfer,omitempty" description:"A download to queue on every run"`
+	Sync       *SyncRequest     `json:"sync,omitempty" description:"A sync to plan and execute on every run"`
+}
+
+type Schedule struct {
+	ID         string           `json:"id" format:"uuid"`
+	Name       string           `json:"name"`
+	Cron       string           `json:"cron"`
+	Timezone   string           `json:"timezone"`
+	MissedRuns string           `json:"missed_runs"`
+	Enabled    bool             `json:"enabled"`
+	Kind       string           `json:"kind" description:"transfer or sync"`
+	Transfer   *TransferRequest `json:"transfer,omitempty"`
+	Sync       *SyncRequest     `json:"sync,omitempty"`
+	NextRunAt  *time.Time       `json:"next_run_at" description:"Null when the schedule is disabled or will not run again"`
+	LastRunAt  *time.Time       `json:"last_run_at"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// ScheduleRun records one run of a schedule. A started run has queued its
+// transfer or executed its sync plan; follow those for the outcome.
+type ScheduleRun struct {
+	ID           string     `json:"id" format:"uuid"`
+	ScheduleID   string     `json:"schedule_id" format:"uuid"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	Status       string     `json:"status" description:"pending, started, skipped or failed"`
+	Detail       *string    `json:"detail"`
+	TransferID   *string    `json:"transfer_id" format:"uuid"`
+	SyncPlanID   *string    `json:"sync_plan_id" format:"uuid"`
+	CreatedAt    time.Time  `json:"created_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
+
+type ScheduleResponse struct {
+	Success  bool      `json:"success"`
+	Schedule *Schedule `json:"schedule"`
+}
+
+type ScheduleListResponse struct {
+	Success   bool       `json:"success"`
+	Schedules []Schedule `json:"schedules"`
+}
+
+type ScheduleRunListQuery struct {
+	Limit int `form:"limit" description:"Number of runs, clamped to 1-200 (default 50)"`
+}
+
+type ScheduleRunListResponse struct {
+	Success bool          `json:"success"`
+	Runs    []ScheduleRun `json:"runs"`
+}
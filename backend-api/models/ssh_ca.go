@@ -0,0 +1,143 @@
+package models
+
+import "time"
+
+// Team grants its members SSH certificates for its principals.
+type Team struct {
+	ID         string    `json:"id" format:"uuid"`
+	Name       string    `json:"name" example:"Platform"`
+	Principals []string  `json:"principals" example:"deploy,ops" description:"Unix accounts members may log in as with a LiveCode certificate"`
+	Members    int       `json:"members" description:"Number of members"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type TeamInput struct {
+	Name       string   `json:"name" binding:"required,max=100"`
+	Principals []string `json:"principals" binding:"max=20" description:"1-32 letters, digits, dots, underscores or hyphens each"`
+}
+
+type TeamMember struct {
+	UserID   string    `json:"user_id" format:"uuid"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+type TeamListResponse struct {
+	Success bool   `json:"success"`
+	Teams   []Team `json:"teams"`
+}
+
+type TeamResponse struct {
+	Success bool  `json:"success"`
+	Team    *Team `json:"team"`
+}
+
+type TeamMemberListResponse struct {
+	Success bool         `json:"success"`
+	Members []TeamMember `json:"members"`
+}
+
+// SSHCertificateAuthority is a CA key. Only the active CA signs; retired
+// ones stay trusted until the certificates they issued expire.
+type SSHCertificateAuthority struct {
+	ID                string     `json:"id" format:"uuid"`
+	Name              string     `json:"name"`
+	Type              string     `json:"type" example:"ssh-ed25519"`
+	PublicKey         string     `json:"public_key" description:"The CA key in authorized_keys format, for sshd's TrustedUserCAKeys"`
+	FingerprintSHA256 string     `json:"fingerprint_sha256"`
+	Active            bool       `json:"active"`
+	CreatedAt         time.Time  `json:"created_at"`
+	RetiredAt         *time.Time `json:"retired_at,omitempty"`
+}
+
+// SSHCertificateAuthorityRequest generates a CA key, or imports PrivateKey
+// when it is set.
+type SSHCertificateAuthorityRequest struct {
+	Name       string `json:"name" binding:"required,max=100"`
+	Algorithm  string `json:"algorithm,omitempty" binding:"omitempty,oneof=ed25519 rsa" description:"Defaults to ed25519; ignored when importing"`
+	Bits       int    `json:"bits,omitempty" binding:"omitempty,oneof=2048 3072 4096" description:"RSA only; defaults to 4096"`
+	PrivateKey string `json:"private_key,omitempty" binding:"max=65536" description:"An existing OpenSSH, PEM or PuTTY .ppk CA key to import"`
+	Passphrase string `json:"passphrase,omitempty" binding:"max=1024"`
+}
+
+type SSHCertificateAuthorityListResponse struct {
+	Success     bool                      `json:"success"`
+	Authorities []SSHCertificateAuthority `json:"authorities" description:"The active CA followed by retired CAs that are still trusted"`
+}
+
+type SSHCertificateAuthorityResponse struct {
+	Success   bool                     `json:"success"`
+	Authority *SSHCertificateAuthority `json:"authority"`
+}
+
+// SSHCertificate is the audit record of an issued certificate.
+type SSHCertificate struct {
+	Serial           int64      `json:"serial" example:"1042"`
+	CAID             string     `json:"ca_id" format:"uuid"`
+	UserID           *string    `json:"user_id" format:"uuid"`
+	SSHKeyID         *string    `json:"ssh_key_id" format:"uuid"`
+	KeyFingerprint   string     `json:"key_fingerprint"`
+	KeyID            string     `json:"key_id" example:"livecode:@ada:1042"`
+	Principals       []string   `json:"principals"`
+	ValidAfter       time.Time  `json:"valid_after"`
+	ValidBefore      time.Time  `json:"valid_before"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	ClientIP         string     `json:"client_ip,omitempty"`
+	UserAgent        string     `json:"user_agent,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy        *string    `json:"revoked_by,omitempty" format:"uuid"`
+	RevocationReason *string    `json:"revocation_reason,omitempty"`
+	Certificate      string     `json:"certificate,omitempty" description:"The signed certificate, returned only when it is issued. Save it next to the key as <key>-cert.pub"`
+}
+
+type SSHCertificateRequest struct {
+	SSHKeyID        string `json:"ssh_key_id,omitempty" binding:"omitempty,uuid" description:"A registered SSH key; give this or public_key"`
+	PublicKey       string `json:"public_key,omitempty" binding:"max=16384" description:"An authorized_keys line or RFC 4716 public key"`
+	ValidForMinutes int    `json:"valid_for_minutes,omitempty" binding:"omitempty,min=5" description:"Defaults to the server's configured lifetime, and may not exceed its maximum"`
+}
+
+// SSHCertificateIssue is a validated SSHCertificateRequest with the audit
+// details of the request.
+type SSHCertificateIssue struct {
+	SSHKeyID  string
+	PublicKey string
+	ValidFor  time.Duration
+	ClientIP  string
+	UserAgent string
+}
+
+type SSHCertificateFilter struct {
+	UserID string
+	Limit  int
+	Offset int
+}
+
+type SSHCertificateListQuery struct {
+	Limit  int `form:"limit" description:"Page size, clamped to 1-200 (default 50)"`
+	Offset int `form:"offset" description:"Number of certificates to skip (default 0)"`
+}
+
+type SSHCertificateAdminListQuery struct {
+	UserID string `form:"user_id" binding:"omitempty,uuid"`
+	Limit  int    `form:"limit" description:"Page size, clamped to 1-200 (default 50)"`
+	Offset int    `form:"offset" description:"Number of certificates to skip (default 0)"`
+}
+
+type SSHCertificateRevokeRequest struct {
+	Reason string `json:"reason,omitempty" binding:"max=255"`
+}
+
+type SSHCertificateListResponse struct {
+	Success      bool             `json:"success"`
+	Certificates []SSHCertificate `json:"certificates"`
+	Total        int              `json:"total"`
+	Limit        int              `json:"limit"`
+	Offset       int              `json:"offset"`
+}
+
+type SSHCertificateResponse struct {
+	Success     bool            `json:"success"`
+	Certificate *SSHCertificate `json:"certificate"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+const (
+	SSHKeySourceRegistered = "registered"
+	SSHKeySourceGenerated  = "generated"
+	SSHKeySourceImported   = "imported"
+)
+
+// SSHKey is a public key registered with LiveCode. Generated and imported
+// keys keep their private key in the vault, referenced by VaultItemID.
+type SSHKey struct {
+	ID                string    `json:"id" format:"uuid"`
+	Name              string    `json:"name"`
+	Type              string    `json:"type" example:"ssh-ed25519"`
+	Bits              int       `json:"bits" example:"256"`
+	PublicKey         string    `json:"public_key" description:"The key in authorized_keys format, including the comment"`
+	Comment           string    `json:"comment"`
+	FingerprintSHA256 string    `json:"fingerprint_sha256" example:"SHA256:sOLk9a1GemMY7EL+y5zRDyR86uzzSX/T53woIR1Colc"`
+	FingerprintMD5    string    `json:"fingerprint_md5" example:"MD5:9b:7b:8c:63:2d:00:78:70:6e:9b:1d:1e:b1:55:fe:54"`
+	Source            string    `json:"source" description:"registered, generated or imported"`
+	VaultItemID       *string   `json:"vault_item_id,omitempty" format:"uuid" description:"Vault item holding the private key, if LiveCode has it"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type SSHKeyInput struct {
+	Name        string `json:"name" binding:"required,max=100" example:"Work laptop"`
+	PublicKey   string `json:"public_key" binding:"required,max=16384" description:"An authorized_keys line or an RFC 4716 public key as saved by PuTTYgen"`
+	Comment     string `json:"comment,omitempty" binding:"max=255" description:"Defaults to the comment in the public key"`
+	VaultItemID string `json:"vault_item_id,omitempty" binding:"omitempty,uuid" description:"A client-encrypted private_key vault item holding the matching private key"`
+}
+
+type SSHKeyGenerateRequest struct {
+	Name      string `json:"name" binding:"required,max=100" example:"Deploy key"`
+	Algorithm string `json:"algorithm" binding:"required,oneof=ed25519 rsa"`
+	Bits      int    `json:"bits,omitempty" binding:"omitempty,oneof=2048 3072 4096" description:"RSA only; defaults to 4096"`
+	Comment   string `json:"comment,omitempty" binding:"max=255" description:"Defaults to the key name"`
+}
+
+type SSHKeyImportRequest struct {
+	Name       string `json:"name" binding:"required,max=100"`
+	PrivateKey string `json:"private_key" binding:"required,max=65536" description:"An OpenSSH, PEM or PuTTY .ppk private key"`
+	Passphrase string `json:"passphrase,omitempty" binding:"max=1024" description:"Required if the private key is encrypted; the key is stored without it"`
+	Comment    string `json:"comment,omitempty" binding:"max=255" description:"Defaults to the comment in a .ppk file, or the key name"`
+}
+
+type SSHKeyExportRequest struct {
+	Format     string `json:"format" binding:"required,oneof=openssh ppk"`
+	Passphrase string `json:"passphrase,omitempty" binding:"max=1024" description:"Encrypt the exported private key with this passphrase"`
+}
+
+type SSHKeyExport struct {
+	Format     string `json:"format"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+}
+
+type SSHKeyListResponse struct {
+	Success bool     `json:"success"`
+	Keys    []SSHKey `json:"keys"`
+}
+
+type SSHKeyResponse struct {
+	Success bool    `json:"success"`
+	Key     *SSHKey `json:"key"`
+}
+
+type SSHKeyExportResponse struct {
+	Success bool         `json:"success"`
+	Export  SSHKeyExport `json:"export"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// SyncRequest asks for a plan that syncs a directory on a connection with
+// a directory on the client, described by Local.
+type SyncRequest struct {
+	ConnectionID     string            `json:"connection_id" binding:"required,uuid"`
+	RemotePath       string            `json:"remote_path" binding:"required,max=4096" example:"/var/www"`
+	Direction        string            `json:"direction" binding:"required,oneof=upload download both" description:"upload makes the remote directory match the local one, download the reverse, and both copies the newer file each way"`
+	Criteria         string            `json:"criteria,omitempty" binding:"omitempty,oneof=mtime size checksum" description:"How files are compared: mtime (the default) also compares sizes, checksum compares the SHA-256 of files of the same size"`
+	Mirror           bool              `json:"mirror,omitempty" description:"Copy files that differ even when the target's copy is newer, instead of reporting a conflict"`
+	Delete           bool              `json:"delete,omitempty" description:"Delete files and directories the target has and the source does not"`
+	Include          []string          `json:"include,omitempty" binding:"omitempty,max=100,dive,max=1024" example:"*.go" description:"Only sync files matching one of these globs"`
+	Exclude          []string          `json:"exclude,omitempty" binding:"omitempty,max=100,dive,max=1024" example:"node_modules/" description:"Leave out paths matching these globs"`
+	IgnoreFiles      []string          `json:"ignore_files,omitempty" binding:"omitempty,max=10,dive,max=255" example:".gitignore" description:"Names of .gitignore-style files to honour in every directory"`
+	LocalIgnoreFiles map[string]string `json:"local_ignore_files,omitempty" binding:"omitempty,max=100" description:"Contents of the client's ignore files, by path relative to the directory"`
+	Local            []SyncLocalEntry  `json:"local" binding:"required"`
+}
+
+// SyncLocalEntry is a file or directory on the client, with a path relative
+// to the synced directory.
+type SyncLocalEntry struct {
+	Path       string    `json:"path" binding:"required,max=4096" example:"src/main.go"`
+	Type       string    `json:"type" binding:"required,oneof=file directory"`
+	Size       int64     `json:"size,omitempty" binding:"omitempty,min=0"`
+	ModifiedAt time.Time `json:"modified_at"`
+	SHA256     string    `json:"sha256,omitempty" binding:"omitempty,len=64,hexadecimal" description:"Required for files when criteria is checksum"`
+}
+
+type SyncSide struct {
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+	SHA256     string    `json:"sha256,omitempty"`
+}
+
+// SyncAction is one step of a sync plan.
+type SyncAction struct {
+	Path      string    `json:"path" example:"src/main.go"`
+	Action    string    `json:"action" description:"upload, download, delete_remote, delete_local, mkdir_remote, mkdir_local or conflict"`
+	Reason    string    `json:"reason" description:"missing, changed, extraneous, type_mismatch, target_newer or same_time"`
+	Directory bool      `json:"directory"`
+	Local     *SyncSide `json:"local"`
+	Remote    *SyncSide `json:"remote"`
+}
+
+type SyncSummary struct {
+	Uploads           int   `json:"uploads"`
+	Downloads         int   `json:"downloads"`
+	RemoteDeletes     int   `json:"remote_deletes"`
+	LocalDeletes      int   `json:"local_deletes"`
+	RemoteDirectories int   `json:"remote_directories"`
+	LocalDirectories  int   `json:"local_directories"`
+	Conflicts         int   `json:"conflicts"`
+	UploadBytes       int64 `json:"upload_bytes"`
+	DownloadBytes     int64 `json:"download_bytes"`
+}
+
+// SyncPlan is the result of a dry run. It can be executed once, before it
+// expires.
+type SyncPlan struct {
+	ID           string       `json:"id" format:"uuid"`
+	ConnectionID string       `json:"connection_id" format:"uuid"`
+	RemotePath   string       `json:"remote_path" description:"The remote directory, resolved to an absolute path"`
+	Direction    string       `json:"direction"`
+	Criteria     string       `json:"criteria"`
+	Mirror       bool         `json:"mirror"`
+	Delete       bool         `json:"delete"`
+	Actions      []SyncAction `json:"actions"`
+	Summary      SyncSummary  `json:"summary"`
+	CreatedAt    time.Time    `json:"created_at"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	ExecutedAt   *time.Time   `json:"executed_at"`
+}
+
+type SyncPlanResponse struct {
+	Success bool      `json:"success"`
+	Plan    *SyncPlan `json:"plan"`
+}
+
+// SyncExecutionResponse lists the transfers started for a plan. Uploads
+// wait in staging for the client to send their data; local deletes and
+// directories are left to the client.
+type SyncExecutionResponse struct {
+	Success   bool       `json:"success"`
+	Plan      *SyncPlan  `json:"plan"`
+	Transfers []Transfer `json:"transfers"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import "time"
+
+const (
+	TransferUpload   = "upload"
+	TransferDownload = "download"
+	TransferDelete   = "delete"
+
+	TransferStaging   = "staging"
+	TransferQueued    = "queued"
+	TransferRunning   = "running"
+	TransferPaused    = "paused"
+	TransferCompleted = "completed"
+	TransferFailed    = "failed"
+	TransferCanceled  = "canceled"
+
+	TransferVerifiedByExec     = "exec"
+	TransferVerifiedByReadback = "readback"
+)
+
+// Transfer is a queued upload to or download from a connection. Uploads are
+// first staged on the server by the client, then sent on by a worker;
+// downloads are fetched by a worker and then read back by the client. Sync
+// plans also queue remote deletes as transfers. Checksums are hex digests,
+// set once the copy has been verified; an extracted archive only records
+// its own source digest.
+type Transfer struct {
+	ID                  string          `json:"id" format:"uuid"`
+	ConnectionID        string          `json:"connection_id" format:"uuid"`
+	Direction           string          `json:"direction" description:"upload, download or delete"`
+	RemotePath          string          `json:"remote_path"`
+	Overwrite           bool            `json:"overwrite"`
+	ModifiedAt          *time.Time      `json:"modified_at" description:"Modification time given to an uploaded file, or that of a downloaded one"`
+	SyncPlanID          *string         `json:"sync_plan_id,omitempty" format:"uuid"`
+	Status              string          `json:"status" description:"staging, queued, running, paused, completed, failed or canceled"`
+	Size                *int64          `json:"size" description:"Null for a download until the worker has looked at the remote file"`
+	StagedBytes         int64           `json:"staged_bytes" description:"Bytes the client has uploaded to the server, or can download from it"`
+	TransferredBytes    int64           `json:"transferred_bytes" description:"Bytes copied between the server and the remote host"`
+	Progress            float64         `json:"progress" description:"Share of the remote copy that is done, from 0 to 1"`
+	BandwidthLimit      *int64          `json:"bandwidth_limit" description:"Bytes per second, on top of the user's and the server's limits"`
+	Archive             *string         `json:"archive" description:"zip or tar.gz if a directory is downloaded as an archive, or an uploaded archive is extracted into remote_path"`
+	Symlinks            string          `json:"symlinks" description:"What extraction does with links: skip, reject or create"`
+	ArchiveEntries      int             `json:"archive_entries" description:"Entries of the archive packed or extracted so far"`
+	ChecksumAlgorithm   string          `json:"checksum_algorithm" description:"sha256, blake3 or xxh64"`
+	ExpectedChecksum    *string         `json:"expected_checksum"`
+	SourceChecksum      *string         `json:"source_checksum" description:"Digest of the data as it was read from the source"`
+	DestinationChecksum *string         `json:"destination_checksum" description:"Digest of the data as written to the destination"`
+	VerifiedBy          *string         `json:"verified_by" description:"exec if the remote host computed the digest, readback if the server read the file back"`
+	VerifiedAt          *time.Time      `json:"verified_at"`
+	Attempts            int             `json:"attempts"`
+	NextAttemptAt       *time.Time      `json:"next_attempt_at,omitempty" description:"When a failed attempt will be retried"`
+	LastError           *string         `json:"last_error"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	StartedAt           *time.Time      `json:"started_at"`
+	FinishedAt          *time.Time      `json:"finished_at"`
+	History             []TransferEvent `json:"history,omitempty"`
+}
+
+// TransferEvent records a change of a transfer's status.
+type TransferEvent struct {
+	Status    string    `json:"status"`
+	Detail    *string   `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TransferRequest struct {
+	ConnectionID      string     `json:"connection_id" binding:"required,uuid"`
+	Direction         string     `json:"direction" binding:"required,oneof=upload download"`
+	RemotePath        string     `json:"remote_path" binding:"required,max=4096" example:"/var/www/video.mp4"`
+	Size              *int64     `json:"size,omitempty" binding:"omitempty,min=0" description:"Required for uploads, and capped by the server"`
+	Overwrite         bool       `json:"overwrite,omitempty" description:"Replace an existing remote file when the upload completes"`
+	ModifiedAt        *time.Time `json:"modified_at,omitempty" description:"Modification time to give the uploaded file"`
+	ChecksumAlgorithm string     `json:"checksum_algorithm,omitempty" binding:"omitempty,oneof=sha256 blake3 xxh64" description:"Digest used to verify the copy (default sha256)"`
+	ExpectedChecksum  string     `json:"expected_checksum,omitempty" binding:"omitempty,max=64,hexadecimal" description:"Hex digest the source data must have. The transfer fails without retrying if it does not"`
+	BandwidthLimit    *int64     `json:"bandwidth_limit,omitempty" binding:"omitempty,min=1024" description:"Bytes per second"`
+	Archive           string     `json:"archive,omitempty" binding:"omitempty,oneof=zip tar.gz" description:"For a download, pack the remote directory into an archive of this format. For an upload, extract the uploaded archive into remote_path, a directory that is created if needed"`
+	Symlinks          string     `json:"symlinks,omitempty" binding:"omitempty,oneof=skip reject create" description:"For an extracted upload: leave links out (the default), fail the transfer if there are any, or create symbolic links that stay inside remote_path"`
+}
+
+type TransferFilter struct {
+	Status     string
+	SyncPlanID string
+	Limit      int
+	Offset     int
+}
+
+type TransferListQuery struct {
+	Status     string `form:"status" binding:"omitempty,oneof=staging queued running paused completed failed canceled"`
+	SyncPlanID string `form:"sync_plan_id" binding:"omitempty,uuid" description:"Only transfers started by this sync plan"`
+	Limit      int    `form:"limit" description:"Page size, clamped to 1-200 (default 50)"`
+	Offset     int    `form:"offset" description:"Number of transfers to skip (default 0)"`
+}
+
+type TransferDataQuery struct {
+	Offset int64 `form:"offset" binding:"required,min=0" description:"Must equal the transfer's staged_bytes"`
+}
+
+type TransferListResponse struct {
+	Success   bool       `json:"success"`
+	Transfers []Transfer `json:"transfers"`
+	Total     int        `json:"total"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+}
+
+type TransferResponse struct {
+	Success  bool      `json:"success"`
+	Transfer *Transfer `json:"transfer"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+const (
+	VaultItemPassword        = "password"
+	VaultItemPrivateKey      = "private_key"
+	VaultItemPassphrase      = "passphrase"
+	VaultItemSecretAccessKey = "secret_access_key"
+)
+
+// VaultKDF describes how clients derive the zero-knowledge vault key from the
+// user's password. The server stores it so every device derives the same key
+// but never sees the key itself.
+type VaultKDF struct {
+	Algorithm   string `json:"algorithm" binding:"required,oneof=argon2id"`
+	Salt        []byte `json:"salt" binding:"required" description:"16 to 64 random bytes"`
+	MemoryKiB   int    `json:"memory_kib" binding:"required,min=19456,max=4194304"`
+	Iterations  int    `json:"iterations" binding:"required,min=2,max=100"`
+	Parallelism int    `json:"parallelism" binding:"required,min=1,max=16"`
+	KeyCheck    []byte `json:"key_check,omitempty" description:"Optional ciphertext of a known value, so clients can tell a wrong password from corrupt items"`
+}
+
+type VaultSettings struct {
+	ZeroKnowledge    bool      `json:"zero_knowledge" description:"When true the server only accepts client-encrypted items"`
+	KDF              *VaultKDF `json:"kdf"`
+	ServerEncryption bool      `json:"server_encryption" description:"Whether this server has a master key and can encrypt items itself"`
+	Items            int       `json:"items"`
+}
+
+type VaultSettingsRequest struct {
+	ZeroKnowledge bool      `json:"zero_knowledge"`
+	KDF           *VaultKDF `json:"kdf,omitempty" description:"Required when enabling zero-knowledge mode; omit to keep the current parameters"`
+}
+
+type VaultSettingsResponse struct {
+	Success bool          `json:"success"`
+	Vault   VaultSettings `json:"vault"`
+}
+
+// VaultItem is one stored secret. Secret is set for server-encrypted items
+// when a single item is fetched; client-encrypted items carry Nonce and
+// Ciphertext instead.
+type VaultItem struct {
+	ID           string    `json:"id" format:"uuid"`
+	ConnectionID *string   `json:"connection_id,omitempty" format:"uuid"`
+	Kind         string    `json:"kind"`
+	Label        string    `json:"label"`
+	Encryption   string    `json:"encryption"`
+	Secret       string    `json:"secret,omitempty"`
+	Nonce        []byte    `json:"nonce,omitempty"`
+	Ciphertext   []byte    `json:"ciphertext,omitempty"`
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type VaultItemInput struct {
+	ID           string `json:"id,omitempty" binding:"omitempty,uuid" description:"Client-chosen ID, required on create for client encryption because it is part of the associated data"`
+	ConnectionID string `json:"connection_id,omitempty" binding:"omitempty,uuid"`
+	Kind         string `json:"kind" binding:"required,oneof=password private_key passphrase secret_access_key"`
+	Label        string `json:"label,omitempty" binding:"max=100"`
+	Encryption   string `json:"encryption" binding:"required,oneof=server client"`
+	Secret       string `json:"secret,omitempty" binding:"max=16384" description:"Plaintext secret for server encryption"`
+	Nonce        []byte `json:"nonce,omitempty" description:"12-byte AES-GCM nonce for client encryption"`
+	Ciphertext   []byte `json:"ciphertext,omitempty" description:"AES-256-GCM ciphertext and tag for client encryption"`
+	Version      int    `json:"version,omitempty" binding:"omitempty,min=1" description:"Required on update: the version the change was made against"`
+}
+
+type VaultItemListQuery struct {
+	ConnectionID string `form:"connection_id" binding:"omitempty,uuid"`
+}
+
+type VaultItemDeleteQuery struct {
+	Version int `form:"version" binding:"omitempty,min=1" description:"Only delete if the item is still at this version"`
+}
+
+type VaultItemListResponse struct {
+	Success bool        `json:"success"`
+	Items   []VaultItem `json:"items"`
+}
+
+type VaultItemResponse struct {
+	Success bool       `json:"success"`
+	Item    *VaultItem `json:"item"`
+}
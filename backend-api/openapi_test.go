@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const openAPISpecPath = "api/openapi.json"
+
+func TestOpenAPISpec_DocumentsEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	middleware.Logger = zap.NewNop()
+
+	router, apiSpec := buildRouter(&Config{}, nil, nil)
+	document := apiSpec.Document()
+
+	for _, route := range router.Routes() {
+		path := route.Path
+		for _, segment := range strings.Split(path, "/") {
+			if strings.HasPrefix(segment, ":") {
+				path = strings.Replace(path, segment, "{"+segment[1:]+"}", 1)
+			}
+		}
+
+		if document.Paths[path][strings.ToLower(route.Method)] == nil {
+			t.Errorf("Expected %s %s to be documented in routes.APIOperations", route.Method, route.Path)
+		}
+	}
+}
+
+func TestOpenAPISpec_MatchesCommittedFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	middleware.Logger = zap.NewNop()
+
+	_, apiSpec := buildRouter(&Config{}, nil, nil)
+
+	if os.Getenv("UPDATE_OPENAPI") == "true" {
+		if err := os.WriteFile(openAPISpecPath, apiSpec.JSON(), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", openAPISpecPath, err)
+		}
+		return
+	}
+
+	committed, err := os.ReadFile(openAPISpecPath)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", openAPISpecPath, err)
+	}
+
+	if !bytes.Equal(committed, apiSpec.JSON()) {
+		t.Errorf("%s is out of date; regenerate it with UPDATE_OPENAPI=true go test -run TestOpenAPISpec .", openAPISpecPath)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"livecode-api/database"
 	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
 	"livecode-api/middleware"
 	"livecode-api/models"
 
@@ -23,7 +24,7 @@ func CheckFieldAvailable(c *gin.Context) {
 			zap.String("field", field.(string)),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"available": nil})
+		apierror.Write(c, apierror.Internal().WithLegacy(http.StatusInternalServerError, gin.H{"available": nil}))
 		return
 	}
 
@@ -32,31 +33,24 @@ func CheckFieldAvailable(c *gin.Context) {
 		zap.Bool("available", *available),
 	)
 
-	c.JSON(http.StatusOK, gin.H{"available": available})
+	c.JSON(http.StatusOK, models.CheckFieldResponse{Available: available})
 }
 
 func Register(c *gin.Context) {
 	validatedPayload, exists := c.Get("validated_payload")
 	if !exists {
 		middleware.GetLogger(c).Error("register_validation_missing")
-		c.JSON(http.StatusInternalServerError, models.RegisterResponse{
-			Success: false,
-			Message: "Validation error occurred.",
-		})
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred.").
+			WithLegacy(http.StatusInternalServerError, models.RegisterResponse{
+				Success:   false,
+				Message:   apierror.LocalizedMessage(c, apierror.CodeInternal, "Validation error occurred."),
+				Code:      string(apierror.CodeInternal),
+				RequestID: middleware.GetRequestID(c),
+			}))
 		return
 	}
 
-	payload := validatedPayload.(struct {
-		Email    string `json:"email"`
-		Username string `json:"username"`
-		Password string `json:"password"`
-	})
-
-	req := models.RegisterRequest{
-		Email:    payload.Email,
-		Username: payload.Username,
-		Password: payload.Password,
-	}
+	req := validatedPayload.(models.RegisterRequest)
 
 	response, err := handlers.RegisterUserInternal(req, database.DB)
 
@@ -66,10 +60,12 @@ func Register(c *gin.Context) {
 			zap.String("email", req.Email),
 			zap.String("username", req.Username),
 		)
-		c.JSON(http.StatusInternalServerError, models.RegisterResponse{
-			Success: false,
-			Message: "An unexpected error occurred. Please try again.",
-		})
+		apierror.Write(c, apierror.Internal().WithLegacy(http.StatusInternalServerError, models.RegisterResponse{
+			Success:   false,
+			Message:   apierror.LocalizedMessage(c, apierror.CodeInternal, "An unexpected error occurred. Please try again."),
+			Code:      string(apierror.CodeInternal),
+			RequestID: middleware.GetRequestID(c),
+		}))
 		return
 	}
 
@@ -79,7 +75,12 @@ func Register(c *gin.Context) {
 			zap.String("username", req.Username),
 			zap.Int("field_errors_count", len(response.FieldErrors)),
 		)
-		c.JSON(http.StatusOK, response)
+		response.RequestID = middleware.GetRequestID(c)
+		response.Message = apierror.LocalizedMessage(c, apierror.CodeAccountConflict, response.Message)
+		response.FieldErrors = apierror.LocalizedFields(c, response.FieldErrors)
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeAccountConflict, response.Message).
+			WithFields(response.FieldErrors...).
+			WithLegacy(http.StatusOK, response))
 		return
 	}
 
@@ -95,22 +96,17 @@ func Login(c *gin.Context) {
 	validatedPayload, exists := c.Get("validated_payload")
 	if !exists {
 		middleware.GetLogger(c).Error("login_validation_missing")
-		c.JSON(http.StatusInternalServerError, models.LoginResponse{
-			Success: false,
-			Message: "Validation error occurred.",
-		})
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred.").
+			WithLegacy(http.StatusInternalServerError, models.LoginResponse{
+				Success:   false,
+				Message:   apierror.LocalizedMessage(c, apierror.CodeInternal, "Validation error occurred."),
+				Code:      string(apierror.CodeInternal),
+				RequestID: middleware.GetRequestID(c),
+			}))
 		return
 	}
 
-	payload := validatedPayload.(struct {
-		Identifier string `json:"identifier"`
-		Password   string `json:"password"`
-	})
-
-	req := models.LoginRequest{
-		Identifier: payload.Identifier,
-		Password:   payload.Password,
-	}
+	req := validatedPayload.(models.LoginRequest)
 
 	response, err := handlers.LoginUserInternal(req, database.DB)
 
@@ -119,10 +115,12 @@ func Login(c *gin.Context) {
 			zap.String("error", err.Error()),
 			zap.String("identifier", req.Identifier),
 		)
-		c.JSON(http.StatusInternalServerError, models.LoginResponse{
-			Success: false,
-			Message: "An unexpected error occurred. Please try again.",
-		})
+		apierror.Write(c, apierror.Internal().WithLegacy(http.StatusInternalServerError, models.LoginResponse{
+			Success:   false,
+			Message:   apierror.LocalizedMessage(c, apierror.CodeInternal, "An unexpected error occurred. Please try again."),
+			Code:      string(apierror.CodeInternal),
+			RequestID: middleware.GetRequestID(c),
+		}))
 		return
 	}
 
@@ -130,7 +128,10 @@ func Login(c *gin.Context) {
 		middleware.GetLogger(c).Warn("login_invalid_credentials",
 			zap.String("identifier", req.Identifier),
 		)
-		c.JSON(http.StatusUnauthorized, response)
+		response.RequestID = middleware.GetRequestID(c)
+		response.Message = apierror.LocalizedMessage(c, apierror.Code(response.Code), response.Message)
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.Code(response.Code), response.Message).
+			WithLegacy(http.StatusUnauthorized, response))
 		return
 	}
 
@@ -145,16 +146,11 @@ func Login(c *gin.Context) {
 func RefreshToken(c *gin.Context) {
 	validatedPayload, exists := c.Get("validated_payload")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Validation error occurred.",
-		})
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
 		return
 	}
 
-	payload := validatedPayload.(struct {
-		RefreshToken string `json:"refresh_token"`
-	})
+	payload := validatedPayload.(models.RefreshTokenRequest)
 
 	response, err := handlers.RefreshTokenInternal(payload.RefreshToken, database.DB)
 
@@ -163,23 +159,21 @@ func RefreshToken(c *gin.Context) {
 			zap.String("error", err.Error()),
 		)
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "An unexpected error occurred. Please try again.",
-		})
+		apierror.Write(c, apierror.Internal())
 		return
 	}
 
 	if !response.Success {
 		middleware.GetLogger(c).Warn("refresh_token_invalid",
 			zap.String("message", response.Message),
+			zap.String("code", response.Code),
 		)
+		response.RequestID = middleware.GetRequestID(c)
+		response.Message = apierror.LocalizedMessage(c, apierror.Code(response.Code), response.Message)
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.Code(response.Code), response.Message).
+			WithLegacy(http.StatusUnauthorized, response))
+		return
 	}
 
-	statusCode := http.StatusOK
-	if !response.Success {
-		statusCode = http.StatusUnauthorized
-	}
-
-	c.JSON(statusCode, response)
+	c.JSON(http.StatusOK, response)
 }
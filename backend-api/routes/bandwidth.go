@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"net/http"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func GetBandwidthSettings(c *gin.Context) {
+	getBandwidthSettings(c, c.GetString("user_id"))
+}
+
+func UpdateBandwidthSettings(c *gin.Context) {
+	updateBandwidthSettings(c, c.GetString("user_id"))
+}
+
+// GetServerBandwidthSettings returns the limits shared by all transfers on
+// each server.
+func GetServerBandwidthSettings(c *gin.Context) {
+	getBandwidthSettings(c, "")
+}
+
+func UpdateServerBandwidthSettings(c *gin.Context) {
+	updateBandwidthSettings(c, "")
+}
+
+func getBandwidthSettings(c *gin.Context, userID string) {
+	settings, current, err := handlers.GetBandwidthSettingsInternal(userID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("bandwidth_settings_lookup_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BandwidthResponse{
+		Success:      true,
+		Settings:     settings,
+		CurrentLimit: current,
+	})
+}
+
+func updateBandwidthSettings(c *gin.Context, userID string) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("bandwidth_settings_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.BandwidthSettings)
+
+	settings, current, err := handlers.UpdateBandwidthSettingsInternal(c.Request.Context(), userID, req, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("bandwidth_settings_update_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	middleware.GetLogger(c).Info("bandwidth_settings_updated",
+		zap.String("user_id", userID),
+		zap.Bool("server", userID == ""),
+		zap.Int("windows", len(settings.Windows)),
+	)
+
+	c.JSON(http.StatusOK, models.BandwidthResponse{
+		Success:      true,
+		Settings:     settings,
+		CurrentLimit: current,
+	})
+}
+
+// SetTransferBandwidth changes a transfer's own limit. A transfer running
+// on another server switches to it after the chunk in flight.
+func SetTransferBandwidth(c *gin.Context) {
+	transferID := c.Param("id")
+	if _, err := uuid.Parse(transferID); err != nil {
+		transferNotFound(c)
+		return
+	}
+
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("transfer_bandwidth_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.TransferBandwidthRequest)
+
+	transfer, err := handlers.SetTransferBandwidthInternal(c.GetString("user_id"), transferID, req.Limit, database.DB)
+	if err != nil {
+		writeTransferError(c, "transfer_bandwidth_update_failed", transferID, err)
+		return
+	}
+	if transfer == nil {
+		transferNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TransferResponse{
+		Success:  true,
+		Transfer: transfer,
+	})
+}
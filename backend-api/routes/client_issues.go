@@ -0,0 +1,146 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var clientIssueStatuses = []string{
+	models.ClientIssueStatusOpen, models.ClientIssueStatusResolved,
+	models.ClientIssueStatusIgnored, models.ClientIssueStatusRegressed,
+}
+
+func ListClientIssues(c *gin.Context) {
+	filter := models.ClientIssueFilter{
+		Status:     c.Query("status"),
+		AppVersion: c.Query("app_version"),
+		Limit:      queryInt(c, "limit", 50, 1, 200),
+		Offset:     queryInt(c, "offset", 0, 0, 1000000),
+	}
+
+	if filter.Status != "" && !isClientIssueStatus(filter.Status) {
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid status filter.").
+			WithFields(apierror.Field("status", apierror.FieldNotAllowed, map[string]any{"allowed": clientIssueStatuses})))
+		return
+	}
+
+	issues, total, err := handlers.ListClientIssuesInternal(filter, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("client_issues_list_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ClientIssueListResponse{
+		Success: true,
+		Issues:  issues,
+		Total:   total,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+	})
+}
+
+func GetClientIssue(c *gin.Context) {
+	issueID := c.Param("id")
+	if _, err := uuid.Parse(issueID); err != nil {
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Issue not found."))
+		return
+	}
+
+	issue, err := handlers.GetClientIssueInternal(issueID, queryInt(c, "reports", 20, 0, 100), database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("client_issue_lookup_failed",
+			zap.String("issue_id", issueID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if issue == nil {
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Issue not found."))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ClientIssueResponse{
+		Success: true,
+		Issue:   issue,
+	})
+}
+
+func UpdateClientIssueStatus(c *gin.Context) {
+	issueID := c.Param("id")
+	if _, err := uuid.Parse(issueID); err != nil {
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Issue not found."))
+		return
+	}
+
+	var req models.ClientIssueStatusUpdate
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Status must be one of open, resolved, ignored or regressed.").
+			WithFields(apierror.Field("status", apierror.FieldNotAllowed, map[string]any{"allowed": clientIssueStatuses})))
+		return
+	}
+
+	issue, err := handlers.UpdateClientIssueStatusInternal(issueID, req.Status, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("client_issue_status_update_failed",
+			zap.String("issue_id", issueID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if issue == nil {
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Issue not found."))
+		return
+	}
+
+	middleware.GetLogger(c).Info("client_issue_status_updated",
+		zap.String("issue_id", issueID),
+		zap.String("status", issue.Status),
+	)
+
+	c.JSON(http.StatusOK, models.ClientIssueResponse{
+		Success: true,
+		Issue:   issue,
+	})
+}
+
+func isClientIssueStatus(status string) bool {
+	switch status {
+	case models.ClientIssueStatusOpen, models.ClientIssueStatusResolved,
+		models.ClientIssueStatusIgnored, models.ClientIssueStatusRegressed:
+		return true
+	}
+	return false
+}
+
+func queryInt(c *gin.Context, key string, fallback, min, max int) int {
+	value, err := strconv.Atoi(c.Query(key))
+	if err != nil {
+		return fallback
+	}
+
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
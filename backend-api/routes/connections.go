@@ -0,0 +1,218 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func ListConnections(c *gin.Context) {
+	userID := c.GetString("user_id")
+	filter := models.ConnectionFilter{
+		Folder: c.Query("folder"),
+		Tag:    c.Query("tag"),
+	}
+
+	connections, err := handlers.ListConnectionsInternal(userID, filter, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("connections_list_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ConnectionListResponse{
+		Success:     true,
+		Connections: connections,
+	})
+}
+
+func GetConnection(c *gin.Context) {
+	userID := c.GetString("user_id")
+	connectionID := c.Param("id")
+	if _, err := uuid.Parse(connectionID); err != nil {
+		connectionNotFound(c)
+		return
+	}
+
+	connection, err := handlers.GetConnectionInternal(userID, connectionID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("connection_lookup_failed",
+			zap.String("connection_id", connectionID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if connection == nil {
+		connectionNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ConnectionResponse{
+		Success:    true,
+		Connection: connection,
+	})
+}
+
+func CreateConnection(c *gin.Context) {
+	input, ok := validatedConnection(c)
+	if !ok {
+		return
+	}
+	userID := c.GetString("user_id")
+
+	connection, err := handlers.CreateConnectionInternal(userID, input, database.DB)
+	if err != nil {
+		writeConnectionError(c, "connection_create_failed", "", err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("connection_created",
+		zap.String("user_id", userID),
+		zap.String("connection_id", connection.ID),
+		zap.String("protocol", connection.Protocol),
+	)
+
+	c.JSON(http.StatusCreated, models.ConnectionResponse{
+		Success:    true,
+		Connection: connection,
+	})
+}
+
+func UpdateConnection(c *gin.Context) {
+	connectionID := c.Param("id")
+	if _, err := uuid.Parse(connectionID); err != nil {
+		connectionNotFound(c)
+		return
+	}
+
+	input, ok := validatedConnection(c)
+	if !ok {
+		return
+	}
+	if input.Version == 0 {
+		apierror.Write(c, apierror.Validation(apierror.Field("version", apierror.FieldRequired, nil)))
+		return
+	}
+	userID := c.GetString("user_id")
+
+	connection, err := handlers.UpdateConnectionInternal(userID, connectionID, input, database.DB)
+	if err != nil {
+		writeConnectionError(c, "connection_update_failed", connectionID, err)
+		return
+	}
+
+	if connection == nil {
+		connectionNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("connection_updated",
+		zap.String("user_id", userID),
+		zap.String("connection_id", connectionID),
+		zap.Int("version", connection.Version),
+	)
+
+	c.JSON(http.StatusOK, models.ConnectionResponse{
+		Success:    true,
+		Connection: connection,
+	})
+}
+
+func DeleteConnection(c *gin.Context) {
+	connectionID := c.Param("id")
+	if _, err := uuid.Parse(connectionID); err != nil {
+		connectionNotFound(c)
+		return
+	}
+	userID := c.GetString("user_id")
+
+	deleted, err := handlers.DeleteConnectionInternal(userID, connectionID, queryInt(c, "version", 0, 0, 1<<31-1), database.DB)
+	if err != nil {
+		writeConnectionError(c, "connection_delete_failed", connectionID, err)
+		return
+	}
+
+	if !deleted {
+		connectionNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("connection_deleted",
+		zap.String("user_id", userID),
+		zap.String("connection_id", connectionID),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func SyncConnections(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var since int64
+	if value := c.Query("since"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			apierror.Write(c, apierror.Validation(apierror.Field("since", apierror.FieldInvalidFormat, nil)))
+			return
+		}
+		since = parsed
+	}
+
+	response, err := handlers.SyncConnectionsInternal(userID, since, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("connections_sync_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func validatedConnection(c *gin.Context) (models.ConnectionInput, bool) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("connection_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return models.ConnectionInput{}, false
+	}
+	return validatedPayload.(models.ConnectionInput), true
+}
+
+func connectionNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Connection not found."))
+}
+
+func writeConnectionError(c *gin.Context, event, connectionID string, err error) {
+	switch {
+	case errors.Is(err, handlers.ErrConnectionNameTaken):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeConnectionConflict, "The connection could not be saved.").
+			WithFields(apierror.Field("name", apierror.FieldConnectionNameTaken, nil)))
+	case errors.Is(err, handlers.ErrConnectionVersionConflict):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeVersionConflict,
+			"This connection was changed on another device. Reload it and try again."))
+	default:
+		middleware.GetLogger(c).Error(event,
+			zap.String("connection_id", connectionID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+	}
+}
@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/events"
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StreamEvents sends the user's events as Server-Sent Events, starting
+// with any missed since Last-Event-ID. Comments are sent every heartbeat so
+// proxies keep the connection open. The stream ends when the access token
+// expires; the client reconnects with a fresh one and its last event ID.
+func StreamEvents(hub *events.Hub, heartbeat time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		sub, replay, err := hub.Subscribe(userID, c.GetHeader("Last-Event-ID"))
+		switch {
+		case errors.Is(err, events.ErrTooManySubscribers):
+			apierror.Write(c, apierror.New(http.StatusTooManyRequests, apierror.CodeEventsTooManyStreams,
+				"Too many event streams are open. Close one in another window first."))
+			return
+		case err != nil:
+			apierror.Write(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeServiceUnavailable,
+				"The server is shutting down. Try again shortly."))
+			return
+		}
+		defer sub.Close()
+
+		extendWriteDeadline(c)
+
+		var expired <-chan time.Time
+		if expiresAt := c.GetTime("token_expires_at"); !expiresAt.IsZero() {
+			timer := time.NewTimer(time.Until(expiresAt))
+			defer timer.Stop()
+			expired = timer.C
+		}
+		ping := time.NewTicker(heartbeat)
+		defer ping.Stop()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		fmt.Fprintf(c.Writer, "retry: 5000\n\n")
+		for _, event := range replay {
+			writeEvent(c, event)
+		}
+		c.Writer.Flush()
+
+		middleware.GetLogger(c).Debug("event_stream_opened",
+			zap.Int("replayed", len(replay)),
+		)
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-expired:
+				return
+			case <-ping.C:
+				fmt.Fprintf(c.Writer, ": ping\n\n")
+			case event, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				writeEvent(c, event)
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeEvent(c *gin.Context, event events.Event) {
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
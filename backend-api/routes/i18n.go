@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/i18n"
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func GetMessageCatalog(c *gin.Context) {
+	catalog := i18n.Default()
+
+	locale := c.GetString(i18n.ContextKey)
+	if requested := c.Query("locale"); requested != "" {
+		locale = catalog.Negotiate(requested)
+	}
+	if locale == "" {
+		locale = i18n.DefaultLocale
+	}
+
+	body, err := json.Marshal(i18n.CatalogResponse{
+		Success:  true,
+		Locale:   locale,
+		Locales:  catalog.Locales(),
+		Messages: catalog.Messages(locale),
+	})
+	if err != nil {
+		middleware.GetLogger(c).Error("message_catalog_encode_failed",
+			zap.String("locale", locale),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("Content-Language", locale)
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
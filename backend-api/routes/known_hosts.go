@@ -0,0 +1,400 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var knownHostEvents = []string{
+	models.KnownHostEventKeyChanged, models.KnownHostEventKeyReplaced,
+	models.KnownHostEventPinAdded, models.KnownHostEventPinReplaced, models.KnownHostEventPinRemoved,
+}
+
+func ListKnownHosts(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	hosts, err := handlers.ListKnownHostsInternal(userID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("known_hosts_list_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.KnownHostListResponse{
+		Success: true,
+		Hosts:   hosts,
+	})
+}
+
+// VerifyKnownHost checks the key a host presented. A changed key is
+// reported with status "changed" rather than an error, so clients can show
+// both fingerprints before refusing the connection.
+func VerifyKnownHost(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("known_host_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	key := validatedPayload.(models.KnownHostKey)
+	userID := c.GetString("user_id")
+
+	verification, err := handlers.VerifyKnownHostInternal(userID, key, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("known_host_verify_failed",
+			zap.String("user_id", userID),
+			zap.String("host", key.Address),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if verification.Status == models.KnownHostChanged {
+		middleware.GetLogger(c).Warn("known_host_key_changed",
+			zap.String("user_id", userID),
+			zap.String("host", key.Address),
+			zap.String("presented", verification.Fingerprint),
+			zap.Bool("pinned", verification.Pinned),
+			zap.String("client_ip", key.ClientIP),
+		)
+	}
+
+	c.JSON(http.StatusOK, models.KnownHostVerifyResponse{
+		Success:      true,
+		Verification: *verification,
+	})
+}
+
+func TrustKnownHost(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("known_host_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	key := validatedPayload.(models.KnownHostKey)
+	userID := c.GetString("user_id")
+
+	host, replaced, err := handlers.TrustKnownHostInternal(userID, key, database.DB)
+	switch {
+	case errors.Is(err, handlers.ErrKnownHostChanged):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeKnownHostChanged,
+			"The host key differs from the one you trust.").
+			WithFields(apierror.Field("replace", apierror.FieldRequired, nil)))
+		return
+	case errors.Is(err, handlers.ErrKnownHostPinned):
+		middleware.GetLogger(c).Warn("known_host_pin_mismatch",
+			zap.String("user_id", userID),
+			zap.String("host", key.Address),
+			zap.String("client_ip", key.ClientIP),
+		)
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeKnownHostPinned,
+			"Your team has pinned a different key for this host."))
+		return
+	case err != nil:
+		middleware.GetLogger(c).Error("known_host_trust_failed",
+			zap.String("user_id", userID),
+			zap.String("host", key.Address),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if replaced {
+		middleware.GetLogger(c).Warn("known_host_key_replaced",
+			zap.String("user_id", userID),
+			zap.String("host", host.Host),
+			zap.String("fingerprint", host.FingerprintSHA256),
+			zap.String("client_ip", key.ClientIP),
+		)
+	} else {
+		middleware.GetLogger(c).Info("known_host_trusted",
+			zap.String("user_id", userID),
+			zap.String("host", host.Host),
+			zap.String("fingerprint", host.FingerprintSHA256),
+		)
+	}
+
+	c.JSON(http.StatusOK, models.KnownHostResponse{
+		Success: true,
+		Host:    host,
+	})
+}
+
+func DeleteKnownHost(c *gin.Context) {
+	hostID := c.Param("id")
+	if _, err := uuid.Parse(hostID); err != nil {
+		knownHostNotFound(c)
+		return
+	}
+	userID := c.GetString("user_id")
+
+	deleted, err := handlers.DeleteKnownHostInternal(userID, hostID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("known_host_delete_failed",
+			zap.String("host_id", hostID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if !deleted {
+		knownHostNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("known_host_deleted",
+		zap.String("user_id", userID),
+		zap.String("host_id", hostID),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func ImportKnownHosts(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("known_hosts_import_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	req := validatedPayload.(models.KnownHostsImportRequest)
+	userID := c.GetString("user_id")
+	audit := models.KnownHostKey{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+
+	result, err := handlers.ImportKnownHostsInternal(userID, req.KnownHosts, audit, database.DB)
+	if errors.Is(err, sshkeys.ErrInvalidKnownHosts) {
+		apierror.Write(c, apierror.Validation(apierror.Field("known_hosts", apierror.FieldInvalidFormat,
+			map[string]any{"reason": err.Error()})))
+		return
+	}
+	if err != nil {
+		middleware.GetLogger(c).Error("known_hosts_import_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	logger := middleware.GetLogger(c)
+	if len(result.Changed) > 0 {
+		logger.Warn("known_hosts_import_key_changed",
+			zap.String("user_id", userID),
+			zap.Strings("hosts", result.Changed),
+			zap.String("client_ip", audit.ClientIP),
+		)
+	}
+	logger.Info("known_hosts_imported",
+		zap.String("user_id", userID),
+		zap.Int("imported", result.Imported),
+		zap.Int("unchanged", result.Unchanged),
+		zap.Int("skipped", result.Skipped),
+		zap.Int("changed", len(result.Changed)),
+	)
+
+	c.JSON(http.StatusOK, models.KnownHostsImportResponse{
+		Success: true,
+		Result:  *result,
+	})
+}
+
+// ExportKnownHosts serves the user's keys and team pins as a known_hosts
+// file.
+func ExportKnownHosts(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	hosts, err := handlers.ExportKnownHostsInternal(userID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("known_hosts_export_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", `attachment; filename="known_hosts"`)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", sshkeys.MarshalKnownHosts(hosts, c.Query("hash") == "true"))
+}
+
+func ListTeamKnownHosts(c *gin.Context) {
+	teamID := c.Param("id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+
+	hosts, err := handlers.ListTeamKnownHostsInternal(teamID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("team_known_hosts_list_failed",
+			zap.String("team_id", teamID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if hosts == nil {
+		teamNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.KnownHostListResponse{
+		Success: true,
+		Hosts:   hosts,
+	})
+}
+
+func PinKnownHost(c *gin.Context) {
+	teamID := c.Param("id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("known_host_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	key := validatedPayload.(models.KnownHostKey)
+	adminID := c.GetString("user_id")
+
+	host, err := handlers.PinKnownHostInternal(teamID, adminID, key, database.DB)
+	if errors.Is(err, handlers.ErrTeamNotFound) {
+		teamNotFound(c)
+		return
+	}
+	if err != nil {
+		middleware.GetLogger(c).Error("known_host_pin_failed",
+			zap.String("team_id", teamID),
+			zap.String("host", key.Address),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	middleware.GetLogger(c).Info("known_host_pinned",
+		zap.String("team_id", teamID),
+		zap.String("host", host.Host),
+		zap.String("fingerprint", host.FingerprintSHA256),
+		zap.String("pinned_by", adminID),
+	)
+
+	c.JSON(http.StatusOK, models.KnownHostResponse{
+		Success: true,
+		Host:    host,
+	})
+}
+
+func UnpinKnownHost(c *gin.Context) {
+	teamID, hostID := c.Param("id"), c.Param("host_id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		knownHostNotFound(c)
+		return
+	}
+	if _, err := uuid.Parse(hostID); err != nil {
+		knownHostNotFound(c)
+		return
+	}
+
+	adminID := c.GetString("user_id")
+	audit := models.KnownHostKey{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+
+	removed, err := handlers.UnpinKnownHostInternal(teamID, hostID, adminID, audit, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("known_host_unpin_failed",
+			zap.String("team_id", teamID),
+			zap.String("host_id", hostID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if !removed {
+		knownHostNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("known_host_unpinned",
+		zap.String("team_id", teamID),
+		zap.String("host_id", hostID),
+		zap.String("unpinned_by", adminID),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func ListKnownHostEvents(c *gin.Context) {
+	filter := models.KnownHostEventFilter{
+		UserID: c.Query("user_id"),
+		TeamID: c.Query("team_id"),
+		Event:  c.Query("event"),
+		Limit:  queryInt(c, "limit", 50, 1, 200),
+		Offset: queryInt(c, "offset", 0, 0, 1000000),
+	}
+
+	var fields []models.FieldError
+	if _, err := uuid.Parse(filter.UserID); filter.UserID != "" && err != nil {
+		fields = append(fields, apierror.Field("user_id", apierror.FieldInvalidFormat, nil))
+	}
+	if _, err := uuid.Parse(filter.TeamID); filter.TeamID != "" && err != nil {
+		fields = append(fields, apierror.Field("team_id", apierror.FieldInvalidFormat, nil))
+	}
+	if filter.Event != "" && !slices.Contains(knownHostEvents, filter.Event) {
+		fields = append(fields, apierror.Field("event", apierror.FieldNotAllowed, map[string]any{"allowed": knownHostEvents}))
+	}
+	if len(fields) > 0 {
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid event filter.").
+			WithFields(fields...))
+		return
+	}
+
+	events, total, err := handlers.ListKnownHostEventsInternal(filter, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("known_host_events_list_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.KnownHostEventListResponse{
+		Success: true,
+		Events:  events,
+		Total:   total,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+	})
+}
+
+func knownHostNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Known host not found."))
+}
@@ -1,6 +1,9 @@
 package routes
 
 import (
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
 	"livecode-api/middleware"
 	"livecode-api/models"
 	"net/http"
@@ -12,19 +15,41 @@ import (
 func LogClientError(c *gin.Context) {
 	validatedPayload, exists := c.Get("validated_payload")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false})
+		apierror.Write(c, apierror.Internal().WithLegacy(http.StatusInternalServerError, gin.H{"success": false}))
 		return
 	}
 
 	payload := validatedPayload.(models.ClientErrorLog)
 
-	middleware.GetLogger(c).Error("client_critical_error",
+	logFields := []zap.Field{
 		zap.String("error_type", payload.ErrorType),
 		zap.String("error_message", payload.ErrorMessage),
 		zap.String("app_version", payload.AppVersion),
 		zap.String("os", payload.OS),
-		zap.String("timestamp_client", payload.Timestamp),
+		zap.Time("timestamp_client", payload.Timestamp),
+		zap.String("client_request_id", payload.RequestID),
+	}
+
+	receipt, err := handlers.StoreClientErrorInternal(payload, c.GetString("user_id"), database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("client_critical_error", logFields...)
+		middleware.GetLogger(c).Error("client_error_store_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal().WithLegacy(http.StatusInternalServerError, gin.H{"success": false}))
+		return
+	}
+
+	logFields = append(logFields,
+		zap.String("issue_id", receipt.Issue.ID),
+		zap.String("report_id", receipt.ReportID),
+		zap.Bool("new_issue", receipt.NewIssue),
 	)
+	middleware.GetLogger(c).Error("client_critical_error", logFields...)
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, models.ClientErrorAccepted{
+		Success:  true,
+		ReportID: receipt.ReportID,
+		IssueID:  receipt.Issue.ID,
+	})
 }
@@ -0,0 +1,1732 @@
+package routes
+
+import (
+	"net/http"
+
+	"livecode-api/internal/i18n"
+	"livecode-api/internal/openapi"
+	"livecode-api/internal/pow"
+	"livecode-api/internal/telemetry"
+	"livecode-api/models"
+)
+
+var (
+	errorResponse           = models.ErrorResponse{}
+	validationErrorResponse = models.ValidationErrorResponse{}
+
+	powHeaders = []openapi.Parameter{
+		{Name: pow.HeaderChallenge, Description: "Challenge from GET /api/v1/auth/challenge; required when the route enforces proof of work"},
+		{Name: pow.HeaderNonce, Description: "Nonce solving the challenge"},
+	}
+)
+
+func APIOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/metrics",
+			OperationID: "getMetrics",
+			Summary:     "Prometheus metrics in the text exposition format",
+			Tags:        []string{"Operations"},
+			Responses:   map[int]any{http.StatusOK: nil},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/health",
+			OperationID: "getHealth",
+			Summary:     "Database connectivity and migration status",
+			Tags:        []string{"Operations"},
+			Responses: map[int]any{
+				http.StatusOK:                 models.HealthStatus{},
+				http.StatusServiceUnavailable: models.HealthStatus{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/openapi.json",
+			OperationID: "getOpenAPISpec",
+			Summary:     "This OpenAPI document",
+			Tags:        []string{"Operations"},
+			Responses:   map[int]any{http.StatusOK: nil},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/i18n/catalog",
+			OperationID: "getMessageCatalog",
+			Summary:     "Localised messages keyed by error code",
+			Description: "Field error messages use {name} placeholders filled from the error's params and {field} from the matching field.<name> entry. Plural entries name the numeric param that selects the CLDR form.",
+			Tags:        []string{"Localization"},
+			Headers:     []openapi.Parameter{{Name: "Accept-Language", Description: "Used when the locale query parameter is absent"}},
+			Query:       i18n.CatalogQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  i18n.CatalogResponse{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/auth/refresh",
+			OperationID: "refreshToken",
+			Summary:     "Exchange a refresh token for a new token pair",
+			Description: "Refresh tokens are single use. Presenting a rotated token revokes every token issued from the same sign-in.",
+			Tags:        []string{"Auth"},
+			Request:     models.RefreshTokenRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.RefreshTokenResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        models.RefreshTokenResponse{},
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/auth/challenge",
+			OperationID: "getProofOfWorkChallenge",
+			Summary:     "Issue a proof-of-work challenge for a route",
+			Description: "Challenges are single use, bound to the requesting IP and the route scope, and get harder while the client or the service is being rate limited.",
+			Tags:        []string{"Auth"},
+			Query:       pow.ChallengeQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  pow.ChallengeResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/auth/register",
+			OperationID: "register",
+			Summary:     "Create an account",
+			Description: "Duplicate emails or usernames are reported in field_errors with a 200 status, or as a 409 problem for clients accepting application/vnd.livecode.v2+json.",
+			Tags:        []string{"Auth"},
+			Headers:     powHeaders,
+			Request:     models.RegisterRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:              models.RegisterResponse{},
+				http.StatusOK:                   models.RegisterResponse{},
+				http.StatusBadRequest:           validationErrorResponse,
+				http.StatusForbidden:            errorResponse,
+				http.StatusConflict:             nil,
+				http.StatusPreconditionRequired: errorResponse,
+				http.StatusTooManyRequests:      errorResponse,
+				http.StatusInternalServerError:  models.RegisterResponse{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/auth/login",
+			OperationID: "login",
+			Summary:     "Sign in with an email or username",
+			Tags:        []string{"Auth"},
+			Request:     models.LoginRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.LoginResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        models.LoginResponse{},
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: models.LoginResponse{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/auth/password/strength",
+			OperationID: "checkPasswordStrength",
+			Summary:     "Score a candidate password against the password policy",
+			Description: "Runs the same checks as registration: length, character classes, personal information, estimated guessability and, when configured, the offline breached-password dataset.",
+			Tags:        []string{"Auth"},
+			Request:     models.PasswordStrengthRequest{},
+			Responses: map[int]any{
+				http.StatusOK:              models.PasswordStrengthResponse{},
+				http.StatusBadRequest:      validationErrorResponse,
+				http.StatusTooManyRequests: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/auth/check-field",
+			OperationID: "checkFieldAvailable",
+			Summary:     "Check whether an email or username is still free",
+			Description: "A username that reads the same as an existing one, such as @paypa1 next to @paypal, is reported as unavailable.",
+			Tags:        []string{"Auth"},
+			Headers:     powHeaders,
+			Query:       models.CheckFieldQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                   models.CheckFieldResponse{},
+				http.StatusBadRequest:           validationErrorResponse,
+				http.StatusForbidden:            errorResponse,
+				http.StatusPreconditionRequired: errorResponse,
+				http.StatusTooManyRequests:      errorResponse,
+				http.StatusInternalServerError:  models.CheckFieldResponse{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/monitoring/client-errors",
+			OperationID: "reportClientError",
+			Summary:     "Report a crash or unhandled error from the desktop app",
+			Tags:        []string{"Monitoring"},
+			Auth:        openapi.AuthOptional,
+			Request:     models.ClientErrorLog{},
+			Responses: map[int]any{
+				http.StatusOK:                    models.ClientErrorAccepted{},
+				http.StatusBadRequest:            validationErrorResponse,
+				http.StatusRequestEntityTooLarge: errorResponse,
+				http.StatusTooManyRequests:       errorResponse,
+				http.StatusInternalServerError:   errorResponse,
+			},
+		},
+		{
+			Method:             http.MethodPost,
+			Path:               "/api/v1/monitoring/events",
+			OperationID:        "ingestTelemetry",
+			Summary:            "Upload a batch of telemetry events",
+			Description:        "The body is newline-delimited JSON, one event per line, optionally compressed with gzip or zstd.",
+			Tags:               []string{"Monitoring"},
+			Auth:               openapi.AuthOptional,
+			Headers:            []openapi.Parameter{{Name: "X-Install-ID", Required: true, Format: "uuid"}},
+			Request:            telemetry.Event{},
+			RequestContentType: "application/x-ndjson",
+			Responses: map[int]any{
+				http.StatusAccepted:              telemetry.IngestResponse{},
+				http.StatusBadRequest:            validationErrorResponse,
+				http.StatusRequestEntityTooLarge: errorResponse,
+				http.StatusUnsupportedMediaType:  errorResponse,
+				http.StatusTooManyRequests:       errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/monitoring/installs/:install_id/consent",
+			OperationID: "getTelemetryConsent",
+			Summary:     "Read the telemetry consent recorded for an install",
+			Tags:        []string{"Monitoring"},
+			Auth:        openapi.AuthOptional,
+			Responses: map[int]any{
+				http.StatusOK:                  telemetry.ConsentResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/monitoring/installs/:install_id/consent",
+			OperationID: "updateTelemetryConsent",
+			Summary:     "Record telemetry consent for an install",
+			Description: "The first update issues the install's secret in install_secret. Later updates must send it in X-Install-Secret.",
+			Tags:        []string{"Monitoring"},
+			Auth:        openapi.AuthOptional,
+			Headers:     []openapi.Parameter{{Name: "X-Install-Secret", Description: "Required once the install has been issued a secret"}},
+			Request:     telemetry.ConsentUpdate{},
+			Responses: map[int]any{
+				http.StatusOK:                  telemetry.ConsentResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/profile",
+			OperationID: "getProfile",
+			Summary:     "Return the signed-in user",
+			Tags:        []string{"Account"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:           models.ProfileResponse{},
+				http.StatusUnauthorized: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/profile/username",
+			OperationID: "changeUsername",
+			Summary:     "Change the signed-in user's username",
+			Description: "Applies the same username policy as registration. Returns a fresh token pair carrying the new username; tokens issued earlier keep the old one until they expire.",
+			Tags:        []string{"Account"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.ChangeUsernameRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ChangeUsernameResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            validationErrorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/connections",
+			OperationID: "listConnections",
+			Summary:     "List the signed-in user's saved connections",
+			Tags:        []string{"Connections"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.ConnectionListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ConnectionListResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/connections",
+			OperationID: "createConnection",
+			Summary:     "Save a connection",
+			Description: "Passwords and keys are not accepted; the desktop app keeps them in the OS keychain. Options that do not apply to the protocol are dropped.",
+			Tags:        []string{"Connections"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.ConnectionInput{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.ConnectionResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            validationErrorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/connections/sync",
+			OperationID: "syncConnections",
+			Summary:     "Connections changed or deleted since a cursor",
+			Description: "Call without since after signing in for a full copy, then pass the returned cursor to receive only later changes.",
+			Tags:        []string{"Connections"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.ConnectionSyncQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ConnectionSyncResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/connections/:id",
+			OperationID: "getConnection",
+			Summary:     "Get a saved connection",
+			Tags:        []string{"Connections"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.ConnectionResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/connections/:id",
+			OperationID: "updateConnection",
+			Summary:     "Replace a saved connection",
+			Description: "The request must carry the version it was based on. If another client saved the connection since, the update is rejected with 409 resource.version_conflict.",
+			Tags:        []string{"Connections"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.ConnectionInput{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ConnectionResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            validationErrorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/connections/:id",
+			OperationID: "deleteConnection",
+			Summary:     "Delete a saved connection",
+			Description: "Other devices see the deletion on their next sync.",
+			Tags:        []string{"Connections"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.ConnectionDeleteQuery{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/vault",
+			OperationID: "getVaultSettings",
+			Summary:     "Vault mode and key derivation parameters",
+			Tags:        []string{"Vault"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.VaultSettingsResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/vault",
+			OperationID: "updateVaultSettings",
+			Summary:     "Switch zero-knowledge mode or store key derivation parameters",
+			Description: "Enabling zero-knowledge mode fails with 409 vault.migration_required while any item is still server-encrypted. Once enabled, the server discards its copy of the data key.",
+			Tags:        []string{"Vault"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.VaultSettingsRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.VaultSettingsResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/vault/items",
+			OperationID: "listVaultItems",
+			Summary:     "List vault items without their secrets",
+			Tags:        []string{"Vault"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.VaultItemListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.VaultItemListResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/vault/items",
+			OperationID: "createVaultItem",
+			Summary:     "Store a password, private key or other secret",
+			Description: "Server-encrypted secrets are sealed with AES-256-GCM under the user's data key. Client-encrypted secrets must use AES-256-GCM with associated data `livecode-vault:v1:{user_id}:{item_id}` and are stored as sent.",
+			Tags:        []string{"Vault"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.VaultItemInput{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.VaultItemResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/vault/items/:id",
+			OperationID: "getVaultItem",
+			Summary:     "Get a vault item with its secret",
+			Tags:        []string{"Vault"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.VaultItemResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/vault/items/:id",
+			OperationID: "updateVaultItem",
+			Summary:     "Replace a vault item",
+			Description: "The request must carry the version it was based on. Changing encryption moves the item between server and client encryption.",
+			Tags:        []string{"Vault"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.VaultItemInput{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.VaultItemResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/vault/items/:id",
+			OperationID: "deleteVaultItem",
+			Summary:     "Delete a vault item",
+			Tags:        []string{"Vault"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.VaultItemDeleteQuery{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/ssh-keys",
+			OperationID: "listSSHKeys",
+			Summary:     "List registered SSH public keys",
+			Tags:        []string{"SSH Keys"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHKeyListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/ssh-keys",
+			OperationID: "createSSHKey",
+			Summary:     "Register an SSH public key",
+			Description: "Accepts OpenSSH authorized_keys lines and RFC 4716 keys. Registering a key whose SHA256 fingerprint is already registered fails with 409 ssh_key.conflict.",
+			Tags:        []string{"SSH Keys"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHKeyInput{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.SSHKeyResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/ssh-keys/generate",
+			OperationID: "generateSSHKey",
+			Summary:     "Generate an Ed25519 or RSA key pair",
+			Description: "The private key is stored in the vault, encrypted by the server. Use the export operation to download it.",
+			Tags:        []string{"SSH Keys"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHKeyGenerateRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.SSHKeyResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/ssh-keys/import",
+			OperationID: "importSSHKey",
+			Summary:     "Import an OpenSSH, PEM or PuTTY private key",
+			Description: "The key is decrypted with the passphrase, if any, and stored in the vault encrypted by the server. PuTTY key files of version 2 and 3 are accepted.",
+			Tags:        []string{"SSH Keys"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHKeyImportRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.SSHKeyResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/ssh-keys/:id",
+			OperationID: "getSSHKey",
+			Summary:     "Get an SSH key",
+			Tags:        []string{"SSH Keys"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHKeyResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/ssh-keys/:id/export",
+			OperationID: "exportSSHKey",
+			Summary:     "Export the private key in OpenSSH or PuTTY format",
+			Description: "Only keys whose private key the server holds can be exported. PuTTY exports use the version 3 .ppk format with Argon2id key derivation.",
+			Tags:        []string{"SSH Keys"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHKeyExportRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHKeyExportResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/ssh-keys/:id",
+			OperationID: "deleteSSHKey",
+			Summary:     "Delete an SSH key",
+			Description: "The private key of a generated or imported key is deleted from the vault with it.",
+			Tags:        []string{"SSH Keys"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/ssh-ca",
+			OperationID: "listSSHCertificateAuthorities",
+			Summary:     "List the SSH certificate authorities servers should trust",
+			Tags:        []string{"SSH Certificates"},
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHCertificateAuthorityListResponse{},
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/ssh-ca/trusted-keys",
+			OperationID: "getSSHTrustedKeys",
+			Summary:     "Trusted CA keys as a TrustedUserCAKeys file",
+			Description: "One CA key per line. Point sshd's `TrustedUserCAKeys` at a copy of this file and refresh it after the CA is rotated.",
+			Tags:        []string{"SSH Certificates"},
+			Responses: map[int]any{
+				http.StatusOK:                  openapi.Raw{ContentType: "text/plain"},
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/ssh-ca/krl",
+			OperationID: "getSSHKeyRevocationList",
+			Summary:     "Revoked certificates as an OpenSSH key revocation list",
+			Description: "Point sshd's `RevokedKeys` at a copy of this file. Certificates leave the list once they expire.",
+			Tags:        []string{"SSH Certificates"},
+			Responses: map[int]any{
+				http.StatusOK:                  openapi.Raw{ContentType: "application/octet-stream"},
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/teams",
+			OperationID: "listMyTeams",
+			Summary:     "List the caller's teams and their principals",
+			Tags:        []string{"SSH Certificates"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TeamListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/ssh-certificates",
+			OperationID: "listSSHCertificates",
+			Summary:     "List the certificates issued to the caller, newest first",
+			Tags:        []string{"SSH Certificates"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.SSHCertificateListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHCertificateListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/ssh-certificates",
+			OperationID: "issueSSHCertificate",
+			Summary:     "Issue a short-lived SSH user certificate",
+			Description: "The certificate is valid for every principal of the caller's teams. Callers that belong to no team with principals get `403 ssh_ca.no_principals`.",
+			Tags:        []string{"SSH Certificates"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHCertificateRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.SSHCertificateResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/ssh-certificates/:serial/revoke",
+			OperationID: "revokeSSHCertificate",
+			Summary:     "Revoke one of the caller's certificates",
+			Description: "Revoking a certificate twice keeps the first revocation.",
+			Tags:        []string{"SSH Certificates"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHCertificateRevokeRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHCertificateResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/known-hosts",
+			OperationID: "listKnownHosts",
+			Summary:     "List the caller's trusted host keys and their teams' pinned keys",
+			Tags:        []string{"Known Hosts"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.KnownHostListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/known-hosts",
+			OperationID: "trustKnownHost",
+			Summary:     "Trust a host key on first connection",
+			Description: "Trusting the same key again is a no-op. A different key of the same type gets `409 known_host.changed` unless `replace` is set, and `409 known_host.pinned` when it contradicts a team pin. Replacing a key is recorded as an audit event.",
+			Tags:        []string{"Known Hosts"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.KnownHostInput{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.KnownHostResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            validationErrorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/known-hosts/verify",
+			OperationID: "verifyKnownHost",
+			Summary:     "Check the key a host presented",
+			Description: "Team pins take precedence over the caller's own keys. A `changed` status is recorded as an audit event; clients should refuse the connection and show both fingerprints.",
+			Tags:        []string{"Known Hosts"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.KnownHostVerifyRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.KnownHostVerifyResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/known-hosts/import",
+			OperationID: "importKnownHosts",
+			Summary:     "Import an OpenSSH known_hosts file",
+			Description: "Hosts whose key differs from the trusted one keep the trusted key, are listed under `changed` and are recorded as audit events.",
+			Tags:        []string{"Known Hosts"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.KnownHostsImportRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                    models.KnownHostsImportResponse{},
+				http.StatusBadRequest:            validationErrorResponse,
+				http.StatusUnauthorized:          errorResponse,
+				http.StatusRequestEntityTooLarge: errorResponse,
+				http.StatusTooManyRequests:       errorResponse,
+				http.StatusInternalServerError:   errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/known-hosts/export",
+			OperationID: "exportKnownHosts",
+			Summary:     "Trusted and pinned host keys as an OpenSSH known_hosts file",
+			Tags:        []string{"Known Hosts"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.KnownHostsExportQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  openapi.Raw{ContentType: "text/plain"},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/known-hosts/:id",
+			OperationID: "deleteKnownHost",
+			Summary:     "Stop trusting a host key",
+			Description: "Team pins can only be removed by an admin.",
+			Tags:        []string{"Known Hosts"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/connections/:id/sftp/list",
+			OperationID: "listRemoteFiles",
+			Summary:     "List a directory on a connection's server",
+			Description: "Works for SFTP, FTP, FTPS, WebDAV and S3 connections, despite the path. Sessions use the password, private key or secret access key stored in the vault; for SFTP the server's host key must be trusted in known hosts. Directories are listed first. capabilities lists the features of the protocol; requests for a missing one fail with remote.operation_unsupported.",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.RemoteListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.RemoteFileListResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/connections/:id/sftp/stat",
+			OperationID: "statRemoteFile",
+			Summary:     "Describe a remote file without following symlinks",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.RemotePathQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.RemoteFileResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/connections/:id/sftp/download",
+			OperationID: "downloadRemoteFile",
+			Summary:     "Download a remote file",
+			Description: "Supports single and multiple byte ranges and If-Range, so interrupted downloads can be resumed. With archive, a directory is sent as a zip or tar.gz built as it streams, without ranges; links and special files are left out.",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Headers: []openapi.Parameter{
+				{Name: "Range", Description: "Byte ranges to return, e.g. bytes=1048576-"},
+				{Name: "If-Range", Description: "Only apply Range if the file still has this modification date"},
+			},
+			Query: models.RemoteDownloadQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                           openapi.Raw{ContentType: "application/octet-stream"},
+				http.StatusPartialContent:               openapi.Raw{ContentType: "application/octet-stream"},
+				http.StatusNotModified:                  nil,
+				http.StatusRequestedRangeNotSatisfiable: openapi.Raw{ContentType: "text/plain"},
+				http.StatusBadRequest:                   errorResponse,
+				http.StatusUnauthorized:                 errorResponse,
+				http.StatusForbidden:                    errorResponse,
+				http.StatusNotFound:                     errorResponse,
+				http.StatusConflict:                     errorResponse,
+				http.StatusTooManyRequests:              errorResponse,
+				http.StatusInternalServerError:          errorResponse,
+				http.StatusBadGateway:                   errorResponse,
+				http.StatusServiceUnavailable:           errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/connections/:id/sftp/upload",
+			OperationID: "uploadRemoteFile",
+			Summary:     "Upload the request body to a remote file",
+			Description: "The body is streamed to the server. Without overwrite the upload fails if the file exists; with offset it resumes a partial upload, where the protocol supports it.",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.RemoteUploadQuery{},
+			Request:     openapi.Raw{ContentType: "application/octet-stream"},
+			Responses: map[int]any{
+				http.StatusCreated:               models.RemoteFileResponse{},
+				http.StatusBadRequest:            errorResponse,
+				http.StatusUnauthorized:          errorResponse,
+				http.StatusForbidden:             errorResponse,
+				http.StatusNotFound:              errorResponse,
+				http.StatusConflict:              errorResponse,
+				http.StatusRequestEntityTooLarge: errorResponse,
+				http.StatusTooManyRequests:       errorResponse,
+				http.StatusInternalServerError:   errorResponse,
+				http.StatusBadGateway:            errorResponse,
+				http.StatusServiceUnavailable:    errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/connections/:id/sftp/mkdir",
+			OperationID: "makeRemoteDirectory",
+			Summary:     "Create a remote directory",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.RemoteMkdirRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.RemoteFileResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/connections/:id/sftp/rename",
+			OperationID: "renameRemoteFile",
+			Summary:     "Rename or move a remote file",
+			Description: "S3 connections can only rename files.",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.RemoteRenameRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.RemoteFileResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/connections/:id/sftp/delete",
+			OperationID: "deleteRemoteFile",
+			Summary:     "Delete a remote file or directory",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.RemoteDeleteRequest{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/connections/:id/sftp/chmod",
+			OperationID: "chmodRemoteFile",
+			Summary:     "Change the permissions of a remote file",
+			Description: "Not supported on WebDAV and S3 connections.",
+			Tags:        []string{"Remote Files"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.RemoteChmodRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.RemoteFileResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/connections/:id/sftp/search",
+			OperationID: "searchRemoteFiles",
+			Summary:     "Search a remote directory tree by name and content",
+			Description: "Finds files and directories whose names match the `name` glob, or lines in files that contain `content`, or both. " +
+				"Servers that run commands search with `find` and `grep`; otherwise the gateway lists and reads the files itself. " +
+				"Results stream as Server-Sent Events: a `match` event per result, with the file and, for content, the line, " +
+				"then `done` with a summary, or `error` with a `code` and `detail` if the search failed part way. " +
+				"The search stops early at the result, scanned bytes or time limit, which `done` reports in `limit`. " +
+				"Closing the stream cancels it. Binary files and files that cannot be read are skipped.",
+			Tags:    []string{"Remote Files"},
+			Auth:    openapi.AuthRequired,
+			Request: models.RemoteSearchRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  openapi.Raw{ContentType: "text/event-stream"},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/transfers",
+			OperationID: "listTransfers",
+			Summary:     "List the user's transfers with their progress",
+			Description: "Newest first.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.TransferListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.TransferListResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/transfers",
+			OperationID: "createTransfer",
+			Summary:     "Queue an upload to or download from a connection",
+			Description: "An upload starts in the staging status: send its data with PUT /api/v1/transfers/{id}/data, and it is queued once all of it has arrived. An upload that stops receiving data is removed after a while, and each user has a quota for the data staged on the server. A download is queued at once; fetch its data when it has completed. With archive, a download packs a remote directory into a zip or tar.gz, and an upload is an archive that is extracted into remote_path. Extraction refuses archives with entries outside remote_path, more entries or more extracted data than the server allows, or, with symlinks set to reject, any links.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.TransferRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.TransferResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/transfers/:id",
+			OperationID: "getTransfer",
+			Summary:     "A transfer with its status history",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TransferResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/transfers/:id/data",
+			OperationID: "writeTransferData",
+			Summary:     "Stage upload data",
+			Description: "The body is written from offset, which must equal the transfer's staged_bytes. Data that arrives before the connection drops is kept, so the client can continue from the new staged_bytes.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.TransferDataQuery{},
+			Request:     openapi.Raw{ContentType: "application/octet-stream"},
+			Responses: map[int]any{
+				http.StatusOK:                  models.TransferResponse{},
+				http.StatusBadRequest:          errorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/transfers/:id/data",
+			OperationID: "readTransferData",
+			Summary:     "Download the data of a completed download",
+			Description: "SHA-256 transfers include the verified digest in a Repr-Digest header.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Headers: []openapi.Parameter{
+				{Name: "Range", Description: "Byte ranges to return, e.g. bytes=1048576-"},
+				{Name: "If-Range", Description: "Only apply Range if the data still has this modification date"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                           openapi.Raw{ContentType: "application/octet-stream"},
+				http.StatusPartialContent:               openapi.Raw{ContentType: "application/octet-stream"},
+				http.StatusNotModified:                  nil,
+				http.StatusRequestedRangeNotSatisfiable: openapi.Raw{ContentType: "text/plain"},
+				http.StatusUnauthorized:                 errorResponse,
+				http.StatusNotFound:                     errorResponse,
+				http.StatusConflict:                     errorResponse,
+				http.StatusTooManyRequests:              errorResponse,
+				http.StatusInternalServerError:          errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/transfers/:id/pause",
+			OperationID: "pauseTransfer",
+			Summary:     "Pause a queued or running transfer",
+			Description: "A running transfer stops after the chunk in flight and resumes from there.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TransferResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/transfers/:id/resume",
+			OperationID: "resumeTransfer",
+			Summary:     "Queue a paused or failed transfer again",
+			Description: "A failed transfer gets a fresh set of attempts.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TransferResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/transfers/:id/cancel",
+			OperationID: "cancelTransfer",
+			Summary:     "Cancel an unfinished transfer",
+			Description: "Staged data, and the partial file of an upload on the remote host, are removed.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TransferResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/transfers/:id",
+			OperationID: "deleteTransfer",
+			Summary:     "Delete a finished transfer and its staged data",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/transfers/:id/bandwidth",
+			OperationID: "setTransferBandwidth",
+			Summary:     "Change the bandwidth limit of an unfinished transfer",
+			Description: "The transfer's own limit applies on top of the user's and the server's. A running transfer switches to it while it runs.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.TransferBandwidthRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.TransferResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/bandwidth",
+			OperationID: "getBandwidthSettings",
+			Summary:     "Get the user's bandwidth limits",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.BandwidthResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/bandwidth",
+			OperationID: "updateBandwidthSettings",
+			Summary:     "Set the user's bandwidth limits",
+			Description: "The limit is shared by all of the user's running transfers on a server. Windows set other limits during parts of the day; the first matching window applies. Running transfers pick up the change within seconds.",
+			Tags:        []string{"Transfers"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.BandwidthSettings{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.BandwidthResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/events",
+			OperationID: "streamEvents",
+			Summary:     "Stream live events",
+			Description: "A Server-Sent Events stream of the user's events: `transfer.progress`, `transfer.status`, " +
+				"`schedule.run`, `session.revoked` and `notification`, each with a JSON `data` line. Events missed since `Last-Event-ID` " +
+				"are replayed while they are still buffered; otherwise a `stream.reset` event tells the client to reload. " +
+				"A comment is sent periodically to keep the connection open, and the stream ends when the access token expires.",
+			Tags: []string{"Events"},
+			Auth: openapi.AuthRequired,
+			Headers: []openapi.Parameter{
+				{Name: "Last-Event-ID", Description: "ID of the last event received, to replay what came after it"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:                 openapi.Raw{ContentType: "text/event-stream"},
+				http.StatusUnauthorized:       errorResponse,
+				http.StatusTooManyRequests:    errorResponse,
+				http.StatusServiceUnavailable: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/sync/plan",
+			OperationID: "planSync",
+			Summary:     "Compare a local directory with a remote one and plan a sync",
+			Description: "A dry run: the client lists its files in `local`, the server walks the remote directory and returns the uploads, downloads, deletes and conflicts needed, without changing anything. Include and exclude globs and .gitignore-style files (named in `ignore_files` on the server, sent in `local_ignore_files` from the client) leave paths out. The plan can be executed once before it expires. The OpenAPI validator does not read bodies this large, so the whole request is checked by the endpoint.",
+			Tags:        []string{"Sync"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SyncRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:               models.SyncPlanResponse{},
+				http.StatusBadRequest:            errorResponse,
+				http.StatusUnauthorized:          errorResponse,
+				http.StatusForbidden:             errorResponse,
+				http.StatusNotFound:              errorResponse,
+				http.StatusConflict:              errorResponse,
+				http.StatusRequestEntityTooLarge: errorResponse,
+				http.StatusTooManyRequests:       errorResponse,
+				http.StatusInternalServerError:   errorResponse,
+				http.StatusBadGateway:            errorResponse,
+				http.StatusServiceUnavailable:    errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/sync/plans/:id",
+			OperationID: "getSyncPlan",
+			Summary:     "A sync plan",
+			Tags:        []string{"Sync"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.SyncPlanResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/sync/plans/:id/execute",
+			OperationID: "executeSyncPlan",
+			Summary:     "Start the transfers of a sync plan",
+			Description: "Missing remote directories are created straight away. Uploads, downloads and remote deletes become transfers linked to the plan; uploads wait in staging for their data. Conflicts, local deletes and local directories are left to the client.",
+			Tags:        []string{"Sync"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.SyncExecutionResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusBadGateway:          errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/schedules",
+			OperationID: "listSchedules",
+			Summary:     "List recurring transfers and syncs",
+			Tags:        []string{"Schedules"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.ScheduleListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/schedules",
+			OperationID: "createSchedule",
+			Summary:     "Create a recurring download or sync",
+			Description: "Give either transfer, a download, or sync, a sync request whose plan is made and executed on every run. Uploads in a sync wait in staging for the client's data, so keep the schedule's file list current.",
+			Tags:        []string{"Schedules"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.ScheduleRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:               models.ScheduleResponse{},
+				http.StatusBadRequest:            validationErrorResponse,
+				http.StatusUnauthorized:          errorResponse,
+				http.StatusConflict:              errorResponse,
+				http.StatusRequestEntityTooLarge: errorResponse,
+				http.StatusTooManyRequests:       errorResponse,
+				http.StatusInternalServerError:   errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/schedules/:id",
+			OperationID: "getSchedule",
+			Summary:     "Get a schedule",
+			Tags:        []string{"Schedules"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.ScheduleResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/schedules/:id",
+			OperationID: "updateSchedule",
+			Summary:     "Replace a schedule",
+			Description: "The next run is worked out again from now.",
+			Tags:        []string{"Schedules"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.ScheduleRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                    models.ScheduleResponse{},
+				http.StatusBadRequest:            validationErrorResponse,
+				http.StatusUnauthorized:          errorResponse,
+				http.StatusNotFound:              errorResponse,
+				http.StatusRequestEntityTooLarge: errorResponse,
+				http.StatusTooManyRequests:       errorResponse,
+				http.StatusInternalServerError:   errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/schedules/:id",
+			OperationID: "deleteSchedule",
+			Summary:     "Delete a schedule and its run history",
+			Description: "Transfers it started carry on.",
+			Tags:        []string{"Schedules"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/schedules/:id/runs",
+			OperationID: "listScheduleRuns",
+			Summary:     "List a schedule's runs, newest first",
+			Description: "Every run is recorded, including those that were skipped because the server could not start them on time.",
+			Tags:        []string{"Schedules"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.ScheduleRunListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ScheduleRunListResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/client-issues",
+			OperationID: "listClientIssues",
+			Summary:     "List grouped client errors, most recently seen first",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.ClientIssueListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ClientIssueListResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/client-issues/:id",
+			OperationID: "getClientIssue",
+			Summary:     "Get a client issue with its versions and recent reports",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.ClientIssueQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ClientIssueResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v1/admin/client-issues/:id",
+			OperationID: "updateClientIssueStatus",
+			Summary:     "Resolve, ignore or reopen a client issue",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.ClientIssueStatusUpdate{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.ClientIssueResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/teams",
+			OperationID: "listTeams",
+			Summary:     "List teams",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TeamListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/admin/teams",
+			OperationID: "createTeam",
+			Summary:     "Create a team",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.TeamInput{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.TeamResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusConflict:            validationErrorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/teams/:id",
+			OperationID: "getTeam",
+			Summary:     "Get a team",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TeamResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/admin/teams/:id",
+			OperationID: "updateTeam",
+			Summary:     "Rename a team or replace its principals",
+			Description: "Certificates already issued keep the principals they were issued with.",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.TeamInput{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.TeamResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusConflict:            validationErrorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/admin/teams/:id",
+			OperationID: "deleteTeam",
+			Summary:     "Delete a team",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/teams/:id/members",
+			OperationID: "listTeamMembers",
+			Summary:     "List a team's members",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.TeamMemberListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/admin/teams/:id/members/:user_id",
+			OperationID: "addTeamMember",
+			Summary:     "Add a user to a team",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/admin/teams/:id/members/:user_id",
+			OperationID: "removeTeamMember",
+			Summary:     "Remove a user from a team",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/admin/ssh-ca",
+			OperationID: "createSSHCertificateAuthority",
+			Summary:     "Generate or import a CA key and make it the active CA",
+			Description: "The previous CA stops signing but stays in `/api/v1/ssh-ca/trusted-keys` until the certificates it issued expire. The private key is encrypted with the vault master key.",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHCertificateAuthorityRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.SSHCertificateAuthorityResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusTooManyRequests:     errorResponse,
+				http.StatusInternalServerError: errorResponse,
+				http.StatusServiceUnavailable:  errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/ssh-certificates",
+			OperationID: "adminListSSHCertificates",
+			Summary:     "Audit trail of issued certificates, newest first",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.SSHCertificateAdminListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHCertificateListResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/admin/ssh-certificates/:serial/revoke",
+			OperationID: "adminRevokeSSHCertificate",
+			Summary:     "Revoke any certificate",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.SSHCertificateRevokeRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.SSHCertificateResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/teams/:id/known-hosts",
+			OperationID: "listTeamKnownHosts",
+			Summary:     "List the host keys pinned for a team",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.KnownHostListResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/admin/teams/:id/known-hosts",
+			OperationID: "pinKnownHost",
+			Summary:     "Pin a host key for every member of a team",
+			Description: "A pinned key of the same type is replaced. Pins override members' own keys; `replace` is ignored.",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.KnownHostInput{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.KnownHostResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/admin/teams/:id/known-hosts/:host_id",
+			OperationID: "unpinKnownHost",
+			Summary:     "Remove a team's pinned host key",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusNotFound:            errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/known-host-events",
+			OperationID: "listKnownHostEvents",
+			Summary:     "Audit trail of host key changes and pins, newest first",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Query:       models.KnownHostEventListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.KnownHostEventListResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/admin/bandwidth",
+			OperationID: "getServerBandwidthSettings",
+			Summary:     "Get the server-wide bandwidth limits",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Responses: map[int]any{
+				http.StatusOK:                  models.BandwidthResponse{},
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/admin/bandwidth",
+			OperationID: "updateServerBandwidthSettings",
+			Summary:     "Set the server-wide bandwidth limits",
+			Description: "Each server applies the limit to all the transfers it runs, on top of per-user and per-transfer limits.",
+			Tags:        []string{"Admin"},
+			Auth:        openapi.AuthRequired,
+			Request:     models.BandwidthSettings{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.BandwidthResponse{},
+				http.StatusBadRequest:          validationErrorResponse,
+				http.StatusUnauthorized:        errorResponse,
+				http.StatusForbidden:           errorResponse,
+				http.StatusInternalServerError: errorResponse,
+			},
+		},
+	}
+}
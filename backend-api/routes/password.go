@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/i18n"
+	"livecode-api/internal/passwords"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func PasswordStrength(c *gin.Context) {
+	var req models.PasswordStrengthRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+		return
+	}
+
+	policy := middleware.PasswordPolicy()
+	evaluation, err := policy.Evaluate(c.Request.Context(), req.Password, passwords.UserContext{
+		Username: strings.TrimSpace(strings.ToLower(req.Username)),
+		Email:    strings.TrimSpace(strings.ToLower(req.Email)),
+	})
+	if err != nil {
+		middleware.GetLogger(c).Error("password_breach_lookup_failed",
+			zap.Error(err),
+		)
+	}
+
+	locale := c.GetString(i18n.ContextKey)
+	hint := func(key string) models.PasswordHint {
+		message, _ := i18n.Default().Translate(locale, key, nil)
+		return models.PasswordHint{Code: key, Message: message}
+	}
+
+	response := models.PasswordStrengthResponse{
+		Success:      true,
+		Acceptable:   evaluation.Acceptable(),
+		Score:        evaluation.Strength.Score,
+		MinScore:     policy.MinScore,
+		GuessesLog10: evaluation.Strength.GuessesLog10,
+		Suggestions:  []models.PasswordHint{},
+		Errors:       apierror.LocalizedFields(c, evaluation.Errors),
+	}
+
+	if warning := evaluation.Strength.Feedback.Warning; warning != "" {
+		warningHint := hint(warning)
+		response.Warning = &warningHint
+	}
+	for _, suggestion := range evaluation.Strength.Feedback.Suggestions {
+		response.Suggestions = append(response.Suggestions, hint(suggestion))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"net/http"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/pow"
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func IssueChallenge(issuer *pow.Issuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := c.Query("scope")
+
+		challenge, err := issuer.Issue(scope, c.ClientIP())
+		if err != nil {
+			middleware.GetLogger(c).Error("pow_challenge_issue_failed",
+				zap.String("scope", scope),
+				zap.Error(err),
+			)
+			apierror.Write(c, apierror.Internal())
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, pow.ChallengeResponse{
+			Success:    true,
+			Challenge:  challenge.Token,
+			Algorithm:  pow.Algorithm,
+			Difficulty: challenge.Difficulty,
+			ExpiresAt:  challenge.ExpiresAt,
+			Enforced:   issuer.Enforced(scope),
+		})
+	}
+}
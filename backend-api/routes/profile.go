@@ -3,29 +3,76 @@ package routes
 import (
 	"net/http"
 
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func GetProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated.",
-		})
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeAuthRequired, "User not authenticated."))
 		return
 	}
 
-	username, _ := c.Get("username")
-	email, _ := c.Get("email")
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Profile retrieved successfully.",
-		"user": gin.H{
-			"id":       userID,
-			"username": username,
-			"email":    email,
+	c.JSON(http.StatusOK, models.ProfileResponse{
+		Success: true,
+		Message: "Profile retrieved successfully.",
+		User: &models.UserData{
+			ID:       userID.(string),
+			Username: c.GetString("username"),
+			Email:    c.GetString("email"),
+			Role:     c.GetString("role"),
 		},
 	})
 }
+
+func ChangeUsername(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("change_username_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	req := validatedPayload.(models.ChangeUsernameRequest)
+	userID := c.GetString("user_id")
+
+	response, err := handlers.ChangeUsernameInternal(userID, req.Username, database.DB)
+
+	if err != nil {
+		middleware.GetLogger(c).Error("change_username_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if !response.Success {
+		middleware.GetLogger(c).Warn("change_username_rejected",
+			zap.String("user_id", userID),
+			zap.String("username", req.Username),
+			zap.String("code", response.Code),
+		)
+		status := http.StatusConflict
+		if response.Code == string(apierror.CodeNotFound) {
+			status = http.StatusNotFound
+		}
+		apierror.Write(c, apierror.New(status, apierror.Code(response.Code), response.Message).
+			WithFields(response.FieldErrors...))
+		return
+	}
+
+	middleware.GetLogger(c).Info("change_username_success",
+		zap.String("user_id", userID),
+		zap.String("username", response.User.Username),
+	)
+
+	c.JSON(http.StatusOK, response)
+}
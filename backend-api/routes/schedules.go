@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func ListSchedules(c *gin.Context) {
+	list, err := handlers.ListSchedulesInternal(c.GetString("user_id"), database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("schedules_list_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ScheduleListResponse{
+		Success:   true,
+		Schedules: list,
+	})
+}
+
+func CreateSchedule(c *gin.Context) {
+	req, ok := scheduleRequest(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := handlers.CreateScheduleInternal(c.GetString("user_id"), req, database.DB)
+	if err != nil {
+		writeScheduleError(c, "schedule_create_failed", "", err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("schedule_created",
+		zap.String("schedule_id", schedule.ID),
+		zap.String("kind", schedule.Kind),
+		zap.String("cron", schedule.Cron),
+	)
+
+	c.JSON(http.StatusCreated, models.ScheduleResponse{
+		Success:  true,
+		Schedule: schedule,
+	})
+}
+
+func GetSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		scheduleNotFound(c)
+		return
+	}
+
+	schedule, err := handlers.GetScheduleInternal(c.GetString("user_id"), scheduleID, database.DB)
+	if err != nil {
+		writeScheduleError(c, "schedule_get_failed", scheduleID, err)
+		return
+	}
+	if schedule == nil {
+		scheduleNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ScheduleResponse{
+		Success:  true,
+		Schedule: schedule,
+	})
+}
+
+func UpdateSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		scheduleNotFound(c)
+		return
+	}
+	req, ok := scheduleRequest(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := handlers.UpdateScheduleInternal(c.GetString("user_id"), scheduleID, req, database.DB)
+	if err != nil {
+		writeScheduleError(c, "schedule_update_failed", scheduleID, err)
+		return
+	}
+	if schedule == nil {
+		scheduleNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("schedule_updated",
+		zap.String("schedule_id", schedule.ID),
+		zap.Bool("enabled", schedule.Enabled),
+	)
+
+	c.JSON(http.StatusOK, models.ScheduleResponse{
+		Success:  true,
+		Schedule: schedule,
+	})
+}
+
+func DeleteSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		scheduleNotFound(c)
+		return
+	}
+
+	deleted, err := handlers.DeleteScheduleInternal(c.GetString("user_id"), scheduleID, database.DB)
+	if err != nil {
+		writeScheduleError(c, "schedule_delete_failed", scheduleID, err)
+		return
+	}
+	if !deleted {
+		scheduleNotFound(c)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func ListScheduleRuns(c *gin.Context) {
+	scheduleID := c.Param("id")
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		scheduleNotFound(c)
+		return
+	}
+
+	runs, err := handlers.ListScheduleRunsInternal(c.GetString("user_id"), scheduleID, queryInt(c, "limit", 50, 1, 200), database.DB)
+	if err != nil {
+		writeScheduleError(c, "schedule_runs_list_failed", scheduleID, err)
+		return
+	}
+	if runs == nil {
+		scheduleNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ScheduleRunListResponse{
+		Success: true,
+		Runs:    runs,
+	})
+}
+
+func scheduleRequest(c *gin.Context) (models.ScheduleRequest, bool) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("schedule_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return models.ScheduleRequest{}, false
+	}
+	return validatedPayload.(models.ScheduleRequest), true
+}
+
+func scheduleNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Schedule not found."))
+}
+
+func writeScheduleError(c *gin.Context, event, scheduleID string, err error) {
+	switch {
+	case errors.Is(err, handlers.ErrScheduleLimit):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeScheduleLimitReached,
+			"You have too many schedules. Delete one you no longer need."))
+	case errors.Is(err, handlers.ErrTransferConnectionNotFound), errors.Is(err, handlers.ErrRemoteUnsupported):
+		writeTransferError(c, event, "", err)
+	default:
+		middleware.GetLogger(c).Error(event,
+			zap.String("schedule_id", scheduleID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+	}
+}
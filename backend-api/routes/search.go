@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SearchRemoteFiles streams the results of a search as Server-Sent Events:
+// a match event for each result, then done with the summary, or error if
+// the search failed part way. Closing the stream cancels the search.
+// Problems found before the search starts are ordinary error responses.
+func SearchRemoteFiles(heartbeat time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		validatedPayload, exists := c.Get("validated_payload")
+		if !exists {
+			middleware.GetLogger(c).Error("remote_search_validation_missing")
+			apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+			return
+		}
+		req := validatedPayload.(models.RemoteSearchRequest)
+
+		session, connection, ok := openRemoteSession(c)
+		if !ok {
+			return
+		}
+		defer session.Release()
+
+		root, err := handlers.RemoteSearchRootInternal(session, connection, req.Path)
+		if err != nil {
+			writeRemoteError(c, "remote_search_failed", connection.ID, err)
+			return
+		}
+
+		extendWriteDeadline(c)
+
+		type result struct {
+			summary models.RemoteSearchSummary
+			err     error
+		}
+		ctx := c.Request.Context()
+		matches := make(chan models.RemoteSearchMatch, 64)
+		done := make(chan result, 1)
+		go func() {
+			defer close(matches)
+			summary, err := handlers.SearchRemoteFilesInternal(ctx, session, root, req, func(m models.RemoteSearchMatch) error {
+				select {
+				case matches <- m:
+					return nil
+				case <-ctx.Done():
+					return context.Cause(ctx)
+				}
+			})
+			done <- result{summary, err}
+		}()
+
+		ping := time.NewTicker(heartbeat)
+		defer ping.Stop()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		// The search stops once the client is gone, since its context is
+		// the request's; the session is only released after that.
+		for matches != nil {
+			select {
+			case <-ping.C:
+				fmt.Fprintf(c.Writer, ": ping\n\n")
+			case m, ok := <-matches:
+				if !ok {
+					matches = nil
+					continue
+				}
+				writeSearchEvent(c, "match", m)
+			}
+			c.Writer.Flush()
+		}
+
+		res := <-done
+		switch {
+		case res.err == nil:
+			middleware.GetLogger(c).Info("remote_search_finished",
+				zap.String("connection_id", connection.ID),
+				zap.String("path", root),
+				zap.String("method", res.summary.Method),
+				zap.Int("results", res.summary.Results),
+				zap.Int64("bytes_scanned", res.summary.BytesScanned),
+				zap.String("limit", res.summary.Limit),
+			)
+			writeSearchEvent(c, "done", res.summary)
+		case ctx.Err() != nil:
+			return
+		case errors.Is(res.err, os.ErrPermission):
+			writeSearchEvent(c, "error", models.RemoteSearchError{
+				Code:   string(apierror.CodeRemotePermissionDenied),
+				Detail: "The server denied access to this path.",
+			})
+		default:
+			middleware.GetLogger(c).Warn("remote_search_failed",
+				zap.String("connection_id", connection.ID),
+				zap.Error(res.err),
+			)
+			writeSearchEvent(c, "error", models.RemoteSearchError{
+				Code:   string(apierror.CodeRemoteFailed),
+				Detail: "The server could not complete the search.",
+			})
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeSearchEvent(c *gin.Context, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+}
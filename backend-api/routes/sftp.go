@@ -0,0 +1,506 @@
+package routes
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/archive"
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/throttle"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pkg/sftp"
+	"go.uber.org/zap"
+)
+
+// openRemoteSession opens a gateway session for the connection in the
+// path, writing the error response if it cannot. Callers release the
+// session.
+func openRemoteSession(c *gin.Context) (*remotefs.Session, *models.Connection, bool) {
+	connectionID := c.Param("id")
+	if _, err := uuid.Parse(connectionID); err != nil {
+		connectionNotFound(c)
+		return nil, nil, false
+	}
+
+	audit := models.KnownHostKey{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	session, connection, err := handlers.OpenRemoteSessionInternal(c.Request.Context(), c.GetString("user_id"), connectionID, audit, database.DB)
+
+	switch {
+	case errors.Is(err, remotefs.ErrConnectFailed), errors.Is(err, remotefs.ErrAuthFailed), errors.Is(err, remotefs.ErrPoolClosed),
+		errors.Is(err, handlers.ErrRemoteUnsupported), errors.Is(err, handlers.ErrRemoteNoCredentials),
+		errors.Is(err, handlers.ErrRemoteClientEncrypted):
+		writeRemoteError(c, "sftp_session_failed", connectionID, err)
+		return nil, nil, false
+	case err != nil:
+		writeVaultError(c, "sftp_session_failed", "", err)
+		return nil, nil, false
+	case session == nil:
+		connectionNotFound(c)
+		return nil, nil, false
+	}
+
+	return session, connection, true
+}
+
+func remotePathQuery(c *gin.Context) (string, bool) {
+	name, ok := middleware.CleanRemotePath(c.Query("path"))
+	if !ok {
+		apierror.Write(c, apierror.Validation(apierror.Field("path", apierror.FieldInvalidCharacters, nil)))
+	}
+	return name, ok
+}
+
+// ListRemoteFiles lists a directory, by default the connection's remote
+// directory.
+func ListRemoteFiles(c *gin.Context) {
+	session, connection, ok := openRemoteSession(c)
+	if !ok {
+		return
+	}
+	defer session.Release()
+
+	dir := c.Query("path")
+	if dir == "" {
+		dir = connection.RemoteDirectory
+	}
+	if dir == "" {
+		dir = "."
+	}
+	dir, ok = middleware.CleanRemotePath(dir)
+	if !ok {
+		apierror.Write(c, apierror.Validation(apierror.Field("path", apierror.FieldInvalidCharacters, nil)))
+		return
+	}
+
+	resolved, files, err := handlers.ListRemoteDirectoryInternal(session, dir)
+	if err != nil {
+		writeRemoteError(c, "remote_list_failed", connection.ID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RemoteFileListResponse{
+		Success:      true,
+		Path:         resolved,
+		Files:        files,
+		Capabilities: handlers.RemoteCapabilitiesFrom(session),
+	})
+}
+
+func StatRemoteFile(c *gin.Context) {
+	name, ok := remotePathQuery(c)
+	if !ok {
+		return
+	}
+
+	session, connection, ok := openRemoteSession(c)
+	if !ok {
+		return
+	}
+	defer session.Release()
+
+	file, err := handlers.StatRemoteFileInternal(session, name)
+	if err != nil {
+		writeRemoteError(c, "remote_stat_failed", connection.ID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RemoteFileResponse{
+		Success: true,
+		File:    file,
+	})
+}
+
+// The server's read and write timeouts are sized for JSON requests and
+// responses. extendWriteDeadline lifts the write timeout for a response
+// that is streamed or held open, so it can take as long as it needs.
+func extendWriteDeadline(c *gin.Context) {
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+}
+
+// extendReadDeadline does the same for a request body that is streamed.
+func extendReadDeadline(c *gin.Context) {
+	http.NewResponseController(c.Writer).SetReadDeadline(time.Time{})
+}
+
+// DownloadRemoteFile streams a file. http.ServeContent handles Range and
+// conditional requests.
+func DownloadRemoteFile(c *gin.Context) {
+	name, ok := remotePathQuery(c)
+	if !ok {
+		return
+	}
+
+	session, connection, ok := openRemoteSession(c)
+	if !ok {
+		return
+	}
+	defer session.Release()
+
+	if format := c.Query("archive"); format != "" {
+		downloadRemoteArchive(c, session, connection, name, format)
+		return
+	}
+
+	file, info, err := handlers.OpenRemoteFileInternal(c.Request.Context(), session, name)
+	if err != nil {
+		writeRemoteError(c, "remote_download_failed", connection.ID, err)
+		return
+	}
+	defer file.Close()
+
+	buckets, release, err := handlers.AcquireStreamBandwidth(c.Request.Context(), c.GetString("user_id"), database.DB)
+	if err != nil {
+		writeRemoteError(c, "remote_download_failed", connection.ID, err)
+		return
+	}
+	defer release()
+
+	middleware.GetLogger(c).Info("remote_file_downloaded",
+		zap.String("connection_id", connection.ID),
+		zap.String("path", name),
+		zap.Int64("size", info.Size()),
+		zap.String("range", c.GetHeader("Range")),
+	)
+
+	extendWriteDeadline(c)
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), handlers.ThrottleRemoteFile(c.Request.Context(), file, buckets))
+}
+
+// downloadRemoteArchive streams the tree under dir as an archive that is
+// built as it is sent, so it has no length and no ranges. A failure part
+// way can only cut the response short, which leaves an archive unpackers
+// reject.
+func downloadRemoteArchive(c *gin.Context, session *remotefs.Session, connection *models.Connection, dir, format string) {
+	ctx := c.Request.Context()
+	root, items, err := handlers.ListRemoteArchiveInternal(ctx, session, dir)
+	if err != nil {
+		writeRemoteError(c, "remote_archive_failed", connection.ID, err)
+		return
+	}
+	buckets, release, err := handlers.AcquireStreamBandwidth(ctx, c.GetString("user_id"), database.DB)
+	if err != nil {
+		writeRemoteError(c, "remote_archive_failed", connection.ID, err)
+		return
+	}
+	defer release()
+
+	extendWriteDeadline(c)
+
+	name := path.Base(root)
+	if name == "/" {
+		name = "root"
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", archive.ContentType(format))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	c.Status(http.StatusOK)
+
+	var entries int
+	var read int64
+	packer := archive.Packer{
+		FS:   session,
+		Root: root,
+		Source: func(r io.Reader) io.Reader {
+			return throttle.Reader(ctx, r, buckets...)
+		},
+		Progress: func(n int, r int64) error {
+			entries, read = n, r
+			return nil
+		},
+	}
+	err = packer.Pack(ctx, c.Writer, format, items)
+
+	fields := []zap.Field{
+		zap.String("connection_id", connection.ID),
+		zap.String("path", root),
+		zap.String("format", format),
+		zap.Int("entries", entries),
+		zap.Int64("bytes_read", read),
+	}
+	switch {
+	case err == nil:
+		middleware.GetLogger(c).Info("remote_archive_downloaded", fields...)
+	case ctx.Err() == nil:
+		middleware.GetLogger(c).Warn("remote_archive_failed", append(fields, zap.Error(err))...)
+	}
+}
+
+// UploadRemoteFile streams the request body to a file, up to maxBytes.
+func UploadRemoteFile(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, ok := remotePathQuery(c)
+		if !ok {
+			return
+		}
+		overwrite, _ := strconv.ParseBool(c.Query("overwrite"))
+		offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+
+		session, connection, ok := openRemoteSession(c)
+		if !ok {
+			return
+		}
+		defer session.Release()
+
+		extendReadDeadline(c)
+		extendWriteDeadline(c)
+
+		buckets, release, err := handlers.AcquireStreamBandwidth(c.Request.Context(), c.GetString("user_id"), database.DB)
+		if err != nil {
+			writeRemoteError(c, "remote_upload_failed", connection.ID, err)
+			return
+		}
+		defer release()
+
+		body := throttle.Reader(c.Request.Context(), http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes), buckets...)
+		file, err := handlers.UploadRemoteFileInternal(c.Request.Context(), session, name, body, offset, overwrite)
+		if err != nil {
+			writeRemoteError(c, "remote_upload_failed", connection.ID, err)
+			return
+		}
+
+		middleware.GetLogger(c).Info("remote_file_uploaded",
+			zap.String("connection_id", connection.ID),
+			zap.String("path", file.Path),
+			zap.Int64("size", file.Size),
+			zap.Int64("offset", offset),
+		)
+
+		c.JSON(http.StatusCreated, models.RemoteFileResponse{
+			Success: true,
+			File:    file,
+		})
+	}
+}
+
+func MakeRemoteDirectory(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("remote_mkdir_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.RemoteMkdirRequest)
+
+	session, connection, ok := openRemoteSession(c)
+	if !ok {
+		return
+	}
+	defer session.Release()
+
+	file, err := handlers.MakeRemoteDirectoryInternal(session, req.Path, req.Parents)
+	if err != nil {
+		writeRemoteError(c, "remote_mkdir_failed", connection.ID, err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("remote_directory_created",
+		zap.String("connection_id", connection.ID),
+		zap.String("path", req.Path),
+	)
+
+	c.JSON(http.StatusCreated, models.RemoteFileResponse{
+		Success: true,
+		File:    file,
+	})
+}
+
+func RenameRemoteFile(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("remote_rename_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.RemoteRenameRequest)
+
+	session, connection, ok := openRemoteSession(c)
+	if !ok {
+		return
+	}
+	defer session.Release()
+
+	file, err := handlers.RenameRemoteFileInternal(session, req.From, req.To, req.Overwrite)
+	if err != nil {
+		writeRemoteError(c, "remote_rename_failed", connection.ID, err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("remote_file_renamed",
+		zap.String("connection_id", connection.ID),
+		zap.String("from", req.From),
+		zap.String("to", req.To),
+	)
+
+	c.JSON(http.StatusOK, models.RemoteFileResponse{
+		Success: true,
+		File:    file,
+	})
+}
+
+func DeleteRemoteFile(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("remote_delete_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.RemoteDeleteRequest)
+
+	session, connection, ok := openRemoteSession(c)
+	if !ok {
+		return
+	}
+	defer session.Release()
+
+	if err := handlers.DeleteRemoteFileInternal(session, req.Path, req.Recursive); err != nil {
+		writeRemoteError(c, "remote_delete_failed", connection.ID, err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("remote_file_deleted",
+		zap.String("connection_id", connection.ID),
+		zap.String("path", req.Path),
+		zap.Bool("recursive", req.Recursive),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func ChmodRemoteFile(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("remote_chmod_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.RemoteChmod)
+
+	session, connection, ok := openRemoteSession(c)
+	if !ok {
+		return
+	}
+	defer session.Release()
+
+	file, err := handlers.ChmodRemoteFileInternal(session, req.Path, req.Mode)
+	if err != nil {
+		writeRemoteError(c, "remote_chmod_failed", connection.ID, err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("remote_file_chmod",
+		zap.String("connection_id", connection.ID),
+		zap.String("path", req.Path),
+		zap.String("mode", strconv.FormatUint(uint64(req.Mode), 8)),
+	)
+
+	c.JSON(http.StatusOK, models.RemoteFileResponse{
+		Success: true,
+		File:    file,
+	})
+}
+
+func writeRemoteError(c *gin.Context, event, connectionID string, err error) {
+	var hostKeyErr *handlers.HostKeyError
+	var offsetErr *handlers.RemoteOffsetError
+	var maxBytesErr *http.MaxBytesError
+	var statusErr *sftp.StatusError
+	var serverErr *remotefs.ServerError
+
+	switch {
+	case errors.As(err, &hostKeyErr):
+		code, detail := apierror.CodeKnownHostUnknown, "The server's host key is not trusted yet."
+		if hostKeyErr.Status == models.KnownHostChanged {
+			code, detail = apierror.CodeKnownHostChanged, "The host key differs from the one you trust."
+			middleware.GetLogger(c).Warn("remote_host_key_changed",
+				zap.String("connection_id", connectionID),
+				zap.String("host", hostKeyErr.Address),
+				zap.String("presented", hostKeyErr.Fingerprint),
+			)
+		}
+		apierror.Write(c, apierror.New(http.StatusConflict, code, detail).
+			WithFields(apierror.Field("host_key", apierror.FieldHostKeyUntrusted, map[string]any{
+				"host":        hostKeyErr.Address,
+				"fingerprint": hostKeyErr.Fingerprint,
+				"public_key":  hostKeyErr.PublicKey,
+			})))
+	case errors.Is(err, remotefs.ErrAuthFailed):
+		apierror.Write(c, apierror.New(http.StatusBadGateway, apierror.CodeRemoteAuthFailed,
+			"The server rejected the stored credentials."))
+	case errors.Is(err, remotefs.ErrAddressBlocked):
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeRemoteAddressBlocked,
+			"The server's address is on a network the gateway may not reach."))
+	case errors.Is(err, remotefs.ErrConnectFailed):
+		middleware.GetLogger(c).Warn(event,
+			zap.String("connection_id", connectionID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.New(http.StatusBadGateway, apierror.CodeRemoteConnectFailed,
+			"Could not connect to the server."))
+	case errors.Is(err, remotefs.ErrPoolClosed):
+		apierror.Write(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeServiceUnavailable,
+			"The server is shutting down. Please try again."))
+	case errors.Is(err, handlers.ErrRemoteUnsupported):
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeRemoteUnsupported,
+			"This connection cannot be opened through the gateway."))
+	case errors.Is(err, handlers.ErrRemoteNoCredentials):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeRemoteNoCredentials,
+			"No password, private key or secret access key is stored in the vault for this connection."))
+	case errors.Is(err, handlers.ErrRemoteClientEncrypted):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeRemoteClientEncrypted,
+			"This connection's credentials are encrypted on the client and cannot be used by the gateway."))
+	case errors.Is(err, remotefs.ErrUnsupported):
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeRemoteOperationUnsupported,
+			"The server's protocol does not support this operation."))
+	case errors.Is(err, os.ErrNotExist):
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Remote file not found."))
+	case errors.Is(err, os.ErrPermission):
+		apierror.Write(c, apierror.New(http.StatusForbidden, apierror.CodeRemotePermissionDenied,
+			"The server denied access to this path."))
+	case errors.Is(err, handlers.ErrRemoteExists), errors.Is(err, os.ErrExist):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeRemoteExists,
+			"A file or directory already exists at this path."))
+	case errors.Is(err, handlers.ErrRemoteNotEmpty):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeRemoteNotEmpty, "The directory is not empty."))
+	case errors.Is(err, handlers.ErrRemoteIsDirectory):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeRemoteIsDirectory, "The path is a directory."))
+	case errors.Is(err, handlers.ErrRemoteNotDirectory):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeRemoteNotDirectory, "The path is not a directory."))
+	case errors.Is(err, archive.ErrTooManyEntries):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeRemoteArchiveTooLarge,
+			"The directory has too many entries to download as an archive."))
+	case errors.As(err, &offsetErr):
+		apierror.Write(c, apierror.Validation(apierror.Field("offset", apierror.FieldTooLarge, map[string]any{"max": offsetErr.Size})))
+	case errors.As(err, &maxBytesErr):
+		apierror.Write(c, apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "The upload is too large."))
+	case errors.As(err, &statusErr), errors.As(err, &serverErr):
+		middleware.GetLogger(c).Warn(event,
+			zap.String("connection_id", connectionID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.New(http.StatusBadGateway, apierror.CodeRemoteFailed,
+			"The server could not complete the operation."))
+	default:
+		middleware.GetLogger(c).Error(event,
+			zap.String("connection_id", connectionID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.New(http.StatusBadGateway, apierror.CodeRemoteFailed,
+			"The server could not complete the operation."))
+	}
+}
@@ -0,0 +1,515 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ListMyTeams returns the teams the caller belongs to, and so the
+// principals their certificates are valid for.
+func ListMyTeams(c *gin.Context) {
+	listTeams(c, c.GetString("user_id"))
+}
+
+func ListTeams(c *gin.Context) {
+	listTeams(c, "")
+}
+
+func listTeams(c *gin.Context, userID string) {
+	teams, err := handlers.ListTeamsInternal(userID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("teams_list_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TeamListResponse{
+		Success: true,
+		Teams:   teams,
+	})
+}
+
+func GetTeam(c *gin.Context) {
+	teamID := c.Param("id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+
+	team, err := handlers.GetTeamInternal(teamID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("team_lookup_failed",
+			zap.String("team_id", teamID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if team == nil {
+		teamNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TeamResponse{
+		Success: true,
+		Team:    team,
+	})
+}
+
+func CreateTeam(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("team_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	team, err := handlers.CreateTeamInternal(validatedPayload.(models.TeamInput), database.DB)
+	if err != nil {
+		writeTeamError(c, "team_create_failed", "", err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("team_created",
+		zap.String("team_id", team.ID),
+		zap.Strings("principals", team.Principals),
+	)
+
+	c.JSON(http.StatusCreated, models.TeamResponse{
+		Success: true,
+		Team:    team,
+	})
+}
+
+func UpdateTeam(c *gin.Context) {
+	teamID := c.Param("id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("team_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	team, err := handlers.UpdateTeamInternal(teamID, validatedPayload.(models.TeamInput), database.DB)
+	if err != nil {
+		writeTeamError(c, "team_update_failed", teamID, err)
+		return
+	}
+
+	if team == nil {
+		teamNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("team_updated",
+		zap.String("team_id", team.ID),
+		zap.Strings("principals", team.Principals),
+	)
+
+	c.JSON(http.StatusOK, models.TeamResponse{
+		Success: true,
+		Team:    team,
+	})
+}
+
+func DeleteTeam(c *gin.Context) {
+	teamID := c.Param("id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+
+	deleted, err := handlers.DeleteTeamInternal(teamID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("team_delete_failed",
+			zap.String("team_id", teamID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if !deleted {
+		teamNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("team_deleted",
+		zap.String("team_id", teamID),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func ListTeamMembers(c *gin.Context) {
+	teamID := c.Param("id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+
+	members, err := handlers.ListTeamMembersInternal(teamID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("team_members_list_failed",
+			zap.String("team_id", teamID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if members == nil {
+		teamNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TeamMemberListResponse{
+		Success: true,
+		Members: members,
+	})
+}
+
+func AddTeamMember(c *gin.Context) {
+	teamID, userID := c.Param("id"), c.Param("user_id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "User not found."))
+		return
+	}
+
+	err := handlers.AddTeamMemberInternal(teamID, userID, database.DB)
+	switch {
+	case errors.Is(err, handlers.ErrTeamNotFound):
+		teamNotFound(c)
+		return
+	case errors.Is(err, handlers.ErrTeamUserNotFound):
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "User not found."))
+		return
+	case err != nil:
+		middleware.GetLogger(c).Error("team_member_add_failed",
+			zap.String("team_id", teamID),
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	middleware.GetLogger(c).Info("team_member_added",
+		zap.String("team_id", teamID),
+		zap.String("user_id", userID),
+		zap.String("added_by", c.GetString("user_id")),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func RemoveTeamMember(c *gin.Context) {
+	teamID, userID := c.Param("id"), c.Param("user_id")
+	if _, err := uuid.Parse(teamID); err != nil {
+		teamNotFound(c)
+		return
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Team member not found."))
+		return
+	}
+
+	removed, err := handlers.RemoveTeamMemberInternal(teamID, userID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("team_member_remove_failed",
+			zap.String("team_id", teamID),
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if !removed {
+		apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Team member not found."))
+		return
+	}
+
+	middleware.GetLogger(c).Info("team_member_removed",
+		zap.String("team_id", teamID),
+		zap.String("user_id", userID),
+		zap.String("removed_by", c.GetString("user_id")),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func ListSSHCertificateAuthorities(c *gin.Context) {
+	authorities, err := handlers.ListSSHCertificateAuthoritiesInternal(database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_ca_list_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SSHCertificateAuthorityListResponse{
+		Success:     true,
+		Authorities: authorities,
+	})
+}
+
+// GetSSHTrustedKeys serves the trusted CA keys as a TrustedUserCAKeys file.
+func GetSSHTrustedKeys(c *gin.Context) {
+	authorities, err := handlers.ListSSHCertificateAuthoritiesInternal(database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_ca_list_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	var keys strings.Builder
+	for _, ca := range authorities {
+		keys.WriteString(ca.PublicKey + " " + ca.Name + "\n")
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(keys.String()))
+}
+
+// GetSSHKeyRevocationList serves the KRL for sshd's RevokedKeys option.
+func GetSSHKeyRevocationList(c *gin.Context) {
+	krl, err := handlers.SSHKeyRevocationListInternal(database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_krl_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Content-Disposition", `attachment; filename="livecode.krl"`)
+	c.Data(http.StatusOK, "application/octet-stream", krl)
+}
+
+func CreateSSHCertificateAuthority(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("ssh_ca_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	req := validatedPayload.(models.SSHCertificateAuthorityRequest)
+
+	ca, err := handlers.CreateSSHCertificateAuthorityInternal(req, database.DB)
+	if err != nil {
+		keyField := "algorithm"
+		if req.PrivateKey != "" {
+			keyField = "private_key"
+		}
+		writeSSHKeyError(c, "ssh_ca_create_failed", keyField, "", err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("ssh_ca_created",
+		zap.String("ca_id", ca.ID),
+		zap.String("type", ca.Type),
+		zap.String("fingerprint", ca.FingerprintSHA256),
+		zap.Bool("imported", req.PrivateKey != ""),
+		zap.String("created_by", c.GetString("user_id")),
+	)
+
+	c.JSON(http.StatusCreated, models.SSHCertificateAuthorityResponse{
+		Success:   true,
+		Authority: ca,
+	})
+}
+
+func IssueSSHCertificate(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("ssh_certificate_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	issue := validatedPayload.(models.SSHCertificateIssue)
+	userID := c.GetString("user_id")
+
+	cert, err := handlers.IssueSSHCertificateInternal(userID, c.GetString("username"), issue, database.DB)
+	switch {
+	case errors.Is(err, handlers.ErrSSHCertKeyNotFound):
+		apierror.Write(c, apierror.Validation(apierror.Field("ssh_key_id", apierror.FieldNotFound, nil)))
+		return
+	case errors.Is(err, handlers.ErrSSHCANoPrincipals):
+		apierror.Write(c, apierror.New(http.StatusForbidden, apierror.CodeSSHCANoPrincipals,
+			"You are not a member of a team with SSH principals."))
+		return
+	case errors.Is(err, handlers.ErrSSHCANotConfigured):
+		apierror.Write(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeSSHCAUnavailable,
+			"No SSH certificate authority is configured."))
+		return
+	case errors.Is(err, sshkeys.ErrUnsupportedKeyType) || errors.Is(err, sshkeys.ErrKeyTooWeak):
+		apierror.Write(c, apierror.Validation(middleware.SSHKeyFieldError("ssh_key_id", err)))
+		return
+	case err != nil:
+		writeVaultError(c, "ssh_certificate_issue_failed", "", err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("ssh_certificate_issued",
+		zap.String("user_id", userID),
+		zap.Int64("serial", cert.Serial),
+		zap.String("ca_id", cert.CAID),
+		zap.String("key_fingerprint", cert.KeyFingerprint),
+		zap.Strings("principals", cert.Principals),
+		zap.Time("valid_before", cert.ValidBefore),
+	)
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, models.SSHCertificateResponse{
+		Success:     true,
+		Certificate: cert,
+	})
+}
+
+func ListSSHCertificates(c *gin.Context) {
+	listSSHCertificates(c, c.GetString("user_id"))
+}
+
+func AdminListSSHCertificates(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid user filter.").
+				WithFields(apierror.Field("user_id", apierror.FieldInvalidFormat, nil)))
+			return
+		}
+	}
+	listSSHCertificates(c, userID)
+}
+
+func listSSHCertificates(c *gin.Context, userID string) {
+	filter := models.SSHCertificateFilter{
+		UserID: userID,
+		Limit:  queryInt(c, "limit", 50, 1, 200),
+		Offset: queryInt(c, "offset", 0, 0, 1000000),
+	}
+
+	certificates, total, err := handlers.ListSSHCertificatesInternal(filter, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_certificates_list_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SSHCertificateListResponse{
+		Success:      true,
+		Certificates: certificates,
+		Total:        total,
+		Limit:        filter.Limit,
+		Offset:       filter.Offset,
+	})
+}
+
+// RevokeSSHCertificate lets users revoke their own certificates, for
+// example after losing the key.
+func RevokeSSHCertificate(c *gin.Context) {
+	revokeSSHCertificate(c, c.GetString("user_id"))
+}
+
+func AdminRevokeSSHCertificate(c *gin.Context) {
+	revokeSSHCertificate(c, "")
+}
+
+func revokeSSHCertificate(c *gin.Context, ownerID string) {
+	serial, err := strconv.ParseInt(c.Param("serial"), 10, 64)
+	if err != nil || serial < 1 {
+		sshCertificateNotFound(c)
+		return
+	}
+
+	var req models.SSHCertificateRevokeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+		return
+	}
+	revokedBy := c.GetString("user_id")
+
+	cert, err := handlers.RevokeSSHCertificateInternal(serial, ownerID, revokedBy, strings.TrimSpace(req.Reason), database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_certificate_revoke_failed",
+			zap.Int64("serial", serial),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if cert == nil {
+		sshCertificateNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("ssh_certificate_revoked",
+		zap.Int64("serial", serial),
+		zap.String("revoked_by", revokedBy),
+		zap.Bool("admin", ownerID == ""),
+	)
+
+	c.JSON(http.StatusOK, models.SSHCertificateResponse{
+		Success:     true,
+		Certificate: cert,
+	})
+}
+
+func teamNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Team not found."))
+}
+
+func sshCertificateNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Certificate not found."))
+}
+
+func writeTeamError(c *gin.Context, event, teamID string, err error) {
+	if errors.Is(err, handlers.ErrTeamExists) {
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeTeamConflict, "The team could not be saved.").
+			WithFields(apierror.Field("name", apierror.FieldTeamNameTaken, nil)))
+		return
+	}
+
+	middleware.GetLogger(c).Error(event,
+		zap.String("team_id", teamID),
+		zap.Error(err),
+	)
+	apierror.Write(c, apierror.Internal())
+}
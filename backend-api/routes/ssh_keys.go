@@ -0,0 +1,244 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/sshkeys"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func ListSSHKeys(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	keys, err := handlers.ListSSHKeysInternal(userID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_keys_list_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SSHKeyListResponse{
+		Success: true,
+		Keys:    keys,
+	})
+}
+
+func GetSSHKey(c *gin.Context) {
+	keyID := c.Param("id")
+	if _, err := uuid.Parse(keyID); err != nil {
+		sshKeyNotFound(c)
+		return
+	}
+
+	key, err := handlers.GetSSHKeyInternal(c.GetString("user_id"), keyID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_key_lookup_failed",
+			zap.String("key_id", keyID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if key == nil {
+		sshKeyNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SSHKeyResponse{
+		Success: true,
+		Key:     key,
+	})
+}
+
+func CreateSSHKey(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("ssh_key_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	input := validatedPayload.(models.SSHKeyInput)
+	userID := c.GetString("user_id")
+
+	key, err := handlers.CreateSSHKeyInternal(userID, input, database.DB)
+	if err != nil {
+		writeSSHKeyError(c, "ssh_key_create_failed", "public_key", "", err)
+		return
+	}
+
+	logSSHKeyCreated(c, userID, key)
+	c.JSON(http.StatusCreated, models.SSHKeyResponse{
+		Success: true,
+		Key:     key,
+	})
+}
+
+func GenerateSSHKey(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("ssh_key_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	req := validatedPayload.(models.SSHKeyGenerateRequest)
+	userID := c.GetString("user_id")
+
+	key, err := handlers.GenerateSSHKeyInternal(userID, req, database.DB)
+	if err != nil {
+		writeSSHKeyError(c, "ssh_key_generate_failed", "algorithm", "", err)
+		return
+	}
+
+	logSSHKeyCreated(c, userID, key)
+	c.JSON(http.StatusCreated, models.SSHKeyResponse{
+		Success: true,
+		Key:     key,
+	})
+}
+
+func ImportSSHKey(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("ssh_key_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	req := validatedPayload.(models.SSHKeyImportRequest)
+	userID := c.GetString("user_id")
+
+	key, err := handlers.ImportSSHKeyInternal(userID, req, database.DB)
+	if err != nil {
+		writeSSHKeyError(c, "ssh_key_import_failed", "private_key", "", err)
+		return
+	}
+
+	logSSHKeyCreated(c, userID, key)
+	c.JSON(http.StatusCreated, models.SSHKeyResponse{
+		Success: true,
+		Key:     key,
+	})
+}
+
+func ExportSSHKey(c *gin.Context) {
+	keyID := c.Param("id")
+	if _, err := uuid.Parse(keyID); err != nil {
+		sshKeyNotFound(c)
+		return
+	}
+
+	var req models.SSHKeyExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Invalid JSON format"))
+		return
+	}
+	userID := c.GetString("user_id")
+
+	export, err := handlers.ExportSSHKeyInternal(userID, keyID, req, database.DB)
+	if err != nil {
+		writeSSHKeyError(c, "ssh_key_export_failed", "", keyID, err)
+		return
+	}
+
+	if export == nil {
+		sshKeyNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("ssh_key_exported",
+		zap.String("user_id", userID),
+		zap.String("key_id", keyID),
+		zap.String("format", export.Format),
+		zap.Bool("encrypted", req.Passphrase != ""),
+	)
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, models.SSHKeyExportResponse{
+		Success: true,
+		Export:  *export,
+	})
+}
+
+func DeleteSSHKey(c *gin.Context) {
+	keyID := c.Param("id")
+	if _, err := uuid.Parse(keyID); err != nil {
+		sshKeyNotFound(c)
+		return
+	}
+	userID := c.GetString("user_id")
+
+	deleted, err := handlers.DeleteSSHKeyInternal(userID, keyID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("ssh_key_delete_failed",
+			zap.String("key_id", keyID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	if !deleted {
+		sshKeyNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("ssh_key_deleted",
+		zap.String("user_id", userID),
+		zap.String("key_id", keyID),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func logSSHKeyCreated(c *gin.Context, userID string, key *models.SSHKey) {
+	middleware.GetLogger(c).Info("ssh_key_created",
+		zap.String("user_id", userID),
+		zap.String("key_id", key.ID),
+		zap.String("type", key.Type),
+		zap.String("source", key.Source),
+	)
+}
+
+func sshKeyNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "SSH key not found."))
+}
+
+// writeSSHKeyError reports key parsing errors against keyField and falls
+// back to the vault's errors for anything involving the private key's
+// storage.
+func writeSSHKeyError(c *gin.Context, event, keyField, keyID string, err error) {
+	switch {
+	case errors.Is(err, handlers.ErrSSHKeyExists):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeSSHKeyConflict, "The SSH key could not be saved.").
+			WithFields(apierror.Field(keyField, apierror.FieldSSHKeyDuplicate, nil)))
+	case errors.Is(err, handlers.ErrSSHKeyVaultItemNotFound):
+		apierror.Write(c, apierror.Validation(apierror.Field("vault_item_id", apierror.FieldNotFound, nil)))
+	case errors.Is(err, handlers.ErrSSHKeyNoPrivateKey):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeSSHKeyNoPrivateKey,
+			"LiveCode does not hold the private key for this SSH key."))
+	case errors.Is(err, handlers.ErrSSHKeyClientEncrypted):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeSSHKeyClientEncrypted,
+			"This private key is encrypted on the client. Export it from the desktop app."))
+	case keyField != "" && (errors.Is(err, sshkeys.ErrInvalidPublicKey) || errors.Is(err, sshkeys.ErrInvalidPrivateKey) ||
+		errors.Is(err, sshkeys.ErrUnsupportedKeyType) || errors.Is(err, sshkeys.ErrKeyTooWeak) ||
+		errors.Is(err, sshkeys.ErrPassphraseRequired) || errors.Is(err, sshkeys.ErrIncorrectPassphrase)):
+		apierror.Write(c, apierror.Validation(middleware.SSHKeyFieldError(keyField, err)))
+	default:
+		writeVaultError(c, event, keyID, err)
+	}
+}
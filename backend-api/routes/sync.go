@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/remotefs"
+	"livecode-api/internal/syncplan"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pkg/sftp"
+	"go.uber.org/zap"
+)
+
+// PlanSync is the dry run of a sync: it compares the client's file list
+// with the remote directory and returns the plan without changing
+// anything.
+func PlanSync(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("sync_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.SyncRequest)
+
+	extendWriteDeadline(c)
+
+	audit := models.KnownHostKey{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	plan, err := handlers.PlanSyncInternal(c.Request.Context(), c.GetString("user_id"), req, audit, database.DB)
+	if err != nil {
+		writeSyncError(c, "sync_plan_failed", req.ConnectionID, err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("sync_planned",
+		zap.String("sync_plan_id", plan.ID),
+		zap.String("connection_id", plan.ConnectionID),
+		zap.String("direction", plan.Direction),
+		zap.Int("actions", len(plan.Actions)),
+	)
+
+	c.JSON(http.StatusCreated, models.SyncPlanResponse{
+		Success: true,
+		Plan:    plan,
+	})
+}
+
+func GetSyncPlan(c *gin.Context) {
+	planID := c.Param("id")
+	if _, err := uuid.Parse(planID); err != nil {
+		syncPlanNotFound(c)
+		return
+	}
+
+	plan, err := handlers.GetSyncPlanInternal(c.GetString("user_id"), planID, database.DB)
+	if err != nil {
+		writeSyncError(c, "sync_plan_get_failed", "", err)
+		return
+	}
+	if plan == nil {
+		syncPlanNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SyncPlanResponse{
+		Success: true,
+		Plan:    plan,
+	})
+}
+
+// ExecuteSyncPlan starts the transfers of a plan. A plan runs at most
+// once; plan again to pick up later changes.
+func ExecuteSyncPlan(c *gin.Context) {
+	planID := c.Param("id")
+	if _, err := uuid.Parse(planID); err != nil {
+		syncPlanNotFound(c)
+		return
+	}
+
+	audit := models.KnownHostKey{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	plan, list, err := handlers.ExecuteSyncPlanInternal(c.Request.Context(), c.GetString("user_id"), planID, audit, database.DB)
+	if err != nil {
+		writeSyncError(c, "sync_execute_failed", "", err)
+		return
+	}
+	if plan == nil {
+		syncPlanNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("sync_executed",
+		zap.String("sync_plan_id", plan.ID),
+		zap.String("connection_id", plan.ConnectionID),
+		zap.Int("transfers", len(list)),
+	)
+
+	c.JSON(http.StatusOK, models.SyncExecutionResponse{
+		Success:   true,
+		Plan:      plan,
+		Transfers: list,
+	})
+}
+
+func syncPlanNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Sync plan not found."))
+}
+
+func writeSyncError(c *gin.Context, event, connectionID string, err error) {
+	var hostKeyErr *handlers.HostKeyError
+	var statusErr *sftp.StatusError
+	var serverErr *remotefs.ServerError
+
+	switch {
+	case errors.Is(err, handlers.ErrSyncPlanExecuted):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeSyncPlanExecuted,
+			"This plan was already executed. Create a new plan to sync again."))
+	case errors.Is(err, handlers.ErrSyncPlanExpired):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeSyncPlanExpired,
+			"This plan has expired. Create a new plan to sync again."))
+	case errors.Is(err, handlers.ErrSyncTooManyEntries), errors.Is(err, syncplan.ErrTooManyActions):
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeSyncTooLarge,
+			"The directory is too large to sync in one go. Sync a subdirectory or narrow the filters."))
+	case errors.Is(err, handlers.ErrSyncChecksumLimit):
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeSyncChecksumLimit,
+			"Too much data would have to be read to compare checksums. Compare by modification time instead."))
+	case errors.Is(err, handlers.ErrTransferConnectionNotFound), errors.Is(err, handlers.ErrTransferLimit),
+		errors.Is(err, handlers.ErrTransferQuota):
+		writeTransferError(c, event, "", err)
+	case errors.As(err, &hostKeyErr), errors.As(err, &statusErr), errors.As(err, &serverErr),
+		errors.Is(err, remotefs.ErrConnectFailed), errors.Is(err, remotefs.ErrAuthFailed), errors.Is(err, remotefs.ErrPoolClosed),
+		errors.Is(err, remotefs.ErrUnsupported),
+		errors.Is(err, handlers.ErrRemoteUnsupported), errors.Is(err, handlers.ErrRemoteNoCredentials),
+		errors.Is(err, handlers.ErrRemoteClientEncrypted), errors.Is(err, handlers.ErrRemoteNotDirectory),
+		errors.Is(err, os.ErrNotExist), errors.Is(err, os.ErrPermission):
+		writeRemoteError(c, event, connectionID, err)
+	default:
+		writeVaultError(c, event, "", err)
+	}
+}
@@ -0,0 +1,169 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/telemetry"
+	"livecode-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxTelemetryCompressedBytes = 1 << 20
+	maxTelemetryDecodedBytes    = 8 << 20
+	maxTelemetryBatchEvents     = 1000
+)
+
+func IngestTelemetry(ingestor *telemetry.Ingestor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		installID := c.GetHeader("X-Install-ID")
+		if _, err := uuid.Parse(installID); err != nil {
+			apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidInstallID, "A valid X-Install-ID header is required."))
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTelemetryCompressedBytes)
+
+		body, err := telemetry.NewBodyReader(c.Request.Body, c.GetHeader("Content-Encoding"), maxTelemetryDecodedBytes)
+		if errors.Is(err, telemetry.ErrUnsupportedEncoding) {
+			apierror.Write(c, apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedEncoding, "Content-Encoding must be gzip, zstd or identity."))
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Request body could not be decompressed."))
+			return
+		}
+		defer body.Close()
+
+		events, rejected, err := telemetry.DecodeBatch(body, maxTelemetryBatchEvents)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			apiErr := apierror.New(http.StatusBadRequest, apierror.CodeInvalidJSON, "Request body is not valid NDJSON.")
+
+			switch {
+			case errors.Is(err, telemetry.ErrBodyTooLarge), errors.As(err, &maxBytesErr):
+				apiErr = apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "Batch is too large.")
+			case errors.Is(err, telemetry.ErrTooManyEvents):
+				apiErr = apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "Batch exceeds the maximum number of events.")
+			}
+
+			middleware.GetLogger(c).Warn("telemetry_batch_rejected",
+				zap.String("install_id", installID),
+				zap.Error(err),
+			)
+			apierror.Write(c, apiErr)
+			return
+		}
+
+		result := ingestor.Ingest(events, rejected, installID, c.GetString("user_id"))
+
+		middleware.GetLogger(c).Info("telemetry_batch_ingested",
+			zap.String("install_id", installID),
+			zap.Int("accepted", result.Accepted),
+			zap.Int("sampled_out", result.SampledOut),
+			zap.Int("not_consented", result.NotConsented),
+			zap.Int("dropped", result.Dropped),
+			zap.Int("rejected", len(result.Rejected)),
+		)
+
+		if result.Dropped > 0 {
+			c.Header("Retry-After", "30")
+		}
+
+		c.JSON(http.StatusAccepted, telemetry.IngestResponse{
+			Success: true,
+			Result:  result,
+		})
+	}
+}
+
+func GetTelemetryConsent(ingestor *telemetry.Ingestor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		installID := c.Param("install_id")
+		if _, err := uuid.Parse(installID); err != nil {
+			apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidInstallID, "Invalid install ID."))
+			return
+		}
+
+		consent, _, err := ingestor.Consents.Get(c.Request.Context(), installID)
+		if err != nil {
+			middleware.GetLogger(c).Error("telemetry_consent_lookup_failed",
+				zap.String("install_id", installID),
+				zap.Error(err),
+			)
+			apierror.Write(c, apierror.Internal())
+			return
+		}
+
+		c.JSON(http.StatusOK, telemetry.ConsentResponse{
+			Success: true,
+			Consent: consent,
+		})
+	}
+}
+
+func UpdateTelemetryConsent(ingestor *telemetry.Ingestor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		installID := c.Param("install_id")
+		if _, err := uuid.Parse(installID); err != nil {
+			apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeInvalidInstallID, "Invalid install ID."))
+			return
+		}
+
+		var req telemetry.ConsentUpdate
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Both usage and errors consent flags are required."))
+			return
+		}
+
+		consent := telemetry.Consent{
+			InstallID: installID,
+			Usage:     *req.Usage,
+			Errors:    *req.Errors,
+		}
+
+		secret, err := ingestor.Consents.Set(c.Request.Context(), consent, c.GetHeader("X-Install-Secret"), c.GetString("user_id"))
+		if errors.Is(err, telemetry.ErrInstallSecret) {
+			apierror.Write(c, apierror.New(http.StatusForbidden, apierror.CodeInvalidInstallSecret,
+				"A valid X-Install-Secret header is required to change this install's consent."))
+			return
+		}
+		if err != nil {
+			middleware.GetLogger(c).Error("telemetry_consent_update_failed",
+				zap.String("install_id", installID),
+				zap.Error(err),
+			)
+			apierror.Write(c, apierror.Internal())
+			return
+		}
+
+		consent, _, err = ingestor.Consents.Get(c.Request.Context(), installID)
+		if err != nil {
+			middleware.GetLogger(c).Error("telemetry_consent_lookup_failed",
+				zap.String("install_id", installID),
+				zap.Error(err),
+			)
+			apierror.Write(c, apierror.Internal())
+			return
+		}
+
+		middleware.GetLogger(c).Info("telemetry_consent_updated",
+			zap.String("install_id", installID),
+			zap.Bool("usage", consent.Usage),
+			zap.Bool("errors", consent.Errors),
+			zap.Bool("secret_issued", secret != ""),
+		)
+
+		c.JSON(http.StatusOK, telemetry.ConsentResponse{
+			Success:       true,
+			Consent:       consent,
+			InstallSecret: secret,
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package routes
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/checksum"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func ListTransfers(c *gin.Context) {
+	filter := models.TransferFilter{
+		Status:     c.Query("status"),
+		SyncPlanID: c.Query("sync_plan_id"),
+		Limit:      queryInt(c, "limit", 50, 1, 200),
+		Offset:     queryInt(c, "offset", 0, 0, 1000000),
+	}
+
+	list, total, err := handlers.ListTransfersInternal(c.GetString("user_id"), filter, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("transfers_list_failed",
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TransferListResponse{
+		Success:   true,
+		Transfers: list,
+		Total:     total,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+	})
+}
+
+func CreateTransfer(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("transfer_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+	req := validatedPayload.(models.TransferRequest)
+
+	transfer, err := handlers.CreateTransferInternal(c.GetString("user_id"), req, database.DB)
+	if err != nil {
+		writeTransferError(c, "transfer_create_failed", "", err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("transfer_created",
+		zap.String("transfer_id", transfer.ID),
+		zap.String("connection_id", transfer.ConnectionID),
+		zap.String("direction", transfer.Direction),
+	)
+
+	c.JSON(http.StatusCreated, models.TransferResponse{
+		Success:  true,
+		Transfer: transfer,
+	})
+}
+
+func GetTransfer(c *gin.Context) {
+	transferID := c.Param("id")
+	if _, err := uuid.Parse(transferID); err != nil {
+		transferNotFound(c)
+		return
+	}
+
+	transfer, err := handlers.GetTransferInternal(c.GetString("user_id"), transferID, database.DB)
+	if err != nil {
+		writeTransferError(c, "transfer_get_failed", transferID, err)
+		return
+	}
+	if transfer == nil {
+		transferNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TransferResponse{
+		Success:  true,
+		Transfer: transfer,
+	})
+}
+
+// WriteTransferData stages a piece of an upload. Clients that lose the
+// connection read staged_bytes from the transfer and continue from there.
+func WriteTransferData(c *gin.Context) {
+	transferID := c.Param("id")
+	if _, err := uuid.Parse(transferID); err != nil {
+		transferNotFound(c)
+		return
+	}
+	offset, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+
+	extendReadDeadline(c)
+	extendWriteDeadline(c)
+
+	transfer, err := handlers.WriteTransferDataInternal(c.GetString("user_id"), transferID, offset, c.Request.Body, database.DB)
+	if err != nil {
+		writeTransferError(c, "transfer_write_failed", transferID, err)
+		return
+	}
+	if transfer == nil {
+		transferNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TransferResponse{
+		Success:  true,
+		Transfer: transfer,
+	})
+}
+
+// ReadTransferData serves a completed download, with Range support.
+func ReadTransferData(c *gin.Context) {
+	transferID := c.Param("id")
+	if _, err := uuid.Parse(transferID); err != nil {
+		transferNotFound(c)
+		return
+	}
+
+	transfer, file, err := handlers.OpenTransferDataInternal(c.GetString("user_id"), transferID, database.DB)
+	if err != nil {
+		writeTransferError(c, "transfer_read_failed", transferID, err)
+		return
+	}
+	if transfer == nil {
+		transferNotFound(c)
+		return
+	}
+	defer file.Close()
+
+	extendWriteDeadline(c)
+
+	name := path.Base(transfer.RemotePath)
+	if transfer.ChecksumAlgorithm == checksum.SHA256 && transfer.DestinationChecksum != nil {
+		if digest, err := hex.DecodeString(*transfer.DestinationChecksum); err == nil {
+			c.Header("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest)+":")
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	http.ServeContent(c.Writer, c.Request, name, *transfer.FinishedAt, file)
+}
+
+func PauseTransfer(c *gin.Context) {
+	changeTransfer(c, "transfer_paused", handlers.PauseTransferInternal)
+}
+
+func ResumeTransfer(c *gin.Context) {
+	changeTransfer(c, "transfer_resumed", handlers.ResumeTransferInternal)
+}
+
+func CancelTransfer(c *gin.Context) {
+	changeTransfer(c, "transfer_canceled", handlers.CancelTransferInternal)
+}
+
+func changeTransfer(c *gin.Context, event string, change func(userID, transferID string, db *sql.DB) (*models.Transfer, error)) {
+	transferID := c.Param("id")
+	if _, err := uuid.Parse(transferID); err != nil {
+		transferNotFound(c)
+		return
+	}
+
+	transfer, err := change(c.GetString("user_id"), transferID, database.DB)
+	if err != nil {
+		writeTransferError(c, event+"_failed", transferID, err)
+		return
+	}
+	if transfer == nil {
+		transferNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info(event,
+		zap.String("transfer_id", transferID),
+	)
+
+	c.JSON(http.StatusOK, models.TransferResponse{
+		Success:  true,
+		Transfer: transfer,
+	})
+}
+
+func DeleteTransfer(c *gin.Context) {
+	transferID := c.Param("id")
+	if _, err := uuid.Parse(transferID); err != nil {
+		transferNotFound(c)
+		return
+	}
+
+	deleted, err := handlers.DeleteTransferInternal(c.GetString("user_id"), transferID, database.DB)
+	if err != nil {
+		writeTransferError(c, "transfer_delete_failed", transferID, err)
+		return
+	}
+	if !deleted {
+		transferNotFound(c)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func transferNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Transfer not found."))
+}
+
+func writeTransferError(c *gin.Context, event, transferID string, err error) {
+	var offsetErr *handlers.TransferOffsetError
+	var sizeErr *handlers.TransferSizeError
+
+	switch {
+	case errors.Is(err, handlers.ErrTransferConnectionNotFound):
+		apierror.Write(c, apierror.Validation(apierror.Field("connection_id", apierror.FieldNotFound, nil)))
+	case errors.Is(err, handlers.ErrRemoteUnsupported):
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeRemoteUnsupported,
+			"This connection cannot be opened through the gateway."))
+	case errors.Is(err, handlers.ErrTransferState):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeTransferInvalidState,
+			"The transfer cannot do this in its current state."))
+	case errors.Is(err, handlers.ErrTransferLimit):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeTransferLimitReached,
+			"You have too many unfinished transfers. Wait for some to finish or cancel them."))
+	case errors.As(err, &sizeErr):
+		apierror.Write(c, apierror.Validation(apierror.Field("size", apierror.FieldTooLarge, map[string]any{"max": sizeErr.Max})))
+	case errors.Is(err, handlers.ErrTransferQuota):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeTransferQuotaExceeded,
+			"Your transfers would hold more data on the server than you are allowed. Finish or cancel some uploads, or delete downloads you have fetched."))
+	case errors.Is(err, handlers.ErrTransferBusy):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeTransferBusy,
+			"Another upload to this transfer is in progress."))
+	case errors.As(err, &offsetErr):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeTransferOffsetMismatch,
+			"The data does not continue where the staged data ends.").
+			WithFields(apierror.Field("offset", apierror.FieldTransferOffset, map[string]any{"expected": offsetErr.Expected})))
+	case errors.Is(err, handlers.ErrTransferIncomplete):
+		apierror.Write(c, apierror.New(http.StatusBadRequest, apierror.CodeTransferIncomplete,
+			"The upload was interrupted. Continue from the transfer's staged_bytes."))
+	default:
+		middleware.GetLogger(c).Error(event,
+			zap.String("transfer_id", transferID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+	}
+}
@@ -0,0 +1,249 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"livecode-api/database"
+	"livecode-api/handlers"
+	"livecode-api/internal/apierror"
+	"livecode-api/internal/vault"
+	"livecode-api/middleware"
+	"livecode-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func GetVaultSettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	settings, err := handlers.GetVaultSettingsInternal(userID, database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("vault_settings_lookup_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.VaultSettingsResponse{
+		Success: true,
+		Vault:   settings,
+	})
+}
+
+func UpdateVaultSettings(c *gin.Context) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("vault_settings_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return
+	}
+
+	req := validatedPayload.(models.VaultSettingsRequest)
+	userID := c.GetString("user_id")
+
+	settings, err := handlers.UpdateVaultSettingsInternal(userID, req, database.DB)
+	if err != nil {
+		writeVaultError(c, "vault_settings_update_failed", "", err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("vault_settings_updated",
+		zap.String("user_id", userID),
+		zap.Bool("zero_knowledge", settings.ZeroKnowledge),
+	)
+
+	c.JSON(http.StatusOK, models.VaultSettingsResponse{
+		Success: true,
+		Vault:   settings,
+	})
+}
+
+func ListVaultItems(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	items, err := handlers.ListVaultItemsInternal(userID, c.Query("connection_id"), database.DB)
+	if err != nil {
+		middleware.GetLogger(c).Error("vault_items_list_failed",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.VaultItemListResponse{
+		Success: true,
+		Items:   items,
+	})
+}
+
+func GetVaultItem(c *gin.Context) {
+	itemID := c.Param("id")
+	if _, err := uuid.Parse(itemID); err != nil {
+		vaultItemNotFound(c)
+		return
+	}
+	userID := c.GetString("user_id")
+
+	item, err := handlers.GetVaultItemInternal(userID, itemID, database.DB)
+	if err != nil {
+		writeVaultError(c, "vault_item_lookup_failed", itemID, err)
+		return
+	}
+
+	if item == nil {
+		vaultItemNotFound(c)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, models.VaultItemResponse{
+		Success: true,
+		Item:    item,
+	})
+}
+
+func CreateVaultItem(c *gin.Context) {
+	input, ok := validatedVaultItem(c)
+	if !ok {
+		return
+	}
+	if input.Encryption == vault.EncryptionClient && input.ID == "" {
+		apierror.Write(c, apierror.Validation(apierror.Field("id", apierror.FieldRequired, nil)))
+		return
+	}
+	userID := c.GetString("user_id")
+
+	item, err := handlers.CreateVaultItemInternal(userID, input, database.DB)
+	if err != nil {
+		writeVaultError(c, "vault_item_create_failed", input.ID, err)
+		return
+	}
+
+	middleware.GetLogger(c).Info("vault_item_created",
+		zap.String("user_id", userID),
+		zap.String("item_id", item.ID),
+		zap.String("encryption", item.Encryption),
+	)
+
+	c.JSON(http.StatusCreated, models.VaultItemResponse{
+		Success: true,
+		Item:    item,
+	})
+}
+
+func UpdateVaultItem(c *gin.Context) {
+	itemID := c.Param("id")
+	if _, err := uuid.Parse(itemID); err != nil {
+		vaultItemNotFound(c)
+		return
+	}
+
+	input, ok := validatedVaultItem(c)
+	if !ok {
+		return
+	}
+	if input.Version == 0 {
+		apierror.Write(c, apierror.Validation(apierror.Field("version", apierror.FieldRequired, nil)))
+		return
+	}
+	userID := c.GetString("user_id")
+
+	item, err := handlers.UpdateVaultItemInternal(userID, itemID, input, database.DB)
+	if err != nil {
+		writeVaultError(c, "vault_item_update_failed", itemID, err)
+		return
+	}
+
+	if item == nil {
+		vaultItemNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("vault_item_updated",
+		zap.String("user_id", userID),
+		zap.String("item_id", itemID),
+		zap.Int("version", item.Version),
+	)
+
+	c.JSON(http.StatusOK, models.VaultItemResponse{
+		Success: true,
+		Item:    item,
+	})
+}
+
+func DeleteVaultItem(c *gin.Context) {
+	itemID := c.Param("id")
+	if _, err := uuid.Parse(itemID); err != nil {
+		vaultItemNotFound(c)
+		return
+	}
+	userID := c.GetString("user_id")
+
+	deleted, err := handlers.DeleteVaultItemInternal(userID, itemID, queryInt(c, "version", 0, 0, 1<<31-1), database.DB)
+	if err != nil {
+		writeVaultError(c, "vault_item_delete_failed", itemID, err)
+		return
+	}
+
+	if !deleted {
+		vaultItemNotFound(c)
+		return
+	}
+
+	middleware.GetLogger(c).Info("vault_item_deleted",
+		zap.String("user_id", userID),
+		zap.String("item_id", itemID),
+	)
+
+	c.Status(http.StatusNoContent)
+}
+
+func validatedVaultItem(c *gin.Context) (models.VaultItemInput, bool) {
+	validatedPayload, exists := c.Get("validated_payload")
+	if !exists {
+		middleware.GetLogger(c).Error("vault_item_validation_missing")
+		apierror.Write(c, apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Validation error occurred."))
+		return models.VaultItemInput{}, false
+	}
+	return validatedPayload.(models.VaultItemInput), true
+}
+
+func vaultItemNotFound(c *gin.Context) {
+	apierror.Write(c, apierror.New(http.StatusNotFound, apierror.CodeNotFound, "Vault item not found."))
+}
+
+func writeVaultError(c *gin.Context, event, itemID string, err error) {
+	switch {
+	case errors.Is(err, handlers.ErrVaultUnavailable):
+		apierror.Write(c, apierror.New(http.StatusServiceUnavailable, apierror.CodeVaultUnavailable,
+			"Server-side encryption is not configured. Encrypt the secret on the client instead."))
+	case errors.Is(err, handlers.ErrVaultZeroKnowledge):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeVaultZeroKnowledge,
+			"This vault is in zero-knowledge mode and only accepts client-encrypted secrets."))
+	case errors.Is(err, handlers.ErrVaultMigrationRequired):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeVaultMigrationRequired,
+			"Re-encrypt every server-encrypted secret on the client before enabling zero-knowledge mode."))
+	case errors.Is(err, handlers.ErrVaultKDFRequired):
+		apierror.Write(c, apierror.Validation(apierror.Field("kdf", apierror.FieldRequired, nil)))
+	case errors.Is(err, handlers.ErrVaultConnectionNotFound):
+		apierror.Write(c, apierror.Validation(apierror.Field("connection_id", apierror.FieldNotFound, nil)))
+	case errors.Is(err, handlers.ErrVaultItemExists):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeVaultItemExists,
+			"A secret with this ID, or of this kind for the connection, is already stored."))
+	case errors.Is(err, handlers.ErrVaultVersionConflict):
+		apierror.Write(c, apierror.New(http.StatusConflict, apierror.CodeVersionConflict,
+			"This secret was changed on another device. Reload it and try again."))
+	default:
+		middleware.GetLogger(c).Error(event,
+			zap.String("item_id", itemID),
+			zap.Error(err),
+		)
+		apierror.Write(c, apierror.Internal())
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+const maxFingerprintFrames = 5
+
+var (
+	uuidPattern        = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	urlPattern         = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s'"]+`)
+	windowsPathPattern = regexp.MustCompile(`[a-zA-Z]:\\[^\s'"]+`)
+	unixPathPattern    = regexp.MustCompile(`(^|[\s('"=])(/[^\s'"():]+)+`)
+	quotedPattern      = regexp.MustCompile(`'[^']*'|"[^"]*"|` + "`[^`]*`")
+	hexPattern         = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b|\b[0-9a-fA-F]{8,}\b`)
+	numberPattern      = regexp.MustCompile(`\d+`)
+	whitespacePattern  = regexp.MustCompile(`\s+`)
+
+	frameLocationPattern = regexp.MustCompile(`:\d+(:\d+)?\)?$`)
+	frameAddressPattern  = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	frameQueryPattern    = regexp.MustCompile(`\?[^\s:)]*`)
+	bundleHashPattern    = regexp.MustCompile(`[-.][A-Za-z0-9_]{8,}(\.(js|mjs|css))`)
+	frameIndexPattern    = regexp.MustCompile(`^#?\d+[:\s]+`)
+)
+
+// FingerprintClientError groups client errors that share a root cause. Stack
+// frames win over the message when present because messages often embed
+// runtime values that would otherwise split one issue into many.
+func FingerprintClientError(errorType, message, stackTrace string) string {
+	parts := []string{strings.ToLower(strings.TrimSpace(errorType))}
+
+	frames := NormalizeStackFrames(stackTrace, maxFingerprintFrames)
+	if len(frames) > 0 {
+		parts = append(parts, frames...)
+	} else {
+		parts = append(parts, NormalizeErrorMessage(message))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func NormalizeErrorMessage(message string) string {
+	normalized := strings.ToLower(message)
+	normalized = uuidPattern.ReplaceAllString(normalized, "<uuid>")
+	normalized = urlPattern.ReplaceAllString(normalized, "<url>")
+	normalized = windowsPathPattern.ReplaceAllString(normalized, "<path>")
+	normalized = unixPathPattern.ReplaceAllString(normalized, "$1<path>")
+	normalized = quotedPattern.ReplaceAllString(normalized, "<str>")
+	normalized = hexPattern.ReplaceAllString(normalized, "<hex>")
+	normalized = numberPattern.ReplaceAllString(normalized, "<n>")
+	normalized = whitespacePattern.ReplaceAllString(normalized, " ")
+	normalized = strings.TrimSpace(normalized)
+
+	if len(normalized) > 200 {
+		normalized = normalized[:200]
+	}
+
+	return normalized
+}
+
+func NormalizeStackFrames(stackTrace string, limit int) []string {
+	frames := []string{}
+
+	for _, line := range strings.Split(stackTrace, "\n") {
+		frame := strings.TrimSpace(line)
+		if frame == "" || !looksLikeFrame(frame) {
+			continue
+		}
+
+		frame = frameIndexPattern.ReplaceAllString(frame, "")
+		frame = frameQueryPattern.ReplaceAllString(frame, "")
+		frame = frameLocationPattern.ReplaceAllString(frame, "")
+		frame = frameAddressPattern.ReplaceAllString(frame, "<addr>")
+		frame = bundleHashPattern.ReplaceAllString(frame, "$1")
+		frame = stripFramePathPrefix(frame)
+
+		frames = append(frames, strings.ToLower(frame))
+		if len(frames) == limit {
+			break
+		}
+	}
+
+	return frames
+}
+
+func looksLikeFrame(line string) bool {
+	return strings.HasPrefix(line, "at ") ||
+		strings.Contains(line, "@") ||
+		strings.Contains(line, "::") ||
+		frameIndexPattern.MatchString(line) ||
+		frameLocationPattern.MatchString(line)
+}
+
+func stripFramePathPrefix(frame string) string {
+	for _, marker := range []string{"/src/", "/assets/", "\\src\\"} {
+		if index := strings.LastIndex(frame, marker); index >= 0 {
+			start := strings.LastIndexAny(frame[:index], " (@")
+			return frame[:start+1] + frame[index+1:]
+		}
+	}
+
+	return frame
+}
+
+func ClientIssueTitle(errorType, message string) string {
+	firstLine := strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+	title := strings.TrimSpace(errorType)
+	if firstLine != "" {
+		title += ": " + firstLine
+	}
+
+	if runes := []rune(title); len(runes) > 300 {
+		title = string(runes[:297]) + "..."
+	}
+
+	return title
+}
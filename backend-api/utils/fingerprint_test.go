@@ -0,0 +1,51 @@
+package utils
+
+import "testing"
+
+func TestFingerprintClientError_GroupsByStackIgnoringLocations(t *testing.T) {
+	first := FingerprintClientError("TypeError", "Cannot read properties of undefined (reading 'id')",
+		"TypeError: Cannot read properties of undefined\n"+
+			"    at loadProfile (http://localhost:1420/assets/index-a1b2c3d4.js:120:15)\n"+
+			"    at async App (http://localhost:1420/assets/index-a1b2c3d4.js:88:3)")
+
+	second := FingerprintClientError("TypeError", "Cannot read properties of undefined (reading 'email')",
+		"TypeError: Cannot read properties of undefined\n"+
+			"    at loadProfile (tauri://localhost/assets/index-9f8e7d6c.js:131:9)\n"+
+			"    at async App (tauri://localhost/assets/index-9f8e7d6c.js:90:1)")
+
+	if first != second {
+		t.Errorf("Expected matching fingerprints, got: %s and %s", first, second)
+	}
+}
+
+func TestFingerprintClientError_GroupsByNormalizedMessage(t *testing.T) {
+	first := FingerprintClientError("NetworkError", "Request 42 to https://api.example.com/v1/users failed after 3000ms", "")
+	second := FingerprintClientError("NetworkError", "Request 7 to https://api.example.com/v1/profile failed after 15ms", "")
+
+	if first != second {
+		t.Errorf("Expected matching fingerprints, got: %s and %s", first, second)
+	}
+
+	other := FingerprintClientError("NetworkError", "Connection refused", "")
+	if other == first {
+		t.Error("Expected different messages to produce different fingerprints")
+	}
+}
+
+func TestFingerprintClientError_SeparatesErrorTypes(t *testing.T) {
+	first := FingerprintClientError("TypeError", "boom", "")
+	second := FingerprintClientError("RangeError", "boom", "")
+
+	if first == second {
+		t.Error("Expected different error types to produce different fingerprints")
+	}
+}
+
+func TestNormalizeErrorMessage(t *testing.T) {
+	got := NormalizeErrorMessage(`Failed to open "C:\Users\ana\config.json" for user 3f2504e0-4f89-11d3-9a0c-0305e82c3301`)
+	want := "failed to open <str> for user <uuid>"
+
+	if got != want {
+		t.Errorf("Expected %q, got: %q", want, got)
+	}
+}
@@ -3,15 +3,18 @@ package utils
 import (
 	"errors"
 	"livecode-api/config"
+	"livecode-api/internal/metrics"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 func VerifyJWT(tokenString string) (*jwt.Token, jwt.MapClaims, error) {
 	if config.JWTSecret == "" {
+		metrics.JWTVerificationFailuresTotal.WithLabelValues("not_configured").Inc()
 		return nil, nil, errors.New("JWT_SECRET not configured")
 	}
 
@@ -23,27 +26,66 @@ func VerifyJWT(tokenString string) (*jwt.Token, jwt.MapClaims, error) {
 	})
 
 	if err != nil {
+		metrics.JWTVerificationFailuresTotal.WithLabelValues(jwtFailureReason(err)).Inc()
 		return nil, nil, err
 	}
 
 	if !token.Valid {
+		metrics.JWTVerificationFailuresTotal.WithLabelValues("invalid").Inc()
 		return nil, nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
+		metrics.JWTVerificationFailuresTotal.WithLabelValues("invalid_claims").Inc()
 		return nil, nil, errors.New("invalid token claims")
 	}
 
 	return token, claims, nil
 }
 
+func jwtFailureReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "not_valid_yet"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrSignatureInvalid):
+		return "bad_signature"
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "unverifiable"
+	default:
+		return "invalid"
+	}
+}
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// TokenType returns the typ claim. Tokens issued before it was set are
+// told apart by their claims: only access tokens carry a username.
+func TokenType(claims jwt.MapClaims) string {
+	if typ, ok := claims["typ"].(string); ok {
+		return typ
+	}
+	if _, ok := claims["username"]; ok {
+		return TokenTypeAccess
+	}
+	return TokenTypeRefresh
+}
+
 type TokenPair struct {
-	AccessToken  string
-	RefreshToken string
+	AccessToken           string
+	RefreshToken          string
+	RefreshTokenID        string
+	RefreshTokenExpiresAt time.Time
 }
 
-func GenerateTokenPair(userID string, username string, email string) (*TokenPair, error) {
+func GenerateTokenPair(userID string, username string, email string, role string) (*TokenPair, error) {
 	if config.JWTSecret == "" {
 		return nil, errors.New("JWT_SECRET not configured")
 	}
@@ -57,6 +99,8 @@ func GenerateTokenPair(userID string, username string, email string) (*TokenPair
 		"user_id":  userID,
 		"username": username,
 		"email":    email,
+		"role":     role,
+		"typ":      TokenTypeAccess,
 		"exp":      time.Now().Add(time.Duration(accessTokenExpiry) * time.Minute).Unix(),
 		"iat":      time.Now().Unix(),
 	}
@@ -73,9 +117,14 @@ func GenerateTokenPair(userID string, username string, email string) (*TokenPair
 		refreshTokenExpiry = 30
 	}
 
+	refreshTokenID := uuid.New().String()
+	refreshTokenExpiresAt := time.Now().Add(time.Duration(refreshTokenExpiry) * 24 * time.Hour)
+
 	refreshTokenClaims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Duration(refreshTokenExpiry) * 24 * time.Hour).Unix(),
+		"jti":     refreshTokenID,
+		"typ":     TokenTypeRefresh,
+		"exp":     refreshTokenExpiresAt.Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
@@ -87,7 +136,9 @@ func GenerateTokenPair(userID string, username string, email string) (*TokenPair
 	}
 
 	return &TokenPair{
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
+		AccessToken:           accessTokenString,
+		RefreshToken:          refreshTokenString,
+		RefreshTokenID:        refreshTokenID,
+		RefreshTokenExpiresAt: refreshTokenExpiresAt,
 	}, nil
 }
@@ -7,6 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"livecode-api/internal/metrics"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -25,7 +28,9 @@ func HashPassword(password string) (string, error) {
 		return "", err
 	}
 
+	start := time.Now()
 	hash := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, keyLength)
+	metrics.PasswordHashDuration.WithLabelValues("hash").Observe(time.Since(start).Seconds())
 
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
@@ -52,7 +57,9 @@ func VerifyPassword(encodedHash, password string) (bool, error) {
 		return false, err
 	}
 
+	start := time.Now()
 	compareHash := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, keyLength)
+	metrics.PasswordHashDuration.WithLabelValues("verify").Observe(time.Since(start).Seconds())
 
 	if subtle.ConstantTimeCompare(hash, compareHash) == 1 {
 		return true, nil